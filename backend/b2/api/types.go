@@ -128,6 +128,26 @@ type File struct {
 	SHA1            string            `json:"contentSha1"`     // The SHA1 of the bytes stored in the file.
 	ContentType     string            `json:"contentType"`     // The MIME type of the file.
 	Info            map[string]string `json:"fileInfo"`        // The custom information that was uploaded with the file. This is a JSON object, holding the name/value pairs that were uploaded with the file.
+	FileRetention   *FileRetention    `json:"fileRetention"`   // The Object Lock retention settings for this file, if readable with the key.
+	LegalHold       *LegalHold        `json:"legalHold"`       // The Object Lock legal hold status for this file, if readable with the key.
+}
+
+// FileRetentionValue is the Object Lock retention of a file
+type FileRetentionValue struct {
+	Mode                 string     `json:"mode"`                 // "governance" or "compliance" or null if not set
+	RetainUntilTimestamp *Timestamp `json:"retainUntilTimestamp"` // when the retention expires or null if not set
+}
+
+// FileRetention is the Object Lock retention of a file as returned by B2
+type FileRetention struct {
+	IsClientAuthorizedToRead bool                `json:"isClientAuthorizedToRead"`
+	Value                    *FileRetentionValue `json:"value"`
+}
+
+// LegalHold is the Object Lock legal hold of a file as returned by B2
+type LegalHold struct {
+	IsClientAuthorizedToRead bool   `json:"isClientAuthorizedToRead"`
+	Value                    string `json:"value"` // "on" or "off" or null if not set
 }
 
 // AuthorizeAccountResponse is as returned from the b2_authorize_account call
@@ -176,6 +196,16 @@ type ListFileNamesResponse struct {
 	NextFileID   *string `json:"nextFileId"`   // What to pass in to startFileId for the next search to continue where this one left off, or null if there are no more files.
 }
 
+// ListUnfinishedLargeFilesRequest is passed to b2_list_unfinished_large_files
+//
+// The response is a ListFileNamesResponse
+type ListUnfinishedLargeFilesRequest struct {
+	BucketID     string `json:"bucketId"`               // required - The bucket to look for file names in.
+	NamePrefix   string `json:"namePrefix,omitempty"`   // optional - Only return files whose names match this prefix.
+	StartFileID  string `json:"startFileId,omitempty"`  // optional - The first upload to return.
+	MaxFileCount int    `json:"maxFileCount,omitempty"` // optional - The maximum number of files to return from this call. The default value is 100, and the maximum allowed is 100.
+}
+
 // GetUploadURLRequest is passed to b2_get_upload_url
 type GetUploadURLRequest struct {
 	BucketID string `json:"bucketId"` // The ID of the bucket that you want to upload to.
@@ -200,6 +230,8 @@ type FileInfo struct {
 	SHA1            string            `json:"contentSha1"`     // The SHA1 of the bytes stored in the file.
 	ContentType     string            `json:"contentType"`     // The MIME type of the file.
 	Info            map[string]string `json:"fileInfo"`        // The custom information that was uploaded with the file. This is a JSON object, holding the name/value pairs that were uploaded with the file.
+	FileRetention   *FileRetention    `json:"fileRetention"`   // The Object Lock retention settings for this file, if readable with the key.
+	LegalHold       *LegalHold        `json:"legalHold"`       // The Object Lock legal hold status for this file, if readable with the key.
 }
 
 // CreateBucketRequest is used to create a bucket
@@ -253,6 +285,9 @@ type StartLargeFileRequest struct {
 	Name        string            `json:"fileName"`    // The name of the file. See Files for requirements on file names.
 	ContentType string            `json:"contentType"` // The MIME type of the content of the file, which will be returned in the Content-Type header when downloading the file. Use the Content-Type b2/x-auto to automatically set the stored Content-Type post upload. In the case where a file extension is absent or the lookup fails, the Content-Type is set to application/octet-stream.
 	Info        map[string]string `json:"fileInfo"`    // A JSON object holding the name/value pairs for the custom file info.
+	// Object Lock settings for the file - only allowed on buckets with Object Lock enabled
+	FileRetention *FileRetentionValue `json:"fileRetention,omitempty"` // The retention mode and time to set
+	LegalHold     string              `json:"legalHold,omitempty"`     // "on" to set a legal hold
 }
 
 // StartLargeFileResponse is the response to StartLargeFileRequest
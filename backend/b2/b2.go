@@ -34,28 +34,32 @@ import (
 )
 
 const (
-	defaultEndpoint     = "https://api.backblazeb2.com"
-	headerPrefix        = "x-bz-info-" // lower case as that is what the server returns
-	timeKey             = "src_last_modified_millis"
-	timeHeader          = headerPrefix + timeKey
-	sha1Key             = "large_file_sha1"
-	sha1Header          = "X-Bz-Content-Sha1"
-	sha1InfoHeader      = headerPrefix + sha1Key
-	testModeHeader      = "X-Bz-Test-Mode"
-	retryAfterHeader    = "Retry-After"
-	minSleep            = 10 * time.Millisecond
-	maxSleep            = 5 * time.Minute
-	decayConstant       = 1 // bigger for slower decay, exponential
-	maxParts            = 10000
-	maxVersions         = 100 // maximum number of versions we search in --b2-versions mode
-	minChunkSize        = 5E6
-	defaultChunkSize    = 96 * 1024 * 1024
-	defaultUploadCutoff = 200E6
+	defaultEndpoint      = "https://api.backblazeb2.com"
+	headerPrefix         = "x-bz-info-" // lower case as that is what the server returns
+	timeKey              = "src_last_modified_millis"
+	timeHeader           = headerPrefix + timeKey
+	sha1Key              = "large_file_sha1"
+	sha1Header           = "X-Bz-Content-Sha1"
+	sha1InfoHeader       = headerPrefix + sha1Key
+	testModeHeader       = "X-Bz-Test-Mode"
+	retentionModeHeader  = "X-Bz-File-Retention-Mode"
+	retentionUntilHeader = "X-Bz-File-Retention-Retain-Until-Timestamp"
+	legalHoldHeader      = "X-Bz-File-Legal-Hold"
+	retryAfterHeader     = "Retry-After"
+	minSleep             = 10 * time.Millisecond
+	maxSleep             = 5 * time.Minute
+	decayConstant        = 1 // bigger for slower decay, exponential
+	maxParts             = 10000
+	maxVersions          = 100 // maximum number of versions we search in --b2-versions mode
+	minChunkSize         = 5E6
+	defaultChunkSize     = 96 * 1024 * 1024
+	defaultUploadCutoff  = 200E6
 )
 
 // Globals
 var (
-	errNotWithVersions = errors.New("can't modify or delete files in --b2-versions mode")
+	errNotWithVersions  = errors.New("can't modify or delete files in --b2-versions mode")
+	errNotWithVersionAt = errors.New("can't modify or delete files with --version-at")
 )
 
 // Register with Fs
@@ -101,20 +105,52 @@ func init() {
 			Help:     "Upload chunk size. Must fit in memory.",
 			Default:  fs.SizeSuffix(defaultChunkSize),
 			Advanced: true,
+		}, {
+			Name: "file_lock_mode",
+			Help: `Object Lock retention mode to set on uploaded files.
+
+The bucket must have been created with Object Lock enabled. Set
+file_lock_retention as well to say how long files are kept for.`,
+			Default:  "",
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Don't set a retention mode",
+			}, {
+				Value: "governance",
+				Help:  "Governance mode - can be removed by keys with the bypassGovernance capability",
+			}, {
+				Value: "compliance",
+				Help:  "Compliance mode - can't be removed until the retention period is over",
+			}},
+		}, {
+			Name:     "file_lock_retention",
+			Help:     "How long uploaded files are locked for with file_lock_mode.",
+			Default:  fs.Duration(0),
+			Advanced: true,
+		}, {
+			Name:     "file_lock_legal_hold",
+			Help:     "Set a legal hold on uploaded files so they can't be deleted until it is removed.",
+			Default:  false,
+			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	Account      string        `config:"account"`
-	Key          string        `config:"key"`
-	Endpoint     string        `config:"endpoint"`
-	TestMode     string        `config:"test_mode"`
-	Versions     bool          `config:"versions"`
-	HardDelete   bool          `config:"hard_delete"`
-	UploadCutoff fs.SizeSuffix `config:"upload_cutoff"`
-	ChunkSize    fs.SizeSuffix `config:"chunk_size"`
+	Account           string        `config:"account"`
+	Key               string        `config:"key"`
+	Endpoint          string        `config:"endpoint"`
+	TestMode          string        `config:"test_mode"`
+	Versions          bool          `config:"versions"`
+	HardDelete        bool          `config:"hard_delete"`
+	UploadCutoff      fs.SizeSuffix `config:"upload_cutoff"`
+	ChunkSize         fs.SizeSuffix `config:"chunk_size"`
+	FileLockMode      string        `config:"file_lock_mode"`
+	FileLockRetention fs.Duration   `config:"file_lock_retention"`
+	FileLockLegalHold bool          `config:"file_lock_legal_hold"`
 }
 
 // Fs represents a remote b2 server
@@ -135,17 +171,20 @@ type Fs struct {
 	authMu        sync.Mutex                   // lock for authorizing the account
 	pacer         *pacer.Pacer                 // To pace and retry the API calls
 	bufferTokens  chan []byte                  // control concurrency of multipart uploads
+	versionAt     time.Time                    // show the files as they were at this time if set
 }
 
 // Object describes a b2 object
 type Object struct {
-	fs       *Fs       // what this object is part of
-	remote   string    // The remote path
-	id       string    // b2 id of the file
-	modTime  time.Time // The modified time of the object if known
-	sha1     string    // SHA-1 hash if known
-	size     int64     // Size of the object
-	mimeType string    // Content-Type of the object
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	id          string    // b2 id of the file
+	modTime     time.Time // The modified time of the object if known
+	sha1        string    // SHA-1 hash if known
+	size        int64     // Size of the object
+	mimeType    string    // Content-Type of the object
+	lockedUntil time.Time // Object Lock retention time if known
+	legalHold   bool      // set if the object has a legal hold
 }
 
 // ------------------------------------------------------------
@@ -277,6 +316,10 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if opt.ChunkSize < minChunkSize {
 		return nil, errors.Errorf("b2: chunk size can't be less than %v - was %v", minChunkSize, opt.ChunkSize)
 	}
+	opt.FileLockMode = strings.ToLower(opt.FileLockMode)
+	if (opt.FileLockMode == "") != (opt.FileLockRetention <= 0) {
+		return nil, errors.New("b2: file_lock_mode and file_lock_retention must be set together")
+	}
 	bucket, directory, err := parsePath(root)
 	if err != nil {
 		return nil, err
@@ -296,8 +339,9 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		bucket:       bucket,
 		root:         directory,
 		srv:          rest.NewClient(fshttp.NewClient(fs.Config)).SetErrorHandler(errorHandler),
-		pacer:        pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		pacer:        pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant).SetName(name),
 		bufferTokens: make(chan []byte, fs.Config.Transfers),
+		versionAt:    time.Time(fs.Config.VersionAt),
 	}
 	f.features = (&fs.Features{
 		ReadMimeType:  true,
@@ -570,12 +614,61 @@ func (f *Fs) list(dir string, recurse bool, prefix string, limit int, hidden boo
 	return nil
 }
 
+// listHidden returns whether the listings need the old versions and
+// the hidden files
+func (f *Fs) listHidden() bool {
+	return f.opt.Versions || !f.versionAt.IsZero()
+}
+
+// fileRetention returns the Object Lock retention to set on uploads
+// or nil if none is configured
+func (f *Fs) fileRetention() *api.FileRetentionValue {
+	if f.opt.FileLockMode == "" {
+		return nil
+	}
+	until := api.Timestamp(time.Now().Add(time.Duration(f.opt.FileLockRetention)))
+	return &api.FileRetentionValue{
+		Mode:                 f.opt.FileLockMode,
+		RetainUntilTimestamp: &until,
+	}
+}
+
+// checkWritable returns an error if the remote can't be modified
+// because it is showing old versions
+func (f *Fs) checkWritable() error {
+	if f.opt.Versions {
+		return errNotWithVersions
+	}
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
+	return nil
+}
+
+// visibleAt returns whether object is the version of remote to show
+// with --version-at.  The versions of each file are listed newest
+// first so this is the first one uploaded before versionAt.
+func (f *Fs) visibleAt(remote string, object *api.File, last *string) bool {
+	if remote == *last || object.Action == "start" || time.Time(object.UploadTimestamp).After(f.versionAt) {
+		return false
+	}
+	*last = remote
+	// a hide marker means the file was deleted at versionAt
+	return object.Action != "hide"
+}
+
 // Convert a list item into a DirEntry
 func (f *Fs) itemToDirEntry(remote string, object *api.File, isDirectory bool, last *string) (fs.DirEntry, error) {
 	if isDirectory {
 		d := fs.NewDir(remote, time.Time{})
 		return d, nil
 	}
+	if !f.versionAt.IsZero() {
+		if !f.visibleAt(remote, object, last) {
+			return nil, nil
+		}
+		return f.newObjectWithInfo(remote, object)
+	}
 	if remote == *last {
 		remote = object.UploadTimestamp.AddVersion(remote)
 	} else {
@@ -604,7 +697,7 @@ func (f *Fs) markBucketOK() {
 // listDir lists a single directory
 func (f *Fs) listDir(dir string) (entries fs.DirEntries, err error) {
 	last := ""
-	err = f.list(dir, false, "", 0, f.opt.Versions, func(remote string, object *api.File, isDirectory bool) error {
+	err = f.list(dir, false, "", 0, f.listHidden(), func(remote string, object *api.File, isDirectory bool) error {
 		entry, err := f.itemToDirEntry(remote, object, isDirectory, &last)
 		if err != nil {
 			return err
@@ -676,7 +769,7 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 	}
 	list := walk.NewListRHelper(callback)
 	last := ""
-	err = f.list(dir, true, "", 0, f.opt.Versions, func(remote string, object *api.File, isDirectory bool) error {
+	err = f.list(dir, true, "", 0, f.listHidden(), func(remote string, object *api.File, isDirectory bool) error {
 		entry, err := f.itemToDirEntry(remote, object, isDirectory, &last)
 		if err != nil {
 			return err
@@ -970,19 +1063,238 @@ func (f *Fs) purge(oldOnly bool) error {
 
 // Purge deletes all the files and directories including the old versions.
 func (f *Fs) Purge() error {
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
 	return f.purge(false)
 }
 
 // CleanUp deletes all the hidden files.
 func (f *Fs) CleanUp() error {
+	if !f.versionAt.IsZero() {
+		return errNotWithVersionAt
+	}
 	return f.purge(true)
 }
 
+// ListUploads lists the large file uploads under the root which
+// have been started but not finished or cancelled
+func (f *Fs) ListUploads() (uploads []fs.PendingUpload, err error) {
+	bucketID, err := f.getBucketID()
+	if err != nil {
+		return nil, err
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_list_unfinished_large_files",
+	}
+	var request = api.ListUnfinishedLargeFilesRequest{
+		BucketID:     bucketID,
+		NamePrefix:   f.root,
+		MaxFileCount: 100,
+	}
+	for {
+		var response api.ListFileNamesResponse
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(&opts, &request, &response)
+			return f.shouldRetry(resp, err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range response.Files {
+			file := &response.Files[i]
+			uploads = append(uploads, fs.PendingUpload{
+				Remote:  strings.TrimPrefix(file.Name, f.root),
+				ID:      file.ID,
+				Started: time.Time(file.UploadTimestamp),
+			})
+		}
+		if response.NextFileID == nil {
+			break
+		}
+		request.StartFileID = *response.NextFileID
+	}
+	return uploads, nil
+}
+
+// AbortUpload cancels a large file upload returned by ListUploads
+func (f *Fs) AbortUpload(upload fs.PendingUpload) error {
+	return f.cancelLargeFile(upload.ID)
+}
+
+// listVersions returns the versions of remote, newest first
+func (f *Fs) listVersions(remote string) (files []api.File, err error) {
+	err = f.list("", true, remote, 0, true, func(name string, object *api.File, isDirectory bool) error {
+		if isDirectory {
+			return nil
+		}
+		if name != remote {
+			// all the versions of remote come first
+			return errEndList
+		}
+		if object.Action != "start" {
+			files = append(files, *object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return files, nil
+}
+
+// versions lists the versions of the object at remote, newest first
+func (f *Fs) versions(remote string) (versions []fs.ObjectVersion, err error) {
+	files, err := f.listVersions(remote)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		file := &files[i]
+		o := &Object{fs: f, remote: remote}
+		err = o.decodeMetaData(file)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, fs.ObjectVersion{
+			ID:      file.ID,
+			Size:    file.Size,
+			ModTime: o.modTime,
+			Created: time.Time(file.UploadTimestamp),
+			Deleted: file.Action == "hide",
+		})
+	}
+	return versions, nil
+}
+
+// restoreVersion makes the version with the ID given the current
+// version of the object at remote by uploading a copy of it
+func (f *Fs) restoreVersion(remote string, id string) (newObj fs.Object, err error) {
+	err = f.checkWritable()
+	if err != nil {
+		return nil, err
+	}
+	files, err := f.listVersions(remote)
+	if err != nil {
+		return nil, err
+	}
+	var old *Object
+	for i := range files {
+		file := &files[i]
+		if file.ID != id {
+			continue
+		}
+		if file.Action == "hide" {
+			return nil, errors.Errorf("version %q is a deletion marker so can't be restored", id)
+		}
+		old = &Object{fs: f, remote: remote}
+		err = old.decodeMetaData(file)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if old == nil {
+		return nil, errors.Errorf("version %q of %q not found", id, remote)
+	}
+	in, err := old.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	o := &Object{fs: f, remote: remote}
+	err = o.Update(in, old)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.SHA1)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "versions",
+	Short: "List the old versions of a file",
+	Long: `This command lists the versions of a file kept by b2, newest first,
+with their IDs, sizes and modification times.  Versions which hide the
+file are marked as deleted.
+
+Usage Example:
+
+    rclone backend versions b2:bucket/path/to/dir file.txt
+
+The file is given relative to the remote.  The ID of each version can
+be passed to the restore-version command.  This command returns a list
+of version dictionaries, eg
+
+    [
+        {
+            "ID": "4_z...c001_t0046",
+            "Size": 9,
+            "ModTime": "2016-07-04T14:10:55Z",
+            "Created": "2016-07-04T14:10:55Z",
+            "Deleted": false
+        }
+    ]
+`,
+}, {
+	Name:  "restore-version",
+	Short: "Make an old version of a file the current one",
+	Long: `This command makes the version with the ID given the current version
+of a file.  Use the versions command to find the IDs.
+
+Usage Example:
+
+    rclone backend restore-version b2:bucket/path/to/dir file.txt ID
+
+The old version is uploaded again so it becomes the newest version -
+the versions in between are kept.
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "versions":
+		if len(arg) != 1 {
+			return nil, errors.New("need 1 argument: the file to list the versions of")
+		}
+		return f.versions(arg[0])
+	case "restore-version":
+		if len(arg) != 2 {
+			return nil, errors.New("need 2 arguments: the file and the ID of the version to restore")
+		}
+		remote, id := arg[0], arg[1]
+		if fs.Config.DryRun {
+			fs.Logf(remote, "Not restoring version %q as --dry-run", id)
+			return nil, nil
+		}
+		o, err := f.restoreVersion(remote, id)
+		if err != nil {
+			return nil, err
+		}
+		fs.Infof(o, "Restored version %q", id)
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -1052,6 +1364,7 @@ func (o *Object) decodeMetaDataRaw(ID, SHA1 string, Size int64, UploadTimestamp
 //  o.size
 //  o.sha1
 func (o *Object) decodeMetaData(info *api.File) (err error) {
+	o.decodeLock(info.FileRetention, info.LegalHold)
 	return o.decodeMetaDataRaw(info.ID, info.SHA1, info.Size, info.UploadTimestamp, info.Info, info.ContentType)
 }
 
@@ -1063,9 +1376,28 @@ func (o *Object) decodeMetaData(info *api.File) (err error) {
 //  o.size
 //  o.sha1
 func (o *Object) decodeMetaDataFileInfo(info *api.FileInfo) (err error) {
+	o.decodeLock(info.FileRetention, info.LegalHold)
 	return o.decodeMetaDataRaw(info.ID, info.SHA1, info.Size, info.UploadTimestamp, info.Info, info.ContentType)
 }
 
+// decodeLock sets the Object Lock state of the object from the
+// retention and legal hold returned by B2.  These are only returned
+// if the key is allowed to read them.
+//
+// Sets
+//  o.lockedUntil
+//  o.legalHold
+func (o *Object) decodeLock(retention *api.FileRetention, legalHold *api.LegalHold) {
+	o.lockedUntil = time.Time{}
+	o.legalHold = false
+	if retention != nil && retention.Value != nil && retention.Value.RetainUntilTimestamp != nil {
+		o.lockedUntil = time.Time(*retention.Value.RetainUntilTimestamp)
+	}
+	if legalHold != nil {
+		o.legalHold = legalHold.Value == "on"
+	}
+}
+
 // readMetaData gets the metadata if it hasn't already been fetched
 //
 // Sets
@@ -1084,11 +1416,28 @@ func (o *Object) readMetaData() (err error) {
 		timestamp, baseRemote = api.RemoveVersion(baseRemote)
 		maxSearched = maxVersions
 	}
+	if !o.fs.versionAt.IsZero() {
+		maxSearched = maxVersions
+	}
 	var info *api.File
-	err = o.fs.list("", true, baseRemote, maxSearched, o.fs.opt.Versions, func(remote string, object *api.File, isDirectory bool) error {
+	last := ""
+	err = o.fs.list("", true, baseRemote, maxSearched, o.fs.listHidden(), func(remote string, object *api.File, isDirectory bool) error {
 		if isDirectory {
 			return nil
 		}
+		if !o.fs.versionAt.IsZero() {
+			if remote != baseRemote {
+				return errEndList
+			}
+			if o.fs.visibleAt(remote, object, &last) {
+				info = object
+			}
+			if last == "" {
+				// all the versions so far are too new
+				return nil
+			}
+			return errEndList
+		}
 		if remote == baseRemote {
 			if !timestamp.IsZero() && !timestamp.Equal(object.UploadTimestamp) {
 				return nil
@@ -1299,8 +1648,9 @@ func urlEncode(in string) string {
 //
 // The new object may have been created if an error is returned
 func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
-	if o.fs.opt.Versions {
-		return errNotWithVersions
+	err = o.fs.checkWritable()
+	if err != nil {
+		return err
 	}
 	err = o.fs.Mkdir("")
 	if err != nil {
@@ -1344,7 +1694,9 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 	modTime := src.ModTime()
 
-	calculatedSha1, _ := src.Hash(hash.SHA1)
+	// Don't read the source just to find the SHA1 - if it isn't
+	// known it is calculated as the data is uploaded
+	calculatedSha1, _ := fs.QuickHash(src, hash.SHA1)
 	if calculatedSha1 == "" {
 		calculatedSha1 = "hex_digits_at_end"
 		har := newHashAppendingReader(in, sha1.New())
@@ -1428,6 +1780,13 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		},
 		ContentLength: &size,
 	}
+	if retention := o.fs.fileRetention(); retention != nil {
+		opts.ExtraHeaders[retentionModeHeader] = retention.Mode
+		opts.ExtraHeaders[retentionUntilHeader] = timeString(time.Time(*retention.RetainUntilTimestamp))
+	}
+	if o.fs.opt.FileLockLegalHold {
+		opts.ExtraHeaders[legalHoldHeader] = "on"
+	}
 	// for go1.8 (see release notes) we must nil the Body if we want a
 	// "Content-Length: 0" header which b2 requires for all files.
 	if size == 0 {
@@ -1453,8 +1812,9 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 // Remove an object
 func (o *Object) Remove() error {
-	if o.fs.opt.Versions {
-		return errNotWithVersions
+	err := o.fs.checkWritable()
+	if err != nil {
+		return err
 	}
 	if o.fs.opt.HardDelete {
 		return o.fs.deleteByID(o.id, o.fs.root+o.remote)
@@ -1462,6 +1822,18 @@ func (o *Object) Remove() error {
 	return o.fs.hide(o.fs.root + o.remote)
 }
 
+// LockedUntil returns the time the Object Lock retention of the
+// object runs out and whether it has a legal hold.
+//
+// Hiding a locked file is allowed so this is only returned if Remove
+// deletes files.
+func (o *Object) LockedUntil() (until time.Time, legalHold bool) {
+	if !o.fs.opt.HardDelete {
+		return time.Time{}, false
+	}
+	return o.lockedUntil, o.legalHold
+}
+
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType() string {
 	return o.mimeType
@@ -1474,12 +1846,15 @@ func (o *Object) ID() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.CleanUpper  = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
-	_ fs.IDer        = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Purger       = &Fs{}
+	_ fs.PutStreamer  = &Fs{}
+	_ fs.CleanUpper   = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.Commander    = &Fs{}
+	_ fs.UploadLister = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
+	_ fs.IDer         = &Object{}
+	_ fs.Locker       = &Object{}
 )
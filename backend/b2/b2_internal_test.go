@@ -4,7 +4,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ncw/rclone/backend/b2/api"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
 )
 
 // Test b2 string encoding
@@ -168,3 +171,39 @@ func TestParseTimeString(t *testing.T) {
 	}
 
 }
+
+func TestVisibleAt(t *testing.T) {
+	at := func(s string) api.Timestamp {
+		return api.Timestamp(fstest.Time(s))
+	}
+	f := &Fs{versionAt: fstest.Time("2019-06-01T00:00:00Z")}
+	// versions are listed newest first for each name
+	var last string
+	for _, test := range []struct {
+		remote string
+		file   api.File
+		want   bool
+	}{
+		{"a", api.File{Action: "upload", UploadTimestamp: at("2019-07-01T00:00:00Z")}, false},
+		{"a", api.File{Action: "upload", UploadTimestamp: at("2019-05-01T00:00:00Z")}, true},
+		{"a", api.File{Action: "upload", UploadTimestamp: at("2019-04-01T00:00:00Z")}, false},
+		{"b", api.File{Action: "hide", UploadTimestamp: at("2019-05-01T00:00:00Z")}, false},
+		{"b", api.File{Action: "upload", UploadTimestamp: at("2019-04-01T00:00:00Z")}, false},
+		{"c", api.File{Action: "start", UploadTimestamp: at("2019-05-01T00:00:00Z")}, false},
+		{"c", api.File{Action: "upload", UploadTimestamp: at("2019-04-01T00:00:00Z")}, true},
+		{"d", api.File{Action: "upload", UploadTimestamp: at("2019-06-02T00:00:00Z")}, false},
+	} {
+		file := test.file
+		assert.Equal(t, test.want, f.visibleAt(test.remote, &file, &last), "%s %+v", test.remote, test.file)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	f := &Fs{}
+	_, err := f.Command("potato", nil, nil)
+	assert.Equal(t, fs.ErrorCommandNotFound, err)
+	_, err = f.Command("versions", nil, nil)
+	assert.Error(t, err)
+	_, err = f.Command("restore-version", []string{"file.txt"}, nil)
+	assert.Error(t, err)
+}
@@ -119,6 +119,10 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 	if calculatedSha1, err := src.Hash(hash.SHA1); err == nil && calculatedSha1 != "" {
 		request.Info[sha1Key] = calculatedSha1
 	}
+	request.FileRetention = f.fileRetention()
+	if f.opt.FileLockLegalHold {
+		request.LegalHold = "on"
+	}
 	var response api.StartLargeFileResponse
 	err = f.pacer.Call(func() (bool, error) {
 		resp, err := f.srv.CallJSON(&opts, &request, &response)
@@ -283,17 +287,22 @@ func (up *largeUpload) finish() error {
 
 // cancel aborts the large upload
 func (up *largeUpload) cancel() error {
+	return up.f.cancelLargeFile(up.id)
+}
+
+// cancelLargeFile aborts the large upload with the ID given
+func (f *Fs) cancelLargeFile(id string) error {
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_cancel_large_file",
 	}
 	var request = api.CancelLargeFileRequest{
-		ID: up.id,
+		ID: id,
 	}
 	var response api.CancelLargeFileResponse
-	err := up.f.pacer.Call(func() (bool, error) {
-		resp, err := up.f.srv.CallJSON(&opts, &request, &response)
-		return up.f.shouldRetry(resp, err)
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, &response)
+		return f.shouldRetry(resp, err)
 	})
 	return err
 }
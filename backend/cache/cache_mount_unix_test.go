@@ -29,7 +29,7 @@ func (r *run) mountFs(t *testing.T, f fs.Fs) {
 	require.NoError(t, err)
 	c, err := fuse.Mount(r.mntDir, options...)
 	require.NoError(t, err)
-	filesys := mount.NewFS(f)
+	filesys := mount.NewFS(f, "")
 	server := fusefs.New(c, nil)
 
 	// Serve the mount point in the background returning error to errChan
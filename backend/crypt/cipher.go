@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/aes"
 	gocipher "crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base32"
 	"fmt"
 	"io"
@@ -93,6 +95,8 @@ type Cipher interface {
 	DecryptedSize(int64) (int64, error)
 	// NameEncryptionMode returns the used mode for name handling
 	NameEncryptionMode() NameEncryptionMode
+	// DeriveKey returns a 32 byte key derived from the data key for purpose
+	DeriveKey(purpose string) []byte
 }
 
 // NameEncryptionMode is the type of file name encryption in use
@@ -187,6 +191,12 @@ func (c *cipher) Key(password, salt string) (err error) {
 			return err
 		}
 	}
+	return c.setKey(key)
+}
+
+// setKey sets all the internal keys from the key material passed in
+// which should be as long as all of them together.
+func (c *cipher) setKey(key []byte) (err error) {
 	copy(c.dataKey[:], key)
 	copy(c.nameKey[:], key[len(c.dataKey):])
 	copy(c.nameTweak[:], key[len(c.dataKey)+len(c.nameKey):])
@@ -1059,6 +1069,15 @@ func (c *cipher) EncryptedSize(size int64) int64 {
 	return encryptedSize
 }
 
+// DeriveKey returns a 32 byte key derived from the data key for
+// purpose, so other parts of rclone can encrypt things with the crypt
+// password without using the data key itself.
+func (c *cipher) DeriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, c.dataKey[:])
+	_, _ = mac.Write([]byte("rclone derived key: " + purpose))
+	return mac.Sum(nil)
+}
+
 // DecryptedSize calculates the size of the data when decrypted
 func (c *cipher) DecryptedSize(size int64) (int64, error) {
 	size -= int64(fileHeaderSize)
@@ -373,6 +373,18 @@ func TestDecryptedSize(t *testing.T) {
 	}
 }
 
+func TestDeriveKey(t *testing.T) {
+	c, _ := newCipher(NameEncryptionStandard, "", "", true)
+	key := c.DeriveKey("test")
+	assert.Len(t, key, 32)
+	assert.Equal(t, key, c.DeriveKey("test"))
+	assert.NotEqual(t, key, c.DeriveKey("other"))
+	assert.NotEqual(t, key, c.dataKey[:])
+
+	c2, _ := newCipher(NameEncryptionStandard, "potato", "", true)
+	assert.NotEqual(t, key, c2.DeriveKey("test"))
+}
+
 func TestNoncePointer(t *testing.T) {
 	var x nonce
 	assert.Equal(t, (*[24]byte)(&x), x.pointer())
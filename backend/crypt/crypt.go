@@ -66,6 +66,15 @@ func init() {
 			Name:       "password2",
 			Help:       "Password or pass phrase for salt. Optional but recommended.\nShould be different to the previous password.",
 			IsPassword: true,
+		}, {
+			Name:     "kms_key",
+			Help:     "KMS key to wrap the encryption key with instead of using the passwords.\nEither an AWS KMS key ARN, eg \"arn:aws:kms:us-east-1:123456789012:key/...\"\nor a Google Cloud KMS key name, eg \"projects/P/locations/L/keyRings/R/cryptoKeys/K\".",
+			Advanced: true,
+		}, {
+			Name:     "kms_wrapped_key",
+			Help:     "Encryption key wrapped with the kms_key.\nThis is made and saved in the config the first time the remote is used.\nThe data can't be decrypted without it.",
+			Hide:     fs.OptionHideConfigurator,
+			Advanced: true,
 		}, {
 			Name:     "show_mapping",
 			Help:     "For all files listed show how the names encrypt.",
@@ -77,11 +86,20 @@ func init() {
 }
 
 // newCipherForConfig constructs a Cipher for the given config name
-func newCipherForConfig(opt *Options) (Cipher, error) {
+//
+// If a KMS key is in use then m is used to save a newly made key.
+func newCipherForConfig(opt *Options, m configmap.Setter) (Cipher, error) {
 	mode, err := NewNameEncryptionMode(opt.FilenameEncryption)
 	if err != nil {
 		return nil, err
 	}
+	if opt.KMSKey != "" {
+		w, err := newKeyWrapper(opt.KMSKey)
+		if err != nil {
+			return nil, err
+		}
+		return newCipherForKMS(mode, w, opt, m)
+	}
 	if opt.Password == "" {
 		return nil, errors.New("password not set in config file")
 	}
@@ -103,6 +121,24 @@ func newCipherForConfig(opt *Options) (Cipher, error) {
 	return cipher, nil
 }
 
+// newCipherForKMS constructs a Cipher using the key material wrapped
+// with the KMS key w
+func newCipherForKMS(mode NameEncryptionMode, w keyWrapper, opt *Options, m configmap.Setter) (Cipher, error) {
+	key, err := kmsKeyMaterial(w, opt, m)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := newCipher(mode, "", "", opt.DirectoryNameEncryption)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make cipher")
+	}
+	err = cipher.setKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make cipher")
+	}
+	return cipher, nil
+}
+
 // NewCipher constructs a Cipher for the given config
 func NewCipher(m configmap.Mapper) (Cipher, error) {
 	// Parse config into Options struct
@@ -111,7 +147,7 @@ func NewCipher(m configmap.Mapper) (Cipher, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newCipherForConfig(opt)
+	return newCipherForConfig(opt, m)
 }
 
 // NewFs contstructs an Fs from the path, container:path
@@ -122,7 +158,7 @@ func NewFs(name, rpath string, m configmap.Mapper) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	cipher, err := newCipherForConfig(opt)
+	cipher, err := newCipherForConfig(opt, m)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +203,13 @@ func NewFs(name, rpath string, m configmap.Mapper) (fs.Fs, error) {
 	if doChangeNotify != nil {
 		f.features.ChangeNotify = func(notifyFunc func(string, fs.EntryType), pollInterval <-chan time.Duration) {
 			wrappedNotifyFunc := func(path string, entryType fs.EntryType) {
-				decrypted, err := f.DecryptFileName(path)
+				var decrypted string
+				var err error
+				if entryType == fs.EntryDirectory {
+					decrypted, err = f.cipher.DecryptDirName(path)
+				} else {
+					decrypted, err = f.DecryptFileName(path)
+				}
 				if err != nil {
 					fs.Logf(f, "ChangeNotify was unable to decrypt %q: %s", path, err)
 					return
@@ -188,6 +230,8 @@ type Options struct {
 	DirectoryNameEncryption bool   `config:"directory_name_encryption"`
 	Password                string `config:"password"`
 	Password2               string `config:"password2"`
+	KMSKey                  string `config:"kms_key"`
+	KMSWrappedKey           string `config:"kms_wrapped_key"`
 	ShowMapping             bool   `config:"show_mapping"`
 }
 
@@ -546,6 +590,12 @@ func (f *Fs) DecryptFileName(encryptedFileName string) (string, error) {
 	return f.cipher.DecryptFileName(encryptedFileName)
 }
 
+// DeriveKey returns a 32 byte key derived from the crypt password for
+// purpose.  This is used by the VFS to encrypt its cache.
+func (f *Fs) DeriveKey(purpose string) []byte {
+	return f.cipher.DeriveKey(purpose)
+}
+
 // ComputeHash takes the nonce from o, and encrypts the contents of
 // src with it, and calcuates the hash given by HashType on the fly
 //
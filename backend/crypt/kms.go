@@ -0,0 +1,277 @@
+// Envelope encryption of the crypt keys with a cloud KMS key
+
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/lib/rest"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	kmsKeySize     = 32 + 32 + nameCipherBlockSize // size of the dataKey, nameKey and nameTweak
+	gcpKMSRootURL  = "https://cloudkms.googleapis.com/v1/"
+	gcpKMSScope    = "https://www.googleapis.com/auth/cloudkms"
+	awsKMSTarget   = "TrentService."
+	awsContentType = "application/x-amz-json-1.1"
+)
+
+// matches an AWS KMS key ARN capturing the region
+var awsKMSARN = regexp.MustCompile(`^arn:aws[a-z-]*:kms:([a-z0-9-]+):`)
+
+// keyWrapper wraps and unwraps the key material with a KMS key
+type keyWrapper interface {
+	// wrap encrypts the plaintext with the KMS key
+	wrap(plaintext []byte) (ciphertext []byte, err error)
+	// unwrap decrypts the ciphertext made by wrap
+	unwrap(ciphertext []byte) (plaintext []byte, err error)
+}
+
+// newKeyWrapper returns a keyWrapper for the KMS key kmsKey which
+// should be an AWS KMS key ARN or a Google Cloud KMS key name
+func newKeyWrapper(kmsKey string) (keyWrapper, error) {
+	if match := awsKMSARN.FindStringSubmatch(kmsKey); match != nil {
+		return newAWSKMS(kmsKey, match[1])
+	}
+	if strings.HasPrefix(kmsKey, "projects/") {
+		return newGCPKMS(kmsKey)
+	}
+	return nil, errors.Errorf("kms_key %q should be an AWS KMS key ARN or a Google Cloud KMS key name", kmsKey)
+}
+
+// kmsKeyMaterial returns the key material for the cipher, unwrapping
+// it with w.
+//
+// If there isn't a wrapped key in the config yet then it makes a
+// random one, wraps it with w and saves it in the config with m.
+func kmsKeyMaterial(w keyWrapper, opt *Options, m configmap.Setter) ([]byte, error) {
+	if opt.KMSWrappedKey == "" {
+		key := make([]byte, kmsKeySize)
+		_, err := io.ReadFull(rand.Reader, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make key")
+		}
+		wrapped, err := w.wrap(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to wrap key with KMS key")
+		}
+		opt.KMSWrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+		m.Set("kms_wrapped_key", opt.KMSWrappedKey)
+		fs.Logf(nil, "Made a new crypt key wrapped with KMS key %q - keep a copy of kms_wrapped_key from the config as the data can't be decrypted without it", opt.KMSKey)
+		return key, nil
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(opt.KMSWrappedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode kms_wrapped_key")
+	}
+	key, err := w.unwrap(wrapped)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unwrap kms_wrapped_key with KMS key")
+	}
+	if len(key) != kmsKeySize {
+		return nil, errors.Errorf("unwrapped key is %d bytes long, expecting %d", len(key), kmsKeySize)
+	}
+	return key, nil
+}
+
+// awsKMS wraps keys with the AWS Key Management Service
+type awsKMS struct {
+	keyID string       // ARN of the key
+	srv   *rest.Client // the connection to the KMS endpoint
+}
+
+// newAWSKMS makes an awsKMS for keyID in region using the
+// credentials from the environment, the shared config or the EC2
+// instance role in the same way as the aws command line tools.
+func newAWSKMS(keyID, region string) (*awsKMS, error) {
+	ses, err := session.NewSessionWithOptions(session.Options{
+		Config:            *aws.NewConfig().WithRegion(region),
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make AWS session")
+	}
+	cfg := ses.ClientConfig("kms")
+	return newAWSKMSClient(keyID, cfg.Endpoint, cfg.SigningRegion, v4.NewSigner(cfg.Config.Credentials), fshttp.NewClient(fs.Config)), nil
+}
+
+// newAWSKMSClient makes an awsKMS talking to endpoint
+func newAWSKMSClient(keyID, endpoint, region string, signer *v4.Signer, client *http.Client) *awsKMS {
+	k := &awsKMS{
+		keyID: keyID,
+		srv:   rest.NewClient(client).SetRoot(endpoint),
+	}
+	k.srv.SetErrorHandler(awsKMSError)
+	k.srv.SetSigner(func(req *http.Request) error {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		buf, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		_, err = signer.Sign(req, bytes.NewReader(buf), "kms", region, time.Now())
+		return err
+	})
+	return k
+}
+
+// awsKMSError parses the error from an AWS KMS call
+func awsKMSError(resp *http.Response) error {
+	var e struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	if err := rest.DecodeJSON(resp, &e); err != nil || e.Type == "" {
+		return errors.Errorf("KMS error %s", resp.Status)
+	}
+	return errors.Errorf("KMS error %s: %s: %s", resp.Status, e.Type, e.Message)
+}
+
+// call calls the AWS KMS action with request decoding the reply into
+// response
+func (k *awsKMS) call(action string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        "/",
+		Body:        bytes.NewReader(body),
+		ContentType: awsContentType,
+		ExtraHeaders: map[string]string{
+			"X-Amz-Target": awsKMSTarget + action,
+		},
+	}
+	resp, err := k.srv.Call(&opts)
+	if err != nil {
+		return err
+	}
+	return rest.DecodeJSON(resp, response)
+}
+
+// wrap encrypts the plaintext with the KMS key
+func (k *awsKMS) wrap(plaintext []byte) (ciphertext []byte, err error) {
+	var response struct {
+		CiphertextBlob []byte
+	}
+	err = k.call("Encrypt", struct {
+		KeyID     string `json:"KeyId"`
+		Plaintext []byte
+	}{k.keyID, plaintext}, &response)
+	return response.CiphertextBlob, err
+}
+
+// unwrap decrypts the ciphertext made by wrap
+func (k *awsKMS) unwrap(ciphertext []byte) (plaintext []byte, err error) {
+	var response struct {
+		Plaintext []byte
+	}
+	err = k.call("Decrypt", struct {
+		KeyID          string `json:"KeyId"`
+		CiphertextBlob []byte
+	}{k.keyID, ciphertext}, &response)
+	return response.Plaintext, err
+}
+
+// gcpKMS wraps keys with Google Cloud Key Management Service
+type gcpKMS struct {
+	name string       // resource name of the key
+	srv  *rest.Client // the connection to the KMS API
+}
+
+// newGCPKMS makes a gcpKMS for the key called name using the
+// application default credentials.
+func newGCPKMS(name string) (*gcpKMS, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, fshttp.NewClient(fs.Config))
+	client, err := google.DefaultClient(ctx, gcpKMSScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find Google Cloud credentials")
+	}
+	return newGCPKMSClient(name, gcpKMSRootURL, client), nil
+}
+
+// newGCPKMSClient makes a gcpKMS talking to rootURL
+func newGCPKMSClient(name, rootURL string, client *http.Client) *gcpKMS {
+	k := &gcpKMS{
+		name: name,
+		srv:  rest.NewClient(client).SetRoot(rootURL),
+	}
+	k.srv.SetErrorHandler(gcpKMSError)
+	return k
+}
+
+// gcpKMSError parses the error from a Google Cloud KMS call
+func gcpKMSError(resp *http.Response) error {
+	var e struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := rest.DecodeJSON(resp, &e); err != nil || e.Error.Message == "" {
+		return errors.Errorf("KMS error %s", resp.Status)
+	}
+	return errors.Errorf("KMS error %s: %s: %s", resp.Status, e.Error.Status, e.Error.Message)
+}
+
+// call calls the method on the key with request decoding the reply
+// into response
+func (k *gcpKMS) call(method string, request, response interface{}) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   fmt.Sprintf("%s:%s", k.name, method),
+	}
+	_, err := k.srv.CallJSON(&opts, request, response)
+	return err
+}
+
+// wrap encrypts the plaintext with the KMS key
+func (k *gcpKMS) wrap(plaintext []byte) (ciphertext []byte, err error) {
+	var response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	err = k.call("encrypt", struct {
+		Plaintext []byte `json:"plaintext"`
+	}{plaintext}, &response)
+	return response.Ciphertext, err
+}
+
+// unwrap decrypts the ciphertext made by wrap
+func (k *gcpKMS) unwrap(ciphertext []byte) (plaintext []byte, err error) {
+	var response struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	err = k.call("decrypt", struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}{ciphertext}, &response)
+	return response.Plaintext, err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ keyWrapper = (*awsKMS)(nil)
+	_ keyWrapper = (*gcpKMS)(nil)
+)
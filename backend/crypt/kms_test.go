@@ -0,0 +1,159 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xorWrapper is a keyWrapper which xors the key with 0xAA
+type xorWrapper struct {
+	calls int
+}
+
+func (w *xorWrapper) xor(in []byte) ([]byte, error) {
+	w.calls++
+	out := make([]byte, len(in))
+	for i := range in {
+		out[i] = in[i] ^ 0xAA
+	}
+	return out, nil
+}
+
+func (w *xorWrapper) wrap(plaintext []byte) ([]byte, error)    { return w.xor(plaintext) }
+func (w *xorWrapper) unwrap(ciphertext []byte) ([]byte, error) { return w.xor(ciphertext) }
+
+func TestNewKeyWrapper(t *testing.T) {
+	w, err := newKeyWrapper("arn:aws:kms:eu-west-2:123456789012:key/potato")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:kms:eu-west-2:123456789012:key/potato", w.(*awsKMS).keyID)
+
+	w, err = newKeyWrapper("projects/p/locations/global/keyRings/r/cryptoKeys/k")
+	if err == nil {
+		assert.Equal(t, "projects/p/locations/global/keyRings/r/cryptoKeys/k", w.(*gcpKMS).name)
+	}
+
+	w, err = newKeyWrapper("potato")
+	assert.Error(t, err)
+	assert.Nil(t, w)
+}
+
+func TestKMSKeyMaterial(t *testing.T) {
+	w := &xorWrapper{}
+	m := configmap.Simple{}
+	opt := &Options{KMSKey: "potato", DirectoryNameEncryption: true}
+
+	// no wrapped key so one is made and saved
+	c1, err := newCipherForKMS(NameEncryptionStandard, w, opt, m)
+	require.NoError(t, err)
+	assert.Equal(t, 1, w.calls)
+	assert.NotEqual(t, "", m["kms_wrapped_key"])
+	assert.Equal(t, m["kms_wrapped_key"], opt.KMSWrappedKey)
+
+	// reading it back gives the same key
+	opt2 := &Options{KMSKey: "potato", KMSWrappedKey: m["kms_wrapped_key"], DirectoryNameEncryption: true}
+	c2, err := newCipherForKMS(NameEncryptionStandard, w, opt2, configmap.Simple{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, w.calls)
+	assert.Equal(t, c1.EncryptFileName("potato"), c2.EncryptFileName("potato"))
+
+	// and it isn't the key from an empty password
+	c3, err := newCipher(NameEncryptionStandard, "", "", true)
+	require.NoError(t, err)
+	assert.NotEqual(t, c3.EncryptFileName("potato"), c1.EncryptFileName("potato"))
+
+	// bad wrapped keys
+	opt2.KMSWrappedKey = "!!!"
+	_, err = kmsKeyMaterial(w, opt2, m)
+	assert.Error(t, err)
+	opt2.KMSWrappedKey = "AAAA"
+	_, err = kmsKeyMaterial(w, opt2, m)
+	assert.Error(t, err)
+}
+
+func TestAWSKMS(t *testing.T) {
+	const keyID = "arn:aws:kms:us-east-1:123456789012:key/potato"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, awsContentType, r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"), r.Header.Get("Authorization"))
+		var in struct {
+			KeyID          string `json:"KeyId"`
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Equal(t, keyID, in.KeyID)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": append([]byte("wrapped:"), in.Plaintext...)})
+		case "TrentService.Decrypt":
+			blob := in.CiphertextBlob
+			if !bytes.HasPrefix(blob, []byte("wrapped:")) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException","message":"bad"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": blob[len("wrapped:"):]})
+		default:
+			t.Errorf("unknown target %q", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	defer ts.Close()
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	k := newAWSKMSClient(keyID, ts.URL, "us-east-1", signer, http.DefaultClient)
+	wrapped, err := k.wrap([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, "wrapped:key", string(wrapped))
+	key, err := k.unwrap(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(key))
+	_, err = k.unwrap([]byte("potato"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InvalidCiphertextException: bad")
+}
+
+func TestGCPKMS(t *testing.T) {
+	const name = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		var in map[string][]byte
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		switch r.URL.Path {
+		case "/" + name + ":encrypt":
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": append([]byte("wrapped:"), in["plaintext"]...)})
+		case "/" + name + ":decrypt":
+			blob := in["ciphertext"]
+			if !bytes.HasPrefix(blob, []byte("wrapped:")) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"code":400,"message":"Decryption failed","status":"INVALID_ARGUMENT"}}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"plaintext": blob[len("wrapped:"):]})
+		default:
+			t.Errorf("unknown path %q", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	k := newGCPKMSClient(name, ts.URL+"/", http.DefaultClient)
+	wrapped, err := k.wrap([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, "wrapped:key", string(wrapped))
+	key, err := k.unwrap(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(key))
+	_, err = k.unwrap([]byte("potato"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "INVALID_ARGUMENT: Decryption failed")
+}
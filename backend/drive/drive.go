@@ -32,6 +32,7 @@ import (
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/dircache"
 	"github.com/ncw/rclone/lib/oauthutil"
@@ -291,6 +292,7 @@ func init() {
 			Help:     "If Object's are greater, use drive v2 API to download.",
 			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 
 	// register duplicate MIME types first
@@ -337,6 +339,7 @@ type Options struct {
 // Fs represents a remote drive server
 type Fs struct {
 	name             string             // name of this remote
+	m                configmap.Mapper   // config of this remote
 	root             string             // the path we are working on
 	opt              Options            // parsed options
 	features         *fs.Features       // optional features
@@ -771,9 +774,10 @@ func NewFs(name, path string, m configmap.Mapper) (fs.Fs, error) {
 
 	f := &Fs{
 		name:  name,
+		m:     m,
 		root:  root,
 		opt:   *opt,
-		pacer: newPacer(),
+		pacer: newPacer().SetName(name),
 	}
 	f.isTeamDrive = opt.TeamDriveID != ""
 	f.features = (&fs.Features{
@@ -781,6 +785,7 @@ func NewFs(name, path string, m configmap.Mapper) (fs.Fs, error) {
 		ReadMimeType:            true,
 		WriteMimeType:           true,
 		CanHaveEmptyDirectories: true,
+		ServerSideAcrossConfigs: true,
 	}).Fill(f)
 
 	// Create a new authorized Drive client.
@@ -1390,6 +1395,132 @@ func (f *Fs) ListR(dir string, callback fs.ListRCallback) (err error) {
 	return list.Flush()
 }
 
+// searchDirPath finds the path relative to the root of the directory
+// with the ID passed in, walking up its parents and caching them in
+// the dirCache.
+//
+// It returns false if the directory isn't below the root.  Lookups
+// which failed are remembered in notFound.
+func (f *Fs) searchDirPath(dirID string, notFound map[string]struct{}) (dirPath string, ok bool, err error) {
+	if dirPath, ok = f.dirCache.GetInv(dirID); ok {
+		return dirPath, true, nil
+	}
+	if _, found := notFound[dirID]; found {
+		return "", false, nil
+	}
+	var info *drive.File
+	err = f.pacer.Call(func() (bool, error) {
+		info, err = f.svc.Files.Get(dirID).
+			Fields("name,parents").
+			SupportsTeamDrives(f.isTeamDrive).
+			Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", false, errors.Wrap(err, "couldn't read parent directory")
+	}
+	if len(info.Parents) > 0 {
+		var parentPath string
+		parentPath, ok, err = f.searchDirPath(info.Parents[0], notFound)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			dirPath = path.Join(parentPath, strings.Replace(info.Name, "/", "／", -1))
+			f.dirCache.Put(dirPath, dirID)
+			return dirPath, true, nil
+		}
+	}
+	notFound[dirID] = struct{}{}
+	return "", false, nil
+}
+
+// Search finds objects and directories under dir whose names contain
+// query using the drive search API.
+//
+// Search params: https://developers.google.com/drive/search-parameters
+func (f *Fs) Search(dir, query string, callback fs.ListRCallback) (err error) {
+	err = f.dirCache.FindRoot(false)
+	if err != nil {
+		return err
+	}
+	_, err = f.dirCache.FindDir(dir, false)
+	if err != nil {
+		return err
+	}
+	// Escaping the backslash isn't documented but seems to work
+	searchQuery := strings.Replace(query, `\`, `\\`, -1)
+	searchQuery = strings.Replace(searchQuery, `'`, `\'`, -1)
+	// Convert ／ to / for search
+	searchQuery = strings.Replace(searchQuery, "／", "/", -1)
+	list := f.svc.Files.List()
+	list.Q(fmt.Sprintf("name contains '%s' and trashed=%s", searchQuery, strconv.FormatBool(f.opt.TrashedOnly)))
+	if f.opt.ListChunk > 0 {
+		list.PageSize(f.opt.ListChunk)
+	}
+	if f.isTeamDrive {
+		list.TeamDriveId(f.opt.TeamDriveID)
+		list.SupportsTeamDrives(true)
+		list.IncludeTeamDriveItems(true)
+		list.Corpora("teamDrive")
+	}
+	// If using appDataFolder then need to add Spaces
+	if f.rootFolderID == "appDataFolder" {
+		list.Spaces("appDataFolder")
+	}
+	var fields = partialFields
+	if f.opt.AuthOwnerOnly {
+		fields += ",owners"
+	}
+	fields = fmt.Sprintf("files(%s),nextPageToken", fields)
+
+	notFound := make(map[string]struct{})
+	for {
+		var files *drive.FileList
+		err = f.pacer.Call(func() (bool, error) {
+			files, err = list.Fields(googleapi.Field(fields)).Do()
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "couldn't search")
+		}
+		var entries fs.DirEntries
+		for _, item := range files.Files {
+			if len(item.Parents) == 0 {
+				continue
+			}
+			parentPath, ok, err := f.searchDirPath(item.Parents[0], notFound)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			// Convert / to ／ for listing purposes
+			remote := path.Join(parentPath, strings.Replace(item.Name, "/", "／", -1))
+			if dir != "" && !strings.HasPrefix(remote, dir+"/") {
+				continue
+			}
+			entry, err := f.itemToDirEntry(remote, item)
+			if err != nil {
+				return err
+			}
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+		err = callback(entries)
+		if err != nil {
+			return err
+		}
+		if files.NextPageToken == "" {
+			break
+		}
+		list.PageToken(files.NextPageToken)
+	}
+	return nil
+}
+
 // itemToDirEntry converts a drive.File to a fs.DirEntry.
 // When the drive.File cannot be represented as a fs.DirEntry
 // (nil, nil) is returned.
@@ -1770,6 +1901,63 @@ func (f *Fs) About() (*fs.Usage, error) {
 	return usage, nil
 }
 
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo() (map[string]string, error) {
+	var about *drive.About
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		about, err = f.svc.About.Get().Fields("user").Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Drive user")
+	}
+	user := about.User
+	return map[string]string{
+		"DisplayName":  user.DisplayName,
+		"EmailAddress": user.EmailAddress,
+		"PermissionId": user.PermissionId,
+	}, nil
+}
+
+// revokeURL is where tokens are revoked
+const revokeURL = "https://oauth2.googleapis.com/revoke"
+
+// Disconnect revokes the token and removes it from the config
+func (f *Fs) Disconnect() error {
+	if f.opt.ServiceAccountFile != "" || f.opt.ServiceAccountCredentials != "" {
+		return errors.New("can't disconnect a remote using a service account")
+	}
+	token, err := oauthutil.GetToken(f.name, f.m)
+	if err != nil {
+		return err
+	}
+	// revoking the refresh token revokes the access token too
+	revoke := token.RefreshToken
+	if revoke == "" {
+		revoke = token.AccessToken
+	}
+	client := fshttp.NewClient(fs.Config)
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = client.PostForm(revokeURL, url.Values{"token": {revoke}})
+		if err == nil && resp.StatusCode >= 500 {
+			_ = resp.Body.Close()
+			return true, errors.Errorf("revoke failed: %s", resp.Status)
+		}
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke token")
+	}
+	_ = resp.Body.Close()
+	// A 400 means the token is already invalid
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return errors.Errorf("failed to revoke token: %s", resp.Status)
+	}
+	return config.SetValueAndSave(f.name, config.ConfigToken, "")
+}
+
 // Move src to this remote using server side move operations.
 //
 // This is stored with the remote path given
@@ -2117,11 +2305,175 @@ func (f *Fs) DirCacheFlush() {
 	f.dirCache.ResetRoot()
 }
 
+// getRegularObject finds the regular file at remote - revisions
+// aren't available for google documents
+func (f *Fs) getRegularObject(remote string) (*Object, error) {
+	obj, err := f.NewObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := obj.(*Object)
+	if !ok {
+		return nil, errors.Errorf("can't read revisions of google document %q", remote)
+	}
+	return o, nil
+}
+
+// versions lists the revisions of the object at remote, newest first
+func (f *Fs) versions(remote string) (versions []fs.ObjectVersion, err error) {
+	o, err := f.getRegularObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []*drive.Revision
+	pageToken := ""
+	for {
+		var list *drive.RevisionList
+		err = f.pacer.Call(func() (bool, error) {
+			list, err = f.svc.Revisions.List(o.id).
+				Fields("nextPageToken,revisions(id,modifiedTime,size)").
+				PageToken(pageToken).
+				Do()
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list revisions")
+		}
+		revisions = append(revisions, list.Revisions...)
+		if list.NextPageToken == "" {
+			break
+		}
+		pageToken = list.NextPageToken
+	}
+	// drive returns the oldest revision first
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		modTime, _ := time.Parse(timeFormatIn, revision.ModifiedTime)
+		versions = append(versions, fs.ObjectVersion{
+			ID:      revision.Id,
+			Size:    revision.Size,
+			ModTime: modTime,
+			Created: modTime,
+		})
+	}
+	return versions, nil
+}
+
+// restoreVersion makes the revision with the ID given the current
+// revision of the object at remote by uploading a copy of it
+func (f *Fs) restoreVersion(remote string, id string) (newObj fs.Object, err error) {
+	o, err := f.getRegularObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	var revision *drive.Revision
+	err = f.pacer.Call(func() (bool, error) {
+		revision, err = f.svc.Revisions.Get(o.id, id).
+			Fields("id,modifiedTime,size").
+			Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read revision %q", id)
+	}
+	var res *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		res, err = f.svc.Revisions.Get(o.id, id).Download()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download revision %q", id)
+	}
+	defer fs.CheckClose(res.Body, &err)
+	modTime, _ := time.Parse(timeFormatIn, revision.ModifiedTime)
+	src := object.NewStaticObjectInfo(remote, modTime, revision.Size, true, nil, f)
+	err = o.Update(res.Body, src)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "versions",
+	Short: "List the old versions of a file",
+	Long: `This command lists the revisions of a file kept by drive, newest
+first, with their IDs, sizes and modification times.
+
+Usage Example:
+
+    rclone backend versions drive:path/to/dir file.txt
+
+The file is given relative to the remote.  The ID of each version can
+be passed to the restore-version command.  This command returns a list
+of version dictionaries, eg
+
+    [
+        {
+            "ID": "0B...Uk5xQT0",
+            "Size": 9,
+            "ModTime": "2016-07-04T14:10:55Z",
+            "Created": "2016-07-04T14:10:55Z",
+            "Deleted": false
+        }
+    ]
+`,
+}, {
+	Name:  "restore-version",
+	Short: "Make an old version of a file the current one",
+	Long: `This command makes the version with the ID given the current version
+of a file.  Use the versions command to find the IDs.
+
+Usage Example:
+
+    rclone backend restore-version drive:path/to/dir file.txt ID
+
+The old revision is uploaded again so it becomes the newest revision -
+the revisions in between are kept.  Google docs aren't supported.
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "versions":
+		if len(arg) != 1 {
+			return nil, errors.New("need 1 argument: the file to list the versions of")
+		}
+		return f.versions(arg[0])
+	case "restore-version":
+		if len(arg) != 2 {
+			return nil, errors.New("need 2 arguments: the file and the ID of the version to restore")
+		}
+		remote, id := arg[0], arg[1]
+		if fs.Config.DryRun {
+			fs.Logf(remote, "Not restoring version %q as --dry-run", id)
+			return nil, nil
+		}
+		o, err := f.restoreVersion(remote, id)
+		if err != nil {
+			return nil, err
+		}
+		fs.Infof(o, "Restored version %q", id)
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -2591,6 +2943,10 @@ var (
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Searcher        = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
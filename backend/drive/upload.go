@@ -14,6 +14,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
 	"google.golang.org/api/drive/v3"
@@ -45,12 +47,55 @@ type resumableUpload struct {
 	MediaType string
 	// ContentLength is the full size of the object being uploaded.
 	ContentLength int64
+	// Start is where to start uploading from when resuming
+	Start int64
 	// Return value
 	ret *drive.File
 }
 
+// resumeState is saved so uploads can be resumed with --resume-uploads
+type resumeState struct {
+	URI string // the upload session
+}
+
+// resume carries on the upload saved in resume if possible,
+// returning nil if it couldn't be resumed.
+func (f *Fs) resume(resume *operations.ResumeUpload, in io.Reader, size int64, contentType, remote string) *resumableUpload {
+	var state resumeState
+	if !resume.Load(&state) {
+		return nil
+	}
+	rx := &resumableUpload{
+		f:             f,
+		remote:        remote,
+		URI:           state.URI,
+		Media:         in,
+		MediaType:     contentType,
+		ContentLength: size,
+	}
+	start, err := rx.transferStatus()
+	if err != nil || start >= size {
+		fs.Debugf(remote, "Can't resume upload: status %d/%d: %v", start, size, err)
+		resume.Remove()
+		return nil
+	}
+	// Skip the part of the source which has been uploaded already
+	_, err = io.CopyN(ioutil.Discard, in, start)
+	if err != nil {
+		fs.Debugf(remote, "Can't resume upload: failed to skip source: %v", err)
+		return nil
+	}
+	fs.Infof(remote, "Resuming upload from %d/%d bytes", start, size)
+	rx.Start = start
+	return rx
+}
+
 // Upload the io.Reader in of size bytes with contentType and info
 func (f *Fs) Upload(in io.Reader, size int64, contentType, fileID, remote string, info *drive.File) (*drive.File, error) {
+	resume := operations.NewResumeUpload(f, remote, fmt.Sprintf("%d %s %s", size, info.ModifiedTime, fileID))
+	if rx := f.resume(resume, in, size, contentType, remote); rx != nil {
+		return rx.finish(resume)
+	}
 	params := url.Values{
 		"alt":        {"json"},
 		"uploadType": {"resumable"},
@@ -108,7 +153,21 @@ func (f *Fs) Upload(in io.Reader, size int64, contentType, fileID, remote string
 		MediaType:     contentType,
 		ContentLength: size,
 	}
-	return rx.Upload()
+	resume.Save(&resumeState{URI: loc})
+	return rx.finish(resume)
+}
+
+// finish does the upload, removing the saved state if it worked or
+// can't be resumed
+func (rx *resumableUpload) finish(resume *operations.ResumeUpload) (*drive.File, error) {
+	ret, err := rx.Upload()
+	if err == nil {
+		resume.Remove()
+	} else if apiErr, ok := errors.Cause(err).(*googleapi.Error); ok && apiErr.Code >= 400 && apiErr.Code < 500 {
+		// the session has expired or been rejected
+		resume.Remove()
+	}
+	return ret, err
 }
 
 // Make an http.Request for the range passed in
@@ -126,7 +185,7 @@ func (rx *resumableUpload) makeRequest(start int64, body io.ReadSeeker, reqSize
 
 // rangeRE matches the transfer status response from the server. $1 is
 // the last byte index uploaded.
-var rangeRE = regexp.MustCompile(`^0\-(\d+)$`)
+var rangeRE = regexp.MustCompile(`^(?:bytes=)?0\-(\d+)$`)
 
 // Query drive for the amount transferred so far
 //
@@ -149,10 +208,15 @@ func (rx *resumableUpload) transferStatus() (start int64, err error) {
 		return 0, errors.Errorf("unexpected http return code %v", res.StatusCode)
 	}
 	Range := res.Header.Get("Range")
+	if Range == "" {
+		// nothing has been received yet
+		return 0, nil
+	}
 	if m := rangeRE.FindStringSubmatch(Range); len(m) == 2 {
-		start, err = strconv.ParseInt(m[1], 10, 64)
+		var last int64
+		last, err = strconv.ParseInt(m[1], 10, 64)
 		if err == nil {
-			return start, nil
+			return last + 1, nil
 		}
 	}
 	return 0, errors.Errorf("unable to parse range %q", Range)
@@ -195,7 +259,7 @@ func (rx *resumableUpload) transferChunk(start int64, chunk io.ReadSeeker, chunk
 // Upload uploads the chunks from the input
 // It retries each chunk using the pacer and --low-level-retries
 func (rx *resumableUpload) Upload() (*drive.File, error) {
-	start := int64(0)
+	start := rx.Start
 	var StatusCode int
 	var err error
 	buf := make([]byte, int(rx.f.opt.ChunkSize))
@@ -142,8 +142,9 @@ type Fs struct {
 	srv            files.Client   // the connection to the dropbox server
 	sharing        sharing.Client // as above, but for generating sharing links
 	users          users.Client   // as above, but for accessing user information
-	slashRoot      string         // root with "/" prefix, lowercase
-	slashRootSlash string         // root with "/" prefix and postfix, lowercase
+	slashRoot      string         // root with "/" prefix
+	slashRootSlash string         // root with "/" prefix and postfix
+	rootLower      string         // slashRootSlash in lowercase
 	pacer          *pacer.Pacer   // To pace the API calls
 	ns             string         // The namespace we are using or "" for none
 }
@@ -228,7 +229,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	f := &Fs{
 		name:  name,
 		opt:   *opt,
-		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		pacer: pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant).SetName(name),
 	}
 	config := dropbox.Config{
 		LogLevel:        dropbox.LogOff, // logging in the SDK: LogOff, LogDebug, LogInfo
@@ -298,6 +299,22 @@ func (f *Fs) setRoot(root string) {
 	if f.root != "" {
 		f.slashRootSlash += "/"
 	}
+	f.rootLower = strings.ToLower(f.slashRootSlash)
+}
+
+// remoteFromPath returns the remote for pathDisplay, the path of an
+// entry returned by dropbox, and whether it is inside the root.
+//
+// Dropbox paths are case insensitive so the root is compared case
+// insensitively, but the remote is cut from pathDisplay to keep its
+// case.
+func (f *Fs) remoteFromPath(pathDisplay string) (remote string, ok bool) {
+	if !strings.HasPrefix(strings.ToLower(pathDisplay), f.rootLower) {
+		return "", false
+	}
+	// skip as many path elements as there are in the root
+	parts := strings.SplitN(pathDisplay, "/", strings.Count(f.slashRootSlash, "/")+1)
+	return parts[len(parts)-1], true
 }
 
 // getMetadata gets the metadata for a file or directory
@@ -792,6 +809,200 @@ func (f *Fs) About() (usage *fs.Usage, err error) {
 	return usage, nil
 }
 
+// Search finds objects and directories under dir whose names match
+// query using the Dropbox search API.
+//
+// Note that Dropbox only matches whole words and prefixes of the
+// last word so the caller should filter the results further.
+func (f *Fs) Search(dir, query string, callback fs.ListRCallback) (err error) {
+	root := f.slashRoot
+	if dir != "" {
+		root += "/" + dir
+	}
+	arg := files.NewSearchArg(root, query)
+	if root == "/" {
+		arg.Path = "" // Specify root folder as empty string
+	}
+	arg.MaxResults = 1000
+	for {
+		var res *files.SearchResult
+		err = f.pacer.Call(func() (bool, error) {
+			res, err = f.srv.Search(arg)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			switch e := err.(type) {
+			case files.SearchAPIError:
+				if e.EndpointError != nil && e.EndpointError.Path != nil && e.EndpointError.Path.Tag == files.LookupErrorNotFound {
+					return fs.ErrorDirNotFound
+				}
+			}
+			return errors.Wrap(err, "search failed")
+		}
+		var entries fs.DirEntries
+		for _, match := range res.Matches {
+			var metadata *files.Metadata
+			switch info := match.Metadata.(type) {
+			case *files.FolderMetadata:
+				metadata = &info.Metadata
+			case *files.FileMetadata:
+				metadata = &info.Metadata
+			default:
+				fs.Errorf(f, "Unknown type %T", match.Metadata)
+				continue
+			}
+			remote, ok := f.remoteFromPath(metadata.PathDisplay)
+			if !ok {
+				fs.Debugf(f, "Ignoring search result %q outside root", metadata.PathDisplay)
+				continue
+			}
+			switch info := match.Metadata.(type) {
+			case *files.FolderMetadata:
+				entries = append(entries, fs.NewDir(remote, time.Now()))
+			case *files.FileMetadata:
+				o, err := f.newObjectWithInfo(remote, info)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, o)
+			}
+		}
+		err = callback(entries)
+		if err != nil {
+			return err
+		}
+		if !res.More {
+			break
+		}
+		arg.Start = res.Start
+	}
+	return nil
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+// If the implementation uses polling, it should adhere to the given interval.
+//
+// Automatically restarts itself in case of unexpected behaviour of the remote.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	go func() {
+		// get the cursor early so all changes from now on get processed
+		cursor, err := f.changeNotifyCursor()
+		if err != nil {
+			fs.Infof(f, "Failed to get list cursor: %s", err)
+		}
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					if ticker != nil {
+						ticker.Stop()
+					}
+					return
+				}
+				if ticker != nil {
+					ticker.Stop()
+					ticker, tickerC = nil, nil
+				}
+				if pollInterval != 0 {
+					ticker = time.NewTicker(pollInterval)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				if cursor == "" {
+					cursor, err = f.changeNotifyCursor()
+					if err != nil {
+						fs.Infof(f, "Failed to get list cursor: %s", err)
+						continue
+					}
+				}
+				fs.Debugf(f, "Checking for changes on remote")
+				cursor, err = f.changeNotifyRunner(notifyFunc, cursor)
+				if err != nil {
+					fs.Infof(f, "Change notify listener failure: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// changeNotifyCursor returns a cursor for the recursive listing of
+// the root which will only return changes made after this call
+func (f *Fs) changeNotifyCursor() (cursor string, err error) {
+	arg := files.ListFolderArg{
+		Path:      f.slashRoot,
+		Recursive: true,
+	}
+	if arg.Path == "/" {
+		arg.Path = ""
+	}
+	var res *files.ListFolderGetLatestCursorResult
+	err = f.pacer.Call(func() (bool, error) {
+		res, err = f.srv.ListFolderGetLatestCursor(&arg)
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.Cursor, nil
+}
+
+// changeNotifyRunner calls notifyFunc with the changes made since
+// cursor and returns the cursor to use next time.
+//
+// If the cursor has expired it returns "" so a new one is fetched
+// and the whole cache is forgotten.
+func (f *Fs) changeNotifyRunner(notifyFunc func(string, fs.EntryType), cursor string) (newCursor string, err error) {
+	for {
+		arg := files.ListFolderContinueArg{
+			Cursor: cursor,
+		}
+		var res *files.ListFolderResult
+		err = f.pacer.Call(func() (bool, error) {
+			res, err = f.srv.ListFolderContinue(&arg)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			if apiErr, ok := err.(files.ListFolderContinueAPIError); ok && apiErr.EndpointError != nil && apiErr.EndpointError.Tag == files.ListFolderContinueErrorReset {
+				fs.Debugf(f, "List cursor was reset - forgetting everything")
+				notifyFunc("", fs.EntryDirectory)
+				return "", nil
+			}
+			return cursor, err
+		}
+		for _, entry := range res.Entries {
+			var entryType fs.EntryType
+			var pathDisplay string
+			switch info := entry.(type) {
+			case *files.FolderMetadata:
+				entryType, pathDisplay = fs.EntryDirectory, info.PathDisplay
+			case *files.FileMetadata:
+				entryType, pathDisplay = fs.EntryObject, info.PathDisplay
+			case *files.DeletedMetadata:
+				// we don't know whether this was a file or a
+				// directory so forget it as a directory which
+				// also invalidates its parent
+				entryType, pathDisplay = fs.EntryDirectory, info.PathDisplay
+			default:
+				fs.Errorf(f, "Unknown type %T in change list", entry)
+				continue
+			}
+			remote, ok := f.remoteFromPath(pathDisplay)
+			if !ok {
+				continue
+			}
+			notifyFunc(remote, entryType)
+		}
+		cursor = res.Cursor
+		if !res.HasMore {
+			return cursor, nil
+		}
+	}
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.Dropbox)
@@ -1070,13 +1281,15 @@ func (o *Object) Remove() (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs           = (*Fs)(nil)
-	_ fs.Copier       = (*Fs)(nil)
-	_ fs.Purger       = (*Fs)(nil)
-	_ fs.PutStreamer  = (*Fs)(nil)
-	_ fs.Mover        = (*Fs)(nil)
-	_ fs.PublicLinker = (*Fs)(nil)
-	_ fs.DirMover     = (*Fs)(nil)
-	_ fs.Abouter      = (*Fs)(nil)
-	_ fs.Object       = (*Object)(nil)
+	_ fs.Fs             = (*Fs)(nil)
+	_ fs.Copier         = (*Fs)(nil)
+	_ fs.Purger         = (*Fs)(nil)
+	_ fs.PutStreamer    = (*Fs)(nil)
+	_ fs.Mover          = (*Fs)(nil)
+	_ fs.PublicLinker   = (*Fs)(nil)
+	_ fs.DirMover       = (*Fs)(nil)
+	_ fs.Abouter        = (*Fs)(nil)
+	_ fs.Searcher       = (*Fs)(nil)
+	_ fs.ChangeNotifier = (*Fs)(nil)
+	_ fs.Object         = (*Object)(nil)
 )
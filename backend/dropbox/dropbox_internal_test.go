@@ -0,0 +1,32 @@
+package dropbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteFromPath(t *testing.T) {
+	for _, test := range []struct {
+		root        string
+		pathDisplay string
+		wantRemote  string
+		wantOK      bool
+	}{
+		{"", "/file.txt", "file.txt", true},
+		{"", "/Dir/File.txt", "Dir/File.txt", true},
+		{"Photos", "/Photos/Summer/IMG.jpg", "Summer/IMG.jpg", true},
+		{"Photos", "/photos/Summer/IMG.jpg", "Summer/IMG.jpg", true},
+		{"photos", "/PHOTOS/Summer/IMG.jpg", "Summer/IMG.jpg", true},
+		{"/Photos/2018/", "/photos/2018/Summer/IMG.jpg", "Summer/IMG.jpg", true},
+		{"Photos", "/Photos", "", false},
+		{"Photos", "/PhotosOld/IMG.jpg", "", false},
+		{"Photos", "/Music/song.mp3", "", false},
+	} {
+		f := &Fs{}
+		f.setRoot(test.root)
+		gotRemote, gotOK := f.remoteFromPath(test.pathDisplay)
+		assert.Equal(t, test.wantOK, gotOK, test)
+		assert.Equal(t, test.wantRemote, gotRemote, test)
+	}
+}
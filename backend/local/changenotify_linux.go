@@ -0,0 +1,220 @@
+// Change notification using inotify
+
+// +build linux
+
+package local
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask is the events which mean an entry has changed
+const inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// watchChange is a changed entry waiting to be notified
+type watchChange struct {
+	remote    string
+	entryType fs.EntryType
+}
+
+// watcher watches all the directories under the root of an Fs with
+// inotify, collecting the entries which change
+type watcher struct {
+	f       *Fs
+	fd      int
+	file    *os.File
+	mu      sync.Mutex
+	dirs    map[int]string // remote directory by watch descriptor
+	changes []watchChange  // changes in the order they were seen
+	seen    map[watchChange]struct{}
+	closed  bool
+}
+
+// newWatcher starts an inotify watch of everything under the root
+func newWatcher(f *Fs) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{
+		f:    f,
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		dirs: make(map[int]string),
+		seen: make(map[watchChange]struct{}),
+	}
+	w.mu.Lock()
+	w.addTree("")
+	w.mu.Unlock()
+	return w, nil
+}
+
+// addTree watches the directory dir and all the directories under it
+// - call with the lock held
+func (w *watcher) addTree(dir string) {
+	root := filepath.Join(w.f.root, filepath.FromSlash(dir))
+	_ = filepath.Walk(root, func(osPath string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		remote := dir
+		if osPath != root {
+			rel, err := filepath.Rel(root, osPath)
+			if err != nil {
+				return nil
+			}
+			remote = path.Join(dir, w.f.cleanRemote(rel))
+		}
+		wd, err := unix.InotifyAddWatch(w.fd, osPath, inotifyMask)
+		if err != nil {
+			fs.Debugf(w.f, "Failed to watch %q: %v", osPath, err)
+			return nil
+		}
+		w.dirs[wd] = remote
+		return nil
+	})
+}
+
+// changed records that remote has changed - call with the lock held
+func (w *watcher) changed(remote string, entryType fs.EntryType) {
+	change := watchChange{remote: remote, entryType: entryType}
+	if _, found := w.seen[change]; found {
+		return
+	}
+	w.seen[change] = struct{}{}
+	w.changes = append(w.changes, change)
+}
+
+// event processes an inotify event
+func (w *watcher) event(wd int, mask uint32, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		// Events were lost so everything may have changed
+		w.changed("", fs.EntryDirectory)
+		return
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.dirs, wd)
+		return
+	}
+	dir, ok := w.dirs[wd]
+	if !ok || name == "" {
+		return
+	}
+	remote := path.Join(dir, w.f.cleanRemote(name))
+	entryType := fs.EntryObject
+	if mask&unix.IN_ISDIR != 0 {
+		entryType = fs.EntryDirectory
+		if mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			w.addTree(remote)
+		}
+	}
+	w.changed(remote, entryType)
+}
+
+// read events from inotify until the watcher is closed
+func (w *watcher) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			w.mu.Lock()
+			closed := w.closed
+			w.mu.Unlock()
+			if !closed {
+				fs.Errorf(w.f, "Change notification stopped: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			start := offset + unix.SizeofInotifyEvent
+			offset = start + int(event.Len)
+			if offset > n {
+				break
+			}
+			name := strings.TrimRight(string(buf[start:offset]), "\x00")
+			w.event(int(event.Wd), event.Mask, name)
+		}
+	}
+}
+
+// flush calls notifyFunc with the changes since the last flush
+func (w *watcher) flush(notifyFunc func(string, fs.EntryType)) {
+	w.mu.Lock()
+	changes := w.changes
+	w.changes = nil
+	w.seen = make(map[watchChange]struct{})
+	w.mu.Unlock()
+	for _, change := range changes {
+		notifyFunc(change.remote, change.entryType)
+	}
+}
+
+// close stops the watcher
+func (w *watcher) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	_ = w.file.Close()
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+//
+// The changes are found with inotify and passed on once every poll
+// interval, with each changed path notified once.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	w, err := newWatcher(f)
+	if err != nil {
+		fs.Errorf(f, "Failed to start change notification: %v", err)
+		go func() {
+			for range pollIntervalChan {
+			}
+		}()
+		return
+	}
+	go w.read()
+	go func() {
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					if ticker != nil {
+						ticker.Stop()
+					}
+					w.close()
+					return
+				}
+				if ticker != nil {
+					ticker.Stop()
+					ticker, tickerC = nil, nil
+				}
+				if pollInterval != 0 {
+					ticker = time.NewTicker(pollInterval)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				w.flush(notifyFunc)
+			}
+		}
+	}()
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.ChangeNotifier = &Fs{}
+)
@@ -0,0 +1,132 @@
+// +build !plan9
+
+package local
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// hashCacheBucket is the bucket the hashes are stored in
+var hashCacheBucket = []byte("hashes")
+
+// hashCaches are the open hash caches by path so that remotes using
+// the same database share it
+var (
+	hashCachesMu sync.Mutex
+	hashCaches   = make(map[string]*hashCache)
+)
+
+// hashCache is a persistent cache of the hashes of local files so
+// that files which haven't changed aren't read again to hash them
+type hashCache struct {
+	db *bolt.DB
+}
+
+// hashCacheEntry is what is stored in the hashCache for each file
+//
+// The hashes are only valid if the size, modification time and inode
+// of the file are the same.
+type hashCacheEntry struct {
+	Size    int64
+	ModTime int64 // in nanoseconds since the epoch
+	Inode   uint64
+	Hashes  map[string]string // by hash name
+}
+
+// openHashCache opens the hash cache database at dbPath, creating it
+// if necessary
+func openHashCache(dbPath string) (*hashCache, error) {
+	dbPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	hashCachesMu.Lock()
+	defer hashCachesMu.Unlock()
+	if c, ok := hashCaches[dbPath]; ok {
+		return c, nil
+	}
+	err = os.MkdirAll(filepath.Dir(dbPath), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create hash cache directory")
+	}
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open hash cache %q", dbPath)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(hashCacheBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to initialise hash cache %q", dbPath)
+	}
+	c := &hashCache{db: db}
+	hashCaches[dbPath] = c
+	return c, nil
+}
+
+// newHashCacheEntry makes the entry for the file described by info
+func newHashCacheEntry(info os.FileInfo) hashCacheEntry {
+	return hashCacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Inode:   readInode(info),
+	}
+}
+
+// get returns the hashes cached for the file at path described by
+// info, or nil if there aren't any valid ones
+func (c *hashCache) get(path string, info os.FileInfo) (hashes map[hash.Type]string) {
+	var entry hashCacheEntry
+	err := c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(hashCacheBucket).Get([]byte(path))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		fs.Debugf(path, "Failed to read hash cache: %v", err)
+		return nil
+	}
+	want := newHashCacheEntry(info)
+	if entry.Hashes == nil || entry.Size != want.Size || entry.ModTime != want.ModTime || entry.Inode != want.Inode {
+		return nil
+	}
+	hashes = make(map[hash.Type]string, len(entry.Hashes))
+	for name, sum := range entry.Hashes {
+		var ht hash.Type
+		if ht.Set(name) == nil {
+			hashes[ht] = sum
+		}
+	}
+	return hashes
+}
+
+// put stores the hashes for the file at path described by info
+func (c *hashCache) put(path string, info os.FileInfo, hashes map[hash.Type]string) {
+	entry := newHashCacheEntry(info)
+	entry.Hashes = make(map[string]string, len(hashes))
+	for ht, sum := range hashes {
+		entry.Hashes[ht.String()] = sum
+	}
+	data, err := json.Marshal(&entry)
+	if err == nil {
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(hashCacheBucket).Put([]byte(path), data)
+		})
+	}
+	if err != nil {
+		fs.Debugf(path, "Failed to write hash cache: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+// +build plan9
+
+package local
+
+import (
+	"errors"
+	"os"
+
+	"github.com/ncw/rclone/fs/hash"
+)
+
+// hashCache isn't supported on plan9
+type hashCache struct{}
+
+// openHashCache returns an error as the hash cache isn't supported
+func openHashCache(dbPath string) (*hashCache, error) {
+	return nil, errors.New("hash_cache is not supported on plan9")
+}
+
+// get returns no hashes
+func (c *hashCache) get(path string, info os.FileInfo) map[hash.Type]string {
+	return nil
+}
+
+// put does nothing
+func (c *hashCache) put(path string, info os.FileInfo, hashes map[hash.Type]string) {
+}
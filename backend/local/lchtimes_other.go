@@ -0,0 +1,15 @@
+//+build !linux
+
+package local
+
+import (
+	"time"
+)
+
+// lchtimes sets the modification time of name without following
+// symlinks
+//
+// This isn't supported on this OS so it does nothing.
+func lchtimes(name string, modTime time.Time) error {
+	return nil
+}
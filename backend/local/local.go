@@ -2,6 +2,7 @@
 package local
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -27,6 +28,8 @@ import (
 
 // Constants
 const devUnset = 0xdeadbeefcafebabe // a device id meaning it is unset
+const linkSuffix = ".rclonelink"    // suffix added to a translated symbolic link
+const maxLinkHops = 255             // maximum number of links to follow when resolving a path
 
 // Register with Fs
 func init() {
@@ -48,6 +51,17 @@ func init() {
 			NoPrefix: true,
 			ShortOpt: "L",
 			Advanced: true,
+		}, {
+			Name: "links",
+			Help: `Translate symlinks to/from regular files with a '` + linkSuffix + `' extension.
+
+Symlinks are uploaded as small files containing the link target and
+made into symlinks again when downloaded.  On Windows creating
+symlinks needs administrator rights or Developer Mode enabled.`,
+			Default:  false,
+			NoPrefix: true,
+			ShortOpt: "l",
+			Advanced: true,
 		}, {
 			Name:     "skip_links",
 			Help:     "Don't warn about skipped symlinks.",
@@ -71,6 +85,16 @@ func init() {
 			NoPrefix: true,
 			ShortOpt: "x",
 			Advanced: true,
+		}, {
+			Name: "hash_cache",
+			Help: `Path of a database to cache the hashes of files in between runs.
+
+If set, the hashes rclone calculates are stored in this file with the
+size, modification time and inode of the file they were calculated
+from.  Files which haven't changed since are then not read again to
+hash them, which makes syncing large trees with --checksum much
+quicker.  Leave blank (the default) to not cache hashes.`,
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -78,12 +102,14 @@ func init() {
 
 // Options defines the configuration for this backend
 type Options struct {
-	FollowSymlinks bool `config:"copy_links"`
-	SkipSymlinks   bool `config:"skip_links"`
-	NoUTFNorm      bool `config:"no_unicode_normalization"`
-	NoCheckUpdated bool `config:"no_check_updated"`
-	NoUNC          bool `config:"nounc"`
-	OneFileSystem  bool `config:"one_file_system"`
+	FollowSymlinks    bool   `config:"copy_links"`
+	TranslateSymlinks bool   `config:"links"`
+	SkipSymlinks      bool   `config:"skip_links"`
+	NoUTFNorm         bool   `config:"no_unicode_normalization"`
+	NoCheckUpdated    bool   `config:"no_check_updated"`
+	NoUNC             bool   `config:"nounc"`
+	OneFileSystem     bool   `config:"one_file_system"`
+	HashCache         string `config:"hash_cache"`
 }
 
 // Fs represents a local filesystem rooted at root
@@ -101,6 +127,7 @@ type Fs struct {
 	lstat          func(name string) (os.FileInfo, error)
 	dirNames       *mapper    // directory name mapping
 	objectHashesMu sync.Mutex // global lock for Object.hashes
+	hashCache      *hashCache // persistent cache of hashes if set
 }
 
 // Object represents a local filesystem object
@@ -112,6 +139,7 @@ type Object struct {
 	mode    os.FileMode
 	modTime time.Time
 	hashes  map[hash.Type]string // Hashes
+	link    bool                 // set if this is a translated symlink
 }
 
 // ------------------------------------------------------------
@@ -128,6 +156,9 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if opt.NoUTFNorm {
 		fs.Errorf(nil, "The --local-no-unicode-normalization flag is deprecated and will be removed")
 	}
+	if opt.FollowSymlinks && opt.TranslateSymlinks {
+		return nil, errors.New("can't use -L/--copy-links with -l/--links")
+	}
 
 	f := &Fs{
 		name:     name,
@@ -141,10 +172,17 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	f.features = (&fs.Features{
 		CaseInsensitive:         f.caseInsensitive(),
 		CanHaveEmptyDirectories: true,
+		MoveOverwrites:          true,
 	}).Fill(f)
 	if opt.FollowSymlinks {
 		f.lstat = os.Stat
 	}
+	if opt.HashCache != "" {
+		f.hashCache, err = openHashCache(opt.HashCache)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Check to see if this points to a file
 	fi, err := f.lstat(f.root)
@@ -194,14 +232,20 @@ func (f *Fs) caseInsensitive() bool {
 //
 // if dstPath is empty then it is made from remote
 func (f *Fs) newObject(remote, dstPath string) *Object {
+	link := f.opt.TranslateSymlinks && strings.HasSuffix(remote, linkSuffix)
 	if dstPath == "" {
-		dstPath = f.cleanPath(filepath.Join(f.root, remote))
+		localRemote := remote
+		if link {
+			localRemote = strings.TrimSuffix(remote, linkSuffix)
+		}
+		dstPath = f.cleanPath(filepath.Join(f.root, localRemote))
 	}
 	remote = f.cleanRemote(remote)
 	return &Object{
 		fs:     f,
 		remote: remote,
 		path:   dstPath,
+		link:   link,
 	}
 }
 
@@ -227,6 +271,13 @@ func (f *Fs) newObjectWithInfo(remote, dstPath string, info os.FileInfo) (fs.Obj
 	if o.mode.IsDir() {
 		return nil, errors.Wrapf(fs.ErrorNotAFile, "%q", remote)
 	}
+	if o.link {
+		// remote.rclonelink must be a symlink
+		fi, err := os.Lstat(o.path)
+		if err != nil || !isLink(o.path, fi) {
+			return nil, fs.ErrorObjectNotFound
+		}
+	}
 	return o, nil
 }
 
@@ -276,11 +327,20 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 
 		for _, fi := range fis {
 			name := fi.Name()
-			mode := fi.Mode()
 			newRemote := path.Join(remote, name)
 			newPath := filepath.Join(fsDirPath, name)
+			link := isLink(newPath, fi)
+			// Translate symlinks if required
+			if f.opt.TranslateSymlinks && link {
+				fso, err := f.newObjectWithInfo(newRemote+linkSuffix, newPath, fi)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, fso)
+				continue
+			}
 			// Follow symlinks if required
-			if f.opt.FollowSymlinks && (mode&os.ModeSymlink) != 0 {
+			if f.opt.FollowSymlinks && link {
 				fi, err = os.Stat(newPath)
 				if os.IsNotExist(err) {
 					// Skip bad symlinks
@@ -292,12 +352,20 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 				if err != nil {
 					return nil, err
 				}
-				mode = fi.Mode()
+				// Don't follow symlinks or junctions which point
+				// back up the tree as they would recurse forever
+				if fi.IsDir() && f.isLoop(fsDirPath, newPath) {
+					err = fserrors.NoRetryError(errors.New("symlink points to a parent directory - not following"))
+					fs.Errorf(newRemote, "Listing error: %v", err)
+					accounting.Stats.Error(err)
+					continue
+				}
+				link = false
 			}
 			if fi.IsDir() {
 				// Ignore directories which are symlinks.  These are junction points under windows which
 				// are kind of a souped up symlink. Unix doesn't have directories which are symlinks.
-				if (mode&os.ModeSymlink) == 0 && f.dev == readDevice(fi, f.opt.OneFileSystem) {
+				if !link && f.dev == readDevice(fi, f.opt.OneFileSystem) {
 					d := fs.NewDir(f.dirNames.Save(newRemote, f.cleanRemote(newRemote)), fi.ModTime())
 					entries = append(entries, d)
 				}
@@ -315,6 +383,87 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 	return entries, nil
 }
 
+// isLoop returns true if following the directory symlink or junction
+// at linkPath in dirPath would lead back to dirPath or one of its
+// parents.
+//
+// The parents are resolved too so that loops made of more than one
+// link are found.
+func (f *Fs) isLoop(dirPath, linkPath string) bool {
+	target := f.comparablePath(resolveLinks(linkPath))
+	for p := dirPath; ; {
+		dir := f.comparablePath(resolveLinks(p))
+		if dir == target || strings.HasPrefix(dir, strings.TrimSuffix(target, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return false
+		}
+		p = parent
+	}
+}
+
+// comparablePath returns p in a form which can be compared with
+// other paths, removing any UNC prefix and folding the case if
+// necessary
+func (f *Fs) comparablePath(p string) string {
+	if strings.HasPrefix(p, `\\?\UNC\`) {
+		p = `\\` + p[len(`\\?\UNC\`):]
+	} else if strings.HasPrefix(p, `\\?\`) {
+		p = p[len(`\\?\`):]
+	}
+	p = filepath.Clean(p)
+	if f.caseInsensitive() {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// resolveLinks returns p with any symlinks or junctions in it
+// replaced by their targets.
+//
+// This is used rather than filepath.EvalSymlinks as that doesn't
+// resolve junctions on Windows.  Any part of p which can't be read is
+// left as it is.
+func resolveLinks(p string) string {
+	const sep = string(filepath.Separator)
+	p = filepath.Clean(p)
+	for hops := 0; hops < maxLinkHops; hops++ {
+		vol := filepath.VolumeName(p)
+		parts := strings.Split(p[len(vol):], sep)
+		current := vol
+		resolved := false
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			if !strings.HasSuffix(current, sep) {
+				current += sep
+			}
+			current += part
+			fi, err := os.Lstat(current)
+			if err != nil || !isLink(current, fi) {
+				continue
+			}
+			target, err := os.Readlink(current)
+			if err != nil {
+				continue
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			p = filepath.Join(append([]string{target}, parts[i+1:]...)...)
+			resolved = true
+			break
+		}
+		if !resolved {
+			break
+		}
+	}
+	return p
+}
+
 // cleanRemote makes string a valid UTF-8 string for remote strings.
 //
 // Any invalid UTF-8 characters will be replaced with utf8.RuneError
@@ -428,7 +577,7 @@ func (f *Fs) readPrecision() (precision time.Duration) {
 	precision = time.Second
 
 	// Create temporary file and test it
-	fd, err := ioutil.TempFile("", "rclone")
+	fd, err := ioutil.TempFile(fs.TempDir(), "rclone")
 	if err != nil {
 		// If failed return 1s
 		// fmt.Println("Failed to create temp file", err)
@@ -508,6 +657,10 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 
 	// Temporary Object under construction
 	dstObj := f.newObject(remote, "")
+	if srcObj.link != dstObj.link {
+		fs.Debugf(src, "Can't move - translated symlink to or from a file")
+		return nil, fs.ErrorCantMove
+	}
 
 	// Check it is a file if it exists
 	err := dstObj.lstat()
@@ -515,7 +668,7 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 		// OK
 	} else if err != nil {
 		return nil, err
-	} else if !dstObj.mode.IsRegular() {
+	} else if !dstObj.mode.IsRegular() && !dstObj.link {
 		// It isn't a file
 		return nil, errors.New("can't move file onto non-file")
 	}
@@ -624,20 +777,57 @@ func (o *Object) Remote() string {
 
 // Hash returns the requested hash of a file as a lowercase hex string
 func (o *Object) Hash(r hash.Type) (string, error) {
+	return o.hash(r, false)
+}
+
+// QuickHash returns the requested hash of a file if it is known
+// without reading the file, eg because it was calculated when the file
+// was last read or it is in the hash cache, or "" otherwise
+func (o *Object) QuickHash(r hash.Type) (string, error) {
+	return o.hash(r, true)
+}
+
+// hash returns the requested hash of a file, reading the file to
+// calculate it unless quick is set
+func (o *Object) hash(r hash.Type, quick bool) (string, error) {
 	// Check that the underlying file hasn't changed
 	oldtime := o.modTime
 	oldsize := o.size
-	err := o.lstat()
+	info, err := o.fs.lstat(o.path)
 	if err != nil {
 		return "", errors.Wrap(err, "hash: failed to stat")
 	}
+	o.setMetadata(info)
 
 	o.fs.objectHashesMu.Lock()
 	hashes := o.hashes
 	o.fs.objectHashesMu.Unlock()
 
-	if !o.modTime.Equal(oldtime) || oldsize != o.size || hashes == nil {
-		in, err := os.Open(o.path)
+	if !o.modTime.Equal(oldtime) || oldsize != o.size {
+		hashes = nil
+	} else if _, found := hashes[r]; !found {
+		// only some hashes were calculated when the file was read
+		hashes = nil
+	}
+	useCache := o.fs.hashCache != nil && !o.link
+	if hashes == nil && useCache {
+		hashes = o.fs.hashCache.get(o.path, info)
+		if hashes != nil {
+			o.fs.objectHashesMu.Lock()
+			o.hashes = hashes
+			o.fs.objectHashesMu.Unlock()
+		}
+	}
+	if hashes == nil && quick {
+		return "", nil
+	}
+	if hashes == nil {
+		var in io.ReadCloser
+		if o.link {
+			in, err = o.openLink()
+		} else {
+			in, err = os.Open(o.path)
+		}
 		if err != nil {
 			return "", errors.Wrap(err, "hash: failed to open")
 		}
@@ -652,6 +842,9 @@ func (o *Object) Hash(r hash.Type) (string, error) {
 		o.fs.objectHashesMu.Lock()
 		o.hashes = hashes
 		o.fs.objectHashesMu.Unlock()
+		if useCache {
+			o.fs.hashCache.put(o.path, info, hashes)
+		}
 	}
 	return hashes[r], nil
 }
@@ -668,7 +861,12 @@ func (o *Object) ModTime() time.Time {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(modTime time.Time) error {
-	err := os.Chtimes(o.path, modTime, modTime)
+	var err error
+	if o.link {
+		err = lchtimes(o.path, modTime)
+	} else {
+		err = os.Chtimes(o.path, modTime, modTime)
+	}
 	if err != nil {
 		return err
 	}
@@ -686,9 +884,11 @@ func (o *Object) Storable() bool {
 		}
 	}
 	mode := o.mode
-	if mode&os.ModeSymlink != 0 {
+	if o.link {
+		return true
+	} else if mode&os.ModeSymlink != 0 {
 		if !o.fs.opt.SkipSymlinks {
-			fs.Logf(o, "Can't follow symlink without -L/--copy-links")
+			fs.Logf(o, "Can't follow symlink without -L/--copy-links or -l/--links")
 		}
 		return false
 	} else if mode&(os.ModeNamedPipe|os.ModeSocket|os.ModeDevice) != 0 {
@@ -766,6 +966,15 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 		}
 	}
 
+	if o.link {
+		in, err = o.openLink()
+		if err != nil {
+			return nil, err
+		}
+		_, err = in.(io.Seeker).Seek(offset, io.SeekStart)
+		return readers.NewLimitedReadCloser(in, limit), err
+	}
+
 	fd, err := os.Open(o.path)
 	if err != nil {
 		return
@@ -791,6 +1000,55 @@ func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	return in, nil
 }
 
+// linkReader is an io.ReadCloser reading the target of a translated
+// symlink
+type linkReader struct {
+	*strings.Reader
+}
+
+// Close the linkReader - a no-op
+func (linkReader) Close() error {
+	return nil
+}
+
+// openLink returns a reader for the target of the translated symlink
+func (o *Object) openLink() (io.ReadCloser, error) {
+	target, err := os.Readlink(o.path)
+	if err != nil {
+		return nil, err
+	}
+	return linkReader{strings.NewReader(target)}, nil
+}
+
+// updateLink makes the translated symlink point to the target read
+// from in
+func (o *Object) updateLink(in io.Reader, src fs.ObjectInfo) error {
+	target, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(o.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = symlink(string(target), o.path)
+	if err != nil {
+		return err
+	}
+	err = o.SetModTime(src.ModTime())
+	if err != nil {
+		return err
+	}
+	hashes, err := hash.Stream(bytes.NewReader(target))
+	if err != nil {
+		return err
+	}
+	o.fs.objectHashesMu.Lock()
+	o.hashes = hashes
+	o.fs.objectHashesMu.Unlock()
+	return nil
+}
+
 // mkdirAll makes all the directories needed to store the object
 func (o *Object) mkdirAll() error {
 	dir := filepath.Dir(o.path)
@@ -812,6 +1070,10 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		return err
 	}
 
+	if o.link {
+		return o.updateLink(in, src)
+	}
+
 	out, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
@@ -858,6 +1120,37 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	return o.lstat()
 }
 
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+//
+// It truncates any existing object
+func (f *Fs) OpenWriterAt(remote string, size int64) (fs.WriterAtCloser, error) {
+	// Temporary Object under construction
+	o := f.newObject(remote, "")
+
+	err := o.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.link {
+		return nil, errors.New("can't open a symlink for random writing")
+	}
+
+	out, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pre-allocate the file for performance reasons
+	err = preAllocate(size, out)
+	if err != nil {
+		fs.Debugf(o, "Failed to pre-allocate: %v", err)
+	}
+	return out, nil
+}
+
 // setMetadata sets the file info from the os.FileInfo passed in
 func (o *Object) setMetadata(info os.FileInfo) {
 	// Don't overwrite the info if we don't need to
@@ -871,6 +1164,12 @@ func (o *Object) setMetadata(info os.FileInfo) {
 	if o.mode != info.Mode() {
 		o.mode = info.Mode()
 	}
+	// The size of a translated symlink is the length of its target
+	if o.link {
+		if target, err := os.Readlink(o.path); err == nil && o.size != int64(len(target)) {
+			o.size = int64(len(target))
+		}
+	}
 }
 
 // Stat a Object into info
@@ -999,10 +1298,12 @@ func cleanWindowsName(f *Fs, name string) string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Purger         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.OpenWriterAter = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.QuickHasher    = &Object{}
 )
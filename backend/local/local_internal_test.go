@@ -1,12 +1,20 @@
 package local
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fstest"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
@@ -72,3 +80,190 @@ func TestUpdatingCheck(t *testing.T) {
 	require.NoError(t, err)
 
 }
+
+// makeSymlink makes newname a symlink to oldname skipping the test if
+// that isn't possible, eg on Windows without the privilege
+func makeSymlink(t *testing.T, oldname, newname string) {
+	err := symlink(oldname, newname)
+	if err != nil && runtime.GOOS == "windows" {
+		t.Skipf("can't make symlinks: %v", err)
+	}
+	require.NoError(t, err)
+}
+
+func TestSymlinkTranslate(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	when := time.Now().Add(-time.Hour).Truncate(time.Second)
+	r.WriteFile("file", "hello", when)
+	makeSymlink(t, "file", filepath.Join(r.LocalName, "link"))
+
+	f, err := NewFs("local", r.LocalName, configmap.Simple{"links": "true"})
+	require.NoError(t, err)
+
+	// The symlink should be listed as a translated link
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	o, ok := entries[1].(*Object)
+	require.True(t, ok)
+	assert.Equal(t, "link"+linkSuffix, o.Remote())
+	assert.Equal(t, int64(4), o.Size())
+	assert.True(t, o.Storable())
+
+	// Reading it should return the target
+	in, err := o.Open()
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "file", string(data))
+	in, err = o.Open(&fs.RangeOption{Start: 1, End: 2})
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "il", string(data))
+
+	// NewObject should only find symlinks
+	_, err = f.NewObject("link" + linkSuffix)
+	require.NoError(t, err)
+	_, err = f.NewObject("file" + linkSuffix)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Uploading a translated link should make a symlink
+	src := object.NewStaticObjectInfo("new"+linkSuffix, when, 6, true, nil, nil)
+	o2, err := f.Put(strings.NewReader("target"), src)
+	require.NoError(t, err)
+	target, err := os.Readlink(filepath.Join(r.LocalName, "new"))
+	require.NoError(t, err)
+	assert.Equal(t, "target", target)
+	assert.Equal(t, int64(6), o2.Size())
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, when, o2.ModTime())
+	}
+
+	// Can't use -L and -l together
+	_, err = NewFs("local", r.LocalName, configmap.Simple{"links": "true", "copy_links": "true"})
+	assert.Error(t, err)
+}
+
+// listRemotes lists dir in f returning the remotes found
+func listRemotes(t *testing.T, f fs.Fs, dir string) (remotes []string) {
+	entries, err := f.List(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		remotes = append(remotes, entry.Remote())
+	}
+	return remotes
+}
+
+func TestSymlinkLoop(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteFile("dir/file", "hello", time.Now())
+	r.WriteFile("other/file", "hello", time.Now())
+	makeSymlink(t, "..", filepath.Join(r.LocalName, "dir", "loop"))
+	makeSymlink(t, ".", filepath.Join(r.LocalName, "dir", "self"))
+	makeSymlink(t, "file", filepath.Join(r.LocalName, "dir", "link"))
+	// a loop through two links
+	makeSymlink(t, filepath.Join(r.LocalName, "other"), filepath.Join(r.LocalName, "dir", "other"))
+	makeSymlink(t, filepath.Join(r.LocalName, "dir"), filepath.Join(r.LocalName, "other", "back"))
+
+	f, err := NewFs("local", r.LocalName, configmap.Simple{"copy_links": "true"})
+	require.NoError(t, err)
+
+	accounting.Stats.ResetCounters()
+	assert.Equal(t, []string{"dir/file", "dir/link", "dir/other"}, listRemotes(t, f, "dir"))
+	assert.Equal(t, int64(2), accounting.Stats.GetErrors())
+
+	accounting.Stats.ResetCounters()
+	assert.Equal(t, []string{"dir/other/file"}, listRemotes(t, f, "dir/other"))
+	assert.Equal(t, int64(1), accounting.Stats.GetErrors())
+}
+
+func TestHashCache(t *testing.T) {
+	if runtime.GOOS == "plan9" {
+		t.Skip("hash cache not supported on plan9")
+	}
+	dir, err := ioutil.TempDir("", "rclone-hash-cache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0777))
+	filePath := filepath.Join(root, "file.txt")
+	modTime := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeFile := func(content string) {
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0666))
+		require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+	}
+	writeFile("hello")
+
+	opt := configmap.Simple{"hash_cache": filepath.Join(dir, "cache", "hashes.db")}
+	getHash := func() string {
+		f, err := NewFs("local", root, opt)
+		require.NoError(t, err)
+		o, err := f.NewObject("file.txt")
+		require.NoError(t, err)
+		sum, err := o.Hash(hash.MD5)
+		require.NoError(t, err)
+		return sum
+	}
+
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	assert.Equal(t, helloMD5, getHash())
+
+	// Replace the cached hash to check it is used by a new Fs
+	c, err := openHashCache(opt["hash_cache"])
+	require.NoError(t, err)
+	info, err := os.Lstat(filePath)
+	require.NoError(t, err)
+	require.NotNil(t, c.get(filePath, info))
+	c.put(filePath, info, map[hash.Type]string{hash.MD5: "cached"})
+	assert.Equal(t, "cached", getHash())
+
+	// Change the file keeping the modification time - the size
+	// changes so the hash is recalculated
+	writeFile("potato")
+	assert.Equal(t, "8ee2027983915ec78acc45027d874316", getHash())
+}
+
+func TestQuickHash(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	const helloSHA1 = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	r.WriteFile("file.txt", "hello", time.Now())
+	o, err := r.Flocal.NewObject("file.txt")
+	require.NoError(t, err)
+	qh := o.(fs.QuickHasher)
+
+	// not known until the file is read
+	sum, err := qh.QuickHash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "", sum)
+
+	// reading the file calculates the hashes asked for
+	in, err := o.Open(&fs.HashesOption{Hashes: hash.NewHashSet(hash.MD5)})
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+
+	sum, err = qh.QuickHash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, helloMD5, sum)
+	sum, err = qh.QuickHash(hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, "", sum)
+
+	// hashes which weren't calculated are read from the file
+	sum, err = o.Hash(hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, helloSHA1, sum)
+	sum, err = fs.QuickHash(o, hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, helloSHA1, sum)
+}
@@ -24,3 +24,13 @@ func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
 	}
 	return uint64(statT.Dev)
 }
+
+// readInode turns a valid os.FileInfo into an inode number, returning
+// 0 if it fails.
+func readInode(fi os.FileInfo) uint64 {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(statT.Ino)
+}
@@ -0,0 +1,15 @@
+//+build !windows
+
+package local
+
+import "os"
+
+// symlink creates newname as a symbolic link to oldname
+func symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+// isLink returns true if the file at path with info fi is a symlink
+func isLink(path string, fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,43 @@
+//+build windows
+
+package local
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+// symlink creates newname as a symbolic link to oldname
+//
+// Creating symlinks on Windows needs a privilege which normal users
+// don't have unless Developer Mode is enabled so give a helpful
+// error if it is missing.
+func symlink(oldname, newname string) error {
+	err := os.Symlink(oldname, newname)
+	if linkErr, ok := err.(*os.LinkError); ok && linkErr.Err == windows.ERROR_PRIVILEGE_NOT_HELD {
+		return errors.Wrap(err, "creating symlinks needs administrator rights or Developer Mode enabled")
+	}
+	return err
+}
+
+// isLink returns true if the file at path with info fi is a symlink
+// or a junction point.
+//
+// Junction points are reported by Go as irregular directories rather
+// than symlinks so look for a reparse point which can be read as a
+// link.  This excludes other reparse points such as deduplicated or
+// cloud placeholder files which should be treated as normal files.
+func isLink(path string, fi os.FileInfo) bool {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return true
+	}
+	attr, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok || attr.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT == 0 {
+		return false
+	}
+	_, err := os.Readlink(path)
+	return err == nil
+}
@@ -0,0 +1,57 @@
+//+build windows
+
+package local
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeJunction makes a junction point at link pointing to the
+// directory target - unlike symlinks these don't need any privileges
+func makeJunction(t *testing.T, target, link string) {
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func TestJunction(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteFile("dir/file", "hello", time.Now())
+	dir := filepath.Join(r.LocalName, "dir")
+	loop := filepath.Join(dir, "loop")
+	makeJunction(t, r.LocalName, loop)
+
+	fi, err := os.Lstat(loop)
+	require.NoError(t, err)
+	assert.True(t, isLink(loop, fi))
+	fi, err = os.Lstat(dir)
+	require.NoError(t, err)
+	assert.False(t, isLink(dir, fi))
+
+	// Junctions are skipped by default
+	f, err := NewFs("local", r.LocalName, configmap.Simple{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir/file"}, listRemotes(t, f, "dir"))
+
+	// With -L a junction loop is reported and not followed
+	f, err = NewFs("local", r.LocalName, configmap.Simple{"copy_links": "true"})
+	require.NoError(t, err)
+	accounting.Stats.ResetCounters()
+	assert.Equal(t, []string{"dir/file"}, listRemotes(t, f, "dir"))
+	assert.Equal(t, int64(1), accounting.Stats.GetErrors())
+
+	// With -l the junction is translated
+	f, err = NewFs("local", r.LocalName, configmap.Simple{"links": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir/file", "dir/loop" + linkSuffix}, listRemotes(t, f, "dir"))
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"path"
 	"strings"
 	"time"
@@ -388,7 +389,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		driveID:   opt.DriveID,
 		driveType: opt.DriveType,
 		srv:       rest.NewClient(oAuthClient).SetRoot(graphURL + "/drives/" + opt.DriveID),
-		pacer:     pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
+		pacer:     pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant).SetName(name),
 	}
 	f.features = (&fs.Features{
 		CaseInsensitive:         true,
@@ -641,6 +642,137 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 	return entries, nil
 }
 
+// parentItemID returns the normalized ID of the parent of info in the
+// same form as GetID or "" if it has no parent
+func parentItemID(info *api.Item) string {
+	parent := info.GetParentReferance()
+	if parent == nil || parent.ID == "" {
+		return ""
+	}
+	if parent.DriveID != "" && strings.Index(parent.ID, "#") == -1 {
+		return parent.DriveID + "#" + parent.ID
+	}
+	return parent.ID
+}
+
+// searchDirPath finds the path relative to the root of the directory
+// with the ID passed in, walking up its parents and caching them in
+// the dirCache.
+//
+// It returns false if the directory isn't below the root.  Lookups
+// which failed are remembered in notFound.
+func (f *Fs) searchDirPath(dirID string, notFound map[string]struct{}) (dirPath string, ok bool, err error) {
+	if dirPath, ok = f.dirCache.GetInv(dirID); ok {
+		return dirPath, true, nil
+	}
+	if _, found := notFound[dirID]; found {
+		return "", false, nil
+	}
+	var info *api.Item
+	var resp *http.Response
+	opts := newOptsCall(dirID, "GET", "")
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &info)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", false, errors.Wrap(err, "couldn't read parent directory")
+	}
+	if parentID := parentItemID(info); parentID != "" {
+		var parentPath string
+		parentPath, ok, err = f.searchDirPath(parentID, notFound)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			dirPath = path.Join(parentPath, restoreReservedChars(info.GetName()))
+			f.dirCache.Put(dirPath, dirID)
+			return dirPath, true, nil
+		}
+	}
+	notFound[dirID] = struct{}{}
+	return "", false, nil
+}
+
+// Search finds objects and directories under dir whose names match
+// query using the OneDrive search API.
+//
+// Note that OneDrive matches against other fields as well as the
+// name so the caller should filter the results further.
+func (f *Fs) Search(dir, query string, callback fs.ListRCallback) (err error) {
+	err = f.dirCache.FindRoot(false)
+	if err != nil {
+		return err
+	}
+	directoryID, err := f.dirCache.FindDir(dir, false)
+	if err != nil {
+		return err
+	}
+	searchQuery := strings.Replace(replaceReservedChars(query), "'", "''", -1)
+	opts := newOptsCall(directoryID, "GET", rest.URLPathEscape("/search(q='"+searchQuery+"')"))
+	notFound := make(map[string]struct{})
+	for {
+		var result api.ListChildrenResponse
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(&opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "couldn't search")
+		}
+		var entries fs.DirEntries
+		for i := range result.Value {
+			info := &result.Value[i]
+			if info.Deleted != nil {
+				continue
+			}
+			if !f.opt.ExposeOneNoteFiles && info.GetPackageType() == api.PackageTypeOneNote {
+				continue
+			}
+			parentID := parentItemID(info)
+			if parentID == "" {
+				continue
+			}
+			parentPath, ok, err := f.searchDirPath(parentID, notFound)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			remote := path.Join(parentPath, restoreReservedChars(info.GetName()))
+			if dir != "" && !strings.HasPrefix(remote, dir+"/") {
+				continue
+			}
+			if folder := info.GetFolder(); folder != nil {
+				// cache the directory ID for later lookups
+				id := info.GetID()
+				f.dirCache.Put(remote, id)
+				d := fs.NewDir(remote, time.Time(info.GetLastModifiedDateTime())).SetID(id)
+				d.SetItems(folder.ChildCount)
+				entries = append(entries, d)
+			} else {
+				o, err := f.newObjectWithInfo(remote, info)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, o)
+			}
+		}
+		err = callback(entries)
+		if err != nil {
+			return err
+		}
+		if result.NextLink == "" {
+			break
+		}
+		opts.Path = ""
+		opts.RootURL = result.NextLink
+	}
+	return nil
+}
+
 // Creates from the parameters passed in a half finished Object which
 // must have setMetaData called on it
 //
@@ -1040,6 +1172,180 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// ChangeNotify calls the passed function with a path that has had changes.
+// If the implementation uses polling, it should adhere to the given interval.
+//
+// Automatically restarts itself in case of unexpected behaviour of the remote.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	go func() {
+		// get the delta link early so all changes from now on get processed
+		deltaLink, err := f.changeNotifyDeltaLink()
+		if err != nil {
+			fs.Infof(f, "Failed to get delta link: %s", err)
+		}
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					if ticker != nil {
+						ticker.Stop()
+					}
+					return
+				}
+				if ticker != nil {
+					ticker.Stop()
+					ticker, tickerC = nil, nil
+				}
+				if pollInterval != 0 {
+					ticker = time.NewTicker(pollInterval)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				if deltaLink == "" {
+					deltaLink, err = f.changeNotifyDeltaLink()
+					if err != nil {
+						fs.Infof(f, "Failed to get delta link: %s", err)
+						continue
+					}
+				}
+				fs.Debugf(f, "Checking for changes on remote")
+				deltaLink, err = f.changeNotifyRunner(notifyFunc, deltaLink)
+				if err != nil {
+					fs.Infof(f, "Change notify listener failure: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// changeNotifyDeltaLink returns a link to read the changes made to
+// the drive after this call
+func (f *Fs) changeNotifyDeltaLink() (deltaLink string, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/root/delta",
+		Parameters: url.Values{"token": {"latest"}},
+	}
+	var result api.ViewDeltaResponse
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.DeltaLink == "" {
+		return "", errors.New("no delta link returned")
+	}
+	return result.DeltaLink, nil
+}
+
+// changeNotifyRunner calls notifyFunc with the changes made since
+// deltaLink was returned and returns the delta link to use next time.
+//
+// If the delta link has expired it returns "" so a new one is fetched
+// and the whole cache is forgotten.
+func (f *Fs) changeNotifyRunner(notifyFunc func(string, fs.EntryType), deltaLink string) (newDeltaLink string, err error) {
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: deltaLink,
+	}
+	for {
+		var result api.ViewDeltaResponse
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(&opts, nil, &result)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusGone {
+				fs.Debugf(f, "Delta link has expired - forgetting everything")
+				notifyFunc("", fs.EntryDirectory)
+				return "", nil
+			}
+			return deltaLink, err
+		}
+		f.changeNotifyItems(notifyFunc, result.Value)
+		if result.DeltaLink != "" {
+			return result.DeltaLink, nil
+		}
+		if result.NextLink == "" {
+			return deltaLink, errors.New("no next or delta link returned")
+		}
+		opts.RootURL = result.NextLink
+	}
+}
+
+// cachedDirPath returns the path of the directory with the ID passed
+// in if it is in the dirCache
+func (f *Fs) cachedDirPath(dirID string) (dirPath string, ok bool) {
+	if dirPath, ok = f.dirCache.GetInv(dirID); ok {
+		return dirPath, true
+	}
+	// the root of the drive is cached without its drive ID
+	if i := strings.Index(dirID, "#"); i >= 0 {
+		return f.dirCache.GetInv(dirID[i+1:])
+	}
+	return "", false
+}
+
+// changeNotifyItems calls notifyFunc with the old and new paths of
+// the changed items passed in.
+//
+// Only the directories in the dirCache are looked up, so changes in
+// directories which haven't been listed are ignored, as are changes
+// outside the root.
+func (f *Fs) changeNotifyItems(notifyFunc func(string, fs.EntryType), items []api.Item) {
+	type entryType struct {
+		path      string
+		entryType fs.EntryType
+	}
+	var pathsToClear []entryType
+	for i := range items {
+		info := &items[i]
+		changeType := fs.EntryObject
+		if info.GetFolder() != nil {
+			changeType = fs.EntryDirectory
+		}
+
+		// find the new path - deleted items may not have a name
+		// so forget their parent directory instead
+		newPath, newOK := "", false
+		if parentPath, ok := f.cachedDirPath(parentItemID(info)); ok {
+			name := info.GetName()
+			if info.Deleted != nil || name == "" {
+				pathsToClear = append(pathsToClear, entryType{path: parentPath, entryType: fs.EntryDirectory})
+			} else {
+				newPath, newOK = path.Join(parentPath, restoreReservedChars(name)), true
+				pathsToClear = append(pathsToClear, entryType{path: newPath, entryType: changeType})
+			}
+		}
+
+		// find the previous path of directories
+		if oldPath, ok := f.cachedDirPath(info.GetID()); ok && oldPath != "" {
+			pathsToClear = append(pathsToClear, entryType{path: oldPath, entryType: fs.EntryDirectory})
+			if !newOK || newPath != oldPath {
+				// the directory was moved or deleted
+				f.dirCache.FlushDir(oldPath)
+			}
+		}
+	}
+
+	visitedPaths := make(map[string]struct{})
+	for _, entry := range pathsToClear {
+		if _, ok := visitedPaths[entry.path]; ok {
+			continue
+		}
+		visitedPaths[entry.path] = struct{}{}
+		notifyFunc(entry.path, entry.entryType)
+	}
+}
+
 // DirCacheFlush resets the directory cache - used in testing as an
 // optional interface
 func (f *Fs) DirCacheFlush() {
@@ -1071,6 +1377,34 @@ func (f *Fs) About() (usage *fs.Usage, err error) {
 	return usage, nil
 }
 
+// PublicLink returns a link for downloading without account.
+func (f *Fs) PublicLink(remote string) (link string, err error) {
+	info, _, err := f.readMetaDataForPath(replaceReservedChars(f.rootSlash() + remote))
+	if err != nil {
+		if apiErr, ok := err.(*api.Error); ok && apiErr.ErrorInfo.Code == "itemNotFound" {
+			return "", fs.ErrorObjectNotFound
+		}
+		return "", err
+	}
+	opts := newOptsCall(info.GetID(), "POST", "/createLink")
+
+	share := api.CreateShareLinkRequest{
+		Type:  "view",
+		Scope: "anonymous",
+	}
+
+	var resp *http.Response
+	var result api.CreateShareLinkResponse
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(&opts, &share, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create link")
+	}
+	return result.Link.WebURL, nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	if f.driveType == driveTypePersonal {
@@ -1531,6 +1865,9 @@ var (
 	_ fs.DirMover        = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Searcher        = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = &Object{}
 	_ fs.IDer            = &Object{}
@@ -0,0 +1,55 @@
+package onedrive
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/backend/onedrive/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/dircache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeNotifyItems(t *testing.T) {
+	f := &Fs{}
+	f.dirCache = dircache.New("", "rootid", f)
+	f.dirCache.Put("", "rootid")
+	f.dirCache.Put("dir", "drv#dirid")
+	f.dirCache.Put("dir/sub", "drv#subid")
+
+	parent := func(id string) *api.ItemReference {
+		return &api.ItemReference{DriveID: "drv", ID: id}
+	}
+	items := []api.Item{
+		// new file in a directory
+		{ID: "file1", Name: "new.txt", ParentReference: parent("dirid"), File: &api.FileFacet{}},
+		// new file in the root of the drive
+		{ID: "file2", Name: "top.txt", ParentReference: parent("rootid"), File: &api.FileFacet{}},
+		// deleted file without a name
+		{ID: "file3", ParentReference: parent("subid"), Deleted: &api.DeletedFacet{}},
+		// renamed directory
+		{ID: "subid", Name: "sub2", ParentReference: parent("dirid"), Folder: &api.FolderFacet{}},
+		// change in a directory which isn't known
+		{ID: "file4", Name: "other.txt", ParentReference: parent("unknown"), File: &api.FileFacet{}},
+	}
+
+	type change struct {
+		path      string
+		entryType fs.EntryType
+	}
+	var changes []change
+	f.changeNotifyItems(func(path string, entryType fs.EntryType) {
+		changes = append(changes, change{path, entryType})
+	}, items)
+	assert.Equal(t, []change{
+		{"dir/new.txt", fs.EntryObject},
+		{"top.txt", fs.EntryObject},
+		{"dir/sub", fs.EntryDirectory},
+		{"dir/sub2", fs.EntryDirectory},
+	}, changes)
+
+	// the renamed directory is forgotten
+	_, ok := f.dirCache.GetInv("drv#subid")
+	assert.False(t, ok)
+	_, ok = f.dirCache.GetInv("drv#dirid")
+	assert.True(t, ok)
+}
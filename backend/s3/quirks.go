@@ -0,0 +1,55 @@
+package s3
+
+import "github.com/ncw/rclone/fs"
+
+// quirks are the ways an S3 compatible provider differs from AWS
+type quirks struct {
+	listVersion      int  // version of ListObjects to use
+	virtualHostStyle bool // set if the provider doesn't support path style
+}
+
+// defaultQuirks are used for providers not in providerQuirks
+//
+// ListObjects version 1 is the one supported by everything
+var defaultQuirks = quirks{
+	listVersion: 1,
+}
+
+// providerQuirks are the quirks of each provider
+var providerQuirks = map[string]quirks{
+	"AWS":          {listVersion: 2},
+	"Alibaba":      {listVersion: 1, virtualHostStyle: true},
+	"Ceph":         {listVersion: 1},
+	"DigitalOcean": {listVersion: 2},
+	"Dreamhost":    {listVersion: 1},
+	"IBMCOS":       {listVersion: 1},
+	"Minio":        {listVersion: 2},
+	"Wasabi":       {listVersion: 2},
+}
+
+// setQuirks sets the options which depend on the provider, unless the
+// user has set them
+func setQuirks(opt *Options) {
+	q, ok := providerQuirks[opt.Provider]
+	if !ok {
+		q = defaultQuirks
+	}
+	if opt.ListVersion == 0 {
+		opt.ListVersion = q.listVersion
+	}
+	if q.virtualHostStyle && opt.ForcePathStyle {
+		fs.Debugf(nil, "Using virtual hosted style as %s doesn't support path style", opt.Provider)
+		opt.ForcePathStyle = false
+	}
+	// The old way of asking for v2 signatures
+	if opt.Region == "other-v2-signature" {
+		opt.V2Auth = true
+	}
+}
+
+// etagIsMD5 returns whether the ETag of an object uploaded in one
+// part is its MD5 sum with these options.  It isn't for objects
+// encrypted with KMS.
+func etagIsMD5(opt *Options) bool {
+	return opt.ServerSideEncryption != "aws:kms" && opt.SSEKMSKeyID == ""
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +38,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/chunksize"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/fserrors"
@@ -53,7 +55,7 @@ import (
 func init() {
 	fs.Register(&fs.RegInfo{
 		Name:        "s3",
-		Description: "Amazon S3 Compliant Storage Providers (AWS, Ceph, Dreamhost, IBM COS, Minio)",
+		Description: "Amazon S3 Compliant Storage Providers (AWS, Alibaba, Ceph, Dreamhost, IBM COS, Minio)",
 		NewFs:       NewFs,
 		Options: []fs.Option{{
 			Name: fs.ConfigProvider,
@@ -61,6 +63,9 @@ func init() {
 			Examples: []fs.OptionExample{{
 				Value: "AWS",
 				Help:  "Amazon Web Services (AWS) S3",
+			}, {
+				Value: "Alibaba",
+				Help:  "Alibaba Cloud Object Storage System (OSS) formerly Aliyun",
 			}, {
 				Value: "Ceph",
 				Help:  "Ceph Object Storage",
@@ -557,10 +562,56 @@ func init() {
 			Advanced: true,
 		}, {
 			Name:     "force_path_style",
-			Help:     "If true use path style access if false use virtual hosted style.\nSome providers (eg Aliyun OSS or Netease COS) require this.",
+			Help:     "If true use path style access if false use virtual hosted style.\nSome providers (eg Aliyun OSS or Netease COS) require this.\nThis is set to false for providers which don't support path style.",
 			Default:  true,
 			Advanced: true,
+		}, {
+			Name:     "v2_auth",
+			Help:     "If true use v2 authentication.\nIf false (the default) v4 authentication is used.\nOnly use this if v4 signatures don't work, eg with old Ceph or Jewel versions.",
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name:     "list_version",
+			Help:     "Version of ListObjects to use: 1, 2 or 0 for auto.\nIf 0 rclone chooses the version the provider supports best.",
+			Default:  0,
+			Advanced: true,
+		}, {
+			Name: "object_lock_mode",
+			Help: `Object Lock retention mode to set on uploaded objects.
+
+The bucket must have Object Lock enabled.  Use with
+object_lock_retention to say how long objects are kept.`,
+			Default: "",
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Don't set a retention mode",
+			}, {
+				Value: "GOVERNANCE",
+				Help:  "Users with special permissions can remove the lock",
+			}, {
+				Value: "COMPLIANCE",
+				Help:  "Nobody can remove the lock until it expires",
+			}},
+			Advanced: true,
+		}, {
+			Name: "object_lock_retention",
+			Help: `How long uploaded objects are locked for with object_lock_mode.
+
+Objects can't be deleted or overwritten until this long after they
+were uploaded, eg "30d".  rclone doesn't try to delete objects which
+are still locked.`,
+			Default:  fs.Duration(0),
+			Advanced: true,
+		}, {
+			Name: "object_lock_legal_hold",
+			Help: `Set an Object Lock legal hold on uploaded objects.
+
+Objects with a legal hold can't be deleted until it is removed.  rclone
+doesn't try to delete objects with a legal hold.`,
+			Default:  false,
+			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
@@ -593,6 +644,11 @@ type Options struct {
 	SessionToken         string        `config:"session_token"`
 	UploadConcurrency    int           `config:"upload_concurrency"`
 	ForcePathStyle       bool          `config:"force_path_style"`
+	V2Auth               bool          `config:"v2_auth"`
+	ListVersion          int           `config:"list_version"`
+	ObjectLockMode       string        `config:"object_lock_mode"`
+	ObjectLockRetention  fs.Duration   `config:"object_lock_retention"`
+	ObjectLockLegalHold  bool          `config:"object_lock_legal_hold"`
 }
 
 // Fs represents a remote s3 server
@@ -608,6 +664,8 @@ type Fs struct {
 	bucketOK      bool             // true if we have created the bucket
 	bucketDeleted bool             // true if we have deleted the bucket
 	pacer         *pacer.Pacer     // To pace the API calls
+	etagIsMD5     bool             // set if single part ETags are MD5 sums
+	uploadTuner   *chunksize.Tuner // chooses the concurrency of multipart uploads
 }
 
 // Object describes a s3 object
@@ -623,6 +681,9 @@ type Object struct {
 	lastModified time.Time          // Last modified
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
+	storageClass string             // storage class of the object - may be "" for STANDARD
+	lockedUntil  time.Time          // Object Lock retain until date - read with meta
+	legalHold    bool               // set if the object has an Object Lock legal hold - read with meta
 }
 
 // ------------------------------------------------------------
@@ -767,7 +828,7 @@ func s3Connection(opt *Options) (*s3.S3, *session.Session, error) {
 	// awsConfig.WithLogLevel(aws.LogDebugWithSigning)
 	ses := session.New()
 	c := s3.New(ses, awsConfig)
-	if opt.Region == "other-v2-signature" {
+	if opt.V2Auth {
 		fs.Debugf(nil, "Using v2 auth")
 		signer := func(req *request.Request) {
 			// Ignore AnonymousCredentials object
@@ -794,27 +855,40 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if opt.ChunkSize < fs.SizeSuffix(s3manager.MinUploadPartSize) {
 		return nil, errors.Errorf("s3 chunk size (%v) must be >= %v", opt.ChunkSize, fs.SizeSuffix(s3manager.MinUploadPartSize))
 	}
+	opt.ObjectLockMode = strings.ToUpper(opt.ObjectLockMode)
+	if (opt.ObjectLockMode == "") != (opt.ObjectLockRetention <= 0) {
+		return nil, errors.New("s3 object_lock_mode and object_lock_retention must be set together")
+	}
 	bucket, directory, err := s3ParsePath(root)
 	if err != nil {
 		return nil, err
 	}
+	setQuirks(opt)
+	if opt.ListVersion != 1 && opt.ListVersion != 2 {
+		return nil, errors.Errorf("s3 list_version must be 0, 1 or 2 not %d", opt.ListVersion)
+	}
 	c, ses, err := s3Connection(opt)
 	if err != nil {
 		return nil, err
 	}
 	f := &Fs{
-		name:   name,
-		root:   directory,
-		opt:    *opt,
-		c:      c,
-		bucket: bucket,
-		ses:    ses,
-		pacer:  pacer.New().SetMinSleep(minSleep).SetPacer(pacer.S3Pacer),
-	}
+		name:      name,
+		root:      directory,
+		opt:       *opt,
+		c:         c,
+		bucket:    bucket,
+		ses:       ses,
+		pacer:     pacer.New().SetMinSleep(minSleep).SetPacer(pacer.S3Pacer).SetName(name),
+		etagIsMD5: etagIsMD5(opt),
+	}
+	f.uploadTuner = chunksize.NewTuner(opt.UploadConcurrency)
 	f.features = (&fs.Features{
-		ReadMimeType:  true,
-		WriteMimeType: true,
-		BucketBased:   true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		BucketBased:             true,
+		ServerSideAcrossConfigs: true,
+		SetTier:                 true,
+		GetTier:                 true,
 	}).Fill(f)
 	if f.root != "" {
 		f.root += "/"
@@ -860,6 +934,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *s3.Object) (fs.Object, error
 		}
 		o.etag = aws.StringValue(info.ETag)
 		o.bytes = aws.Int64Value(info.Size)
+		o.storageClass = aws.StringValue(info.StorageClass)
 	} else {
 		err := o.readMetaData() // reads info and meta, returning an error
 		if err != nil {
@@ -896,19 +971,48 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 	var marker *string
 	for {
 		// FIXME need to implement ALL loop
-		req := s3.ListObjectsInput{
-			Bucket:    &f.bucket,
-			Delimiter: &delimiter,
-			Prefix:    &root,
-			MaxKeys:   &maxKeys,
-			Marker:    marker,
+		var (
+			contents       []*s3.Object
+			commonPrefixes []*s3.CommonPrefix
+			isTruncated    bool
+			nextMarker     *string
+			err            error
+		)
+		if f.opt.ListVersion == 2 {
+			req := s3.ListObjectsV2Input{
+				Bucket:            &f.bucket,
+				Delimiter:         &delimiter,
+				Prefix:            &root,
+				MaxKeys:           &maxKeys,
+				ContinuationToken: marker,
+			}
+			var resp *s3.ListObjectsV2Output
+			err = f.pacer.Call(func() (bool, error) {
+				resp, err = f.c.ListObjectsV2(&req)
+				return shouldRetry(err)
+			})
+			if err == nil {
+				contents, commonPrefixes = resp.Contents, resp.CommonPrefixes
+				isTruncated, nextMarker = aws.BoolValue(resp.IsTruncated), resp.NextContinuationToken
+			}
+		} else {
+			req := s3.ListObjectsInput{
+				Bucket:    &f.bucket,
+				Delimiter: &delimiter,
+				Prefix:    &root,
+				MaxKeys:   &maxKeys,
+				Marker:    marker,
+			}
+			var resp *s3.ListObjectsOutput
+			err = f.pacer.Call(func() (bool, error) {
+				resp, err = f.c.ListObjects(&req)
+				return shouldRetry(err)
+			})
+			if err == nil {
+				contents, commonPrefixes = resp.Contents, resp.CommonPrefixes
+				isTruncated, nextMarker = aws.BoolValue(resp.IsTruncated), resp.NextMarker
+			}
 		}
-		var resp *s3.ListObjectsOutput
-		var err error
-		err = f.pacer.Call(func() (bool, error) {
-			resp, err = f.c.ListObjects(&req)
-			return shouldRetry(err)
-		})
 		if err != nil {
 			if awsErr, ok := err.(awserr.RequestFailure); ok {
 				if awsErr.StatusCode() == http.StatusNotFound {
@@ -919,7 +1023,7 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 		}
 		rootLength := len(f.root)
 		if !recurse {
-			for _, commonPrefix := range resp.CommonPrefixes {
+			for _, commonPrefix := range commonPrefixes {
 				if commonPrefix.Prefix == nil {
 					fs.Logf(f, "Nil common prefix received")
 					continue
@@ -939,7 +1043,7 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 				}
 			}
 		}
-		for _, object := range resp.Contents {
+		for _, object := range contents {
 			key := aws.StringValue(object.Key)
 			if !strings.HasPrefix(key, f.root) {
 				fs.Logf(f, "Odd name received %q", key)
@@ -963,17 +1067,20 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 				return err
 			}
 		}
-		if !aws.BoolValue(resp.IsTruncated) {
+		if !isTruncated {
 			break
 		}
 		// Use NextMarker if set, otherwise use last Key
-		if resp.NextMarker == nil || *resp.NextMarker == "" {
-			if len(resp.Contents) == 0 {
+		if nextMarker == nil || *nextMarker == "" {
+			if f.opt.ListVersion == 2 {
+				return errors.New("s3 protocol error: received listing v2 with IsTruncated set and no NextContinuationToken")
+			}
+			if len(contents) == 0 {
 				return errors.New("s3 protocol error: received listing with IsTruncated set, no NextMarker and no Contents")
 			}
-			marker = resp.Contents[len(resp.Contents)-1].Key
+			marker = contents[len(contents)-1].Key
 		} else {
-			marker = resp.NextMarker
+			marker = nextMarker
 		}
 	}
 	return nil
@@ -1241,7 +1348,7 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
 	}
 	err = f.pacer.Call(func() (bool, error) {
-		_, err = f.c.CopyObject(&req)
+		_, err = f.c.CopyObjectWithContext(aws.BackgroundContext(), &req, f.objectLockHeaders)
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -1250,11 +1357,196 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	return f.NewObject(remote)
 }
 
+// objectLockHeaders is a request.Option which sets the Object Lock
+// headers configured on requests which make new objects
+func (f *Fs) objectLockHeaders(r *request.Request) {
+	switch r.Operation.Name {
+	case "PutObject", "CreateMultipartUpload", "CopyObject":
+	default:
+		return
+	}
+	if f.opt.ObjectLockMode != "" {
+		until := time.Now().Add(time.Duration(f.opt.ObjectLockRetention))
+		r.HTTPRequest.Header.Set("X-Amz-Object-Lock-Mode", f.opt.ObjectLockMode)
+		r.HTTPRequest.Header.Set("X-Amz-Object-Lock-Retain-Until-Date", until.UTC().Format(time.RFC3339))
+	}
+	if f.opt.ObjectLockLegalHold {
+		r.HTTPRequest.Header.Set("X-Amz-Object-Lock-Legal-Hold", "ON")
+	}
+}
+
+// ListUploads lists the multipart uploads under the root which have
+// been started but not completed or aborted
+func (f *Fs) ListUploads() (uploads []fs.PendingUpload, err error) {
+	req := s3.ListMultipartUploadsInput{
+		Bucket: &f.bucket,
+		Prefix: &f.root,
+	}
+	for {
+		var resp *s3.ListMultipartUploadsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListMultipartUploads(&req)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			if awsErr, ok := err.(awserr.RequestFailure); ok && awsErr.StatusCode() == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, upload := range resp.Uploads {
+			uploads = append(uploads, fs.PendingUpload{
+				Remote:  strings.TrimPrefix(aws.StringValue(upload.Key), f.root),
+				ID:      aws.StringValue(upload.UploadId),
+				Started: aws.TimeValue(upload.Initiated),
+			})
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.UploadIdMarker = resp.NextUploadIdMarker
+	}
+	return uploads, nil
+}
+
+// AbortUpload aborts a multipart upload returned by ListUploads
+func (f *Fs) AbortUpload(upload fs.PendingUpload) error {
+	key := f.root + upload.Remote
+	req := s3.AbortMultipartUploadInput{
+		Bucket:   &f.bucket,
+		Key:      &key,
+		UploadId: &upload.ID,
+	}
+	return f.pacer.Call(func() (bool, error) {
+		_, err := f.c.AbortMultipartUpload(&req)
+		return shouldRetry(err)
+	})
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "restore",
+	Short: "Restore objects from GLACIER to normal storage",
+	Long: `This command can be used to restore one or more objects from GLACIER
+to normal storage.
+
+Usage Examples:
+
+    rclone backend restore s3:bucket/path/to/directory [-o priority=PRIORITY] [-o lifetime=DAYS]
+    rclone backend restore s3:bucket path/to/file1 path/to/file2 [-o priority=PRIORITY] [-o lifetime=DAYS]
+
+With no arguments every object under the remote is restored,
+otherwise just the objects named, relative to the remote.
+
+This command returns a list of status dictionaries with Remote and
+Status keys.  The Status will be OK if it was successful or an error
+message if not.
+
+    [
+        {
+            "Status": "OK",
+            "Remote": "test.txt"
+        },
+        {
+            "Status": "OK",
+            "Remote": "test/file4.txt"
+        }
+    ]
+`,
+	Opts: map[string]string{
+		"priority":    "Priority of restore: Standard|Expedited|Bulk",
+		"lifetime":    "Lifetime of the active copy in days",
+		"description": "The optional description for the job.",
+	},
+}}
+
+// RestoreStatus is the result of restoring a single object
+type RestoreStatus struct {
+	Status string
+	Remote string
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "restore":
+		return f.restore(arg, opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// restore restores the objects named in arg, or all the objects
+// under the root if there are none, from GLACIER
+func (f *Fs) restore(arg []string, opt map[string]string) (out []RestoreStatus, err error) {
+	if f.bucket == "" {
+		return nil, fs.ErrorListBucketRequired
+	}
+	req := s3.RestoreObjectInput{
+		Bucket: &f.bucket,
+		RestoreRequest: &s3.RestoreRequest{
+			GlacierJobParameters: &s3.GlacierJobParameters{
+				Tier: aws.String(s3.TierStandard),
+			},
+		},
+	}
+	if lifetime := opt["lifetime"]; lifetime != "" {
+		days, err := strconv.ParseInt(lifetime, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad lifetime")
+		}
+		req.RestoreRequest.Days = &days
+	}
+	if priority := opt["priority"]; priority != "" {
+		req.RestoreRequest.GlacierJobParameters.Tier = &priority
+	}
+	if description := opt["description"]; description != "" {
+		req.RestoreRequest.Description = &description
+	}
+	remotes := arg
+	if len(remotes) == 0 {
+		err = f.list("", true, func(remote string, object *s3.Object, isDirectory bool) error {
+			if !isDirectory {
+				remotes = append(remotes, remote)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	out = []RestoreStatus{}
+	for _, remote := range remotes {
+		key := f.root + remote
+		reqCopy := req
+		reqCopy.Key = &key
+		err := f.pacer.Call(func() (bool, error) {
+			_, err := f.c.RestoreObject(&reqCopy)
+			return shouldRetry(err)
+		})
+		st := RestoreStatus{Remote: remote, Status: "OK"}
+		if err != nil {
+			st.Status = err.Error()
+			fs.Errorf(remote, "Failed to restore: %v", err)
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -1284,7 +1576,7 @@ func (o *Object) Hash(t hash.Type) (string, error) {
 	}
 	hash := strings.Trim(strings.ToLower(o.etag), `"`)
 	// Check the etag is a valid md5sum
-	if !matchMd5.MatchString(hash) {
+	if !o.fs.etagIsMD5 || !matchMd5.MatchString(hash) {
 		err := o.readMetaData()
 		if err != nil {
 			return "", err
@@ -1321,9 +1613,14 @@ func (o *Object) readMetaData() (err error) {
 		Key:    &key,
 	}
 	var resp *s3.HeadObjectOutput
+	var header http.Header
 	err = o.fs.pacer.Call(func() (bool, error) {
-		var err error
-		resp, err = o.fs.c.HeadObject(&req)
+		var httpReq *request.Request
+		httpReq, resp = o.fs.c.HeadObjectRequest(&req)
+		err := httpReq.Send()
+		if httpReq.HTTPResponse != nil {
+			header = httpReq.HTTPResponse.Header
+		}
 		return shouldRetry(err)
 	})
 	if err != nil {
@@ -1350,9 +1647,37 @@ func (o *Object) readMetaData() (err error) {
 		o.lastModified = *resp.LastModified
 	}
 	o.mimeType = aws.StringValue(resp.ContentType)
+	o.storageClass = aws.StringValue(resp.StorageClass)
+	// The Object Lock fields aren't in HeadObjectOutput so read them
+	// from the headers
+	o.lockedUntil = time.Time{}
+	if until := header.Get("X-Amz-Object-Lock-Retain-Until-Date"); until != "" {
+		o.lockedUntil, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			fs.Debugf(o, "Failed to parse Object Lock retain until date: %v", err)
+		}
+	}
+	o.legalHold = header.Get("X-Amz-Object-Lock-Legal-Hold") == "ON"
 	return nil
 }
 
+// LockedUntil returns the time the object is locked until and
+// whether it has a legal hold
+//
+// This reads the metadata only if Object Lock is configured as
+// objects can only be locked in buckets which use it
+func (o *Object) LockedUntil() (until time.Time, legalHold bool) {
+	if o.fs.opt.ObjectLockMode == "" && !o.fs.opt.ObjectLockLegalHold {
+		return time.Time{}, false
+	}
+	err := o.readMetaData()
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Time{}, false
+	}
+	return o.lockedUntil, o.legalHold
+}
+
 // ModTime returns the modification time of the object
 //
 // It attempts to read the objects mtime and if that isn't present the
@@ -1416,6 +1741,52 @@ func (o *Object) SetModTime(modTime time.Time) error {
 	return err
 }
 
+// SetTier changes the storage class of the object by copying it to
+// itself
+func (o *Object) SetTier(tier string) error {
+	tier = strings.ToUpper(tier)
+	if o.GetTier() == tier {
+		return nil
+	}
+	if o.bytes >= maxSizeForCopy {
+		return errors.Errorf("can't change the storage class of objects bigger than %v", fs.SizeSuffix(maxSizeForCopy))
+	}
+	key := o.fs.root + o.remote
+	sourceKey := o.fs.bucket + "/" + key
+	req := s3.CopyObjectInput{
+		Bucket:            &o.fs.bucket,
+		ACL:               &o.fs.opt.ACL,
+		Key:               &key,
+		CopySource:        aws.String(pathEscape(sourceKey)),
+		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
+		StorageClass:      &tier,
+	}
+	if o.fs.opt.ServerSideEncryption != "" {
+		req.ServerSideEncryption = &o.fs.opt.ServerSideEncryption
+	}
+	if o.fs.opt.SSEKMSKeyID != "" {
+		req.SSEKMSKeyId = &o.fs.opt.SSEKMSKeyID
+	}
+	err := o.fs.pacer.Call(func() (bool, error) {
+		_, err := o.fs.c.CopyObjectWithContext(aws.BackgroundContext(), &req, o.fs.objectLockHeaders)
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set storage class")
+	}
+	o.storageClass = tier
+	fs.Debugf(o, "Changed storage class to %s", tier)
+	return nil
+}
+
+// GetTier returns the storage class of the object
+func (o *Object) GetTier() string {
+	if o.storageClass == "" {
+		return s3.ObjectStorageClassStandard
+	}
+	return o.storageClass
+}
+
 // Storable raturns a boolean indicating if this object is storable
 func (o *Object) Storable() bool {
 	return true
@@ -1465,11 +1836,11 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	modTime := src.ModTime()
 	size := src.Size()
 
+	concurrency := o.fs.uploadTuner.Concurrency()
 	uploader := s3manager.NewUploader(o.fs.ses, func(u *s3manager.Uploader) {
-		u.Concurrency = o.fs.opt.UploadConcurrency
+		u.Concurrency = concurrency
 		u.LeavePartsOnError = false
 		u.S3 = o.fs.c
-		u.PartSize = int64(o.fs.opt.ChunkSize)
 
 		if size == -1 {
 			// Make parts as small as possible while still being able to upload to the
@@ -1478,10 +1849,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 			return
 		}
 		// Adjust PartSize until the number of parts is small enough.
-		if size/u.PartSize >= s3manager.MaxUploadParts {
-			// Calculate partition size rounded up to the nearest MB
-			u.PartSize = (((size / s3manager.MaxUploadParts) >> 20) + 1) << 20
-		}
+		u.PartSize = int64(chunksize.Calculator(size, s3manager.MaxUploadParts, o.fs.opt.ChunkSize, concurrency))
 	})
 
 	// Set the mtime in the meta data
@@ -1523,13 +1891,18 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	if o.fs.opt.StorageClass != "" {
 		req.StorageClass = &o.fs.opt.StorageClass
 	}
+	start := time.Now()
 	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
-		_, err = uploader.Upload(&req)
+		_, err = uploader.Upload(&req, s3manager.WithUploaderRequestOptions(o.fs.objectLockHeaders))
 		return shouldRetry(err)
 	})
 	if err != nil {
 		return err
 	}
+	if size > uploader.PartSize {
+		parts := (size + uploader.PartSize - 1) / uploader.PartSize
+		o.fs.uploadTuner.Record(size, int(parts), concurrency, time.Since(start))
+	}
 
 	// Read the metadata from the newly created object
 	o.meta = nil // wipe old metadata
@@ -1563,10 +1936,15 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Copier       = &Fs{}
+	_ fs.PutStreamer  = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.UploadLister = &Fs{}
+	_ fs.Commander    = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
+	_ fs.Locker       = &Object{}
+	_ fs.SetTierer    = &Object{}
+	_ fs.GetTierer    = &Object{}
 )
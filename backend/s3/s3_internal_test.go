@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetQuirks(t *testing.T) {
+	for _, test := range []struct {
+		opt                Options
+		wantListVersion    int
+		wantForcePathStyle bool
+		wantV2Auth         bool
+	}{
+		{Options{Provider: "AWS", ForcePathStyle: true}, 2, true, false},
+		{Options{Provider: "Ceph", ForcePathStyle: true}, 1, true, false},
+		{Options{Provider: "Alibaba", ForcePathStyle: true}, 1, false, false},
+		{Options{Provider: "Other", ForcePathStyle: true}, 1, true, false},
+		{Options{Provider: "", ForcePathStyle: false}, 1, false, false},
+		{Options{Provider: "AWS", ListVersion: 1, ForcePathStyle: true}, 1, true, false},
+		{Options{Provider: "Other", Region: "other-v2-signature"}, 1, false, true},
+		{Options{Provider: "Minio", V2Auth: true}, 2, false, true},
+	} {
+		opt := test.opt
+		setQuirks(&opt)
+		what := test.opt.Provider
+		assert.Equal(t, test.wantListVersion, opt.ListVersion, what)
+		assert.Equal(t, test.wantForcePathStyle, opt.ForcePathStyle, what)
+		assert.Equal(t, test.wantV2Auth, opt.V2Auth, what)
+	}
+}
+
+func TestEtagIsMD5(t *testing.T) {
+	assert.True(t, etagIsMD5(&Options{}))
+	assert.True(t, etagIsMD5(&Options{ServerSideEncryption: "AES256"}))
+	assert.False(t, etagIsMD5(&Options{ServerSideEncryption: "aws:kms"}))
+	assert.False(t, etagIsMD5(&Options{SSEKMSKeyID: "key"}))
+}
+
+func TestGetTier(t *testing.T) {
+	assert.Equal(t, "STANDARD", (&Object{}).GetTier())
+	assert.Equal(t, "STANDARD_IA", (&Object{storageClass: "STANDARD_IA"}).GetTier())
+}
@@ -0,0 +1,304 @@
+// Package snapshot provides a read only remote made from a listing
+// saved with "rclone lsjson --snapshot".
+//
+// The objects have the size, modification time and hashes they had
+// when the snapshot was made but no data, so the remote can be
+// checked or synced against to find the changes since.
+package snapshot
+
+import (
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+)
+
+var (
+	errorReadOnly = errors.New("snapshot remotes are read only")
+	errorNoData   = errors.New("snapshot remotes have no file data")
+)
+
+func init() {
+	fsi := &fs.RegInfo{
+		Name:        "snapshot",
+		Description: "Listing snapshot made with lsjson --snapshot",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "file",
+			Help:     "Path of the snapshot made with \"rclone lsjson -R --snapshot\".",
+			Required: true,
+		}},
+	}
+	fs.Register(fsi)
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	File string `config:"file"`
+}
+
+// Fs represents a snapshot
+type Fs struct {
+	name     string
+	root     string
+	opt      Options      // options for this backend
+	features *fs.Features // optional features
+	hashes   hash.Set     // hashes found in the snapshot
+	dirs     map[string]fs.DirEntries
+	objects  map[string]*Object
+}
+
+// Object describes an object in the snapshot
+type Object struct {
+	fs   *Fs
+	item *operations.ListJSONItem
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.File == "" {
+		return nil, errors.New("snapshot file not set")
+	}
+	items, err := operations.ReadListJSONFile(opt.File)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read snapshot")
+	}
+	root = strings.Trim(root, "/")
+	f := &Fs{
+		name:    name,
+		root:    root,
+		opt:     *opt,
+		hashes:  hash.Set(hash.None),
+		dirs:    map[string]fs.DirEntries{"": nil},
+		objects: map[string]*Object{},
+	}
+	f.features = (&fs.Features{
+		ReadMimeType: true,
+	}).Fill(f)
+
+	// If the root is a file then point to the directory it is in
+	for _, item := range items {
+		if item.Path == root && !item.IsDir && root != "" {
+			newRoot := path.Dir(root)
+			if newRoot == "." {
+				newRoot = ""
+			}
+			f.root = newRoot
+			break
+		}
+	}
+	prefix := ""
+	if f.root != "" {
+		prefix = f.root + "/"
+	}
+	for _, item := range items {
+		if !strings.HasPrefix(item.Path, prefix) || item.Path == f.root {
+			continue
+		}
+		remote := item.Path[len(prefix):]
+		if item.IsDir {
+			f.addDir(remote, time.Time(item.ModTime))
+			continue
+		}
+		relItem := *item
+		relItem.Path = remote
+		o := &Object{fs: f, item: &relItem}
+		f.objects[remote] = o
+		f.addEntry(o)
+		for name := range item.Hashes {
+			var ht hash.Type
+			if ht.Set(name) == nil {
+				f.hashes.Add(ht)
+			}
+		}
+	}
+	if f.root != root {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// addEntry adds entry to the listing of its parent directory making
+// the directories above it if necessary
+func (f *Fs) addEntry(entry fs.DirEntry) {
+	parent := path.Dir(entry.Remote())
+	if parent == "." {
+		parent = ""
+	}
+	if _, ok := f.dirs[parent]; !ok {
+		f.addDir(parent, time.Time{})
+	}
+	f.dirs[parent] = append(f.dirs[parent], entry)
+}
+
+// addDir adds the directory dir if it doesn't already exist
+func (f *Fs) addDir(dir string, modTime time.Time) {
+	if _, ok := f.dirs[dir]; ok {
+		return
+	}
+	f.dirs[dir] = nil
+	f.addEntry(fs.NewDir(dir, modTime))
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if f.root == "" {
+		return "snapshot " + f.opt.File
+	}
+	return "snapshot " + f.opt.File + " path " + f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the modification times in the snapshot.  They are
+// stored as they were read so are as precise as the remote they came
+// from.
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the hashes found in the snapshot
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// List the objects and directories in dir into entries
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, ok := f.dirs[dir]
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	return append(fs.DirEntries(nil), entries...), nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	o, ok := f.objects[remote]
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return o, nil
+}
+
+// Put can't be done on a snapshot
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir can't be done on a snapshot
+func (f *Fs) Mkdir(dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir can't be done on a snapshot
+func (f *Fs) Rmdir(dir string) error {
+	return errorReadOnly
+}
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.item.Path
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.item.Path
+}
+
+// Hash returns the hash of type t from the snapshot, or "" if it
+// wasn't recorded for this object
+func (o *Object) Hash(t hash.Type) (string, error) {
+	if !o.fs.hashes.Contains(t) {
+		return "", hash.ErrUnsupported
+	}
+	return o.item.Hashes[t.String()], nil
+}
+
+// Size returns the size of the object when the snapshot was made
+func (o *Object) Size() int64 {
+	return o.item.Size
+}
+
+// ModTime returns the modification time of the object when the
+// snapshot was made
+func (o *Object) ModTime() time.Time {
+	return time.Time(o.item.ModTime)
+}
+
+// SetModTime can't be done on a snapshot
+func (o *Object) SetModTime(modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns a boolean showing whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open fails as there is no data in a snapshot
+func (o *Object) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	return nil, errorNoData
+}
+
+// Update can't be done on a snapshot
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove can't be done on a snapshot
+func (o *Object) Remove() error {
+	return errorReadOnly
+}
+
+// MimeType of the Object when the snapshot was made if known
+func (o *Object) MimeType() string {
+	return o.item.MimeType
+}
+
+// ID of the Object when the snapshot was made if known
+func (o *Object) ID() string {
+	return o.item.ID
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = &Fs{}
+	_ fs.Object    = &Object{}
+	_ fs.MimeTyper = &Object{}
+	_ fs.IDer      = &Object{}
+)
@@ -0,0 +1,102 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSnapshot = `[
+{"Path":"file.txt","Name":"file.txt","Size":6,"MimeType":"text/plain","ModTime":"2001-02-03T04:05:06.499999999Z","IsDir":false,"Hashes":{"MD5":"b1946ac92492d2347c6235b4d2611184"}},
+{"Path":"dir","Name":"dir","Size":-1,"ModTime":"2011-12-25T12:59:59.123456789Z","IsDir":true},
+{"Path":"dir/sub/deep.txt","Name":"deep.txt","Size":1,"ModTime":"2011-12-25T12:59:59.123456789Z","IsDir":false,"ID":"potato"}
+]`
+
+// writeSnapshot writes the test snapshot compressed with gzip
+// returning its name
+func writeSnapshot(t *testing.T, dir string) string {
+	name := filepath.Join(dir, "snap.json.gz")
+	fd, err := os.Create(name)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(fd)
+	_, err = gz.Write([]byte(testSnapshot))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, fd.Close())
+	return name
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-snapshot")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	m := configmap.Simple{"file": writeSnapshot(t, dir)}
+
+	f, err := NewFs("snap", "", m)
+	require.NoError(t, err)
+	assert.True(t, f.Hashes().Contains(hash.MD5))
+	assert.False(t, f.Hashes().Contains(hash.SHA1))
+
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+	entries.ForObject(func(o fs.Object) {
+		assert.Equal(t, "file.txt", o.Remote())
+		assert.Equal(t, int64(6), o.Size())
+		assert.True(t, o.ModTime().Equal(time.Date(2001, 2, 3, 4, 5, 6, 499999999, time.UTC)))
+		sum, err := o.Hash(hash.MD5)
+		require.NoError(t, err)
+		assert.Equal(t, "b1946ac92492d2347c6235b4d2611184", sum)
+		_, err = o.Hash(hash.SHA1)
+		assert.Equal(t, hash.ErrUnsupported, err)
+		assert.Equal(t, "text/plain", fs.MimeType(o))
+		_, err = o.Open()
+		assert.Equal(t, errorNoData, err)
+		assert.Equal(t, errorReadOnly, o.Remove())
+	})
+	entries.ForDir(func(d fs.Directory) {
+		assert.Equal(t, "dir", d.Remote())
+	})
+
+	// the directory which wasn't in the snapshot is made
+	entries, err = f.List("dir")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "dir/sub", entries[0].Remote())
+
+	_, err = f.List("potato")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	_, err = f.NewObject("potato")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// a root inside the snapshot
+	f, err = NewFs("snap", "dir/sub", m)
+	require.NoError(t, err)
+	o, err := f.NewObject("deep.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "deep.txt", o.Remote())
+	assert.Equal(t, "potato", o.(fs.IDer).ID())
+
+	// a root pointing to a file
+	f, err = NewFs("snap", "dir/sub/deep.txt", m)
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "dir/sub", f.Root())
+	_, err = f.NewObject("deep.txt")
+	require.NoError(t, err)
+
+	// missing file
+	_, err = NewFs("snap", "", configmap.Simple{"file": filepath.Join(dir, "potato")})
+	assert.Error(t, err)
+}
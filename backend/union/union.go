@@ -12,6 +12,7 @@ import (
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/pkg/errors"
 )
 
@@ -44,6 +45,16 @@ type Fs struct {
 	remotes  []fs.Fs      // slice of remotes
 }
 
+// Object describes a union Object
+//
+// This is a wrapped object which copies itself up to the remote which
+// is written to when it is modified
+type Object struct {
+	fs.Object
+	fs       *Fs  // what this object is part of
+	writable bool // set if the object is on the remote written to
+}
+
 // Name of the remote (as passed into NewFs)
 func (f *Fs) Name() string {
 	return f.name
@@ -64,6 +75,14 @@ func (f *Fs) Features() *fs.Features {
 	return f.features
 }
 
+// UnWrap returns the remote which is written to
+//
+// This means limits such as max_transfers set on that remote apply to
+// the union too.
+func (f *Fs) UnWrap() fs.Fs {
+	return f.remotes[len(f.remotes)-1]
+}
+
 // Rmdir removes the root directory of the Fs object
 func (f *Fs) Rmdir(dir string) error {
 	return f.remotes[len(f.remotes)-1].Rmdir(dir)
@@ -86,7 +105,25 @@ func (f *Fs) Mkdir(dir string) error {
 // will return the object and the error, otherwise will return
 // nil and the error
 func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	return f.remotes[len(f.remotes)-1].Put(in, src, options...)
+	o, err := f.wRemote().Put(in, src, options...)
+	if o != nil {
+		return f.newObject(o, true), err
+	}
+	return nil, err
+}
+
+// wRemote returns the remote which is written to
+func (f *Fs) wRemote() fs.Fs {
+	return f.remotes[len(f.remotes)-1]
+}
+
+// newObject wraps o which is on the remote written to if writable is set
+func (f *Fs) newObject(o fs.Object, writable bool) *Object {
+	return &Object{
+		Object:   o,
+		fs:       f,
+		writable: writable,
+	}
 }
 
 // List the objects and directories in dir into entries.  The
@@ -101,7 +138,8 @@ func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.
 func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 	set := make(map[string]fs.DirEntry)
 	found := false
-	for _, remote := range f.remotes {
+	for i, remote := range f.remotes {
+		writable := i == len(f.remotes)-1
 		var remoteEntries, err = remote.List(dir)
 		if err == fs.ErrorDirNotFound {
 			continue
@@ -111,6 +149,9 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 		}
 		found = true
 		for _, remoteEntry := range remoteEntries {
+			if o, ok := remoteEntry.(fs.Object); ok {
+				remoteEntry = f.newObject(o, writable)
+			}
 			set[remoteEntry.Remote()] = remoteEntry
 		}
 	}
@@ -134,11 +175,20 @@ func (f *Fs) NewObject(path string) (fs.Object, error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "NewObject failed on %v", remote)
 		}
-		return obj, nil
+		return f.newObject(obj, i == 0), nil
 	}
 	return nil, fs.ErrorObjectNotFound
 }
 
+// About gets quota information from the remote which is written to
+func (f *Fs) About() (*fs.Usage, error) {
+	do := f.remotes[len(f.remotes)-1].Features().About
+	if do == nil {
+		return nil, errors.New("about not supported by the remote written to")
+	}
+	return do()
+}
+
 // Precision is the greatest Precision of all remotes
 func (f *Fs) Precision() time.Duration {
 	var greatestPrecision time.Duration
@@ -150,6 +200,64 @@ func (f *Fs) Precision() time.Duration {
 	return greatestPrecision
 }
 
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// UnWrap returns the Object that this Object is wrapping or nil if it
+// isn't wrapping anything
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// copyUp puts the data from in to the remote written to, replacing the
+// object wrapped, so that it can be modified there leaving the read
+// only copy untouched.
+func (o *Object) copyUp(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	// make sure the object keeps its name whatever src is called
+	src = object.NewStaticObjectInfo(o.Remote(), src.ModTime(), src.Size(), true, nil, o.fs)
+	newObj, err := o.fs.wRemote().Put(in, src, options...)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy up to the remote written to")
+	}
+	fs.Debugf(o, "Copied up to %v", o.fs.wRemote())
+	o.Object = newObj
+	o.writable = true
+	return nil
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// If the object isn't on the remote written to then it is copied up
+// there instead.
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.writable {
+		return o.Object.Update(in, src, options...)
+	}
+	return o.copyUp(in, src, options...)
+}
+
+// SetModTime sets the modification time of the object
+//
+// If the object isn't on the remote written to then it is copied up
+// there with the new modification time.
+func (o *Object) SetModTime(modTime time.Time) error {
+	if o.writable {
+		return o.Object.SetModTime(modTime)
+	}
+	in, err := o.Object.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open object to copy up")
+	}
+	err = o.copyUp(in, object.NewStaticObjectInfo(o.Remote(), modTime, o.Size(), true, nil, o.fs))
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 // NewFs constructs an Fs from the path.
 //
 // The returned Fs is the actual Fs, referenced by remote in the config
@@ -216,6 +324,10 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	for _, remote := range f.remotes {
 		features = features.Mask(remote)
 	}
+	// About only needs the remote which is written to
+	if f.remotes[len(f.remotes)-1].Features().About != nil {
+		features.About = f.About
+	}
 	f.features = features
 
 	return f, nil
@@ -223,5 +335,9 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs = &Fs{}
+	_ fs.Fs              = &Fs{}
+	_ fs.UnWrapper       = &Fs{}
+	_ fs.Abouter         = &Fs{}
+	_ fs.Object          = &Object{}
+	_ fs.ObjectUnWrapper = &Object{}
 )
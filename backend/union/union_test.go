@@ -2,10 +2,20 @@
 package union_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/backend/union"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestIntegration runs integration tests against the remote
@@ -16,3 +26,46 @@ func TestIntegration(t *testing.T) {
 		SkipFsMatch: true,
 	})
 }
+
+// TestCopyUp checks objects on the read only remotes are copied up to
+// the remote written to when they are modified
+func TestCopyUp(t *testing.T) {
+	lower, err := ioutil.TempDir("", "rclone-union-lower")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(lower) }()
+	upper, err := ioutil.TempDir("", "rclone-union-upper")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(upper) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lower, "file.txt"), []byte("lower"), 0600))
+
+	f, err := union.NewFs("TestCopyUp", "", configmap.Simple{
+		"remotes": lower + " " + upper,
+	})
+	require.NoError(t, err)
+
+	o, err := f.NewObject("file.txt")
+	require.NoError(t, err)
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", modTime, 5, true, nil, nil)
+	require.NoError(t, o.Update(bytes.NewBufferString("upper"), src))
+	assert.Equal(t, f, o.Fs())
+
+	data, err := ioutil.ReadFile(filepath.Join(lower, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "lower", string(data))
+	data, err = ioutil.ReadFile(filepath.Join(upper, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "upper", string(data))
+
+	// SetModTime copies up too
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lower, "file2.txt"), []byte("lower2"), 0600))
+	o, err = f.NewObject("file2.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.SetModTime(modTime))
+	fi, err := os.Stat(filepath.Join(upper, "file2.txt"))
+	require.NoError(t, err)
+	assert.True(t, fi.ModTime().Equal(modTime))
+	data, err = ioutil.ReadFile(filepath.Join(upper, "file2.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "lower2", string(data))
+}
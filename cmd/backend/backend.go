@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	options []string
+	useJSON bool
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	commandDefinition.Flags().StringArrayVarP(&options, "option", "o", options, "Option in the form name=value or name.")
+	commandDefinition.Flags().BoolVarP(&useJSON, "json", "", useJSON, "Always output in JSON format.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "backend <command> remote:path [opts] <args>",
+	Short: `Run a backend specific command.`,
+	Long: `
+This runs a backend specific command.  The commands themselves (except
+for "help") are defined by the backends and you should see the backend
+docs for definitions.  They are for operations which only make sense
+for a particular provider, eg restoring objects from Glacier on s3.
+
+You can discover what commands a backend implements by using
+
+    rclone backend help remote:
+    rclone backend help <backendname>
+
+Pass options to the backend command with -o.  These should be
+key=value or key, eg:
+
+    rclone backend restore s3:bucket/path -o priority=Standard -o lifetime=1
+
+Pass arguments to the backend by placing them on the end of the line
+
+    rclone backend COMMAND remote:path arg1 arg2 arg3
+
+The output is shown as text if the command returns a string or a list
+of strings, otherwise it is shown as JSON.  Use --json to always show
+JSON.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 1e6, command, args)
+		name, remote := args[0], args[1]
+		cmd.Run(false, false, command, func() error {
+			if name == "help" {
+				return showHelp(os.Stdout, remote)
+			}
+			opt, err := parseOptions(options)
+			if err != nil {
+				return err
+			}
+			fsInfo, _, _, _ := fs.ParseRemote(remote)
+			f := cmd.NewFsSrc([]string{remote})
+			doCommand := f.Features().Command
+			if doCommand == nil {
+				return errors.Errorf("%v doesn't support backend commands", f)
+			}
+			out, err := doCommand(name, args[2:], opt)
+			if err == fs.ErrorCommandNotFound {
+				help := ""
+				if fsInfo != nil {
+					help = fmt.Sprintf(` - see "rclone backend help %s"`, fsInfo.Name)
+				}
+				return errors.Errorf("%v: command %q not found%s", f, name, help)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "command %q failed", name)
+			}
+			return writeOutput(os.Stdout, out)
+		})
+	},
+}
+
+// parseOptions parses the -o options into a map
+func parseOptions(options []string) (map[string]string, error) {
+	opt := make(map[string]string, len(options))
+	for _, option := range options {
+		equals := strings.IndexRune(option, '=')
+		name, value := option, ""
+		if equals >= 0 {
+			name, value = option[:equals], option[equals+1:]
+		}
+		if name == "" {
+			return nil, errors.Errorf("bad option %q", option)
+		}
+		opt[name] = value
+	}
+	return opt, nil
+}
+
+// writeOutput writes the result of a command to w as text if
+// possible, or JSON if not or --json is set
+func writeOutput(w io.Writer, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	if !useJSON {
+		switch x := out.(type) {
+		case string:
+			_, err := fmt.Fprintln(w, x)
+			return err
+		case []string:
+			for _, line := range x {
+				if _, err := fmt.Fprintln(w, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(out)
+}
+
+// showHelp shows the help for the backend commands of backend, which
+// is a backend name or a remote
+func showHelp(w io.Writer, backend string) error {
+	fsInfo, err := fs.Find(backend)
+	if err != nil {
+		if !strings.Contains(backend, ":") {
+			return err
+		}
+		fsInfo, _, _, err = fs.ParseRemote(backend)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't find backend for %q", backend)
+		}
+	}
+	cmds := fsInfo.CommandHelp
+	name := fsInfo.Name
+	if len(cmds) == 0 {
+		return errors.Errorf("%s backend has no commands", name)
+	}
+	cmds = append([]fs.CommandHelp(nil), cmds...)
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	fmt.Fprintf(w, "### Backend commands\n\n")
+	fmt.Fprintf(w, "Here are the commands specific to the %s backend.\n\n", name)
+	fmt.Fprintf(w, "Run them with\n\n")
+	fmt.Fprintf(w, "    rclone backend COMMAND remote:\n\n")
+	fmt.Fprintf(w, "The help below will explain what arguments each command takes.\n\n")
+	fmt.Fprintf(w, "See [the \"rclone backend\" command](/commands/rclone_backend/) for more\n")
+	fmt.Fprintf(w, "info on how to pass options and arguments.\n\n")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "#### %s\n\n", c.Name)
+		fmt.Fprintf(w, "%s\n\n", c.Short)
+		fmt.Fprintf(w, "    rclone backend %s remote: [options] [<arguments>+]\n\n", c.Name)
+		if c.Long != "" {
+			fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(c.Long))
+		}
+		if len(c.Opts) != 0 {
+			fmt.Fprintf(w, "Options:\n\n")
+			keys := make([]string, 0, len(c.Opts))
+			for key := range c.Opts {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(w, "- %q: %s\n", key, c.Opts[key])
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"bytes"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	opt, err := parseOptions([]string{"a=b", "c", "d=e=f", "g="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "b", "c": "", "d": "e=f", "g": ""}, opt)
+
+	_, err = parseOptions([]string{"=potato"})
+	assert.Error(t, err)
+}
+
+func TestWriteOutput(t *testing.T) {
+	for _, test := range []struct {
+		in   interface{}
+		json bool
+		want string
+	}{
+		{nil, false, ""},
+		{"hello", false, "hello\n"},
+		{[]string{"one", "two"}, false, "one\ntwo\n"},
+		{[]string{"one", "two"}, true, "[\n\t\"one\",\n\t\"two\"\n]\n"},
+		{map[string]int{"a": 1}, false, "{\n\t\"a\": 1\n}\n"},
+	} {
+		useJSON = test.json
+		var buf bytes.Buffer
+		require.NoError(t, writeOutput(&buf, test.in))
+		assert.Equal(t, test.want, buf.String(), test.in)
+	}
+	useJSON = false
+}
+
+func TestShowHelp(t *testing.T) {
+	fsInfo, err := fs.Find("local")
+	require.NoError(t, err)
+	oldHelp := fsInfo.CommandHelp
+	defer func() { fsInfo.CommandHelp = oldHelp }()
+
+	var buf bytes.Buffer
+	fsInfo.CommandHelp = nil
+	assert.Error(t, showHelp(&buf, "local"))
+
+	fsInfo.CommandHelp = []fs.CommandHelp{{
+		Name:  "potato",
+		Short: "Make potatoes",
+		Long:  "Long help",
+		Opts:  map[string]string{"size": "How big"},
+	}}
+	require.NoError(t, showHelp(&buf, "local"))
+	out := buf.String()
+	assert.Contains(t, out, "#### potato\n\nMake potatoes\n")
+	assert.Contains(t, out, "Long help\n")
+	assert.Contains(t, out, `- "size": How big`)
+
+	assert.Error(t, showHelp(&buf, "notabackend"))
+}
@@ -0,0 +1,70 @@
+package bisync
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/bisync"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	opt = bisync.DefaultOptions()
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	flags := commandDefintion.Flags()
+	flags.BoolVarP(&opt.Resync, "resync", "", opt.Resync, "Make both paths the same ignoring the listings from the last run - needed on the first run")
+	flags.StringVarP(&opt.Conflict, "conflict", "", opt.Conflict, "How to resolve files changed on both paths: newer, keep-both or fail")
+	flags.BoolVarP(&opt.Force, "force", "", opt.Force, "Carry on even if every file on one path was deleted")
+	flags.StringVarP(&opt.WorkDir, "workdir", "", opt.WorkDir, "Directory to store the listings in")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "bisync path1 path2",
+	Short: `Keep two paths in sync, copying changes made on either to the other.`,
+	Long: `
+Bidirectional sync between path1 and path2.  Files added, changed or
+deleted on either path since the last run are added, changed or
+deleted on the other one.
+
+To know what has changed bisync stores listings of both paths after
+each run in the ` + "`" + `--workdir` + "`" + ` (the bisync directory in the rclone cache
+directory by default).  The first run must use ` + "`" + `--resync` + "`" + ` which copies
+the files which are missing or different from path1 to path2 and then
+the files missing from path1 from path2, so where a file is on both
+paths the path1 version is kept.  Use ` + "`" + `--resync` + "`" + ` again if the listings
+are lost or the paths were changed by something else.
+
+The listings stored are the ones the run synced from, updated with the
+changes the run made, so files changed on either path while bisync is
+running are found and synced by the next run.
+
+A file which was changed on one path and deleted on the other is kept
+and copied back.  If a file was changed differently on both paths
+(a conflict) then ` + "`" + `--conflict` + "`" + ` decides what happens
+
+  * ` + "`" + `newer` + "`" + ` - the file with the newest modification time is copied over the older one (the default)
+  * ` + "`" + `keep-both` + "`" + ` - the file on path1 is renamed with ` + "`" + `.conflict1` + "`" + ` on the end and the file on path2 with ` + "`" + `.conflict2` + "`" + ` and both are copied to the other path
+  * ` + "`" + `fail` + "`" + ` - nothing is changed and bisync exits with an error
+
+If every file on one path has been deleted bisync refuses to delete
+them from the other path as that usually means the path wasn't
+available.  Use ` + "`" + `--force` + "`" + ` if they really were deleted.
+
+If there are any errors the listings aren't updated so the changes
+will be found again on the next run.  Only files are synced -
+directories left empty by deletes aren't removed.  Only one bisync of
+the same paths can run at once.
+
+Test first with ` + "`" + `--dry-run` + "`" + ` which shows what would be done without
+changing the paths or the listings.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fs1, fs2 := cmd.NewFsDir(args[:1]), cmd.NewFsDir(args[1:])
+		cmd.Run(true, true, command, func() error {
+			return bisync.Bisync(fs1, fs2, opt)
+		})
+	},
+}
@@ -47,6 +47,11 @@ Or like this to output any .txt files in dir or subdirectories.
 
     rclone --include "*.txt" cat remote:path/to/dir
 
+The files are output in sorted order of their paths, so you can
+rebuild a file which has been split into parts with
+
+    rclone --include "archive.part.*" cat remote:path/to/dir > archive
+
 Use the --head flag to print characters only at the start, --tail for
 the end and --offset and --count to print a section in the middle.
 Note that if offset is negative it will count from the end, so
@@ -31,8 +31,9 @@ the hashes as well.  Use this for a quick check.
 
 If you supply the --download flag, it will download the data from
 both remotes and check them against each other on the fly.  This can
-be useful for remotes that don't support hashes or if you really want
-to check all the data.
+be useful for remotes that don't support hashes (eg crypt), if the
+remotes don't have a hash in common, or if you really want to check
+all the data.  Files whose contents differ are logged as errors.
 
 If you supply the --one-way flag, it will only check that files in source
 match the files in destination, not the other way around. Meaning extra files in
@@ -1,13 +1,23 @@
 package cleanup
 
 import (
+	"fmt"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listUploads = false
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	flagSet := commandDefintion.Flags()
+	flags.BoolVarP(flagSet, &listUploads, "list-uploads", "", listUploads, "List the unfinished multipart uploads instead of cleaning up.")
+	flags.DurationVarP(flagSet, &operations.UploadMaxAge, "max-upload-age", "", operations.UploadMaxAge, "Abort unfinished multipart uploads started longer ago than this.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -16,10 +26,34 @@ var commandDefintion = &cobra.Command{
 	Long: `
 Clean up the remote if possible.  Empty the trash or delete old file
 versions. Not supported by all remotes.
+
+On remotes which upload big files in parts (eg S3 and B2) it also
+aborts multipart uploads which were started but never finished, which
+are otherwise charged for but invisible.  Only uploads started more
+than ` + "`--max-upload-age`" + ` ago (default 24h) are aborted as newer ones may
+still be running.
+
+Use ` + "`--list-uploads`" + ` to list the unfinished uploads without aborting
+them, eg
+
+    rclone cleanup --list-uploads s3:bucket
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
+		if listUploads {
+			cmd.Run(false, false, command, func() error {
+				uploads, err := operations.ListUploads(fsrc)
+				if err != nil {
+					return err
+				}
+				for _, upload := range uploads {
+					fmt.Printf("%s %s\n", upload.Started.Local().Format("2006-01-02 15:04:05"), upload.Remote)
+				}
+				return nil
+			})
+			return
+		}
 		cmd.Run(true, false, command, func() error {
 			return operations.CleanUp(fsrc)
 		})
@@ -27,6 +27,7 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config/configflags"
 	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/cost"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/filter/filterflags"
 	"github.com/ncw/rclone/fs/fserrors"
@@ -64,6 +65,7 @@ const (
 	exitCodeNoRetryError
 	exitCodeFatalError
 	exitCodeTransferExceeded
+	exitCodeDurationExceeded
 )
 
 // Root is the main rclone command
@@ -222,6 +224,15 @@ func NewFsDir(args []string) fs.Fs {
 	return fdst
 }
 
+// NewFsDirOrFile creates a new Fs from the arguments
+//
+// The argument may point to a directory or a file.  If it points to a
+// file then the file name is returned and the Fs is limited to that
+// file.
+func NewFsDirOrFile(args []string) (fs.Fs, string) {
+	return newFsFileAddFilter(args[0])
+}
+
 // NewFsSrcDst creates a new src and dst fs from the arguments
 func NewFsSrcDst(args []string) (fs.Fs, fs.Fs) {
 	fsrc, _ := newFsFileAddFilter(args[0])
@@ -254,6 +265,7 @@ func NewFsSrcDstFiles(args []string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs
 		if dstFileName == "" {
 			log.Fatalf("%q is a directory", args[1])
 		}
+		dstFileName = expandFileName(dstFileName)
 	}
 	fdst, err := fs.NewFs(dstRemote)
 	switch err {
@@ -268,6 +280,21 @@ func NewFsSrcDstFiles(args []string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs
 	return
 }
 
+// expandFileName expands the templates in a destination file name
+// split off a remote if --path-templates is set.  The directory part
+// is expanded when the Fs is made.
+func expandFileName(fileName string) string {
+	if !fs.Config.PathTemplates {
+		return fileName
+	}
+	fileName, err := fs.ExpandPathTemplate(fileName, fs.PathTemplateTime())
+	if err != nil {
+		fs.CountError(err)
+		log.Fatalf("Failed to expand destination file name: %v", err)
+	}
+	return fileName
+}
+
 // NewFsDstFile creates a new dst fs with a destination file name from the arguments
 func NewFsDstFile(args []string) (fdst fs.Fs, dstFileName string) {
 	dstRemote, dstFileName := fspath.Split(args[0])
@@ -277,6 +304,7 @@ func NewFsDstFile(args []string) (fdst fs.Fs, dstFileName string) {
 	if dstFileName == "" {
 		log.Fatalf("%q is a directory", args[0])
 	}
+	dstFileName = expandFileName(dstFileName)
 	fdst = newFsDir(dstRemote)
 	return
 }
@@ -334,6 +362,9 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	if showStats {
 		close(stopStats)
 	}
+	if fs.Config.EstimateCost {
+		cost.Report()
+	}
 	if err != nil {
 		log.Printf("Failed to %s: %v", cmd.Name(), err)
 		resolveExitCode(err)
@@ -413,8 +444,7 @@ func initConfig() {
 	configflags.SetFlags()
 
 	// Load filters
-	var err error
-	filter.Active, err = filter.NewFilter(&filterflags.Opt)
+	err := filterflags.Reload()
 	if err != nil {
 		log.Fatalf("Failed to load filters: %v", err)
 	}
@@ -422,9 +452,22 @@ func initConfig() {
 	// Write the args for debug purposes
 	fs.Debugf("rclone", "Version %q starting with parameters %q", fs.Version, os.Args)
 
+	// Remove temporary files left behind by previous runs
+	fs.CleanTempDir(24 * time.Hour)
+
 	// Start the remote control if configured
 	rc.Start(&rcflags.Opt)
 
+	// Write the recent errors on exit if required
+	if fs.Config.ErrorLog != "" {
+		atexit.Register(func() {
+			err := fs.WriteErrorLog(fs.Config.ErrorLog)
+			if err != nil {
+				fs.Errorf(nil, "Failed to write --error-log: %v", err)
+			}
+		})
+	}
+
 	// Setup CPU profiling if desired
 	if *cpuProfile != "" {
 		fs.Infof(nil, "Creating CPU profile %q\n", *cpuProfile)
@@ -490,6 +533,8 @@ func resolveExitCode(err error) {
 		os.Exit(exitCodeUncategorizedError)
 	case unwrapped == accounting.ErrorMaxTransferLimitReached:
 		os.Exit(exitCodeTransferExceeded)
+	case unwrapped == accounting.ErrorMaxDurationReached:
+		os.Exit(exitCodeDurationExceeded)
 	case fserrors.ShouldRetry(err):
 		os.Exit(exitCodeRetryError)
 	case fserrors.IsNoRetryError(err):
@@ -34,7 +34,7 @@ type FS struct {
 // NewFS makes a new FS
 func NewFS(f fs.Fs) *FS {
 	fsys := &FS{
-		VFS:   vfs.New(f, &vfsflags.Opt),
+		VFS:   vfs.NewShared(f, &vfsflags.Opt),
 		f:     f,
 		ready: make(chan (struct{})),
 	}
@@ -281,6 +281,9 @@ func (fsys *FS) Statfs(path string, stat *fuse.Statfs_t) (errc int) {
 	}
 	if free >= 0 {
 		stat.Bavail = uint64(free) / blockSize
+		if used < 0 {
+			stat.Bfree = stat.Bavail
+		}
 	}
 	mountlib.ClipBlocks(&stat.Blocks)
 	mountlib.ClipBlocks(&stat.Bfree)
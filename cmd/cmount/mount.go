@@ -30,7 +30,7 @@ func init() {
 	if runtime.GOOS == "windows" {
 		name = "mount"
 	}
-	mountlib.NewMountCommand(name, Mount)
+	mountlib.NewMountCommand(name, Mount, nil)
 }
 
 // mountOptions configures the options from the command line flags
@@ -216,6 +216,7 @@ func Mount(f fs.Fs, mountpoint string) error {
 	if err := sdnotify.Ready(); err != nil && err != sdnotify.ErrSdNotifyNoSocket {
 		return errors.Wrap(err, "failed to notify systemd")
 	}
+	stopWatchdog := mountlib.StartWatchdog(mountpoint)
 
 waitloop:
 	for {
@@ -225,15 +226,14 @@ waitloop:
 			break waitloop
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
-			root, err := FS.Root()
-			if err != nil {
-				fs.Errorf(f, "Error reading root: %v", err)
-			} else {
-				root.ForgetAll()
+			fs.Infof(f, "Received SIGHUP - refreshing directory cache")
+			if err := FS.Refresh(); err != nil {
+				fs.Errorf(f, "Error refreshing directory cache: %v", err)
 			}
 		}
 	}
 
+	stopWatchdog()
 	_ = sdnotify.Stopping()
 	if err != nil {
 		return errors.Wrap(err, "failed to umount FUSE fs")
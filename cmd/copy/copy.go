@@ -1,7 +1,10 @@
 package copy
 
 import (
+	"log"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/sync"
 	"github.com/spf13/cobra"
@@ -12,7 +15,7 @@ func init() {
 }
 
 var commandDefintion = &cobra.Command{
-	Use:   "copy source:path dest:path",
+	Use:   "copy source:path dest:path [dest:path]*",
 	Short: `Copy files from source to dest, skipping already copied`,
 	Long: `
 Copy the source to the destination.  Doesn't transfer
@@ -50,9 +53,25 @@ If you are familiar with ` + "`rsync`" + `, rclone always works as if you had
 written a trailing / - meaning "copy the contents of this directory".
 This applies to all commands and whether you are talking about the
 source or destination.
+
+If more than one destination is given then the source is copied to
+all of them, eg
+
+    rclone copy source:sourcepath dest1:destpath dest2:destpath
+
+Each file which is needed by more than one destination is only read
+from the source once and is uploaded to those destinations at the same
+time, so replicating to several providers doesn't cost any more source
+bandwidth or API calls than copying to one.  The upload of each file
+goes as fast as the slowest destination.  ` + "`--backup-dir`" + ` can't be used with
+more than one destination.
 `,
 	Run: func(command *cobra.Command, args []string) {
-		cmd.CheckArgs(2, 2, command, args)
+		cmd.CheckArgs(2, 256, command, args)
+		if len(args) > 2 {
+			copyMulti(command, args)
+			return
+		}
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
@@ -62,3 +81,21 @@ source or destination.
 		})
 	},
 }
+
+// copyMulti copies the source in args[0] to all the destinations after it
+func copyMulti(command *cobra.Command, args []string) {
+	if fs.Config.BackupDir != "" {
+		log.Fatalf("Can't use --backup-dir with more than one destination")
+	}
+	fsrc, srcFileName := cmd.NewFsFile(args[0])
+	var fdsts []fs.Fs
+	for i := range args[1:] {
+		fdsts = append(fdsts, cmd.NewFsDir(args[1+i:]))
+	}
+	cmd.Run(true, true, command, func() error {
+		if srcFileName == "" {
+			return sync.CopyDirMulti(fdsts, fsrc)
+		}
+		return operations.CopyFileMulti(fdsts, fsrc, srcFileName)
+	})
+}
@@ -10,11 +10,13 @@ import (
 
 var (
 	dedupeMode = operations.DeduplicateInteractive
+	byHash     = false
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().VarP(&dedupeMode, "dedupe-mode", "", "Dedupe mode interactive|skip|first|newest|oldest|rename.")
+	commandDefintion.Flags().BoolVarP(&byHash, "by-hash", "", byHash, "Find files with the same contents anywhere in the remote instead of the same name.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -100,6 +102,17 @@ For example to rename all the identically named photos in your Google Photos dir
 Or
 
     rclone dedupe rename "drive:Google Photos"
+
+Use the ` + "`" + `--by-hash` + "`" + ` flag to find files with the same contents
+anywhere in the remote, whatever their names, on any remote which
+supports hashes.  Files with a size in common are hashed and those
+with the same hash are treated as duplicates.  The interactive, skip,
+first, newest and oldest modes work as above (skip just lists the
+duplicates) and largest is the same as first since the files are the
+same size.  The rename mode can't be used with ` + "`" + `--by-hash` + "`" + `.
+Filters are obeyed so you can restrict the search, eg
+
+    rclone dedupe --by-hash --dedupe-mode oldest --include "*.jpg" remote:photos
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 2, command, args)
@@ -112,6 +125,9 @@ Or
 		}
 		fdst := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
+			if byHash {
+				return operations.DeduplicateByHash(fdst, dedupeMode)
+			}
 			return operations.Deduplicate(fdst, dedupeMode)
 		})
 	},
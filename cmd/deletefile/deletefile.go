@@ -2,6 +2,7 @@ package deletefile
 
 import (
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/operations"
 
 	"github.com/pkg/errors"
@@ -22,14 +23,16 @@ it will always be removed.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
-		fs, fileName := cmd.NewFsFile(args[0])
+		f, fileName := cmd.NewFsFile(args[0])
 		cmd.Run(true, false, command, func() error {
 			if fileName == "" {
 				return errors.Errorf("%s is a directory or doesn't exist", args[0])
 			}
-			fileObj, err := fs.NewObject(fileName)
-			if err != nil {
-				return err
+			fileObj, err := f.NewObject(fileName)
+			if err == fs.ErrorObjectNotFound {
+				return errors.Errorf("%s doesn't exist", args[0])
+			} else if err != nil {
+				return errors.Wrapf(err, "failed to find %s", args[0])
 			}
 			return operations.DeleteFile(fileObj)
 		})
@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"os"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	baseFile = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&baseFile, "base", "", baseFile, "lsjson listing of an earlier state to say which side changed.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "diff A:path B:path",
+	Short: `Show the differences between two remotes.`,
+	Long: `
+Compares the files in A:path and B:path and prints the differences to
+standard output, one per line, sorted by path.  It doesn't alter
+either remote.
+
+Each line starts with
+
+    < - the file is only in A
+    > - the file is only in B
+    * - the file is in both but differs
+
+followed by the path and why it differs, eg
+
+    $ rclone diff remote1:path remote2:path
+    * file.txt (size 6 vs 9)
+    < new.txt (only in A)
+    > dir/old.txt (only in B)
+
+The files are compared in the same way that sync compares them so
+--size-only and --checksum can be used.  Unlike sync the modification
+times are never updated.
+
+Use --base to supply a listing of an earlier state of both remotes,
+made with "rclone lsjson -R" (with --hash if you are using
+--checksum), or a snapshot made with "rclone lsjson --snapshot".  This is used to say which side each difference was made
+on, eg
+
+    $ rclone lsjson -R remote1:path > base.json
+    ... time passes ...
+    $ rclone diff --base base.json remote1:path remote2:path
+    * file.txt (size 6 vs 9, changed in B)
+    < new.txt (only in A, added in A)
+    > dir/old.txt (only in B, deleted in A)
+
+If differences are found it exits with an error.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fa, fb := cmd.NewFsSrcDst(args)
+		cmd.Run(false, false, command, func() error {
+			var base []*operations.ListJSONItem
+			if baseFile != "" {
+				var err error
+				base, err = operations.ReadListJSONFile(baseFile)
+				if err != nil {
+					return errors.Wrap(err, "failed to read --base")
+				}
+			}
+			return operations.Diff(fa, fb, base, os.Stdout)
+		})
+	},
+}
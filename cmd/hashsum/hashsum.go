@@ -1,18 +1,66 @@
 package hashsum
 
 import (
-	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Flags shared with md5sum and sha1sum
+var (
+	OutputBase64 = false
+	ChecksumFile = ""
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
+	AddHashFlags(commandDefinition.Flags())
+}
+
+// AddHashFlags adds the flags to output base64 and check against a
+// sum file to a hash command
+func AddHashFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&OutputBase64, "base64", "", OutputBase64, "Output base64 encoded hashsum")
+	flags.StringVarP(&ChecksumFile, "checkfile", "C", ChecksumFile, "Check the hashes against this sum file instead of printing them - use \"-\" for stdin")
+}
+
+// HashHelp is the help for the flags added by AddHashFlags
+const HashHelp = `
+Use --base64 to output the sums in base64 instead of hex as some
+remotes show them this way.
+
+Use --checkfile SUMFILE (or -C) to check the objects against a sum
+file, as made by this command, instead of printing them.  It prints
+OK, FAILED or MISSING for each file listed in SUMFILE, the same as
+"md5sum -c" does, and returns an error if any weren't OK.  Use
+--base64 too if the sums in SUMFILE are in base64.  Files on the
+remote which aren't in SUMFILE are ignored.
+`
+
+// HashSum prints the sums of type ht for the objects in fsrc, or
+// checks them against ChecksumFile if set
+func HashSum(ht hash.Type, fsrc fs.Fs) (err error) {
+	if ChecksumFile == "" {
+		return operations.HashListerBase64(ht, OutputBase64, fsrc, os.Stdout)
+	}
+	var in io.Reader = os.Stdin
+	if ChecksumFile != "-" {
+		fd, err := os.Open(ChecksumFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to open sum file")
+		}
+		defer fs.CheckClose(fd, &err)
+		in = fd
+	}
+	return operations.HashCheck(ht, fsrc, in, OutputBase64, os.Stdout)
 }
 
 var commandDefinition = &cobra.Command{
@@ -35,7 +83,7 @@ Run without a hash to see the list of supported hashes, eg
 Then
 
     $ rclone hashsum MD5 remote:path
-`,
+`+HashHelp,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(0, 2, command, args)
 		if len(args) == 0 {
@@ -54,7 +102,7 @@ Then
 		}
 		fsrc := cmd.NewFsSrc(args[1:])
 		cmd.Run(false, false, command, func() error {
-			return operations.HashLister(ht, fsrc, os.Stdout)
+			return HashSum(ht, fsrc)
 		})
 		return nil
 	},
@@ -68,6 +68,7 @@ output:
     t - modification time
     h - hash
     i - ID of object if known
+    o - Original ID of underlying object if known
     m - MimeType of object if known
 
 So if you wanted the path, size and modification time, you would use
@@ -174,6 +175,8 @@ func Lsf(fsrc fs.Fs, out io.Writer) error {
 			list.AddHash(hashType)
 		case 'i':
 			list.AddID()
+		case 'o':
+			list.AddOrigID()
 		case 'm':
 			list.AddMimeType()
 		default:
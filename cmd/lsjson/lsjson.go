@@ -1,12 +1,15 @@
 package lsjson
 
 import (
+	"bufio"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path"
-	"time"
+	"strings"
 
 	"github.com/ncw/rclone/backend/crypt"
 	"github.com/ncw/rclone/cmd"
@@ -24,6 +27,9 @@ var (
 	showEncrypted bool
 	showOrigIDs   bool
 	noModTime     bool
+	filesOnly     bool
+	dirsOnly      bool
+	snapshot      string
 )
 
 func init() {
@@ -33,32 +39,9 @@ func init() {
 	commandDefintion.Flags().BoolVarP(&noModTime, "no-modtime", "", false, "Don't read the modification time (can speed things up).")
 	commandDefintion.Flags().BoolVarP(&showEncrypted, "encrypted", "M", false, "Show the encrypted names.")
 	commandDefintion.Flags().BoolVarP(&showOrigIDs, "original", "", false, "Show the ID of the underlying Object.")
-}
-
-// lsJSON in the struct which gets marshalled for each line
-type lsJSON struct {
-	Path      string
-	Name      string
-	Encrypted string `json:",omitempty"`
-	Size      int64
-	MimeType  string    `json:",omitempty"`
-	ModTime   Timestamp //`json:",omitempty"`
-	IsDir     bool
-	Hashes    map[string]string `json:",omitempty"`
-	ID        string            `json:",omitempty"`
-	OrigID    string            `json:",omitempty"`
-}
-
-// Timestamp a time in RFC3339 format with Nanosecond precision secongs
-type Timestamp time.Time
-
-// MarshalJSON turns a Timestamp into JSON
-func (t Timestamp) MarshalJSON() (out []byte, err error) {
-	tt := time.Time(t)
-	if tt.IsZero() {
-		return []byte(`""`), nil
-	}
-	return []byte(`"` + tt.Format(time.RFC3339Nano) + `"`), nil
+	commandDefintion.Flags().BoolVarP(&filesOnly, "files-only", "", false, "Show only files in the listing.")
+	commandDefintion.Flags().BoolVarP(&dirsOnly, "dirs-only", "", false, "Show only directories in the listing.")
+	commandDefintion.Flags().StringVarP(&snapshot, "snapshot", "", "", "Write the listing to this file instead, compressed if it ends in .gz.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -91,6 +74,11 @@ If --no-modtime is specified then ModTime will be blank.
 
 If --encrypted is not specified the Encrypted won't be emitted.
 
+If --dirs-only is specified then only directories will be listed and
+if --files-only is specified then only files will be listed.  These
+are useful with --recursive to find all the files or directories
+below the path.
+
 The Path field will only show folders below the remote path being listed.
 If "remote:path" contains the file "subfolder/file.txt", the Path for "file.txt"
 will be "subfolder/file.txt", not "remote:path/subfolder/file.txt".
@@ -100,9 +88,25 @@ The time is in RFC3339 format with nanosecond precision.
 
 The whole output can be processed as a JSON blob, or alternatively it
 can be processed line by line as each item is written one to a line.
+
+Use --snapshot to write the listing to a file instead of standard
+output.  If the file name ends in .gz then it is compressed with gzip.
+A snapshot made with -R (and --hash to catch changes in the data) can
+be used later with the snapshot backend to check or sync against the
+state of the remote when the snapshot was made, eg to find files
+which have changed or rotted since.
+
+    rclone lsjson -R --hash --snapshot snap.json.gz remote:path
+    ... time passes ...
+    rclone check --snapshot-file snap.json.gz :snapshot: remote:path
+
+It can also be used as the --base for "rclone diff".
 ` + lshelp.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		if filesOnly && dirsOnly {
+			log.Fatalf("Can't use --files-only and --dirs-only together")
+		}
 		fsrc := cmd.NewFsSrc(args)
 		var cipher crypt.Cipher
 		if showEncrypted {
@@ -118,99 +122,95 @@ can be processed line by line as each item is written one to a line.
 				log.Fatalf(err.Error())
 			}
 		}
-		cmd.Run(false, false, command, func() error {
-			fmt.Println("[")
-			first := true
-			err := walk.Walk(fsrc, "", false, operations.ConfigMaxDepth(recurse), func(dirPath string, entries fs.DirEntries, err error) error {
-				if err != nil {
-					fs.CountError(err)
-					fs.Errorf(dirPath, "error listing: %v", err)
-					return nil
+		opt := operations.ListJSONOpt{
+			NoModTime:   noModTime,
+			ShowHash:    showHash,
+			ShowOrigIDs: showOrigIDs,
+		}
+		cmd.Run(false, false, command, func() (err error) {
+			if snapshot == "" {
+				return list(fsrc, cipher, &opt, os.Stdout)
+			}
+			fd, err := os.Create(snapshot)
+			if err != nil {
+				return errors.Wrap(err, "failed to create snapshot")
+			}
+			defer fs.CheckClose(fd, &err)
+			buf := bufio.NewWriter(fd)
+			defer func() {
+				flushErr := buf.Flush()
+				if err == nil {
+					err = flushErr
 				}
-				for _, entry := range entries {
-					item := lsJSON{
-						Path:     entry.Remote(),
-						Name:     path.Base(entry.Remote()),
-						Size:     entry.Size(),
-						MimeType: fs.MimeTypeDirEntry(entry),
-					}
-					if !noModTime {
-						item.ModTime = Timestamp(entry.ModTime())
-					}
-					if cipher != nil {
-						switch entry.(type) {
-						case fs.Directory:
-							item.Encrypted = cipher.EncryptDirName(path.Base(entry.Remote()))
-						case fs.Object:
-							item.Encrypted = cipher.EncryptFileName(path.Base(entry.Remote()))
-						default:
-							fs.Errorf(nil, "Unknown type %T in listing", entry)
-						}
-					}
-					if do, ok := entry.(fs.IDer); ok {
-						item.ID = do.ID()
-					}
-					if showOrigIDs {
-						cur := entry
-						for {
-							u, ok := cur.(fs.ObjectUnWrapper)
-							if !ok {
-								break // not a wrapped object, use current id
-							}
-							next := u.UnWrap()
-							if next == nil {
-								break // no base object found, use current id
-							}
-							cur = next
-						}
-						if do, ok := cur.(fs.IDer); ok {
-							item.OrigID = do.ID()
-						}
-					}
-					switch x := entry.(type) {
-					case fs.Directory:
-						item.IsDir = true
-					case fs.Object:
-						item.IsDir = false
-						if showHash {
-							item.Hashes = make(map[string]string)
-							for _, hashType := range x.Fs().Hashes().Array() {
-								hash, err := x.Hash(hashType)
-								if err != nil {
-									fs.Errorf(x, "Failed to read hash: %v", err)
-								} else if hash != "" {
-									item.Hashes[hashType.String()] = hash
-								}
-							}
-						}
-					default:
-						fs.Errorf(nil, "Unknown type %T in listing", entry)
-					}
-					out, err := json.Marshal(item)
-					if err != nil {
-						return errors.Wrap(err, "failed to marshal list object")
-					}
-					if first {
-						first = false
-					} else {
-						fmt.Print(",\n")
-					}
-					_, err = os.Stdout.Write(out)
-					if err != nil {
-						return errors.Wrap(err, "failed to write to output")
-					}
+			}()
+			var out io.Writer = buf
+			if strings.HasSuffix(snapshot, ".gz") {
+				gz := gzip.NewWriter(buf)
+				defer fs.CheckClose(gz, &err)
+				out = gz
+			}
+			return list(fsrc, cipher, &opt, out)
+		})
+	},
+}
 
+// list writes the JSON listing of fsrc to out
+func list(fsrc fs.Fs, cipher crypt.Cipher, opt *operations.ListJSONOpt, out io.Writer) error {
+	_, err := fmt.Fprintln(out, "[")
+	if err != nil {
+		return errors.Wrap(err, "failed to write to output")
+	}
+	first := true
+	err = walk.Walk(fsrc, "", false, operations.ConfigMaxDepth(recurse), func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(dirPath, "error listing: %v", err)
+			return nil
+		}
+		for _, entry := range entries {
+			_, isDir := entry.(fs.Directory)
+			if (isDir && filesOnly) || (!isDir && dirsOnly) {
+				continue
+			}
+			item := operations.NewListJSONItem(entry, opt)
+			if cipher != nil {
+				switch entry.(type) {
+				case fs.Directory:
+					item.Encrypted = cipher.EncryptDirName(path.Base(entry.Remote()))
+				case fs.Object:
+					item.Encrypted = cipher.EncryptFileName(path.Base(entry.Remote()))
+				default:
+					fs.Errorf(nil, "Unknown type %T in listing", entry)
 				}
-				return nil
-			})
+			}
+			data, err := json.Marshal(item)
 			if err != nil {
-				return errors.Wrap(err, "error listing JSON")
+				return errors.Wrap(err, "failed to marshal list object")
 			}
-			if !first {
-				fmt.Println()
+			if first {
+				first = false
+			} else {
+				data = append([]byte(",\n"), data...)
 			}
-			fmt.Println("]")
-			return nil
-		})
-	},
+			_, err = out.Write(data)
+			if err != nil {
+				return errors.Wrap(err, "failed to write to output")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error listing JSON")
+	}
+	if !first {
+		_, err = fmt.Fprintln(out)
+		if err != nil {
+			return errors.Wrap(err, "failed to write to output")
+		}
+	}
+	_, err = fmt.Fprintln(out, "]")
+	if err != nil {
+		return errors.Wrap(err, "failed to write to output")
+	}
+	return nil
 }
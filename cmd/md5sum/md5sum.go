@@ -1,15 +1,15 @@
 package md5sum
 
 import (
-	"os"
-
 	"github.com/ncw/rclone/cmd"
-	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/cmd/hashsum"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	hashsum.AddHashFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -18,12 +18,12 @@ var commandDefintion = &cobra.Command{
 	Long: `
 Produces an md5sum file for all the objects in the path.  This
 is in the same format as the standard md5sum tool produces.
-`,
+`+hashsum.HashHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			return operations.Md5sum(fsrc, os.Stdout)
+			return hashsum.HashSum(hash.MD5, fsrc)
 		})
 	},
 }
@@ -21,17 +21,22 @@ import (
 // FS represents the top level filing system
 type FS struct {
 	*vfs.VFS
-	f fs.Fs
+	f        fs.Fs
+	fileName string // if set, the single file being mounted as the root
 }
 
 // Check interface satistfied
 var _ fusefs.FS = (*FS)(nil)
 
 // NewFS makes a new FS
-func NewFS(f fs.Fs) *FS {
+//
+// If fileName is set then the file of that name in f is the root
+// rather than the directory.
+func NewFS(f fs.Fs, fileName string) *FS {
 	fsys := &FS{
-		VFS: vfs.New(f, &vfsflags.Opt),
-		f:   f,
+		VFS:      vfs.NewShared(f, &vfsflags.Opt),
+		f:        f,
+		fileName: fileName,
 	}
 	return fsys
 }
@@ -39,6 +44,18 @@ func NewFS(f fs.Fs) *FS {
 // Root returns the root node
 func (f *FS) Root() (node fusefs.Node, err error) {
 	defer log.Trace("", "")("node=%+v, err=%v", &node, &err)
+	if f.fileName != "" {
+		// Mounting a single file so it is the root
+		node, err := f.VFS.Stat(f.fileName)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		file, ok := node.(*vfs.File)
+		if !ok {
+			return nil, fuse.Errno(syscall.EISDIR)
+		}
+		return &File{file}, nil
+	}
 	root, err := f.VFS.Root()
 	if err != nil {
 		return nil, translateError(err)
@@ -72,6 +89,9 @@ func (f *FS) Statfs(ctx context.Context, req *fuse.StatfsRequest, resp *fuse.Sta
 	}
 	if free >= 0 {
 		resp.Bavail = uint64(free) / blockSize
+		if used < 0 {
+			resp.Bfree = resp.Bavail
+		}
 	}
 	mountlib.ClipBlocks(&resp.Blocks)
 	mountlib.ClipBlocks(&resp.Bfree)
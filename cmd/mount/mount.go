@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
 
 	"bazil.org/fuse"
@@ -22,7 +23,7 @@ import (
 )
 
 func init() {
-	mountlib.NewMountCommand("mount", Mount)
+	mountlib.NewMountCommand("mount", Mount, MountFile)
 }
 
 // mountOptions configures the options from the command line flags
@@ -83,13 +84,19 @@ func mountOptions(device string) (options []fuse.MountOption) {
 // returns an error, and an error channel for the serve process to
 // report an error when fusermount is called.
 func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
+	return mountFile(f, "", mountpoint)
+}
+
+// mountFile mounts the file fileName in f on mountpoint, or the
+// whole of f if fileName is empty, in the same way as mount.
+func mountFile(f fs.Fs, fileName, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
 	fs.Debugf(f, "Mounting on %q", mountpoint)
-	c, err := fuse.Mount(mountpoint, mountOptions(f.Name()+":"+f.Root())...)
+	c, err := fuse.Mount(mountpoint, mountOptions(f.Name()+":"+path.Join(f.Root(), fileName))...)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	filesys := NewFS(f)
+	filesys := NewFS(f, fileName)
 	server := fusefs.New(c, nil)
 
 	// Serve the mount point in the background returning error to errChan
@@ -122,6 +129,12 @@ func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, er
 //
 // If noModTime is set then it
 func Mount(f fs.Fs, mountpoint string) error {
+	return MountFile(f, "", mountpoint)
+}
+
+// MountFile mounts the file fileName in f at mountpoint, or the whole
+// of f if fileName is empty.
+func MountFile(f fs.Fs, fileName, mountpoint string) error {
 	if mountlib.DebugFUSE {
 		fuse.Debug = func(msg interface{}) {
 			fs.Debugf("fuse", "%v", msg)
@@ -129,7 +142,7 @@ func Mount(f fs.Fs, mountpoint string) error {
 	}
 
 	// Mount it
-	FS, errChan, unmount, err := mount(f, mountpoint)
+	FS, errChan, unmount, err := mountFile(f, fileName, mountpoint)
 	if err != nil {
 		return errors.Wrap(err, "failed to mount FUSE fs")
 	}
@@ -143,6 +156,7 @@ func Mount(f fs.Fs, mountpoint string) error {
 	if err := sdnotify.Ready(); err != nil && err != sdnotify.ErrSdNotifyNoSocket {
 		return errors.Wrap(err, "failed to notify systemd")
 	}
+	stopWatchdog := mountlib.StartWatchdog(mountpoint)
 
 waitloop:
 	for {
@@ -156,15 +170,14 @@ waitloop:
 			break waitloop
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
-			root, err := FS.Root()
-			if err != nil {
-				fs.Errorf(f, "Error reading root: %v", err)
-			} else {
-				root.ForgetAll()
+			fs.Infof(f, "Received SIGHUP - refreshing directory cache")
+			if err := FS.Refresh(); err != nil {
+				fs.Errorf(f, "Error refreshing directory cache: %v", err)
 			}
 		}
 	}
 
+	stopWatchdog()
 	_ = sdnotify.Stopping()
 	if err != nil {
 		return errors.Wrap(err, "failed to umount FUSE fs")
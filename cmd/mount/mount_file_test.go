@@ -0,0 +1,51 @@
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMountFile tests mounting a single file on a file mountpoint
+func TestMountFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-mount-file")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	remoteDir := filepath.Join(dir, "remote")
+	require.NoError(t, os.Mkdir(remoteDir, 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remoteDir, "file.txt"), []byte("hello world"), 0666))
+	mountPath := filepath.Join(dir, "mountpoint")
+	require.NoError(t, ioutil.WriteFile(mountPath, nil, 0666))
+
+	f, err := fs.NewFs(remoteDir)
+	require.NoError(t, err)
+
+	VFS, errChan, unmount, err := mountFile(f, "file.txt", mountPath)
+	if err != nil {
+		t.Skipf("FUSE not found so skipping test: %v", err)
+	}
+
+	fi, err := os.Stat(mountPath)
+	require.NoError(t, err)
+	assert.True(t, fi.Mode().IsRegular())
+	assert.Equal(t, int64(11), fi.Size())
+
+	data, err := ioutil.ReadFile(mountPath)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	require.NoError(t, unmount())
+	require.NoError(t, <-errChan)
+	require.NoError(t, VFS.CleanUp())
+}
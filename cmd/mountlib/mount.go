@@ -4,6 +4,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"path"
 	"runtime"
 	"strings"
 	"time"
@@ -62,8 +63,27 @@ func checkMountEmpty(mountpoint string) error {
 	return nil
 }
 
+// Check the mountpoint is suitable for mounting a single file on
+func checkMountFile(mountpoint string) error {
+	if runtime.GOOS != "linux" {
+		return errors.Errorf("mounting a single file is not supported on %s", runtime.GOOS)
+	}
+	fi, err := os.Stat(mountpoint)
+	if err != nil {
+		return errors.Wrap(err, "Can not stat: "+mountpoint)
+	}
+	if !fi.Mode().IsRegular() {
+		return errors.New("Mountpoint must be a file when mounting a single file: " + mountpoint)
+	}
+	return nil
+}
+
 // NewMountCommand makes a mount command with the given name and Mount function
-func NewMountCommand(commandName string, Mount func(f fs.Fs, mountpoint string) error) *cobra.Command {
+//
+// MountFile is used to mount a single file fileName in f on
+// mountpoint if the remote points to a file rather than a directory.
+// It may be nil if the command can't mount single files.
+func NewMountCommand(commandName string, Mount func(f fs.Fs, mountpoint string) error, MountFile func(f fs.Fs, fileName, mountpoint string) error) *cobra.Command {
 	var commandDefintion = &cobra.Command{
 		Use:   commandName + " remote:path /path/to/mountpoint",
 		Short: `Mount the remote as a mountpoint. **EXPERIMENTAL**`,
@@ -95,6 +115,20 @@ When that happens, it is the user's responsibility to stop the mount manually wi
     # OS X
     umount /path/to/local/mount
 
+### Mounting a single file
+
+On Linux, if the remote points to a file rather than a directory then
+rclone mount will mount just that file.  The mountpoint must be an existing
+file which the remote file will appear in place of, eg
+
+    touch /path/to/disk.img
+    rclone ` + commandName + ` remote:images/disk.img /path/to/disk.img
+
+This is useful for large files such as disk images or database dumps
+which are only read or written in parts.  Use "--vfs-cache-mode" as
+described below if the file needs to be written other than
+sequentially.
+
 ### Installing on Windows
 
 To run rclone ` + commandName + ` on Windows, you will need to
@@ -196,24 +230,20 @@ after the mountpoint has been successfully set up.
 Units having the rclone ` + commandName + ` service specified as a requirement
 will see all files and folders immediately in this mode.
 
-### chunked reading ###
-
---vfs-read-chunk-size will enable reading the source objects in parts.
-This can reduce the used download quota for some remotes by requesting only chunks
-from the remote that are actually read at the cost of an increased number of requests.
+### Watchdog
 
-When --vfs-read-chunk-size-limit is also specified and greater than --vfs-read-chunk-size,
-the chunk size for each open file will get doubled for each chunk read, until the
-specified value is reached. A value of -1 will disable the limit and the chunk size will
-grow indefinitely.
+Use --watchdog-interval to make rclone check the mount is still
+responding by running stat on the mountpoint this often, eg
+"--watchdog-interval 30s".  If the stat fails, or doesn't return
+within the interval, the mount is reported as unhealthy by the
+/healthz endpoint of the remote control server when it is run with
+"--rc --rc-healthz", so it can be used as a liveness or readiness
+probe, eg in Kubernetes.
 
-With --vfs-read-chunk-size 100M and --vfs-read-chunk-size-limit 0 the following
-parts will be downloaded: 0-100M, 100M-200M, 200M-300M, 300M-400M and so on.
-When --vfs-read-chunk-size-limit 500M is specified, the result would be
-0-100M, 100M-300M, 300M-700M, 700M-1200M, 1200M-1700M and so on.
-
-Chunked reading will only work with --vfs-cache-mode < full, as the file will always
-be copied to the vfs cache before opening with --vfs-cache-mode full.
+If the systemd service has WatchdogSec= set then rclone sends
+systemd a keepalive each time the check succeeds, so systemd restarts
+rclone if the mount hangs.  This is checked at twice the rate systemd
+requires, or the rate set with --watchdog-interval if that is faster.
 ` + vfs.Help,
 		Run: func(command *cobra.Command, args []string) {
 			cmd.CheckArgs(2, 2, command, args)
@@ -222,7 +252,7 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 				config.PassConfigKeyForDaemonization = true
 			}
 
-			fdst := cmd.NewFsDir(args)
+			fdst, fileName := cmd.NewFsDirOrFile(args)
 
 			// Show stats if the user has specifically requested them
 			if cmd.ShowStats() {
@@ -230,9 +260,18 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 				defer close(stopStats)
 			}
 
-			// Skip checkMountEmpty if --allow-non-empty flag is used or if
-			// the Operating System is Windows
-			if !AllowNonEmpty && runtime.GOOS != "windows" {
+			if fileName != "" {
+				// Mounting a single file
+				if MountFile == nil {
+					log.Fatalf("Fatal error: rclone %s can't mount a single file", commandName)
+				}
+				err := checkMountFile(args[1])
+				if err != nil {
+					log.Fatalf("Fatal error: %v", err)
+				}
+			} else if !AllowNonEmpty && runtime.GOOS != "windows" {
+				// Skip checkMountEmpty if --allow-non-empty flag is used or if
+				// the Operating System is Windows
 				err := checkMountEmpty(args[1])
 				if err != nil {
 					log.Fatalf("Fatal error: %v", err)
@@ -242,7 +281,7 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 			// Work out the volume name, removing special
 			// characters from it if necessary
 			if VolumeName == "" {
-				VolumeName = fdst.Name() + ":" + fdst.Root()
+				VolumeName = fdst.Name() + ":" + path.Join(fdst.Root(), fileName)
 			}
 			VolumeName = strings.Replace(VolumeName, ":", " ", -1)
 			VolumeName = strings.Replace(VolumeName, "/", " ", -1)
@@ -256,7 +295,12 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 				}
 			}
 
-			err := Mount(fdst, args[1])
+			var err error
+			if fileName != "" {
+				err = MountFile(fdst, fileName, args[1])
+			} else {
+				err = Mount(fdst, args[1])
+			}
 			if err != nil {
 				log.Fatalf("Fatal error: %v", err)
 			}
@@ -282,6 +326,7 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 	flags.BoolVarP(flagSet, &Daemon, "daemon", "", Daemon, "Run mount as a daemon (background mode).")
 	flags.StringVarP(flagSet, &VolumeName, "volname", "", VolumeName, "Set the volume name (not supported by all OSes).")
 	flags.DurationVarP(flagSet, &DaemonTimeout, "daemon-timeout", "", DaemonTimeout, "Time limit for rclone to respond to kernel (not supported by all OSes).")
+	flags.DurationVarP(flagSet, &WatchdogInterval, "watchdog-interval", "", WatchdogInterval, "Check the mount is responding this often - 0 to only use the systemd watchdog.")
 
 	if runtime.GOOS == "darwin" {
 		flags.BoolVarP(flagSet, &NoAppleDouble, "noappledouble", "", NoAppleDouble, "Sets the OSXFUSE option noappledouble.")
@@ -0,0 +1,162 @@
+package mountlib
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/health"
+	"github.com/okzk/sdnotify"
+	"github.com/pkg/errors"
+)
+
+// WatchdogInterval is how often the watchdog checks the mount is
+// responding - 0 to use the systemd watchdog interval if set
+var WatchdogInterval time.Duration
+
+// the name of the health check the watchdog registers
+const watchdogHealthName = "mount"
+
+// errWatchdogNotReady is returned by the health check until the first
+// probe succeeds
+var errWatchdogNotReady = errors.New("mount not ready yet")
+
+// watchdog probes a mount periodically with stat and keeps the
+// result for the health checks and the systemd watchdog
+type watchdog struct {
+	mountpoint string
+	interval   time.Duration
+	stat       func(name string) (os.FileInfo, error)
+	notify     func() error  // tell systemd we are alive
+	stop       chan struct{} // close to stop the watchdog
+	done       chan struct{} // closed when the watchdog has stopped
+
+	mu      sync.Mutex
+	err     error      // result of the last probe
+	probing chan error // set while a probe is in progress
+}
+
+// systemdWatchdogInterval returns the interval to probe at to satisfy
+// the systemd watchdog or 0 if it isn't enabled for this process
+func systemdWatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// notify at twice the rate systemd requires
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// StartWatchdog starts checking the mount at mountpoint is
+// responding if --watchdog-interval is set or systemd has enabled its
+// watchdog for rclone.  It returns a function to stop it.
+func StartWatchdog(mountpoint string) (stop func()) {
+	interval := WatchdogInterval
+	notify := func() error { return nil }
+	if systemdInterval := systemdWatchdogInterval(); systemdInterval > 0 {
+		notify = sdnotify.Watchdog
+		if interval <= 0 || interval > systemdInterval {
+			interval = systemdInterval
+		}
+	}
+	if interval <= 0 {
+		return func() {}
+	}
+	fs.Debugf(nil, "Starting mount watchdog on %q every %v", mountpoint, interval)
+	w := newWatchdog(mountpoint, interval, os.Stat, notify)
+	remove := health.Add(watchdogHealthName, w.check)
+	go w.run()
+	return func() {
+		remove()
+		close(w.stop)
+		<-w.done
+	}
+}
+
+// newWatchdog makes a watchdog which probes mountpoint with stat
+func newWatchdog(mountpoint string, interval time.Duration, stat func(name string) (os.FileInfo, error), notify func() error) *watchdog {
+	return &watchdog{
+		mountpoint: mountpoint,
+		interval:   interval,
+		stat:       stat,
+		notify:     notify,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		err:        errWatchdogNotReady,
+	}
+}
+
+// run probes the mount every interval until stopped
+func (w *watchdog) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.probe()
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe stats the mountpoint recording the result.
+//
+// If the stat doesn't return within the interval then the mount is
+// marked as hung.  A hung stat isn't started again until it returns.
+func (w *watchdog) probe() {
+	w.mu.Lock()
+	result := w.probing
+	if result == nil {
+		result = make(chan error, 1)
+		w.probing = result
+		go func() {
+			_, err := w.stat(w.mountpoint)
+			result <- err
+		}()
+	}
+	w.mu.Unlock()
+
+	var err error
+	select {
+	case err = <-result:
+		w.mu.Lock()
+		w.probing = nil
+		w.mu.Unlock()
+		if err != nil {
+			err = errors.Wrap(err, "stat of mountpoint failed")
+		}
+	case <-time.After(w.interval):
+		err = errors.Errorf("stat of mountpoint %q hasn't returned after %v", w.mountpoint, w.interval)
+	case <-w.stop:
+		return
+	}
+
+	w.mu.Lock()
+	changed := (err == nil) != (w.err == nil)
+	w.err = err
+	w.mu.Unlock()
+	if err != nil {
+		fs.Errorf(nil, "Mount watchdog: %v", err)
+		return
+	}
+	if changed {
+		fs.Infof(nil, "Mount watchdog: mount at %q is responding", w.mountpoint)
+	}
+	if err := w.notify(); err != nil && err != sdnotify.ErrSdNotifyNoSocket {
+		fs.Errorf(nil, "Mount watchdog: failed to notify systemd: %v", err)
+	}
+}
+
+// check is the health check for the mount
+func (w *watchdog) check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -0,0 +1,94 @@
+package mountlib
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdWatchdogInterval(t *testing.T) {
+	defer func() {
+		_ = os.Unsetenv("WATCHDOG_USEC")
+		_ = os.Unsetenv("WATCHDOG_PID")
+	}()
+	assert.Equal(t, time.Duration(0), systemdWatchdogInterval())
+	require.NoError(t, os.Setenv("WATCHDOG_USEC", "10000000"))
+	assert.Equal(t, 5*time.Second, systemdWatchdogInterval())
+	require.NoError(t, os.Setenv("WATCHDOG_PID", "1"))
+	assert.Equal(t, time.Duration(0), systemdWatchdogInterval())
+	require.NoError(t, os.Unsetenv("WATCHDOG_PID"))
+	require.NoError(t, os.Setenv("WATCHDOG_USEC", "potato"))
+	assert.Equal(t, time.Duration(0), systemdWatchdogInterval())
+}
+
+func TestWatchdogProbe(t *testing.T) {
+	var statErr error
+	stat := func(name string) (os.FileInfo, error) {
+		assert.Equal(t, "/mnt", name)
+		return nil, statErr
+	}
+	notified := 0
+	notify := func() error {
+		notified++
+		return nil
+	}
+	w := newWatchdog("/mnt", time.Second, stat, notify)
+	assert.Equal(t, errWatchdogNotReady, w.check())
+
+	w.probe()
+	assert.NoError(t, w.check())
+	assert.Equal(t, 1, notified)
+
+	statErr = errors.New("transport endpoint is not connected")
+	w.probe()
+	assert.Error(t, w.check())
+	assert.Equal(t, 1, notified)
+
+	statErr = nil
+	w.probe()
+	assert.NoError(t, w.check())
+	assert.Equal(t, 2, notified)
+}
+
+func TestWatchdogHung(t *testing.T) {
+	unblock := make(chan struct{})
+	calls := 0
+	stat := func(name string) (os.FileInfo, error) {
+		calls++
+		<-unblock
+		return nil, nil
+	}
+	w := newWatchdog("/mnt", 10*time.Millisecond, stat, func() error { return nil })
+
+	// a stat which doesn't return marks the mount as hung
+	w.probe()
+	assert.Error(t, w.check())
+	assert.NotEqual(t, errWatchdogNotReady, w.check())
+
+	// and another stat isn't started while it is stuck
+	w.probe()
+	assert.Error(t, w.check())
+
+	// when it returns the mount is healthy again
+	close(unblock)
+	w.probe()
+	assert.NoError(t, w.check())
+	assert.Equal(t, 1, calls)
+}
+
+func TestStartWatchdog(t *testing.T) {
+	oldInterval := WatchdogInterval
+	defer func() { WatchdogInterval = oldInterval }()
+
+	// does nothing if not configured
+	WatchdogInterval = 0
+	StartWatchdog(os.TempDir())()
+
+	WatchdogInterval = time.Hour
+	stop := StartWatchdog(os.TempDir())
+	stop()
+}
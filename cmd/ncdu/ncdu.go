@@ -13,6 +13,7 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/ncdu/scan"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
 	termbox "github.com/nsf/termbox-go"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -41,9 +42,13 @@ Here are the keys - press '?' to toggle the help on and off
 
     ` + strings.Join(helpText[1:], "\n    ") + `
 
+Files and directories can be deleted with 'd' once the scan has
+finished.  This asks for confirmation first and deletes directories
+along with all their contents, so take care.
+
 This an homage to the [ncdu tool](https://dev.yorhel.nl/ncdu) but for
-rclone remotes.  It is missing lots of features at the moment, most
-importantly deleting files, but is useful as it stands.
+rclone remotes.  It is missing lots of features at the moment but is
+useful as it stands.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
@@ -63,6 +68,7 @@ var helpText = []string{
 	" c toggle counts",
 	" g toggle graph",
 	" n,s,C sort by name,size,count",
+	" d delete file/directory",
 	" ^L refresh screen",
 	" ? to toggle help on and off",
 	" q/ESC/c-C to quit",
@@ -88,6 +94,7 @@ type UI struct {
 	sortBySize    int8
 	sortByCount   int8
 	dirPosMap     map[string]dirPos // store for directory positions
+	confirm       func()            // run if the user answers y to the box
 }
 
 // Where we have got to in the directory listing
@@ -416,6 +423,47 @@ func (u *UI) up() {
 	}
 }
 
+// delete asks whether to delete the current entry and deletes it if
+// the user answers y
+func (u *UI) delete() {
+	if u.d == nil || len(u.entries) == 0 {
+		return
+	}
+	if u.listing {
+		u.popupBox([]string{"Can't delete while listing", "Wait for the listing to finish"})
+		return
+	}
+	dirPos := u.dirPosMap[u.path]
+	i := u.sortPerm[dirPos.entry]
+	entry := u.entries[i]
+	d := u.d
+	question := "Delete this file?"
+	if _, isDir := entry.(fs.Directory); isDir {
+		question = "Delete this directory and ALL its contents?"
+	}
+	u.popupBox([]string{"Delete " + entry.Remote(), question, "Press y to delete, any other key to cancel"})
+	u.confirm = func() {
+		if fs.Config.DryRun {
+			u.popupBox([]string{"Not deleting " + entry.Remote(), "--dry-run is set"})
+			return
+		}
+		var err error
+		switch x := entry.(type) {
+		case fs.Object:
+			err = operations.DeleteFile(x)
+		case fs.Directory:
+			err = operations.Purge(u.f, x.Remote())
+		}
+		if err != nil {
+			u.popupBox([]string{"Failed to delete " + entry.Remote(), err.Error()})
+			return
+		}
+		d.Remove(i)
+		u.setCurrentDir(d)
+		u.move(0)
+	}
+}
+
 // popupBox shows a box with the text in
 func (u *UI) popupBox(text []string) {
 	u.boxText = text
@@ -507,6 +555,16 @@ outer:
 			u.sortCurrentDir()
 		case ev := <-events:
 			doneWithEvent <- true
+			if ev.Type == termbox.EventKey && u.confirm != nil {
+				// answering the question in the box
+				confirm := u.confirm
+				u.confirm = nil
+				u.showBox = false
+				if ev.Ch == 'y' {
+					confirm()
+				}
+				continue
+			}
 			if ev.Type == termbox.EventKey {
 				switch ev.Key + termbox.Key(ev.Ch) {
 				case termbox.KeyEsc, termbox.KeyCtrlC, 'q':
@@ -537,6 +595,8 @@ outer:
 					u.toggleSort(&u.sortBySize)
 				case 'C':
 					u.toggleSort(&u.sortByCount)
+				case 'd':
+					u.delete()
 				case '?':
 					u.togglePopupBox(helpText)
 
@@ -97,6 +97,30 @@ func (d *Dir) GetDir(i int) (subDir *Dir, isDir bool) {
 	return d.getDir(i)
 }
 
+// Remove removes the i-th entry from the directory and takes its size
+// and count off the totals of this directory and its parents.
+//
+// It should only be called once the scan has finished.
+func (d *Dir) Remove(i int) {
+	d.mu.Lock()
+	subDir, isDir := d.getDir(i)
+	var size, count int64
+	if !isDir {
+		size, count = d.entries[i].Size(), 1
+	} else if subDir != nil {
+		size, count = subDir.Attr()
+		delete(d.dirs, path.Base(subDir.path))
+	}
+	d.entries = append(d.entries[:i], d.entries[i+1:]...)
+	d.mu.Unlock()
+	for dir := d; dir != nil; dir = dir.parent {
+		dir.mu.Lock()
+		dir.count -= count
+		dir.size -= size
+		dir.mu.Unlock()
+	}
+}
+
 // Attr returns the size and count for the directory
 func (d *Dir) Attr() (size int64, count int64) {
 	d.mu.Lock()
@@ -0,0 +1,47 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirRemove(t *testing.T) {
+	now := time.Now()
+	root := newDir(nil, "", fs.DirEntries{
+		object.NewMemoryObject("a", now, []byte("12345")),
+		fs.NewDir("dir", now),
+	})
+	dir := newDir(root, "dir", fs.DirEntries{
+		object.NewMemoryObject("dir/b", now, []byte("123")),
+		object.NewMemoryObject("dir/c", now, []byte("1")),
+	})
+	size, count := root.Attr()
+	assert.Equal(t, int64(9), size)
+	assert.Equal(t, int64(3), count)
+
+	// remove a file in the subdirectory
+	dir.Remove(0)
+	assert.Equal(t, 1, len(dir.Entries()))
+	size, count = dir.Attr()
+	assert.Equal(t, int64(1), size)
+	assert.Equal(t, int64(1), count)
+	size, count = root.Attr()
+	assert.Equal(t, int64(6), size)
+	assert.Equal(t, int64(2), count)
+
+	// remove the subdirectory
+	subDir, isDir := root.GetDir(1)
+	assert.True(t, isDir)
+	assert.Equal(t, dir, subDir)
+	root.Remove(1)
+	assert.Equal(t, 1, len(root.Entries()))
+	size, count = root.Attr()
+	assert.Equal(t, int64(5), size)
+	assert.Equal(t, int64(1), count)
+	_, isDir = root.GetDir(0)
+	assert.False(t, isDir)
+}
@@ -19,14 +19,18 @@ import (
 )
 
 var (
-	noOutput = false
-	url      = "http://localhost:5572/"
+	noOutput  = false
+	url       = "http://localhost:5572/"
+	jsonInput = ""
+	loopback  = false
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&noOutput, "no-output", "", noOutput, "If set don't output the JSON result.")
 	commandDefintion.Flags().StringVarP(&url, "url", "", url, "URL to connect to rclone remote control.")
+	commandDefintion.Flags().StringVarP(&jsonInput, "json", "", jsonInput, "Input JSON - use instead of key=value args.")
+	commandDefintion.Flags().BoolVarP(&loopback, "loopback", "", loopback, "If set connect to this rclone instance not via HTTP.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -40,6 +44,19 @@ Arguments should be passed in as parameter=value.
 
 The result will be returned as a JSON object by default.
 
+The --json parameter can be used to pass in a JSON blob as an input
+instead of key=value arguments.  This is the only way of passing in
+more complicated values.  Any key=value arguments are added to it,
+replacing the keys in the blob.
+
+    rclone rc --json '{ "p1": [1,"2",null,4], "p2": { "a":1, "b":2 } }' rc/noop
+
+Use --loopback to run the command in this rclone rather than
+connecting to a running one.  This is useful for testing and for
+running commands which don't need a server, eg
+
+    rclone rc --loopback operations/list fs=remote: remote=dir
+
 Use "rclone rc" to see a list of all possible commands.`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(0, 1E9, command, args)
@@ -56,6 +73,11 @@ Use "rclone rc" to see a list of all possible commands.`,
 //
 // if err is set, out may be a valid error return or it may be nil
 func doCall(path string, in rc.Params) (out rc.Params, err error) {
+	// Do the call in this process if required
+	if loopback {
+		return doLoopbackCall(path, in)
+	}
+
 	// Do HTTP request
 	client := fshttp.NewClient(fs.Config)
 	url := url
@@ -109,20 +131,70 @@ func doCall(path string, in rc.Params) (out rc.Params, err error) {
 	return out, err
 }
 
-// Run the remote control command passed in
-func run(args []string) (err error) {
-	path := strings.Trim(args[0], "/")
+// doLoopbackCall does the call to the function registered in this
+// rclone without going through the HTTP server
+func doLoopbackCall(path string, in rc.Params) (out rc.Params, err error) {
+	if in == nil {
+		in = make(rc.Params)
+	}
+	call := rc.Get(path)
+	if call == nil {
+		return nil, errors.Errorf("method %q not found", path)
+	}
+	if call.NeedsResponse {
+		return nil, errors.Errorf("method %q can't be used with --loopback", path)
+	}
+	// Round trip the input through JSON so the values are the same
+	// types as they would be from the server
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode JSON")
+	}
+	in = make(rc.Params)
+	err = json.Unmarshal(data, &in)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JSON")
+	}
+	out, err = call.Fn(in)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loopback call %q failed", path)
+	}
+	if out == nil {
+		out = make(rc.Params)
+	}
+	return out, nil
+}
 
-	// parse input
-	in := make(rc.Params)
-	for _, param := range args[1:] {
+// parseArgs parses the JSON blob in jsonInput, if any, and the
+// key=value args into the input parameters
+func parseArgs(jsonInput string, args []string) (in rc.Params, err error) {
+	in = make(rc.Params)
+	if jsonInput != "" {
+		err = json.Unmarshal([]byte(jsonInput), &in)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad --json input")
+		}
+	}
+	for _, param := range args {
 		equals := strings.IndexRune(param, '=')
 		if equals < 0 {
-			return errors.Errorf("No '=' found in parameter %q", param)
+			return nil, errors.Errorf("No '=' found in parameter %q", param)
 		}
 		key, value := param[:equals], param[equals+1:]
 		in[key] = value
 	}
+	return in, nil
+}
+
+// Run the remote control command passed in
+func run(args []string) (err error) {
+	path := strings.Trim(args[0], "/")
+
+	// parse input
+	in, err := parseArgs(jsonInput, args[1:])
+	if err != nil {
+		return err
+	}
 
 	// Do the call
 	out, callErr := doCall(path, in)
@@ -0,0 +1,40 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	in, err := parseArgs("", []string{"a=b", "c=d=e", "f="})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"a": "b", "c": "d=e", "f": ""}, in)
+
+	in, err = parseArgs(`{"a": [1, "2"], "b": {"c": true}}`, []string{"b=override"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"a": []interface{}{float64(1), "2"}, "b": "override"}, in)
+
+	_, err = parseArgs("", []string{"potato"})
+	assert.Error(t, err)
+
+	_, err = parseArgs("{bad json", nil)
+	assert.Error(t, err)
+}
+
+func TestLoopback(t *testing.T) {
+	loopback = true
+	defer func() { loopback = false }()
+
+	out, err := doCall("rc/noop", rc.Params{"a": 1, "b": "two"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"a": float64(1), "b": "two"}, out)
+
+	_, err = doCall("rc/error", rc.Params{})
+	assert.Error(t, err)
+
+	_, err = doCall("not/found", nil)
+	assert.Error(t, err)
+}
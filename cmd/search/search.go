@@ -0,0 +1,96 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showHash  bool
+	noModTime bool
+	noSearch  bool
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&showHash, "hash", "", false, "Include hashes in the output (may take longer).")
+	commandDefintion.Flags().BoolVarP(&noModTime, "no-modtime", "", false, "Don't read the modification time (can speed things up).")
+	commandDefintion.Flags().BoolVarP(&noSearch, "no-search", "", false, "Don't use the remote's search API - list the remote instead.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "search remote:path query",
+	Short: `Search for objects and directories by name in JSON format.`,
+	Long: `
+Search for objects and directories under remote:path whose name
+contains query, ignoring case, and print them in JSON format.
+
+If the remote has a search API (for example Google Drive, OneDrive
+and Dropbox) then it is used, which is much quicker than listing a
+large remote.  Otherwise the remote is listed recursively and the
+names are matched by rclone.  Use --no-search to force the listing
+method.
+
+The output is an array of Items in the same format as lsjson, eg
+
+    rclone search remote:path report
+
+    [
+    {"Path":"2018/report.pdf","Name":"report.pdf","Size":153284,"MimeType":"application/pdf","ModTime":"2018-06-01T11:23:09Z","IsDir":false,"ID":"1mQYFo5Y"}
+    ]
+
+If --hash is not specified the Hashes property won't be emitted.
+
+If --no-modtime is specified then ModTime will be blank.
+
+The Path field is relative to remote:path.
+
+The search obeys the filters and --max-depth.  Note that provider
+search APIs are sometimes only eventually consistent so recently
+created objects may not be found.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		query := args[1]
+		opt := operations.ListJSONOpt{
+			NoModTime: noModTime,
+			ShowHash:  showHash,
+		}
+		cmd.Run(false, false, command, func() error {
+			fmt.Println("[")
+			first := true
+			err := operations.Search(fsrc, "", query, !noSearch, func(entry fs.DirEntry) error {
+				out, err := json.Marshal(operations.NewListJSONItem(entry, &opt))
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal search result")
+				}
+				if first {
+					first = false
+				} else {
+					fmt.Print(",\n")
+				}
+				_, err = os.Stdout.Write(out)
+				if err != nil {
+					return errors.Wrap(err, "failed to write to output")
+				}
+				return nil
+			})
+			if err != nil {
+				return errors.Wrap(err, "error searching")
+			}
+			if !first {
+				fmt.Println()
+			}
+			fmt.Println("]")
+			return nil
+		})
+	},
+}
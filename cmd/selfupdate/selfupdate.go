@@ -0,0 +1,382 @@
+// Package selfupdate provides the selfupdate command.
+package selfupdate
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// Options for the command
+var (
+	check      = false
+	beta       = false
+	version    = ""
+	output     = ""
+	signingKey = ""
+)
+
+// Where the releases are downloaded from - variables for the tests
+var (
+	stableURL = "https://downloads.rclone.org/"
+	betaURL   = "https://beta.rclone.org/"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	flags := commandDefinition.Flags()
+	flags.BoolVarP(&check, "check", "", check, "Check for a newer version only - don't update.")
+	flags.BoolVarP(&beta, "beta", "", beta, "Update to the latest beta instead of the latest release.")
+	flags.StringVarP(&version, "version", "", version, "Install this version, eg 1.50.2, instead of the latest.")
+	flags.StringVarP(&output, "output", "", output, "Write the new binary here instead of replacing the running rclone.")
+	flags.StringVarP(&signingKey, "signing-key", "", signingKey, "File with the PGP public key the releases are signed with.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "selfupdate",
+	Short: `Update the rclone binary.`,
+	Long: `
+This downloads the latest release of rclone for this OS and
+architecture from https://downloads.rclone.org/ and replaces the
+running rclone binary with it.
+
+Use ` + "`--beta`" + ` to update to the latest beta from
+https://beta.rclone.org/ instead, or ` + "`--version`" + ` to install a
+particular release, eg ` + "`--version 1.50.2`" + `, which can be used to
+downgrade.
+
+Use ` + "`--check`" + ` to see whether there is a newer version without
+downloading it.
+
+    $ rclone selfupdate --check
+    yours:  1.49.5
+    latest: 1.50.2 - run "rclone selfupdate" to update
+
+The download is checked against the SHA256 hash in the SHA256SUMS
+file published alongside it, and the PGP signature on SHA256SUMS is
+checked, before it is installed.  Pass the public key the releases
+are signed with, exported from a keyring you trust, in a file with
+` + "`--signing-key`" + `.  This can be armored or binary, eg
+
+    gpg --export --armor KEYID > rclone-release.asc
+    rclone selfupdate --signing-key rclone-release.asc
+
+rclone won't update without it, as the hashes alone only show the
+download wasn't corrupted, not that it is the real release.
+
+The new binary is written next to the old one and renamed over it so
+the update is atomic.  This means rclone needs permission to write to
+the directory it is installed in, so you may need to run it with
+sudo.  On Windows the running binary is renamed to ` + "`rclone.old.exe`" + `
+first as it can't be replaced while it is running.
+
+Use ` + "`--output`" + ` to write the new binary to a different file
+instead of replacing the running one.
+
+If rclone was installed by a package manager then update it with that
+instead.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		cmd.Run(false, false, command, func() error {
+			return selfUpdate()
+		})
+	},
+}
+
+// release describes the release to update to
+type release struct {
+	version string // eg "v1.50.2"
+	url     string // directory the release files are in, eg "https://downloads.rclone.org/v1.50.2/"
+}
+
+// archiveName returns the name of the zip file for the release for
+// goos and goarch
+func (r *release) archiveName(goos, goarch string) string {
+	if goos == "darwin" {
+		goos = "osx"
+	}
+	return fmt.Sprintf("rclone-%s-%s-%s.zip", r.version, goos, goarch)
+}
+
+// matches a version as passed in to --version
+var versionRe = regexp.MustCompile(`^v?\d+\.\d+(\.\d+)?$`)
+
+// findRelease works out which release to update to from the flags
+func findRelease(client *http.Client) (*release, error) {
+	if version != "" {
+		if !versionRe.MatchString(version) {
+			return nil, errors.Errorf("bad --version %q - should be like 1.50.2", version)
+		}
+		if beta {
+			return nil, errors.New("can't use --version with --beta")
+		}
+		vs := "v" + strings.TrimPrefix(version, "v")
+		return &release{version: vs, url: stableURL + vs + "/"}, nil
+	}
+	baseURL := stableURL
+	if beta {
+		baseURL = betaURL
+	}
+	body, err := get(client, baseURL+"version.txt")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find latest version")
+	}
+	vs := strings.TrimSpace(string(body))
+	vs = strings.TrimPrefix(vs, "rclone ")
+	vs = strings.TrimRight(vs, "β")
+	if !strings.HasPrefix(vs, "v") {
+		return nil, errors.Errorf("bad version %q in version.txt", vs)
+	}
+	return &release{version: vs, url: baseURL + vs + "/"}, nil
+}
+
+// get fetches url returning the body
+func get(client *http.Client, url string) (body []byte, err error) {
+	fs.Debugf(nil, "Fetching %q", url)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch %q: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// matches a line of a SHA256SUMS file
+var sumRe = regexp.MustCompile(`^([0-9a-fA-F]{64}) [ *](\S+)$`)
+
+// findSum finds the hash of name in the SHA256SUMS file passed in,
+// which may be clearsigned
+func findSum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		match := sumRe.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match != nil && match[2] == name {
+			return strings.ToLower(match[1]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("no hash for %q in SHA256SUMS", name)
+}
+
+// readKeyring reads the public keys from the armored or binary file
+// name
+func readKeyring(name string) (openpgp.EntityList, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signing key")
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse signing key %q", name)
+	}
+	return keyring, nil
+}
+
+// verifySums checks the clearsigned SHA256SUMS file passed in was
+// signed by a key in keyring and returns the text which was signed
+func verifySums(sums []byte, keyring openpgp.KeyRing) ([]byte, error) {
+	block, _ := clearsign.Decode(sums)
+	if block == nil {
+		return nil, errors.New("SHA256SUMS isn't signed")
+	}
+	_, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad signature on SHA256SUMS")
+	}
+	return block.Plaintext, nil
+}
+
+// extractBinary returns the rclone binary from the zip archive
+func extractBinary(archive []byte, binaryName string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read archive")
+	}
+	for _, file := range zr.File {
+		if filepath.Base(file.Name) != binaryName {
+			continue
+		}
+		in, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		return data, err
+	}
+	return nil, errors.Errorf("%q not found in archive", binaryName)
+}
+
+// download fetches the binary for the release checking the hash and
+// that the hashes were signed by a key in keyring
+func download(client *http.Client, r *release, keyring openpgp.KeyRing) ([]byte, error) {
+	archiveName := r.archiveName(runtime.GOOS, runtime.GOARCH)
+	sums, err := get(client, r.url+"SHA256SUMS")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch hashes")
+	}
+	sums, err = verifySums(sums, keyring)
+	if err != nil {
+		return nil, err
+	}
+	fs.Debugf(nil, "Signature of SHA256SUMS OK")
+	wantSum, err := findSum(sums, archiveName)
+	if err != nil {
+		return nil, err
+	}
+	fs.Infof(nil, "Downloading %s", r.url+archiveName)
+	archive, err := get(client, r.url+archiveName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download")
+	}
+	sum := sha256.Sum256(archive)
+	if gotSum := hex.EncodeToString(sum[:]); gotSum != wantSum {
+		return nil, errors.Errorf("SHA256 of %q is %s but should be %s", archiveName, gotSum, wantSum)
+	}
+	fs.Debugf(nil, "SHA256 of %q OK", archiveName)
+	binaryName := "rclone"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	return extractBinary(archive, binaryName)
+}
+
+// replaceFile atomically replaces target with data keeping its
+// permissions.
+//
+// The data is written to a temporary file in the same directory and
+// renamed over target.  If oldName is set then target is renamed to
+// that first, as is needed for running executables on Windows.
+func replaceFile(target string, data []byte, oldName string) (err error) {
+	mode := os.FileMode(0755)
+	if fi, err := os.Stat(target); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	dir := filepath.Dir(target)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(target)+".new")
+	if err != nil {
+		return errors.Wrap(err, "failed to create new binary - do you have permission to write to the directory?")
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = io.Copy(tmp, bytes.NewReader(data))
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write new binary")
+	}
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return errors.Wrap(err, "failed to set permissions on new binary")
+	}
+	if oldName != "" {
+		_ = os.Remove(oldName)
+		err = os.Rename(target, oldName)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to move old binary out of the way")
+		}
+	}
+	err = os.Rename(tmp.Name(), target)
+	if err != nil {
+		return errors.Wrap(err, "failed to replace binary")
+	}
+	return nil
+}
+
+// selfUpdate does the update
+func selfUpdate() error {
+	client := fshttp.NewClient(fs.Config)
+	r, err := findRelease(client)
+	if err != nil {
+		return err
+	}
+	current := fs.Version
+	if strings.HasSuffix(current, "-DEV") {
+		fs.Logf(nil, "Your version is compiled from git so comparisons may be wrong.")
+	}
+
+	if check {
+		fmt.Printf("yours:  %s\n", strings.TrimPrefix(current, "v"))
+		if r.version == current {
+			fmt.Printf("latest: %s - up to date\n", strings.TrimPrefix(r.version, "v"))
+		} else {
+			fmt.Printf("latest: %s - run \"rclone selfupdate\" to update\n", strings.TrimPrefix(r.version, "v"))
+		}
+		return nil
+	}
+	if r.version == current && version == "" && output == "" {
+		fs.Logf(nil, "rclone %s is already installed", current)
+		return nil
+	}
+
+	if signingKey == "" {
+		return errors.New("need --signing-key to check the release is genuine")
+	}
+	keyring, err := readKeyring(signingKey)
+	if err != nil {
+		return err
+	}
+
+	target := output
+	oldName := ""
+	if target == "" {
+		target, err = os.Executable()
+		if err != nil {
+			return errors.Wrap(err, "failed to find the running rclone")
+		}
+		target, err = filepath.EvalSymlinks(target)
+		if err != nil {
+			return errors.Wrap(err, "failed to find the running rclone")
+		}
+		if runtime.GOOS == "windows" {
+			oldName = strings.TrimSuffix(target, ".exe") + ".old.exe"
+		}
+	}
+
+	data, err := download(client, r, keyring)
+	if err != nil {
+		return err
+	}
+	err = replaceFile(target, data, oldName)
+	if err != nil {
+		return err
+	}
+	fs.Logf(nil, "Successfully updated %q from %s to %s", target, current, r.version)
+	return nil
+}
@@ -0,0 +1,239 @@
+package selfupdate
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestArchiveName(t *testing.T) {
+	r := &release{version: "v1.50.2"}
+	assert.Equal(t, "rclone-v1.50.2-linux-amd64.zip", r.archiveName("linux", "amd64"))
+	assert.Equal(t, "rclone-v1.50.2-osx-amd64.zip", r.archiveName("darwin", "amd64"))
+}
+
+func TestFindSum(t *testing.T) {
+	sums := []byte(`-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA1
+
+0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef  rclone-v1.50.2-linux-amd64.zip
+FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210  rclone-v1.50.2-osx-amd64.zip
+-----BEGIN PGP SIGNATURE-----
+`)
+	sum, err := findSum(sums, "rclone-v1.50.2-linux-amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", sum)
+	sum, err = findSum(sums, "rclone-v1.50.2-osx-amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", sum)
+	_, err = findSum(sums, "rclone-v1.50.2-windows-amd64.zip")
+	assert.Error(t, err)
+}
+
+// makeKey makes a signing key and writes its public key to the
+// armored file name
+func makeKey(t *testing.T, name string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", &packet.Config{RSABits: 1024})
+	require.NoError(t, err)
+	out, err := os.Create(name)
+	require.NoError(t, err)
+	w, err := armor.Encode(out, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, out.Close())
+	return entity
+}
+
+// sign clearsigns text with entity
+func sign(t *testing.T, entity *openpgp.Entity, text string) string {
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, entity.PrivateKey, nil)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(text))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestVerifySums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-selfupdate")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	keyName := filepath.Join(dir, "key.asc")
+	entity := makeKey(t, keyName)
+	keyring, err := readKeyring(keyName)
+	require.NoError(t, err)
+
+	text := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef  rclone-v1.50.2-linux-amd64.zip\n"
+	signed := sign(t, entity, text)
+	plain, err := verifySums([]byte(signed), keyring)
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(text), strings.TrimSpace(string(plain)))
+
+	// tampered with
+	tampered := strings.Replace(signed, "0123", "3210", 1)
+	_, err = verifySums([]byte(tampered), keyring)
+	assert.Error(t, err)
+
+	// not signed
+	_, err = verifySums([]byte(text), keyring)
+	assert.Error(t, err)
+
+	// signed by a different key
+	other := makeKey(t, filepath.Join(dir, "other.asc"))
+	_, err = verifySums([]byte(sign(t, other, text)), keyring)
+	assert.Error(t, err)
+
+	// bad key files
+	_, err = readKeyring(filepath.Join(dir, "notfound"))
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad.asc"), []byte("potato"), 0600))
+	_, err = readKeyring(filepath.Join(dir, "bad.asc"))
+	assert.Error(t, err)
+}
+
+// makeZip makes a release archive with the binary in
+func makeZip(t *testing.T, dir, binaryName string, contents []byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(dir + "/README.txt")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("readme"))
+	require.NoError(t, err)
+	w, err = zw.Create(dir + "/" + binaryName)
+	require.NoError(t, err)
+	_, err = w.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractBinary(t *testing.T) {
+	archive := makeZip(t, "rclone-v1.50.2-linux-amd64", "rclone", []byte("binary"))
+	data, err := extractBinary(archive, "rclone")
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(data))
+	_, err = extractBinary(archive, "rclone.exe")
+	assert.Error(t, err)
+	_, err = extractBinary([]byte("not a zip"), "rclone")
+	assert.Error(t, err)
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-selfupdate")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	target := filepath.Join(dir, "rclone")
+	require.NoError(t, ioutil.WriteFile(target, []byte("old"), 0700))
+
+	require.NoError(t, replaceFile(target, []byte("new"), ""))
+	data, err := ioutil.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(target)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+	}
+
+	oldName := filepath.Join(dir, "rclone.old")
+	require.NoError(t, replaceFile(target, []byte("newer"), oldName))
+	data, err = ioutil.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "newer", string(data))
+	data, err = ioutil.ReadFile(oldName)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	// only the two files should be left
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestSelfUpdate(t *testing.T) {
+	binaryName := "rclone"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	r := &release{version: "v9.9.9"}
+	archiveName := r.archiveName(runtime.GOOS, runtime.GOARCH)
+	archive := makeZip(t, "rclone-v9.9.9", binaryName, []byte("new rclone"))
+	sum := sha256.Sum256(archive)
+
+	dir, err := ioutil.TempDir("", "rclone-selfupdate")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	keyName := filepath.Join(dir, "key.asc")
+	entity := makeKey(t, keyName)
+	sums := sign(t, entity, fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("rclone v9.9.9\n"))
+	})
+	mux.HandleFunc("/v9.9.9/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sums))
+	})
+	mux.HandleFunc("/v9.9.9/"+archiveName, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	oldStableURL, oldOutput, oldSigningKey := stableURL, output, signingKey
+	defer func() {
+		stableURL, output, signingKey = oldStableURL, oldOutput, oldSigningKey
+	}()
+	stableURL = ts.URL + "/"
+	output = filepath.Join(dir, binaryName)
+
+	// the signing key is required
+	signingKey = ""
+	err = selfUpdate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--signing-key")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+
+	signingKey = keyName
+	require.NoError(t, selfUpdate())
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "new rclone", string(data))
+
+	// a bad hash must stop the update
+	sums = sign(t, entity, fmt.Sprintf("%064x  %s\n", 0, archiveName))
+	require.NoError(t, os.Remove(output))
+	err = selfUpdate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SHA256")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+
+	// as must hashes which aren't signed
+	sums = fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName)
+	err = selfUpdate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't signed")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,112 @@
+//+build !windows,!darwin,!plan9
+
+package ftp
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	ftp "github.com/goftp/server"
+	ftpclient "github.com/jlaffaye/ftp"
+	"github.com/ncw/rclone/cmd/serve/ftp/ftpopt"
+	"github.com/ncw/rclone/cmd/serve/servetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConformancePORT = "51781"
+
+// ftpClient is a servetest.Client talking FTP
+type ftpClient struct {
+	c *ftpclient.ServerConn
+}
+
+func (c *ftpClient) List(dir string) (entries []servetest.Entry, err error) {
+	ftpEntries, err := c.c.List("/" + dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ftpEntry := range ftpEntries {
+		entry := servetest.Entry{Name: ftpEntry.Name, Size: int64(ftpEntry.Size)}
+		if ftpEntry.Type == ftpclient.EntryTypeFolder {
+			entry.IsDir = true
+			entry.Size = -1
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (c *ftpClient) Get(remote string) (string, error) {
+	resp, err := c.c.Retr("/" + remote)
+	if err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(resp)
+	closeErr := resp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return string(body), err
+}
+
+func (c *ftpClient) Put(remote string, contents string) error {
+	return c.c.Stor("/"+remote, strings.NewReader(contents))
+}
+
+func (c *ftpClient) Mkdir(dir string) error {
+	return c.c.MakeDir("/" + dir)
+}
+
+func (c *ftpClient) Rename(oldName, newName string) error {
+	return c.c.Rename("/"+oldName, "/"+newName)
+}
+
+func (c *ftpClient) Remove(remote string) error {
+	return c.c.Delete("/" + remote)
+}
+
+func (c *ftpClient) Rmdir(dir string) error {
+	return c.c.RemoveDir("/" + dir)
+}
+
+// TestConformance checks an FTP client sees what it should
+func TestConformance(t *testing.T) {
+	f, clean := servetest.NewConformanceFs(t)
+	defer clean()
+
+	opt := ftpopt.DefaultOpt
+	opt.ListenAddr = testHOST + ":" + testConformancePORT
+	opt.PassivePorts = testPASSIVEPORTRANGE
+	opt.BasicUser = "rclone"
+	opt.BasicPass = "password"
+	s, err := newServer(f, &opt)
+	require.NoError(t, err)
+	go func() {
+		err := s.serve()
+		if err != ftp.ErrServerClosed {
+			assert.NoError(t, err)
+		}
+	}()
+	defer func() {
+		assert.NoError(t, s.close())
+	}()
+
+	// wait for the server to start
+	var c *ftpclient.ServerConn
+	for i := 0; i < 10; i++ {
+		c, err = ftpclient.DialTimeout(net.JoinHostPort(testHOST, testConformancePORT), time.Second)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond << uint(i))
+	}
+	require.NoError(t, err)
+	defer func() { _ = c.Quit() }()
+	require.NoError(t, c.Login(opt.BasicUser, opt.BasicPass))
+
+	servetest.Conformance(t, f, &ftpClient{c: c}, "testdata/golden/conformance.txt")
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/lib/sdactivation"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
 	"github.com/spf13/cobra"
@@ -74,7 +75,7 @@ func newServer(f fs.Fs, opt *ftpopt.Options) (*server, error) {
 		Name:           "Rclone FTP Server",
 		WelcomeMessage: "Welcome on Rclone FTP Server",
 		Factory: &DriverFactory{
-			vfs: vfs.New(f, &vfsflags.Opt),
+			vfs: vfs.NewShared(f, &vfsflags.Opt),
 		},
 		Hostname:     host,
 		Port:         portNum,
@@ -94,6 +95,12 @@ func newServer(f fs.Fs, opt *ftpopt.Options) (*server, error) {
 
 // serve runs the ftp server
 func (s *server) serve() error {
+	// Note that Serve doesn't set up the FEAT reply so clients
+	// see no extensions when socket activated
+	if ln := sdactivation.Listener(""); ln != nil {
+		fs.Logf(s.f, "Serving FTP on systemd socket %v", ln.Addr())
+		return s.srv.Serve(ln)
+	}
 	fs.Logf(s.f, "Serving FTP on %s", s.srv.Hostname+":"+strconv.Itoa(s.srv.Port))
 	return s.srv.ListenAndServe()
 }
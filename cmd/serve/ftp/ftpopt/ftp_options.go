@@ -13,6 +13,9 @@ IPs.  By default it only listens on localhost.  You can use port
 If you set --addr to listen on a public or LAN accessible IP address
 then using Authentication is advised - see the next section for info.
 
+If rclone is started by systemd socket activation then it serves on
+the socket passed in by systemd instead of listening on --addr.
+
 #### Authentication
 
 By default this will serve files without needing a login.
@@ -0,0 +1,104 @@
+// +build go1.8
+
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/cmd/serve/servetest"
+	"github.com/pkg/errors"
+)
+
+// httpClient is a servetest.Client talking HTTP to the read only
+// server, reading the directory listings from the HTML
+type httpClient struct {
+	url string
+}
+
+// do makes a request returning the body or an error with the status
+// if it wasn't successful
+func (c *httpClient) do(method, remote string, body io.Reader) (string, error) {
+	req, err := http.NewRequest(method, c.url+(&url.URL{Path: remote}).String(), body)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", errors.New(resp.Status)
+	}
+	out, err := ioutil.ReadAll(resp.Body)
+	return string(out), err
+}
+
+// matches the links in a directory listing
+var linkRe = regexp.MustCompile(`<a href="([^"]*)">`)
+
+func (c *httpClient) List(dir string) (entries []servetest.Entry, err error) {
+	if dir != "" {
+		dir += "/"
+	}
+	page, err := c.do("GET", dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range linkRe.FindAllStringSubmatch(page, -1) {
+		name, err := url.PathUnescape(strings.TrimPrefix(match[1], "./"))
+		if err != nil {
+			return nil, err
+		}
+		isDir := strings.HasSuffix(name, "/")
+		entries = append(entries, servetest.Entry{Name: strings.TrimSuffix(name, "/"), IsDir: isDir, Size: -1})
+	}
+	return entries, nil
+}
+
+func (c *httpClient) Get(remote string) (string, error) {
+	return c.do("GET", remote, nil)
+}
+
+func (c *httpClient) Put(remote string, contents string) error {
+	_, err := c.do("PUT", remote, strings.NewReader(contents))
+	return err
+}
+
+func (c *httpClient) Mkdir(dir string) error {
+	_, err := c.do("MKCOL", dir, nil)
+	return err
+}
+
+func (c *httpClient) Rename(oldName, newName string) error {
+	_, err := c.do("MOVE", oldName, nil)
+	return err
+}
+
+func (c *httpClient) Remove(remote string) error {
+	_, err := c.do("DELETE", remote, nil)
+	return err
+}
+
+func (c *httpClient) Rmdir(dir string) error {
+	_, err := c.do("DELETE", dir+"/", nil)
+	return err
+}
+
+// TestConformance checks an HTTP client sees what it should
+func TestConformance(t *testing.T) {
+	f, clean := servetest.NewConformanceFs(t)
+	defer clean()
+	s := newServer(f, &httplib.DefaultOpt)
+	ts := httptest.NewServer(http.HandlerFunc(s.handler))
+	defer ts.Close()
+	servetest.Conformance(t, f, &httpClient{url: ts.URL + "/"}, "testdata/golden/conformance.txt")
+}
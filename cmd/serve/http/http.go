@@ -3,9 +3,13 @@ package http
 import (
 	"fmt"
 	"html/template"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,9 +24,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	accessFiles = false
+	at          = fs.TimeOff
+	dirsFirst   = false
+	naturalSort = false
+)
+
 func init() {
 	httpflags.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
+	Command.Flags().BoolVarP(&accessFiles, "access-files", "", accessFiles, "Control access to each directory with "+httplib.AccessFileName+" files.")
+	Command.Flags().VarP(&at, "at", "", "Serve the remote as it was at this time (on remotes which keep versions).")
+	Command.Flags().BoolVarP(&dirsFirst, "dirs-first", "", dirsFirst, "List directories before files in the directory listings.")
+	Command.Flags().BoolVarP(&naturalSort, "natural-sort", "", naturalSort, "Sort the numbers in names by value in the directory listings, eg file2 before file10.")
 }
 
 // Command definition for cobra
@@ -40,9 +55,41 @@ The server will log errors.  Use -v to see access logs.
 
 --bwlimit will be respected for file transfers.  Use --stats to
 control the stats printing.
-` + httplib.Help + vfs.Help,
+
+### Serving the remote as it was ###
+
+Use --at TIME to serve the remote as it was at TIME on remotes which
+keep old versions of files.  This is the same as --version-at so TIME
+can be a date, a date and time or a duration like 3d meaning that long
+ago.  The directory listings show the time being served.
+
+This can be used to browse and download the files from before an
+incident to check a restore, eg
+
+    rclone serve http --at "2019-01-02 15:04:05" b2:bucket/path
+
+Only B2 supports this at the moment - other remotes serve their
+current contents.
+
+### Directory listings ###
+
+The entries in the directory listings are sorted by comparing the
+bytes of their names, so the order is the same on every platform and
+doesn't depend on the locale.  Use --dirs-first to list the
+directories before the files and --natural-sort to compare the
+numbers in the names by value, so file2 comes before file10.
+
+Long listings can be fetched a page at a time by adding ?limit=N to
+the URL of the directory.  If there are more entries the page ends
+with a link with rel="next" to the next page, which carries on after
+the last entry shown (?after=NAME&limit=N) so the pages don't skip or
+repeat entries if files are added or removed in between.
+` + httplib.Help + httplib.AccessHelp + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		if at.IsSet() {
+			fs.Config.VersionAt = at
+		}
 		f := cmd.NewFsSrc(args)
 		cmd.Run(false, true, command, func() error {
 			s := newServer(f, &httpflags.Opt)
@@ -57,19 +104,35 @@ type server struct {
 	f   fs.Fs
 	vfs *vfs.VFS
 	srv *httplib.Server
+	at  fs.Time // time the remote is being served at if set
 }
 
 func newServer(f fs.Fs, opt *httplib.Options) *server {
 	mux := http.NewServeMux()
 	s := &server{
 		f:   f,
-		vfs: vfs.New(f, &vfsflags.Opt),
-		srv: httplib.NewServer(mux, opt),
+		vfs: vfs.NewShared(f, &vfsflags.Opt),
+		at:  fs.Config.VersionAt,
+	}
+	var handler http.Handler = mux
+	if accessFiles {
+		accessHandler := httplib.NewAccessHandler(mux, s.openAccessFile, opt.Authenticated())
+		err := accessHandler.Check()
+		if err != nil {
+			log.Fatalf("Can't use --access-files: %v", err)
+		}
+		handler = accessHandler
 	}
+	s.srv = httplib.NewServer(handler, opt)
 	mux.HandleFunc("/", s.handler)
 	return s
 }
 
+// openAccessFile opens the access file at name in the VFS
+func (s *server) openAccessFile(name string) (io.ReadCloser, error) {
+	return s.vfs.OpenFile(name, os.O_RDONLY, 0)
+}
+
 // serve runs the http server - doesn't return
 func (s *server) serve() {
 	err := s.srv.Serve()
@@ -125,6 +188,119 @@ func (es *entries) addEntry(node interface {
 	*es = append(*es, entry{remote: remote, URL: rest.URLPathEscape(urlRemote), Leaf: leaf})
 }
 
+// name returns the name of the entry without the trailing / of a
+// directory
+func (e *entry) name() string {
+	return strings.TrimSuffix(e.Leaf, "/")
+}
+
+// isDir returns whether the entry is a directory
+func (e *entry) isDir() bool {
+	return strings.HasSuffix(e.Leaf, "/")
+}
+
+// less returns whether a sorts before b.
+//
+// The names are compared byte by byte so the order doesn't depend on
+// the locale.  If dirsFirst is set then directories come before
+// files, and if natural is set then runs of digits are compared by
+// their value.  Entries which are otherwise equal are compared by
+// Leaf so the order is always the same.
+func less(a, b *entry, dirsFirst, natural bool) bool {
+	if dirsFirst && a.isDir() != b.isDir() {
+		return a.isDir()
+	}
+	aName, bName := a.name(), b.name()
+	if natural {
+		if c := naturalCompare(aName, bName); c != 0 {
+			return c < 0
+		}
+	}
+	if aName != bName {
+		return aName < bName
+	}
+	return a.Leaf < b.Leaf
+}
+
+// isDigit returns whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// naturalCompare compares a and b returning -1, 0 or 1, comparing
+// runs of digits by their value and everything else byte by byte.
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			// find the runs of digits
+			iStart, jStart := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			// compare them by value ignoring leading zeros
+			aNum := strings.TrimLeft(a[iStart:i], "0")
+			bNum := strings.TrimLeft(b[jStart:j], "0")
+			if len(aNum) != len(bNum) {
+				if len(aNum) < len(bNum) {
+					return -1
+				}
+				return 1
+			}
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	}
+	return 0
+}
+
+// sort sorts the entries - see less for the order
+func (es entries) sort(dirsFirst, natural bool) {
+	sort.Slice(es, func(i, j int) bool {
+		return less(&es[i], &es[j], dirsFirst, natural)
+	})
+}
+
+// page returns at most limit of the sorted entries which come after
+// the entry with the Leaf after, or all of them if limit is 0.  more
+// is set if there are entries after the page.
+func (es entries) page(after string, limit int, dirsFirst, natural bool) (page entries, more bool) {
+	start := 0
+	if after != "" {
+		cursor := &entry{Leaf: after}
+		start = sort.Search(len(es), func(i int) bool {
+			return less(cursor, &es[i], dirsFirst, natural)
+		})
+	}
+	page = es[start:]
+	if limit > 0 && len(page) > limit {
+		return page[:limit], true
+	}
+	return page, false
+}
+
 // indexPage is a directory listing template
 var indexPage = `<!DOCTYPE html>
 <html lang="en">
@@ -135,6 +311,7 @@ var indexPage = `<!DOCTYPE html>
 <body>
 <h1>{{ .Title }}</h1>
 {{ range $i := .Entries }}<a href="{{ $i.URL }}">{{ $i.Leaf }}</a><br />
+{{ end }}{{ if .Next }}<a rel="next" href="{{ .Next }}">Next page</a><br />
 {{ end }}</body>
 </html>
 `
@@ -146,6 +323,7 @@ var indexTemplate = template.Must(template.New("index").Parse(indexPage))
 type indexData struct {
 	Title   string
 	Entries entries
+	Next    string // URL of the next page if set
 }
 
 // error returns an http.StatusInternalServerError and logs the error
@@ -157,6 +335,18 @@ func internalError(what interface{}, w http.ResponseWriter, text string, err err
 
 // serveDir serves a directory index at dirRemote
 func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string) {
+	query := r.URL.Query()
+	after := query.Get("after")
+	limit := 0
+	if limitString := query.Get("limit"); limitString != "" {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 0 {
+			http.Error(w, "Bad limit", http.StatusBadRequest)
+			return
+		}
+	}
+
 	// List the directory
 	node, err := s.vfs.Stat(dirRemote)
 	if err == vfs.ENOENT {
@@ -179,8 +369,20 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 
 	var out entries
 	for _, node := range dirEntries {
+		if accessFiles && node.Name() == httplib.AccessFileName {
+			continue
+		}
 		out.addEntry(node)
 	}
+	out.sort(dirsFirst, naturalSort)
+	out, more := out.page(after, limit, dirsFirst, naturalSort)
+	next := ""
+	if more {
+		next = "?" + url.Values{
+			"after": {out[len(out)-1].Leaf},
+			"limit": {strconv.Itoa(limit)},
+		}.Encode()
+	}
 
 	// Account the transfer
 	accounting.Stats.Transferring(dirRemote)
@@ -189,7 +391,8 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 	fs.Infof(dirRemote, "%s: Serving directory", r.RemoteAddr)
 	err = indexTemplate.Execute(w, indexData{
 		Entries: out,
-		Title:   fmt.Sprintf("Directory listing of /%s", dirRemote),
+		Title:   s.title(dirRemote),
+		Next:    next,
 	})
 	if err != nil {
 		internalError(dirRemote, w, "Failed to render template", err)
@@ -197,6 +400,15 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 	}
 }
 
+// title returns the title of the directory listing of dirRemote
+func (s *server) title(dirRemote string) string {
+	title := fmt.Sprintf("Directory listing of /%s", dirRemote)
+	if s.at.IsSet() {
+		title += " at " + s.at.String()
+	}
+	return title
+}
+
 // serveFile serves a file object at remote
 func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string) {
 	node, err := s.vfs.Stat(remote)
@@ -212,19 +424,17 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 		http.Error(w, "Not a file", http.StatusNotFound)
 		return
 	}
-	entry := node.DirEntry()
-	if entry == nil {
-		http.Error(w, "Can't open file being written", http.StatusNotFound)
-		return
-	}
-	obj := entry.(fs.Object)
 	file := node.(*vfs.File)
 
 	// Set content length since we know how long the object is
 	w.Header().Set("Content-Length", strconv.FormatInt(node.Size(), 10))
 
-	// Set content type
-	mimeType := fs.MimeType(obj)
+	// Set content type - the object may not exist yet if the file
+	// is being written through the shared VFS
+	mimeType := fs.MimeTypeFromName(remote)
+	if obj, ok := node.DirEntry().(fs.Object); ok {
+		mimeType = fs.MimeType(obj)
+	}
 	if mimeType == "application/octet-stream" && path.Ext(remote) == "" {
 		// Leave header blank so http server guesses
 	} else {
@@ -3,17 +3,16 @@
 package http
 
 import (
-	"flag"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"path"
-	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/ncw/rclone/backend/local"
 	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/cmd/serve/servetest"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/filter"
@@ -22,8 +21,7 @@ import (
 )
 
 var (
-	updateGolden = flag.Bool("updategolden", false, "update golden files for regression test")
-	httpServer   *server
+	httpServer *server
 )
 
 const (
@@ -71,22 +69,6 @@ func TestInit(t *testing.T) {
 	startServer(t, f)
 }
 
-// check body against the file, or re-write body if -updategolden is
-// set.
-func checkGolden(t *testing.T, fileName string, got []byte) {
-	if *updateGolden {
-		t.Logf("Updating golden file %q", fileName)
-		err := ioutil.WriteFile(fileName, got, 0666)
-		require.NoError(t, err)
-	} else {
-		want, err := ioutil.ReadFile(fileName)
-		require.NoError(t, err)
-		wants := strings.Split(string(want), "\n")
-		gots := strings.Split(string(got), "\n")
-		assert.Equal(t, wants, gots, fileName)
-	}
-}
-
 func TestGET(t *testing.T) {
 	for _, test := range []struct {
 		URL    string
@@ -198,7 +180,7 @@ func TestGET(t *testing.T) {
 		body, err := ioutil.ReadAll(resp.Body)
 		require.NoError(t, err)
 
-		checkGolden(t, test.Golden, body)
+		servetest.CheckGolden(t, test.Golden, body)
 	}
 }
 
@@ -232,6 +214,128 @@ func TestAddEntry(t *testing.T) {
 	}, es)
 }
 
+func TestGETPaging(t *testing.T) {
+	get := func(query string) string {
+		resp, err := http.Get(testURL + query)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		if resp.StatusCode != http.StatusOK {
+			return resp.Status
+		}
+		return string(body)
+	}
+
+	page := get("?limit=2")
+	assert.Contains(t, page, `<a href="one%25.txt">one%.txt</a>`)
+	assert.Contains(t, page, `<a href="three/">three/</a>`)
+	assert.NotContains(t, page, "two.txt")
+	assert.Contains(t, page, `<a rel="next" href="?after=three%2F&amp;limit=2">Next page</a>`)
+
+	page = get("?after=three%2F&limit=2")
+	assert.NotContains(t, page, "one%.txt")
+	assert.NotContains(t, page, "three/")
+	assert.Contains(t, page, `<a href="two.txt">two.txt</a>`)
+	assert.NotContains(t, page, "Next page")
+
+	assert.Equal(t, "400 Bad Request", get("?limit=potato"))
+}
+
+// makeEntries makes entries from the leaves passed in
+func makeEntries(leaves ...string) (es entries) {
+	for _, leaf := range leaves {
+		es = append(es, entry{Leaf: leaf})
+	}
+	return es
+}
+
+// leaves returns the leaves of the entries
+func (es entries) leaves() (leaves []string) {
+	for _, e := range es {
+		leaves = append(leaves, e.Leaf)
+	}
+	return leaves
+}
+
+func TestEntriesSort(t *testing.T) {
+	for _, test := range []struct {
+		dirsFirst bool
+		natural   bool
+		want      []string
+	}{
+		{false, false, []string{"B", "a", "dir/", "file", "file/", "file01", "file10", "file2", "é"}},
+		{true, false, []string{"dir/", "file/", "B", "a", "file", "file01", "file10", "file2", "é"}},
+		{false, true, []string{"B", "a", "dir/", "file", "file/", "file01", "file2", "file10", "é"}},
+		{true, true, []string{"dir/", "file/", "B", "a", "file", "file01", "file2", "file10", "é"}},
+	} {
+		es := makeEntries("file10", "é", "file2", "file/", "a", "file01", "B", "dir/", "file")
+		es.sort(test.dirsFirst, test.natural)
+		assert.Equal(t, test.want, es.leaves(), "dirsFirst=%v, natural=%v", test.dirsFirst, test.natural)
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"a", "a", 0},
+		{"a", "b", -1},
+		{"b", "a", 1},
+		{"a", "ab", -1},
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file02", "file2", 0},
+		{"file2a", "file2b", -1},
+		{"file2", "file2a", -1},
+		{"1.10", "1.9", 1},
+		{"x99y", "x100y", -1},
+		{"10", "a", -1},
+	} {
+		assert.Equal(t, test.want, naturalCompare(test.a, test.b), "%q vs %q", test.a, test.b)
+	}
+}
+
+func TestEntriesPage(t *testing.T) {
+	es := makeEntries("a", "b", "c/", "d")
+
+	page, more := es.page("", 0, false, false)
+	assert.Equal(t, []string{"a", "b", "c/", "d"}, page.leaves())
+	assert.False(t, more)
+
+	page, more = es.page("", 2, false, false)
+	assert.Equal(t, []string{"a", "b"}, page.leaves())
+	assert.True(t, more)
+
+	page, more = es.page("b", 2, false, false)
+	assert.Equal(t, []string{"c/", "d"}, page.leaves())
+	assert.False(t, more)
+
+	// carries on from the right place if the cursor entry is gone
+	page, more = es.page("bb", 1, false, false)
+	assert.Equal(t, []string{"c/"}, page.leaves())
+	assert.True(t, more)
+
+	page, more = es.page("d", 2, false, false)
+	assert.Equal(t, []string(nil), page.leaves())
+	assert.False(t, more)
+
+	// directories first
+	es.sort(true, false)
+	page, more = es.page("c/", 2, true, false)
+	assert.Equal(t, []string{"a", "b"}, page.leaves())
+	assert.True(t, more)
+}
+
 func TestFinalise(t *testing.T) {
 	httpServer.srv.Close()
 }
+
+func TestTitle(t *testing.T) {
+	s := &server{}
+	assert.Equal(t, "Directory listing of /dir", s.title("dir"))
+	s.at = fs.Time(time.Date(2019, 1, 2, 15, 4, 5, 0, time.UTC))
+	assert.Equal(t, "Directory listing of /dir at 2019-01-02T15:04:05Z", s.title("dir"))
+}
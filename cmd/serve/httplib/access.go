@@ -0,0 +1,308 @@
+package httplib
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// AccessFileName is the name of the file which controls who may
+// access a directory and its subdirectories
+const AccessFileName = ".rclone-access"
+
+// AccessHelp describes the access files to add to the command help
+var AccessHelp = `
+#### Access files
+
+Use --access-files to control who can read each directory with a file
+called ` + "`" + AccessFileName + "`" + ` in it.  This applies to the directory
+and all its subdirectories.  Each line is a rule, the first rule which
+matches the request decides, and if no rule matches the request is
+refused, eg
+
+    # let alice in from anywhere and anyone on the LAN
+    allow user alice
+    allow ip 192.168.1.0/24
+    deny all
+
+A rule is ` + "`" + `allow` + "`" + ` or ` + "`" + `deny` + "`" + ` followed by ` + "`" + `user NAME` + "`" + ` which
+matches the user authenticated with --htpasswd or --user, ` + "`" + `ip ADDRESS` + "`" + `
+where ADDRESS is an IP address or a CIDR block, or ` + "`" + `all` + "`" + `.  Lines
+starting with # are comments.
+
+User rules need --htpasswd or --user.  Without them rclone won't
+start if the access file in the root has user rules in, and refuses
+all requests to directories below an access file with user rules in.
+
+Every access file from the root down to the directory must allow the
+request, so an access file can only restrict access further than the
+ones above it.  The access files themselves are never served.  They
+are re-read after 10 seconds so they can be edited while the server is
+running.
+`
+
+// accessCacheTime is how long an access file is cached for
+const accessCacheTime = 10 * time.Second
+
+// maxAccessFileSize is the largest access file which will be read
+const maxAccessFileSize = 64 * 1024
+
+// maxAccessCacheEntries is the most directories whose access files
+// are cached
+const maxAccessCacheEntries = 1024
+
+// accessRule is a single line of an access file
+type accessRule struct {
+	allow bool
+	all   bool
+	user  string
+	ipNet *net.IPNet
+}
+
+// matches returns true if the rule applies to user at ip
+func (rule *accessRule) matches(user string, ip net.IP) bool {
+	switch {
+	case rule.all:
+		return true
+	case rule.ipNet != nil:
+		return ip != nil && rule.ipNet.Contains(ip)
+	default:
+		return user != "" && user == rule.user
+	}
+}
+
+// accessList is the parsed contents of an access file
+type accessList []accessRule
+
+// parseAccess parses the contents of an access file
+func parseAccess(data []byte) (rules accessList, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		var rule accessRule
+		switch fields[0] {
+		case "allow":
+			rule.allow = true
+		case "deny":
+		default:
+			return nil, errors.Errorf("line %d: expecting allow or deny but got %q", lineNumber, fields[0])
+		}
+		switch {
+		case len(fields) == 2 && fields[1] == "all":
+			rule.all = true
+		case len(fields) == 3 && fields[1] == "user":
+			rule.user = fields[2]
+		case len(fields) == 3 && fields[1] == "ip":
+			rule.ipNet, err = parseIPNet(fields[2])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNumber)
+			}
+		default:
+			return nil, errors.Errorf("line %d: expecting all, user NAME or ip ADDRESS after %s", lineNumber, fields[0])
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// parseIPNet parses an IP address or CIDR block
+func parseIPNet(s string) (*net.IPNet, error) {
+	if strings.ContainsRune(s, '/') {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.Errorf("bad IP address %q", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// hasUser returns true if any of the rules match on the user
+func (rules accessList) hasUser() bool {
+	for i := range rules {
+		if rules[i].user != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the first rule matching user at ip allows
+// access.  If no rule matches access is refused.
+func (rules accessList) allowed(user string, ip net.IP) bool {
+	for i := range rules {
+		if rules[i].matches(user, ip) {
+			return rules[i].allow
+		}
+	}
+	return false
+}
+
+// AccessOpenFunc opens the access file at name, which is a slash
+// separated path relative to the root of the server.  It should
+// return an error satisfying os.IsNotExist if there isn't one.
+type AccessOpenFunc func(name string) (io.ReadCloser, error)
+
+// accessCacheEntry is a parsed access file, rules is nil if there
+// wasn't one
+type accessCacheEntry struct {
+	rules   accessList
+	err     error
+	expires time.Time
+}
+
+// AccessHandler checks requests against the access files in the
+// directories they are for before passing them on
+type AccessHandler struct {
+	handler       http.Handler
+	open          AccessOpenFunc
+	authenticated bool // set if the user is authenticated
+	mu            sync.Mutex
+	cache         map[string]accessCacheEntry
+}
+
+// NewAccessHandler makes an AccessHandler which reads the access
+// files with open and passes allowed requests to handler.
+//
+// It should be inside the authentication so the user is known, and
+// authenticated should be set if authentication is configured.
+// Without it access files with user rules in refuse everything.
+func NewAccessHandler(handler http.Handler, open AccessOpenFunc, authenticated bool) *AccessHandler {
+	return &AccessHandler{
+		handler:       handler,
+		open:          open,
+		authenticated: authenticated,
+		cache:         make(map[string]accessCacheEntry),
+	}
+}
+
+// Check reads the access file in the root returning an error if it
+// is bad or has user rules in without authentication.  Call it before
+// serving anything.
+func (a *AccessHandler) Check() error {
+	_, err := a.rules("/")
+	return err
+}
+
+// read reads the access file in dir
+func (a *AccessHandler) read(dir string) (data []byte, err error) {
+	in, err := a.open(strings.TrimPrefix(path.Join(dir, AccessFileName), "/"))
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	// read one byte more than the limit so too big files are noticed
+	return ioutil.ReadAll(io.LimitReader(in, maxAccessFileSize+1))
+}
+
+// rules returns the rules for the directory dir, or nil if it doesn't
+// have an access file
+func (a *AccessHandler) rules(dir string) (accessList, error) {
+	now := time.Now()
+	a.mu.Lock()
+	entry, ok := a.cache[dir]
+	a.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.rules, entry.err
+	}
+	entry = accessCacheEntry{expires: now.Add(accessCacheTime)}
+	data, err := a.read(dir)
+	if err == nil && len(data) > maxAccessFileSize {
+		err = errors.New("access file too big")
+	}
+	if err == nil {
+		entry.rules, entry.err = parseAccess(data)
+		if entry.err == nil && entry.rules == nil {
+			entry.rules = accessList{} // empty file - refuse everything
+		}
+		if entry.err == nil && !a.authenticated && entry.rules.hasUser() {
+			entry.err = errors.New("user rules need --htpasswd or --user")
+		}
+	} else if !os.IsNotExist(err) {
+		entry.err = err
+	}
+	if entry.err != nil {
+		entry.err = errors.Wrapf(entry.err, "access file in %q", dir)
+	}
+	a.mu.Lock()
+	if len(a.cache) >= maxAccessCacheEntries {
+		// the directories come from the requests so don't let
+		// the cache grow without limit
+		for cachedDir, cached := range a.cache {
+			if !now.Before(cached.expires) {
+				delete(a.cache, cachedDir)
+			}
+		}
+		if len(a.cache) >= maxAccessCacheEntries {
+			a.cache = make(map[string]accessCacheEntry)
+		}
+	}
+	a.cache[dir] = entry
+	a.mu.Unlock()
+	return entry.rules, entry.err
+}
+
+// ServeHTTP checks the request against the access files and serves it
+// if allowed
+func (a *AccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	if path.Base(urlPath) == AccessFileName {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	dir := urlPath
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		dir = path.Dir(urlPath)
+	}
+	user := User(r)
+	var ip net.IP
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = net.ParseIP(host)
+	}
+
+	// check every directory from the root down
+	var elements []string
+	if dir != "/" {
+		elements = strings.Split(dir[1:], "/")
+	}
+	current := "/"
+	for i := 0; i <= len(elements); i++ {
+		if i > 0 {
+			current = path.Join(current, elements[i-1])
+		}
+		rules, err := a.rules(current)
+		if err != nil {
+			fs.Errorf(nil, "%s: %v", r.RemoteAddr, err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if rules != nil && !rules.allowed(user, ip) {
+			fs.Infof(urlPath, "%s: Access denied by %s in %q", r.RemoteAddr, AccessFileName, current)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	a.handler.ServeHTTP(w, r)
+}
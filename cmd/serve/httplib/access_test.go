@@ -0,0 +1,162 @@
+package httplib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccess(t *testing.T) {
+	rules, err := parseAccess([]byte(`
+# comment
+allow user alice
+deny ip 192.168.1.5
+allow ip 192.168.1.0/24
+deny all
+`))
+	require.NoError(t, err)
+	require.Equal(t, 4, len(rules))
+
+	for _, test := range []struct {
+		user string
+		ip   string
+		want bool
+	}{
+		{"alice", "10.0.0.1", true},
+		{"alice", "192.168.1.5", true},
+		{"bob", "192.168.1.5", false},
+		{"bob", "192.168.1.6", true},
+		{"", "192.168.2.6", false},
+	} {
+		assert.Equal(t, test.want, rules.allowed(test.user, net.ParseIP(test.ip)), test)
+	}
+
+	for _, bad := range []string{
+		"permit all",
+		"allow",
+		"allow user",
+		"allow ip 1.2.3",
+		"deny ip 1.2.3.4/99",
+		"allow all users",
+	} {
+		_, err := parseAccess([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAccessHandler(t *testing.T) {
+	files := map[string]string{
+		AccessFileName:                  "allow ip 127.0.0.1\nallow user alice\n",
+		"private/" + AccessFileName:     "allow user alice\n",
+		"private/sub/" + AccessFileName: "",
+		"broken/" + AccessFileName:      "let everyone in\n",
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(bytes.NewBufferString(content)), nil
+	}
+	handler := NewAccessHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}), open, true)
+	require.NoError(t, handler.Check())
+
+	for _, test := range []struct {
+		path   string
+		user   string
+		remote string
+		want   int
+	}{
+		{"/", "", "127.0.0.1:1234", http.StatusOK},
+		{"/file.txt", "", "127.0.0.1:1234", http.StatusOK},
+		{"/file.txt", "", "10.0.0.1:1234", http.StatusForbidden},
+		{"/file.txt", "alice", "10.0.0.1:1234", http.StatusOK},
+		{"/public/dir/", "", "127.0.0.1:1234", http.StatusOK},
+		{"/private/", "", "127.0.0.1:1234", http.StatusForbidden},
+		{"/private/file.txt", "bob", "127.0.0.1:1234", http.StatusForbidden},
+		{"/private/file.txt", "alice", "127.0.0.1:1234", http.StatusOK},
+		{"/private/sub/file.txt", "alice", "127.0.0.1:1234", http.StatusForbidden},
+		{"/private/sub", "alice", "127.0.0.1:1234", http.StatusOK},
+		{"/broken/file.txt", "alice", "127.0.0.1:1234", http.StatusForbidden},
+		{"/" + AccessFileName, "alice", "127.0.0.1:1234", http.StatusNotFound},
+		{"/private/../private/file.txt", "bob", "127.0.0.1:1234", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest("GET", "http://example.com"+test.path, nil)
+		r.RemoteAddr = test.remote
+		if test.user != "" {
+			r = withUser(r, test.user)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.want, w.Code, test)
+	}
+
+	// a user which hasn't been authenticated doesn't match
+	r := httptest.NewRequest("GET", "http://example.com/file.txt", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.SetBasicAuth("alice", "wrong password")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAccessHandlerNoAuth(t *testing.T) {
+	files := map[string]string{
+		"public/" + AccessFileName: "allow ip 127.0.0.1\n",
+		"users/" + AccessFileName:  "allow ip 127.0.0.1\nallow user alice\n",
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(bytes.NewBufferString(content)), nil
+	}
+	handler := NewAccessHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}), open, false)
+	require.NoError(t, handler.Check())
+
+	for _, test := range []struct {
+		path string
+		want int
+	}{
+		{"/public/file.txt", http.StatusOK},
+		{"/users/file.txt", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest("GET", "http://example.com"+test.path, nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.SetBasicAuth("alice", "anything")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.want, w.Code, test)
+	}
+
+	// user rules in the root stop the server starting
+	files[AccessFileName] = "allow user alice\n"
+	handler = NewAccessHandler(http.NotFoundHandler(), open, false)
+	assert.Error(t, handler.Check())
+}
+
+func TestAccessHandlerCacheLimit(t *testing.T) {
+	open := func(name string) (io.ReadCloser, error) {
+		return nil, os.ErrNotExist
+	}
+	handler := NewAccessHandler(http.NotFoundHandler(), open, true)
+	for i := 0; i < 3*maxAccessCacheEntries; i++ {
+		_, err := handler.rules(fmt.Sprintf("/dir%d", i))
+		require.NoError(t, err)
+	}
+	assert.True(t, len(handler.cache) <= maxAccessCacheEntries)
+}
@@ -24,6 +24,7 @@ func AddFlagsPrefix(flagSet *pflag.FlagSet, prefix string, Opt *httplib.Options)
 	flags.StringVarP(flagSet, &Opt.Realm, prefix+"realm", "", Opt.Realm, "realm for authentication")
 	flags.StringVarP(flagSet, &Opt.BasicUser, prefix+"user", "", Opt.BasicUser, "User name for authentication.")
 	flags.StringVarP(flagSet, &Opt.BasicPass, prefix+"pass", "", Opt.BasicPass, "Password for authentication.")
+	flags.BoolVarP(flagSet, &Opt.Healthz, prefix+"healthz", "", Opt.Healthz, "Serve /healthz for liveness and readiness probes.")
 }
 
 // AddFlags adds flags for the httplib
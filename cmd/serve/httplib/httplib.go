@@ -2,6 +2,7 @@
 package httplib
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -13,6 +14,8 @@ import (
 
 	auth "github.com/abbot/go-http-auth"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/health"
+	"github.com/ncw/rclone/lib/sdactivation"
 )
 
 // Globals
@@ -70,8 +73,39 @@ supply --client-ca also.
 of that with the CA certificate.  --key should be the PEM encoded
 private key and --client-ca should be the PEM encoded client
 certificate authority certificate.
+
+#### Socket activation
+
+If rclone is started by systemd socket activation then it serves on
+the socket passed in by systemd instead of listening on --addr.  This
+means it can be started on demand when the first connection arrives
+and restarted without refusing connections.  Eg with this in
+~/.config/systemd/user/rclone.socket
+
+    [Socket]
+    ListenStream=8080
+
+    [Install]
+    WantedBy=sockets.target
+
+and the rclone command in the ExecStart of rclone.service, run
+"systemctl --user enable --now rclone.socket".
+
+If the remote control server is running too (--rc) then it only uses
+a socket with FileDescriptorName=rc set in the socket unit.
+
+#### Health checks
+
+Use --healthz to serve ` + "`/" + HealthPath + "`" + ` for liveness and readiness probes, eg
+from Kubernetes.  It doesn't need authentication.  It returns 200 and
+"ok" if rclone is healthy, or 503 and a line saying what is wrong,
+for instance if the watchdog of a mount running in the same process
+finds the mount has stopped responding.
 `
 
+// HealthPath is the path the health checks are served on with --healthz
+const HealthPath = "healthz"
+
 // Options contains options for the http Server
 type Options struct {
 	ListenAddr         string        // Port to listen on
@@ -85,6 +119,8 @@ type Options struct {
 	Realm              string        // realm for authentication
 	BasicUser          string        // single username for basic auth if not using Htpasswd
 	BasicPass          string        // password for BasicUser
+	SocketName         string        // name of the systemd socket to use if socket activated
+	Healthz            bool          // serve the health checks on /healthz
 }
 
 // DefaultOpt is the default values used for Options
@@ -130,7 +166,7 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 	}
 
 	// Use htpasswd if required on everything
-	if s.Opt.HtPasswd != "" || s.Opt.BasicUser != "" {
+	if s.Opt.Authenticated() {
 		var secretProvider auth.SecretProvider
 		if s.Opt.HtPasswd != "" {
 			fs.Infof(nil, "Using %q as htpasswd storage", s.Opt.HtPasswd)
@@ -141,7 +177,12 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 			secretProvider = s.singleUserProvider
 		}
 		authenticator := auth.NewBasicAuthenticator(s.Opt.Realm, secretProvider)
-		handler = auth.JustCheck(authenticator, handler.ServeHTTP)
+		handler = withAuth(authenticator, handler)
+	}
+
+	// Serve the health checks without authentication so probes can use them
+	if s.Opt.Healthz {
+		handler = withHealthz(handler)
 	}
 
 	s.useSSL = s.Opt.SslKey != ""
@@ -182,13 +223,65 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 	return s
 }
 
+// ctxKey is the type of the keys of the values stored in the request
+// context
+type ctxKey int
+
+// ctxKeyUser is the key of the authenticated user
+const ctxKeyUser ctxKey = iota
+
+// withUser returns r with user stored as the authenticated user
+func withUser(r *http.Request, user string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), ctxKeyUser, user))
+}
+
+// User returns the user authenticated with --htpasswd or --user for
+// the request, or "" if no authentication is configured.
+//
+// Use this rather than r.BasicAuth() which returns the user whether
+// the password was checked or not.
+func User(r *http.Request) string {
+	user, _ := r.Context().Value(ctxKeyUser).(string)
+	return user
+}
+
+// withAuth returns a handler which checks the authentication and
+// passes the request on to handler with the user stored in it
+func withAuth(authenticator *auth.BasicAuth, handler http.Handler) http.Handler {
+	return authenticator.Wrap(func(w http.ResponseWriter, ar *auth.AuthenticatedRequest) {
+		ar.Header.Set(auth.AuthUsernameHeader, ar.Username)
+		handler.ServeHTTP(w, withUser(&ar.Request, ar.Username))
+	})
+}
+
+// Authenticated returns true if the options configure authentication
+func (o *Options) Authenticated() bool {
+	return o.HtPasswd != "" || o.BasicUser != ""
+}
+
+// withHealthz returns a handler which serves the health checks on
+// /healthz and passes everything else to handler
+func withHealthz(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+HealthPath && (r.Method == "GET" || r.Method == "HEAD") {
+			health.Handler(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // Serve runs the server - returns an error only if
 // the listener was not started; does not block, so
 // use s.Wait() to block on the listener indefinitely.
 func (s *Server) Serve() error {
-	ln, err := net.Listen("tcp", s.httpServer.Addr)
-	if err != nil {
-		return err
+	ln := sdactivation.Listener(s.Opt.SocketName)
+	if ln == nil {
+		var err error
+		ln, err = net.Listen("tcp", s.httpServer.Addr)
+		if err != nil {
+			return err
+		}
 	}
 	s.listener = ln
 	s.waitChan = make(chan struct{})
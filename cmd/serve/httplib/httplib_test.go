@@ -0,0 +1,45 @@
+package httplib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ncw/rclone/lib/health"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHealthz(t *testing.T) {
+	handler := withHealthz(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("handler"))
+	}))
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := get("GET", "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+
+	remove := health.Add("test", func() error { return errors.New("broken") })
+	w = get("GET", "/healthz")
+	remove()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "test: broken\n", w.Body.String())
+
+	for _, test := range []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/"},
+		{"GET", "/healthz/"},
+		{"GET", "/dir/healthz"},
+		{"PUT", "/healthz"},
+	} {
+		w = get(test.method, test.path)
+		assert.Equal(t, "handler", w.Body.String(), test)
+	}
+}
@@ -1,14 +1,28 @@
 package serve
 
 import (
-	"errors"
+	"strings"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/serve/ftp"
 	"github.com/ncw/rclone/cmd/serve/http"
 	"github.com/ncw/rclone/cmd/serve/restic"
 	"github.com/ncw/rclone/cmd/serve/webdav"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Config keys for serving from a config section
+const (
+	protocolKey = "serve"
+	remoteKey   = "remote"
+)
+
+var (
+	configSection = ""
 )
 
 func init() {
@@ -19,6 +33,7 @@ func init() {
 		Command.AddCommand(ftp.Command)
 	}
 	cmd.Root.AddCommand(Command)
+	flags.StringVarP(Command.Flags(), &configSection, "config-section", "", configSection, "Serve using the protocol, remote and options in this config file section.")
 }
 
 // Command definition for cobra
@@ -31,11 +46,90 @@ command requires the use of a subcommand to specify the protocol, eg
     rclone serve http remote:
 
 Each subcommand has its own options which you can see in their help.
+
+### Serving from the config file
+
+Servers can be set up in a section of the config file so they are
+easy to run again, eg
+
+    [myserver]
+    serve = webdav
+    remote = remote:path
+    addr = :8081
+    user = me
+    pass = secret
+    vfs_cache_mode = writes
+
+Then run it with
+
+    rclone serve --config-section myserver
+
+The "serve" key is the protocol and the "remote" key is the remote to
+serve.  All the other keys are the names of the flags for that
+protocol, or global flags, with "-" or "_" between the words.  Flags
+given on the command line take priority over the config section.
+
+Note that global flags which take effect as rclone starts up, such as
+the logging flags, must be given on the command line.  The section
+will be shown by "rclone listremotes" but can't be used as a remote.
 `,
 	RunE: func(command *cobra.Command, args []string) error {
+		if configSection != "" {
+			return serveSection(command, configSection, args)
+		}
 		if len(args) == 0 {
 			return errors.New("serve requires a protocol, eg 'rclone serve http remote:'")
 		}
 		return errors.New("unknown protocol")
 	},
 }
+
+// serveSection runs the server configured in section of the config
+// file using the subcommands of command
+func serveSection(command *cobra.Command, section string, args []string) error {
+	if len(args) != 0 {
+		return errors.New("can't use arguments with --config-section")
+	}
+	protocol := config.FileGet(section, protocolKey)
+	if protocol == "" {
+		return errors.Errorf("config section %q needs %q set to the protocol to serve", section, protocolKey)
+	}
+	remote := config.FileGet(section, remoteKey)
+	if remote == "" {
+		return errors.Errorf("config section %q needs %q set to the remote to serve", section, remoteKey)
+	}
+	var sub *cobra.Command
+	for _, c := range command.Commands() {
+		if c.Name() == protocol {
+			sub = c
+		}
+	}
+	if sub == nil {
+		return errors.Errorf("unknown protocol %q in config section %q", protocol, section)
+	}
+	for _, key := range config.FileSectionKeys(section) {
+		if key == protocolKey || key == remoteKey {
+			continue
+		}
+		name := strings.Replace(key, "_", "-", -1)
+		flagSet := sub.Flags()
+		flag := flagSet.Lookup(name)
+		if flag == nil {
+			flagSet = pflag.CommandLine
+			flag = flagSet.Lookup(name)
+		}
+		if flag == nil {
+			return errors.Errorf("unknown option %q in config section %q", key, section)
+		}
+		if flag.Changed {
+			// set on the command line
+			continue
+		}
+		err := flagSet.Set(name, config.FileGet(section, key))
+		if err != nil {
+			return errors.Wrapf(err, "bad option %q in config section %q", key, section)
+		}
+	}
+	sub.Run(sub, []string{remote})
+	return nil
+}
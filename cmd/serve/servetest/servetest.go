@@ -0,0 +1,262 @@
+// Package servetest provides infrastructure for running tests of the
+// serve protocols.
+//
+// Run starts a server and runs the integration tests of the matching
+// backend against it.
+//
+// Conformance runs the same script of operations against every
+// protocol with a protocol level Client and checks what the client
+// saw against a golden transcript in the testdata directory of the
+// protocol, so a change to one protocol can't silently change the
+// behaviour of the others.
+package servetest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local" // import the local backend for the test remotes
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("updategolden", false, "update golden files for regression test")
+
+// StartFn starts the server serving f returning the config for the
+// backend to talk to it and a function to stop the server.
+type StartFn func(f fs.Fs) (configmap.Simple, func())
+
+// Run starts the server with start then runs the integration tests
+// for the backend called name against it.
+//
+// It should be called from a test in cmd/serve/<name>.
+func Run(t *testing.T, name string, start StartFn) {
+	fstest.Initialise()
+
+	fremote, _, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
+	require.NoError(t, err)
+	defer clean()
+
+	err = fremote.Mkdir("")
+	require.NoError(t, err)
+
+	config, stop := start(fremote)
+	defer stop()
+
+	// Change directory to run the tests and back afterwards
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	err = os.Chdir("../../../backend/" + name)
+	require.NoError(t, err, "failed to cd to %s remote", name)
+	defer func() {
+		require.NoError(t, os.Chdir(cwd))
+	}()
+
+	// Run the backend tests with an on the fly remote
+	remoteName := name + "test"
+	args := []string{"test"}
+	if testing.Verbose() {
+		args = append(args, "-v")
+	}
+	if *fstest.Verbose {
+		args = append(args, "-verbose")
+	}
+	args = append(args, "-list-retries", fmt.Sprint(*fstest.ListRetries))
+	args = append(args, "-remote", remoteName+":")
+	cmd := exec.Command("go", args...)
+	cmd.Env = os.Environ()
+	prefix := "RCLONE_CONFIG_" + strings.ToUpper(remoteName) + "_"
+	cmd.Env = append(cmd.Env, prefix+"TYPE="+name)
+	for k, v := range config {
+		cmd.Env = append(cmd.Env, prefix+strings.ToUpper(k)+"="+v)
+	}
+	out, err := cmd.CombinedOutput()
+	if len(out) != 0 {
+		t.Logf("\n----------\n%s----------\n", string(out))
+	}
+	assert.NoError(t, err, "Running %s integration tests", name)
+}
+
+// CheckGolden checks got against the contents of the file fileName,
+// or re-writes the file with got if -updategolden is set.
+func CheckGolden(t *testing.T, fileName string, got []byte) {
+	if *updateGolden {
+		t.Logf("Updating golden file %q", fileName)
+		err := ioutil.WriteFile(fileName, got, 0666)
+		require.NoError(t, err)
+	} else {
+		want, err := ioutil.ReadFile(fileName)
+		require.NoError(t, err)
+		wants := strings.Split(string(want), "\n")
+		gots := strings.Split(string(got), "\n")
+		assert.Equal(t, wants, gots, fileName)
+	}
+}
+
+// Transcript records what a client saw so it can be checked against
+// a golden file
+type Transcript struct {
+	lines []string
+}
+
+// Printf adds a line to the transcript
+func (tr *Transcript) Printf(format string, a ...interface{}) {
+	tr.lines = append(tr.lines, fmt.Sprintf(format, a...))
+}
+
+// Check the transcript against the golden file fileName
+func (tr *Transcript) Check(t *testing.T, fileName string) {
+	CheckGolden(t, fileName, []byte(strings.Join(tr.lines, "\n")+"\n"))
+}
+
+// Entry is a directory entry as seen by a Client
+type Entry struct {
+	Name  string // leaf name
+	IsDir bool   // set if this is a directory
+	Size  int64  // size of a file or -1 if not known
+}
+
+// String turns an Entry into a line for the transcript
+func (e Entry) String() string {
+	if e.IsDir {
+		return e.Name + "/"
+	}
+	if e.Size < 0 {
+		return e.Name
+	}
+	return fmt.Sprintf("%s (%d)", e.Name, e.Size)
+}
+
+// Client talks the protocol of a server.  Paths are relative to the
+// root of the server.
+//
+// Errors are written to the transcript so should be the errors the
+// protocol returns, eg including status codes.
+type Client interface {
+	// List the directory dir
+	List(dir string) ([]Entry, error)
+	// Get the contents of the file at remote
+	Get(remote string) (string, error)
+	// Put a file with contents at remote
+	Put(remote string, contents string) error
+	// Mkdir makes the directory dir
+	Mkdir(dir string) error
+	// Rename the file oldName to newName
+	Rename(oldName, newName string) error
+	// Remove the file at remote
+	Remove(remote string) error
+	// Rmdir removes the empty directory dir
+	Rmdir(dir string) error
+}
+
+// The files Conformance starts with
+var (
+	conformanceModTime = fstest.Time("2001-02-03T04:05:06Z")
+	conformanceFiles   = []struct {
+		remote   string
+		contents string
+	}{
+		{"one.txt", "one"},
+		{"dir/two.txt", "two two"},
+	}
+)
+
+// NewConformanceFs makes the local Fs for Conformance to be served
+// returning it and a function to remove it.
+func NewConformanceFs(t *testing.T) (fs.Fs, func()) {
+	dir, err := ioutil.TempDir("", "rclone-servetest")
+	require.NoError(t, err)
+	for _, file := range conformanceFiles {
+		name := filepath.Join(dir, filepath.FromSlash(file.remote))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0777))
+		require.NoError(t, ioutil.WriteFile(name, []byte(file.contents), 0666))
+		require.NoError(t, os.Chtimes(name, conformanceModTime, conformanceModTime))
+	}
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	return f, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// Conformance runs a script of operations with c against a server
+// serving f, which should have been made with NewConformanceFs, and
+// checks the transcript of what c saw against the golden file
+// fileName.
+//
+// The transcript finishes with the contents of f so the effect of the
+// operations on the remote is checked too.
+func Conformance(t *testing.T, f fs.Fs, c Client, fileName string) {
+	var tr Transcript
+	result := func(op string, err error) {
+		if err != nil {
+			tr.Printf("%s: error: %v", op, err)
+		} else {
+			tr.Printf("%s: OK", op)
+		}
+	}
+	list := func(dir string) {
+		entries, err := c.List(dir)
+		result(fmt.Sprintf("List %q", dir), err)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		for _, entry := range entries {
+			tr.Printf("  %v", entry)
+		}
+	}
+	get := func(remote string) {
+		contents, err := c.Get(remote)
+		result(fmt.Sprintf("Get %q", remote), err)
+		if err == nil {
+			tr.Printf("  %q", contents)
+		}
+	}
+
+	list("")
+	list("dir")
+	list("notfound")
+	get("one.txt")
+	get("dir/two.txt")
+	get("notfound.txt")
+	result(`Put "three.txt"`, c.Put("three.txt", "three"))
+	result(`Mkdir "newdir"`, c.Mkdir("newdir"))
+	result(`Rename "three.txt" "newdir/three.txt"`, c.Rename("three.txt", "newdir/three.txt"))
+	list("newdir")
+	get("newdir/three.txt")
+	result(`Put "dir/two.txt"`, c.Put("dir/two.txt", "overwritten"))
+	get("dir/two.txt")
+	result(`Remove "newdir/three.txt"`, c.Remove("newdir/three.txt"))
+	result(`Rmdir "newdir"`, c.Rmdir("newdir"))
+	result(`Remove "notfound.txt"`, c.Remove("notfound.txt"))
+	list("")
+
+	tr.Printf("Remote:")
+	listRemote(t, f, "", &tr)
+
+	tr.Check(t, fileName)
+}
+
+// listRemote adds the contents of dir in f to the transcript recursively
+func listRemote(t *testing.T, f fs.Fs, dir string, tr *Transcript) {
+	entries, err := f.List(dir)
+	require.NoError(t, err)
+	sort.Sort(entries)
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Directory:
+			tr.Printf("  %s/", x.Remote())
+			listRemote(t, f, x.Remote(), tr)
+		case fs.Object:
+			tr.Printf("  %s (%d)", x.Remote(), x.Size())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package webdav
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+)
+
+// auditEntry is one line in the audit log
+type auditEntry struct {
+	Time        time.Time
+	User        string `json:",omitempty"`
+	RemoteAddr  string
+	Method      string
+	Path        string
+	Destination string `json:",omitempty"`
+	Status      int
+	OldSize     int64  // -1 if it didn't exist
+	OldHash     string `json:",omitempty"`
+	NewSize     int64  // -1 if it doesn't exist
+	NewHash     string `json:",omitempty"`
+}
+
+// methods which modify the remote
+var auditMethods = map[string]bool{
+	"PUT":       true,
+	"DELETE":    true,
+	"MOVE":      true,
+	"COPY":      true,
+	"MKCOL":     true,
+	"PROPPATCH": true,
+}
+
+// auditHandler logs every request which modifies the remote to an
+// append only file with the size and hash of the file before and
+// after
+type auditHandler struct {
+	w        *WebDAV
+	handler  http.Handler
+	hashType hash.Type
+	mu       sync.Mutex // protect out
+	out      *os.File
+}
+
+// newAuditHandler wraps handler, writing the audit log to the file name
+func newAuditHandler(w *WebDAV, handler http.Handler, name string) (*auditHandler, error) {
+	out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditHandler{
+		w:        w,
+		handler:  handler,
+		hashType: w.f.Hashes().GetOne(),
+		out:      out,
+	}, nil
+}
+
+// statusRecorder remembers the status written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status and passes it on
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// stat returns the size and hash of the file at name or -1 if it
+// doesn't exist
+func (a *auditHandler) stat(name string) (size int64, hashSum string) {
+	node, err := a.w.vfs.Stat(name)
+	if err != nil {
+		return -1, ""
+	}
+	if !node.IsFile() {
+		return 0, ""
+	}
+	size = node.Size()
+	if o, ok := node.DirEntry().(fs.Object); ok && a.hashType != hash.None {
+		hashSum, _ = o.Hash(a.hashType)
+	}
+	return size, hashSum
+}
+
+// ServeHTTP serves the request, auditing it if it is a modification
+func (a *auditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !auditMethods[r.Method] {
+		a.handler.ServeHTTP(w, r)
+		return
+	}
+	entry := auditEntry{
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+	entry.User, _, _ = r.BasicAuth()
+	newPath := entry.Path
+	if destination := r.Header.Get("Destination"); destination != "" {
+		if u, err := url.Parse(destination); err == nil {
+			entry.Destination = u.Path
+			newPath = u.Path
+		}
+	}
+	entry.OldSize, entry.OldHash = a.stat(entry.Path)
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	a.handler.ServeHTTP(rec, r)
+	entry.Status = rec.status
+	entry.NewSize, entry.NewHash = a.stat(newPath)
+	entry.Time = time.Now()
+	a.write(&entry)
+}
+
+// write the entry to the audit log as a line of JSON
+func (a *auditHandler) write(entry *auditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fs.Errorf(nil, "Failed to make audit log entry: %v", err)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.out.Write(append(data, '\n'))
+	if err != nil {
+		fs.Errorf(nil, "Failed to write audit log: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package webdav
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-webdav-audit")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	f, err := fs.NewFs(filepath.Join(dir, "remote"))
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(""))
+
+	auditLog = filepath.Join(dir, "audit.log")
+	defer func() { auditLog = "" }()
+	w, err := newWebDAV(f, &httplib.DefaultOpt)
+	require.NoError(t, err)
+
+	do := func(method, path, body string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.SetBasicAuth("alice", "secret")
+		rec := httptest.NewRecorder()
+		w.handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusCreated, do("PUT", "/file.txt", "hello"))
+	assert.Equal(t, http.StatusCreated, do("PUT", "/file.txt", "hello world"))
+	assert.Equal(t, http.StatusMultiStatus, do("PROPFIND", "/", ""))
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/file.txt", ""))
+
+	in, err := os.Open(auditLog)
+	require.NoError(t, err)
+	defer func() { _ = in.Close() }()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		var entry auditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+
+	// PROPFIND isn't a modification so isn't logged
+	require.Equal(t, 3, len(entries))
+	for _, entry := range entries {
+		assert.Equal(t, "alice", entry.User)
+		assert.Equal(t, "/file.txt", entry.Path)
+	}
+	assert.Equal(t, "PUT", entries[0].Method)
+	assert.Equal(t, int64(-1), entries[0].OldSize)
+	assert.Equal(t, int64(5), entries[0].NewSize)
+	assert.Equal(t, "PUT", entries[1].Method)
+	assert.Equal(t, int64(5), entries[1].OldSize)
+	assert.Equal(t, int64(11), entries[1].NewSize)
+	assert.Equal(t, "DELETE", entries[2].Method)
+	assert.Equal(t, http.StatusNoContent, entries[2].Status)
+	assert.Equal(t, int64(11), entries[2].OldSize)
+	assert.Equal(t, int64(-1), entries[2].NewSize)
+}
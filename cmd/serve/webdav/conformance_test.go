@@ -0,0 +1,146 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/cmd/serve/servetest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// davClient is a servetest.Client talking WebDAV
+type davClient struct {
+	url string
+}
+
+// do makes a request returning the response or an error with the
+// status if it wasn't successful
+func (c *davClient) do(method, remote string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url+(&url.URL{Path: remote}).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_ = resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+// call makes a request discarding the response
+func (c *davClient) call(method, remote string, body io.Reader, headers map[string]string) error {
+	resp, err := c.do(method, remote, body, headers)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// multistatus is the reply to a PROPFIND
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const propfind = `<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/></D:prop></D:propfind>`
+
+func (c *davClient) List(dir string) (entries []servetest.Entry, err error) {
+	resp, err := c.do("PROPFIND", dir+"/", strings.NewReader(propfind), map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result multistatus
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range result.Responses {
+		href, err := url.PathUnescape(response.Href)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Trim(href, "/") == dir {
+			continue
+		}
+		entry := servetest.Entry{Name: path.Base(href), Size: -1}
+		for _, propstat := range response.Propstat {
+			if propstat.Prop.ResourceType.Collection != nil {
+				entry.IsDir = true
+			}
+			if propstat.Prop.ContentLength != "" {
+				entry.Size, _ = strconv.ParseInt(propstat.Prop.ContentLength, 10, 64)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (c *davClient) Get(remote string) (string, error) {
+	resp, err := c.do("GET", remote, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func (c *davClient) Put(remote string, contents string) error {
+	return c.call("PUT", remote, strings.NewReader(contents), nil)
+}
+
+func (c *davClient) Mkdir(dir string) error {
+	return c.call("MKCOL", dir, nil, nil)
+}
+
+func (c *davClient) Rename(oldName, newName string) error {
+	return c.call("MOVE", oldName, nil, map[string]string{"Destination": c.url + (&url.URL{Path: newName}).String()})
+}
+
+func (c *davClient) Remove(remote string) error {
+	return c.call("DELETE", remote, nil, nil)
+}
+
+func (c *davClient) Rmdir(dir string) error {
+	return c.call("DELETE", dir+"/", nil, nil)
+}
+
+// TestConformance checks a WebDAV client sees what it should
+func TestConformance(t *testing.T) {
+	f, clean := servetest.NewConformanceFs(t)
+	defer clean()
+	w, err := newWebDAV(f, &httplib.DefaultOpt)
+	require.NoError(t, err)
+	ts := httptest.NewServer(w.handler)
+	defer ts.Close()
+	servetest.Conformance(t, f, &davClient{url: ts.URL + "/"}, "testdata/golden/conformance.txt")
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.8
 
@@ -21,12 +22,14 @@ import (
 var (
 	hashName string
 	hashType = hash.None
+	auditLog string
 )
 
 func init() {
 	httpflags.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
 	Command.Flags().StringVar(&hashName, "etag-hash", "", "Which hash to use for the ETag, or auto or blank for off")
+	Command.Flags().StringVar(&auditLog, "audit-log", "", "Append a line of JSON to this file for every modification")
 }
 
 // Command definition for cobra
@@ -52,6 +55,27 @@ supported hash on the backend or you can use a named hash such as
 
 Use "rclone hashsum" to see the full list.
 
+#### --audit-log 
+
+If this is set to a file name then a line of JSON is appended to that
+file for every request which modifies the remote (PUT, DELETE, MOVE,
+COPY, MKCOL and PROPPATCH).  Each line records the time, the user, the
+client address, the method, the path (and destination for MOVE and
+COPY), the HTTP status and the size and hash of the file before and
+after the request.  The size is -1 if the file didn't exist.
+
+### Windows
+
+The server sends the ` + "`" + `MS-Author-Via: DAV` + "`" + ` header and accepts the
+file times Windows sets with PROPPATCH, using ` + "`" + `Win32LastModifiedTime` + "`" + `
+as the modification time, so it can be mapped as a drive with
+
+    net use Z: http://localhost:8080/
+
+Note that Windows will only send passwords over HTTP (rather than
+HTTPS) if the BasicAuthLevel of its WebClient service is changed so
+use ` + "`" + `--cert` + "`" + ` and ` + "`" + `--key` + "`" + ` if you need authentication.
+
 ` + httplib.Help + vfs.Help,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
@@ -69,7 +93,10 @@ Use "rclone hashsum" to see the full list.
 			fs.Debugf(f, "Using hash %v for ETag", hashType)
 		}
 		cmd.Run(false, false, command, func() error {
-			w := newWebDAV(f, &httpflags.Opt)
+			w, err := newWebDAV(f, &httpflags.Opt)
+			if err != nil {
+				return err
+			}
 			w.serve()
 			return nil
 		})
@@ -90,29 +117,39 @@ Use "rclone hashsum" to see the full list.
 // might apply". In particular, whether or not renaming a file or directory
 // overwriting another existing file or directory is an error is OS-dependent.
 type WebDAV struct {
-	f   fs.Fs
-	vfs *vfs.VFS
-	srv *httplib.Server
+	f       fs.Fs
+	vfs     *vfs.VFS
+	srv     *httplib.Server
+	handler http.Handler
 }
 
 // check interface
 var _ webdav.FileSystem = (*WebDAV)(nil)
 
 // Make a new WebDAV to serve the remote
-func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
+func newWebDAV(f fs.Fs, opt *httplib.Options) (*WebDAV, error) {
 	w := &WebDAV{
 		f:   f,
-		vfs: vfs.New(f, &vfsflags.Opt),
+		vfs: vfs.NewShared(f, &vfsflags.Opt),
 	}
 
-	handler := &webdav.Handler{
+	var handler http.Handler = &webdav.Handler{
 		FileSystem: w,
 		LockSystem: webdav.NewMemLS(),
 		Logger:     w.logRequest, // FIXME
 	}
+	if auditLog != "" {
+		var err error
+		handler, err = newAuditHandler(w, handler, auditLog)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open --audit-log")
+		}
+	}
+	handler = windowsHandler{handler: handler}
+	w.handler = handler
 
 	w.srv = httplib.NewServer(handler, opt)
-	return w
+	return w, nil
 }
 
 // serve runs the http server - doesn't return
@@ -144,6 +181,10 @@ func (w *WebDAV) Mkdir(ctx context.Context, name string, perm os.FileMode) (err
 // OpenFile opens a file or a directory
 func (w *WebDAV) OpenFile(ctx context.Context, name string, flags int, perm os.FileMode) (file webdav.File, err error) {
 	defer log.Trace(name, "flags=%v, perm=%v", flags, perm)("err = %v", &err)
+	if isProppatch(ctx) {
+		// PROPPATCH only needs to read the file - see windowsHandler
+		flags = os.O_RDONLY
+	}
 	f, err := w.vfs.OpenFile(name, flags, perm)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,107 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.8
+	"golang.org/x/net/webdav"
+)
+
+// msNamespace is the XML namespace of the properties the Windows
+// WebDAV client (the mini-redirector) sets
+const msNamespace = "urn:schemas-microsoft-com:"
+
+// contextKey is the type of the keys put into the request context
+type contextKey int
+
+// proppatchKey marks the context of a PROPPATCH request
+const proppatchKey contextKey = iota
+
+// windowsHandler adds the things the Windows WebDAV client expects
+// which the webdav library doesn't do
+type windowsHandler struct {
+	handler http.Handler
+}
+
+// ServeHTTP serves the request
+func (h windowsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Windows won't map a drive unless the server says it
+	// can be authored with DAV
+	w.Header().Set("MS-Author-Via", "DAV")
+	// Windows sends "Translate: f" to ask for the source of
+	// scripts, but we only have the source so ignore it
+	if r.Method == "PROPPATCH" {
+		// Windows sets its file times with PROPPATCH after
+		// every upload - mark the request so the file is opened
+		// read only to do it, as opening it read write fails
+		// without the VFS cache
+		r = r.WithContext(context.WithValue(r.Context(), proppatchKey, true))
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// isProppatch returns true if ctx is from a PROPPATCH request
+func isProppatch(ctx context.Context) bool {
+	isPatch, _ := ctx.Value(proppatchKey).(bool)
+	return isPatch
+}
+
+// DeadProps returns the dead properties of the handle, of which there
+// aren't any
+func (h Handle) DeadProps() (map[xml.Name]webdav.Property, error) {
+	return nil, nil
+}
+
+// Patch sets the properties of the handle.
+//
+// Only the properties Windows sets are allowed.  Win32LastModifiedTime
+// sets the modification time and the others (Win32CreationTime,
+// Win32LastAccessTime and Win32FileAttributes) are accepted and
+// ignored so Windows doesn't report the upload as failed.
+func (h Handle) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	var modTime time.Time
+	ok := webdav.Propstat{Status: http.StatusOK}
+	forbidden := webdav.Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			prop := webdav.Property{XMLName: p.XMLName}
+			if p.XMLName.Space != msNamespace || patch.Remove {
+				forbidden.Props = append(forbidden.Props, prop)
+				continue
+			}
+			if p.XMLName.Local == "Win32LastModifiedTime" {
+				t, err := http.ParseTime(string(p.InnerXML))
+				if err != nil {
+					fs.Debugf(h.Handle, "Ignoring bad Win32LastModifiedTime %q: %v", p.InnerXML, err)
+				} else {
+					modTime = t
+				}
+			}
+			ok.Props = append(ok.Props, prop)
+		}
+	}
+	if len(forbidden.Props) > 0 {
+		// Patching is all or nothing so the rest fail too
+		propstats := []webdav.Propstat{forbidden}
+		if len(ok.Props) > 0 {
+			ok.Status = http.StatusFailedDependency
+			propstats = append(propstats, ok)
+		}
+		return propstats, nil
+	}
+	if !modTime.IsZero() {
+		if node := h.Handle.Node(); node != nil {
+			err := node.SetModTime(modTime)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return []webdav.Propstat{ok}, nil
+}
+
+// check interface
+var _ webdav.DeadPropsHolder = Handle{}
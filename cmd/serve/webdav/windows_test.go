@@ -0,0 +1,71 @@
+package webdav
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const windowsProppatch = `<?xml version="1.0" encoding="utf-8" ?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:schemas-microsoft-com:">
+<D:set><D:prop>
+<Z:Win32CreationTime>Tue, 02 Jan 2018 03:04:05 GMT</Z:Win32CreationTime>
+<Z:Win32LastAccessTime>Tue, 02 Jan 2018 03:04:05 GMT</Z:Win32LastAccessTime>
+<Z:Win32LastModifiedTime>Tue, 02 Jan 2018 03:04:05 GMT</Z:Win32LastModifiedTime>
+<Z:Win32FileAttributes>00000020</Z:Win32FileAttributes>
+</D:prop></D:set>
+</D:propertyupdate>`
+
+const otherProppatch = `<?xml version="1.0" encoding="utf-8" ?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:X="http://example.com/">
+<D:set><D:prop><X:colour>red</X:colour></D:prop></D:set>
+</D:propertyupdate>`
+
+func TestWindows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-webdav-windows")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	w, err := newWebDAV(f, &httplib.DefaultOpt)
+	require.NoError(t, err)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		w.handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := do("OPTIONS", "/", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "DAV", rec.Header().Get("MS-Author-Via"))
+
+	assert.Equal(t, http.StatusCreated, do("PUT", "/file.txt", "hello").Code)
+
+	// Windows sets the times after the upload
+	rec = do("PROPPATCH", "/file.txt", windowsProppatch)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), "200 OK")
+	assert.NotContains(t, rec.Body.String(), "403")
+	fi, err := os.Stat(filepath.Join(dir, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), fi.ModTime().UTC())
+
+	// Other properties can't be set
+	rec = do("PROPPATCH", "/file.txt", otherProppatch)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), "403 Forbidden")
+}
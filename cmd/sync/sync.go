@@ -1,13 +1,36 @@
 package sync
 
 import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/manifest"
 	"github.com/ncw/rclone/fs/sync"
+	"github.com/ncw/rclone/lib/atexit"
 	"github.com/spf13/cobra"
 )
 
+// Globals
+var (
+	manifestName    = ""
+	manifestSignCmd = ""
+	watch           = false
+	watchDebounce   = 5 * time.Second
+	watchPoll       = time.Minute
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&manifestName, "manifest", "", manifestName, "Write a manifest of the destination to this file in it after the sync, eg "+manifest.DefaultName)
+	commandDefintion.Flags().StringVarP(&manifestSignCmd, "manifest-sign-cmd", "", manifestSignCmd, "Command to sign the manifest with, eg \"gpg --batch --detach-sign --armor\"")
+	commandDefintion.Flags().BoolVarP(&watch, "watch", "", watch, "Keep running, syncing changes to the source as they happen")
+	commandDefintion.Flags().DurationVarP(&watchDebounce, "watch-debounce", "", watchDebounce, "With --watch, wait for changes to be quiet this long before syncing them")
+	commandDefintion.Flags().DurationVarP(&watchPoll, "watch-poll-interval", "", watchPoll, "With --watch, sync this often if the source can't notify changes")
 }
 
 var commandDefintion = &cobra.Command{
@@ -32,12 +55,97 @@ extended explanation in the ` + "`" + `copy` + "`" + ` command above if unsure.
 
 If dest:path doesn't exist, it is created and the source:path contents
 go there.
+
+If the ` + "`" + `--manifest` + "`" + ` flag is supplied then after a successful sync
+rclone will write a manifest to that file in dest:path listing the
+path, size, modification time and hash of every file in the
+destination.  This can be checked later with ` + "`" + `rclone verify` + "`" + `.
+
+If ` + "`" + `--manifest-sign-cmd` + "`" + ` is supplied too then the manifest is
+piped into that command and its output is stored next to the manifest
+with ` + "`" + `.sig` + "`" + ` appended to the name.  The command is split into
+arguments on spaces - use single or double quotes around arguments
+which contain spaces, eg
+
+    rclone sync --manifest .rclone-manifest.json \
+        --manifest-sign-cmd "gpg --batch --detach-sign --armor --local-user 'Archive Key'" \
+        /path/to/archive remote:archive
+
+The manifest and its signature are excluded from the sync so they
+aren't deleted from the destination.  If you sync to the destination
+later without ` + "`" + `--manifest` + "`" + ` you must exclude them yourself or they
+will be deleted, eg
+
+    rclone sync --exclude "/.rclone-manifest.json*" /path/to/archive remote:archive
+
+If the ` + "`" + `--watch` + "`" + ` flag is supplied then after the sync rclone keeps
+running, watching the source for changes and syncing them to the
+destination, which makes it into a simple continuous backup.  Once
+the changes have been quiet for ` + "`" + `--watch-debounce` + "`" + ` (default 5s)
+only the directories they were in are synced again, eg
+
+    rclone sync --watch /home/user/documents remote:documents
+
+Local sources are watched with inotify on Linux as are remotes which
+can notify changes, eg Google Drive.  Other sources are synced again
+in full every ` + "`" + `--watch-poll-interval` + "`" + ` (default 1m).  Errors are logged
+and the watch carries on.  Stop it with CTRL-C which stops any sync in
+progress and shows the final stats.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
+		if watch {
+			if manifestName != "" {
+				log.Fatalf("Can't use --manifest with --watch")
+			}
+			cmd.Run(false, true, command, func() error {
+				return sync.Watch(fdst, fsrc, watchDebounce, watchPoll, stopOnSignal())
+			})
+			return
+		}
 		cmd.Run(true, true, command, func() error {
-			return sync.Sync(fdst, fsrc)
+			var signCmd []string
+			if manifestName != "" {
+				var err error
+				signCmd, err = manifest.SplitCommand(manifestSignCmd)
+				if err != nil {
+					return err
+				}
+				err = manifest.Exclude(manifestName)
+				if err != nil {
+					return err
+				}
+			}
+			err := sync.Sync(fdst, fsrc)
+			if err != nil || manifestName == "" {
+				return err
+			}
+			if fs.Config.DryRun {
+				fs.Logf(fdst, "Not writing manifest as --dry-run")
+				return nil
+			}
+			m, err := manifest.Make(fdst, manifestName)
+			if err != nil {
+				return err
+			}
+			return manifest.Write(fdst, manifestName, m, signCmd)
 		})
 	},
 }
+
+// stopOnSignal returns a channel which is closed when rclone is
+// interrupted so the watch can stop cleanly
+func stopOnSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	sigInt := make(chan os.Signal, 1)
+	signal.Notify(sigInt, syscall.SIGINT, syscall.SIGTERM)
+	atexit.IgnoreSignals()
+	go func() {
+		sig := <-sigInt
+		fs.Logf(nil, "Signal received: %s - stopping the watch", sig)
+		signal.Stop(sigInt)
+		close(stop)
+	}()
+	return stop
+}
@@ -23,7 +23,7 @@ func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	flags := commandDefintion.Flags()
 	flags.BoolVarP(&notCreateNewFile, "no-create", "C", false, "Do not create the file if it does not exist.")
-	flags.StringVarP(&timeAsArgument, "timestamp", "t", "", "Change the modification times to the specified time instead of the current time of day. The argument is of the form 'YYMMDD' (ex. 17.10.30) or 'YYYY-MM-DDTHH:MM:SS' (ex. 2006-01-02T15:04:05)")
+	flags.StringVarP(&timeAsArgument, "timestamp", "t", "", "Change the modification times to the specified time instead of the current time of day. The argument is of the form 'YYMMDD' (ex. 171030) or 'YYYY-MM-DDTHH:MM:SS' (ex. 2006-01-02T15:04:05)")
 }
 
 var commandDefintion = &cobra.Command{
@@ -53,6 +53,13 @@ func Touch(fsrc fs.Fs, srcFileName string) error {
 		timeAtr = timeAtrFromFlags
 	}
 	file, err := fsrc.NewObject(srcFileName)
+	if err != nil && err != fs.ErrorObjectNotFound {
+		return errors.Wrap(err, "touch: couldn't read object")
+	}
+	if fs.Config.DryRun {
+		fs.Logf(srcFileName, "Not touching as --dry-run")
+		return nil
+	}
 	if err != nil {
 		if !notCreateNewFile {
 			var buffer []byte
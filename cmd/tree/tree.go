@@ -109,7 +109,14 @@ short options as they conflict with rclone's short options.
 			opts.DeepLevel = fs.Config.MaxDepth
 		}
 		cmd.Run(false, false, command, func() error {
-			return Tree(fsrc, outFile, &opts)
+			err := Tree(fsrc, outFile, &opts)
+			if outFile != os.Stdout {
+				closeErr := outFile.Close()
+				if err == nil && closeErr != nil {
+					err = errors.Errorf("failed to close output file: %v", closeErr)
+				}
+			}
+			return err
 		})
 		return nil
 	},
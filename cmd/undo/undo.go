@@ -0,0 +1,42 @@
+package undo
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "undo undo.json",
+	Short: `Restore the files deleted or overwritten by a sync.`,
+	Long: `
+Restore the files listed in an undo file written by a previous
+sync, copy or move with ` + "`--undo-file`" + `.
+
+The files are moved back from the ` + "`--backup-dir`" + ` they were
+moved into, most recent first, so a file overwritten more than once
+ends up as it was before the sync.
+
+    rclone sync --backup-dir remote:old --undo-file undo.json source:path remote:current
+    rclone undo undo.json
+
+Files which were deleted without ` + "`--backup-dir`" + ` can't be
+restored - these are logged and counted as errors.
+
+Use ` + "`--dry-run`" + ` to see what would be restored.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		cmd.Run(true, false, command, func() error {
+			u, err := operations.ReadUndoFile(args[0])
+			if err != nil {
+				return err
+			}
+			return operations.Undo(u)
+		})
+	},
+}
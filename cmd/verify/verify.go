@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/manifest"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	manifestName      = manifest.DefaultName
+	manifestVerifyCmd = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&manifestName, "manifest", "", manifestName, "Name of the manifest file in remote:path.")
+	commandDefintion.Flags().StringVarP(&manifestVerifyCmd, "manifest-verify-cmd", "", manifestVerifyCmd, "Command to check the manifest signature with, eg \"gpg --verify {signature} -\"")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "verify remote:path",
+	Short: `Checks the files in the path match the manifest written by sync.`,
+	Long: `
+Checks the files in remote:path match the manifest written by
+` + "`rclone sync --manifest`" + `.  It reports any files which are missing,
+which aren't in the manifest or whose size, modification time or hash
+differ from the manifest.  It doesn't alter the remote.
+
+Use ` + "`--manifest`" + ` to set the name of the manifest file if it isn't
+the default.
+
+If ` + "`--manifest-verify-cmd`" + ` is supplied then the signature of the
+manifest is checked before it is used by running the command with the
+manifest on its standard input.  The string ` + "`{signature}`" + ` in the
+command is replaced with the path of a temporary file holding the
+signature.  If the command fails the verification fails.  The command
+is split into arguments on spaces - use single or double quotes around
+arguments which contain spaces, eg
+
+    rclone verify --manifest-verify-cmd "gpg --verify {signature} -" remote:archive
+
+The same filter flags used when the manifest was written should be
+used when verifying it.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			verifyCmd, err := manifest.SplitCommand(manifestVerifyCmd)
+			if err != nil {
+				return err
+			}
+			m, err := manifest.Read(fsrc, manifestName, verifyCmd)
+			if err != nil {
+				return err
+			}
+			return manifest.Verify(fsrc, manifestName, m)
+		})
+	},
+}
@@ -17,6 +17,49 @@ import (
 // transfer limit is reached.
 var ErrorMaxTransferLimitReached = fserrors.FatalError(errors.New("Max transfer limit reached as set by --max-transfer"))
 
+// CheckMaxTransfer returns ErrorMaxTransferLimitReached if a transfer
+// of size bytes shouldn't be started because of --max-transfer and
+// --cutoff-mode.
+//
+// With --cutoff-mode hard the limit is enforced while reading instead.
+func CheckMaxTransfer(size int64) error {
+	max := int64(fs.Config.MaxTransfer)
+	if max < 0 {
+		return nil
+	}
+	bytes := Stats.GetBytes()
+	switch fs.Config.CutoffMode {
+	case fs.CutoffModeSoft:
+		if bytes >= max {
+			return ErrorMaxTransferLimitReached
+		}
+	case fs.CutoffModeCautious:
+		if size < 0 {
+			size = 0
+		}
+		if bytes+size > max {
+			return ErrorMaxTransferLimitReached
+		}
+	}
+	return nil
+}
+
+// ErrorMaxDurationReached is returned from Read when the max duration
+// is reached.
+var ErrorMaxDurationReached = fserrors.FatalError(errors.New("Max duration reached as set by --max-duration"))
+
+// CheckMaxDuration returns ErrorMaxDurationReached if a transfer
+// shouldn't be started because the time set by --max-duration has
+// passed.
+//
+// With --cutoff-mode hard transfers in progress are stopped too.
+func CheckMaxDuration() error {
+	if fs.Config.MaxDuration <= 0 || Stats.Elapsed() < fs.Config.MaxDuration {
+		return nil
+	}
+	return ErrorMaxDurationReached
+}
+
 // Account limits and accounts for one transfer
 type Account struct {
 	// The mutex is to make sure Read() and Close() aren't called
@@ -40,6 +83,8 @@ type Account struct {
 	closed  bool          // set if the file is closed
 	exit    chan struct{} // channel that will be closed when transfer is finished
 	withBuf bool          // is using a buffered in
+	class   *bwClass      // bandwidth class if set
+	noWait  bool          // don't wait for metadata calls with --metadata-priority
 }
 
 const averagePeriod = 16 // period to do exponentially weighted averages over
@@ -57,6 +102,7 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 		avg:    0,
 		lpTime: time.Now(),
 		max:    int64(fs.Config.MaxTransfer),
+		class:  findBwClass(name),
 	}
 	go acc.averageLoop()
 	Stats.inProgress.set(acc.name, acc)
@@ -90,6 +136,14 @@ func (acc *Account) WithBuffer() *Account {
 	return acc
 }
 
+// Interactive marks the transfer as one a user is waiting for, eg a
+// read through a mount, so it isn't held up by the metadata calls
+// with --metadata-priority.
+func (acc *Account) Interactive() *Account {
+	acc.noWait = true
+	return acc
+}
+
 // GetReader returns the underlying io.ReadCloser under any Buffer
 func (acc *Account) GetReader() io.ReadCloser {
 	acc.mu.Lock()
@@ -156,16 +210,24 @@ func (acc *Account) averageLoop() {
 // read bytes from the io.Reader passed in and account them
 func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 	acc.statmu.Lock()
-	if acc.max >= 0 && Stats.GetBytes() >= acc.max {
+	if acc.max >= 0 && fs.Config.CutoffMode == fs.CutoffModeHard && Stats.GetBytes() >= acc.max {
 		acc.statmu.Unlock()
 		return 0, ErrorMaxTransferLimitReached
 	}
+	if fs.Config.CutoffMode == fs.CutoffModeHard && CheckMaxDuration() != nil {
+		acc.statmu.Unlock()
+		return 0, ErrorMaxDurationReached
+	}
 	// Set start time.
 	if acc.start.IsZero() {
 		acc.start = time.Now()
 	}
 	acc.statmu.Unlock()
 
+	if !acc.noWait {
+		waitPriority()
+	}
+
 	n, err = in.Read(p)
 
 	// Update Stats
@@ -176,7 +238,11 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 
 	Stats.Bytes(int64(n))
 
-	limitBandwidth(n)
+	if acc.class != nil {
+		acc.class.limitBandwidth(n)
+	} else {
+		limitBandwidth(n)
+	}
 	return
 }
 
@@ -2,10 +2,12 @@ package accounting
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
@@ -208,3 +210,141 @@ func TestAccountMaxTransfer(t *testing.T) {
 	assert.Equal(t, ErrorMaxTransferLimitReached, err)
 	assert.True(t, fserrors.IsFatalError(err))
 }
+
+func TestAccountMaxTransferSoft(t *testing.T) {
+	old, oldMode := fs.Config.MaxTransfer, fs.Config.CutoffMode
+	fs.Config.MaxTransfer = 15
+	fs.Config.CutoffMode = fs.CutoffModeSoft
+	defer func() {
+		fs.Config.MaxTransfer, fs.Config.CutoffMode = old, oldMode
+	}()
+	Stats.ResetCounters()
+
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 1, "test")
+
+	// Transfers in progress carry on past the limit
+	var b = make([]byte, 10)
+	for i := 0; i < 3; i++ {
+		n, err := acc.Read(b)
+		assert.Equal(t, 10, n)
+		assert.NoError(t, err)
+	}
+
+	// But new ones can't start
+	assert.Equal(t, ErrorMaxTransferLimitReached, CheckMaxTransfer(1))
+}
+
+func TestCheckMaxTransfer(t *testing.T) {
+	old, oldMode := fs.Config.MaxTransfer, fs.Config.CutoffMode
+	defer func() {
+		fs.Config.MaxTransfer, fs.Config.CutoffMode = old, oldMode
+	}()
+	Stats.ResetCounters()
+	Stats.Bytes(10)
+
+	for _, test := range []struct {
+		max  fs.SizeSuffix
+		mode fs.CutoffMode
+		size int64
+		want error
+	}{
+		{-1, fs.CutoffModeCautious, 100, nil},
+		{15, fs.CutoffModeHard, 100, nil},
+		{15, fs.CutoffModeSoft, 100, nil},
+		{10, fs.CutoffModeSoft, 1, ErrorMaxTransferLimitReached},
+		{15, fs.CutoffModeCautious, 5, nil},
+		{15, fs.CutoffModeCautious, 6, ErrorMaxTransferLimitReached},
+		{15, fs.CutoffModeCautious, -1, nil},
+	} {
+		fs.Config.MaxTransfer, fs.Config.CutoffMode = test.max, test.mode
+		assert.Equal(t, test.want, CheckMaxTransfer(test.size), fmt.Sprintf("%+v", test))
+	}
+}
+
+func TestCheckMaxDuration(t *testing.T) {
+	old, oldMode := fs.Config.MaxDuration, fs.Config.CutoffMode
+	defer func() {
+		fs.Config.MaxDuration, fs.Config.CutoffMode = old, oldMode
+	}()
+	Stats.ResetCounters()
+
+	fs.Config.MaxDuration = 0
+	assert.NoError(t, CheckMaxDuration())
+	fs.Config.MaxDuration = time.Hour
+	assert.NoError(t, CheckMaxDuration())
+	fs.Config.MaxDuration = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, ErrorMaxDurationReached, CheckMaxDuration())
+	assert.True(t, fserrors.IsFatalError(CheckMaxDuration()))
+}
+
+func TestAccountMaxDuration(t *testing.T) {
+	old, oldMode := fs.Config.MaxDuration, fs.Config.CutoffMode
+	defer func() {
+		fs.Config.MaxDuration, fs.Config.CutoffMode = old, oldMode
+	}()
+	fs.Config.MaxDuration = 50 * time.Millisecond
+	Stats.ResetCounters()
+
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 100, "test")
+	var b = make([]byte, 10)
+	n, err := acc.Read(b)
+	assert.Equal(t, 10, n)
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// With soft cutoff transfers in progress carry on
+	fs.Config.CutoffMode = fs.CutoffModeSoft
+	n, err = acc.Read(b)
+	assert.Equal(t, 10, n)
+	assert.NoError(t, err)
+
+	// But with hard cutoff they stop
+	fs.Config.CutoffMode = fs.CutoffModeHard
+	n, err = acc.Read(b)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, ErrorMaxDurationReached, err)
+}
+
+func TestAccountBwClass(t *testing.T) {
+	var classes fs.BwClasses
+	require.NoError(t, classes.Set("*.iso=1M"))
+	require.NoError(t, classes.Set("/docs/**=off"))
+	startBwClasses(classes)
+	defer startBwClasses(nil)
+
+	for _, test := range []struct {
+		name    string
+		pattern string
+	}{
+		{"file.iso", "*.iso"},
+		{"dir/file.iso", "*.iso"},
+		{"docs/file.iso", "*.iso"},
+		{"docs/dir/file.txt", "/docs/**"},
+		{"file.txt", ""},
+		{"dir/docs/file.txt", ""},
+	} {
+		class := findBwClass(test.name)
+		if test.pattern == "" {
+			assert.Nil(t, class, test.name)
+		} else {
+			require.NotNil(t, class, test.name)
+			assert.Equal(t, test.pattern, class.pattern, test.name)
+		}
+	}
+
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+	acc := NewAccountSizeName(in, 1, "big.iso")
+	require.NotNil(t, acc.class)
+	assert.NotNil(t, acc.class.bucket)
+	var buf = make([]byte, 10)
+	n, err := acc.Read(buf)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, err)
+	require.NoError(t, acc.Close())
+
+	// unlimited class has no bucket
+	assert.Nil(t, findBwClass("docs/a").bucket)
+}
@@ -25,6 +25,7 @@ func startSignalHandler() {
 			tokenBucketMu.Lock()
 			bwLimitToggledOff = !bwLimitToggledOff
 			tokenBucket, prevTokenBucket = prevTokenBucket, tokenBucket
+			tokenBucketChanged()
 			s := "disabled"
 			if tokenBucket != nil {
 				s = "enabled"
@@ -0,0 +1,77 @@
+// Bandwidth limits for the transfers of files matching patterns
+
+package accounting
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/filter"
+	"golang.org/x/time/rate"
+)
+
+// bwClass is the token bucket for a --bwlimit-class
+type bwClass struct {
+	pattern string
+	re      *regexp.Regexp
+	bucket  *rate.Limiter // nil if unlimited
+}
+
+// Globals
+var (
+	bwClassesMu sync.Mutex // protects bwClasses
+	bwClasses   []*bwClass
+)
+
+// startBwClasses makes the token buckets for the --bwlimit-class
+// flags
+func startBwClasses(classes fs.BwClasses) {
+	bwClassesMu.Lock()
+	defer bwClassesMu.Unlock()
+	bwClasses = nil
+	for _, class := range classes {
+		re, err := filter.GlobToRegexp(class.Pattern)
+		if err != nil {
+			fs.Errorf(nil, "Ignoring bandwidth class %q: %v", class.Pattern, err)
+			continue
+		}
+		c := &bwClass{
+			pattern: class.Pattern,
+			re:      re,
+		}
+		if class.Bandwidth > 0 {
+			c.bucket = newTokenBucket(class.Bandwidth)
+			fs.Infof(nil, "Starting bandwidth limiter for %q at %vBytes/s", class.Pattern, &class.Bandwidth)
+		} else {
+			fs.Infof(nil, "No bandwidth limit for %q", class.Pattern)
+		}
+		bwClasses = append(bwClasses, c)
+	}
+}
+
+// findBwClass returns the class for the transfer of the file called
+// name or nil if it isn't in one
+func findBwClass(name string) *bwClass {
+	bwClassesMu.Lock()
+	defer bwClassesMu.Unlock()
+	for _, c := range bwClasses {
+		if c.re.MatchString(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// limitBandwidth sleeps for the correct amount of time for the
+// passage of n bytes according to the limit of the class
+func (c *bwClass) limitBandwidth(n int) {
+	if c.bucket == nil {
+		return
+	}
+	err := c.bucket.WaitN(context.Background(), n)
+	if err != nil {
+		fs.Errorf(nil, "Token bucket error for %q: %v", c.pattern, err)
+	}
+}
@@ -0,0 +1,67 @@
+// Give metadata calls priority over data transfers
+
+package accounting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// priorityMaxWait is the longest a data transfer waits for the
+// metadata calls in progress before reading more data.  This stops
+// the transfers being starved by a continuous stream of calls.
+var priorityMaxWait = 100 * time.Millisecond
+
+// Globals
+var (
+	priorityMu    sync.Mutex
+	priorityCalls int           // number of metadata calls in progress
+	priorityIdle  chan struct{} // closed when priorityCalls drops to 0
+)
+
+// Metadata marks the start of a metadata call, eg a listing, which
+// should have priority over data transfers if --metadata-priority is
+// set.  It returns a function which must be called when the call has
+// finished.
+func Metadata() (done func()) {
+	if !fs.Config.MetadataPriority {
+		return func() {}
+	}
+	priorityMu.Lock()
+	if priorityCalls == 0 {
+		priorityIdle = make(chan struct{})
+	}
+	priorityCalls++
+	priorityMu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			priorityMu.Lock()
+			priorityCalls--
+			if priorityCalls == 0 {
+				close(priorityIdle)
+			}
+			priorityMu.Unlock()
+		})
+	}
+}
+
+// waitPriority waits until there are no metadata calls in progress,
+// but for no longer than priorityMaxWait.
+func waitPriority() {
+	priorityMu.Lock()
+	if priorityCalls == 0 {
+		priorityMu.Unlock()
+		return
+	}
+	idle := priorityIdle
+	priorityMu.Unlock()
+	timer := time.NewTimer(priorityMaxWait)
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+	timer.Stop()
+}
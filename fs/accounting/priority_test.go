@@ -0,0 +1,80 @@
+package accounting
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataPriority(t *testing.T) {
+	oldPriority, oldMaxWait := fs.Config.MetadataPriority, priorityMaxWait
+	defer func() {
+		fs.Config.MetadataPriority, priorityMaxWait = oldPriority, oldMaxWait
+	}()
+	priorityMaxWait = 10 * time.Second
+
+	// off by default so nothing waits
+	fs.Config.MetadataPriority = false
+	done := Metadata()
+	assert.Equal(t, 0, priorityCalls)
+	waitPriority()
+	done()
+
+	fs.Config.MetadataPriority = true
+	done1 := Metadata()
+	done2 := Metadata()
+	assert.Equal(t, 2, priorityCalls)
+
+	finished := make(chan struct{})
+	go func() {
+		waitPriority()
+		close(finished)
+	}()
+	done1()
+	done1() // calling done twice is harmless
+	select {
+	case <-finished:
+		t.Fatal("waitPriority returned with a metadata call in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	done2()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitPriority didn't return when the metadata calls finished")
+	}
+	assert.Equal(t, 0, priorityCalls)
+
+	// the wait is limited
+	priorityMaxWait = 10 * time.Millisecond
+	done = Metadata()
+	start := time.Now()
+	waitPriority()
+	assert.True(t, time.Since(start) < 5*time.Second)
+	done()
+}
+
+func TestMetadataPriorityInteractive(t *testing.T) {
+	oldPriority, oldMaxWait := fs.Config.MetadataPriority, priorityMaxWait
+	defer func() {
+		fs.Config.MetadataPriority, priorityMaxWait = oldPriority, oldMaxWait
+	}()
+	fs.Config.MetadataPriority = true
+	priorityMaxWait = 10 * time.Second
+	done := Metadata()
+	defer done()
+
+	in := ioutil.NopCloser(bytes.NewBufferString("hello"))
+	acc := NewAccountSizeName(in, 5, "interactive").Interactive()
+	defer func() { _ = acc.Close() }()
+	start := time.Now()
+	buf := make([]byte, 5)
+	n, err := acc.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
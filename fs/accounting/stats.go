@@ -36,11 +36,15 @@ Returns the following values:
 {
 	"speed": average speed in bytes/sec since start of the process,
 	"bytes": total transferred bytes since the start of the process,
+	"totalBytes": total bytes to transfer including those queued,
+	"eta": estimated time in seconds until all the transfers are complete,
 	"errors": number of errors,
 	"fatalError": whether there has been at least one FatalError,
 	"retryError": whether there has been at least one non-NoRetryError,
 	"checks": number of checked files,
+	"totalChecks": total number of checks including those queued,
 	"transfers": number of transferred files,
+	"totalTransfers": total number of transfers including those queued,
 	"deletes" : number of deleted files,
 	"elapsedTime": time in seconds since the start of the process,
 	"lastError": last occurred error,
@@ -61,11 +65,31 @@ Returns the following values:
 }
 ` + "```" + `
 Values for "transferring", "checking" and "lastError" are only assigned if data is available.
-The value for "eta" is null if an eta cannot be determined.
+If a mount or serve command is running then "vfs" contains a list of the output of vfs/stats for each VFS in use.
+The values for "eta" are null if an eta cannot be determined.
 `,
 	})
 }
 
+// Extra stats added to the output of core/stats
+var (
+	statsFuncsMu sync.Mutex
+	statsFuncs   = map[string]func() interface{}{}
+)
+
+// SetStatsFunc sets fn to be called to add extra stats under key to
+// the output of core/stats.  If fn is nil the stats are removed.  If
+// fn returns nil then key isn't included in the output.
+func SetStatsFunc(key string, fn func() interface{}) {
+	statsFuncsMu.Lock()
+	defer statsFuncsMu.Unlock()
+	if fn == nil {
+		delete(statsFuncs, key)
+	} else {
+		statsFuncs[key] = fn
+	}
+}
+
 // StatsInfo accounts all transfers
 type StatsInfo struct {
 	mu                sync.RWMutex
@@ -102,6 +126,10 @@ func NewStats() *StatsInfo {
 // RemoteStats returns stats for rc
 func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	out = make(rc.Params)
+	// checking and transferring have their own locking so read
+	// here before lock to prevent deadlock on GetBytes
+	transferring, checking := s.transferring.count(), s.checking.count()
+	transferringBytesDone, transferringBytesTotal := s.transferring.progress()
 	s.mu.RLock()
 	dt := time.Now().Sub(s.start)
 	dtSeconds := dt.Seconds()
@@ -109,13 +137,23 @@ func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	if dt > 0 {
 		speed = float64(s.bytes) / dtSeconds
 	}
+	// note that s.bytes already includes transferringBytesDone so
+	// we take it off here to avoid double counting
+	totalSize := s.transferQueueSize + s.bytes + transferringBytesTotal - transferringBytesDone
 	out["speed"] = speed
 	out["bytes"] = s.bytes
+	out["totalBytes"] = totalSize
+	out["eta"] = nil
+	if d, ok := eta(s.bytes, totalSize, speed); ok {
+		out["eta"] = d.Seconds()
+	}
 	out["errors"] = s.errors
 	out["fatalError"] = s.fatalError
 	out["retryError"] = s.retryError
 	out["checks"] = s.checks
+	out["totalChecks"] = int64(s.checkQueue) + s.checks + int64(checking)
 	out["transfers"] = s.transfers
+	out["totalTransfers"] = int64(s.transferQueue) + s.transfers + int64(transferring)
 	out["deletes"] = s.deletes
 	out["elapsedTime"] = dtSeconds
 	s.mu.RUnlock()
@@ -144,6 +182,13 @@ func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	if s.errors > 0 {
 		out["lastError"] = s.lastError
 	}
+	statsFuncsMu.Lock()
+	for key, fn := range statsFuncs {
+		if stats := fn(); stats != nil {
+			out[key] = stats
+		}
+	}
+	statsFuncsMu.Unlock()
 	return out, nil
 }
 
@@ -368,6 +413,14 @@ func (s *StatsInfo) ResetCounters() {
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.start = time.Now()
+}
+
+// Elapsed returns the time since the stats were started or reset
+func (s *StatsInfo) Elapsed() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.start)
 }
 
 // ResetErrors sets the errors count to 0 and resets lastError, fatalError and retryError
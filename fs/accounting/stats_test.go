@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestETA(t *testing.T) {
@@ -49,3 +50,32 @@ func TestPercentage(t *testing.T) {
 	assert.Equal(t, percent(-100, 100), "-")
 	assert.Equal(t, percent(-100, -100), "-")
 }
+
+func TestRemoteStats(t *testing.T) {
+	s := NewStats()
+	s.SetCheckQueue(3, 0)
+	s.SetTransferQueue(2, 300)
+	s.Checking("checking")
+	s.Transferring("transferring")
+	s.Bytes(100)
+	s.DoneTransferring("done", true)
+
+	out, err := s.RemoteStats(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), out["bytes"])
+	assert.Equal(t, int64(400), out["totalBytes"])
+	assert.Equal(t, int64(0), out["checks"])
+	assert.Equal(t, int64(4), out["totalChecks"])
+	assert.Equal(t, int64(1), out["transfers"])
+	assert.Equal(t, int64(4), out["totalTransfers"])
+	assert.Equal(t, []string{"checking"}, out["checking"])
+	assert.Equal(t, []interface{}{"transferring"}, out["transferring"])
+	assert.IsType(t, 0.0, out["eta"])
+
+	// no ETA when there is nothing to transfer
+	s = NewStats()
+	out, err = s.RemoteStats(nil)
+	require.NoError(t, err)
+	assert.Nil(t, out["eta"])
+	assert.Equal(t, int64(0), out["totalBytes"])
+}
@@ -21,6 +21,10 @@ var (
 	currLimit         fs.BwTimeSlot
 )
 
+// tokenBucketCtx is cancelled when tokenBucket is changed to wake up
+// the waiters - protected by tokenBucketMu
+var tokenBucketCtx, tokenBucketCancel = context.WithCancel(context.Background())
+
 const maxBurstSize = 4 * 1024 * 1024 // must be bigger than the biggest request
 
 // make a new empty token bucket with the bandwidth given
@@ -34,6 +38,16 @@ func newTokenBucket(bandwidth fs.SizeSuffix) *rate.Limiter {
 	return newTokenBucket
 }
 
+// tokenBucketChanged should be called when tokenBucket is changed.
+// It wakes up anything waiting on the old token bucket so it waits on
+// the new one instead.
+//
+// Call with tokenBucketMu held
+func tokenBucketChanged() {
+	tokenBucketCancel()
+	tokenBucketCtx, tokenBucketCancel = context.WithCancel(context.Background())
+}
+
 // StartTokenBucket starts the token bucket if necessary
 func StartTokenBucket() {
 	currLimitMu.Lock()
@@ -48,6 +62,8 @@ func StartTokenBucket() {
 		// This function does nothing in windows systems.
 		startSignalHandler()
 	}
+
+	startBwClasses(fs.Config.BwLimitClasses)
 }
 
 // StartTokenTicker creates a ticker to update the bandwidth limiter every minute.
@@ -91,6 +107,9 @@ func StartTokenTicker() {
 					fs.Logf(nil, "Scheduled bandwidth change. Bandwidth limits disabled")
 				}
 
+				if !bwLimitToggledOff {
+					tokenBucketChanged()
+				}
 				currLimit = limitNow
 				tokenBucketMu.Unlock()
 			}
@@ -101,18 +120,29 @@ func StartTokenTicker() {
 
 // limitBandwith sleeps for the correct amount of time for the passage
 // of n bytes according to the current bandwidth limit
+//
+// The lock isn't held while waiting so the limit can be changed, and
+// if it is the wait starts again with the new limit.
 func limitBandwidth(n int) {
-	tokenBucketMu.Lock()
-
-	// Limit the transfer speed if required
-	if tokenBucket != nil {
-		err := tokenBucket.WaitN(context.Background(), n)
+	for {
+		tokenBucketMu.Lock()
+		tb, ctx := tokenBucket, tokenBucketCtx
+		tokenBucketMu.Unlock()
+
+		// Limit the transfer speed if required
+		if tb == nil {
+			return
+		}
+		err := tb.WaitN(ctx, n)
+		if err != nil && ctx.Err() != nil {
+			// the token bucket was changed while waiting
+			continue
+		}
 		if err != nil {
 			fs.Errorf(nil, "Token bucket error: %v", err)
 		}
+		return
 	}
-
-	tokenBucketMu.Unlock()
 }
 
 // SetBwLimit sets the current bandwidth limit
@@ -126,6 +156,31 @@ func SetBwLimit(bandwidth fs.SizeSuffix) {
 		tokenBucket = nil
 		fs.Logf(nil, "Bandwidth limit reset to unlimited")
 	}
+	tokenBucketChanged()
+}
+
+// getBwLimit returns the current bandwidth limit, or 0 if there
+// isn't one
+func getBwLimit() fs.SizeSuffix {
+	tokenBucketMu.Lock()
+	defer tokenBucketMu.Unlock()
+	if tokenBucket == nil {
+		return 0
+	}
+	return fs.SizeSuffix(tokenBucket.Limit())
+}
+
+// bwLimitParams returns the bandwidth limit as rc.Params
+func bwLimitParams(bandwidth fs.SizeSuffix) rc.Params {
+	bytesPerSecond := int64(bandwidth)
+	if bandwidth <= 0 {
+		bandwidth = -1
+		bytesPerSecond = -1
+	}
+	return rc.Params{
+		"rate":           bandwidth.String(),
+		"bytesPerSecond": bytesPerSecond,
+	}
 }
 
 // Remote control for the token bucket
@@ -135,7 +190,7 @@ func init() {
 		Fn: func(in rc.Params) (out rc.Params, err error) {
 			ibwlimit, ok := in["rate"]
 			if !ok {
-				return out, errors.Errorf("parameter rate not found")
+				return bwLimitParams(getBwLimit()), nil
 			}
 			bwlimit, ok := ibwlimit.(string)
 			if !ok {
@@ -151,7 +206,7 @@ func init() {
 			}
 			bw := bws[0]
 			SetBwLimit(bw.Bandwidth)
-			return rc.Params{"rate": bw.Bandwidth.String()}, nil
+			return bwLimitParams(bw.Bandwidth), nil
 		},
 		Title: "Set the bandwidth limit.",
 		Help: `
@@ -164,6 +219,18 @@ Eg
 
 The format of the parameter is exactly the same as passed to --bwlimit
 except only one bandwidth may be specified.
+
+The new limit applies straight away, including to the transfers which
+are running.  Leave out rate to read the current limit without
+changing it.  Both return the limit like this
+
+    {
+        "bytesPerSecond": 1048576,
+        "rate": "1M"
+    }
+
+where "rate" is "off" and "bytesPerSecond" is -1 if there is no
+limit.
 `,
 	})
 }
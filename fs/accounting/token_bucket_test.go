@@ -0,0 +1,67 @@
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetBwLimitWakesWaiters(t *testing.T) {
+	defer SetBwLimit(0)
+	SetBwLimit(1)
+
+	// this would take 1000s at the current limit
+	finished := make(chan struct{})
+	go func() {
+		limitBandwidth(1000)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		t.Fatal("limitBandwidth didn't wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// changing the limit must not wait for limitBandwidth and
+	// must let it carry on at the new rate
+	changed := make(chan struct{})
+	go func() {
+		SetBwLimit(0)
+		close(changed)
+	}()
+	for _, c := range []chan struct{}{changed, finished} {
+		select {
+		case <-c:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the bandwidth limit to change")
+		}
+	}
+}
+
+func TestRcBwlimit(t *testing.T) {
+	defer SetBwLimit(0)
+	call := rc.Get("core/bwlimit")
+	require.NotNil(t, call)
+
+	out, err := call.Fn(rc.Params{"rate": "1M"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"rate": "1M", "bytesPerSecond": int64(1048576)}, out)
+
+	out, err = call.Fn(rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"rate": "1M", "bytesPerSecond": int64(1048576)}, out)
+
+	out, err = call.Fn(rc.Params{"rate": "off"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"rate": "off", "bytesPerSecond": int64(-1)}, out)
+
+	out, err = call.Fn(rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"rate": "off", "bytesPerSecond": int64(-1)}, out)
+
+	_, err = call.Fn(rc.Params{"rate": "08:00,1M 18:00,off"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,505 @@
+// Package bisync keeps two remotes in sync with each other, copying
+// changes and deletions made on either side to the other.
+//
+// The listings of both sides used for each run, updated with the
+// changes the run made, are stored so the next run can tell which
+// side changed a file.
+package bisync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// Ways of resolving a file changed on both sides
+const (
+	ConflictNewer    = "newer"     // the newer file overwrites the older
+	ConflictKeepBoth = "keep-both" // rename both files and keep them on both sides
+	ConflictFail     = "fail"      // stop without changing anything
+)
+
+// Suffixes added to the names of the files kept by ConflictKeepBoth
+const (
+	ConflictSuffix1 = ".conflict1"
+	ConflictSuffix2 = ".conflict2"
+)
+
+// Options control a bisync
+type Options struct {
+	Resync   bool   // make both sides the same from scratch ignoring the stored listings
+	Conflict string // how to resolve files changed on both sides
+	Force    bool   // allow every file on one side to be deleted
+	WorkDir  string // where to store the listings, defaults to the cache dir
+}
+
+// DefaultOptions returns the default options for a bisync
+func DefaultOptions() *Options {
+	return &Options{
+		Conflict: ConflictNewer,
+		WorkDir:  filepath.Join(config.CacheDir, "bisync"),
+	}
+}
+
+// fileState is the state of a file stored between runs
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// listing is the state of all the files on one side
+type listing map[string]fileState
+
+// state is what is stored between runs
+type state struct {
+	Path1  string
+	Path2  string
+	Files1 listing
+	Files2 listing
+}
+
+// change is how a file has changed on one side since the last run
+type change int
+
+// The kinds of change
+const (
+	unchanged change = iota
+	added
+	modified
+	deleted
+)
+
+var changeNames = [...]string{
+	unchanged: "unchanged",
+	added:     "added",
+	modified:  "modified",
+	deleted:   "deleted",
+}
+
+// String turns a change into a string
+func (c change) String() string {
+	return changeNames[c]
+}
+
+// side is one of the remotes being synced
+type side struct {
+	f       fs.Fs
+	name    string // "Path1" or "Path2" for logging
+	objects map[string]fs.Object
+	changes map[string]change
+	files   listing // the listing to store for the next run
+}
+
+// list reads all the objects on the side
+func (s *side) list() error {
+	objs, _, err := walk.GetAll(s.f, "", false, -1)
+	if err == fs.ErrorDirNotFound {
+		err = nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to list %s", s.name)
+	}
+	s.objects = make(map[string]fs.Object, len(objs))
+	for _, o := range objs {
+		s.objects[o.Remote()] = o
+	}
+	s.files = s.listing()
+	return nil
+}
+
+// listing returns the state of the objects on the side
+func (s *side) listing() listing {
+	l := make(listing, len(s.objects))
+	for remote, o := range s.objects {
+		l[remote] = fileState{Size: o.Size(), ModTime: o.ModTime()}
+	}
+	return l
+}
+
+// findChanges works out what has changed since prev
+func (s *side) findChanges(prev listing) {
+	s.changes = make(map[string]change)
+	window := fs.GetModifyWindow(s.f)
+	for remote, o := range s.objects {
+		old, ok := prev[remote]
+		switch {
+		case !ok:
+			s.changes[remote] = added
+		case old.Size != o.Size():
+			s.changes[remote] = modified
+		case window != fs.ModTimeNotSupported:
+			dt := o.ModTime().Sub(old.ModTime)
+			if dt >= window || dt <= -window {
+				s.changes[remote] = modified
+			}
+		}
+	}
+	for remote := range prev {
+		if _, ok := s.objects[remote]; !ok {
+			s.changes[remote] = deleted
+		}
+	}
+}
+
+// checkDeletes refuses to delete every file because of this side
+// unless --force is set as it probably means the side is missing
+func (s *side) checkDeletes(prev listing, opt *Options) error {
+	if opt.Force || len(prev) == 0 || len(s.objects) != 0 {
+		return nil
+	}
+	return fserrors.NoRetryError(errors.Errorf("all %d files on %s were deleted - use --force if that is what you want", len(prev), s.name))
+}
+
+// fsName returns the remote:path of f
+func fsName(f fs.Fs) string {
+	return f.Name() + ":" + f.Root()
+}
+
+// unsafeChars are replaced in the names of the listings
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// stateName makes a file name to store the state of f1 and f2 in
+func stateName(f1, f2 fs.Fs) string {
+	clean := func(f fs.Fs) string {
+		return unsafeChars.ReplaceAllString(fsName(f), "_")
+	}
+	return clean(f1) + ".." + clean(f2) + ".json"
+}
+
+// readState reads the state from name, returning nil if it doesn't
+// exist
+func readState(name string) (*state, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := new(state)
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q", name)
+	}
+	return st, nil
+}
+
+// writeState writes the state to name atomically
+func writeState(name string, st *state) error {
+	data, err := json.MarshalIndent(st, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// bisync holds the state of a run
+type bisync struct {
+	opt       *Options
+	s1, s2    *side
+	stateFile string
+	errCount  int
+	lastErr   error
+}
+
+// Bisync makes f1 and f2 the same by copying files changed on one
+// side to the other and deleting files deleted on one side from the
+// other.
+//
+// The first run must have opt.Resync set to make the sides the same
+// and store the listings for the next run.
+func Bisync(f1, f2 fs.Fs, opt *Options) (err error) {
+	switch opt.Conflict {
+	case ConflictNewer, ConflictKeepBoth, ConflictFail:
+	default:
+		return errors.Errorf("unknown conflict resolution %q", opt.Conflict)
+	}
+	b := &bisync{
+		opt: opt,
+		s1:  &side{f: f1, name: "Path1"},
+		s2:  &side{f: f2, name: "Path2"},
+	}
+	err = os.MkdirAll(opt.WorkDir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make work dir")
+	}
+	b.stateFile = filepath.Join(opt.WorkDir, stateName(f1, f2))
+
+	// Stop two runs on the same paths at once
+	lockFile := b.stateFile + ".lck"
+	lock, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return errors.Errorf("bisync already running - remove %q if it isn't", lockFile)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to make lock file")
+	}
+	fmt.Fprintf(lock, "%d\n", os.Getpid())
+	_ = lock.Close()
+	defer func() {
+		removeErr := os.Remove(lockFile)
+		if err == nil {
+			err = removeErr
+		}
+	}()
+
+	if opt.Resync {
+		err = b.resync()
+	} else {
+		err = b.run()
+	}
+	if err != nil {
+		return err
+	}
+	if b.errCount > 0 {
+		fs.Errorf(nil, "Bisync had %d errors - not updating the listings so the changes will be found again next run", b.errCount)
+		return b.lastErr
+	}
+	return b.save()
+}
+
+// resync makes the sides the same by copying the files missing or
+// different on Path2 from Path1 then the files missing on Path1 from
+// Path2.  If files differ Path1 wins.
+func (b *bisync) resync() error {
+	fs.Infof(nil, "Resyncing %v and %v", b.s1.f, b.s2.f)
+	for _, s := range []*side{b.s1, b.s2} {
+		if err := s.list(); err != nil {
+			return err
+		}
+	}
+	for _, remote := range sortedKeys(b.s1.objects) {
+		b.copy(b.s1, b.s2, remote, remote)
+	}
+	for _, remote := range sortedKeys(b.s2.objects) {
+		if _, ok := b.s1.objects[remote]; !ok {
+			b.copy(b.s2, b.s1, remote, remote)
+		}
+	}
+	return nil
+}
+
+// run syncs the changes since the last run
+func (b *bisync) run() error {
+	st, err := readState(b.stateFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read listings")
+	}
+	if st == nil {
+		return fserrors.NoRetryError(errors.New("no listings found for these paths - run with --resync first"))
+	}
+	for _, s := range []*side{b.s1, b.s2} {
+		if err := s.list(); err != nil {
+			return err
+		}
+	}
+	if err := b.s1.checkDeletes(st.Files1, b.opt); err != nil {
+		return err
+	}
+	if err := b.s2.checkDeletes(st.Files2, b.opt); err != nil {
+		return err
+	}
+	b.s1.findChanges(st.Files1)
+	b.s2.findChanges(st.Files2)
+
+	// Find the files changed on both sides first so --conflict fail
+	// can stop before anything is done
+	var remotes, conflicts []string
+	seen := make(map[string]bool)
+	for _, s := range []*side{b.s1, b.s2} {
+		for remote := range s.changes {
+			if !seen[remote] {
+				seen[remote] = true
+				remotes = append(remotes, remote)
+			}
+		}
+	}
+	sort.Strings(remotes)
+	for _, remote := range remotes {
+		if b.isConflict(remote) {
+			conflicts = append(conflicts, remote)
+		}
+	}
+	if len(conflicts) > 0 && b.opt.Conflict == ConflictFail {
+		for _, remote := range conflicts {
+			fs.Errorf(remote, "Changed on both sides (%v on Path1, %v on Path2)", b.s1.changes[remote], b.s2.changes[remote])
+		}
+		return fserrors.NoRetryError(errors.Errorf("%d files changed on both sides", len(conflicts)))
+	}
+	fs.Infof(nil, "Found %d changes on Path1, %d changes on Path2 and %d conflicts", len(b.s1.changes), len(b.s2.changes), len(conflicts))
+
+	for _, remote := range remotes {
+		b.syncFile(remote)
+	}
+	return nil
+}
+
+// isConflict returns true if remote was changed on both sides and
+// the files aren't the same now
+func (b *bisync) isConflict(remote string) bool {
+	c1, c2 := b.s1.changes[remote], b.s2.changes[remote]
+	if c1 == unchanged || c2 == unchanged || c1 == deleted || c2 == deleted {
+		return false
+	}
+	return !operations.Equal(b.s1.objects[remote], b.s2.objects[remote])
+}
+
+// syncFile syncs the changes to remote
+func (b *bisync) syncFile(remote string) {
+	c1, c2 := b.s1.changes[remote], b.s2.changes[remote]
+	switch {
+	case c1 == deleted && c2 == deleted:
+		fs.Debugf(remote, "Deleted on both sides")
+	case c1 == deleted && c2 == unchanged:
+		b.delete(b.s2, remote)
+	case c2 == deleted && c1 == unchanged:
+		b.delete(b.s1, remote)
+	case c1 == deleted:
+		fs.Logf(remote, "Deleted on Path1 but %v on Path2 - keeping it", c2)
+		b.copy(b.s2, b.s1, remote, remote)
+	case c2 == deleted:
+		fs.Logf(remote, "Deleted on Path2 but %v on Path1 - keeping it", c1)
+		b.copy(b.s1, b.s2, remote, remote)
+	case c2 == unchanged:
+		b.copy(b.s1, b.s2, remote, remote)
+	case c1 == unchanged:
+		b.copy(b.s2, b.s1, remote, remote)
+	case !b.isConflict(remote):
+		fs.Debugf(remote, "Changed on both sides but identical")
+	default:
+		b.resolve(remote)
+	}
+}
+
+// resolve a file changed differently on both sides
+func (b *bisync) resolve(remote string) {
+	o1, o2 := b.s1.objects[remote], b.s2.objects[remote]
+	switch b.opt.Conflict {
+	case ConflictNewer:
+		if o2.ModTime().After(o1.ModTime()) {
+			fs.Logf(remote, "Changed on both sides - Path2 is newer so using it")
+			b.copy(b.s2, b.s1, remote, remote)
+		} else {
+			fs.Logf(remote, "Changed on both sides - Path1 is newer so using it")
+			b.copy(b.s1, b.s2, remote, remote)
+		}
+	case ConflictKeepBoth:
+		name1, name2 := remote+ConflictSuffix1, remote+ConflictSuffix2
+		fs.Logf(remote, "Changed on both sides - keeping both as %s and %s", name1, name2)
+		if !b.move(b.s1, name1, remote) || !b.move(b.s2, name2, remote) {
+			return
+		}
+		if fs.Config.DryRun {
+			// the renamed files don't exist to copy
+			return
+		}
+		b.copy(b.s1, b.s2, name1, name1)
+		b.copy(b.s2, b.s1, name2, name2)
+	}
+}
+
+// move the file srcRemote on s to dstRemote returning true if it
+// worked
+func (b *bisync) move(s *side, dstRemote, srcRemote string) bool {
+	if !b.check(operations.MoveFile(s.f, s.f, dstRemote, srcRemote)) {
+		return false
+	}
+	delete(s.files, srcRemote)
+	b.record(s, dstRemote)
+	return true
+}
+
+// copy the file srcRemote on src to dstRemote on dst
+func (b *bisync) copy(src, dst *side, dstRemote, srcRemote string) {
+	fs.Debugf(srcRemote, "Copying from %s to %s", src.name, dst.name)
+	if b.check(operations.CopyFile(dst.f, src.f, dstRemote, srcRemote)) {
+		b.record(dst, dstRemote)
+	}
+}
+
+// delete remote from s
+func (b *bisync) delete(s *side, remote string) {
+	o, ok := s.objects[remote]
+	if !ok {
+		return
+	}
+	fs.Debugf(remote, "Deleting from %s", s.name)
+	if b.check(operations.DeleteFile(o)) {
+		delete(s.files, remote)
+	}
+}
+
+// record the state of remote on s after it was changed by the run.
+//
+// Only the file the run wrote is read so changes made to other files
+// while the run was going will be found by the next run.
+func (b *bisync) record(s *side, remote string) {
+	if fs.Config.DryRun {
+		return
+	}
+	o, err := s.f.NewObject(remote)
+	if !b.check(err) {
+		return
+	}
+	s.files[remote] = fileState{Size: o.Size(), ModTime: o.ModTime()}
+}
+
+// check counts err if set, returning true if it wasn't
+func (b *bisync) check(err error) bool {
+	if err == nil {
+		return true
+	}
+	b.errCount++
+	b.lastErr = err
+	return false
+}
+
+// save stores the listings used for the run with the changes it made
+// for next time.
+//
+// The sides aren't listed again as that would store changes made
+// while the run was going without them being synced.
+func (b *bisync) save() error {
+	if fs.Config.DryRun {
+		fs.Logf(nil, "Not updating the listings as --dry-run")
+		return nil
+	}
+	st := &state{
+		Path1:  fsName(b.s1.f),
+		Path2:  fsName(b.s2.f),
+		Files1: b.s1.files,
+		Files2: b.s2.files,
+	}
+	err := writeState(b.stateFile, st)
+	if err != nil {
+		return errors.Wrap(err, "failed to write listings")
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of objects in order
+func sortedKeys(objects map[string]fs.Object) []string {
+	keys := make([]string, 0, len(objects))
+	for remote := range objects {
+		keys = append(keys, remote)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,204 @@
+package bisync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Some times used in the tests
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+	t3 = fstest.Time("2011-12-30T12:59:59.000000000Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// newOptions makes options using a temporary work dir
+func newOptions(t *testing.T, conflict string) (opt *Options, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-bisync-test")
+	require.NoError(t, err)
+	opt = DefaultOptions()
+	opt.WorkDir = dir
+	opt.Conflict = conflict
+	return opt, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// remove deletes remote from f
+func remove(t *testing.T, f fs.Fs, remote string) {
+	o, err := f.NewObject(remote)
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFile(o))
+}
+
+func TestBisync(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictNewer)
+	defer cleanup()
+
+	file1 := r.WriteFile("file1", "file one", t1)
+	file2 := r.WriteObject("sub dir/file2", "file two", t1)
+	file3 := r.WriteBoth("file3", "file three", t1)
+
+	// must resync first
+	err := Bisync(r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--resync")
+
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// nothing to do
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// change on both sides
+	file1 = r.WriteFile("file1", "file one changed", t2)
+	remove(t, r.Fremote, "sub dir/file2")
+	file4 := r.WriteObject("file4", "file four", t2)
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file3, file4)
+	fstest.CheckItems(t, r.Fremote, file1, file3, file4)
+
+	// a change beats a delete
+	remove(t, r.Flocal, "file3")
+	file3 = r.WriteObject("file3", "file three changed", t2)
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file3, file4)
+	fstest.CheckItems(t, r.Fremote, file1, file3, file4)
+}
+
+func TestBisyncConflictNewer(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictNewer)
+	defer cleanup()
+
+	r.WriteBoth("file1", "file one", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	r.WriteFile("file1", "changed on path1", t2)
+	file1 := r.WriteObject("file1", "changed on path2", t3)
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+func TestBisyncConflictKeepBoth(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictKeepBoth)
+	defer cleanup()
+
+	r.WriteBoth("file1", "file one", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	r.WriteFile("file1", "changed on path1", t2)
+	r.WriteObject("file1", "changed on path2", t3)
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	file1 := fstest.NewItem("file1"+ConflictSuffix1, "changed on path1", t2)
+	file2 := fstest.NewItem("file1"+ConflictSuffix2, "changed on path2", t3)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestBisyncConflictFail(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictFail)
+	defer cleanup()
+
+	r.WriteBoth("file1", "file one", t1)
+	r.WriteBoth("file2", "file two", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	file1 := r.WriteFile("file1", "changed on path1", t2)
+	file1b := r.WriteObject("file1", "changed on path2", t3)
+	file2 := r.WriteFile("file2", "file two changed", t2)
+	err := Bisync(r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+
+	// nothing was changed, not even the file without a conflict
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1b, fstest.NewItem("file2", "file two", t1))
+}
+
+func TestBisyncRefuseDeleteAll(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictNewer)
+	defer cleanup()
+
+	file1 := r.WriteBoth("file1", "file one", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	remove(t, r.Flocal, "file1")
+	err := Bisync(r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	opt.Force = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Fremote)
+}
+
+func TestBisyncChangedDuringRun(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOptions(t, ConflictNewer)
+	defer cleanup()
+
+	r.WriteBoth("file1", "file one", t1)
+	r.WriteBoth("file2", "file two", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	// change file2 after the sides were listed but before the
+	// listings are saved
+	file1 := r.WriteFile("file1", "file one changed", t2)
+	b := &bisync{
+		opt:       opt,
+		s1:        &side{f: r.Flocal, name: "Path1"},
+		s2:        &side{f: r.Fremote, name: "Path2"},
+		stateFile: filepath.Join(opt.WorkDir, stateName(r.Flocal, r.Fremote)),
+	}
+	require.NoError(t, b.run())
+	assert.Equal(t, 0, b.errCount)
+	file2 := r.WriteFile("file2", "file two changed", t2)
+	require.NoError(t, b.save())
+	fstest.CheckItems(t, r.Fremote, file1, fstest.NewItem("file2", "file two", t1))
+
+	// the change is found by the next run
+	require.NoError(t, Bisync(r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
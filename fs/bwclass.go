@@ -0,0 +1,50 @@
+package fs
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BwClass is a bandwidth limit for the transfers of files matching a
+// filter style pattern
+type BwClass struct {
+	Pattern   string
+	Bandwidth SizeSuffix
+}
+
+// BwClasses contains all the configured bandwidth classes in the
+// order they should be matched.
+type BwClasses []BwClass
+
+// String returns a printable representation of BwClasses.
+func (x BwClasses) String() string {
+	ret := []string{}
+	for _, class := range x {
+		ret = append(ret, class.Pattern+"="+class.Bandwidth.String())
+	}
+	return strings.Join(ret, " ")
+}
+
+// Set adds a bandwidth class in the form "pattern=bandwidth", eg
+// "*.iso=1M" or "*.docx=off".
+func (x *BwClasses) Set(s string) error {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return errors.Errorf("invalid bandwidth class %q - need pattern=bandwidth", s)
+	}
+	class := BwClass{
+		Pattern: s[:i],
+	}
+	err := class.Bandwidth.Set(s[i+1:])
+	if err != nil {
+		return errors.Wrapf(err, "invalid bandwidth in bandwidth class %q", s)
+	}
+	*x = append(*x, class)
+	return nil
+}
+
+// Type of the value
+func (x BwClasses) Type() string {
+	return "BwClasses"
+}
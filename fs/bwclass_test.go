@@ -0,0 +1,29 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*BwClasses)(nil)
+
+func TestBwClassesSet(t *testing.T) {
+	var classes BwClasses
+	assert.Equal(t, "", classes.String())
+	require.NoError(t, classes.Set("*.iso=1M"))
+	require.NoError(t, classes.Set("{a=b}.docx=off"))
+	assert.Equal(t, BwClasses{
+		{Pattern: "*.iso", Bandwidth: 1024 * 1024},
+		{Pattern: "{a=b}.docx", Bandwidth: -1},
+	}, classes)
+	assert.Equal(t, "*.iso=1M {a=b}.docx=off", classes.String())
+
+	for _, bad := range []string{"", "*.iso", "=1M", "*.iso=potato"} {
+		assert.Error(t, classes.Set(bad), bad)
+	}
+	assert.Equal(t, 2, len(classes))
+}
@@ -0,0 +1,151 @@
+// Package chunksize works out the chunk size and concurrency to use
+// for multipart uploads.
+//
+// Without --adaptive-chunks the chunk size configured for the backend
+// is used, only made bigger if the file wouldn't fit in the maximum
+// number of parts, and the concurrency is as configured.
+//
+// With --adaptive-chunks the chunk size grows with the size of the
+// file so huge files are uploaded in fewer, bigger, parts, as long as
+// the chunks being uploaded fit in --adaptive-chunk-memory.  The
+// concurrency is tuned for each remote by measuring how fast the
+// uploads go, trying more parts at once while that makes them
+// faster and fewer if it makes them slower.
+package chunksize
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+const (
+	// TargetParts is the number of parts --adaptive-chunks aims
+	// to upload files in.  Files with more parts than this get
+	// bigger chunks if there is the memory for them.
+	TargetParts = 1000
+
+	// maxConcurrencyFactor is how many times the configured
+	// concurrency the Tuner may go up to
+	maxConcurrencyFactor = 4
+
+	// tunerThreshold is how much the speed has to change by for
+	// the Tuner to act on it
+	tunerThreshold = 0.1
+)
+
+// Calculator returns the chunk size to upload an object of size
+// bytes with concurrency parts at once, starting from chunkSize.
+//
+// The chunk size is doubled until the object fits in maxParts parts
+// whether or not --adaptive-chunks is set.  With --adaptive-chunks it
+// is doubled until the object fits in TargetParts parts as long as
+// the chunks fit in the memory allowed for each transfer.
+//
+// If size is unknown (-1) then chunkSize is returned.
+func Calculator(size int64, maxParts int, chunkSize fs.SizeSuffix, concurrency int) fs.SizeSuffix {
+	if size < 0 || chunkSize <= 0 {
+		return chunkSize
+	}
+	if fs.Config.AdaptiveChunks {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		transfers := fs.Config.Transfers
+		if transfers < 1 {
+			transfers = 1
+		}
+		maxChunkSize := fs.Config.AdaptiveChunkMemory / fs.SizeSuffix(transfers*concurrency)
+		for parts(size, chunkSize) > TargetParts && 2*chunkSize <= maxChunkSize {
+			chunkSize *= 2
+		}
+	}
+	for parts(size, chunkSize) > int64(maxParts) {
+		chunkSize *= 2
+	}
+	return chunkSize
+}
+
+// parts returns the number of chunkSize parts size is uploaded in
+func parts(size int64, chunkSize fs.SizeSuffix) int64 {
+	n := size / int64(chunkSize)
+	if size%int64(chunkSize) != 0 {
+		n++
+	}
+	return n
+}
+
+// Tuner chooses the concurrency for the multipart uploads to a remote
+// from their measured speed.
+//
+// It is safe to use from several goroutines at once.
+type Tuner struct {
+	mu          sync.Mutex
+	max         int     // most parts to upload at once
+	concurrency int     // current number of parts to upload at once
+	step        int     // +1 or -1 for the way concurrency is being changed
+	rate        float64 // last measured speed in bytes/s at concurrency
+}
+
+// NewTuner makes a Tuner which starts with concurrency parts at once
+func NewTuner(concurrency int) *Tuner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Tuner{
+		max:         concurrency * maxConcurrencyFactor,
+		concurrency: concurrency,
+		step:        1,
+	}
+}
+
+// Concurrency returns how many parts the next upload should upload at
+// once
+func (t *Tuner) Concurrency() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.concurrency
+}
+
+// Record the upload of size bytes in parts parts with concurrency at
+// once taking elapsed.  It does nothing unless --adaptive-chunks is
+// set.
+//
+// If the upload was faster than the last one then the concurrency
+// carries on changing the same way, if slower it goes back the other
+// way and if about the same it stays.
+func (t *Tuner) Record(size int64, parts int, concurrency int, elapsed time.Duration) {
+	if !fs.Config.AdaptiveChunks || parts < 2 || elapsed <= 0 {
+		return
+	}
+	rate := float64(size) / elapsed.Seconds()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if concurrency != t.concurrency {
+		// measured with an out of date concurrency
+		return
+	}
+	fs.Debugf(nil, "Upload of %d parts, %d at once, went at %v/s per part", parts, concurrency, fs.SizeSuffix(rate/float64(concurrency)))
+	switch {
+	case t.rate == 0:
+		// first measurement
+	case rate > t.rate*(1+tunerThreshold):
+		// faster - carry on
+	case rate < t.rate*(1-tunerThreshold):
+		// slower - go back
+		t.step = -t.step
+	default:
+		// about the same - stay here
+		t.rate = rate
+		return
+	}
+	t.rate = rate
+	next := t.concurrency + t.step
+	if next < 1 || next > t.max {
+		t.step = -t.step
+		return
+	}
+	t.concurrency = next
+	fs.Debugf(nil, "Upload concurrency now %d", next)
+}
@@ -0,0 +1,99 @@
+package chunksize
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculator(t *testing.T) {
+	oldAdaptive, oldMemory, oldTransfers := fs.Config.AdaptiveChunks, fs.Config.AdaptiveChunkMemory, fs.Config.Transfers
+	defer func() {
+		fs.Config.AdaptiveChunks, fs.Config.AdaptiveChunkMemory, fs.Config.Transfers = oldAdaptive, oldMemory, oldTransfers
+	}()
+	fs.Config.Transfers = 4
+	fs.Config.AdaptiveChunkMemory = 1 << 30
+	const M = fs.SizeSuffix(1 << 20)
+	for _, test := range []struct {
+		adaptive    bool
+		size        int64
+		maxParts    int
+		chunkSize   fs.SizeSuffix
+		concurrency int
+		want        fs.SizeSuffix
+	}{
+		{false, -1, 10000, 5 * M, 4, 5 * M},
+		{false, 0, 10000, 5 * M, 4, 5 * M},
+		{false, 100 * int64(M), 10000, 5 * M, 4, 5 * M},
+		{false, 10000 * 5 * int64(M), 10000, 5 * M, 4, 5 * M},
+		{false, 10000*5*int64(M) + 1, 10000, 5 * M, 4, 10 * M},
+		{false, 100 * 1000 * int64(M), 100, 5 * M, 4, 1280 * M},
+		{true, -1, 10000, 5 * M, 4, 5 * M},
+		{true, 100 * int64(M), 10000, 5 * M, 4, 5 * M},
+		{true, 1000 * 5 * int64(M), 10000, 5 * M, 4, 5 * M},
+		{true, 1000*5*int64(M) + 1, 10000, 5 * M, 4, 10 * M},
+		// bounded by the memory - 1G / (4 transfers * 4 at once) = 64M
+		{true, 1000 * 100 * int64(M), 10000, 5 * M, 4, 40 * M},
+		{true, 1000 * 100 * int64(M), 10000, 5 * M, 1, 160 * M},
+		// 1G / (4 transfers * 16 at once) = 16M
+		{true, 1000 * 100 * int64(M), 10000, 5 * M, 16, 10 * M},
+		// but must fit in maxParts
+		{true, 1000 * 100 * int64(M), 1000, 5 * M, 16, 160 * M},
+	} {
+		what := fmt.Sprintf("%+v", test)
+		fs.Config.AdaptiveChunks = test.adaptive
+		got := Calculator(test.size, test.maxParts, test.chunkSize, test.concurrency)
+		assert.Equal(t, test.want, got, what)
+	}
+}
+
+func TestTuner(t *testing.T) {
+	oldAdaptive := fs.Config.AdaptiveChunks
+	defer func() { fs.Config.AdaptiveChunks = oldAdaptive }()
+
+	// Does nothing without --adaptive-chunks
+	fs.Config.AdaptiveChunks = false
+	tuner := NewTuner(2)
+	tuner.Record(100, 10, 2, time.Second)
+	tuner.Record(200, 10, 2, time.Second)
+	assert.Equal(t, 2, tuner.Concurrency())
+
+	fs.Config.AdaptiveChunks = true
+	tuner = NewTuner(2)
+
+	// first measurement goes up
+	tuner.Record(100, 10, 2, time.Second)
+	assert.Equal(t, 3, tuner.Concurrency())
+
+	// measurements with old concurrency and single parts are ignored
+	tuner.Record(1000, 10, 2, time.Second)
+	tuner.Record(1000, 1, 3, time.Second)
+	assert.Equal(t, 3, tuner.Concurrency())
+
+	// faster so carry on up
+	tuner.Record(200, 10, 3, time.Second)
+	assert.Equal(t, 4, tuner.Concurrency())
+
+	// about the same so stay
+	tuner.Record(205, 10, 4, time.Second)
+	assert.Equal(t, 4, tuner.Concurrency())
+
+	// slower so go back down
+	tuner.Record(100, 10, 4, time.Second)
+	assert.Equal(t, 3, tuner.Concurrency())
+
+	// faster so carry on down
+	tuner.Record(200, 10, 3, time.Second)
+	assert.Equal(t, 2, tuner.Concurrency())
+	tuner.Record(300, 10, 2, time.Second)
+	assert.Equal(t, 1, tuner.Concurrency())
+
+	// can't go below 1 so turn round
+	tuner.Record(400, 10, 1, time.Second)
+	assert.Equal(t, 1, tuner.Concurrency())
+	tuner.Record(500, 10, 1, time.Second)
+	assert.Equal(t, 2, tuner.Concurrency())
+}
@@ -40,7 +40,9 @@ var (
 type ConfigInfo struct {
 	LogLevel              LogLevel
 	StatsLogLevel         LogLevel
+	LogDedupe             time.Duration
 	DryRun                bool
+	DryRunJSON            bool
 	CheckSum              bool
 	SizeOnly              bool
 	IgnoreTimes           bool
@@ -53,6 +55,11 @@ type ConfigInfo struct {
 	Timeout               time.Duration // Data channel timeout
 	Dump                  DumpFlags
 	InsecureSkipVerify    bool // Skip server certificate verification
+	NoVerifyHostname      bool // Verify the server certificate but not its host name
+	TLSMinVersion         string
+	TLSMaxVersion         string
+	TLSCipherSuites       string
+	NoTLSSessionTickets   bool
 	DeleteMode            DeleteMode
 	MaxDelete             int64
 	TrackRenames          bool // Track file renames.
@@ -62,26 +69,56 @@ type ConfigInfo struct {
 	MaxDepth              int
 	IgnoreSize            bool
 	IgnoreChecksum        bool
+	HashSidecar           bool
 	NoUpdateModTime       bool
+	PathTemplates         bool // Expand ${ENV} and %Y style dates in remote paths
+	RefreshTimes          bool // Set modtimes of files which are the same but have the wrong time
 	DataRateUnit          string
 	BackupDir             string
 	Suffix                string
+	CompareDest           string
+	CopyDest              string
+	UndoFile              string
+	EstimateCost          bool
+	CostTable             string
+	ErrorLog              string
+	DumpFile              string // write the HTTP transactions to this HAR file
+	TempDir               string
 	UseListR              bool
+	UseListingsCache      bool
+	ListingsCacheAge      time.Duration
 	BufferSize            SizeSuffix
 	BwLimit               BwTimetable
+	BwLimitClasses        BwClasses
 	TPSLimit              float64
 	TPSLimitBurst         int
+	MetadataPriority      bool   // give metadata calls priority over data transfers
+	PacerState            string // file to save the pacer state in between runs
 	BindAddr              net.IP
 	DisableFeatures       []string
 	UserAgent             string
 	Immutable             bool
+	Inplace               bool
+	PartialSuffix         string
+	ResumeUploads         bool
+	NoServerSideAcross    bool
 	AutoConfirm           bool
 	StreamingUploadCutoff SizeSuffix
 	StatsFileNameLength   int
 	AskPassword           bool
 	UseServerModTime      bool
+	VersionAt             Time
 	MaxTransfer           SizeSuffix
+	MaxDuration           time.Duration
+	WaitVisible           time.Duration // wait for up to this long for uploads to be visible
+	CutoffMode            CutoffMode
+	MultiThreadCutoff     SizeSuffix
+	MultiThreadStreams    int
+	AdaptiveChunks        bool       // choose chunk sizes and concurrency for each multipart upload
+	AdaptiveChunkMemory   SizeSuffix // memory the chunks of all the multipart uploads may use with AdaptiveChunks
 	MaxBacklog            int
+	OrderBy               string
+	DirSchedule           string
 	StatsOneLine          bool
 	Progress              bool
 }
@@ -112,7 +149,13 @@ func NewConfig() *ConfigInfo {
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
 	c.MaxTransfer = -1
+	c.PartialSuffix = ".partial"
+	c.MultiThreadCutoff = SizeSuffix(250 * 1024 * 1024)
+	c.MultiThreadStreams = 4
+	c.AdaptiveChunkMemory = SizeSuffix(1 << 30)
+	c.Inplace = true
 	c.MaxBacklog = 10000
+	c.ListingsCacheAge = 5 * time.Minute
 
 	return c
 }
@@ -529,17 +529,23 @@ func saveConfig() error {
 // SaveConfig calling function which saves configuration file.
 // if saveConfig returns error trying again after sleep.
 func SaveConfig() {
-	var err error
+	err := trySaveConfig()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// trySaveConfig saves configuration file, retrying if it fails,
+// returning an error if it can't be saved
+func trySaveConfig() (err error) {
 	for i := 0; i < fs.Config.LowLevelRetries+1; i++ {
 		if err = saveConfig(); err == nil {
-			return
+			return nil
 		}
 		waitingTimeMs := mathrand.Intn(1000)
 		time.Sleep(time.Duration(waitingTimeMs) * time.Millisecond)
 	}
-	log.Fatalf("Failed to save config after %d tries: %v", fs.Config.LowLevelRetries, err)
-
-	return
+	return errors.Errorf("Failed to save config after %d tries: %v", fs.Config.LowLevelRetries, err)
 }
 
 // SetValueAndSave sets the key to the value and saves just that
@@ -918,7 +924,9 @@ func UpdateRemote(name string, keyValues []string) error {
 // adds the new keys rather than replacing all of them.
 func CreateRemote(name string, provider string, keyValues []string) error {
 	// Suppress Confirm
+	oldAutoConfirm := fs.Config.AutoConfirm
 	fs.Config.AutoConfirm = true
+	defer func() { fs.Config.AutoConfirm = oldAutoConfirm }()
 	// Delete the old config if it exists
 	getConfigData().DeleteSection(name)
 	// Set the type
@@ -936,7 +944,9 @@ func PasswordRemote(name string, keyValues []string) error {
 		return errors.New("found key without value")
 	}
 	// Suppress Confirm
+	oldAutoConfirm := fs.Config.AutoConfirm
 	fs.Config.AutoConfirm = true
+	defer func() { fs.Config.AutoConfirm = oldAutoConfirm }()
 	passwd := obscure.MustObscure(keyValues[1])
 	if passwd != "" {
 		getConfigData().SetValue(name, keyValues[0], passwd)
@@ -1277,6 +1287,11 @@ func FileDeleteKey(section, key string) bool {
 	return getConfigData().DeleteKey(section, key)
 }
 
+// FileSectionKeys returns the keys in section of the config file
+func FileSectionKeys(section string) []string {
+	return getConfigData().GetKeyList(section)
+}
+
 var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
 
 // FileSections returns the sections in the config file
@@ -6,12 +6,16 @@ package configflags
 import (
 	"log"
 	"net"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/cost"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/spf13/pflag"
 )
 
@@ -39,17 +43,24 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.IntVarP(flagSet, &fs.Config.Transfers, "transfers", "", fs.Config.Transfers, "Number of file transfers to run in parallel.")
 	flags.StringVarP(flagSet, &config.ConfigPath, "config", "", config.ConfigPath, "Config file.")
 	flags.StringVarP(flagSet, &config.CacheDir, "cache-dir", "", config.CacheDir, "Directory rclone will use for caching.")
+	flags.StringVarP(flagSet, &fs.Config.TempDir, "temp-dir", "", fs.Config.TempDir, "Directory rclone will use for temporary files (default is the OS temporary directory).")
 	flags.BoolVarP(flagSet, &fs.Config.CheckSum, "checksum", "c", fs.Config.CheckSum, "Skip based on checksum & size, not mod-time & size")
 	flags.BoolVarP(flagSet, &fs.Config.SizeOnly, "size-only", "", fs.Config.SizeOnly, "Skip based on size only, not mod-time or checksum")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreTimes, "ignore-times", "I", fs.Config.IgnoreTimes, "Don't skip files that match size and time - transfer all files")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreExisting, "ignore-existing", "", fs.Config.IgnoreExisting, "Skip all files that exist on destination")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreErrors, "ignore-errors", "", fs.Config.IgnoreErrors, "delete even if there are I/O errors")
 	flags.BoolVarP(flagSet, &fs.Config.DryRun, "dry-run", "n", fs.Config.DryRun, "Do a trial run with no permanent changes")
+	flags.BoolVarP(flagSet, &fs.Config.DryRunJSON, "dry-run-json", "", fs.Config.DryRunJSON, "Do a trial run and write the planned actions to stdout as JSON lines.")
 	flags.DurationVarP(flagSet, &fs.Config.ConnectTimeout, "contimeout", "", fs.Config.ConnectTimeout, "Connect timeout")
 	flags.DurationVarP(flagSet, &fs.Config.Timeout, "timeout", "", fs.Config.Timeout, "IO idle timeout")
 	flags.BoolVarP(flagSet, &dumpHeaders, "dump-headers", "", false, "Dump HTTP bodies - may contain sensitive info")
 	flags.BoolVarP(flagSet, &dumpBodies, "dump-bodies", "", false, "Dump HTTP headers and bodies - may contain sensitive info")
 	flags.BoolVarP(flagSet, &fs.Config.InsecureSkipVerify, "no-check-certificate", "", fs.Config.InsecureSkipVerify, "Do not verify the server SSL certificate. Insecure.")
+	flags.BoolVarP(flagSet, &fs.Config.NoVerifyHostname, "no-verify-hostname", "", fs.Config.NoVerifyHostname, "Verify the server SSL certificate but not that it matches the host name.")
+	flags.StringVarP(flagSet, &fs.Config.TLSMinVersion, "tls-min-version", "", fs.Config.TLSMinVersion, "Minimum TLS version to use, eg 1.2")
+	flags.StringVarP(flagSet, &fs.Config.TLSMaxVersion, "tls-max-version", "", fs.Config.TLSMaxVersion, "Maximum TLS version to use, eg 1.3")
+	flags.StringVarP(flagSet, &fs.Config.TLSCipherSuites, "tls-cipher-suites", "", fs.Config.TLSCipherSuites, "Comma separated list of TLS cipher suites to use (TLS 1.2 and below).")
+	flags.BoolVarP(flagSet, &fs.Config.NoTLSSessionTickets, "no-tls-session-tickets", "", fs.Config.NoTLSSessionTickets, "Don't use TLS session tickets to resume connections.")
 	flags.BoolVarP(flagSet, &fs.Config.AskPassword, "ask-password", "", fs.Config.AskPassword, "Allow prompt for password for encrypted configuration.")
 	flags.BoolVarP(flagSet, &deleteBefore, "delete-before", "", false, "When synchronizing, delete files on destination before transfering")
 	flags.BoolVarP(flagSet, &deleteDuring, "delete-during", "", false, "When synchronizing, delete files during transfer")
@@ -65,25 +76,55 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreChecksum, "ignore-checksum", "", fs.Config.IgnoreChecksum, "Skip post copy check of checksums.")
 	flags.BoolVarP(flagSet, &noTraverse, "no-traverse", "", noTraverse, "Obsolete - does nothing.")
 	flags.BoolVarP(flagSet, &fs.Config.NoUpdateModTime, "no-update-modtime", "", fs.Config.NoUpdateModTime, "Don't update destination mod-time if files identical.")
+	flags.BoolVarP(flagSet, &fs.Config.PathTemplates, "path-templates", "", fs.Config.PathTemplates, "Expand ${ENV} variables and %Y-%m-%d style dates in remote paths.")
+	flags.BoolVarP(flagSet, &fs.Config.RefreshTimes, "refresh-times", "", fs.Config.RefreshTimes, "Refresh the modtime of remote files without a hash instead of re-uploading them.")
 	flags.StringVarP(flagSet, &fs.Config.BackupDir, "backup-dir", "", fs.Config.BackupDir, "Make backups into hierarchy based in DIR.")
 	flags.StringVarP(flagSet, &fs.Config.Suffix, "suffix", "", fs.Config.Suffix, "Suffix for use with --backup-dir.")
+	flags.StringVarP(flagSet, &fs.Config.CompareDest, "compare-dest", "", fs.Config.CompareDest, "Skip files which are identical in DIR as well as the destination.")
+	flags.StringVarP(flagSet, &fs.Config.CopyDest, "copy-dest", "", fs.Config.CopyDest, "Server side copy files which are identical in DIR instead of uploading them.")
+	flags.StringVarP(flagSet, &fs.Config.UndoFile, "undo-file", "", fs.Config.UndoFile, "Write the files deleted or overwritten by a sync to this local file for rclone undo.")
 	flags.BoolVarP(flagSet, &fs.Config.UseListR, "fast-list", "", fs.Config.UseListR, "Use recursive list if available. Uses more memory but fewer transactions.")
+	flags.BoolVarP(flagSet, &fs.Config.UseListingsCache, "use-listings-cache", "", fs.Config.UseListingsCache, "Keep directory listings in a cache shared between runs.")
+	flags.DurationVarP(flagSet, &fs.Config.ListingsCacheAge, "listings-cache-age", "", fs.Config.ListingsCacheAge, "Max age of listings in the cache for --use-listings-cache.")
 	flags.Float64VarP(flagSet, &fs.Config.TPSLimit, "tpslimit", "", fs.Config.TPSLimit, "Limit HTTP transactions per second to this.")
 	flags.IntVarP(flagSet, &fs.Config.TPSLimitBurst, "tpslimit-burst", "", fs.Config.TPSLimitBurst, "Max burst of transactions for --tpslimit.")
 	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6 or name.")
 	flags.StringVarP(flagSet, &disableFeatures, "disable", "", "", "Disable a comma separated list of features.  Use help to see a list.")
 	flags.StringVarP(flagSet, &fs.Config.UserAgent, "user-agent", "", fs.Config.UserAgent, "Set the user-agent to a specified string. The default is rclone/ version")
 	flags.BoolVarP(flagSet, &fs.Config.Immutable, "immutable", "", fs.Config.Immutable, "Do not modify files. Fail if existing files have been modified.")
+	flags.BoolVarP(flagSet, &fs.Config.NoServerSideAcross, "no-server-side-across-configs", "", fs.Config.NoServerSideAcross, "Don't try server side copies and moves between different remotes of the same type.")
+	flags.BoolVarP(flagSet, &fs.Config.EstimateCost, "estimate-cost", "", fs.Config.EstimateCost, "Do a trial run and estimate what it would cost on the provider.")
+	flags.StringVarP(flagSet, &fs.Config.CostTable, "cost-table", "", fs.Config.CostTable, "JSON file of provider prices for --estimate-cost.")
+	flags.BoolVarP(flagSet, &fs.Config.HashSidecar, "hash-sidecar", "", fs.Config.HashSidecar, "Store source hashes beside files uploaded to remotes without a common hash.")
+	flags.BoolVarP(flagSet, &fs.Config.Inplace, "inplace", "", fs.Config.Inplace, "Upload files in place. If false upload to a temporary name and rename when complete.")
+	flags.StringVarP(flagSet, &fs.Config.PartialSuffix, "partial-suffix", "", fs.Config.PartialSuffix, "Add partial-suffix to the temporary name of uploads with --inplace=false.")
+	flags.BoolVarP(flagSet, &fs.Config.ResumeUploads, "resume-uploads", "", fs.Config.ResumeUploads, "Save the state of large uploads to Google Drive so they can be resumed if interrupted.")
 	flags.BoolVarP(flagSet, &fs.Config.AutoConfirm, "auto-confirm", "", fs.Config.AutoConfirm, "If enabled, do not request console confirmation.")
 	flags.IntVarP(flagSet, &fs.Config.StatsFileNameLength, "stats-file-name-length", "", fs.Config.StatsFileNameLength, "Max file name length in stats. 0 for no limit")
 	flags.FVarP(flagSet, &fs.Config.LogLevel, "log-level", "", "Log level DEBUG|INFO|NOTICE|ERROR")
+	flags.StringVarP(flagSet, &fs.Config.ErrorLog, "error-log", "", fs.Config.ErrorLog, "Write the most recent errors to this file as JSON on exit.")
+	flags.StringVarP(flagSet, &fs.Config.PacerState, "pacer-state", "", fs.Config.PacerState, "Save how much each remote had to be slowed down by rate limiting in this file and start from there next time.")
+	flags.DurationVarP(flagSet, &fs.Config.LogDedupe, "log-dedupe", "", fs.Config.LogDedupe, "Collapse identical errors logged within this time into one summary line.")
 	flags.FVarP(flagSet, &fs.Config.StatsLogLevel, "stats-log-level", "", "Log level to show --stats output DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &fs.Config.BwLimit, "bwlimit", "", "Bandwidth limit in kBytes/s, or use suffix b|k|M|G or a full timetable.")
+	flags.FVarP(flagSet, &fs.Config.BwLimitClasses, "bwlimit-class", "", "Bandwidth limit for files matching a pattern, eg \"*.iso=1M\" - may be repeated.")
+	flags.BoolVarP(flagSet, &fs.Config.MetadataPriority, "metadata-priority", "", fs.Config.MetadataPriority, "Slow data transfers while listings and other metadata calls from mounts are running.")
 	flags.FVarP(flagSet, &fs.Config.BufferSize, "buffer-size", "", "In memory buffer size when reading files for each --transfer.")
 	flags.FVarP(flagSet, &fs.Config.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown. Upload starts after reaching cutoff or when file ends.")
 	flags.FVarP(flagSet, &fs.Config.Dump, "dump", "", "List of items to dump from: "+fs.DumpFlagsList)
+	flags.StringVarP(flagSet, &fs.Config.DumpFile, "dump-file", "", fs.Config.DumpFile, "Write the HTTP transactions with secrets removed to this file in HAR format.")
 	flags.FVarP(flagSet, &fs.Config.MaxTransfer, "max-transfer", "", "Maximum size of data to transfer.")
+	flags.FVarP(flagSet, &fs.Config.VersionAt, "version-at", "", "Show the remote as it was at this time (on remotes which keep versions).")
+	flags.DurationVarP(flagSet, &fs.Config.MaxDuration, "max-duration", "", fs.Config.MaxDuration, "Maximum duration rclone will transfer data for.")
+	flags.DurationVarP(flagSet, &fs.Config.WaitVisible, "wait-visible", "", fs.Config.WaitVisible, "After each upload wait for up to this long for the file to be visible on the remote.")
+	flags.FVarP(flagSet, &fs.Config.CutoffMode, "cutoff-mode", "", "Mode to stop transfers when reaching the max transfer limit or duration HARD|SOFT|CAUTIOUS")
+	flags.FVarP(flagSet, &fs.Config.MultiThreadCutoff, "multi-thread-cutoff", "", "Use multi-thread downloads for files above this size.")
+	flags.IntVarP(flagSet, &fs.Config.MultiThreadStreams, "multi-thread-streams", "", fs.Config.MultiThreadStreams, "Max number of streams to use for multi-thread downloads.")
+	flags.BoolVarP(flagSet, &fs.Config.AdaptiveChunks, "adaptive-chunks", "", fs.Config.AdaptiveChunks, "Choose the chunk size and concurrency of each multipart upload from its size and the measured speed.")
+	flags.FVarP(flagSet, &fs.Config.AdaptiveChunkMemory, "adaptive-chunk-memory", "", "Memory the chunks of all uploads may use with --adaptive-chunks.")
 	flags.IntVarP(flagSet, &fs.Config.MaxBacklog, "max-backlog", "", fs.Config.MaxBacklog, "Maximum number of objects in sync or check backlog.")
+	flags.StringVarP(flagSet, &fs.Config.OrderBy, "order-by", "", fs.Config.OrderBy, "Instructions on how to order the transfers, eg 'size,descending'")
+	flags.StringVarP(flagSet, &fs.Config.DirSchedule, "dir-schedule", "", fs.Config.DirSchedule, "Schedule transfers by directory: depth to finish each directory first, breadth to spread them across directories.")
 	flags.BoolVarP(flagSet, &fs.Config.StatsOneLine, "stats-one-line", "", fs.Config.StatsOneLine, "Make the stats fit on one line.")
 	flags.BoolVarP(flagSet, &fs.Config.Progress, "progress", "P", fs.Config.Progress, "Show progress during transfer.")
 }
@@ -142,10 +183,50 @@ func SetFlags() {
 		log.Fatalf(`Can't use --size-only and --ignore-size together.`)
 	}
 
+	if fs.Config.AdaptiveChunks && fs.Config.AdaptiveChunkMemory <= 0 {
+		log.Fatalf(`--adaptive-chunk-memory must be greater than 0 with --adaptive-chunks.`)
+	}
+
+	if fs.Config.PartialSuffix == "" {
+		log.Fatalf(`--partial-suffix can't be empty - use --inplace instead.`)
+	}
+
 	if fs.Config.Suffix != "" && fs.Config.BackupDir == "" {
 		log.Fatalf(`Can only use --suffix with --backup-dir.`)
 	}
 
+	if fs.Config.CostTable != "" {
+		err := cost.LoadPrices(fs.Config.CostTable)
+		if err != nil {
+			log.Fatalf("--cost-table: %v", err)
+		}
+	}
+
+	if fs.Config.EstimateCost || fs.Config.DryRunJSON {
+		fs.Config.DryRun = true
+	}
+
+	for _, class := range fs.Config.BwLimitClasses {
+		if _, err := filter.GlobToRegexp(class.Pattern); err != nil {
+			log.Fatalf("--bwlimit-class: bad pattern %q: %v", class.Pattern, err)
+		}
+	}
+
+	if fs.Config.CompareDest != "" && fs.Config.CopyDest != "" {
+		log.Fatalf(`Can't use --compare-dest with --copy-dest.`)
+	}
+
+	if _, err := fshttp.NewTLSConfig(fs.Config); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if fs.Config.TempDir != "" {
+		err := os.MkdirAll(fs.Config.TempDir, 0700)
+		if err != nil {
+			log.Fatalf("--temp-dir: Failed to make %q: %v", fs.Config.TempDir, err)
+		}
+	}
+
 	if bindAddr != "" {
 		addrs, err := net.LookupIP(bindAddr)
 		if err != nil {
@@ -0,0 +1,255 @@
+// Remote control calls for the config file
+
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/driveletter"
+	"github.com/ncw/rclone/fs/fspath"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "config/listremotes",
+		Fn:    rcListRemotes,
+		Title: "Lists the remotes in the config file.",
+		Help: `This takes no parameters and returns
+
+- remotes - array of remote names
+
+This includes the remotes defined by environment variables.
+
+See the [listremotes command](/commands/rclone_listremotes/) for more
+information on the above.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "config/get",
+		Fn:    rcGet,
+		Title: "Get a remote in the config file.",
+		Help: `This takes the following parameters
+
+- name - name of remote to get
+
+This returns the keys and values of the remote, eg
+
+    {
+        "type": "drive",
+        "scope": "drive"
+    }
+
+Passwords are returned obscured as they are stored in the config file.
+`,
+	})
+	rc.Add(rc.Call{
+		Path: "config/create",
+		Fn: func(in rc.Params) (rc.Params, error) {
+			return rcCreateOrUpdate(in, true)
+		},
+		Title: "Create the config for a remote.",
+		Help: `This takes the following parameters
+
+- name - name of remote
+- type - type of new remote
+- parameters - a map of { "key": "value" } pairs
+
+Any existing remote called name is replaced.  Passwords must be
+obscured with "rclone obscure" first.
+
+Unlike "rclone config create" this doesn't run the interactive part
+of the configuration of the remote, as there is nobody to answer its
+questions.  For remotes which use OAuth, pass in the token made by
+"rclone authorize" as the "token" parameter.
+
+This returns
+
+- {} - empty dictionary on success
+
+See the [config create command](/commands/rclone_config_create/) for
+more information on the above.
+`,
+	})
+	rc.Add(rc.Call{
+		Path: "config/update",
+		Fn: func(in rc.Params) (rc.Params, error) {
+			return rcCreateOrUpdate(in, false)
+		},
+		Title: "Update the config for a remote.",
+		Help: `This takes the following parameters
+
+- name - name of remote
+- parameters - a map of { "key": "value" } pairs
+
+The keys passed in are set and the others are left alone.  The
+remote must exist already.  As with config/create the interactive
+part of the configuration isn't run, so an existing OAuth token is
+kept unless a new one is passed in.
+
+This returns
+
+- {} - empty dictionary on success
+
+See the [config update command](/commands/rclone_config_update/) for
+more information on the above.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "config/delete",
+		Fn:    rcDelete,
+		Title: "Delete a remote in the config file.",
+		Help: `This takes the following parameters
+
+- name - name of remote to delete
+
+This returns
+
+- {} - empty dictionary on success
+
+See the [config delete command](/commands/rclone_config_delete/) for
+more information on the above.
+`,
+	})
+}
+
+// rcListRemotes returns the remotes in the config file
+func rcListRemotes(in rc.Params) (out rc.Params, err error) {
+	remotes := FileSections()
+	sort.Strings(remotes)
+	if remotes == nil {
+		remotes = []string{}
+	}
+	return rc.Params{"remotes": remotes}, nil
+}
+
+// remoteExists returns an error if the remote called name isn't in
+// the config
+func remoteExists(name string) error {
+	for _, remote := range FileSections() {
+		if remote == name {
+			return nil
+		}
+	}
+	return errors.Errorf("remote %q not found", name)
+}
+
+// checkRemote returns an error if the remote called name isn't in
+// the config or its type isn't known.  This needs checking before
+// calling the functions which exit on a bad remote.
+func checkRemote(name string) error {
+	err := remoteExists(name)
+	if err != nil {
+		return err
+	}
+	_, err = fs.Find(FileGet(name, "type"))
+	if err != nil {
+		return errors.Wrapf(err, "remote %q", name)
+	}
+	return nil
+}
+
+// checkName returns an error if name can't be used for a new remote
+func checkName(name string) error {
+	parts := fspath.Matcher.FindStringSubmatch(name + ":")
+	switch {
+	case name == "":
+		return errors.New("can't use empty name")
+	case driveletter.IsDriveLetter(name):
+		return errors.Errorf("can't use %q as it can be confused with a drive letter", name)
+	case parts == nil || parts[1] != name:
+		return errors.Errorf("can't use %q as it has invalid characters in it", name)
+	}
+	return nil
+}
+
+// rcGet returns the config of a remote
+func rcGet(in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	err = remoteExists(name)
+	if err != nil {
+		return nil, err
+	}
+	out = rc.Params{}
+	for _, key := range FileSectionKeys(name) {
+		out[key] = FileGet(name, key)
+	}
+	if _, found := out["type"]; !found {
+		// the remote is defined in the environment
+		out["type"] = FileGet(name, "type")
+	}
+	return out, nil
+}
+
+// rcCreateOrUpdate creates or updates a remote
+func rcCreateOrUpdate(in rc.Params, create bool) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	var parameters map[string]interface{}
+	err = in.GetStruct("parameters", &parameters)
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if create {
+		err = checkName(name)
+		if err != nil {
+			return nil, err
+		}
+		remoteType, err := in.GetString("type")
+		if err != nil {
+			return nil, err
+		}
+		_, err = fs.Find(remoteType)
+		if err != nil {
+			return nil, err
+		}
+		getConfigData().DeleteSection(name)
+		getConfigData().SetValue(name, "type", remoteType)
+	} else {
+		err = checkRemote(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Set the values without running the interactive config of
+	// the backend as nobody is there to answer its questions
+	for _, key := range keys {
+		getConfigData().SetValue(name, key, fmt.Sprint(parameters[key]))
+	}
+	err = trySaveConfig()
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, nil
+}
+
+// rcDelete deletes a remote
+func rcDelete(in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	err = remoteExists(name)
+	if err != nil {
+		return nil, err
+	}
+	getConfigData().DeleteSection(name)
+	err = trySaveConfig()
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, nil
+}
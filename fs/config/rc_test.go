@@ -0,0 +1,149 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRc(t *testing.T) {
+	configKey = nil // reset password
+	// create temp config file
+	tempFile, err := ioutil.TempFile("", "rc.conf")
+	require.NoError(t, err)
+	path := tempFile.Name()
+	defer func() {
+		err := os.Remove(path)
+		assert.NoError(t, err)
+	}()
+	require.NoError(t, tempFile.Close())
+
+	// temporarily adapt configuration
+	oldOsStdout := os.Stdout
+	oldConfigPath := ConfigPath
+	oldConfig := fs.Config
+	oldConfigFile := configFile
+	os.Stdout = nil
+	ConfigPath = path
+	fs.Config = &fs.ConfigInfo{}
+	configFile = nil
+	defer func() {
+		os.Stdout = oldOsStdout
+		ConfigPath = oldConfigPath
+		fs.Config = oldConfig
+		configFile = oldConfigFile
+	}()
+
+	LoadConfig()
+
+	// Fake a remote
+	fs.Register(&fs.RegInfo{Name: "config_rc_test_remote"})
+
+	call := func(path string, in rc.Params) (rc.Params, error) {
+		c := rc.Get(path)
+		require.NotNil(t, c, path)
+		return c.Fn(in)
+	}
+
+	out, err := call("config/listremotes", rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"remotes": []string{}}, out)
+
+	// create
+	out, err = call("config/create", rc.Params{
+		"name": "test",
+		"type": "config_rc_test_remote",
+		"parameters": rc.Params{
+			"bool":   true,
+			"string": "hello",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+
+	out, err = call("config/listremotes", rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"remotes": []string{"test"}}, out)
+
+	// get
+	out, err = call("config/get", rc.Params{"name": "test"})
+	require.NoError(t, err)
+	assert.Equal(t, "config_rc_test_remote", out["type"])
+	assert.Equal(t, "true", out["bool"])
+	assert.Equal(t, "hello", out["string"])
+
+	// update
+	out, err = call("config/update", rc.Params{
+		"name": "test",
+		"parameters": rc.Params{
+			"string": "potato",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+	assert.Equal(t, "true", FileGet("test", "bool"))
+	assert.Equal(t, "potato", FileGet("test", "string"))
+
+	// errors
+	_, err = call("config/create", rc.Params{"name": "test2", "type": "not_a_remote"})
+	assert.Error(t, err)
+	_, err = call("config/create", rc.Params{"name": "bad:name", "type": "config_rc_test_remote"})
+	assert.Error(t, err)
+	_, err = call("config/create", rc.Params{"name": "test2"})
+	assert.Error(t, err)
+	_, err = call("config/get", rc.Params{"name": "test2"})
+	assert.Error(t, err)
+	_, err = call("config/update", rc.Params{"name": "test2"})
+	assert.Error(t, err)
+	_, err = call("config/delete", rc.Params{"name": "test2"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"test"}, FileSections())
+
+	// delete
+	out, err = call("config/delete", rc.Params{"name": "test"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+	assert.Equal(t, []string{}, getConfigData().GetSectionList())
+
+	// Fake a remote which uses OAuth and asks questions when
+	// configured - this mustn't be run by the rc
+	configCalled := false
+	fs.Register(&fs.RegInfo{
+		Name: "config_rc_test_oauth",
+		Config: func(name string, m configmap.Mapper) {
+			configCalled = true
+			Confirm()
+		},
+	})
+
+	_, err = call("config/create", rc.Params{
+		"name": "oauth",
+		"type": "config_rc_test_oauth",
+		"parameters": rc.Params{
+			"token": `{"access_token":"one"}`,
+		},
+	})
+	require.NoError(t, err)
+	_, err = call("config/update", rc.Params{
+		"name": "oauth",
+		"parameters": rc.Params{
+			"client_id": "potato",
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, configCalled)
+	assert.False(t, fs.Config.AutoConfirm)
+	assert.Equal(t, `{"access_token":"one"}`, FileGet("oauth", "token"))
+	assert.Equal(t, "potato", FileGet("oauth", "client_id"))
+
+	// the changes are saved in the config file
+	configFile = nil
+	LoadConfig()
+	assert.Equal(t, "potato", FileGet("oauth", "client_id"))
+}
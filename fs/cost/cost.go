@@ -0,0 +1,193 @@
+// Package cost estimates what the API calls and egress of an
+// operation would cost on the provider
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Op is a kind of billable operation
+type Op int
+
+// Billable operations
+const (
+	List   Op = iota // list a directory
+	Get              // download an object
+	Put              // upload an object
+	Copy             // server side copy or move an object
+	Delete           // delete an object
+	numOps
+)
+
+var opNames = [...]string{
+	List:   "list",
+	Get:    "get",
+	Put:    "put",
+	Copy:   "copy",
+	Delete: "delete",
+}
+
+// String turns an Op into a string
+func (op Op) String() string {
+	if op < 0 || op >= numOps {
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+	return opNames[op]
+}
+
+// Price is the pricing of a provider in US dollars
+type Price struct {
+	List     float64 `json:"list"`      // per 1000 list calls
+	Get      float64 `json:"get"`       // per 1000 get calls
+	Put      float64 `json:"put"`       // per 1000 put calls
+	Copy     float64 `json:"copy"`      // per 1000 copy calls
+	Delete   float64 `json:"delete"`    // per 1000 delete calls
+	EgressGB float64 `json:"egress_gb"` // per GB downloaded
+}
+
+// perThousand returns the price per 1000 calls of op
+func (p *Price) perThousand(op Op) float64 {
+	switch op {
+	case List:
+		return p.List
+	case Get:
+		return p.Get
+	case Put:
+		return p.Put
+	case Copy:
+		return p.Copy
+	case Delete:
+		return p.Delete
+	}
+	return 0
+}
+
+// Prices are the default prices by backend type.
+//
+// These are the list prices of the cheapest storage class in the most
+// common region so the estimates are only a guide.  Use
+// --cost-table to supply your own.
+var Prices = map[string]Price{
+	"s3":                   {List: 0.005, Get: 0.0004, Put: 0.005, Copy: 0.005, EgressGB: 0.09},
+	"b2":                   {List: 0.004, Get: 0.004, EgressGB: 0.01},
+	"google cloud storage": {List: 0.005, Get: 0.0004, Put: 0.005, Copy: 0.005, EgressGB: 0.12},
+	"azureblob":            {List: 0.005, Get: 0.0004, Put: 0.005, Copy: 0.005, EgressGB: 0.087},
+	"qingstor":             {List: 0.001, Get: 0.0001, Put: 0.001, Copy: 0.001, EgressGB: 0.08},
+}
+
+// LoadPrices reads prices from the JSON file name, replacing the
+// defaults for the backend types in it.  The file should be an object
+// keyed by backend type, eg
+//
+//	{"s3": {"list": 0.005, "get": 0.0004, "put": 0.005, "egress_gb": 0.09}}
+func LoadPrices(name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var prices map[string]Price
+	err = json.Unmarshal(data, &prices)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse cost table %q", name)
+	}
+	for backend, price := range prices {
+		Prices[backend] = price
+	}
+	return nil
+}
+
+// usage counts the billable operations on one remote
+type usage struct {
+	calls  [numOps]int64
+	egress int64 // bytes downloaded
+}
+
+var (
+	mu     sync.Mutex
+	usages = map[string]*usage{} // by remote name
+)
+
+// Record counts n calls of op on f which download egress bytes
+//
+// It does nothing unless --estimate-cost is set
+func Record(f fs.Info, op Op, n int64, egress int64) {
+	if !fs.Config.EstimateCost {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	u := usages[f.Name()]
+	if u == nil {
+		u = new(usage)
+		usages[f.Name()] = u
+	}
+	u.calls[op] += n
+	u.egress += egress
+}
+
+// Reset forgets everything recorded
+func Reset() {
+	mu.Lock()
+	usages = map[string]*usage{}
+	mu.Unlock()
+}
+
+// backendType returns the type of the backend for the remote name
+func backendType(name string) string {
+	if backend, ok := fs.ConfigFileGet(name, "type"); ok {
+		return backend
+	}
+	// on the fly remotes like ":s3:" and the local backend
+	if len(name) > 0 && name[0] == ':' {
+		return name[1:]
+	}
+	return name
+}
+
+// Estimate returns the estimated cost in US dollars of the operations
+// recorded and a line describing the usage of each remote
+func Estimate() (total float64, lines []string) {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name := range usages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u := usages[name]
+		backend := backendType(name)
+		price, known := Prices[backend]
+		cost := float64(u.egress) / (1 << 30) * price.EgressGB
+		line := fmt.Sprintf("%s (%s):", name, backend)
+		for op := Op(0); op < numOps; op++ {
+			line += fmt.Sprintf(" %d %v", u.calls[op], op)
+			cost += float64(u.calls[op]) / 1000 * price.perThousand(op)
+		}
+		line += fmt.Sprintf(", %v egress", fs.SizeSuffix(u.egress))
+		if known {
+			line += fmt.Sprintf(" = $%.4f", cost)
+		} else {
+			line += " = unknown price"
+		}
+		total += cost
+		lines = append(lines, line)
+	}
+	return total, lines
+}
+
+// Report logs the estimated cost of the operations recorded
+func Report() {
+	total, lines := Estimate()
+	for _, line := range lines {
+		fs.Logf(nil, "Estimated cost: %s", line)
+	}
+	fs.Logf(nil, "Estimated cost: total $%.4f", total)
+}
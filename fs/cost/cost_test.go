@@ -0,0 +1,77 @@
+package cost
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInfo is an fs.Info with just a name
+type testInfo struct {
+	fs.Info
+	name string
+}
+
+func (t testInfo) Name() string { return t.name }
+
+func TestOpString(t *testing.T) {
+	assert.Equal(t, "list", List.String())
+	assert.Equal(t, "delete", Delete.String())
+	assert.Equal(t, "Op(99)", Op(99).String())
+}
+
+func TestRecordAndEstimate(t *testing.T) {
+	oldEstimateCost := fs.Config.EstimateCost
+	defer func() {
+		fs.Config.EstimateCost = oldEstimateCost
+		Reset()
+	}()
+	Reset()
+
+	// nothing recorded unless --estimate-cost
+	fs.Config.EstimateCost = false
+	Record(testInfo{name: ":s3"}, List, 1, 0)
+	total, lines := Estimate()
+	assert.Equal(t, 0.0, total)
+	assert.Equal(t, 0, len(lines))
+
+	fs.Config.EstimateCost = true
+	Record(testInfo{name: ":s3"}, List, 2000, 0)
+	Record(testInfo{name: ":s3"}, Get, 1000, 1<<30)
+	Record(testInfo{name: ":nosuchbackend"}, Put, 5, 0)
+	total, lines = Estimate()
+	assert.InDelta(t, 2*0.005+0.0004+0.09, total, 1E-9)
+	require.Equal(t, 2, len(lines))
+	assert.Equal(t, ":nosuchbackend (nosuchbackend): 0 list 0 get 5 put 0 copy 0 delete, 0 egress = unknown price", lines[0])
+	assert.Equal(t, ":s3 (s3): 2000 list 1000 get 0 put 0 copy 0 delete, 1G egress = $0.1004", lines[1])
+}
+
+func TestLoadPrices(t *testing.T) {
+	oldPrices := Prices["s3"]
+	defer func() {
+		Prices["s3"] = oldPrices
+		delete(Prices, "testbackend")
+	}()
+
+	dir, err := ioutil.TempDir("", "rclone-cost-test")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	name := filepath.Join(dir, "prices.json")
+	require.NoError(t, ioutil.WriteFile(name, []byte(`{"s3": {"list": 1}, "testbackend": {"put": 2, "egress_gb": 3}}`), 0600))
+	require.NoError(t, LoadPrices(name))
+	assert.Equal(t, Price{List: 1}, Prices["s3"])
+	assert.Equal(t, Price{Put: 2, EgressGB: 3}, Prices["testbackend"])
+
+	require.NoError(t, ioutil.WriteFile(name, []byte(`not json`), 0600))
+	assert.Error(t, LoadPrices(name))
+
+	assert.Error(t, LoadPrices(filepath.Join(dir, "notfound.json")))
+}
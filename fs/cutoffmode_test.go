@@ -0,0 +1,36 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*CutoffMode)(nil)
+
+func TestCutoffModeString(t *testing.T) {
+	assert.Equal(t, "HARD", CutoffModeHard.String())
+	assert.Equal(t, "CAUTIOUS", CutoffModeCautious.String())
+	assert.Equal(t, "CutoffMode(17)", CutoffMode(17).String())
+}
+
+func TestCutoffModeSet(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    CutoffMode
+		wantErr bool
+	}{
+		{"hard", CutoffModeHard, false},
+		{"SOFT", CutoffModeSoft, false},
+		{"Cautious", CutoffModeCautious, false},
+		{"", CutoffModeHard, true},
+		{"potato", CutoffModeHard, true},
+	} {
+		m := CutoffModeHard
+		err := m.Set(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.Equal(t, test.want, m, test.in)
+	}
+}
@@ -0,0 +1,88 @@
+// Keep the most recent errors in memory
+
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/ncw/rclone/fs/fserrors"
+)
+
+// errorLogSize is the number of errors kept
+const errorLogSize = 1000
+
+// errorLog keeps the most recent errors logged
+var errorLog = newLogRing(errorLogSize)
+
+// ErrorClass returns the kind of err for the error log - "fatal",
+// "noretry", "retry" or "error" for anything else
+func ErrorClass(err error) string {
+	switch {
+	case fserrors.IsFatalError(err):
+		return "fatal"
+	case fserrors.IsNoRetryError(err):
+		return "noretry"
+	case fserrors.IsRetryError(err) || fserrors.ShouldRetry(err):
+		return "retry"
+	}
+	return "error"
+}
+
+// recordError adds an error log line to the error log, classifying
+// it by the first error in args
+func recordError(level LogLevel, o interface{}, msg string, args []interface{}) {
+	line := LogLine{
+		Time:  time.Now(),
+		Level: level,
+		Class: "error",
+		Text:  msg,
+	}
+	if o != nil {
+		line.Object = fmt.Sprint(o)
+	}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			line.Class = ErrorClass(err)
+			break
+		}
+	}
+	errorLog.add(line)
+}
+
+// RecentErrors returns the most recent errors logged, oldest first
+func RecentErrors() []LogLine {
+	return errorLog.get()
+}
+
+// WriteErrorLog writes the most recent errors logged to the file
+// name as JSON.  Nothing is written if there haven't been any.
+func WriteErrorLog(name string) error {
+	type jsonLine struct {
+		Time   time.Time
+		Level  string
+		Object string `json:",omitempty"`
+		Class  string
+		Text   string
+	}
+	var lines []jsonLine
+	for _, line := range RecentErrors() {
+		lines = append(lines, jsonLine{
+			Time:   line.Time,
+			Level:  line.Level.String(),
+			Object: line.Object,
+			Class:  line.Class,
+			Text:   line.Text,
+		})
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(lines, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0600)
+}
@@ -0,0 +1,60 @@
+package fs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClass(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want string
+	}{
+		{errors.New("potato"), "error"},
+		{fserrors.FatalError(errors.New("potato")), "fatal"},
+		{fserrors.NoRetryError(errors.New("potato")), "noretry"},
+		{fserrors.RetryErrorf("potato"), "retry"},
+	} {
+		assert.Equal(t, test.want, ErrorClass(test.err), test.err.Error())
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	oldLogPrint := LogPrint
+	LogPrint = func(level LogLevel, text string) {}
+	defer func() {
+		LogPrint = oldLogPrint
+	}()
+
+	Errorf("file1", "Failed to copy: %v", fserrors.FatalError(errors.New("disk full")))
+	Logf("file2", "Not an error")
+
+	lines := RecentErrors()
+	require.NotEqual(t, 0, len(lines))
+	line := lines[len(lines)-1]
+	assert.Equal(t, LogLevelError, line.Level)
+	assert.Equal(t, "file1", line.Object)
+	assert.Equal(t, "fatal", line.Class)
+	assert.Equal(t, "Failed to copy: disk full", line.Text)
+
+	dir, err := ioutil.TempDir("", "rclone-errorlog")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	name := filepath.Join(dir, "errors.json")
+	require.NoError(t, WriteErrorLog(name))
+	data, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	var got []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.NotEqual(t, 0, len(got))
+	assert.Equal(t, "ERROR", got[len(got)-1]["Level"])
+	assert.Equal(t, "file1", got[len(got)-1]["Object"])
+}
@@ -10,6 +10,12 @@ import (
 	"github.com/pkg/errors"
 )
 
+// GlobToRegexp converts an rsync style glob to a regexp matching
+// paths in the same way as the filter rules
+func GlobToRegexp(glob string) (*regexp.Regexp, error) {
+	return globToRegexp(glob)
+}
+
 // globToRegexp converts an rsync style glob to a regexp
 //
 // documented in filtering.md
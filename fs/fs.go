@@ -4,7 +4,6 @@ package fs
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"math"
 	"os"
@@ -63,6 +62,8 @@ var (
 	ErrorDirectoryNotEmpty           = errors.New("directory not empty")
 	ErrorImmutableModified           = errors.New("immutable file modified")
 	ErrorPermissionDenied            = errors.New("permission denied")
+	ErrorObjectLocked                = errors.New("object is locked against deletion")
+	ErrorCommandNotFound             = errors.New("command not found")
 )
 
 // RegInfo provides information about a filesystem
@@ -81,6 +82,19 @@ type RegInfo struct {
 	Config func(name string, config configmap.Mapper) `json:"-"`
 	// Options for the Fs configuration
 	Options Options
+	// The backend commands the Fs supports, used for help
+	CommandHelp []CommandHelp
+}
+
+// CommandHelp describes a single backend Command
+//
+// These are in a documentation format and can be shown to the user
+// by "rclone backend help".
+type CommandHelp struct {
+	Name  string            // Name of the command, eg "restore"
+	Short string            // Single line description
+	Long  string            // Long multi-line description
+	Opts  map[string]string // maps option name to a single line help
 }
 
 // Options is a slice of configuration Option for a backend
@@ -321,6 +335,14 @@ type IDer interface {
 	ID() string
 }
 
+// QuickHasher is an optional interface for Object whose Hash may
+// need to read the whole object, eg a local file
+type QuickHasher interface {
+	// QuickHash returns the hash of the Object if it is known
+	// without reading the Object, or "" if not
+	QuickHash(ht hash.Type) (string, error)
+}
+
 // ObjectUnWrapper is an optional interface for Object
 type ObjectUnWrapper interface {
 	// UnWrap returns the Object that this Object is wrapping or
@@ -341,6 +363,14 @@ type GetTierer interface {
 	GetTier() string
 }
 
+// Locker is an optional interface for Object
+type Locker interface {
+	// LockedUntil returns the time the Object can't be deleted
+	// before, which is zero if it isn't locked, and whether it has
+	// a legal hold which stops it being deleted at all
+	LockedUntil() (until time.Time, legalHold bool)
+}
+
 // ListRCallback defines a callback function for ListR to use
 //
 // It is called for each tranche of entries read from the listing and
@@ -350,6 +380,10 @@ type ListRCallback func(entries DirEntries) error
 // ListRFn is defines the call used to recursively list a directory
 type ListRFn func(dir string, callback ListRCallback) error
 
+// SearchFn defines the call used to search a directory using the
+// provider's search API
+type SearchFn func(dir, query string, callback ListRCallback) error
+
 // NewUsageValue makes a valid value
 func NewUsageValue(value int64) *int64 {
 	p := new(int64)
@@ -380,6 +414,8 @@ type Features struct {
 	BucketBased             bool // is bucket based (like s3, swift etc)
 	SetTier                 bool // allows set tier functionality on objects
 	GetTier                 bool // allows to retrieve storage tier of objects
+	ServerSideAcrossConfigs bool // can server side copy between different remotes of the same type
+	MoveOverwrites          bool // Move atomically replaces an existing object at the destination
 
 	// Purge all files in the root and the root directory
 	//
@@ -489,6 +525,42 @@ type Features struct {
 
 	// About gets quota information from the Fs
 	About func() (*Usage, error)
+
+	// Search finds the objects and directories under dir whose
+	// names contain query using the provider's search API.
+	Search SearchFn
+
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt func(remote string, size int64) (WriterAtCloser, error)
+
+	// UserInfo returns info about the connected user
+	UserInfo func() (map[string]string, error)
+
+	// Disconnect the current user by revoking their credentials
+	Disconnect func() error
+
+	// ListUploads lists the multipart uploads which have been
+	// started but not finished or aborted
+	ListUploads func() ([]PendingUpload, error)
+
+	// AbortUpload aborts a multipart upload returned by
+	// ListUploads, deleting the parts uploaded
+	AbortUpload func(upload PendingUpload) error
+
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	Command func(name string, arg []string, opt map[string]string) (interface{}, error)
 }
 
 // Disable nil's out the named feature.  If it isn't found then it
@@ -581,6 +653,25 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(Abouter); ok {
 		ft.About = do.About
 	}
+	if do, ok := f.(Searcher); ok {
+		ft.Search = do.Search
+	}
+	if do, ok := f.(OpenWriterAter); ok {
+		ft.OpenWriterAt = do.OpenWriterAt
+	}
+	if do, ok := f.(UserInfoer); ok {
+		ft.UserInfo = do.UserInfo
+	}
+	if do, ok := f.(Disconnecter); ok {
+		ft.Disconnect = do.Disconnect
+	}
+	if do, ok := f.(UploadLister); ok {
+		ft.ListUploads = do.ListUploads
+		ft.AbortUpload = do.AbortUpload
+	}
+	if do, ok := f.(Commander); ok {
+		ft.Command = do.Command
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -600,6 +691,8 @@ func (ft *Features) Mask(f Fs) *Features {
 	ft.BucketBased = ft.BucketBased && mask.BucketBased
 	ft.SetTier = ft.SetTier && mask.SetTier
 	ft.GetTier = ft.GetTier && mask.GetTier
+	ft.ServerSideAcrossConfigs = ft.ServerSideAcrossConfigs && mask.ServerSideAcrossConfigs
+	ft.MoveOverwrites = ft.MoveOverwrites && mask.MoveOverwrites
 
 	if mask.Purge == nil {
 		ft.Purge = nil
@@ -640,6 +733,27 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.About == nil {
 		ft.About = nil
 	}
+	if mask.Search == nil {
+		ft.Search = nil
+	}
+	if mask.OpenWriterAt == nil {
+		ft.OpenWriterAt = nil
+	}
+	if mask.UserInfo == nil {
+		ft.UserInfo = nil
+	}
+	if mask.Disconnect == nil {
+		ft.Disconnect = nil
+	}
+	if mask.ListUploads == nil {
+		ft.ListUploads = nil
+	}
+	if mask.AbortUpload == nil {
+		ft.AbortUpload = nil
+	}
+	if mask.Command == nil {
+		ft.Command = nil
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -733,6 +847,81 @@ type ChangeNotifier interface {
 	ChangeNotify(func(string, EntryType), <-chan time.Duration)
 }
 
+// WriterAtCloser wraps io.WriterAt and io.Closer
+type WriterAtCloser interface {
+	io.WriterAt
+	io.Closer
+}
+
+// OpenWriterAter is an optional interface for Fs
+type OpenWriterAter interface {
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt(remote string, size int64) (WriterAtCloser, error)
+}
+
+// ObjectVersion describes one version of an object on a remote which
+// keeps old versions.  It is returned by the "versions" backend
+// command.
+type ObjectVersion struct {
+	ID      string    // backend specific ID of the version
+	Size    int64     // size of the version
+	ModTime time.Time // modification time of the version
+	Created time.Time // when the version was made
+	Deleted bool      // set if this version marks the object as deleted
+}
+
+// UserInfoer is an optional interface for Fs
+type UserInfoer interface {
+	// UserInfo returns info about the connected user
+	UserInfo() (map[string]string, error)
+}
+
+// Disconnecter is an optional interface for Fs
+type Disconnecter interface {
+	// Disconnect the current user by revoking their credentials
+	Disconnect() error
+}
+
+// PendingUpload describes a multipart upload which has been started
+// but not finished or aborted
+type PendingUpload struct {
+	Remote  string    // remote the upload is to
+	ID      string    // backend specific ID of the upload
+	Started time.Time // when the upload was started
+}
+
+// UploadLister is an optional interface for Fs
+type UploadLister interface {
+	// ListUploads lists the multipart uploads which have been
+	// started but not finished or aborted
+	ListUploads() ([]PendingUpload, error)
+
+	// AbortUpload aborts a multipart upload returned by
+	// ListUploads, deleting the parts uploaded
+	AbortUpload(upload PendingUpload) error
+}
+
+// Commander is an optional interface for Fs
+type Commander interface {
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	//
+	// It should return ErrorCommandNotFound if name isn't a command
+	// the backend supports.
+	Command(name string, arg []string, opt map[string]string) (interface{}, error)
+}
+
 // UnWrapper is an optional interfaces for Fs
 type UnWrapper interface {
 	// UnWrap returns the Fs that this Fs is wrapping
@@ -839,6 +1028,24 @@ type Abouter interface {
 	About() (*Usage, error)
 }
 
+// Searcher is an optional interface for Fs
+type Searcher interface {
+	// Search finds the objects and directories under dir whose
+	// names contain query using the provider's search API.
+	//
+	// dir should be "" to start from the root, and should not
+	// have trailing slashes.
+	//
+	// This should return ErrDirNotFound if the directory isn't
+	// found.
+	//
+	// It should call callback for each tranche of entries found.
+	// The provider may return entries which don't match query
+	// exactly, so the caller should check the names.  If callback
+	// returns an error then the search will stop immediately.
+	Search(dir, query string, callback ListRCallback) error
+}
+
 // ObjectsChan is a channel of Objects
 type ObjectsChan chan Object
 
@@ -879,7 +1086,13 @@ func MustFind(name string) *RegInfo {
 
 // ParseRemote deconstructs a path into configName, fsPath, looking up
 // the fsName in the config file (returning NotFoundInConfigFile if not found)
+//
+// If --path-templates is set the templates in path are expanded first.
 func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err error) {
+	path, err = expandPath(path)
+	if err != nil {
+		return nil, "", "", err
+	}
 	configName, fsPath = fspath.Parse(path)
 	var fsName string
 	var ok bool
@@ -1031,11 +1244,12 @@ func NewFs(path string) (Fs, error) {
 	return fsInfo.NewFs(configName, fsPath, config)
 }
 
-// TemporaryLocalFs creates a local FS in the OS's temporary directory.
+// TemporaryLocalFs creates a local FS in the temporary directory
+// from TempDir().
 //
 // No cleanup is performed, the caller must call Purge on the Fs themselves.
 func TemporaryLocalFs() (Fs, error) {
-	path, err := ioutil.TempDir("", "rclone-spool")
+	path, err := SpoolDir()
 	if err == nil {
 		err = os.Remove(path)
 	}
@@ -1046,6 +1260,19 @@ func TemporaryLocalFs() (Fs, error) {
 	return NewFs(path)
 }
 
+// QuickHash returns the hash of o if it can be found without reading
+// o, or "" if not.
+//
+// Use this in preference to Hash for hashes which are optional for an
+// upload as the hash of the source will be calculated while the data
+// is being transferred anyway.
+func QuickHash(o ObjectInfo, ht hash.Type) (string, error) {
+	if do, ok := o.(QuickHasher); ok {
+		return do.QuickHash(ht)
+	}
+	return o.Hash(ht)
+}
+
 // CheckClose is a utility function used to check the return from
 // Close in a defer statement.
 func CheckClose(c io.Closer, err *error) {
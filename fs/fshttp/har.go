@@ -0,0 +1,449 @@
+// Capture HTTP transactions to a HAR file for --dump-file
+
+package fshttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+const (
+	// harMaxBody is the largest body captured in the HAR file
+	harMaxBody = 64 * 1024
+	// harRedacted replaces the secrets in the HAR file
+	harRedacted = "XXXX"
+	// harTrailer ends the HAR file after the last entry
+	harTrailer = "\n]}}\n"
+)
+
+// harLog writes HTTP transactions to a file in HTTP Archive (HAR)
+// format.
+//
+// The file is kept valid after every entry by writing the trailer
+// each time and seeking back over it to write the next entry.
+type harLog struct {
+	mu      sync.Mutex
+	fd      *os.File
+	entries int
+}
+
+var (
+	harLogsMu sync.Mutex
+	harLogs   = map[string]*harLog{}
+)
+
+// getHarLog returns the harLog for the file name, creating the file
+// the first time it is asked for.  It returns nil if the file couldn't
+// be created.
+func getHarLog(name string) *harLog {
+	harLogsMu.Lock()
+	defer harLogsMu.Unlock()
+	if h, ok := harLogs[name]; ok {
+		return h
+	}
+	h, err := newHarLog(name)
+	if err != nil {
+		fs.Errorf(nil, "Failed to open --dump-file: %v", err)
+	}
+	harLogs[name] = h
+	return h
+}
+
+// newHarLog creates the HAR file name
+func newHarLog(name string) (*harLog, error) {
+	fd, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	header := map[string]interface{}{
+		"version": "1.2",
+		"creator": map[string]string{
+			"name":    "rclone",
+			"version": fs.Version,
+		},
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	// write the header as the start of the "log" object leaving
+	// it open for the "entries"
+	_, err = fd.Write(append(append([]byte(`{"log":`), headerJSON[:len(headerJSON)-1]...), `,"entries":[`+harTrailer...))
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	return &harLog{fd: fd}, nil
+}
+
+// write adds an entry to the HAR file
+func (h *harLog) write(entry *harEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fs.Errorf(nil, "Failed to encode HTTP transaction for --dump-file: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.fd.Seek(-int64(len(harTrailer)), io.SeekEnd)
+	if err != nil {
+		fs.Errorf(nil, "Failed to write --dump-file: %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	if h.entries > 0 {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\n")
+	buf.Write(data)
+	buf.WriteString(harTrailer)
+	_, err = h.fd.Write(buf.Bytes())
+	if err != nil {
+		fs.Errorf(nil, "Failed to write --dump-file: %v", err)
+		return
+	}
+	h.entries++
+}
+
+// HAR 1.2 structures - see http://www.softwareishard.com/blog/har-12-spec/
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// harSecretNames are the names of headers, query parameters and
+// fields which are always secret
+var harSecretNames = map[string]bool{
+	"authorization":        true,
+	"proxy-authorization":  true,
+	"cookie":               true,
+	"set-cookie":           true,
+	"x-auth-token":         true,
+	"x-storage-token":      true,
+	"x-amz-credential":     true,
+	"x-amz-signature":      true,
+	"signature":            true,
+	"sig":                  true,
+	"key":                  true,
+	"api_key":              true,
+	"apikey":               true,
+	"x-api-key":            true,
+	"accountkey":           true,
+	"x-ms-copy-source-sas": true,
+}
+
+// harIsSecret returns true if the header, query parameter or field
+// called name is likely to contain a secret
+func harIsSecret(name string) bool {
+	name = strings.ToLower(name)
+	if harSecretNames[name] {
+		return true
+	}
+	if strings.Contains(name, "secret") || strings.Contains(name, "password") || strings.Contains(name, "passwd") {
+		return true
+	}
+	// tokens are secret apart from the ones used for paging
+	if strings.Contains(name, "token") {
+		return !strings.Contains(name, "page") && !strings.Contains(name, "next") && !strings.Contains(name, "continuation")
+	}
+	return false
+}
+
+// harHeaders converts headers into HAR form removing the secrets
+func harHeaders(header http.Header) (out []harNameValue) {
+	out = []harNameValue{}
+	for _, name := range harSortedKeys(header) {
+		for _, value := range header[name] {
+			if harIsSecret(name) {
+				value = harRedacted
+			}
+			out = append(out, harNameValue{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+// harSortedKeys returns the keys of m in order
+func harSortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// harValues removes the secrets from values returning the HAR form
+// and the cleaned values
+func harValues(values url.Values) (out []harNameValue, cleaned url.Values) {
+	out = []harNameValue{}
+	cleaned = url.Values{}
+	for _, name := range harSortedKeys(values) {
+		for _, value := range values[name] {
+			if harIsSecret(name) {
+				value = harRedacted
+			}
+			out = append(out, harNameValue{Name: name, Value: value})
+			cleaned.Add(name, value)
+		}
+	}
+	return out, cleaned
+}
+
+// harURL returns the URL with any secrets removed and its query
+func harURL(u *url.URL) (string, []harNameValue) {
+	clean := *u
+	if clean.User != nil {
+		if _, hasPassword := clean.User.Password(); hasPassword {
+			clean.User = url.UserPassword(clean.User.Username(), harRedacted)
+		}
+	}
+	query, cleaned := harValues(u.Query())
+	clean.RawQuery = cleaned.Encode()
+	return clean.String(), query
+}
+
+// harJSONSecret matches "name": "value" pairs in JSON which can't be
+// parsed, eg because the body was truncated
+var harJSONSecret = regexp.MustCompile(`"([^"\\]{1,64})"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+// harScrubJSON removes the secrets from the decoded JSON v
+func harScrubJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for name, value := range x {
+			if _, isString := value.(string); isString && harIsSecret(name) {
+				x[name] = harRedacted
+			} else {
+				x[name] = harScrubJSON(value)
+			}
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = harScrubJSON(x[i])
+		}
+	}
+	return v
+}
+
+// harBody returns the body with any secrets removed
+func harBody(mimeType string, body []byte) string {
+	switch mimeType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err == nil {
+			_, cleaned := harValues(values)
+			return cleaned.Encode()
+		}
+	case "application/json", "text/json":
+		var v interface{}
+		if json.Unmarshal(body, &v) == nil {
+			out, err := json.Marshal(harScrubJSON(v))
+			if err == nil {
+				return string(out)
+			}
+		}
+	}
+	return harJSONSecret.ReplaceAllStringFunc(string(body), func(match string) string {
+		parts := harJSONSecret.FindStringSubmatch(match)
+		if !harIsSecret(parts[1]) {
+			return match
+		}
+		return `"` + parts[1] + `"` + parts[2] + `"` + harRedacted + `"`
+	})
+}
+
+// harTextual returns the media type of contentType and whether bodies
+// of it are text which should be captured
+func harTextual(contentType string) (mimeType string, textual bool) {
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType, false
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		strings.HasSuffix(mimeType, "/json"), strings.HasSuffix(mimeType, "+json"),
+		strings.HasSuffix(mimeType, "/xml"), strings.HasSuffix(mimeType, "+xml"),
+		mimeType == "application/x-www-form-urlencoded":
+		return mimeType, true
+	}
+	return mimeType, false
+}
+
+// readCloser joins a Reader and a Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// harPeek reads up to harMaxBody bytes of *pBody replacing it with a
+// reader which returns the whole body again.  It returns the bytes
+// read and whether that was all of the body.
+func harPeek(pBody *io.ReadCloser) (peeked []byte, all bool) {
+	body := *pBody
+	peeked, err := ioutil.ReadAll(io.LimitReader(body, harMaxBody+1))
+	all = err == nil && len(peeked) <= harMaxBody
+	var rest io.Reader = body
+	if err != nil {
+		rest = &errorReader{err: err}
+	}
+	*pBody = readCloser{Reader: io.MultiReader(bytes.NewReader(peeked), rest), Closer: body}
+	if len(peeked) > harMaxBody {
+		peeked = peeked[:harMaxBody]
+	}
+	return peeked, all
+}
+
+// errorReader returns err when read
+type errorReader struct {
+	err error
+}
+
+// Read returns the error
+func (e *errorReader) Read(p []byte) (int, error) {
+	return 0, e.err
+}
+
+// harStartRequest makes the HAR entry for req, capturing its body if
+// it is text
+func harStartRequest(req *http.Request) *harEntry {
+	u, query := harURL(req.URL)
+	entry := &harEntry{
+		StartedDateTime: time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         u,
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: query,
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		mimeType, textual := harTextual(req.Header.Get("Content-Type"))
+		if textual {
+			body, all := harPeek(&req.Body)
+			entry.Request.PostData = &harPostData{
+				MimeType: mimeType,
+				Text:     harBody(mimeType, body),
+			}
+			if !all {
+				entry.Comment = "request body truncated"
+			}
+		}
+	}
+	return entry
+}
+
+// harFinishResponse fills in the response to the entry, capturing its
+// body if it is text
+func harFinishResponse(entry *harEntry, start time.Time, resp *http.Response, err error) {
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+	entry.Time = elapsed
+	entry.Timings = harTimings{Send: 0, Wait: elapsed, Receive: 0}
+	if err != nil {
+		entry.Comment = "error: " + err.Error()
+		entry.Response = harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		return
+	}
+	mimeType, textual := harTextual(resp.Header.Get("Content-Type"))
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content: harContent{
+			Size:     resp.ContentLength,
+			MimeType: mimeType,
+		},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    resp.ContentLength,
+	}
+	if entry.Response.StatusText == "" {
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+	}
+	if textual && resp.Body != nil {
+		body, all := harPeek(&resp.Body)
+		entry.Response.Content.Text = harBody(mimeType, body)
+		if !all {
+			entry.Response.Content.Comment = "truncated"
+		}
+	}
+}
@@ -127,7 +127,14 @@ func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
 		t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
 		t.TLSHandshakeTimeout = ci.ConnectTimeout
 		t.ResponseHeaderTimeout = ci.Timeout
-		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
+		var err error
+		t.TLSClientConfig, err = NewTLSConfig(ci)
+		if err != nil {
+			// the flags have already been checked so this
+			// can only happen if ci was set up in code
+			fs.Errorf(nil, "Ignoring TLS options: %v", err)
+			t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
+		}
 		t.DisableCompression = ci.NoGzip
 		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
 			return dialContextTimeout(ctx, network, addr, ci)
@@ -155,16 +162,21 @@ type Transport struct {
 	dump          fs.DumpFlags
 	filterRequest func(req *http.Request)
 	userAgent     string
+	har           *harLog // write transactions here if set
 }
 
 // newTransport wraps the http.Transport passed in and logs all
 // roundtrips including the body if logBody is set.
 func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
-	return &Transport{
+	t := &Transport{
 		Transport: transport,
 		dump:      ci.Dump,
 		userAgent: ci.UserAgent,
 	}
+	if ci.DumpFile != "" {
+		t.har = getHarLog(ci.DumpFile)
+	}
+	return t
 }
 
 // SetRequestFilter sets a filter to be used on each request
@@ -278,8 +290,20 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 		fs.Debugf(nil, "%s", string(buf))
 		fs.Debugf(nil, "%s", separatorReq)
 	}
+	// Start the HAR entry
+	var harEntry *harEntry
+	var harStart time.Time
+	if t.har != nil {
+		harEntry = harStartRequest(req)
+		harStart = time.Now()
+	}
 	// Do round trip
 	resp, err = t.Transport.RoundTrip(req)
+	// Write the HAR entry
+	if harEntry != nil {
+		harFinishResponse(harEntry, harStart, resp, err)
+		t.har.write(harEntry)
+	}
 	// Logf response
 	if t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
 		fs.Debugf(nil, "%s", separatorResp)
@@ -0,0 +1,114 @@
+package fshttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// tlsVersions maps the names used in --tls-min-version and
+// --tls-max-version to the versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseTLSVersion parses a TLS version such as "1.2" returning 0 for ""
+func parseTLSVersion(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+	version, ok := tlsVersions[name]
+	if !ok {
+		return 0, errors.Errorf("unknown TLS version %q - must be 1.0, 1.1, 1.2 or 1.3", name)
+	}
+	return version, nil
+}
+
+// parseCipherSuites parses a comma separated list of cipher suite
+// names, eg "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", returning nil for ""
+func parseCipherSuites(names string) (ids []uint16, err error) {
+	if names == "" {
+		return nil, nil
+	}
+	known := map[string]uint16{}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := known[name]
+		if !ok {
+			return nil, errors.Errorf("unknown TLS cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// verifyChain checks the certificates the server sent are signed by
+// a trusted root without checking the host name.
+func verifyChain(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server sent no certificates")
+	}
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+	}
+	var leaf *x509.Certificate
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse server certificate")
+		}
+		if i == 0 {
+			leaf = cert
+		} else {
+			opts.Intermediates.AddCert(cert)
+		}
+	}
+	_, err := leaf.Verify(opts)
+	return err
+}
+
+// NewTLSConfig returns the TLS config for the client transport made
+// from the --tls-* flags in ci
+func NewTLSConfig(ci *fs.ConfigInfo) (*tls.Config, error) {
+	var err error
+	c := &tls.Config{
+		InsecureSkipVerify: ci.InsecureSkipVerify,
+	}
+	c.MinVersion, err = parseTLSVersion(ci.TLSMinVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "--tls-min-version")
+	}
+	c.MaxVersion, err = parseTLSVersion(ci.TLSMaxVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "--tls-max-version")
+	}
+	if c.MinVersion != 0 && c.MaxVersion != 0 && c.MinVersion > c.MaxVersion {
+		return nil, errors.New("--tls-min-version must not be more than --tls-max-version")
+	}
+	c.CipherSuites, err = parseCipherSuites(ci.TLSCipherSuites)
+	if err != nil {
+		return nil, errors.Wrap(err, "--tls-cipher-suites")
+	}
+	if ci.NoTLSSessionTickets {
+		c.SessionTicketsDisabled = true
+	} else {
+		// Keep sessions so reconnections can resume them
+		c.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	if ci.NoVerifyHostname && !ci.InsecureSkipVerify {
+		// Turn off the standard checks and do all but the host
+		// name check ourselves
+		c.InsecureSkipVerify = true
+		c.VerifyPeerCertificate = verifyChain
+	}
+	return c, nil
+}
@@ -0,0 +1,84 @@
+package fshttp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"1.0", tls.VersionTLS10, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.4", 0, true},
+		{"potato", 0, true},
+	} {
+		got, err := parseTLSVersion(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	got, err := parseCipherSuites("")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = parseCipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_RSA_WITH_AES_128_CBC_SHA")
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA}, got)
+
+	_, err = parseCipherSuites("TLS_POTATO")
+	assert.Error(t, err)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	ci := fs.NewConfig()
+	c, err := NewTLSConfig(ci)
+	require.NoError(t, err)
+	assert.False(t, c.InsecureSkipVerify)
+	assert.NotNil(t, c.ClientSessionCache)
+
+	ci.TLSMinVersion = "1.3"
+	ci.TLSMaxVersion = "1.2"
+	_, err = NewTLSConfig(ci)
+	assert.Error(t, err)
+
+	ci = fs.NewConfig()
+	ci.NoTLSSessionTickets = true
+	ci.NoVerifyHostname = true
+	c, err = NewTLSConfig(ci)
+	require.NoError(t, err)
+	assert.True(t, c.SessionTicketsDisabled)
+	assert.Nil(t, c.ClientSessionCache)
+	assert.True(t, c.InsecureSkipVerify)
+	assert.NotNil(t, c.VerifyPeerCertificate)
+}
+
+func TestNoVerifyHostname(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	ci := fs.NewConfig()
+	ci.NoVerifyHostname = true
+	c, err := NewTLSConfig(ci)
+	require.NoError(t, err)
+
+	// The test server's certificate isn't signed by a trusted
+	// root so the chain check must still fail
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: c}}
+	_, err = client.Get(ts.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+}
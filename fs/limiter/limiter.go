@@ -0,0 +1,215 @@
+// Package limiter limits the number of concurrent transfers and
+// checks done on each remote.
+//
+// This is so that when a job uses several remotes (eg a union, or
+// several jobs running at once in the rc) a slow remote can be given
+// fewer of the --transfers and --checkers than a fast one.
+//
+// The limits are read from the config section of the remote
+// ("max_transfers" and "max_checkers") or the equivalent environment
+// variables, eg RCLONE_CONFIG_MYREMOTE_MAX_TRANSFERS.  Remotes
+// without limits, including local paths and on the fly remotes which
+// don't have a config section, are only limited by --transfers and
+// --checkers.
+//
+// The limits of a remote apply to the remotes wrapping it too (eg
+// crypt or union) as long as they return it from Features().UnWrap.
+package limiter
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+)
+
+// Config keys for the limits
+const (
+	TransfersKey = "max_transfers"
+	CheckersKey  = "max_checkers"
+)
+
+// limiter holds the limits for a single remote
+type limiter struct {
+	name      string
+	transfers chan struct{} // tokens for transfers or nil if unlimited
+	checkers  chan struct{} // tokens for checkers or nil if unlimited
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*limiter{}
+
+	releasedMu sync.Mutex
+	released   = make(chan struct{})
+)
+
+// readLimit reads the limit in key from m returning nil if there
+// isn't one
+func readLimit(name string, m configmap.Getter, key string) chan struct{} {
+	value, ok := m.Get(key)
+	if !ok || value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		fs.Errorf(nil, "Ignoring bad %s %q for remote %q", key, value, name)
+		return nil
+	}
+	fs.Debugf(nil, "Limiting remote %q to %s %d", name, key, n)
+	return make(chan struct{}, n)
+}
+
+// get returns the limiter for the remote f is on or nil if it has no
+// limits
+//
+// Only remotes with a config section can have limits, so local paths
+// and on the fly remotes never share a limiter.
+func get(f fs.Info) *limiter {
+	name := f.Name()
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[name]
+	if !ok {
+		m := fs.ConfigMap(nil, name)
+		if _, configured := m.Get("type"); !configured {
+			limiters[name] = nil
+			return nil
+		}
+		l = &limiter{
+			name:      name,
+			transfers: readLimit(name, m, TransfersKey),
+			checkers:  readLimit(name, m, CheckersKey),
+		}
+		if l.transfers == nil && l.checkers == nil {
+			l = nil
+		}
+		limiters[name] = l
+	}
+	return l
+}
+
+// limitersFor returns the limiters of the remotes fses are on, and the
+// remotes they wrap, which have tokens in name order.
+//
+// The tokens are taken in name order so that two callers can't
+// deadlock waiting for each other.
+func limitersFor(tokens func(l *limiter) chan struct{}, fses []fs.Info) (pools []*limiter) {
+	seen := map[string]bool{}
+	for _, f := range fses {
+		for f != nil {
+			l := get(f)
+			if l != nil && tokens(l) != nil && !seen[l.name] {
+				seen[l.name] = true
+				pools = append(pools, l)
+			}
+			unwrap := f.Features().UnWrap
+			if unwrap == nil {
+				break
+			}
+			f = unwrap()
+		}
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].name < pools[j].name
+	})
+	return pools
+}
+
+// release gives back the tokens taken from pools and wakes up
+// anything waiting in Released
+func release(tokens func(l *limiter) chan struct{}, pools []*limiter) {
+	for i := len(pools) - 1; i >= 0; i-- {
+		<-tokens(pools[i])
+	}
+	releasedMu.Lock()
+	close(released)
+	released = make(chan struct{})
+	releasedMu.Unlock()
+}
+
+// acquire gets a token from each of the remotes fses are on using
+// tokens to select the pool and returns a function to give them back.
+func acquire(tokens func(l *limiter) chan struct{}, fses []fs.Info) (done func()) {
+	pools := limitersFor(tokens, fses)
+	if len(pools) == 0 {
+		return func() {}
+	}
+	for _, l := range pools {
+		tokens(l) <- struct{}{}
+	}
+	return func() {
+		release(tokens, pools)
+	}
+}
+
+// tryAcquire is like acquire but returns ok = false without waiting
+// if any of the tokens aren't available.
+func tryAcquire(tokens func(l *limiter) chan struct{}, fses []fs.Info) (done func(), ok bool) {
+	pools := limitersFor(tokens, fses)
+	if len(pools) == 0 {
+		return func() {}, true
+	}
+	for i, l := range pools {
+		select {
+		case tokens(l) <- struct{}{}:
+		default:
+			for j := i - 1; j >= 0; j-- {
+				<-tokens(pools[j])
+			}
+			return nil, false
+		}
+	}
+	return func() {
+		release(tokens, pools)
+	}, true
+}
+
+// Released returns a channel which is closed the next time a transfer
+// or check finishes on a remote with limits.
+//
+// Get the channel before calling TryTransfer or TryCheck so that a
+// release in between isn't missed.
+func Released() <-chan struct{} {
+	releasedMu.Lock()
+	defer releasedMu.Unlock()
+	return released
+}
+
+// Transfer waits until a transfer can start on all the remotes fses
+// are on and returns a function which must be called when the
+// transfer has finished.
+func Transfer(fses ...fs.Info) (done func()) {
+	return acquire(transfers, fses)
+}
+
+// TryTransfer is like Transfer but returns ok = false instead of
+// waiting if the transfer can't start yet.
+func TryTransfer(fses ...fs.Info) (done func(), ok bool) {
+	return tryAcquire(transfers, fses)
+}
+
+// Check waits until a check can start on all the remotes fses are on
+// and returns a function which must be called when the check has
+// finished.
+func Check(fses ...fs.Info) (done func()) {
+	return acquire(checkers, fses)
+}
+
+// TryCheck is like Check but returns ok = false instead of waiting
+// if the check can't start yet.
+func TryCheck(fses ...fs.Info) (done func(), ok bool) {
+	return tryAcquire(checkers, fses)
+}
+
+// transfers selects the transfer tokens of l
+func transfers(l *limiter) chan struct{} {
+	return l.transfers
+}
+
+// checkers selects the checker tokens of l
+func checkers(l *limiter) chan struct{} {
+	return l.checkers
+}
@@ -0,0 +1,191 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+// testInfo is an fs.Info for a remote called by its value
+type testInfo string
+
+func (t testInfo) Name() string             { return string(t) }
+func (t testInfo) Root() string             { return "" }
+func (t testInfo) String() string           { return string(t) + ":" }
+func (t testInfo) Precision() time.Duration { return time.Second }
+func (t testInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (t testInfo) Features() *fs.Features   { return &fs.Features{} }
+
+// wrapInfo is an fs.Info for a remote wrapping another
+type wrapInfo struct {
+	testInfo
+	wrapped fs.Fs
+}
+
+func (w wrapInfo) Features() *fs.Features {
+	return &fs.Features{UnWrap: func() fs.Fs { return w.wrapped }}
+}
+
+// wrappedFs is an fs.Fs for the remote called name
+type wrappedFs struct {
+	fs.Fs
+	name string
+}
+
+func (f wrappedFs) Name() string           { return f.name }
+func (f wrappedFs) Features() *fs.Features { return &fs.Features{} }
+
+// setConfig sets the config file to config and clears the limiters
+// returning a function to restore things
+func setConfig(config map[string]map[string]string) func() {
+	oldConfigFileGet := fs.ConfigFileGet
+	fs.ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := config[section][key]
+		return value, ok
+	}
+	limiters = map[string]*limiter{}
+	return func() {
+		fs.ConfigFileGet = oldConfigFileGet
+		limiters = map[string]*limiter{}
+	}
+}
+
+// run runs n copies of fn at once returning the maximum number which
+// were running concurrently
+func run(n int, acquire func() func()) int32 {
+	var (
+		wg      sync.WaitGroup
+		running int32
+		max     int32
+		mu      sync.Mutex
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := acquire()
+			now := atomic.AddInt32(&running, 1)
+			mu.Lock()
+			if now > max {
+				max = now
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			done()
+		}()
+	}
+	wg.Wait()
+	return max
+}
+
+func TestLimits(t *testing.T) {
+	defer setConfig(map[string]map[string]string{
+		"slow": {
+			"type":       "local",
+			TransfersKey: "2",
+			CheckersKey:  "3",
+		},
+		"bad": {
+			"type":       "local",
+			TransfersKey: "potato",
+		},
+	})()
+	slow, fast, bad := testInfo("slow"), testInfo("fast"), testInfo("bad")
+
+	assert.Equal(t, int32(2), run(10, func() func() { return Transfer(slow) }))
+	assert.Equal(t, int32(3), run(10, func() func() { return Check(slow) }))
+	assert.Equal(t, int32(2), run(10, func() func() { return Transfer(fast, slow) }))
+	assert.Equal(t, int32(10), run(10, func() func() { return Transfer(fast) }))
+	assert.Equal(t, int32(10), run(10, func() func() { return Transfer(bad) }))
+
+	// The same remote twice should only take one token
+	assert.Equal(t, int32(2), run(10, func() func() { return Transfer(slow, slow) }))
+
+	// nil is ignored
+	done := Transfer(nil, fast)
+	done()
+
+	assert.Nil(t, limiters["fast"])
+	assert.Nil(t, limiters["bad"])
+	assert.NotNil(t, limiters["slow"])
+}
+
+func TestNoDeadlock(t *testing.T) {
+	defer setConfig(map[string]map[string]string{
+		"a": {"type": "local", TransfersKey: "1"},
+		"b": {"type": "local", TransfersKey: "1"},
+	})()
+	a, b := testInfo("a"), testInfo("b")
+	var i int32
+	max := run(20, func() func() {
+		if atomic.AddInt32(&i, 1)%2 == 0 {
+			return Transfer(a, b)
+		}
+		return Transfer(b, a)
+	})
+	assert.Equal(t, int32(1), max)
+}
+
+func TestUnconfigured(t *testing.T) {
+	defer setConfig(map[string]map[string]string{
+		"local": {TransfersKey: "1"},
+	})()
+	local := testInfo("local")
+	assert.Equal(t, int32(10), run(10, func() func() { return Transfer(local) }))
+	assert.Nil(t, limiters["local"])
+}
+
+func TestUnWrap(t *testing.T) {
+	defer setConfig(map[string]map[string]string{
+		"slow": {"type": "local", TransfersKey: "2"},
+	})()
+	wrapper := wrapInfo{testInfo: "wrapper", wrapped: wrappedFs{name: "slow"}}
+	assert.Equal(t, int32(2), run(10, func() func() { return Transfer(wrapper) }))
+}
+
+func TestTryTransfer(t *testing.T) {
+	defer setConfig(map[string]map[string]string{
+		"a": {"type": "local", TransfersKey: "1"},
+		"b": {"type": "local", TransfersKey: "1"},
+	})()
+	a, b := testInfo("a"), testInfo("b")
+
+	doneA, ok := TryTransfer(a)
+	assert.True(t, ok)
+
+	// b is free but a isn't so nothing is taken
+	_, ok = TryTransfer(a, b)
+	assert.False(t, ok)
+	doneB, ok := TryTransfer(b)
+	assert.True(t, ok)
+	doneB()
+
+	// Giving back a wakes up Released
+	released := Released()
+	select {
+	case <-released:
+		t.Fatal("released before done")
+	default:
+	}
+	doneA()
+	select {
+	case <-released:
+	default:
+		t.Fatal("not released after done")
+	}
+
+	done, ok := TryTransfer(a, b)
+	assert.True(t, ok)
+	done()
+
+	// Remotes without limits always start
+	done, ok = TryCheck(a)
+	assert.True(t, ok)
+	done()
+}
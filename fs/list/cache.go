@@ -0,0 +1,492 @@
+package list
+
+// This implements the listings cache used by --use-listings-cache
+//
+// Each directory listing is stored as a JSON file in the cache
+// directory named after the MD5 of the absolute path of the directory
+// so that listings can be shared between different Fs rooted at
+// different points in the same remote.
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// cacheEntry is a single entry in a cached listing
+type cacheEntry struct {
+	Remote   string
+	Dir      bool `json:",omitempty"`
+	Size     int64
+	ModTime  time.Time
+	Items    int64  `json:",omitempty"` // number of items in a directory if known
+	ID       string `json:",omitempty"` // ID if known
+	MimeType string `json:",omitempty"` // MIME type of an object if known
+	Tier     string `json:",omitempty"` // storage tier of an object if known
+}
+
+// cacheListing is the contents of a cache file
+type cacheListing struct {
+	Key     string // absolute path of the directory
+	Time    time.Time
+	Entries []cacheEntry
+}
+
+// cacheMu serialises access to the cache directory
+var cacheMu sync.Mutex
+
+// cacheDir returns the directory the listings are stored in
+func cacheDir() string {
+	return filepath.Join(config.CacheDir, "listings")
+}
+
+// cacheKey returns the absolute path of dir in f
+func cacheKey(f fs.Info, dir string) string {
+	return f.Name() + ":" + path.Join(f.Root(), dir)
+}
+
+// cachePath returns the path of the file the listing for key is stored in
+func cachePath(key string) string {
+	sum := md5.Sum([]byte(key))
+	return filepath.Join(cacheDir(), hex.EncodeToString(sum[:])+".json")
+}
+
+// cacheGet reads the listing of dir from the cache returning ok if
+// it was found and hasn't expired.
+func cacheGet(f fs.Fs, dir string) (entries fs.DirEntries, ok bool) {
+	key := cacheKey(f, dir)
+	cacheMu.Lock()
+	data, err := ioutil.ReadFile(cachePath(key))
+	cacheMu.Unlock()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fs.Debugf(f, "Listings cache: failed to read %q: %v", dir, err)
+		}
+		return nil, false
+	}
+	var listing cacheListing
+	err = json.Unmarshal(data, &listing)
+	if err != nil {
+		fs.Debugf(f, "Listings cache: failed to decode %q: %v", dir, err)
+		return nil, false
+	}
+	if listing.Key != key {
+		// MD5 collision - very unlikely
+		return nil, false
+	}
+	if age := time.Since(listing.Time); age > fs.Config.ListingsCacheAge {
+		fs.Debugf(f, "Listings cache: %q expired %v ago", dir, age-fs.Config.ListingsCacheAge)
+		return nil, false
+	}
+	entries = make(fs.DirEntries, 0, len(listing.Entries))
+	parent := &cachedDir{f: f, dir: dir}
+	for _, entry := range listing.Entries {
+		if entry.Dir {
+			entries = append(entries, fs.NewDir(entry.Remote, entry.ModTime).SetSize(entry.Size).SetItems(entry.Items).SetID(entry.ID))
+		} else {
+			entries = append(entries, &cachedObject{
+				parent:   parent,
+				remote:   entry.Remote,
+				size:     entry.Size,
+				modTime:  entry.ModTime,
+				id:       entry.ID,
+				mimeType: entry.MimeType,
+				tier:     entry.Tier,
+			})
+		}
+	}
+	fs.Debugf(f, "Listings cache: using cached listing of %q", dir)
+	return entries, true
+}
+
+// cachePut writes the listing of dir to the cache
+func cachePut(f fs.Fs, dir string, entries fs.DirEntries) {
+	listing := cacheListing{
+		Key:     cacheKey(f, dir),
+		Time:    time.Now(),
+		Entries: make([]cacheEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		item := cacheEntry{
+			Remote:  entry.Remote(),
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(),
+		}
+		switch x := entry.(type) {
+		case fs.Directory:
+			item.Dir = true
+			item.Items = x.Items()
+			item.ID = x.ID()
+		case fs.Object:
+			if do, ok := x.(fs.IDer); ok {
+				item.ID = do.ID()
+			}
+			if do, ok := x.(fs.MimeTyper); ok {
+				item.MimeType = do.MimeType()
+			}
+			if do, ok := x.(fs.GetTierer); ok {
+				item.Tier = do.GetTier()
+			}
+		}
+		listing.Entries = append(listing.Entries, item)
+	}
+	data, err := json.Marshal(&listing)
+	if err == nil {
+		err = writeCacheFile(cachePath(listing.Key), data)
+	}
+	if err != nil {
+		fs.Debugf(f, "Listings cache: failed to write %q: %v", dir, err)
+	}
+}
+
+// writeCacheFile atomically writes data to name
+func writeCacheFile(name string, data []byte) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	err := os.MkdirAll(filepath.Dir(name), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// cachedList lists dir in f using the listings cache if
+// --use-listings-cache is set
+func cachedList(f fs.Fs, dir string) (entries fs.DirEntries, err error) {
+	if !fs.Config.UseListingsCache {
+		return uncachedList(f, dir)
+	}
+	entries, ok := cacheGet(f, dir)
+	if ok {
+		return entries, nil
+	}
+	entries, err = uncachedList(f, dir)
+	if err != nil {
+		return nil, err
+	}
+	cachePut(f, dir, entries)
+	return entries, nil
+}
+
+// invalidate removes the cached listing of dir in f
+func invalidate(f fs.Info, dir string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	err := os.Remove(cachePath(cacheKey(f, dir)))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Debugf(f, "Listings cache: failed to remove %q: %v", dir, err)
+	}
+}
+
+// parent returns the directory remote is in
+func parent(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// Changed should be called when the file or directory remote in f is
+// created, modified or removed so the listings cache isn't used for
+// it or the directory it is in any more.
+//
+// It does nothing unless --use-listings-cache is set, so listings
+// cached by earlier runs may be stale if rclone was used without the
+// flag in between.  --listings-cache-age limits how stale they can be.
+func Changed(f fs.Info, remote string) {
+	if !fs.Config.UseListingsCache {
+		return
+	}
+	invalidate(f, parent(remote))
+	invalidate(f, remote)
+}
+
+// ChangedTree should be called when dir in f and everything under it
+// has been removed.  It removes dir, all the directories under it
+// and the directory it is in from the listings cache.
+//
+// Like Changed it does nothing unless --use-listings-cache is set.
+func ChangedTree(f fs.Info, dir string) {
+	if !fs.Config.UseListingsCache {
+		return
+	}
+	invalidate(f, parent(dir))
+	prefix := cacheKey(f, dir)
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	files, err := ioutil.ReadDir(cacheDir())
+	if err != nil {
+		return
+	}
+	for _, fi := range files {
+		if !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		name := filepath.Join(cacheDir(), fi.Name())
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		var listing cacheListing
+		if json.Unmarshal(data, &listing) != nil {
+			continue
+		}
+		if isUnder(listing.Key, prefix) {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// isUnder returns true if key is prefix or is a directory under it
+func isUnder(key, prefix string) bool {
+	if key == prefix {
+		return true
+	}
+	if !strings.HasSuffix(prefix, "/") && !strings.HasSuffix(prefix, ":") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(key, prefix)
+}
+
+// cachedDir is a directory listing read from the listings cache.
+//
+// It lists the directory on the remote the first time one of its
+// objects is needed, so looking up the real objects costs one listing
+// per directory rather than one request per object.
+type cachedDir struct {
+	f   fs.Fs
+	dir string
+
+	mu      sync.Mutex
+	listed  bool
+	err     error
+	objects map[string]fs.Object // real objects by remote
+}
+
+// object returns the real object for remote, listing the directory
+// if necessary
+func (d *cachedDir) object(remote string) (fs.Object, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.listed {
+		d.listed = true
+		entries, err := d.f.List(d.dir)
+		if err != nil {
+			d.err = errors.Wrap(err, "listings cache: failed to list directory")
+		} else {
+			cachePut(d.f, d.dir, entries)
+			d.objects = make(map[string]fs.Object, len(entries))
+			for _, entry := range entries {
+				if o, ok := entry.(fs.Object); ok {
+					d.objects[o.Remote()] = o
+				}
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	o, ok := d.objects[remote]
+	if !ok {
+		return nil, errors.Wrap(fs.ErrorObjectNotFound, "listings cache: failed to find object")
+	}
+	return o, nil
+}
+
+// cachedObject is an fs.Object read from the listings cache.
+//
+// It returns the size, modification time, ID, MIME type and tier
+// from the cache and looks up the real object on the remote when
+// anything else is needed.
+//
+// Backends can't use it in place of their own objects, so operations
+// which pass objects to backend methods such as Copy, Move or
+// SetTier must use Uncached first.
+type cachedObject struct {
+	parent   *cachedDir
+	remote   string
+	size     int64
+	modTime  time.Time
+	id       string
+	mimeType string
+	tier     string
+
+	mu sync.Mutex
+	o  fs.Object // the real object, or nil if not looked up yet
+}
+
+// object returns the real object, looking it up if necessary
+func (o *cachedObject) object() (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o == nil {
+		obj, err := o.parent.object(o.remote)
+		if err != nil {
+			return nil, err
+		}
+		o.o = obj
+	}
+	return o.o, nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *cachedObject) Fs() fs.Info {
+	return o.parent.f
+}
+
+// String returns a description of the Object
+func (o *cachedObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *cachedObject) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification date of the file
+func (o *cachedObject) ModTime() time.Time {
+	return o.modTime
+}
+
+// Size returns the size of the file
+func (o *cachedObject) Size() int64 {
+	return o.size
+}
+
+// Storable says whether this object can be stored
+func (o *cachedObject) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+func (o *cachedObject) Hash(ht hash.Type) (string, error) {
+	obj, err := o.object()
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ht)
+}
+
+// SetModTime sets the metadata on the object to set the modification date
+func (o *cachedObject) SetModTime(t time.Time) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	err = obj.SetModTime(t)
+	Changed(o.parent.f, o.remote)
+	return err
+}
+
+// Open opens the file for read
+func (o *cachedObject) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(options...)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *cachedObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	err = obj.Update(in, src, options...)
+	Changed(o.parent.f, o.remote)
+	return err
+}
+
+// Remove this object
+func (o *cachedObject) Remove() error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	err = obj.Remove()
+	Changed(o.parent.f, o.remote)
+	return err
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *cachedObject) ID() string {
+	return o.id
+}
+
+// MimeType returns the content type of the Object if known, or ""
+// if not
+func (o *cachedObject) MimeType() string {
+	return o.mimeType
+}
+
+// GetTier returns the storage tier of the Object if known, or "" if
+// not
+func (o *cachedObject) GetTier() string {
+	return o.tier
+}
+
+// UnWrap returns the real object or nil if it can't be found
+func (o *cachedObject) UnWrap() fs.Object {
+	obj, err := o.object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// Uncached returns the real object if o came from the listings
+// cache, otherwise it returns o.
+//
+// This should be used before passing an object to a backend which
+// needs its own object type, eg for server side copies.
+func Uncached(o fs.Object) fs.Object {
+	if co, ok := o.(*cachedObject); ok {
+		if obj := co.UnWrap(); obj != nil {
+			return obj
+		}
+	}
+	return o
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*cachedObject)(nil)
+	_ fs.ObjectUnWrapper = (*cachedObject)(nil)
+	_ fs.IDer            = (*cachedObject)(nil)
+	_ fs.MimeTyper       = (*cachedObject)(nil)
+	_ fs.GetTierer       = (*cachedObject)(nil)
+)
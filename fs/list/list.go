@@ -6,10 +6,21 @@ import (
 	"strings"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/cost"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/pkg/errors"
 )
 
+// HashSidecarSuffix is the suffix of the objects which hold the
+// hashes of the source with --hash-sidecar
+const HashSidecarSuffix = ".rclone-hash"
+
+// IsHashSidecar returns true if remote is a hash sidecar which should
+// be hidden from listings
+func IsHashSidecar(remote string) bool {
+	return fs.Config.HashSidecar && strings.HasSuffix(remote, HashSidecarSuffix)
+}
+
 // DirSorted reads Object and *Dir into entries for the given Fs.
 //
 // dir is the start directory, "" for root
@@ -17,10 +28,32 @@ import (
 // If includeAll is specified all files will be added, otherwise only
 // files and directories passing the filter will be added.
 //
+// If --use-listings-cache is set then the listing may come from the
+// listings cache.
+//
 // Files will be returned in sorted order
 func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+	return dirSorted(f, includeAll, dir, cachedList)
+}
+
+// DirSortedNoCache is like DirSorted but never uses the listings
+// cache.  It is for users which keep their own directory cache, eg
+// the VFS.
+func DirSortedNoCache(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+	return dirSorted(f, includeAll, dir, uncachedList)
+}
+
+// uncachedList lists dir in f recording the List call for
+// --estimate-cost
+func uncachedList(f fs.Fs, dir string) (fs.DirEntries, error) {
+	cost.Record(f, cost.List, 1, 0)
+	return f.List(dir)
+}
+
+// dirSorted implements DirSorted using listDir to read the directory
+func dirSorted(f fs.Fs, includeAll bool, dir string, listDir func(f fs.Fs, dir string) (fs.DirEntries, error)) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(dir)
+	entries, err = listDir(f, dir)
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +85,8 @@ func filterAndSortDir(entries fs.DirEntries, includeAll bool, dir string,
 			if !includeAll && !IncludeObject(x) {
 				ok = false
 				fs.Debugf(x, "Excluded")
+			} else if IsHashSidecar(x.Remote()) {
+				ok = false
 			}
 		case fs.Directory:
 			if !includeAll {
@@ -75,10 +75,17 @@ var LogPrint = func(level LogLevel, text string) {
 
 // LogPrintf produces a log string from the arguments passed in
 func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{}) {
-	out := fmt.Sprintf(text, args...)
+	msg := fmt.Sprintf(text, args...)
+	out := msg
 	if o != nil {
 		out = fmt.Sprintf("%v: %s", o, out)
 	}
+	if level <= LogLevelError {
+		recordError(level, o, msg, args)
+	}
+	if logDedupe.suppress(level, msg, out) {
+		return
+	}
 	LogPrint(level, out)
 }
 
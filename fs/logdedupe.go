@@ -0,0 +1,116 @@
+// Collapse repeated log messages
+
+package fs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// suppressedLogSize is the number of suppressed log lines kept
+const suppressedLogSize = 1000
+
+// LogLine is a log line kept in memory
+type LogLine struct {
+	Time   time.Time
+	Level  LogLevel
+	Object string // the object or Fs logged about, if any
+	Class  string // for errors, the kind of error - see ErrorClass
+	Text   string
+}
+
+// logRing keeps the last few log lines
+type logRing struct {
+	mu    sync.Mutex
+	size  int
+	lines []LogLine
+	next  int
+}
+
+// newLogRing makes a logRing which keeps size lines
+func newLogRing(size int) *logRing {
+	return &logRing{size: size}
+}
+
+// add a line to the ring overwriting the oldest if full
+func (r *logRing) add(line LogLine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.lines) < r.size {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % r.size
+}
+
+// get returns a copy of the lines in the ring, oldest first
+func (r *logRing) get() []LogLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]LogLine, 0, len(r.lines))
+	lines = append(lines, r.lines[r.next:]...)
+	lines = append(lines, r.lines[:r.next]...)
+	return lines
+}
+
+// logDedupeKey identifies identical messages
+type logDedupeKey struct {
+	level LogLevel
+	msg   string
+}
+
+// logDeduper collapses identical messages logged within
+// Config.LogDedupe of the first one into a summary line
+type logDeduper struct {
+	mu         sync.Mutex
+	seen       map[logDedupeKey]int // number of times suppressed
+	suppressed *logRing
+}
+
+var logDedupe = &logDeduper{
+	seen:       make(map[logDedupeKey]int),
+	suppressed: newLogRing(suppressedLogSize),
+}
+
+// suppress returns true if the log line out should be suppressed.
+//
+// msg is the message without the object it refers to so that the
+// same error on different objects is collapsed.
+func (d *logDeduper) suppress(level LogLevel, msg, out string) bool {
+	if Config.LogDedupe <= 0 || level > LogLevelWarning {
+		return false
+	}
+	key := logDedupeKey{level: level, msg: msg}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	count, found := d.seen[key]
+	if !found {
+		d.seen[key] = 0
+		time.AfterFunc(Config.LogDedupe, func() {
+			d.flush(key)
+		})
+		return false
+	}
+	d.seen[key] = count + 1
+	d.suppressed.add(LogLine{Time: time.Now(), Level: level, Text: out})
+	return true
+}
+
+// flush logs a summary of the suppressed messages for key
+func (d *logDeduper) flush(key logDedupeKey) {
+	d.mu.Lock()
+	count := d.seen[key]
+	delete(d.seen, key)
+	d.mu.Unlock()
+	if count > 0 {
+		LogPrint(key.level, fmt.Sprintf("%s (repeated %d more times in the last %v)", key.msg, count, Config.LogDedupe))
+	}
+}
+
+// SuppressedLogs returns the most recent log lines suppressed by
+// --log-dedupe, oldest first
+func SuppressedLogs() []LogLine {
+	return logDedupe.suppressed.get()
+}
@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogRing(t *testing.T) {
+	r := newLogRing(3)
+	assert.Equal(t, []LogLine{}, r.get())
+	for i, text := range []string{"one", "two", "three", "four", "five"} {
+		r.add(LogLine{Level: LogLevel(i), Text: text})
+	}
+	var got []string
+	for _, line := range r.get() {
+		got = append(got, line.Text)
+	}
+	assert.Equal(t, []string{"three", "four", "five"}, got)
+}
+
+func TestLogDedupe(t *testing.T) {
+	var mu sync.Mutex
+	var logged []string
+	oldLogPrint, oldLogDedupe := LogPrint, Config.LogDedupe
+	LogPrint = func(level LogLevel, text string) {
+		mu.Lock()
+		logged = append(logged, text)
+		mu.Unlock()
+	}
+	Config.LogDedupe = 50 * time.Millisecond
+	defer func() {
+		LogPrint, Config.LogDedupe = oldLogPrint, oldLogDedupe
+	}()
+
+	Errorf("file1", "Failed to copy: %v", "403 Forbidden")
+	Errorf("file2", "Failed to copy: %v", "403 Forbidden")
+	Errorf("file3", "Failed to copy: %v", "403 Forbidden")
+	Errorf("file4", "Failed to copy: %v", "404 Not Found")
+
+	mu.Lock()
+	assert.Equal(t, []string{
+		"file1: Failed to copy: 403 Forbidden",
+		"file4: Failed to copy: 404 Not Found",
+	}, logged)
+	mu.Unlock()
+
+	suppressed := SuppressedLogs()
+	assert.True(t, len(suppressed) >= 2)
+	assert.Equal(t, "file3: Failed to copy: 403 Forbidden", suppressed[len(suppressed)-1].Text)
+	assert.Equal(t, LogLevelError, suppressed[len(suppressed)-1].Level)
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []string{
+		"file1: Failed to copy: 403 Forbidden",
+		"file4: Failed to copy: 404 Not Found",
+		"Failed to copy: 403 Forbidden (repeated 2 more times in the last 50ms)",
+	}, logged)
+	mu.Unlock()
+}
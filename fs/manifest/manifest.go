@@ -0,0 +1,422 @@
+// Package manifest makes, signs and verifies manifests of the files
+// in a remote so the remote can be checked against them later.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// DefaultName is the default name of the manifest file
+const DefaultName = ".rclone-manifest.json"
+
+// SignatureSuffix is added to the name of the manifest to make the
+// name of the signature file
+const SignatureSuffix = ".sig"
+
+// SignaturePlaceholder is replaced by the path of the signature file
+// in the verify command
+const SignaturePlaceholder = "{signature}"
+
+// Version is the version of the manifest format
+const Version = 1
+
+// Entry describes one file in the manifest
+type Entry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hash    string `json:",omitempty"`
+}
+
+// Manifest is a list of the files in a remote
+type Manifest struct {
+	Version  int
+	Remote   string    // the remote the manifest was made from
+	Created  time.Time // when the manifest was made
+	HashType string    // type of the hashes in the Files or "None"
+	Files    []Entry   // the files sorted by Path
+}
+
+// excluded returns true if remote is the manifest or its signature
+func excluded(name, remote string) bool {
+	return remote == name || remote == name+SignatureSuffix
+}
+
+// escapeGlob escapes the characters in s which are special in filter
+// globs
+func escapeGlob(s string) string {
+	var out bytes.Buffer
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]{}\`, c) {
+			_, _ = out.WriteRune('\\')
+		}
+		_, _ = out.WriteRune(c)
+	}
+	return out.String()
+}
+
+// Exclude adds rules to the active filter to exclude the manifest
+// called name and its signature.
+//
+// This should be used when syncing to a remote with a manifest in so
+// that the manifest isn't deleted as an extraneous file.
+func Exclude(name string) error {
+	for _, remote := range []string{name, name + SignatureSuffix} {
+		err := filter.Active.Add(false, "/"+escapeGlob(remote))
+		if err != nil {
+			return errors.Wrap(err, "failed to exclude manifest")
+		}
+	}
+	return nil
+}
+
+// SplitCommand splits the command line s into arguments on white
+// space.  Arguments containing spaces can be quoted with single or
+// double quotes, eg
+//
+//     gpg --batch --detach-sign --local-user "Archive Key"
+func SplitCommand(s string) (args []string, err error) {
+	var (
+		arg     bytes.Buffer
+		inArg   bool
+		inQuote rune
+	)
+	for _, c := range s {
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			} else {
+				_, _ = arg.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			_, _ = arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inQuote != 0 {
+		return nil, errors.Errorf("unterminated %c in command %q", inQuote, s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// list returns all the objects in f except the manifest named name
+func list(f fs.Fs, name string) (objs []fs.Object, err error) {
+	err = walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		entries.ForObject(func(o fs.Object) {
+			if !excluded(name, o.Remote()) {
+				objs = append(objs, o)
+			}
+		})
+		return nil
+	})
+	return objs, err
+}
+
+// Make makes a manifest for all the files in f except the manifest
+// called name.  It uses the first hash type f supports.  It obeys
+// includes and excludes.
+func Make(f fs.Fs, name string) (*Manifest, error) {
+	objs, err := list(f, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list files for manifest")
+	}
+	ht := f.Hashes().GetOne()
+	m := &Manifest{
+		Version:  Version,
+		Remote:   f.Name() + ":" + f.Root(),
+		Created:  time.Now().UTC(),
+		HashType: ht.String(),
+		Files:    make([]Entry, len(objs)),
+	}
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+		in       = make(chan int, fs.Config.Checkers)
+	)
+	for i := 0; i < fs.Config.Checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range in {
+				o := objs[i]
+				accounting.Stats.Checking(o.Remote())
+				entry := Entry{
+					Path:    o.Remote(),
+					Size:    o.Size(),
+					ModTime: o.ModTime().UTC(),
+				}
+				if ht != hash.None {
+					var err error
+					entry.Hash, err = o.Hash(ht)
+					if err != nil {
+						fs.Errorf(o, "Failed to read hash: %v", err)
+						errMu.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						errMu.Unlock()
+					}
+				}
+				accounting.Stats.DoneChecking(o.Remote())
+				m.Files[i] = entry
+			}
+		}()
+	}
+	for i := range objs {
+		in <- i
+	}
+	close(in)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, errors.Wrap(firstErr, "failed to make manifest")
+	}
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+	return m, nil
+}
+
+// runCommand runs the command line in cmdLine with in as its standard
+// input, returning its standard output
+func runCommand(cmdLine []string, in []byte) ([]byte, error) {
+	if len(cmdLine) == 0 {
+		return nil, errors.New("empty command")
+	}
+	cmd := exec.Command(cmdLine[0], cmdLine[1:]...)
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q failed: %s", strings.Join(cmdLine, " "), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// upload writes data to name in f
+func upload(f fs.Fs, name string, data []byte) error {
+	_, err := operations.Rcat(f, name, ioutil.NopCloser(bytes.NewReader(data)), time.Now())
+	return err
+}
+
+// Write uploads the manifest m to name in f.
+//
+// If signCmd is not empty then the command is run with the manifest
+// on its standard input and its standard output is uploaded as the
+// signature alongside the manifest, eg
+//
+//     gpg --batch --detach-sign --armor
+func Write(f fs.Fs, name string, m *Manifest, signCmd []string) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifest")
+	}
+	data = append(data, '\n')
+	if len(signCmd) > 0 {
+		signature, err := runCommand(signCmd, data)
+		if err != nil {
+			return errors.Wrap(err, "failed to sign manifest")
+		}
+		err = upload(f, name+SignatureSuffix, signature)
+		if err != nil {
+			return errors.Wrap(err, "failed to upload manifest signature")
+		}
+	}
+	err = upload(f, name, data)
+	if err != nil {
+		return errors.Wrap(err, "failed to upload manifest")
+	}
+	fs.Infof(f, "Wrote manifest of %d files to %q", len(m.Files), name)
+	return nil
+}
+
+// download reads name from f
+func download(f fs.Fs, name string) ([]byte, error) {
+	o, err := f.NewObject(name)
+	if err != nil {
+		return nil, err
+	}
+	in, err := o.Open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(in)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return data, err
+}
+
+// checkSignature runs verifyCmd to check signature is valid for data
+func checkSignature(verifyCmd []string, data, signature []byte) error {
+	sigFile, err := ioutil.TempFile(fs.TempDir(), "rclone-manifest-sig")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(sigFile.Name())
+	}()
+	_, err = sigFile.Write(signature)
+	closeErr := sigFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write signature to temporary file")
+	}
+	cmdLine := make([]string, len(verifyCmd))
+	for i, arg := range verifyCmd {
+		cmdLine[i] = strings.Replace(arg, SignaturePlaceholder, sigFile.Name(), -1)
+	}
+	_, err = runCommand(cmdLine, data)
+	return err
+}
+
+// Read downloads the manifest called name from f.
+//
+// If verifyCmd is not empty then the signature of the manifest is
+// checked by running the command with the manifest on its standard
+// input.  Any arguments containing SignaturePlaceholder have it
+// replaced with the path of a temporary file holding the signature,
+// eg
+//
+//     gpg --verify {signature} -
+func Read(f fs.Fs, name string, verifyCmd []string) (*Manifest, error) {
+	data, err := download(f, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest")
+	}
+	if len(verifyCmd) > 0 {
+		signature, err := download(f, name+SignatureSuffix)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read manifest signature")
+		}
+		err = checkSignature(verifyCmd, data, signature)
+		if err != nil {
+			return nil, errors.Wrap(err, "manifest signature is not valid")
+		}
+		fs.Infof(f, "Manifest signature is valid")
+	}
+	m := new(Manifest)
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifest")
+	}
+	if m.Version != Version {
+		return nil, errors.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return m, nil
+}
+
+// Verify checks the files in f against the manifest m (which was
+// read from name) logging any differences.  It returns an error if
+// there were any differences.
+func Verify(f fs.Fs, name string, m *Manifest) error {
+	var ht hash.Type
+	err := ht.Set(m.HashType)
+	if err != nil {
+		return errors.Wrap(err, "bad manifest")
+	}
+	if ht != hash.None && !f.Hashes().Contains(ht) {
+		fs.Logf(f, "Remote doesn't support %v hashes - only checking sizes and modification times", ht)
+		ht = hash.None
+	}
+	objs, err := list(f, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to list files to verify")
+	}
+	byPath := make(map[string]fs.Object, len(objs))
+	for _, o := range objs {
+		byPath[o.Remote()] = o
+	}
+	modifyWindow := fs.GetModifyWindow(f)
+	var differences, matches int
+	differ := func(o interface{}, format string, args ...interface{}) {
+		differences++
+		err := errors.Errorf(format, args...)
+		fs.CountError(err)
+		fs.Errorf(o, "%v", err)
+	}
+	for _, entry := range m.Files {
+		o, ok := byPath[entry.Path]
+		if !ok {
+			differ(entry.Path, "File in manifest not found")
+			continue
+		}
+		delete(byPath, entry.Path)
+		accounting.Stats.Checking(o.Remote())
+		switch {
+		case o.Size() != entry.Size:
+			differ(o, "Sizes differ: manifest %d, remote %d", entry.Size, o.Size())
+		case modifyWindow != fs.ModTimeNotSupported && !withinWindow(o.ModTime(), entry.ModTime, modifyWindow):
+			differ(o, "Modification times differ: manifest %v, remote %v", entry.ModTime, o.ModTime())
+		case ht != hash.None && entry.Hash != "":
+			sum, err := o.Hash(ht)
+			if err != nil {
+				differ(o, "Failed to read hash: %v", err)
+			} else if !hash.Equals(sum, entry.Hash) {
+				differ(o, "%v differ: manifest %q, remote %q", ht, entry.Hash, sum)
+			} else {
+				matches++
+			}
+		default:
+			matches++
+		}
+		accounting.Stats.DoneChecking(o.Remote())
+	}
+	extra := make([]string, 0, len(byPath))
+	for remote := range byPath {
+		extra = append(extra, remote)
+	}
+	sort.Strings(extra)
+	for _, remote := range extra {
+		differ(byPath[remote], "File not in manifest")
+	}
+	fs.Logf(f, "%d files matched the manifest made at %v", matches, m.Created)
+	if differences > 0 {
+		return errors.Errorf("%d differences found", differences)
+	}
+	return nil
+}
+
+// withinWindow returns true if t1 and t2 are the same to within
+// modifyWindow
+func withinWindow(t1, t2 time.Time, modifyWindow time.Duration) bool {
+	dt := t1.Sub(t2)
+	return dt < modifyWindow && dt > -modifyWindow
+}
@@ -0,0 +1,143 @@
+package manifest
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Some times used in the tests
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+func TestMakeWriteReadVerify(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("file1", "hello world", t1)
+	file2 := r.WriteObject("sub dir/file2", "potato", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	m, err := Make(r.Fremote, DefaultName)
+	require.NoError(t, err)
+	assert.Equal(t, Version, m.Version)
+	require.Len(t, m.Files, 2)
+	assert.Equal(t, "file1", m.Files[0].Path)
+	assert.Equal(t, int64(11), m.Files[0].Size)
+	assert.Equal(t, "sub dir/file2", m.Files[1].Path)
+	assert.Equal(t, int64(6), m.Files[1].Size)
+	if m.HashType != "None" {
+		assert.NotEqual(t, "", m.Files[0].Hash)
+	}
+
+	require.NoError(t, Write(r.Fremote, DefaultName, m, nil))
+
+	// The manifest itself shouldn't be in a new manifest
+	m2, err := Make(r.Fremote, DefaultName)
+	require.NoError(t, err)
+	assert.Len(t, m2.Files, 2)
+
+	got, err := Read(r.Fremote, DefaultName, nil)
+	require.NoError(t, err)
+	assert.Equal(t, m.Files[0].Path, got.Files[0].Path)
+	assert.Equal(t, m.Files[0].Hash, got.Files[0].Hash)
+	assert.True(t, m.Files[0].ModTime.Equal(got.Files[0].ModTime))
+	assert.NoError(t, Verify(r.Fremote, DefaultName, got))
+
+	// Change a file, delete a file and add a file
+	r.WriteObject("file1", "hello world!", t1)
+	o, err := r.Fremote.NewObject("sub dir/file2")
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFile(o))
+	r.WriteObject("file3", "extra", t1)
+
+	accounting.Stats.ResetCounters()
+	err = Verify(r.Fremote, DefaultName, got)
+	require.Error(t, err)
+	assert.Equal(t, "3 differences found", err.Error())
+	assert.Equal(t, int64(3), accounting.Stats.GetErrors())
+}
+
+func TestSignature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("file1", "hello world", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	m, err := Make(r.Fremote, DefaultName)
+	require.NoError(t, err)
+
+	// Use a checksum of the manifest as a stand in for a signature
+	signCmd := []string{"sh", "-c", "cksum"}
+	verifyCmd := []string{"sh", "-c", "cksum | cmp -s - " + SignaturePlaceholder}
+	require.NoError(t, Write(r.Fremote, DefaultName, m, signCmd))
+
+	_, err = r.Fremote.NewObject(DefaultName + SignatureSuffix)
+	require.NoError(t, err)
+
+	got, err := Read(r.Fremote, DefaultName, verifyCmd)
+	require.NoError(t, err)
+	assert.NoError(t, Verify(r.Fremote, DefaultName, got))
+
+	// Tamper with the manifest
+	m.Created = m.Created.Add(time.Hour)
+	require.NoError(t, Write(r.Fremote, DefaultName, m, nil))
+	_, err = Read(r.Fremote, DefaultName, verifyCmd)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "manifest signature is not valid")
+}
+
+func TestSplitCommand(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{in: "", want: nil},
+		{in: "  gpg  --verify {signature} -  ", want: []string{"gpg", "--verify", "{signature}", "-"}},
+		{in: `gpg --local-user "Archive Key" -a`, want: []string{"gpg", "--local-user", "Archive Key", "-a"}},
+		{in: `sh -c 'cksum | cmp -s - "x"'`, want: []string{"sh", "-c", `cksum | cmp -s - "x"`}},
+		{in: `C:\gpg\gpg.exe ""`, want: []string{`C:\gpg\gpg.exe`, ""}},
+		{in: `gpg "unterminated`, err: true},
+	} {
+		got, err := SplitCommand(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	oldActive := filter.Active
+	defer func() {
+		filter.Active = oldActive
+	}()
+	filter.Active, _ = filter.NewFilter(nil)
+
+	require.NoError(t, Exclude("dir/manifest[1].json"))
+	assert.False(t, filter.Active.Include("dir/manifest[1].json", 0, t1))
+	assert.False(t, filter.Active.Include("dir/manifest[1].json"+SignatureSuffix, 0, t1))
+	assert.True(t, filter.Active.Include("dir/manifest1.json", 0, t1))
+	assert.True(t, filter.Active.Include("manifest[1].json", 0, t1))
+	assert.True(t, filter.Active.Include("other/dir/manifest[1].json", 0, t1))
+}
@@ -25,9 +25,10 @@ type March struct {
 	dir      string
 	callback Marcher
 	// internal state
-	srcListDir listDirFn // function to call to list a directory in the src
-	dstListDir listDirFn // function to call to list a directory in the dst
-	transforms []matchTransformFn
+	srcListDir     listDirFn // function to call to list a directory in the src
+	dstListDir     listDirFn // function to call to list a directory in the dst
+	transforms     []matchTransformFn
+	srcDirNotFound bool // set if the source directory dir wasn't found
 }
 
 // Marcher is called on each match
@@ -113,7 +114,10 @@ type listDirJob struct {
 }
 
 // Run starts the matching process off
-func (m *March) Run() {
+//
+// If dir was set and isn't found in the source it returns
+// fs.ErrorDirNotFound without logging an error.
+func (m *March) Run() error {
 	srcDepth := fs.Config.MaxDepth
 	if srcDepth < 0 {
 		srcDepth = fs.MaxLevel
@@ -179,6 +183,10 @@ func (m *March) Run() {
 	traversing.Wait()
 	close(in)
 	wg.Wait()
+	if m.srcDirNotFound {
+		return fs.ErrorDirNotFound
+	}
+	return nil
 }
 
 // Check to see if the context has been cancelled
@@ -358,6 +366,11 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 
 	// Wait for listings to complete and report errors
 	wg.Wait()
+	if srcListErr == fs.ErrorDirNotFound && m.dir != "" && job.srcRemote == m.dir {
+		// Only the first job lists dir so this isn't racy
+		m.srcDirNotFound = true
+		return nil
+	}
 	if srcListErr != nil {
 		fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
 		fs.CountError(srcListErr)
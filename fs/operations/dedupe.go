@@ -12,6 +12,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -29,7 +30,8 @@ func dedupeRename(remote string, objs []fs.Object) {
 	for i, o := range objs {
 		newName := fmt.Sprintf("%s-%d%s", base, i+1, ext)
 		if !fs.Config.DryRun {
-			newObj, err := doMove(o, newName)
+			newObj, err := doMove(list.Uncached(o), newName)
+			list.Changed(f, remote)
 			if err != nil {
 				fs.CountError(err)
 				fs.Errorf(o, "Failed to rename: %v", err)
@@ -307,3 +309,102 @@ func Deduplicate(f fs.Fs, mode DeduplicateMode) error {
 	}
 	return nil
 }
+
+// dedupeInteractiveByHash interactively dedupes objs which all have
+// the same contents
+func dedupeInteractiveByHash(ht hash.Type, sum string, objs []fs.Object) {
+	fmt.Printf("%v %s: %d files with the same contents\n", ht, sum, len(objs))
+	for i, o := range objs {
+		fmt.Printf("  %d: %12d bytes, %s, %s\n", i+1, o.Size(), o.ModTime().Local().Format("2006-01-02 15:04:05.000000000"), o.Remote())
+	}
+	switch config.Command([]string{"sSkip and do nothing", "kKeep just one (choose which in next step)"}) {
+	case 's':
+	case 'k':
+		keep := config.ChooseNumber("Enter the number of the file to keep", 1, len(objs))
+		dedupeDeleteAllButOne(keep-1, sum, objs)
+	}
+}
+
+// DeduplicateByHash finds files with the same contents anywhere in f,
+// whatever their names, and resolves them according to mode.
+//
+// Files are compared by size then by hash so only files whose size
+// matches another file are hashed.
+func DeduplicateByHash(f fs.Fs, mode DeduplicateMode) error {
+	ht := f.Hashes().GetOne()
+	if ht == hash.None {
+		return errors.Errorf("%v has no hashes so can't find duplicates by hash", f)
+	}
+	if mode == DeduplicateRename {
+		return errors.New("can't use rename mode when finding duplicates by hash")
+	}
+	fs.Infof(f, "Looking for duplicates by %v using %v mode.", ht, mode)
+
+	// Find files with the same size
+	bySize := map[int64][]fs.Object{}
+	err := walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		entries.ForObject(func(o fs.Object) {
+			bySize[o.Size()] = append(bySize[o.Size()], o)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Of those find the ones with the same hash
+	byHash := map[string][]fs.Object{}
+	for _, objs := range bySize {
+		if len(objs) < 2 {
+			continue
+		}
+		for _, o := range objs {
+			sum, err := o.Hash(ht)
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(o, "Failed to read %v: %v", ht, err)
+				continue
+			}
+			if sum == "" {
+				continue
+			}
+			byHash[sum] = append(byHash[sum], o)
+		}
+	}
+	var sums []string
+	for sum, objs := range byHash {
+		if len(objs) > 1 {
+			sums = append(sums, sum)
+		}
+	}
+	sort.Strings(sums)
+
+	for _, sum := range sums {
+		objs := byHash[sum]
+		sort.Slice(objs, func(i, j int) bool {
+			return objs[i].Remote() < objs[j].Remote()
+		})
+		fs.Logf(sum, "Found %d files with the same contents", len(objs))
+		switch mode {
+		case DeduplicateInteractive:
+			dedupeInteractiveByHash(ht, sum, objs)
+		case DeduplicateFirst, DeduplicateLargest:
+			// the files are all the same size so keep the first
+			dedupeDeleteAllButOne(0, sum, objs)
+		case DeduplicateNewest:
+			sort.Stable(objectsSortedByModTime(objs)) // sort oldest first
+			dedupeDeleteAllButOne(len(objs)-1, sum, objs)
+		case DeduplicateOldest:
+			sort.Stable(objectsSortedByModTime(objs)) // sort oldest first
+			dedupeDeleteAllButOne(0, sum, objs)
+		case DeduplicateSkip:
+			for _, o := range objs {
+				fs.Logf(o, "Duplicate of %v %s", ht, sum)
+			}
+		}
+	}
+	return nil
+}
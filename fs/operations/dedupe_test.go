@@ -182,6 +182,45 @@ func TestDeduplicateRename(t *testing.T) {
 
 // This should really be a unit test, but the test framework there
 // doesn't have enough tools to make it easy
+func TestDeduplicateByHash(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	skipIfNoHash(t, r.Fremote)
+
+	file1 := r.WriteObject("b", "This is one", t2)
+	file2 := r.WriteObject("a/one", "This is one", t1)
+	file3 := r.WriteObject("c", "This is one", t3)
+	file4 := r.WriteObject("d", "This is two", t1)
+	file5 := r.WriteObject("e", "This is three", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4, file5)
+
+	err := operations.DeduplicateByHash(r.Fremote, operations.DeduplicateSkip)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4, file5)
+
+	err = operations.DeduplicateByHash(r.Fremote, operations.DeduplicateRename)
+	require.Error(t, err)
+
+	err = operations.DeduplicateByHash(r.Fremote, operations.DeduplicateNewest)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file3, file4, file5)
+}
+
+func TestDeduplicateByHashFirst(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	skipIfNoHash(t, r.Fremote)
+
+	file1 := r.WriteObject("b", "This is one", t2)
+	file2 := r.WriteObject("a/one", "This is one", t1)
+	file3 := r.WriteObject("c", "This is one", t3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	err := operations.DeduplicateByHash(r.Fremote, operations.DeduplicateFirst)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file2)
+}
+
 func TestMergeDirs(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -0,0 +1,256 @@
+// Compare two remotes without changing anything
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/limiter"
+	"github.com/ncw/rclone/fs/march"
+	"github.com/pkg/errors"
+)
+
+// Kinds of difference found by Diff
+const (
+	DiffOnlyA = '<' // only in A
+	DiffOnlyB = '>' // only in B
+	DiffBoth  = '*' // in both but different
+)
+
+// DiffEntry is a difference found by Diff
+type DiffEntry struct {
+	Kind   byte   // DiffOnlyA, DiffOnlyB or DiffBoth
+	Path   string // path relative to the roots of A and B
+	Reason string // why it is different
+}
+
+// String turns a DiffEntry into a line of the report
+func (d DiffEntry) String() string {
+	return fmt.Sprintf("%c %s (%s)", d.Kind, d.Path, d.Reason)
+}
+
+// diffMarch is used to march over A and B collecting the differences
+type diffMarch struct {
+	fa, fb  fs.Fs
+	base    map[string]*ListJSONItem
+	mu      sync.Mutex
+	entries []DiffEntry
+}
+
+// add records a difference
+func (d *diffMarch) add(kind byte, remote string, reason string) {
+	d.mu.Lock()
+	d.entries = append(d.entries, DiffEntry{Kind: kind, Path: remote, Reason: reason})
+	d.mu.Unlock()
+}
+
+// only records an object in one of A or B saying whether it was added
+// there or deleted from the other if there is a base
+func (d *diffMarch) only(kind byte, remote string) {
+	in, other := "A", "B"
+	if kind == DiffOnlyB {
+		in, other = other, in
+	}
+	reason := "only in " + in
+	if d.base != nil {
+		if _, ok := d.base[remote]; ok {
+			reason += ", deleted in " + other
+		} else {
+			reason += ", added in " + in
+		}
+	}
+	d.add(kind, remote, reason)
+}
+
+// SrcOnly is called for an entry which is only in A
+func (d *diffMarch) SrcOnly(a fs.DirEntry) (recurse bool) {
+	switch a.(type) {
+	case fs.Object:
+		d.only(DiffOnlyA, a.Remote())
+	case fs.Directory:
+		return true
+	}
+	return false
+}
+
+// DstOnly is called for an entry which is only in B
+func (d *diffMarch) DstOnly(b fs.DirEntry) (recurse bool) {
+	switch b.(type) {
+	case fs.Object:
+		d.only(DiffOnlyB, b.Remote())
+	case fs.Directory:
+		return true
+	}
+	return false
+}
+
+// Match is called for an entry which is in A and B
+func (d *diffMarch) Match(b, a fs.DirEntry) (recurse bool) {
+	aObj, aIsObj := a.(fs.Object)
+	bObj, bIsObj := b.(fs.Object)
+	switch {
+	case !aIsObj && !bIsObj:
+		return true
+	case aIsObj && !bIsObj:
+		d.add(DiffBoth, a.Remote(), "file in A, directory in B")
+	case !aIsObj && bIsObj:
+		d.add(DiffBoth, a.Remote(), "directory in A, file in B")
+	default:
+		reason := d.differ(aObj, bObj)
+		if reason == "" {
+			fs.Debugf(aObj, "OK")
+			return false
+		}
+		if d.base != nil {
+			item, ok := d.base[a.Remote()]
+			switch {
+			case !ok:
+				reason += ", added in both"
+			case baseDiffers(aObj, item) == "":
+				reason += ", changed in B"
+			case baseDiffers(bObj, item) == "":
+				reason += ", changed in A"
+			default:
+				reason += ", changed in both"
+			}
+		}
+		d.add(DiffBoth, a.Remote(), reason)
+	}
+	return false
+}
+
+// differ compares a and b in the same way as sync returning why they
+// are different or "" if they are the same.  Unlike sync it never
+// changes a or b.
+func (d *diffMarch) differ(a, b fs.Object) string {
+	defer limiter.Check(a.Fs(), b.Fs())()
+	accounting.Stats.Checking(a.Remote())
+	defer accounting.Stats.DoneChecking(a.Remote())
+	if sizeDiffers(a, b) {
+		return fmt.Sprintf("size %d vs %d", a.Size(), b.Size())
+	}
+	if fs.Config.SizeOnly {
+		return ""
+	}
+	if !fs.Config.CheckSum {
+		modifyWindow := fs.GetModifyWindow(d.fa, d.fb)
+		if modifyWindow == fs.ModTimeNotSupported {
+			return ""
+		}
+		dt := b.ModTime().Sub(a.ModTime())
+		if dt < modifyWindow && dt > -modifyWindow {
+			return ""
+		}
+		// modification times differ so see if the contents do
+		same, ht, _ := CheckHashes(a, b)
+		if ht == hash.None {
+			return fmt.Sprintf("modification time differs by %v", dt)
+		}
+		if !same {
+			return fmt.Sprintf("%v differs", ht)
+		}
+		return ""
+	}
+	same, ht, err := CheckHashes(a, b)
+	if err != nil {
+		return fmt.Sprintf("failed to read hash: %v", err)
+	}
+	if !same {
+		return fmt.Sprintf("%v differs", ht)
+	}
+	return ""
+}
+
+// baseDiffers compares o with the item from the base listing returning
+// why they are different or "" if they are the same
+func baseDiffers(o fs.Object, item *ListJSONItem) string {
+	if item.IsDir {
+		return "directory in base"
+	}
+	if o.Size() >= 0 && item.Size >= 0 && o.Size() != item.Size {
+		return fmt.Sprintf("size %d vs %d in base", o.Size(), item.Size)
+	}
+	if fs.Config.SizeOnly {
+		return ""
+	}
+	if fs.Config.CheckSum {
+		for _, ht := range o.Fs().Hashes().Array() {
+			baseSum := item.Hashes[ht.String()]
+			if baseSum == "" {
+				continue
+			}
+			sum, err := o.Hash(ht)
+			if err != nil || sum == "" {
+				continue
+			}
+			if sum != baseSum {
+				return fmt.Sprintf("%v differs from base", ht)
+			}
+			return ""
+		}
+		return ""
+	}
+	baseModTime := time.Time(item.ModTime)
+	if baseModTime.IsZero() {
+		return ""
+	}
+	modifyWindow := o.Fs().Precision()
+	if modifyWindow < fs.Config.ModifyWindow {
+		modifyWindow = fs.Config.ModifyWindow
+	}
+	if modifyWindow == fs.ModTimeNotSupported {
+		return ""
+	}
+	dt := o.ModTime().Sub(baseModTime)
+	if dt < modifyWindow && dt > -modifyWindow {
+		return ""
+	}
+	return fmt.Sprintf("modification time differs from base by %v", dt)
+}
+
+// Diff compares the files in fa and fb writing a report of the
+// differences to out, one per line, sorted by path.
+//
+// Files are compared in the same way as sync does, but nothing is
+// changed.
+//
+// If base isn't nil it should be the lsjson listing of an earlier
+// state of fa and fb.  It is used to say which side each difference
+// was made on.
+//
+// It returns an error if any differences were found.
+func Diff(fa, fb fs.Fs, base []*ListJSONItem, out io.Writer) error {
+	d := &diffMarch{
+		fa: fa,
+		fb: fb,
+	}
+	if base != nil {
+		d.base = make(map[string]*ListJSONItem, len(base))
+		for _, item := range base {
+			d.base[item.Path] = item
+		}
+	}
+	m := march.New(context.Background(), fb, fa, "", d)
+	_ = m.Run() // only returns an error if dir is set
+	sort.Slice(d.entries, func(i, j int) bool {
+		return d.entries[i].Path < d.entries[j].Path
+	})
+	for _, entry := range d.entries {
+		_, err := fmt.Fprintln(out, entry)
+		if err != nil {
+			return errors.Wrap(err, "failed to write diff")
+		}
+	}
+	if len(d.entries) > 0 {
+		return errors.Errorf("%d differences found", len(d.entries))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package operations_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// A is local and B is remote
+	r.WriteBoth("same", "same", t1)
+	r.WriteFile("onlyA", "a", t1)
+	r.WriteObject("dir/onlyB", "b", t1)
+	r.WriteFile("size", "short", t1)
+	r.WriteObject("size", "longer", t1)
+	r.WriteFile("changedA", "aaa", t2)
+	r.WriteObject("changedA", "aaaa", t1)
+	r.WriteFile("deletedB", "d", t1)
+
+	var out bytes.Buffer
+	err := operations.Diff(r.Flocal, r.Fremote, nil, &out)
+	require.Error(t, err)
+	assert.Equal(t, "5 differences found", err.Error())
+	assert.Equal(t, strings.Join([]string{
+		"* changedA (size 3 vs 4)",
+		"< deletedB (only in A)",
+		"> dir/onlyB (only in B)",
+		"< onlyA (only in A)",
+		"* size (size 5 vs 6)",
+		"",
+	}, "\n"), out.String())
+
+	// With a base saying which side changed
+	base := []*operations.ListJSONItem{
+		{Path: "same", Size: 4, ModTime: operations.Timestamp(t1)},
+		{Path: "size", Size: 5, ModTime: operations.Timestamp(t1)},
+		{Path: "changedA", Size: 4, ModTime: operations.Timestamp(t1)},
+		{Path: "deletedB", Size: 1, ModTime: operations.Timestamp(t1)},
+	}
+	out.Reset()
+	err = operations.Diff(r.Flocal, r.Fremote, base, &out)
+	require.Error(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"* changedA (size 3 vs 4, changed in A)",
+		"< deletedB (only in A, deleted in B)",
+		"> dir/onlyB (only in B, added in B)",
+		"< onlyA (only in A, added in A)",
+		"* size (size 5 vs 6, changed in B)",
+		"",
+	}, "\n"), out.String())
+
+	// Nothing was changed by Diff
+	fstest.CheckItems(t, r.Fremote,
+		fstest.NewItem("same", "same", t1),
+		fstest.NewItem("dir/onlyB", "b", t1),
+		fstest.NewItem("size", "longer", t1),
+		fstest.NewItem("changedA", "aaaa", t1),
+	)
+
+	// No differences
+	out.Reset()
+	err = operations.Diff(r.Fremote, r.Fremote, nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "", out.String())
+}
+
+func TestReadListJSON(t *testing.T) {
+	in := `[
+{"Path":"file.txt","Name":"file.txt","Size":6,"MimeType":"text/plain","ModTime":"2001-02-03T04:05:06.499999999Z","IsDir":false,"Hashes":{"MD5":"b1946ac92492d2347c6235b4d2611184"}},
+{"Path":"dir","Name":"dir","Size":-1,"ModTime":"","IsDir":true}
+]`
+	items, err := operations.ReadListJSON(strings.NewReader(in))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(items))
+	assert.Equal(t, "file.txt", items[0].Path)
+	assert.Equal(t, int64(6), items[0].Size)
+	assert.True(t, t1.Equal(time.Time(items[0].ModTime)))
+	assert.Equal(t, "b1946ac92492d2347c6235b4d2611184", items[0].Hashes["MD5"])
+	assert.True(t, items[1].IsDir)
+	assert.True(t, time.Time(items[1].ModTime).IsZero())
+
+	_, err = operations.ReadListJSON(strings.NewReader("potato"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+// Check the objects in a remote against a sum file
+
+package operations
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// parseSumLine parses a line of a sum file in the format written by
+// md5sum, "<sum>  <path>", or "<sum> *<path>" for binary mode.
+func parseSumLine(line string) (sum, remote string, ok bool) {
+	i := strings.IndexByte(line, ' ')
+	if i <= 0 || i+1 >= len(line) {
+		return "", "", false
+	}
+	sum, remote = line[:i], line[i+1:]
+	if remote[0] != ' ' && remote[0] != '*' {
+		return "", "", false
+	}
+	remote = remote[1:]
+	if remote == "" {
+		return "", "", false
+	}
+	return sum, remote, true
+}
+
+// sumsEqual returns true if the sum read from the file, in hex or
+// base64 if isBase64 is set, is the same as the hex sum from the
+// remote
+func sumsEqual(fileSum, sum string, isBase64 bool) bool {
+	if !isBase64 {
+		return strings.EqualFold(fileSum, sum)
+	}
+	fileSumBytes, err := base64.URLEncoding.DecodeString(fileSum)
+	if err != nil {
+		return false
+	}
+	return hex.EncodeToString(fileSumBytes) == strings.ToLower(sum)
+}
+
+// HashCheck checks the objects in f against the sums of type ht read
+// from sums, which should be in the format produced by HashLister,
+// in base64 if isBase64 is set.
+//
+// It writes a line for each object to w in the same format as
+// "md5sum -c" does, saying whether it is OK, FAILED or MISSING, and
+// returns an error if any weren't OK.
+func HashCheck(ht hash.Type, f fs.Fs, sums io.Reader, isBase64 bool, w io.Writer) error {
+	var checked, failed, missing, unreadable, badLines int
+	scanner := bufio.NewScanner(sums)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fileSum, remote, ok := parseSumLine(line)
+		if !ok {
+			badLines++
+			fs.Errorf(nil, "Improperly formatted line in sum file: %q", line)
+			continue
+		}
+		checked++
+		o, err := f.NewObject(remote)
+		if err == fs.ErrorObjectNotFound {
+			missing++
+			syncFprintf(w, "%s: MISSING\n", remote)
+			continue
+		} else if err != nil {
+			unreadable++
+			fs.Errorf(remote, "Failed to find object: %v", err)
+			syncFprintf(w, "%s: FAILED open or read\n", remote)
+			continue
+		}
+		sum := hashSum(ht, o)
+		switch {
+		case sum == "UNSUPPORTED" || sum == "ERROR":
+			unreadable++
+			syncFprintf(w, "%s: FAILED open or read\n", remote)
+		case sumsEqual(fileSum, sum, isBase64):
+			syncFprintf(w, "%s: OK\n", remote)
+		default:
+			failed++
+			syncFprintf(w, "%s: FAILED\n", remote)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read sum file")
+	}
+	var problems []string
+	if badLines > 0 {
+		problems = append(problems, fmt.Sprintf("%d lines are improperly formatted", badLines))
+	}
+	if missing > 0 {
+		problems = append(problems, fmt.Sprintf("%d listed files are missing", missing))
+	}
+	if unreadable > 0 {
+		problems = append(problems, fmt.Sprintf("%d listed files could not be read", unreadable))
+	}
+	if failed > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d computed checksums did NOT match", failed, checked-missing-unreadable))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	if checked == 0 {
+		return errors.New("no properly formatted checksum lines found")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package operations_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashListerBase64(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !r.Fremote.Hashes().Contains(hash.MD5) {
+		t.Skip("Can't run this test without MD5 support")
+	}
+	file1 := r.WriteObject("potato2", "------------------------------------------------------------", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	var buf bytes.Buffer
+	err := operations.HashListerBase64(hash.MD5, true, r.Fremote, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "1lSLFW6mik4APnht-Z7udg==  potato2\n", buf.String())
+}
+
+func TestHashCheck(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !r.Fremote.Hashes().Contains(hash.MD5) {
+		t.Skip("Can't run this test without MD5 support")
+	}
+	file1 := r.WriteObject("potato2", "------------------------------------------------------------", t1)
+	file2 := r.WriteObject("empty space", "", t2)
+	file3 := r.WriteObject("changed", "changed", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	check := func(sums string, isBase64 bool) (string, error) {
+		var buf bytes.Buffer
+		err := operations.HashCheck(hash.MD5, r.Fremote, strings.NewReader(sums), isBase64, &buf)
+		return buf.String(), err
+	}
+
+	out, err := check("d6548b156ea68a4e003e786df99eee76  potato2\nD41D8CD98F00B204E9800998ECF8427E *empty space\n", false)
+	require.NoError(t, err)
+	assert.Equal(t, "potato2: OK\nempty space: OK\n", out)
+
+	out, err = check("1lSLFW6mik4APnht-Z7udg==  potato2\n", true)
+	require.NoError(t, err)
+	assert.Equal(t, "potato2: OK\n", out)
+
+	out, err = check(strings.Join([]string{
+		"d6548b156ea68a4e003e786df99eee76  potato2",
+		"d41d8cd98f00b204e9800998ecf8427e  changed",
+		"d41d8cd98f00b204e9800998ecf8427e  missing",
+		"potato",
+		"",
+	}, "\n"), false)
+	require.Error(t, err)
+	assert.Equal(t, "1 lines are improperly formatted, 1 listed files are missing, 1 of 2 computed checksums did NOT match", err.Error())
+	assert.Equal(t, "potato2: OK\nchanged: FAILED\nmissing: MISSING\n", out)
+
+	_, err = check("", false)
+	assert.Error(t, err)
+}
@@ -1,11 +1,14 @@
 package operations_test
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/cost"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -102,3 +105,108 @@ func TestListDirSorted(t *testing.T) {
 	assert.Equal(t, "sub dir/ignore dir/.ignore", str(0))
 	assert.Equal(t, "sub dir/ignore dir/should be ignored", str(1))
 }
+
+// TestListDirSortedCache tests the listings cache in fs/list/cache.go
+func TestListDirSortedCache(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.UseListingsCache = true
+	defer func() {
+		list.ChangedTree(r.Fremote, "")
+		fs.Config.UseListingsCache = false
+	}()
+	list.ChangedTree(r.Fremote, "")
+
+	file1 := r.WriteObject("sub dir/file1", "hello world", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	items, err := list.DirSorted(r.Fremote, true, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Changes made behind rclone's back aren't seen
+	r.WriteObject("sub dir/file2", "potato", t2)
+	items, err = list.DirSorted(r.Fremote, true, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	o, ok := items[0].(fs.Object)
+	require.True(t, ok)
+	assert.Equal(t, "sub dir/file1", o.Remote())
+	assert.Equal(t, int64(11), o.Size())
+	_, ok = fstest.CheckTimeEqualWithPrecision(t1, o.ModTime(), fs.GetModifyWindow(r.Fremote))
+	assert.True(t, ok)
+
+	// The cached object can still be read
+	in, err := o.Open()
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "hello world", string(data))
+
+	// Changes made by rclone invalidate the listing
+	require.NoError(t, operations.DeleteFile(o))
+	items, err = list.DirSorted(r.Fremote, true, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "sub dir/file2", items[0].Remote())
+
+	// Expired listings aren't used
+	r.WriteObject("sub dir/file3", "three", t1)
+	oldAge := fs.Config.ListingsCacheAge
+	fs.Config.ListingsCacheAge = 0
+	defer func() {
+		fs.Config.ListingsCacheAge = oldAge
+	}()
+	items, err = list.DirSorted(r.Fremote, true, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	fs.Config.ListingsCacheAge = oldAge
+
+	// Changes aren't tracked without the flag
+	r.WriteObject("sub dir/file4", "four", t1)
+	fs.Config.UseListingsCache = false
+	list.Changed(r.Fremote, "sub dir/file4")
+	fs.Config.UseListingsCache = true
+	items, err = list.DirSorted(r.Fremote, true, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	// All the cached objects in a directory are found with one listing
+	o2, ok := items[0].(fs.Object)
+	require.True(t, ok)
+	o3, ok := items[1].(fs.Object)
+	require.True(t, ok)
+	assert.Equal(t, fs.MimeTypeFromName(o2.Remote()), fs.MimeType(o2))
+	for _, o := range []fs.Object{o2, o3} {
+		obj := list.Uncached(o)
+		assert.NotEqual(t, o, obj)
+		assert.Equal(t, o.Remote(), obj.Remote())
+	}
+}
+
+// TestListDirSortedCacheCost checks a cached listing isn't counted
+// by --estimate-cost
+func TestListDirSortedCacheCost(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.UseListingsCache = true
+	fs.Config.EstimateCost = true
+	defer func() {
+		list.ChangedTree(r.Fremote, "")
+		fs.Config.UseListingsCache = false
+		fs.Config.EstimateCost = false
+		cost.Reset()
+	}()
+	list.ChangedTree(r.Fremote, "")
+	r.WriteObject("sub dir/file1", "hello world", t1)
+
+	cost.Reset()
+	for i := 0; i < 2; i++ {
+		_, err := list.DirSorted(r.Fremote, true, "sub dir")
+		require.NoError(t, err)
+	}
+	_, lines := cost.Estimate()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], ": 1 list ")
+}
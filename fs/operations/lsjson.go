@@ -0,0 +1,199 @@
+// JSON listing of objects and directories
+
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// ListJSONItem in the struct which gets marshalled for each line
+type ListJSONItem struct {
+	Path      string
+	Name      string
+	Encrypted string `json:",omitempty"`
+	Size      int64
+	MimeType  string    `json:",omitempty"`
+	ModTime   Timestamp //`json:",omitempty"`
+	IsDir     bool
+	Hashes    map[string]string `json:",omitempty"`
+	ID        string            `json:",omitempty"`
+	OrigID    string            `json:",omitempty"`
+}
+
+// Timestamp a time in RFC3339 format with Nanosecond precision secongs
+type Timestamp time.Time
+
+// MarshalJSON turns a Timestamp into JSON
+func (t Timestamp) MarshalJSON() (out []byte, err error) {
+	tt := time.Time(t)
+	if tt.IsZero() {
+		return []byte(`""`), nil
+	}
+	return []byte(`"` + tt.Format(time.RFC3339Nano) + `"`), nil
+}
+
+// UnmarshalJSON turns JSON into a Timestamp
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		*t = Timestamp{}
+		return nil
+	}
+	tt, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(tt)
+	return nil
+}
+
+// ReadListJSON reads the output of lsjson from in returning the items
+// in it
+func ReadListJSON(in io.Reader) (items []*ListJSONItem, err error) {
+	err = json.NewDecoder(in).Decode(&items)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read JSON listing")
+	}
+	return items, nil
+}
+
+// gzipMagic is what gzip files start with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadListJSONFile reads the output of lsjson from the file name,
+// which may be compressed with gzip, eg a snapshot made with lsjson
+// --snapshot.
+func ReadListJSONFile(name string) (items []*ListJSONItem, err error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(fd, &err)
+	in := bufio.NewReader(fd)
+	var r io.Reader = in
+	magic, _ := in.Peek(len(gzipMagic))
+	if bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read compressed JSON listing")
+		}
+		defer fs.CheckClose(gz, &err)
+		r = gz
+	}
+	return ReadListJSON(r)
+}
+
+// ListJSONOpt describes the options for NewListJSONItem and ListJSON
+type ListJSONOpt struct {
+	NoModTime   bool `json:"noModTime"`   // don't read the modification time
+	ShowHash    bool `json:"showHash"`    // read the hashes of objects
+	ShowOrigIDs bool `json:"showOrigIDs"` // show the ID of the underlying Object
+	Recurse     bool `json:"recurse"`     // ListJSON recurses into directories
+	DirsOnly    bool `json:"dirsOnly"`    // ListJSON only lists directories
+	FilesOnly   bool `json:"filesOnly"`   // ListJSON only lists files
+}
+
+// ListJSON lists remote in fsrc calling callback with an item made
+// with opt for each entry.  If callback returns an error the listing
+// stops and returns it.
+//
+// Errors listing directories are logged and counted but don't stop
+// the listing, in the same way as lsjson.
+func ListJSON(fsrc fs.Fs, remote string, opt *ListJSONOpt, callback func(*ListJSONItem) error) error {
+	err := walk.Walk(fsrc, remote, false, ConfigMaxDepth(opt.Recurse), func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(dirPath, "error listing: %v", err)
+			return nil
+		}
+		for _, entry := range entries {
+			_, isDir := entry.(fs.Directory)
+			if (isDir && opt.FilesOnly) || (!isDir && opt.DirsOnly) {
+				continue
+			}
+			err = callback(NewListJSONItem(entry, opt))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error listing JSON")
+	}
+	return nil
+}
+
+// origID returns the ID of the object underlying entry if known
+func origID(entry fs.DirEntry) string {
+	cur := entry
+	for {
+		u, ok := cur.(fs.ObjectUnWrapper)
+		if !ok {
+			break // not a wrapped object, use current id
+		}
+		next := u.UnWrap()
+		if next == nil {
+			break // no base object found, use current id
+		}
+		cur = next
+	}
+	if do, ok := cur.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// NewListJSONItem makes a ListJSONItem from the entry passed in
+func NewListJSONItem(entry fs.DirEntry, opt *ListJSONOpt) *ListJSONItem {
+	item := &ListJSONItem{
+		Path:     entry.Remote(),
+		Name:     path.Base(entry.Remote()),
+		Size:     entry.Size(),
+		MimeType: fs.MimeTypeDirEntry(entry),
+	}
+	if !opt.NoModTime {
+		item.ModTime = Timestamp(entry.ModTime())
+	}
+	if do, ok := entry.(fs.IDer); ok {
+		item.ID = do.ID()
+	}
+	if opt.ShowOrigIDs {
+		item.OrigID = origID(entry)
+	}
+	switch x := entry.(type) {
+	case fs.Directory:
+		item.IsDir = true
+	case fs.Object:
+		item.IsDir = false
+		if opt.ShowHash {
+			item.Hashes = make(map[string]string)
+			for _, hashType := range x.Fs().Hashes().Array() {
+				hash, err := x.Hash(hashType)
+				if err != nil {
+					fs.Errorf(x, "Failed to read hash: %v", err)
+				} else if hash != "" {
+					item.Hashes[hashType.String()] = hash
+				}
+			}
+		}
+	default:
+		fs.Errorf(nil, "Unknown type %T in listing", entry)
+	}
+	return item
+}
@@ -0,0 +1,260 @@
+// Copy one source object to several destinations reading it once
+
+package operations
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/cost"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/list"
+	"github.com/pkg/errors"
+)
+
+// multiCopyBufferSize is the size of the chunks the source is read in
+const multiCopyBufferSize = 128 * 1024
+
+// multiCopyDst is one of the destinations of CopyMulti
+type multiCopyDst struct {
+	f      fs.Fs
+	dst    fs.Object // existing object to replace or nil
+	out    *io.PipeWriter
+	failed bool // set if writing to out failed
+	newDst fs.Object
+	err    error
+}
+
+// upload reads in and uploads it to d
+func (d *multiCopyDst) upload(in *io.PipeReader, src fs.ObjectInfo, wg *sync.WaitGroup) {
+	defer wg.Done()
+	if d.dst != nil {
+		d.err = d.dst.Update(in, src)
+		d.newDst = d.dst
+	} else {
+		d.newDst, d.err = d.f.Put(in, src)
+	}
+	// stop the writer blocking if the upload finished early
+	if d.err != nil {
+		_ = in.CloseWithError(d.err)
+	} else {
+		_ = in.Close()
+	}
+}
+
+// CopyMulti copies src to remote on each of fdsts, reading src only
+// once.  dsts should contain the existing object on each of fdsts or
+// nil if there isn't one.
+//
+// Each destination is uploaded to at the same time so the transfer
+// goes as fast as the slowest.  If an upload fails with an error
+// which can be retried then that destination is copied again on its
+// own with Copy.
+//
+// It returns the last error if any of the copies failed.
+func CopyMulti(fdsts []fs.Fs, dsts []fs.Object, remote string, src fs.Object) (err error) {
+	if len(fdsts) != len(dsts) {
+		return errors.New("CopyMulti: need an object or nil for each destination")
+	}
+	if len(fdsts) == 1 {
+		_, err = Copy(fdsts[0], dsts[0], remote, src)
+		return err
+	}
+	if fs.Config.DryRun {
+		for i, f := range fdsts {
+			recordCopyCost(f, src)
+			recordTransferPlan(PlanCopy, f, dsts[i], remote, src)
+			fs.Logf(src, "Not copying to %v as --dry-run", f)
+		}
+		return nil
+	}
+	err = accounting.CheckMaxTransfer(src.Size())
+	if err == nil {
+		err = accounting.CheckMaxDuration()
+	}
+	if err != nil {
+		fs.Errorf(src, "Not copying: %v", err)
+		return err
+	}
+
+	// Calculate a hash each destination supports while reading so
+	// the uploads can be checked without reading the source again
+	var hashes hash.Set
+	if !fs.Config.SizeOnly {
+		for _, f := range fdsts {
+			if ht := f.Hashes().GetOne(); ht != hash.None {
+				hashes.Add(ht)
+			}
+		}
+	}
+	hasher, err := hash.NewMultiHasherTypes(hashes)
+	if err != nil {
+		return err
+	}
+
+	in0, err := src.Open()
+	if err != nil {
+		err = errors.Wrap(err, "failed to open source object")
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to copy: %v", err)
+		return err
+	}
+	in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
+	cost.Record(src.Fs(), cost.Get, 1, src.Size())
+	var wrappedSrc fs.ObjectInfo = src
+	if src.Remote() != remote {
+		wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
+	}
+
+	// Start the uploads
+	var wg sync.WaitGroup
+	ds := make([]*multiCopyDst, len(fdsts))
+	for i, f := range fdsts {
+		pipeReader, pipeWriter := io.Pipe()
+		ds[i] = &multiCopyDst{f: f, dst: dsts[i], out: pipeWriter}
+		cost.Record(f, cost.Put, 1, 0)
+		wg.Add(1)
+		go ds[i].upload(pipeReader, wrappedSrc, &wg)
+	}
+
+	// Read the source once writing it to every upload still running
+	buf := make([]byte, multiCopyBufferSize)
+	var readErr error
+	for {
+		var n int
+		n, readErr = in.Read(buf)
+		if n > 0 {
+			_, _ = hasher.Write(buf[:n])
+			for _, d := range ds {
+				if d.failed {
+					continue
+				}
+				if _, writeErr := d.out.Write(buf[:n]); writeErr != nil {
+					d.failed = true
+				}
+			}
+		}
+		if readErr == io.EOF {
+			readErr = nil
+			break
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	for _, d := range ds {
+		if readErr != nil {
+			_ = d.out.CloseWithError(readErr)
+		} else {
+			_ = d.out.Close()
+		}
+	}
+	wg.Wait()
+	closeErr := in.Close()
+	if readErr == nil {
+		readErr = closeErr
+	}
+	if readErr != nil {
+		err = errors.Wrap(readErr, "failed to read source object")
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to copy: %v", err)
+		return err
+	}
+
+	// Check the uploads
+	sums := hasher.Sums()
+	for _, d := range ds {
+		list.Changed(d.f, remote)
+		if d.err == nil {
+			d.err = checkMultiCopy(d, src, sums)
+		} else if fserrors.IsRetryError(d.err) || fserrors.ShouldRetry(d.err) {
+			fs.Debugf(src, "Received error copying to %v: %v - copying again on its own", d.f, d.err)
+			_, d.err = Copy(d.f, d.dst, remote, src)
+			if d.err != nil {
+				err = d.err
+			}
+			continue
+		}
+		if d.err != nil {
+			fs.CountError(d.err)
+			fs.Errorf(src, "Failed to copy to %v: %v", d.f, d.err)
+			err = d.err
+			continue
+		}
+		if d.dst != nil {
+			fs.Infof(src, "Copied (replaced existing) to %v", d.f)
+		} else {
+			fs.Infof(src, "Copied (new) to %v", d.f)
+		}
+	}
+	return err
+}
+
+// checkMultiCopy checks the upload d of src against the sums
+// calculated while reading it
+func checkMultiCopy(d *multiCopyDst, src fs.Object, sums map[hash.Type]string) error {
+	if d.newDst == nil {
+		return nil
+	}
+	if sizeDiffers(src, d.newDst) {
+		removeFailedCopy(d.newDst)
+		return errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), d.newDst.Size())
+	}
+	if ht := d.f.Hashes().GetOne(); ht != hash.None && sums[ht] != "" && !fs.Config.IgnoreChecksum {
+		dstSum, err := d.newDst.Hash(ht)
+		if err != nil {
+			return errors.Wrap(err, "failed to read hash")
+		}
+		if !hash.Equals(sums[ht], dstSum) {
+			removeFailedCopy(d.newDst)
+			return errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", ht, sums[ht], dstSum)
+		}
+	}
+	if needsSidecar(d.f, src) {
+		err := writeSidecar(d.f, d.newDst.Remote(), src)
+		if err != nil {
+			return errors.Wrap(err, "failed to store hash sidecar")
+		}
+	}
+	return nil
+}
+
+// CopyFileMulti copies srcFileName on fsrc to each of fdsts which
+// needs it, reading the source once
+func CopyFileMulti(fdsts []fs.Fs, fsrc fs.Fs, srcFileName string) error {
+	srcObj, err := fsrc.NewObject(srcFileName)
+	if err != nil {
+		return err
+	}
+	var needFs []fs.Fs
+	var needDsts []fs.Object
+	for _, fdst := range fdsts {
+		dstObj, err := fdst.NewObject(srcFileName)
+		if err == fs.ErrorObjectNotFound {
+			dstObj = nil
+		} else if err != nil {
+			return err
+		}
+		if !NeedTransfer(dstObj, srcObj) {
+			continue
+		}
+		if dstObj != nil && fs.Config.Immutable {
+			fs.Errorf(dstObj, "Source and destination exist but do not match: immutable file modified")
+			return fs.ErrorImmutableModified
+		}
+		needFs = append(needFs, fdst)
+		needDsts = append(needDsts, dstObj)
+	}
+	if len(needFs) == 0 {
+		accounting.Stats.Checking(srcFileName)
+		accounting.Stats.DoneChecking(srcFileName)
+		return nil
+	}
+	accounting.Stats.Transferring(srcFileName)
+	err = CopyMulti(needFs, needDsts, srcFileName, srcObj)
+	accounting.Stats.DoneTransferring(srcFileName, err == nil)
+	return err
+}
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/pkg/errors"
+)
+
+const (
+	multithreadChunkSize     = 64 << 10
+	multithreadChunkSizeMask = multithreadChunkSize - 1
+)
+
+// doMultiThreadCopy returns whether src should be copied to f using
+// multiple streams
+func doMultiThreadCopy(f fs.Fs, src fs.Object) bool {
+	// Disable multi thread if...
+
+	// ...it isn't configured
+	if fs.Config.MultiThreadStreams <= 1 {
+		return false
+	}
+	// ...size of object is less than cutoff
+	if src.Size() < int64(fs.Config.MultiThreadCutoff) {
+		return false
+	}
+	// ...destination doesn't support it
+	if f.Features().OpenWriterAt == nil {
+		return false
+	}
+	// ...source and destination are both local
+	if f.Name() == "local" && src.Fs().Name() == "local" {
+		return false
+	}
+	return true
+}
+
+// state for a multi-thread copy
+type multiThreadCopyState struct {
+	src       fs.Object
+	acc       *accounting.Account
+	size      int64
+	wc        fs.WriterAtCloser
+	streams   int
+	partSize  int64
+	errMu     sync.Mutex
+	err       error
+	cancelled chan struct{}
+}
+
+// offsetWriter writes to an io.WriterAt starting at offset
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+// Write writes p at the current offset
+func (ow *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = ow.w.WriteAt(p, ow.off)
+	ow.off += int64(n)
+	return n, err
+}
+
+// setErr records the first error and stops the other streams
+func (mc *multiThreadCopyState) setErr(err error) {
+	mc.errMu.Lock()
+	defer mc.errMu.Unlock()
+	if mc.err == nil {
+		mc.err = err
+		close(mc.cancelled)
+	}
+}
+
+// copyStream copies stream number "stream" of the file
+func (mc *multiThreadCopyState) copyStream(stream int) (err error) {
+	start := int64(stream) * mc.partSize
+	if start >= mc.size {
+		return nil
+	}
+	end := start + mc.partSize
+	if end > mc.size {
+		end = mc.size
+	}
+
+	fs.Debugf(mc.src, "multi-thread copy: stream %d/%d (%d-%d) size %v starting", stream+1, mc.streams, start, end, fs.SizeSuffix(end-start))
+
+	rc, err := mc.src.Open(&fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		return errors.Wrap(err, "multi-thread copy: failed to open source")
+	}
+	defer fs.CheckClose(rc, &err)
+
+	in := mc.acc.WrapStream(rc)
+	out := &offsetWriter{w: mc.wc, off: start}
+	buf := make([]byte, multithreadChunkSize)
+	for out.off < end {
+		select {
+		case <-mc.cancelled:
+			return nil
+		default:
+		}
+		n, readErr := io.ReadFull(in, buf[:min64(int64(len(buf)), end-out.off)])
+		if n > 0 {
+			_, err = out.Write(buf[:n])
+			if err != nil {
+				return errors.Wrap(err, "multi-thread copy: write failed")
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return errors.Wrap(readErr, "multi-thread copy: read failed")
+		}
+	}
+	if out.off != end {
+		return errors.Errorf("multi-thread copy: stream %d: read %d bytes expecting %d", stream+1, out.off-start, end-start)
+	}
+
+	fs.Debugf(mc.src, "multi-thread copy: stream %d/%d (%d-%d) size %v finished", stream+1, mc.streams, start, end, fs.SizeSuffix(end-start))
+	return nil
+}
+
+// min64 returns the smaller of a and b
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// multiThreadCopy copies src to remote in f using up to streams
+// ranged reads of src written in parallel with OpenWriterAt
+func multiThreadCopy(f fs.Fs, remote string, src fs.Object, streams int) (newDst fs.Object, err error) {
+	openWriterAt := f.Features().OpenWriterAt
+	if openWriterAt == nil {
+		return nil, errors.New("multi-thread copy: OpenWriterAt not supported")
+	}
+	if src.Size() < 0 {
+		return nil, errors.New("multi-thread copy: can't copy unknown sized file")
+	}
+	if src.Size() == 0 {
+		return nil, errors.New("multi-thread copy: can't copy zero sized file")
+	}
+
+	mc := &multiThreadCopyState{
+		src:       src,
+		size:      src.Size(),
+		streams:   streams,
+		cancelled: make(chan struct{}),
+	}
+
+	// Make the parts an exact multiple of the chunk size and work
+	// out how many streams that really needs
+	mc.partSize = (mc.size + int64(streams) - 1) / int64(streams)
+	mc.partSize = (mc.partSize + multithreadChunkSizeMask) &^ multithreadChunkSizeMask
+	mc.streams = int((mc.size + mc.partSize - 1) / mc.partSize)
+
+	mc.wc, err = openWriterAt(remote, mc.size)
+	if err != nil {
+		return nil, errors.Wrap(err, "multi-thread copy: failed to open destination")
+	}
+
+	// Account all the streams together as one transfer
+	mc.acc = accounting.NewAccount(ioutil.NopCloser(strings.NewReader("")), src)
+
+	fs.Debugf(src, "Starting multi-thread copy with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
+	var wg sync.WaitGroup
+	wg.Add(mc.streams)
+	for stream := 0; stream < mc.streams; stream++ {
+		go func(stream int) {
+			defer wg.Done()
+			if err := mc.copyStream(stream); err != nil {
+				mc.setErr(err)
+			}
+		}(stream)
+	}
+	wg.Wait()
+	_ = mc.acc.Close()
+	closeErr := mc.wc.Close()
+	err = mc.err
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		newDst, err = f.NewObject(remote)
+	}
+	if err == nil {
+		err = newDst.SetModTime(src.ModTime())
+		if err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete {
+			err = nil
+		}
+	}
+	if err != nil {
+		fs.Debugf(src, "multi-thread copy: removing partially written file after error: %v", err)
+		if o, newErr := f.NewObject(remote); newErr == nil {
+			_ = o.Remove()
+		}
+		return nil, err
+	}
+
+	fs.Debugf(src, "Finished multi-thread copy with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
+	return newDst, nil
+}
@@ -0,0 +1,65 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+
+func TestDoMultiThreadCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldStreams, oldCutoff := fs.Config.MultiThreadStreams, fs.Config.MultiThreadCutoff
+	defer func() {
+		fs.Config.MultiThreadStreams, fs.Config.MultiThreadCutoff = oldStreams, oldCutoff
+	}()
+	fs.Config.MultiThreadStreams, fs.Config.MultiThreadCutoff = 4, 50
+
+	file1 := r.WriteObject("file1", fstest.RandomString(100), t1)
+	src, err := r.Fremote.NewObject(file1.Path)
+	require.NoError(t, err)
+
+	// local to local is never done multi-thread
+	assert.False(t, doMultiThreadCopy(r.Flocal, src))
+
+	fs.Config.MultiThreadCutoff = 101
+	assert.False(t, doMultiThreadCopy(r.Flocal, src))
+	fs.Config.MultiThreadCutoff = 50
+
+	fs.Config.MultiThreadStreams = 1
+	assert.False(t, doMultiThreadCopy(r.Flocal, src))
+}
+
+func TestMultithreadCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	for _, test := range []struct {
+		size    int
+		streams int
+	}{
+		{size: multithreadChunkSize*2 - 1, streams: 2},
+		{size: multithreadChunkSize * 2, streams: 2},
+		{size: multithreadChunkSize*2 + 1, streams: 2},
+		{size: multithreadChunkSize*5 + 17, streams: 3},
+	} {
+		contents := fstest.RandomString(test.size)
+		file1 := r.WriteObject("file1", contents, t1)
+		src, err := r.Fremote.NewObject(file1.Path)
+		require.NoError(t, err)
+
+		dst, err := multiThreadCopy(r.Flocal, "file1", src, test.streams)
+		require.NoError(t, err)
+		assert.Equal(t, src.Size(), dst.Size())
+		fstest.CheckItems(t, r.Flocal, file1)
+
+		require.NoError(t, dst.Remove())
+		require.NoError(t, src.Remove())
+	}
+}
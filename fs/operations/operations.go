@@ -4,11 +4,14 @@ package operations
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"path"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,8 +21,11 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/cost"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/limiter"
+	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/march"
 	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/walk"
@@ -40,11 +46,24 @@ import (
 // err - may return an error which will already have been logged
 //
 // If an error is returned it will return equal as false
+//
+// If there is no hash in common and --hash-sidecar is set then the
+// hashes stored in the sidecar of dst are used instead.
 func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, err error) {
 	common := src.Fs().Hashes().Overlap(dst.Fs().Hashes())
 	// fs.Debugf(nil, "Shared hashes: %v", common)
 	if common.Count() == 0 {
-		return true, hash.None, nil
+		if !fs.Config.HashSidecar {
+			return true, hash.None, nil
+		}
+		var srcHash, dstHash string
+		ht, srcHash, dstHash, err = sidecarHashes(src, dst)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(dst, "%v", err)
+			return false, ht, err
+		}
+		return srcHash == dstHash, ht, nil
 	}
 	ht = common.GetOne()
 	srcHash, err := src.Hash(ht)
@@ -154,8 +173,11 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 		return false
 	}
 	if ht == hash.None {
-		// if couldn't check hash, return that they differ
-		return false
+		if !fs.Config.RefreshTimes {
+			// if couldn't check hash, return that they differ
+			return false
+		}
+		fs.Debugf(src, "Sizes identical and no hash to check so refreshing modification time")
 	}
 
 	// mod time differs but hash is the same to reset mod time if required
@@ -171,6 +193,7 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 			}
 			// Update the mtime of the dst object here
 			err := dst.SetModTime(srcModTime)
+			list.Changed(dst.Fs(), dst.Remote())
 			if err == fs.ErrorCantSetModTime {
 				fs.Debugf(dst, "src and dst identical but can't set mod time without re-uploading")
 				return false
@@ -232,8 +255,66 @@ func (o *overrideRemoteObject) MimeType() string {
 	return ""
 }
 
-// Check interface is satisfied
-var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
+// QuickHash returns the hash of the underlying object if it is known
+// without reading it
+func (o *overrideRemoteObject) QuickHash(ht hash.Type) (string, error) {
+	return fs.QuickHash(o.Object, ht)
+}
+
+// Check interfaces are satisfied
+var (
+	_ fs.MimeTyper   = (*overrideRemoteObject)(nil)
+	_ fs.QuickHasher = (*overrideRemoteObject)(nil)
+)
+
+// renamePartial renames the partial upload into place at remote
+// replacing dst if it exists.
+//
+// If the remote can't rename over dst then dst is moved out of the
+// way first and moved back if the rename fails.  The partial upload
+// is left in place if it can't be renamed so nothing is lost.
+func renamePartial(f fs.Fs, dst fs.Object, remote string, partial fs.Object) (newDst fs.Object, err error) {
+	doMove := f.Features().Move
+	if dst == nil || f.Features().MoveOverwrites {
+		newDst, err = doMove(partial, remote)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to rename partial upload %q into place", partial.Remote())
+		}
+		return newDst, nil
+	}
+	oldRemote := remote + ".old" + fs.Config.PartialSuffix
+	if _, err = f.NewObject(oldRemote); err == nil {
+		return nil, errors.Errorf("can't move old file out of the way as %q exists", oldRemote)
+	}
+	old, err := doMove(dst, oldRemote)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to move old file out of the way")
+	}
+	newDst, err = doMove(partial, remote)
+	if err != nil {
+		_, restoreErr := doMove(old, remote)
+		if restoreErr != nil {
+			fs.Errorf(old, "Failed to move old file back into place: %v", restoreErr)
+		}
+		return nil, errors.Wrapf(err, "failed to rename partial upload %q into place", partial.Remote())
+	}
+	err = old.Remove()
+	if err != nil {
+		fs.Errorf(old, "Failed to remove old file: %v", err)
+	}
+	return newDst, nil
+}
+
+// recordCopyCost records the cost of copying src to f for
+// --estimate-cost
+func recordCopyCost(f fs.Fs, src fs.Object) {
+	if f.Features().Copy != nil && (SameConfig(src.Fs(), f) || ServerSideAcrossConfigs(f, src.Fs())) {
+		cost.Record(f, cost.Copy, 1, 0)
+		return
+	}
+	cost.Record(src.Fs(), cost.Get, 1, src.Size())
+	cost.Record(f, cost.Put, 1, 0)
+}
 
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
@@ -243,9 +324,19 @@ var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
 func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
 	if fs.Config.DryRun {
+		recordCopyCost(f, src)
+		recordTransferPlan(PlanCopy, f, dst, remote, src)
 		fs.Logf(src, "Not copying as --dry-run")
 		return newDst, nil
 	}
+	err = accounting.CheckMaxTransfer(src.Size())
+	if err == nil {
+		err = accounting.CheckMaxDuration()
+	}
+	if err != nil {
+		fs.Errorf(src, "Not copying: %v", err)
+		return newDst, err
+	}
 	maxTries := fs.Config.LowLevelRetries
 	tries := 0
 	doUpdate := dst != nil
@@ -260,21 +351,44 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		}
 	}
 	hashOption := &fs.HashesOption{Hashes: common}
+	// Upload to a temporary name and rename it into place when
+	// complete if required and the remote can rename
+	partial := !fs.Config.Inplace && f.Features().Move != nil
 	var actionTaken string
 	for {
 		// Try server side copy first - if has optional interface and
 		// is same underlying remote
 		actionTaken = "Copied (server side copy)"
-		if doCopy := f.Features().Copy; doCopy != nil && SameConfig(src.Fs(), f) {
-			newDst, err = doCopy(src, remote)
+		if doCopy := f.Features().Copy; doCopy != nil && (SameConfig(src.Fs(), f) || ServerSideAcrossConfigs(f, src.Fs())) {
+			newDst, err = doCopy(list.Uncached(src), remote)
 			if err == nil {
 				dst = newDst
+			} else if err != fs.ErrorCantCopy && !SameConfig(src.Fs(), f) {
+				fs.Debugf(src, "Server side copy between remotes failed: %v - copying manually", err)
+				newDst, err = dst, fs.ErrorCantCopy
 			}
 		} else {
 			err = fs.ErrorCantCopy
 		}
 		// If can't server side copy, do it manually
-		if err == fs.ErrorCantCopy {
+		if err == fs.ErrorCantCopy && doMultiThreadCopy(f, src) {
+			if doUpdate {
+				actionTaken = "Multi-thread Copied (replaced existing)"
+			} else {
+				actionTaken = "Multi-thread Copied (new)"
+			}
+			if partial {
+				dst, err = multiThreadCopy(f, remote+fs.Config.PartialSuffix, src, fs.Config.MultiThreadStreams)
+				if err == nil {
+					dst, err = renamePartial(f, newDst, remote, dst)
+				}
+			} else {
+				dst, err = multiThreadCopy(f, remote, src, fs.Config.MultiThreadStreams)
+			}
+			if err == nil {
+				newDst = dst
+			}
+		} else if err == fs.ErrorCantCopy {
 			var in0 io.ReadCloser
 			in0, err = src.Open(hashOption)
 			if err != nil {
@@ -286,7 +400,20 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 				if src.Remote() != remote {
 					wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
 				}
-				if doUpdate {
+				if partial {
+					if doUpdate {
+						actionTaken = "Copied (replaced existing)"
+					} else {
+						actionTaken = "Copied (new)"
+					}
+					var partialObj fs.Object
+					partialObj, err = f.Put(in, &overrideRemoteObject{Object: src, remote: remote + fs.Config.PartialSuffix}, hashOption)
+					if err == nil {
+						dst, err = renamePartial(f, newDst, remote, partialObj)
+					} else {
+						removeFailedCopy(partialObj)
+					}
+				} else if doUpdate {
 					actionTaken = "Copied (replaced existing)"
 					err = dst.Update(in, wrappedSrc, hashOption)
 				} else {
@@ -312,6 +439,7 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		// otherwise finish
 		break
 	}
+	list.Changed(f, remote)
 	if err != nil {
 		fs.CountError(err)
 		fs.Errorf(src, "Failed to copy: %v", err)
@@ -352,6 +480,23 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		}
 	}
 
+	err = waitVisible(f, remote, src)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(f, "%s: %v", remote, err)
+		return newDst, err
+	}
+
+	if needsSidecar(f, src) {
+		err = writeSidecar(f, remote, src)
+		if err != nil {
+			err = errors.Wrap(err, "failed to store hash sidecar")
+			fs.CountError(err)
+			fs.Errorf(dst, "%v", err)
+			return newDst, err
+		}
+	}
+
 	fs.Infof(src, actionTaken)
 	return newDst, err
 }
@@ -369,11 +514,20 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	newDst = dst
 	if fs.Config.DryRun {
+		if fdst.Features().Move != nil && SameConfig(src.Fs(), fdst) {
+			cost.Record(fdst, cost.Copy, 1, 0)
+		} else {
+			recordCopyCost(fdst, src)
+			cost.Record(src.Fs(), cost.Delete, 1, 0)
+		}
+		recordTransferPlan(PlanMove, fdst, dst, remote, src)
 		fs.Logf(src, "Not moving as --dry-run")
 		return newDst, nil
 	}
-	// See if we have Move available
-	if doMove := fdst.Features().Move; doMove != nil && SameConfig(src.Fs(), fdst) {
+	// See if we have Move available - only try it between different
+	// remotes if there is nothing to overwrite
+	sameConfig := SameConfig(src.Fs(), fdst)
+	if doMove := fdst.Features().Move; doMove != nil && (sameConfig || (dst == nil && ServerSideAcrossConfigs(fdst, src.Fs()))) {
 		// Delete destination if it exists
 		if dst != nil {
 			err = DeleteFile(dst)
@@ -382,7 +536,15 @@ func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Ob
 			}
 		}
 		// Move dst <- src
-		newDst, err = doMove(src, remote)
+		newDst, err = doMove(list.Uncached(src), remote)
+		if err != nil && err != fs.ErrorCantMove && !sameConfig {
+			fs.Debugf(src, "Server side move between remotes failed: %v", err)
+			newDst, err = nil, fs.ErrorCantMove
+		}
+		if err != fs.ErrorCantMove {
+			list.Changed(fdst, remote)
+			list.Changed(src.Fs(), src.Remote())
+		}
 		switch err {
 		case nil:
 			fs.Infof(src, "Moved (server side)")
@@ -416,6 +578,24 @@ func CanServerSideMove(fdst fs.Fs) bool {
 	return canMove || canCopy
 }
 
+// checkLocked returns an error if dst is locked against deletion so
+// deletes which would fail aren't tried
+func checkLocked(dst fs.Object) error {
+	do, ok := list.Uncached(dst).(fs.Locker)
+	if !ok {
+		return nil
+	}
+	until, legalHold := do.LockedUntil()
+	if legalHold {
+		fs.Errorf(dst, "Not deleting as it has a legal hold")
+	} else if time.Now().Before(until) {
+		fs.Errorf(dst, "Not deleting as it is locked until %v", until)
+	} else {
+		return nil
+	}
+	return fserrors.NoRetryError(fs.ErrorObjectLocked)
+}
+
 // DeleteFileWithBackupDir deletes a single file respecting --dry-run
 // and accumulating stats and errors.
 //
@@ -427,28 +607,51 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 	if fs.Config.MaxDelete != -1 && numDeletes > fs.Config.MaxDelete {
 		return fserrors.FatalError(errors.New("--max-delete threshold reached"))
 	}
+	err = checkLocked(dst)
+	if err != nil {
+		fs.CountError(err)
+		accounting.Stats.DoneChecking(dst.Remote())
+		return err
+	}
 	action, actioned, actioning := "delete", "Deleted", "deleting"
 	if backupDir != nil {
 		action, actioned, actioning = "move into backup dir", "Moved into backup dir", "moving into backup dir"
 	}
+	remoteWithSuffix := dst.Remote() + fs.Config.Suffix
 	if fs.Config.DryRun {
+		entry := PlanEntry{
+			Action: PlanDelete,
+			Dst:    fsString(dst.Fs(), dst.Remote()),
+			Size:   dst.Size(),
+		}
+		if backupDir != nil {
+			cost.Record(backupDir, cost.Copy, 1, 0)
+			entry.Reason = "move into backup dir " + fsString(backupDir, remoteWithSuffix)
+		} else {
+			cost.Record(dst.Fs(), cost.Delete, 1, 0)
+		}
+		recordPlan(entry)
 		fs.Logf(dst, "Not %s as --dry-run", actioning)
 	} else if backupDir != nil {
 		if !SameConfig(dst.Fs(), backupDir) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
 		} else {
-			remoteWithSuffix := dst.Remote() + fs.Config.Suffix
 			overwritten, _ := backupDir.NewObject(remoteWithSuffix)
 			_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
 		}
 	} else {
 		err = dst.Remove()
+		list.Changed(dst.Fs(), dst.Remote())
 	}
 	if err != nil {
 		fs.CountError(err)
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
 	} else if !fs.Config.DryRun {
 		fs.Infof(dst, actioned)
+		if fs.Config.HashSidecar {
+			removeSidecar(dst)
+		}
+		recordUndo(UndoDeleted, dst, backupDir, remoteWithSuffix)
 	}
 	accounting.Stats.DoneChecking(dst.Remote())
 	return err
@@ -512,6 +715,22 @@ func SameConfig(fdst, fsrc fs.Info) bool {
 	return fdst.Name() == fsrc.Name()
 }
 
+// SameRemoteType returns true if fdst and fsrc are the same type of
+// remote
+func SameRemoteType(fdst, fsrc fs.Info) bool {
+	return reflect.TypeOf(fdst) == reflect.TypeOf(fsrc)
+}
+
+// ServerSideAcrossConfigs returns true if a server side copy or move
+// from fsrc to fdst can be tried even though they are using different
+// config file entries.
+//
+// This is true if they are the same type of remote and the remote
+// says it can do this, eg two s3 remotes with different credentials.
+func ServerSideAcrossConfigs(fdst fs.Fs, fsrc fs.Info) bool {
+	return !fs.Config.NoServerSideAcross && fdst.Features().ServerSideAcrossConfigs && SameRemoteType(fdst, fsrc)
+}
+
 // Same returns true if fdst and fsrc point to the same underlying Fs
 func Same(fdst, fsrc fs.Info) bool {
 	return SameConfig(fdst, fsrc) && fdst.Root() == fsrc.Root()
@@ -614,6 +833,7 @@ func (c *checkMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
 
 // check to see if two objects are identical using the check function
 func (c *checkMarch) checkIdentical(dst, src fs.Object) (differ bool, noHash bool) {
+	defer limiter.Check(dst.Fs(), src.Fs())()
 	accounting.Stats.Checking(src.Remote())
 	defer accounting.Stats.DoneChecking(src.Remote())
 	if sizeDiffers(src, dst) {
@@ -686,7 +906,7 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn, oneway bool) error {
 	// set up a march over fdst and fsrc
 	m := march.New(context.Background(), fdst, fsrc, "", c)
 	fs.Infof(fdst, "Waiting for checks to finish")
-	m.Run()
+	_ = m.Run() // only returns an error if dir is set
 
 	if c.dstFilesMissing > 0 {
 		fs.Logf(fdst, "%d files missing", c.dstFilesMissing)
@@ -772,6 +992,11 @@ func CheckDownload(fdst, fsrc fs.Fs, oneway bool) error {
 			fs.Errorf(a, "Failed to download: %v", err)
 			return true, true
 		}
+		if differ {
+			err = errors.New("contents differ")
+			fs.Errorf(b, "%v", err)
+			fs.CountError(err)
+		}
 		return differ, false
 	}
 	return CheckFn(fdst, fsrc, check, oneway)
@@ -873,12 +1098,35 @@ func hashSum(ht hash.Type, o fs.Object) string {
 
 // HashLister does a md5sum equivalent for the hash type passed in
 func HashLister(ht hash.Type, f fs.Fs, w io.Writer) error {
+	return HashListerBase64(ht, false, f, w)
+}
+
+// HashListerBase64 does a md5sum equivalent for the hash type passed
+// in, writing the sums in base64 instead of hex if outputBase64 is set
+func HashListerBase64(ht hash.Type, outputBase64 bool, f fs.Fs, w io.Writer) error {
+	width := hash.Width[ht]
+	if outputBase64 {
+		width = base64.URLEncoding.EncodedLen(width / 2)
+	}
 	return ListFn(f, func(o fs.Object) {
 		sum := hashSum(ht, o)
-		syncFprintf(w, "%*s  %s\n", hash.Width[ht], sum, o.Remote())
+		if outputBase64 {
+			sum = hexToBase64(sum)
+		}
+		syncFprintf(w, "%*s  %s\n", width, sum, o.Remote())
 	})
 }
 
+// hexToBase64 converts a hex sum to base64 leaving it alone if it
+// isn't hex, eg UNSUPPORTED
+func hexToBase64(sum string) string {
+	sumBytes, err := hex.DecodeString(sum)
+	if err != nil || len(sumBytes) == 0 {
+		return sum
+	}
+	return base64.URLEncoding.EncodeToString(sumBytes)
+}
+
 // Count counts the objects and their sizes in the Fs
 //
 // Obeys includes and excludes
@@ -923,6 +1171,7 @@ func Mkdir(f fs.Fs, dir string) error {
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Making directory")
 	err := f.Mkdir(dir)
+	list.Changed(f, dir)
 	if err != nil {
 		fs.CountError(err)
 		return err
@@ -938,7 +1187,9 @@ func TryRmdir(f fs.Fs, dir string) error {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Removing directory")
-	return f.Rmdir(dir)
+	err := f.Rmdir(dir)
+	list.Changed(f, dir)
+	return err
 }
 
 // Rmdir removes a container but not if not empty
@@ -963,6 +1214,7 @@ func Purge(f fs.Fs, dir string) error {
 				fs.Logf(f, "Not purging as --dry-run set")
 			} else {
 				err = doPurge()
+				list.ChangedTree(f, dir)
 				if err == fs.ErrorCantPurge {
 					doFallbackPurge = true
 				}
@@ -1032,17 +1284,83 @@ func listToChan(f fs.Fs, dir string) fs.ObjectsChan {
 	return o
 }
 
-// CleanUp removes the trash for the Fs
+// UploadMaxAge is how long ago a multipart upload must have been
+// started for CleanUp to abort it.  Newer uploads may still be
+// running.
+var UploadMaxAge = 24 * time.Hour
+
+// CleanUp removes the trash for the Fs and aborts the multipart
+// uploads started more than UploadMaxAge ago
 func CleanUp(f fs.Fs) error {
 	doCleanUp := f.Features().CleanUp
-	if doCleanUp == nil {
+	if doCleanUp == nil && f.Features().ListUploads == nil {
 		return errors.Errorf("%v doesn't support cleanup", f)
 	}
+	var err error
+	if f.Features().ListUploads != nil {
+		err = AbortUploads(f, UploadMaxAge)
+	}
+	if doCleanUp == nil {
+		return err
+	}
 	if fs.Config.DryRun {
 		fs.Logf(f, "Not running cleanup as --dry-run set")
-		return nil
+		return err
+	}
+	cleanUpErr := doCleanUp()
+	if cleanUpErr != nil {
+		return cleanUpErr
 	}
-	return doCleanUp()
+	return err
+}
+
+// ListUploads returns the multipart uploads on f which have been
+// started but not finished or aborted, oldest first
+func ListUploads(f fs.Fs) ([]fs.PendingUpload, error) {
+	doListUploads := f.Features().ListUploads
+	if doListUploads == nil {
+		return nil, errors.Errorf("%v can't list multipart uploads", f)
+	}
+	uploads, err := doListUploads()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list multipart uploads")
+	}
+	sort.SliceStable(uploads, func(i, j int) bool {
+		return uploads[i].Started.Before(uploads[j].Started)
+	})
+	return uploads, nil
+}
+
+// AbortUploads aborts the multipart uploads on f started more than
+// maxAge ago, deleting the parts uploaded so they aren't charged for.
+//
+// It respects --dry-run and returns the last error if any.
+func AbortUploads(f fs.Fs, maxAge time.Duration) error {
+	uploads, err := ListUploads(f)
+	if err != nil {
+		return err
+	}
+	doAbortUpload := f.Features().AbortUpload
+	err = nil
+	for _, upload := range uploads {
+		if time.Since(upload.Started) < maxAge {
+			fs.Debugf(upload.Remote, "Not aborting upload started at %v as it may still be running", upload.Started)
+			continue
+		}
+		if fs.Config.DryRun {
+			fs.Logf(upload.Remote, "Not aborting upload started at %v as --dry-run", upload.Started)
+			continue
+		}
+		abortErr := doAbortUpload(upload)
+		if abortErr != nil {
+			fs.CountError(abortErr)
+			fs.Errorf(upload.Remote, "Failed to abort upload started at %v: %v", upload.Started, abortErr)
+			err = abortErr
+			continue
+		}
+		fs.Infof(upload.Remote, "Aborted upload started at %v", upload.Started)
+	}
+	return err
 }
 
 // wrap a Reader and a Closer together into a ReadCloser
@@ -1059,56 +1377,74 @@ type readCloser struct {
 //
 // if count < 0 then it will be ignored
 // if count >= 0 then only that many characters will be output
+//
+// The files are output in sorted order of their paths so that split
+// files, eg archive.part.aa, archive.part.ab, are rebuilt correctly.
 func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 	var mu sync.Mutex
-	return ListFn(f, func(o fs.Object) {
-		var err error
-		accounting.Stats.Transferring(o.Remote())
-		defer func() {
-			accounting.Stats.DoneTransferring(o.Remote(), err == nil)
-		}()
-		opt := fs.RangeOption{Start: offset, End: -1}
-		size := o.Size()
-		if opt.Start < 0 {
-			opt.Start += size
-		}
-		if count >= 0 {
-			opt.End = opt.Start + count - 1
-		}
-		var options []fs.OpenOption
-		if opt.Start > 0 || opt.End >= 0 {
-			options = append(options, &opt)
-		}
-		in, err := o.Open(options...)
-		if err != nil {
-			fs.CountError(err)
-			fs.Errorf(o, "Failed to open: %v", err)
-			return
-		}
-		if count >= 0 {
-			in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
-			// reduce remaining size to count
-			if size > count {
-				size = count
-			}
-		}
-		in = accounting.NewAccountSizeName(in, size, o.Remote()).WithBuffer() // account and buffer the transfer
-		defer func() {
-			err = in.Close()
-			if err != nil {
-				fs.CountError(err)
-				fs.Errorf(o, "Failed to close: %v", err)
-			}
-		}()
-		// take the lock just before we output stuff, so at the last possible moment
+	var objs []fs.Object
+	err := ListFn(f, func(o fs.Object) {
 		mu.Lock()
-		defer mu.Unlock()
-		_, err = io.Copy(w, in)
+		objs = append(objs, o)
+		mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].Remote() < objs[j].Remote()
+	})
+	for _, o := range objs {
+		catObject(o, w, offset, count)
+	}
+	return nil
+}
+
+// catObject outputs the range of o selected by offset and count to w
+func catObject(o fs.Object, w io.Writer, offset, count int64) {
+	var err error
+	accounting.Stats.Transferring(o.Remote())
+	defer func() {
+		accounting.Stats.DoneTransferring(o.Remote(), err == nil)
+	}()
+	opt := fs.RangeOption{Start: offset, End: -1}
+	size := o.Size()
+	if opt.Start < 0 {
+		opt.Start += size
+	}
+	if count >= 0 {
+		opt.End = opt.Start + count - 1
+	}
+	var options []fs.OpenOption
+	if opt.Start > 0 || opt.End >= 0 {
+		options = append(options, &opt)
+	}
+	in, err := o.Open(options...)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(o, "Failed to open: %v", err)
+		return
+	}
+	if count >= 0 {
+		in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
+		// reduce remaining size to count
+		if size > count {
+			size = count
+		}
+	}
+	in = accounting.NewAccountSizeName(in, size, o.Remote()).WithBuffer() // account and buffer the transfer
+	defer func() {
+		err = in.Close()
 		if err != nil {
 			fs.CountError(err)
-			fs.Errorf(o, "Failed to send to output: %v", err)
+			fs.Errorf(o, "Failed to close: %v", err)
 		}
-	})
+	}()
+	_, err = io.Copy(w, in)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(o, "Failed to send to output: %v", err)
+	}
 }
 
 // Rcat reads data from the Reader until EOF and uploads it to a file on remote
@@ -1118,7 +1454,7 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 	defer func() {
 		accounting.Stats.DoneTransferring(dstFileName, err == nil)
 		if otherErr := in.Close(); otherErr != nil {
-			fs.Debugf(fdst, "Rcat: failed to close source: %v", err)
+			fs.Debugf(fdst, "Rcat: failed to close source: %v", otherErr)
 		}
 	}()
 
@@ -1155,6 +1491,13 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		Closer: in,
 	}
 
+	if fs.Config.DryRun {
+		fs.Logf("stdin", "Not uploading as --dry-run")
+		// prevents "broken pipe" errors
+		_, err = io.Copy(ioutil.Discard, in)
+		return nil, err
+	}
+
 	fStreamTo := fdst
 	canStream := fdst.Features().PutStream != nil
 	if !canStream {
@@ -1172,15 +1515,10 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 		fStreamTo = tmpLocalFs
 	}
 
-	if fs.Config.DryRun {
-		fs.Logf("stdin", "Not uploading as --dry-run")
-		// prevents "broken pipe" errors
-		_, err = io.Copy(ioutil.Discard, in)
-		return nil, err
-	}
-
 	objInfo := object.NewStaticObjectInfo(dstFileName, modTime, -1, false, nil, nil)
-	if dst, err = fStreamTo.Features().PutStream(in, objInfo, hashOption); err != nil {
+	dst, err = fStreamTo.Features().PutStream(in, objInfo, hashOption)
+	list.Changed(fStreamTo, dstFileName)
+	if err != nil {
 		return dst, err
 	}
 	if err = compare(dst); err != nil {
@@ -1277,6 +1615,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 	// If we should ignore existing files, don't transfer
 	if fs.Config.IgnoreExisting {
 		fs.Debugf(src, "Destination exists, skipping")
+		recordSkipPlan(src, dst, "destination exists")
 		return false
 	}
 	// If we should upload unconditionally
@@ -1300,12 +1639,14 @@ func NeedTransfer(dst, src fs.Object) bool {
 		switch {
 		case dt >= modifyWindow:
 			fs.Debugf(src, "Destination is newer than source, skipping")
+			recordSkipPlan(src, dst, "destination is newer")
 			return false
 		case dt <= -modifyWindow:
 			fs.Debugf(src, "Destination is older than source, transferring")
 		default:
 			if src.Size() == dst.Size() {
 				fs.Debugf(src, "Destination mod time is within %v of source and sizes identical, skipping", modifyWindow)
+				recordSkipPlan(src, dst, "destination is the same age and size")
 				return false
 			}
 			fs.Debugf(src, "Destination mod time is within %v of source but sizes differ, transferring", modifyWindow)
@@ -1314,6 +1655,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 		// Check to see if changed or not
 		if Equal(src, dst) {
 			fs.Debugf(src, "Unchanged skipping")
+			recordSkipPlan(src, dst, "unchanged")
 			return false
 		}
 	}
@@ -1341,6 +1683,7 @@ func RcatSize(fdst fs.Fs, dstFileName string, in io.ReadCloser, size int64, modT
 		}()
 		info := object.NewStaticObjectInfo(dstFileName, modTime, size, true, nil, fdst)
 		obj, err = fdst.Put(in, info)
+		list.Changed(fdst, dstFileName)
 		if err != nil {
 			fs.Errorf(dstFileName, "Post request put error: %v", err)
 
@@ -1389,9 +1732,24 @@ func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName stri
 	}
 
 	if NeedTransfer(dstObj, srcObj) {
+		// Don't overwrite the destination if it is immutable
+		if dstObj != nil && fs.Config.Immutable {
+			fs.Errorf(dstObj, "Source and destination exist but do not match: immutable file modified")
+			return fs.ErrorImmutableModified
+		}
+		// Move the existing destination out of the way if required
+		if dstObj != nil && fs.Config.BackupDir != "" {
+			err = moveToBackupDir(dstObj)
+			if err != nil {
+				return err
+			}
+			dstObj = nil
+		}
+		done := limiter.Transfer(fdst, fsrc)
 		accounting.Stats.Transferring(srcFileName)
 		_, err = Op(fdst, dstObj, dstFileName, srcObj)
 		accounting.Stats.DoneTransferring(srcFileName, err == nil)
+		done()
 	} else {
 		accounting.Stats.Checking(srcFileName)
 		if !cp {
@@ -1402,6 +1760,68 @@ func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName stri
 	return err
 }
 
+// moveToBackupDir moves dst into --backup-dir (with --suffix) before
+// it is overwritten
+func moveToBackupDir(dst fs.Object) error {
+	backupDir, err := fs.NewFs(fs.Config.BackupDir)
+	if err != nil {
+		return fserrors.FatalError(errors.Errorf("Failed to make fs for --backup-dir %q: %v", fs.Config.BackupDir, err))
+	}
+	if !SameConfig(dst.Fs(), backupDir) {
+		return fserrors.FatalError(errors.New("parameter to --backup-dir has to be on the same remote as destination"))
+	}
+	remoteWithSuffix := dst.Remote() + fs.Config.Suffix
+	overwritten, _ := backupDir.NewObject(remoteWithSuffix)
+	_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+	if err != nil {
+		return err
+	}
+	if !fs.Config.DryRun {
+		RecordOverwrite(dst, backupDir, remoteWithSuffix)
+	}
+	return nil
+}
+
+// CompareOrCopyDest checks the object at src.Remote() in
+// compareOrCopyDest to see whether src needs transferring to fdst
+//
+// If it is identical to src then with --compare-dest src doesn't need
+// transferring and with --copy-dest it is server side copied to fdst
+// instead.  It returns true if src doesn't need transferring any
+// more.
+func CompareOrCopyDest(fdst fs.Fs, dst, src fs.Object, compareOrCopyDest fs.Fs) (noNeedTransfer bool, err error) {
+	if compareOrCopyDest == nil {
+		return false, nil
+	}
+	cmpObj, err := compareOrCopyDest.NewObject(src.Remote())
+	if err == fs.ErrorObjectNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !Equal(src, cmpObj) {
+		return false, nil
+	}
+	if fs.Config.CopyDest == "" {
+		fs.Debugf(src, "Identical file found in --compare-dest, skipping")
+		return true, nil
+	}
+	// If destination already exists, then we must move it into --backup-dir if required
+	if dst != nil && fs.Config.BackupDir != "" {
+		err = moveToBackupDir(dst)
+		if err != nil {
+			return false, err
+		}
+		dst = nil
+	}
+	_, err = Copy(fdst, dst, src.Remote(), cmpObj)
+	if err != nil {
+		return false, err
+	}
+	fs.Debugf(src, "Identical file copied from --copy-dest")
+	return true, nil
+}
+
 // MoveFile moves a single file possibly to a new name
 func MoveFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (err error) {
 	return moveOrCopyFile(fdst, fsrc, dstFileName, srcFileName, false)
@@ -1415,7 +1835,7 @@ func CopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string) (e
 // SetTier changes tier of object in remote
 func SetTier(fsrc fs.Fs, tier string) error {
 	return ListFn(fsrc, func(o fs.Object) {
-		objImpl, ok := o.(fs.SetTierer)
+		objImpl, ok := list.Uncached(o).(fs.SetTierer)
 		if !ok {
 			fs.Errorf(fsrc, "Remote object does not implement SetTier")
 			return
@@ -1521,6 +1941,14 @@ func (l *ListFormat) AddID() {
 	})
 }
 
+// AddOrigID adds the ID of the object underlying a wrapped file (eg
+// crypt) to the output if known
+func (l *ListFormat) AddOrigID() {
+	l.AppendOutput(func() string {
+		return origID(l.entry)
+	})
+}
+
 // AddMimeType adds file's MimeType to the output if known
 func (l *ListFormat) AddMimeType() {
 	l.AppendOutput(func() string {
@@ -3,13 +3,21 @@
 package operations
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSizeDiffers(t *testing.T) {
@@ -38,3 +46,237 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+// noHashFs is an fs.Info without any hashes
+type noHashFs struct {
+	fs.Info
+}
+
+// Hashes returns no hash types
+func (noHashFs) Hashes() hash.Set { return hash.Set(hash.None) }
+
+func TestEqualRefreshTimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-refresh-times-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	oldTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	newTime := time.Date(2011, 12, 25, 12, 59, 59, 0, time.UTC)
+	info := object.NewStaticObjectInfo("file", oldTime, 5, true, nil, f)
+	dst, err := f.Put(bytes.NewBufferString("hello"), info)
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("file", newTime, 5, true, nil, noHashFs{object.MemoryFs})
+
+	oldRefreshTimes := fs.Config.RefreshTimes
+	defer func() { fs.Config.RefreshTimes = oldRefreshTimes }()
+
+	fs.Config.RefreshTimes = false
+	assert.False(t, equal(src, dst, false, false))
+	assert.Equal(t, oldTime, dst.ModTime().UTC())
+
+	fs.Config.RefreshTimes = true
+	assert.True(t, equal(src, dst, false, false))
+	assert.Equal(t, newTime, dst.ModTime().UTC())
+}
+
+// lockedObject is an fs.Object with an Object Lock
+type lockedObject struct {
+	fs.Object
+	until     time.Time
+	legalHold bool
+	removed   bool
+}
+
+// LockedUntil returns the lock on the object
+func (o *lockedObject) LockedUntil() (time.Time, bool) { return o.until, o.legalHold }
+
+// Remove records that the object was removed
+func (o *lockedObject) Remove() error {
+	o.removed = true
+	return nil
+}
+
+func TestDeleteFileLocked(t *testing.T) {
+	when := time.Now()
+	for _, test := range []struct {
+		until     time.Time
+		legalHold bool
+		wantErr   bool
+	}{
+		{time.Time{}, false, false},
+		{when.Add(-time.Hour), false, false},
+		{when.Add(time.Hour), false, true},
+		{time.Time{}, true, true},
+		{when.Add(-time.Hour), true, true},
+	} {
+		what := fmt.Sprintf("until=%v, legalHold=%v", test.until, test.legalHold)
+		o := &lockedObject{
+			Object:    object.NewMemoryObject("file", when, []byte("hello")),
+			until:     test.until,
+			legalHold: test.legalHold,
+		}
+		err := DeleteFile(o)
+		if test.wantErr {
+			require.Error(t, err, what)
+			assert.Equal(t, fs.ErrorObjectLocked.Error(), err.Error(), what)
+			assert.True(t, fserrors.IsNoRetryError(err), what)
+			assert.False(t, o.removed, what)
+		} else {
+			require.NoError(t, err, what)
+			assert.True(t, o.removed, what)
+		}
+	}
+}
+
+// uploadsFs is an fs.Fs with unfinished multipart uploads
+type uploadsFs struct {
+	fs.Fs
+	uploads []fs.PendingUpload
+	aborted []string
+}
+
+// Features returns the optional features of this Fs
+func (f *uploadsFs) Features() *fs.Features {
+	return &fs.Features{ListUploads: f.ListUploads, AbortUpload: f.AbortUpload}
+}
+
+// ListUploads lists the uploads
+func (f *uploadsFs) ListUploads() ([]fs.PendingUpload, error) {
+	return append([]fs.PendingUpload(nil), f.uploads...), nil
+}
+
+// AbortUpload records the upload as aborted
+func (f *uploadsFs) AbortUpload(upload fs.PendingUpload) error {
+	f.aborted = append(f.aborted, upload.ID)
+	return nil
+}
+
+func TestAbortUploads(t *testing.T) {
+	now := time.Now()
+	f := &uploadsFs{
+		Fs: object.MemoryFs,
+		uploads: []fs.PendingUpload{
+			{Remote: "new", ID: "1", Started: now.Add(-time.Hour)},
+			{Remote: "old", ID: "2", Started: now.Add(-48 * time.Hour)},
+			{Remote: "older", ID: "3", Started: now.Add(-72 * time.Hour)},
+		},
+	}
+
+	uploads, err := ListUploads(f)
+	require.NoError(t, err)
+	var ids []string
+	for _, upload := range uploads {
+		ids = append(ids, upload.ID)
+	}
+	assert.Equal(t, []string{"3", "2", "1"}, ids)
+
+	oldDryRun := fs.Config.DryRun
+	defer func() { fs.Config.DryRun = oldDryRun }()
+
+	fs.Config.DryRun = true
+	require.NoError(t, CleanUp(f))
+	assert.Nil(t, f.aborted)
+
+	fs.Config.DryRun = false
+	require.NoError(t, CleanUp(f))
+	assert.Equal(t, []string{"3", "2"}, f.aborted)
+
+	f.aborted = nil
+	require.NoError(t, AbortUploads(f, 0))
+	assert.Equal(t, []string{"3", "2", "1"}, f.aborted)
+
+	// Can't clean up without CleanUp or ListUploads
+	assert.Error(t, CleanUp(object.MemoryFs))
+}
+
+// moveFs is an fs.Fs whose Move fails when moving failRemote
+type moveFs struct {
+	fs.Fs
+	features   fs.Features
+	failRemote string
+}
+
+func newMoveFs(f fs.Fs, overwrites bool, failRemote string) *moveFs {
+	mf := &moveFs{
+		Fs:         f,
+		features:   *f.Features(),
+		failRemote: failRemote,
+	}
+	mf.features.MoveOverwrites = overwrites
+	mf.features.Move = func(src fs.Object, remote string) (fs.Object, error) {
+		if src.Remote() == mf.failRemote {
+			return nil, errors.New("move failed")
+		}
+		return f.Features().Move(src, remote)
+	}
+	return mf
+}
+
+func (f *moveFs) Features() *fs.Features {
+	return &f.features
+}
+
+func TestRenamePartial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-partial-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	put := func(remote, contents string) fs.Object {
+		src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+		o, err := f.Put(bytes.NewBufferString(contents), src)
+		require.NoError(t, err)
+		return o
+	}
+	contents := func(remote string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, remote))
+		if os.IsNotExist(err) {
+			return "<missing>"
+		}
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	for _, overwrites := range []bool{true, false} {
+		t.Run(fmt.Sprintf("overwrites=%v", overwrites), func(t *testing.T) {
+			// a failed rename leaves both the old file and the partial
+			dst := put("file", "old")
+			partial := put("file.partial", "new")
+			_, err := renamePartial(newMoveFs(f, overwrites, "file.partial"), dst, "file", partial)
+			require.Error(t, err)
+			assert.Equal(t, "old", contents("file"))
+			assert.Equal(t, "new", contents("file.partial"))
+			assert.Equal(t, "<missing>", contents("file.old.partial"))
+
+			// a successful one replaces the old file
+			newDst, err := renamePartial(newMoveFs(f, overwrites, ""), dst, "file", partial)
+			require.NoError(t, err)
+			assert.Equal(t, "file", newDst.Remote())
+			assert.Equal(t, "new", contents("file"))
+			assert.Equal(t, "<missing>", contents("file.partial"))
+			assert.Equal(t, "<missing>", contents("file.old.partial"))
+
+			// and a new file is just renamed
+			partial = put("file2.partial", "new2")
+			_, err = renamePartial(newMoveFs(f, overwrites, ""), nil, "file2", partial)
+			require.NoError(t, err)
+			assert.Equal(t, "new2", contents("file2"))
+			require.NoError(t, os.Remove(filepath.Join(dir, "file2")))
+		})
+	}
+
+	// if the old file can't be moved out of the way nothing changes
+	dst := put("file", "old")
+	partial := put("file.partial", "new")
+	_, err = renamePartial(newMoveFs(f, false, "file"), dst, "file", partial)
+	require.Error(t, err)
+	assert.Equal(t, "old", contents("file"))
+	assert.Equal(t, "new", contents("file.partial"))
+}
@@ -25,7 +25,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -38,6 +40,7 @@ import (
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -141,6 +144,119 @@ func TestLsLong(t *testing.T) {
 	}
 }
 
+func TestSearch(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("potato", "hello world", t1)
+	file2 := r.WriteObject("sub dir/Big Potato.txt", "hello world again", t2)
+	file3 := r.WriteObject("sub dir/carrot", "hello", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	search := func(dir, query string) (remotes []string) {
+		err := operations.Search(r.Fremote, dir, query, true, func(entry fs.DirEntry) error {
+			remotes = append(remotes, entry.Remote())
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Strings(remotes)
+		return remotes
+	}
+
+	assert.Equal(t, []string{"potato", "sub dir/Big Potato.txt"}, search("", "potato"))
+	assert.Equal(t, []string{"sub dir/Big Potato.txt"}, search("sub dir", "POTATO"))
+	assert.Equal(t, []string{"sub dir"}, search("", "sub"))
+	assert.Equal(t, []string(nil), search("", "turnip"))
+
+	o, err := r.Fremote.NewObject("sub dir/Big Potato.txt")
+	require.NoError(t, err)
+	item := operations.NewListJSONItem(o, &operations.ListJSONOpt{NoModTime: true})
+	assert.Equal(t, "sub dir/Big Potato.txt", item.Path)
+	assert.Equal(t, "Big Potato.txt", item.Name)
+	assert.Equal(t, int64(17), item.Size)
+	assert.False(t, item.IsDir)
+	assert.True(t, time.Time(item.ModTime).IsZero())
+}
+
+// searchFs is an fs.Fs with a Search method which returns canned
+// results as if from a provider's search API
+type searchFs struct {
+	fs.Fs
+	entries fs.DirEntries
+	dir     string // dir passed to the last Search
+	query   string // query passed to the last Search
+}
+
+// Features returns the optional features of the searchFs
+func (f *searchFs) Features() *fs.Features {
+	return (&fs.Features{}).Fill(f)
+}
+
+// Search returns all the canned entries
+func (f *searchFs) Search(dir, query string, callback fs.ListRCallback) error {
+	f.dir, f.query = dir, query
+	return callback(f.entries)
+}
+
+func TestSearchAPI(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := &searchFs{
+		Fs: r.Fremote,
+		entries: fs.DirEntries{
+			mockobject.Object("potato"),
+			mockobject.Object("potato.txt"),
+			mockobject.Object("carrot"), // search APIs can return entries which don't match
+			fs.NewDir("potatoes", t1),
+			mockobject.Object("potatoes/potato"),
+			mockobject.Object("potatoes/deep/potato"),
+		},
+	}
+	require.NotNil(t, f.Features().Search)
+
+	search := func(dir, query string, useSearch bool) (remotes []string) {
+		err := operations.Search(f, dir, query, useSearch, func(entry fs.DirEntry) error {
+			remotes = append(remotes, entry.Remote())
+			return nil
+		})
+		require.NoError(t, err)
+		return remotes
+	}
+
+	assert.Equal(t, []string{"potato", "potato.txt", "potatoes", "potatoes/potato", "potatoes/deep/potato"}, search("", "POTATO", true))
+	assert.Equal(t, "", f.dir)
+	assert.Equal(t, "POTATO", f.query)
+
+	// Not using the search API should list the remote instead
+	f.query = ""
+	assert.Equal(t, []string(nil), search("", "potato", false))
+	assert.Equal(t, "", f.query)
+
+	// Check --max-depth is obeyed relative to dir
+	oldMaxDepth := fs.Config.MaxDepth
+	fs.Config.MaxDepth = 1
+	assert.Equal(t, []string{"potato", "potato.txt", "potatoes"}, search("", "potato", true))
+	f.entries = f.entries[4:]
+	assert.Equal(t, []string{"potatoes/potato"}, search("potatoes", "potato", true))
+	assert.Equal(t, "potatoes", f.dir)
+	fs.Config.MaxDepth = oldMaxDepth
+	f.entries = fs.DirEntries{
+		mockobject.Object("potato"),
+		mockobject.Object("potato.txt"),
+		fs.NewDir("potatoes", t1),
+		mockobject.Object("potatoes/potato"),
+	}
+
+	// Check filters are obeyed
+	oldActive := filter.Active
+	defer func() {
+		filter.Active = oldActive
+	}()
+	filter.Active, _ = filter.NewFilter(nil)
+	require.NoError(t, filter.Active.AddRule("- *.txt"))
+	require.NoError(t, filter.Active.AddRule("- potatoes/**"))
+	assert.Equal(t, []string{"potato"}, search("", "potato", true))
+}
+
 func TestHashSums(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -299,6 +415,21 @@ func TestCheckDownload(t *testing.T) {
 	testCheck(t, operations.CheckDownload)
 }
 
+// Check --download finds files which differ only in their contents
+func TestCheckDownloadContentsDiffer(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("potato", "contents one", t1)
+	file1r := r.WriteObject("potato", "contents two", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1r)
+
+	oldErrors := accounting.Stats.GetErrors()
+	err := operations.CheckDownload(r.Fremote, r.Flocal, false)
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), accounting.Stats.GetErrors()-oldErrors)
+}
+
 func TestCheckSizeOnly(t *testing.T) {
 	fs.Config.SizeOnly = true
 	defer func() { fs.Config.SizeOnly = false }()
@@ -335,6 +466,28 @@ func TestCat(t *testing.T) {
 	}
 }
 
+func TestCatSorted(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("archive.part.ab", "BB", t1)
+	file2 := r.WriteObject("archive.part.aa", "AA", t1)
+	file3 := r.WriteObject("sub/archive.part.aa", "CC", t1)
+	file4 := r.WriteObject("sub/dir/archive.part.aa", "DD", t1)
+	file5 := r.WriteObject("sub/zz", "EE", t1)
+
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4, file5)
+
+	var buf bytes.Buffer
+	err := operations.Cat(r.Fremote, &buf, 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "AABBCCDDEE", buf.String())
+
+	buf.Reset()
+	err = operations.Cat(r.Fremote, &buf, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCDE", buf.String())
+}
+
 func TestRcat(t *testing.T) {
 	checkSumBefore := fs.Config.CheckSum
 	defer func() { fs.Config.CheckSum = checkSumBefore }()
@@ -374,6 +527,20 @@ func TestRcat(t *testing.T) {
 	check(false)
 }
 
+func TestRcatDryRun(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.DryRun = true
+	defer func() { fs.Config.DryRun = false }()
+
+	for _, size := range []int64{10, int64(fs.Config.StreamingUploadCutoff) + 1} {
+		in := ioutil.NopCloser(bytes.NewReader(make([]byte, size)))
+		_, err := operations.Rcat(r.Fremote, fmt.Sprintf("file%d", size), in, t1)
+		require.NoError(t, err)
+	}
+	fstest.CheckListing(t, r.Fremote, []fstest.Item{})
+}
+
 func TestRmdirsNoLeaveRoot(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -549,6 +716,110 @@ func TestCopyFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+func TestCopyFileMulti(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	dir, err := ioutil.TempDir("", "rclone-copy-multi-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	fdst2, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	accounting.Stats.ResetCounters()
+	err = operations.CopyFileMulti([]fs.Fs{r.Fremote, fdst2}, r.Flocal, file1.Path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	fstest.CheckItems(t, fdst2, file1)
+
+	// a changed file is copied to both destinations reading it once
+	file1 = r.WriteFile("file1", "file1 contents changed", t2)
+	accounting.Stats.ResetCounters()
+	err = operations.CopyFileMulti([]fs.Fs{r.Fremote, fdst2}, r.Flocal, file1.Path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, r.Fremote, file1)
+	fstest.CheckItems(t, fdst2, file1)
+}
+
+func TestCopyFileImmutable(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.Immutable = true
+	defer func() { fs.Config.Immutable = false }()
+
+	file1 := r.WriteFile("file1", "file1 new contents", t2)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	// A new file can be copied
+	err := operations.CopyFile(r.Fremote, r.Flocal, "file1", file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// But an existing one can't be overwritten
+	file2 := r.WriteObject("file2", "file2 old contents", t1)
+	err = operations.CopyFile(r.Fremote, r.Flocal, "file2", file1.Path)
+	assert.Equal(t, fs.ErrorImmutableModified, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestCopyFileNotInplace(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.Inplace = false
+	defer func() { fs.Config.Inplace = true }()
+
+	file1 := r.WriteFile("file1", "file1 new contents", t2)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	// A new file is renamed into place
+	err := operations.CopyFile(r.Fremote, r.Flocal, "file1", file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// And an existing one replaced
+	r.WriteObject("file2", "file2 old contents", t1)
+	err = operations.CopyFile(r.Fremote, r.Flocal, "file2", file1.Path)
+	require.NoError(t, err)
+	file2 := file1
+	file2.Path = "file2"
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestCopyFileBackupDir(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldBackupDir, oldSuffix := fs.Config.BackupDir, fs.Config.Suffix
+	fs.Config.BackupDir = r.FremoteName + "/backup"
+	fs.Config.Suffix = ".bak"
+	defer func() {
+		fs.Config.BackupDir, fs.Config.Suffix = oldBackupDir, oldSuffix
+	}()
+
+	file1 := r.WriteFile("file1", "file1 new contents", t2)
+	fstest.CheckItems(t, r.Flocal, file1)
+	file2 := r.WriteObject("dst/file2", "file2 old contents", t1)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+	err = operations.CopyFile(fdst, r.Flocal, "file2", file1.Path)
+	require.NoError(t, err)
+
+	file2new := file1
+	file2new.Path = "dst/file2"
+	file2backup := file2
+	file2backup.Path = "backup/file2.bak"
+	fstest.CheckItems(t, r.Fremote, file2new, file2backup)
+}
+
 // testFsInfo is for unit testing fs.Info
 type testFsInfo struct {
 	name      string
@@ -597,6 +868,18 @@ func TestSameConfig(t *testing.T) {
 	}
 }
 
+func TestSameRemoteType(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	a := &testFsInfo{name: "name", root: "root"}
+	assert.True(t, operations.SameRemoteType(a, &testFsInfo{name: "namey", root: "root"}))
+	assert.False(t, operations.SameRemoteType(a, r.Flocal))
+	assert.True(t, operations.SameRemoteType(r.Fremote, r.Flocal))
+
+	// local can't server side copy between remotes
+	assert.False(t, operations.ServerSideAcrossConfigs(r.Fremote, r.Flocal))
+}
+
 func TestSame(t *testing.T) {
 	a := &testFsInfo{name: "name", root: "root"}
 	for _, test := range []struct {
@@ -747,6 +1030,14 @@ func TestListFormat(t *testing.T) {
 	list.AddID()
 	_ = list.Format(items[0]) // Can't really check anything - at least it didn't panic!
 
+	list.SetOutput(nil)
+	list.AddOrigID()
+	list.AddID()
+	list.SetSeparator("|")
+	ids := strings.Split(list.Format(items[0]), "|")
+	assert.Equal(t, ids[1], ids[0], "not a wrapped object so the IDs should be the same")
+	list.SetSeparator("")
+
 	list.SetOutput(nil)
 	list.AddMimeType()
 	assert.Contains(t, list.Format(items[0]), "/")
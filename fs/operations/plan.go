@@ -0,0 +1,83 @@
+package operations
+
+// This writes the actions planned by a --dry-run as JSON lines for
+// --dry-run-json so they can be reviewed by a program.
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// Actions written by --dry-run-json
+const (
+	PlanCopy   = "copy"
+	PlanMove   = "move"
+	PlanDelete = "delete"
+	PlanSkip   = "skip"
+)
+
+// PlanEntry describes an action which would have been taken by a
+// --dry-run
+type PlanEntry struct {
+	Action string // PlanCopy, PlanMove, PlanDelete or PlanSkip
+	Src    string `json:",omitempty"` // the source file, eg "/path/file.txt"
+	Dst    string // the destination file, eg "remote:path/file.txt"
+	Size   int64  // size of the file
+	Reason string `json:",omitempty"` // why it was done or skipped
+}
+
+var (
+	planMu sync.Mutex
+
+	// PlanOutput is where --dry-run-json writes to
+	PlanOutput io.Writer = os.Stdout
+)
+
+// recordPlan writes entry to PlanOutput if --dry-run-json is set
+func recordPlan(entry PlanEntry) {
+	if !fs.Config.DryRunJSON {
+		return
+	}
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		fs.Errorf(nil, "Failed to make --dry-run-json entry: %v", err)
+		return
+	}
+	planMu.Lock()
+	defer planMu.Unlock()
+	_, err = PlanOutput.Write(append(data, '\n'))
+	if err != nil {
+		fs.Errorf(nil, "Failed to write --dry-run-json entry: %v", err)
+	}
+}
+
+// recordTransferPlan records that src would be copied or moved
+// (action) to remote on f replacing dst if set
+func recordTransferPlan(action string, f fs.Info, dst fs.Object, remote string, src fs.Object) {
+	reason := "new file"
+	if dst != nil {
+		reason = "replaces existing file"
+	}
+	recordPlan(PlanEntry{
+		Action: action,
+		Src:    fsString(src.Fs(), src.Remote()),
+		Dst:    fsString(f, remote),
+		Size:   src.Size(),
+		Reason: reason,
+	})
+}
+
+// recordSkipPlan records that src wasn't transferred to dst for reason
+func recordSkipPlan(src, dst fs.Object, reason string) {
+	recordPlan(PlanEntry{
+		Action: PlanSkip,
+		Src:    fsString(src.Fs(), src.Remote()),
+		Dst:    fsString(dst.Fs(), dst.Remote()),
+		Size:   src.Size(),
+		Reason: reason,
+	})
+}
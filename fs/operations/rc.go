@@ -0,0 +1,460 @@
+// Remote control calls for operations
+
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:          "operations/list",
+		Fn:            rcList,
+		Title:         "List the given remote and path in JSON format",
+		NeedsResponse: true,
+		Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+- remote - a path within that remote eg "dir"
+- opt - a dictionary of options to control the listing (optional)
+    - recurse - If set recurse directories
+    - noModTime - If set don't return modification time
+    - showHash - If set return a dictionary of hashes
+    - showOrigIDs - If set show the IDs of the underlying objects
+    - dirsOnly - If set only show directories
+    - filesOnly - If set only show files
+- maxItems - return at most this many items then a cursor (optional)
+- cursor - the "next" value from the previous call to get the next page (optional)
+- stream - if set stream the items as they are found (optional)
+
+The items are the same as those returned by "rclone lsjson".
+
+Without maxItems or stream this returns
+
+- list - array of items
+
+If maxItems is set then the listing is returned in pages of at most
+maxItems items.  If there are more items to come then "next" is set to
+a cursor which should be passed in as "cursor" along with maxItems to
+fetch the next page - fs and remote aren't needed.  Each cursor can
+only be used once and they expire if they aren't used for 10 minutes.
+
+The pages are read from the remote as they are asked for, so only one
+page is held in memory.  This means the items aren't sorted, and
+changes made to the remote while the pages are being read may or may
+not be seen, as with "rclone lsjson".
+
+If stream is set then the reply is sent as newline delimited JSON
+(content type application/x-ndjson) with one item per line as soon as
+it is read from the remote, so the items aren't sorted.  If an error
+occurs part way through then the last line will be a JSON object
+with an "error" key.  This is only available over HTTP, eg
+
+    curl -X POST 'http://localhost:5572/operations/list?fs=drive:&remote=dir&stream=true'
+`,
+	})
+
+	rc.Add(rc.Call{
+		Path:  "operations/about",
+		Fn:    rcAbout,
+		Title: "Return the space used on the remote",
+		Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+
+This returns the same values as "rclone about --json", eg "total",
+"used" and "free" - values the remote doesn't know are left out.
+
+See the [about command](/commands/rclone_about/) for more information.
+`,
+	})
+
+	for _, copy := range []bool{false, true} {
+		copy := copy
+		name := "Move"
+		if copy {
+			name = "Copy"
+		}
+		rc.Add(rc.Call{
+			Path: "operations/" + strings.ToLower(name) + "file",
+			Fn: func(in rc.Params) (rc.Params, error) {
+				return rcMoveOrCopyFile(in, copy)
+			},
+			Title: name + " a file from source remote to destination remote",
+			Help: `This takes the following parameters
+
+- srcFs - a remote name string eg "drive:" for the source
+- srcRemote - a path within that remote eg "file.txt" for the source
+- dstFs - a remote name string eg "drive2:" for the destination
+- dstRemote - a path within that remote eg "file2.txt" for the destination
+
+This returns
+
+- {} - empty dictionary on success
+`,
+		})
+	}
+
+	for _, op := range []struct {
+		name   string
+		title  string
+		remote string // example remote for the help
+	}{
+		{name: "mkdir", title: "Make a destination directory or container", remote: "dir"},
+		{name: "rmdir", title: "Remove an empty directory or container", remote: "dir"},
+		{name: "purge", title: "Remove a directory or container and all of its contents", remote: "dir"},
+		{name: "deletefile", title: "Remove the single file pointed to", remote: "dir/file.txt"},
+	} {
+		op := op
+		rc.Add(rc.Call{
+			Path: "operations/" + op.name,
+			Fn: func(in rc.Params) (rc.Params, error) {
+				return rcSingleCommand(in, op.name)
+			},
+			Title: op.title,
+			Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+- remote - a path within that remote eg "` + op.remote + `"
+
+This returns
+
+- {} - empty dictionary on success
+
+See the [` + op.name + ` command](/commands/rclone_` + op.name + `/) for more
+information on the above.
+`,
+		})
+	}
+}
+
+// Return the about info for the remote
+func rcAbout(in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(in)
+	if err != nil {
+		return nil, err
+	}
+	doAbout := f.Features().About
+	if doAbout == nil {
+		return nil, errors.Errorf("%v doesn't support about", f)
+	}
+	u, err := doAbout()
+	if err != nil {
+		return nil, errors.Wrap(err, "about call failed")
+	}
+	err = rc.Reshape(&out, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "about Reshape failed")
+	}
+	return out, nil
+}
+
+// Move or copy a file from srcFs:srcRemote to dstFs:dstRemote
+func rcMoveOrCopyFile(in rc.Params, cp bool) (out rc.Params, err error) {
+	srcFs, err := rc.GetFsNamed(in, "srcFs")
+	if err != nil {
+		return nil, err
+	}
+	srcRemote, err := in.GetString("srcRemote")
+	if err != nil {
+		return nil, err
+	}
+	dstFs, err := rc.GetFsNamed(in, "dstFs")
+	if err != nil {
+		return nil, err
+	}
+	dstRemote, err := in.GetString("dstRemote")
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, moveOrCopyFile(dstFs, srcFs, dstRemote, srcRemote, cp)
+}
+
+// Run a single command, eg Mkdir, on fs:remote
+func rcSingleCommand(in rc.Params, name string) (out rc.Params, err error) {
+	f, err := rc.GetFs(in)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := in.GetString("remote")
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "mkdir":
+		err = Mkdir(f, remote)
+	case "rmdir":
+		err = Rmdir(f, remote)
+	case "purge":
+		err = Purge(f, remote)
+	case "deletefile":
+		var o fs.Object
+		o, err = f.NewObject(remote)
+		if err == fs.ErrorObjectNotFound {
+			return nil, errors.Errorf("%q doesn't exist", remote)
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to find %q", remote)
+		}
+		err = DeleteFile(o)
+	default:
+		return nil, errors.Errorf("unknown command %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, nil
+}
+
+// the number of items streamed between flushes of the response
+const listStreamFlushEvery = 100
+
+// how long an unused listing cursor lasts
+const listCursorExpiry = 10 * time.Minute
+
+// listCursor is a listing being returned in pages as it is read
+type listCursor struct {
+	mu       sync.Mutex         // held while a page is read
+	token    string             // the token identifying the cursor
+	items    chan *ListJSONItem // the items as they are listed
+	err      error              // the listing error - read after items is closed
+	next     *ListJSONItem      // the first item of the next page if read
+	returned int                // number of items returned so far
+	cancel   func()             // call to stop the listing
+	expiry   *time.Timer        // removes the cursor if it isn't used
+}
+
+// listCursors holds the listings being returned in pages indexed by
+// cursor token
+var listCursors = struct {
+	mu      sync.Mutex
+	cursors map[string]*listCursor
+}{
+	cursors: make(map[string]*listCursor),
+}
+
+// newListCursor starts listing remote on f in the background
+// returning a cursor to read the items from.
+//
+// The listing stops when the items are read faster than they are
+// listed, so only the items for the page being read are held in
+// memory.
+func newListCursor(f fs.Fs, remote string, opt *ListJSONOpt) (*listCursor, error) {
+	var buf [16]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make cursor")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &listCursor{
+		token:  hex.EncodeToString(buf[:]),
+		items:  make(chan *ListJSONItem),
+		cancel: cancel,
+	}
+	go func() {
+		c.err = ListJSON(f, remote, opt, func(item *ListJSONItem) error {
+			select {
+			case c.items <- item:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(c.items)
+	}()
+	listCursors.mu.Lock()
+	listCursors.cursors[c.token] = c
+	listCursors.mu.Unlock()
+	c.expiry = time.AfterFunc(listCursorExpiry, c.remove)
+	return c, nil
+}
+
+// getListCursor returns the cursor for token
+func getListCursor(token string) (*listCursor, error) {
+	listCursors.mu.Lock()
+	defer listCursors.mu.Unlock()
+	c, ok := listCursors.cursors[token]
+	if !ok {
+		return nil, errors.New("cursor not found or expired")
+	}
+	return c, nil
+}
+
+// remove stops the listing and forgets the cursor
+func (c *listCursor) remove() {
+	listCursors.mu.Lock()
+	delete(listCursors.cursors, c.token)
+	listCursors.mu.Unlock()
+	c.expiry.Stop()
+	c.cancel()
+}
+
+// page reads the next page of at most maxItems items, or all the
+// remaining items if maxItems is 0, returning the cursor for the page
+// after if there is one.
+//
+// offset must be the number of items returned so far which stops
+// pages being skipped if a cursor is used twice.  The cursor is
+// removed when the last page is returned or there is an error.
+func (c *listCursor) page(offset, maxItems int) (out rc.Params, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset != c.returned {
+		return nil, errors.New("cursor has already been used")
+	}
+	c.expiry.Reset(listCursorExpiry)
+	items := []*ListJSONItem{}
+	if c.next != nil {
+		items = append(items, c.next)
+		c.next = nil
+	}
+	more := true
+	for more && (maxItems == 0 || len(items) < maxItems) {
+		var item *ListJSONItem
+		item, more = <-c.items
+		if more {
+			items = append(items, item)
+		}
+	}
+	// Read the first item of the next page to see if there is one
+	if more {
+		c.next, more = <-c.items
+	}
+	if !more {
+		c.remove()
+		if c.err != nil {
+			return nil, c.err
+		}
+	}
+	c.returned += len(items)
+	out = rc.Params{"list": items}
+	if more {
+		out["next"] = fmt.Sprintf("%s-%d", c.token, c.returned)
+	}
+	return out, nil
+}
+
+// parseCursor parses a cursor of the form token-offset
+func parseCursor(cursor string) (token string, offset int, err error) {
+	i := strings.LastIndex(cursor, "-")
+	if i < 0 {
+		return "", 0, errors.Errorf("bad cursor %q", cursor)
+	}
+	offset, err = strconv.Atoi(cursor[i+1:])
+	if err != nil || offset < 0 {
+		return "", 0, errors.Errorf("bad cursor %q", cursor)
+	}
+	return cursor[:i], offset, nil
+}
+
+// List the directory
+func rcList(in rc.Params) (out rc.Params, err error) {
+	var opt ListJSONOpt
+	err = in.GetStruct("opt", &opt)
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	stream, err := in.GetBool("stream")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	maxItems, err := in.GetInt64("maxItems")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	if maxItems < 0 {
+		return nil, errors.New("maxItems must be positive")
+	}
+	cursor, err := in.GetString("cursor")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	if stream && (maxItems > 0 || cursor != "") {
+		return nil, errors.New("can't use stream with maxItems or cursor")
+	}
+
+	// Carry on with a paged listing
+	if cursor != "" {
+		token, offset, err := parseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		c, err := getListCursor(token)
+		if err != nil {
+			return nil, err
+		}
+		return c.page(offset, int(maxItems))
+	}
+
+	f, err := rc.GetFs(in)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := in.GetString("remote")
+	if err != nil {
+		return nil, err
+	}
+
+	if stream {
+		w, ok := in["_response"].(http.ResponseWriter)
+		if !ok {
+			return nil, errors.New("stream is only supported over HTTP")
+		}
+		rcListStream(w, f, remote, &opt)
+		return nil, nil
+	}
+
+	if maxItems > 0 {
+		c, err := newListCursor(f, remote, &opt)
+		if err != nil {
+			return nil, err
+		}
+		return c.page(0, int(maxItems))
+	}
+
+	var items = []*ListJSONItem{}
+	err = ListJSON(f, remote, &opt, func(item *ListJSONItem) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{"list": items}, nil
+}
+
+// rcListStream writes the listing to w as newline delimited JSON,
+// finishing with an error object if the listing failed.
+func rcListStream(w http.ResponseWriter, f fs.Fs, remote string, opt *ListJSONOpt) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	n := 0
+	err := ListJSON(f, remote, opt, func(item *ListJSONItem) error {
+		err := enc.Encode(item)
+		if err != nil {
+			return err
+		}
+		n++
+		if flusher != nil && n%listStreamFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		fs.Errorf(f, "rc: operations/list: stream error: %v", err)
+		_ = enc.Encode(rc.Params{"error": err.Error()})
+	}
+}
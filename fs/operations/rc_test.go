@@ -0,0 +1,253 @@
+package operations_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rcListSetup makes some files for the operations/list tests
+func rcListSetup(t *testing.T) (*fstest.Run, *rc.Call) {
+	r := fstest.NewRun(t)
+	file1 := r.WriteObject("a", "a", t1)
+	file2 := r.WriteObject("b", "bb", t1)
+	file3 := r.WriteObject("c", "ccc", t1)
+	file4 := r.WriteObject("dir/d", "dddd", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+	call := rc.Get("operations/list")
+	require.NotNil(t, call)
+	return r, call
+}
+
+// rcListPaths returns the paths of the items in the list in out
+func rcListPaths(t *testing.T, out rc.Params) (paths []string) {
+	items, ok := out["list"].([]*operations.ListJSONItem)
+	require.True(t, ok, "list is %T", out["list"])
+	for _, item := range items {
+		paths = append(paths, item.Path)
+	}
+	return paths
+}
+
+func TestRcList(t *testing.T) {
+	r, call := rcListSetup(t)
+	defer r.Finalise()
+
+	out, err := call.Fn(rc.Params{
+		"fs":     r.FremoteName,
+		"remote": "",
+		"opt":    map[string]interface{}{"recurse": true, "filesOnly": true},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "dir/d"}, rcListPaths(t, out))
+	assert.NotContains(t, out, "next")
+
+	_, err = call.Fn(rc.Params{"remote": ""})
+	assert.Error(t, err)
+}
+
+func TestRcListPaging(t *testing.T) {
+	r, call := rcListSetup(t)
+	defer r.Finalise()
+
+	in := rc.Params{
+		"fs":       r.FremoteName,
+		"remote":   "",
+		"maxItems": 2,
+	}
+	var paths []string
+	for i := 0; ; i++ {
+		require.True(t, i < 3, "too many pages")
+		out, err := call.Fn(in)
+		require.NoError(t, err)
+		page := rcListPaths(t, out)
+		assert.True(t, len(page) <= 2)
+		paths = append(paths, page...)
+		next, ok := out["next"]
+		if !ok {
+			break
+		}
+		in = rc.Params{"cursor": next, "maxItems": 2}
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c", "dir"}, paths)
+
+	// the cursor is removed after the last page
+	_, err := call.Fn(in)
+	assert.Error(t, err)
+
+	_, err = call.Fn(rc.Params{"cursor": "potato", "maxItems": 2})
+	assert.Error(t, err)
+
+	// a cursor can't be used twice
+	out, err := call.Fn(rc.Params{"fs": r.FremoteName, "remote": "", "maxItems": 1})
+	require.NoError(t, err)
+	next := out["next"]
+	_, err = call.Fn(rc.Params{"cursor": next, "maxItems": 1})
+	require.NoError(t, err)
+	_, err = call.Fn(rc.Params{"cursor": next, "maxItems": 1})
+	assert.Error(t, err)
+}
+
+func TestRcListStream(t *testing.T) {
+	r, call := rcListSetup(t)
+	defer r.Finalise()
+
+	w := httptest.NewRecorder()
+	out, err := call.Fn(rc.Params{
+		"fs":        r.FremoteName,
+		"remote":    "",
+		"stream":    "true",
+		"opt":       `{"recurse":true}`,
+		"_response": w,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, out)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var paths []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var item operations.ListJSONItem
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &item))
+		paths = append(paths, item.Path)
+	}
+	require.NoError(t, scanner.Err())
+	assert.ElementsMatch(t, []string{"a", "b", "c", "dir", "dir/d"}, paths)
+
+	// stream needs an HTTP response to write to
+	_, err = call.Fn(rc.Params{
+		"fs":     r.FremoteName,
+		"remote": "",
+		"stream": true,
+	})
+	assert.Error(t, err)
+}
+
+func TestRcAbout(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	call := rc.Get("operations/about")
+	require.NotNil(t, call)
+	r.WriteFile("file1", "file1 contents", t1)
+
+	out, err := call.Fn(rc.Params{"fs": r.LocalName})
+	if err != nil {
+		assert.Contains(t, err.Error(), "doesn't support about")
+		return
+	}
+	assert.Contains(t, out, "total")
+	assert.Contains(t, out, "free")
+}
+
+func TestRcCopyFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	call := rc.Get("operations/copyfile")
+	require.NotNil(t, call)
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	out, err := call.Fn(rc.Params{
+		"srcFs":     r.LocalName,
+		"srcRemote": "file1",
+		"dstFs":     r.FremoteName,
+		"dstRemote": "file1-renamed",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+
+	file2 := file1
+	file2.Path = "file1-renamed"
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file2)
+}
+
+func TestRcMoveFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	call := rc.Get("operations/movefile")
+	require.NotNil(t, call)
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	out, err := call.Fn(rc.Params{
+		"srcFs":     r.LocalName,
+		"srcRemote": "file1",
+		"dstFs":     r.FremoteName,
+		"dstRemote": "file1-renamed",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+
+	file2 := file1
+	file2.Path = "file1-renamed"
+	fstest.CheckItems(t, r.Flocal)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	_, err = call.Fn(rc.Params{"srcFs": r.LocalName, "srcRemote": "file1"})
+	assert.Error(t, err)
+}
+
+func TestRcMkdirRmdirPurge(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	mkdir := rc.Get("operations/mkdir")
+	rmdir := rc.Get("operations/rmdir")
+	purge := rc.Get("operations/purge")
+	require.NotNil(t, mkdir)
+	require.NotNil(t, rmdir)
+	require.NotNil(t, purge)
+
+	out, err := mkdir.Fn(rc.Params{"fs": r.FremoteName, "remote": "subdir"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{"subdir"}, fs.GetModifyWindow(r.Fremote))
+
+	_, err = rmdir.Fn(rc.Params{"fs": r.FremoteName, "remote": "subdir"})
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{}, fs.GetModifyWindow(r.Fremote))
+
+	file1 := r.WriteObject("subdir/file1", "subdir/file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// rmdir only removes empty directories
+	_, err = rmdir.Fn(rc.Params{"fs": r.FremoteName, "remote": "subdir"})
+	assert.Error(t, err)
+
+	_, err = purge.Fn(rc.Params{"fs": r.FremoteName, "remote": "subdir"})
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{}, []string{}, fs.GetModifyWindow(r.Fremote))
+
+	_, err = mkdir.Fn(rc.Params{"fs": r.FremoteName})
+	assert.Error(t, err)
+}
+
+func TestRcDeleteFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	call := rc.Get("operations/deletefile")
+	require.NotNil(t, call)
+
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	file2 := r.WriteObject("file2", "file2 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	out, err := call.Fn(rc.Params{"fs": r.FremoteName, "remote": "file1"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	_, err = call.Fn(rc.Params{"fs": r.FremoteName, "remote": "file1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't exist")
+}
@@ -0,0 +1,153 @@
+// Saved state for resuming interrupted uploads
+
+package operations
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+)
+
+// ResumeMaxAge is how long the state of an upload is kept for.  Most
+// providers expire upload sessions after about a week.
+var ResumeMaxAge = 7 * 24 * time.Hour
+
+// resumeDir returns the directory the state of uploads is kept in.
+// This is worked out each time as --cache-dir may change it.
+func resumeDir() string {
+	return filepath.Join(config.CacheDir, "resume")
+}
+
+// resumeFile is what is stored for each upload
+type resumeFile struct {
+	Remote      string          // remote:path of the upload
+	Fingerprint string          // identifies the source being uploaded
+	Saved       time.Time       // when this was saved
+	State       json.RawMessage // backend specific state
+}
+
+// ResumeUpload stores the state of a large upload on disk so it can
+// carry on where it left off if it is interrupted, even by rclone
+// being stopped, rather than starting again.
+//
+// Backends which upload in chunks to a session or upload ID should
+// call NewResumeUpload before starting, Load to see if there is an
+// upload to resume, Save with the state (eg the session URL and the
+// parts uploaded) as it changes and Remove when the upload is
+// finished or can't be resumed.
+//
+// It does nothing unless --resume-uploads is set.  All the methods
+// may be called on a nil *ResumeUpload.
+type ResumeUpload struct {
+	remote      string
+	fingerprint string
+	path        string
+}
+
+// NewResumeUpload returns the ResumeUpload for remote on f, or nil if
+// --resume-uploads isn't set.
+//
+// fingerprint should identify the source being uploaded, eg its size
+// and modification time, so a changed source isn't resumed.
+func NewResumeUpload(f fs.Info, remote, fingerprint string) *ResumeUpload {
+	if !fs.Config.ResumeUploads {
+		return nil
+	}
+	name := fsString(f, remote)
+	sum := sha1.Sum([]byte(name))
+	return &ResumeUpload{
+		remote:      name,
+		fingerprint: fingerprint,
+		path:        filepath.Join(resumeDir(), hex.EncodeToString(sum[:])+".json"),
+	}
+}
+
+// Load reads the saved state into state returning true if there was
+// an upload of the same source to resume.
+func (r *ResumeUpload) Load(state interface{}) bool {
+	if r == nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err != nil {
+		fs.Errorf(r.remote, "Failed to read upload state: %v", err)
+		return false
+	}
+	var saved resumeFile
+	err = json.Unmarshal(data, &saved)
+	if err == nil {
+		err = json.Unmarshal(saved.State, state)
+	}
+	switch {
+	case err != nil:
+		fs.Errorf(r.remote, "Ignoring corrupted upload state: %v", err)
+	case saved.Remote != r.remote || saved.Fingerprint != r.fingerprint:
+		fs.Debugf(r.remote, "Not resuming upload as the source has changed")
+	case time.Since(saved.Saved) > ResumeMaxAge:
+		fs.Debugf(r.remote, "Not resuming upload as it is too old")
+	default:
+		fs.Debugf(r.remote, "Found upload to resume")
+		return true
+	}
+	r.Remove()
+	return false
+}
+
+// Save stores state so the upload can be resumed
+func (r *ResumeUpload) Save(state interface{}) {
+	if r == nil {
+		return
+	}
+	err := r.save(state)
+	if err != nil {
+		fs.Errorf(r.remote, "Failed to save upload state: %v", err)
+	}
+}
+
+// save stores state atomically
+func (r *ResumeUpload) save(state interface{}) error {
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&resumeFile{
+		Remote:      r.remote,
+		Fingerprint: r.fingerprint,
+		Saved:       time.Now(),
+		State:       stateData,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(r.path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// Remove removes the saved state
+func (r *ResumeUpload) Remove() {
+	if r == nil {
+		return
+	}
+	err := os.Remove(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(r.remote, "Failed to remove upload state: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package operations
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-resume-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldCacheDir, oldResumeUploads := config.CacheDir, fs.Config.ResumeUploads
+	config.CacheDir = dir
+	defer func() {
+		config.CacheDir, fs.Config.ResumeUploads = oldCacheDir, oldResumeUploads
+	}()
+
+	type state struct {
+		URI string
+	}
+	var got state
+
+	// does nothing unless --resume-uploads
+	fs.Config.ResumeUploads = false
+	r := NewResumeUpload(object.MemoryFs, "file", "1")
+	assert.Nil(t, r)
+	r.Save(&state{URI: "potato"})
+	assert.False(t, r.Load(&got))
+	r.Remove()
+
+	fs.Config.ResumeUploads = true
+	r = NewResumeUpload(object.MemoryFs, "file", "1")
+	require.NotNil(t, r)
+	assert.False(t, r.Load(&got))
+	r.Save(&state{URI: "potato"})
+	assert.True(t, NewResumeUpload(object.MemoryFs, "file", "1").Load(&got))
+	assert.Equal(t, "potato", got.URI)
+	// in the cache directory set when it was saved
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "resume"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+
+	// a different file or source isn't resumed
+	assert.False(t, NewResumeUpload(object.MemoryFs, "file2", "1").Load(&got))
+	assert.False(t, NewResumeUpload(object.MemoryFs, "file", "2").Load(&got))
+	// and the state was removed as the source changed
+	assert.False(t, NewResumeUpload(object.MemoryFs, "file", "1").Load(&got))
+
+	// old state isn't resumed
+	r.Save(&state{URI: "potato"})
+	oldResumeMaxAge := ResumeMaxAge
+	ResumeMaxAge = -time.Second
+	assert.False(t, r.Load(&got))
+	ResumeMaxAge = oldResumeMaxAge
+
+	r.Save(&state{URI: "potato"})
+	r.Remove()
+	assert.False(t, r.Load(&got))
+	entries, err = ioutil.ReadDir(filepath.Join(dir, "resume"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
@@ -0,0 +1,88 @@
+// Search for objects and directories by name
+
+package operations
+
+import (
+	"path"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/walk"
+)
+
+// searchMatch returns true if the leaf name of entry contains query
+// ignoring case
+func searchMatch(entry fs.DirEntry, query string) bool {
+	return strings.Contains(strings.ToLower(path.Base(entry.Remote())), strings.ToLower(query))
+}
+
+// Search calls fn for each object and directory under dir in f whose
+// name contains query, ignoring case.  It obeys includes and
+// excludes.
+//
+// If useSearch is set and the remote supports the Search feature
+// then the provider's search API is used, which is much quicker than
+// listing large remotes.  Otherwise the remote is listed recursively
+// and the names are matched locally.
+func Search(f fs.Fs, dir, query string, useSearch bool, fn func(entry fs.DirEntry) error) error {
+	doSearch := f.Features().Search
+	if !useSearch || doSearch == nil {
+		fs.Debugf(f, "Searching by listing the remote")
+		return walk.Walk(f, dir, false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(dirPath, "error listing: %v", err)
+				return nil
+			}
+			for _, entry := range entries {
+				if searchMatch(entry, query) {
+					err = fn(entry)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	}
+	fs.Debugf(f, "Searching using the remote's search API")
+	includeDirectory := filter.Active.IncludeDirectory(f)
+	return doSearch(dir, query, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if !searchMatch(entry, query) {
+				continue
+			}
+			if fs.Config.MaxDepth >= 0 {
+				relative := entry.Remote()
+				if dir != "" {
+					relative = strings.TrimPrefix(relative, dir+"/")
+				}
+				if strings.Count(relative, "/")+1 > fs.Config.MaxDepth {
+					continue
+				}
+			}
+			switch x := entry.(type) {
+			case fs.Object:
+				if !filter.Active.IncludeObject(x) {
+					fs.Debugf(x, "Excluded from search")
+					continue
+				}
+			case fs.Directory:
+				include, err := includeDirectory(x.Remote())
+				if err != nil {
+					return err
+				}
+				if !include {
+					fs.Debugf(x, "Excluded from search")
+					continue
+				}
+			}
+			err := fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
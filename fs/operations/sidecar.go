@@ -0,0 +1,146 @@
+// Hash sidecars for remotes without a hash in common with the source
+
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/list"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+)
+
+// maxSidecarSize is the largest sidecar that will be read
+const maxSidecarSize = 4096
+
+// needsSidecar returns true if a sidecar should be stored for src
+// when it is uploaded to f
+func needsSidecar(f fs.Fs, src fs.ObjectInfo) bool {
+	if !fs.Config.HashSidecar {
+		return false
+	}
+	srcHashes := src.Fs().Hashes()
+	return srcHashes.Count() > 0 && srcHashes.Overlap(f.Hashes()).Count() == 0
+}
+
+// writeSidecar stores the size and hashes of src in a sidecar beside
+// remote on f
+func writeSidecar(f fs.Fs, remote string, src fs.ObjectInfo) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "size %d\n", src.Size())
+	for _, ht := range src.Fs().Hashes().Array() {
+		sum, err := src.Hash(ht)
+		if err == hash.ErrUnsupported {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %v", ht)
+		}
+		if sum != "" {
+			fmt.Fprintf(&buf, "%v %s\n", ht, sum)
+		}
+	}
+	sidecarRemote := remote + list.HashSidecarSuffix
+	info := object.NewStaticObjectInfo(sidecarRemote, time.Now(), int64(buf.Len()), true, nil, f)
+	_, err := f.Put(&buf, info)
+	list.Changed(f, sidecarRemote)
+	return err
+}
+
+// readSidecar reads the size and hashes stored in the sidecar for dst
+func readSidecar(dst fs.Object) (size int64, sums map[hash.Type]string, err error) {
+	f, ok := dst.Fs().(fs.Fs)
+	if !ok {
+		return 0, nil, errors.New("can't find sidecar of object without an Fs")
+	}
+	o, err := f.NewObject(dst.Remote() + list.HashSidecarSuffix)
+	if err != nil {
+		return 0, nil, err
+	}
+	in, err := o.Open()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	size = -1
+	sums = make(map[hash.Type]string)
+	scanner := bufio.NewScanner(io.LimitReader(in, maxSidecarSize))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == "size" {
+			size, err = strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "bad size in sidecar")
+			}
+			continue
+		}
+		var ht hash.Type
+		if ht.Set(fields[0]) == nil {
+			sums[ht] = fields[1]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, nil, err
+	}
+	return size, sums, nil
+}
+
+// sidecarHashes finds a hash of src which is stored in the sidecar of
+// dst.  It returns hash.None if there isn't one or the sidecar is out
+// of date.
+func sidecarHashes(src fs.ObjectInfo, dst fs.Object) (ht hash.Type, srcHash, dstHash string, err error) {
+	size, sums, err := readSidecar(dst)
+	if err == fs.ErrorObjectNotFound {
+		return hash.None, "", "", nil
+	}
+	if err != nil {
+		return hash.None, "", "", errors.Wrap(err, "failed to read hash sidecar")
+	}
+	if size != dst.Size() {
+		fs.Debugf(dst, "Ignoring out of date hash sidecar (size %d vs %d)", size, dst.Size())
+		return hash.None, "", "", nil
+	}
+	for _, ht = range src.Fs().Hashes().Array() {
+		dstHash = sums[ht]
+		if dstHash == "" {
+			continue
+		}
+		srcHash, err = src.Hash(ht)
+		if err != nil {
+			return ht, "", "", errors.Wrap(err, "failed to calculate src hash")
+		}
+		if srcHash != "" {
+			return ht, srcHash, dstHash, nil
+		}
+	}
+	return hash.None, "", "", nil
+}
+
+// removeSidecar removes the sidecar of dst if there is one
+func removeSidecar(dst fs.Object) {
+	f, ok := dst.Fs().(fs.Fs)
+	if !ok {
+		return
+	}
+	sidecarRemote := dst.Remote() + list.HashSidecarSuffix
+	o, err := f.NewObject(sidecarRemote)
+	if err != nil {
+		return
+	}
+	err = o.Remove()
+	list.Changed(f, sidecarRemote)
+	if err != nil {
+		fs.Errorf(dst, "Failed to remove hash sidecar: %v", err)
+	}
+}
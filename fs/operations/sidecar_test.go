@@ -0,0 +1,74 @@
+package operations
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/list"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-sidecar-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	oldHashSidecar := fs.Config.HashSidecar
+	fs.Config.HashSidecar = true
+	defer func() { fs.Config.HashSidecar = oldHashSidecar }()
+
+	put := func(remote, content string) fs.Object {
+		info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(content)), true, nil, f)
+		o, err := f.Put(bytes.NewBufferString(content), info)
+		require.NoError(t, err)
+		return o
+	}
+	const md5sum = "5d41402abc4b2a76b9719d911017c592" // of "hello"
+	src := object.NewStaticObjectInfo("file", time.Now(), 5, true, map[hash.Type]string{hash.MD5: md5sum}, object.MemoryFs)
+	dst := put("file", "hello")
+
+	// nothing stored yet
+	ht, _, _, err := sidecarHashes(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, hash.None, ht)
+
+	require.NoError(t, writeSidecar(f, "file", src))
+	size, sums, err := readSidecar(dst)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+	assert.Equal(t, map[hash.Type]string{hash.MD5: md5sum}, sums)
+
+	ht, srcHash, dstHash, err := sidecarHashes(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, hash.MD5, ht)
+	assert.Equal(t, md5sum, srcHash)
+	assert.Equal(t, md5sum, dstHash)
+
+	// sidecars are hidden from listings
+	entries, err := list.DirSorted(f, false, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "file", entries[0].Remote())
+
+	// a sidecar for a different size is ignored
+	dst = put("file", "hello world")
+	ht, _, _, err = sidecarHashes(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, hash.None, ht)
+
+	removeSidecar(dst)
+	_, err = f.NewObject("file" + list.HashSidecarSuffix)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
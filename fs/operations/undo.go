@@ -0,0 +1,199 @@
+package operations
+
+// This records the files deleted or overwritten by a sync so they can
+// be reported at the end and restored from --backup-dir with rclone
+// undo.
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fspath"
+	"github.com/pkg/errors"
+)
+
+// Actions recorded in the undo file
+const (
+	UndoDeleted     = "deleted"
+	UndoOverwritten = "overwritten"
+)
+
+// UndoEntry describes a file which was deleted or overwritten
+type UndoEntry struct {
+	Action  string    // UndoDeleted or UndoOverwritten
+	Remote  string    // where the file was, eg "remote:path/file.txt"
+	Backup  string    `json:",omitempty"` // where it was moved to with --backup-dir, or "" if it is gone
+	Size    int64     // size of the file
+	ModTime time.Time // modification time of the file
+}
+
+// UndoFile is the contents of the file written by --undo-file
+type UndoFile struct {
+	Time    time.Time   // when it was written
+	Entries []UndoEntry // in the order they happened
+}
+
+var (
+	undoMu      sync.Mutex
+	undoEntries []UndoEntry
+)
+
+// fsString returns remote in f as a string which can be passed to
+// fs.NewFs, eg "remote:path/file.txt"
+func fsString(f fs.Info, remote string) string {
+	p := path.Join(f.Root(), remote)
+	if f.Name() == "local" {
+		return p
+	}
+	return f.Name() + ":" + p
+}
+
+// recordUndo records that dst was deleted or overwritten (action)
+// and moved to backupRemote in backupDir if backupDir is set.
+func recordUndo(action string, dst fs.Object, backupDir fs.Fs, backupRemote string) {
+	entry := UndoEntry{
+		Action:  action,
+		Remote:  fsString(dst.Fs(), dst.Remote()),
+		Size:    dst.Size(),
+		ModTime: dst.ModTime(),
+	}
+	if backupDir != nil {
+		entry.Backup = fsString(backupDir, backupRemote)
+	}
+	undoMu.Lock()
+	undoEntries = append(undoEntries, entry)
+	undoMu.Unlock()
+}
+
+// RecordOverwrite records that dst was overwritten after being moved
+// to backupRemote in backupDir
+func RecordOverwrite(dst fs.Object, backupDir fs.Fs, backupRemote string) {
+	recordUndo(UndoOverwritten, dst, backupDir, backupRemote)
+}
+
+// UndoMark returns a mark to pass to UndoReport so that it only
+// reports the files deleted or overwritten after the mark.
+func UndoMark() int {
+	undoMu.Lock()
+	defer undoMu.Unlock()
+	return len(undoEntries)
+}
+
+// UndoReport logs how many files were deleted or overwritten in f
+// since mark and writes them to the --undo-file if set.
+func UndoReport(f fs.Info, mark int) error {
+	undoMu.Lock()
+	entries := append([]UndoEntry(nil), undoEntries[mark:]...)
+	undoMu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+	var deleted, overwritten, backedUp int
+	for _, entry := range entries {
+		if entry.Action == UndoDeleted {
+			deleted++
+		} else {
+			overwritten++
+		}
+		if entry.Backup != "" {
+			backedUp++
+		}
+	}
+	fs.Logf(f, "Deleted %d files and overwritten %d files of which %d were moved to --backup-dir", deleted, overwritten, backedUp)
+	if fs.Config.UndoFile == "" {
+		return nil
+	}
+	err := WriteUndoFile(fs.Config.UndoFile, &UndoFile{
+		Time:    time.Now(),
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	fs.Infof(f, "Wrote undo file %q", fs.Config.UndoFile)
+	return nil
+}
+
+// WriteUndoFile writes u to the local file name
+func WriteUndoFile(name string, u *UndoFile) error {
+	data, err := json.MarshalIndent(u, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode undo file")
+	}
+	err = ioutil.WriteFile(name, data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write undo file")
+	}
+	return nil
+}
+
+// ReadUndoFile reads the local undo file name
+func ReadUndoFile(name string) (*UndoFile, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read undo file")
+	}
+	u := new(UndoFile)
+	err = json.Unmarshal(data, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode undo file")
+	}
+	return u, nil
+}
+
+// newFsFile makes an Fs for the directory remote is in and returns
+// it with the leaf name of remote
+func newFsFile(remote string) (f fs.Fs, leaf string, err error) {
+	parent, leaf := fspath.Split(remote)
+	if parent == "" {
+		parent = "."
+	}
+	f, err = fs.NewFs(parent)
+	return f, leaf, err
+}
+
+// Undo restores the files in u which were moved to --backup-dir by
+// moving them back, most recent first.
+//
+// Files which were deleted without --backup-dir can't be restored so
+// are logged and counted as errors.
+func Undo(u *UndoFile) error {
+	errorCount := 0
+	for i := len(u.Entries) - 1; i >= 0; i-- {
+		entry := u.Entries[i]
+		if entry.Backup == "" {
+			fs.Errorf(entry.Remote, "Can't restore as it was %s without --backup-dir", entry.Action)
+			errorCount++
+			continue
+		}
+		err := undoEntry(entry)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(entry.Remote, "Failed to restore from %q: %v", entry.Backup, err)
+			errorCount++
+			continue
+		}
+		fs.Infof(entry.Remote, "Restored from %q", entry.Backup)
+	}
+	if errorCount > 0 {
+		return errors.Errorf("failed to restore %d files", errorCount)
+	}
+	return nil
+}
+
+// undoEntry moves a single file back from --backup-dir
+func undoEntry(entry UndoEntry) error {
+	fdst, dstLeaf, err := newFsFile(entry.Remote)
+	if err != nil {
+		return err
+	}
+	fsrc, srcLeaf, err := newFsFile(entry.Backup)
+	if err != nil {
+		return err
+	}
+	return MoveFile(fdst, fsrc, dstLeaf, srcLeaf)
+}
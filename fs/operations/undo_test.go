@@ -0,0 +1,60 @@
+package operations_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndo(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+	backupDir, err := fs.NewFs(r.FremoteName + "/backup")
+	require.NoError(t, err)
+
+	file1 := r.WriteObject("dst/one", "one", t1)
+	file2 := r.WriteObject("dst/two", "two", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	mark := operations.UndoMark()
+	obj, err := fdst.NewObject("one")
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFileWithBackupDir(obj, backupDir))
+	obj, err = fdst.NewObject("two")
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFile(obj))
+
+	tempDir, err := ioutil.TempDir("", "rclone-undo")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	undoFile := filepath.Join(tempDir, "undo.json")
+	oldUndoFile := fs.Config.UndoFile
+	fs.Config.UndoFile = undoFile
+	defer func() { fs.Config.UndoFile = oldUndoFile }()
+	require.NoError(t, operations.UndoReport(fdst, mark))
+
+	u, err := operations.ReadUndoFile(undoFile)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(u.Entries))
+	assert.Equal(t, operations.UndoDeleted, u.Entries[0].Action)
+	assert.Equal(t, int64(3), u.Entries[0].Size)
+	assert.NotEqual(t, "", u.Entries[0].Backup)
+	assert.Equal(t, "", u.Entries[1].Backup)
+	fstest.CheckItems(t, r.Fremote, fstest.NewItem("backup/one", "one", t1))
+
+	// Only the file in the backup dir can be restored
+	err = operations.Undo(u)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to restore 1 files")
+	fstest.CheckItems(t, r.Fremote, file1)
+}
@@ -0,0 +1,91 @@
+// Wait for uploads to be visible on eventually consistent remotes
+
+package operations
+
+import (
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// How long to sleep between looking for the uploaded object - these
+// are variables so the tests can change them
+var (
+	waitVisibleMinSleep = 100 * time.Millisecond
+	waitVisibleMaxSleep = 5 * time.Second
+)
+
+// waitVisible waits for up to fs.Config.WaitVisible for the object
+// just uploaded to remote on f to be found with the size of src and
+// its hash, or its modification time if there is no hash in common.
+//
+// On eventually consistent remotes an object may not be returned by
+// NewObject, or the old version may be, for a while after it was
+// uploaded.  This stops the operations which follow the upload, eg a
+// check, from failing.
+func waitVisible(f fs.Fs, remote string, src fs.ObjectInfo) error {
+	if fs.Config.WaitVisible <= 0 {
+		return nil
+	}
+	// Read the hash of src once as it may be expensive
+	hashType, srcSum := hash.None, ""
+	common := f.Hashes()
+	if src.Fs() != nil {
+		common = common.Overlap(src.Fs().Hashes())
+	}
+	if common.Count() > 0 {
+		hashType = common.GetOne()
+		srcSum, _ = src.Hash(hashType)
+	}
+	deadline := time.Now().Add(fs.Config.WaitVisible)
+	sleep := waitVisibleMinSleep
+	for {
+		o, err := f.NewObject(remote)
+		if err == nil {
+			err = checkVisible(f, o, src, hashType, srcSum)
+			if err == nil {
+				return nil
+			}
+		}
+		if time.Now().Add(sleep).After(deadline) {
+			return errors.Wrapf(err, "not visible after upload in %v", fs.Config.WaitVisible)
+		}
+		fs.Debugf(src, "Waiting %v for upload to be visible: %v", sleep, err)
+		time.Sleep(sleep)
+		sleep *= 2
+		if sleep > waitVisibleMaxSleep {
+			sleep = waitVisibleMaxSleep
+		}
+	}
+}
+
+// checkVisible checks the object o found is the one uploaded from src
+// by comparing the sizes and the hashes if srcSum is set, otherwise
+// the modification times.  It returns an error describing the first
+// difference found.
+func checkVisible(f fs.Fs, o fs.Object, src fs.ObjectInfo, hashType hash.Type, srcSum string) error {
+	if src.Size() >= 0 && o.Size() != src.Size() {
+		return errors.Errorf("size is %d not %d", o.Size(), src.Size())
+	}
+	if srcSum != "" {
+		dstSum, err := o.Hash(hashType)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %v", hashType)
+		}
+		if !hash.Equals(srcSum, dstSum) {
+			return errors.Errorf("%v is %q not %q", hashType, dstSum, srcSum)
+		}
+		return nil
+	}
+	modifyWindow := fs.GetModifyWindow(f, src.Fs())
+	if modifyWindow == fs.ModTimeNotSupported {
+		return nil
+	}
+	dt := o.ModTime().Sub(src.ModTime())
+	if dt >= modifyWindow || dt <= -modifyWindow {
+		return errors.Errorf("modification time is %v not %v", o.ModTime(), src.ModTime())
+	}
+	return nil
+}
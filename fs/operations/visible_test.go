@@ -0,0 +1,102 @@
+package operations
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// laggyFs is an fs.Fs where NewObject doesn't find objects until it
+// has been called lag times
+type laggyFs struct {
+	fs.Fs
+	lag   int
+	calls int
+}
+
+func (f *laggyFs) NewObject(remote string) (fs.Object, error) {
+	f.calls++
+	if f.calls <= f.lag {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.Fs.NewObject(remote)
+}
+
+func TestWaitVisible(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-visible-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("file", time.Now(), 5, true, nil, nil)
+	_, err = f.Put(bytes.NewBufferString("hello"), src)
+	require.NoError(t, err)
+
+	oldWaitVisible, oldMinSleep, oldMaxSleep := fs.Config.WaitVisible, waitVisibleMinSleep, waitVisibleMaxSleep
+	defer func() {
+		fs.Config.WaitVisible, waitVisibleMinSleep, waitVisibleMaxSleep = oldWaitVisible, oldMinSleep, oldMaxSleep
+	}()
+	waitVisibleMinSleep = time.Millisecond
+	waitVisibleMaxSleep = 2 * time.Millisecond
+
+	// off by default so doesn't look at all
+	fs.Config.WaitVisible = 0
+	lf := &laggyFs{Fs: f, lag: 1000}
+	require.NoError(t, waitVisible(lf, "file", src))
+	assert.Equal(t, 0, lf.calls)
+
+	// found after a few tries
+	fs.Config.WaitVisible = 10 * time.Second
+	lf = &laggyFs{Fs: f, lag: 3}
+	require.NoError(t, waitVisible(lf, "file", src))
+	assert.Equal(t, 4, lf.calls)
+
+	// never found
+	fs.Config.WaitVisible = 20 * time.Millisecond
+	lf = &laggyFs{Fs: f, lag: 1000000}
+	err = waitVisible(lf, "file", src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not visible after upload")
+	assert.Equal(t, fs.ErrorObjectNotFound, errors.Cause(err))
+
+	// found with the wrong size, eg the old version
+	wrongSize := object.NewStaticObjectInfo("file", time.Now(), 6, true, nil, nil)
+	err = waitVisible(f, "file", wrongSize)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "size is 5 not 6")
+
+	// size unknown so any size will do
+	unknownSize := object.NewStaticObjectInfo("file", src.ModTime(), -1, true, nil, nil)
+	require.NoError(t, waitVisible(f, "file", unknownSize))
+
+	// found with the same size but an old modification time
+	wrongTime := object.NewStaticObjectInfo("file", src.ModTime().Add(time.Hour), 5, true, nil, nil)
+	err = waitVisible(f, "file", wrongTime)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modification time is")
+
+	// found with the same size but a different hash - the
+	// modification time isn't checked if the hash is
+	wrongHash := object.NewStaticObjectInfo("file", src.ModTime().Add(time.Hour), 5, true, map[hash.Type]string{
+		hash.MD5: "0123456789abcdef0123456789abcdef",
+	}, f)
+	err = waitVisible(f, "file", wrongHash)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MD5 is")
+	rightHash := object.NewStaticObjectInfo("file", src.ModTime().Add(time.Hour), 5, true, map[hash.Type]string{
+		hash.MD5: "5d41402abc4b2a76b9719d911017c592",
+	}, f)
+	require.NoError(t, waitVisible(f, "file", rightHash))
+}
@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Time is a time.Time with some more parsing options
+type Time time.Time
+
+// TimeOff is the default value for flags which can be turned off
+var TimeOff = Time{}
+
+// The layouts ParseTime tries, in local time if there is no zone
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses a time or a date, eg "2006-01-02 15:04:05" or
+// "2006-01-02", or a duration (see ParseDuration) meaning that long
+// before now.
+func ParseTime(s string) (t time.Time, err error) {
+	if s == "off" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		t, err = time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	d, err := ParseDuration(s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("can't parse %q as a time or a duration", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// Turn Time into a string
+func (t Time) String() string {
+	if !t.IsSet() {
+		return "off"
+	}
+	return time.Time(t).Format(time.RFC3339Nano)
+}
+
+// IsSet returns if the time is not TimeOff
+func (t Time) IsSet() bool {
+	return !time.Time(t).IsZero()
+}
+
+// Set a Time
+func (t *Time) Set(s string) error {
+	parsed, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	*t = Time(parsed)
+	return nil
+}
+
+// Type of the value
+func (t Time) Type() string {
+	return "time"
+}
+
+// Scan implements the fmt.Scanner interface
+func (t *Time) Scan(s fmt.ScanState, ch rune) error {
+	token, err := s.Token(true, nil)
+	if err != nil {
+		return err
+	}
+	return t.Set(string(token))
+}
@@ -0,0 +1,52 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*Time)(nil)
+
+func TestParseTime(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{"off", time.Time{}, false},
+		{"2019-01-02T03:04:05Z", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"2019-01-02T03:04:05+01:00", time.Date(2019, 1, 2, 2, 4, 5, 0, time.UTC), false},
+		{"2019-01-02T03:04:05", time.Date(2019, 1, 2, 3, 4, 5, 0, time.Local), false},
+		{"2019-01-02 03:04:05", time.Date(2019, 1, 2, 3, 4, 5, 0, time.Local), false},
+		{"2019-01-02", time.Date(2019, 1, 2, 0, 0, 0, 0, time.Local), false},
+		{"potato", time.Time{}, true},
+		{"2019-13-02", time.Time{}, true},
+	} {
+		got, err := ParseTime(test.in)
+		assert.Equal(t, test.err, err != nil, test.in)
+		assert.True(t, test.want.Equal(got), "%s: want %v got %v", test.in, test.want, got)
+	}
+
+	// Durations are relative to now
+	got, err := ParseTime("1d")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), got, time.Minute)
+}
+
+func TestTimeString(t *testing.T) {
+	assert.Equal(t, "off", TimeOff.String())
+	assert.Equal(t, "2019-01-02T03:04:05Z", Time(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)).String())
+}
+
+func TestTimeSet(t *testing.T) {
+	var tm Time
+	assert.NoError(t, tm.Set("2019-01-02T03:04:05Z"))
+	assert.True(t, tm.IsSet())
+	assert.NoError(t, tm.Set("off"))
+	assert.False(t, tm.IsSet())
+	assert.Error(t, tm.Set("potato"))
+}
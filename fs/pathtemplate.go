@@ -0,0 +1,109 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pathTemplateTime is the time the date templates in paths are
+// expanded with.  It is fixed on first use so every path in a run
+// gets the same date even if it runs over midnight.
+var (
+	pathTemplateOnce sync.Once
+	pathTemplateTime time.Time
+)
+
+// PathTemplateTime returns the time to expand path templates with
+func PathTemplateTime() time.Time {
+	pathTemplateOnce.Do(func() {
+		pathTemplateTime = time.Now()
+	})
+	return pathTemplateTime
+}
+
+// pathTemplateDate maps the % directives to time layouts
+var pathTemplateDate = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+	'a': "Mon",
+	'b': "Jan",
+	'z': "-0700",
+}
+
+// pathTemplateEnv looks up the environment variable name for a path
+// template.  HOSTNAME is often not exported by shells so it is found
+// from the OS if it isn't set.
+func pathTemplateEnv(name string) (string, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if name == "HOSTNAME" {
+		return os.Hostname()
+	}
+	return "", errors.Errorf("environment variable %q isn't set", name)
+}
+
+// ExpandPathTemplate expands the templates in path using the time now.
+//
+// ${NAME} is replaced with the environment variable NAME and %Y, %y,
+// %m, %d, %H, %M, %S, %a, %b, %z and %j with the parts of the date as
+// strftime would.  Use %% for a literal %.
+func ExpandPathTemplate(path string, now time.Time) (string, error) {
+	if !strings.ContainsAny(path, "$%") {
+		return path, nil
+	}
+	var out bytes.Buffer
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '$' && i+1 < len(path) && path[i+1] == '{':
+			end := strings.IndexByte(path[i+2:], '}')
+			if end < 0 {
+				return "", errors.Errorf("unterminated ${ in %q", path)
+			}
+			value, err := pathTemplateEnv(path[i+2 : i+2+end])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(value)
+			i += 2 + end
+		case c == '%':
+			if i+1 >= len(path) {
+				return "", errors.Errorf("%% at end of %q", path)
+			}
+			i++
+			d := path[i]
+			if layout, ok := pathTemplateDate[d]; ok {
+				out.WriteString(now.Format(layout))
+			} else if d == 'j' {
+				fmt.Fprintf(&out, "%03d", now.YearDay())
+			} else if d == '%' {
+				out.WriteByte('%')
+			} else {
+				return "", errors.Errorf("unknown template %%%c in %q - use %%%% for a literal %%", d, path)
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+// expandPath expands the templates in path if --path-templates is set
+func expandPath(path string) (string, error) {
+	if !Config.PathTemplates {
+		return path, nil
+	}
+	return ExpandPathTemplate(path, PathTemplateTime())
+}
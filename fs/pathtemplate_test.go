@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPathTemplate(t *testing.T) {
+	require.NoError(t, os.Setenv("RCLONE_TEST_TEMPLATE", "potato"))
+	defer func() {
+		require.NoError(t, os.Unsetenv("RCLONE_TEST_TEMPLATE"))
+	}()
+	now := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"remote:path", "remote:path", false},
+		{"remote:backups/${RCLONE_TEST_TEMPLATE}/%Y-%m-%d/", "remote:backups/potato/2019-03-04/", false},
+		{"%y%m%d-%H%M%S", "190304-050607", false},
+		{"%a %b %j %z", "Mon Mar 063 +0000", false},
+		{"100%%", "100%", false},
+		{"$HOME/${RCLONE_TEST_TEMPLATE}", "$HOME/potato", false},
+		{"${RCLONE_TEST_TEMPLATE_UNSET}", "", true},
+		{"${RCLONE_TEST_TEMPLATE", "", true},
+		{"%q", "", true},
+		{"file%", "", true},
+	} {
+		got, err := ExpandPathTemplate(test.in, now)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestExpandPathTemplateHostname(t *testing.T) {
+	if _, ok := os.LookupEnv("HOSTNAME"); ok {
+		t.Skip("HOSTNAME is set")
+	}
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	got, err := ExpandPathTemplate("${HOSTNAME}", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, hostname, got)
+}
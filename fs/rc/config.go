@@ -0,0 +1,247 @@
+// Implement config options reading and writing
+//
+// This is done here rather than in fs/fs.go so we don't cause a circular dependency
+
+package rc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+var (
+	optionMu     sync.Mutex
+	optionBlock  = map[string]interface{}{}
+	optionReload = map[string]func() error{}
+	optionLive   = map[string]map[string]bool{}
+)
+
+// AddOption adds an option set
+//
+// option should be a pointer to the struct holding the options
+func AddOption(name string, option interface{}) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	optionBlock[name] = option
+}
+
+// AddOptionReload adds an option set with a reload function to be
+// called when options are changed
+func AddOptionReload(name string, option interface{}, reload func() error) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	optionBlock[name] = option
+	optionReload[name] = reload
+}
+
+// AddOptionLive marks fields in the option set name as safe to change
+// with options/set while rclone is running.
+//
+// Only fields which are read fresh each time they are used and which
+// don't need anything else to be reinitialised should be marked.
+// Other fields can be read with options/get but not changed.
+func AddOptionLive(name string, fields ...string) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	live := optionLive[name]
+	if live == nil {
+		live = map[string]bool{}
+		optionLive[name] = live
+	}
+	for _, field := range fields {
+		live[field] = true
+	}
+}
+
+func init() {
+	AddOption("main", fs.Config)
+	AddOptionLive("main", "LogLevel", "StatsLogLevel", "StatsOneLine", "StatsFileNameLength", "DataRateUnit")
+
+	Add(Call{
+		Path:  "options/blocks",
+		Fn:    rcOptionsBlocks,
+		Title: "List all the option blocks",
+		Help: `Returns
+- options - a list of the options block names`,
+	})
+	Add(Call{
+		Path:  "options/get",
+		Fn:    rcOptionsGet,
+		Title: "Get all the options",
+		Help: `
+Returns an object where keys are option block names and values are an
+object with the current option values in.
+
+This shows the internal names of the option within rclone which should
+map to the external options very easily with a few exceptions.
+
+    rclone rc options/get
+`,
+	})
+	Add(Call{
+		Path:  "options/set",
+		Fn:    rcOptionsSet,
+		Title: "Set an option",
+		Help: `
+Parameters
+
+- option block name containing an object with
+  - key: value
+
+Repeated as often as required.
+
+Only supply the options you wish to change.  If the option block is
+unknown an error will be returned, but unknown options within a block
+are silently ignored.
+
+Only options which are safe to change while rclone is running may be
+set - trying to change any other option returns an error and changes
+nothing in any of the blocks.  At the moment these are the logging
+options in the main block: LogLevel, StatsLogLevel, StatsOneLine,
+StatsFileNameLength and DataRateUnit.  The other blocks, eg filter
+and vfs, are read only as they are only read when a command or a VFS
+starts.
+
+The option block may be supplied as a JSON object in a JSON body, or
+as a JSON encoded string in a parameter.
+
+For example this sets DEBUG level logs (-vv)
+
+    rclone rc options/set main='{"LogLevel": 7}'
+
+And this sets INFO level logs (-v)
+
+    rclone rc options/set main='{"LogLevel": 6}'
+
+And this sets NOTICE level logs (normal without -v)
+
+    rclone rc options/set main='{"LogLevel": 5}'
+`,
+	})
+}
+
+// Show the list of all the option blocks
+func rcOptionsBlocks(in Params) (out Params, err error) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	options := []string{}
+	for name := range optionBlock {
+		options = append(options, name)
+	}
+	sort.Strings(options)
+	out = make(Params)
+	out["options"] = options
+	return out, nil
+}
+
+// Show the current values of all the option blocks
+//
+// The blocks are copied under the lock so they can be serialised
+// after it is released without racing with options/set
+func rcOptionsGet(in Params) (out Params, err error) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	out = make(Params)
+	for name, options := range optionBlock {
+		out[name] = reflect.ValueOf(options).Elem().Interface()
+	}
+	return out, nil
+}
+
+// Set the options in the blocks passed in
+//
+// All the blocks are checked before any of them are changed so an
+// error leaves the options as they were.
+func rcOptionsSet(in Params) (out Params, err error) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	changes := make([]*optionChange, 0, len(in))
+	for name, options := range in {
+		current := optionBlock[name]
+		if current == nil {
+			return nil, errors.Errorf("unknown option block %q", name)
+		}
+		// Options passed as URL or form parameters arrive as strings
+		// so decode them as JSON
+		if s, ok := options.(string); ok {
+			var decoded interface{}
+			err = json.Unmarshal([]byte(s), &decoded)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode JSON for option block %q", name)
+			}
+			options = decoded
+		}
+		change, err := checkLive(name, current, options)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	for _, change := range changes {
+		change.apply()
+		if reload := optionReload[change.name]; reload != nil {
+			err = reload()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to reload options from block %q", change.name)
+			}
+		}
+		fs.Debugf(nil, "rc: options block %q updated", change.name)
+	}
+	return make(Params), nil
+}
+
+// optionChange is a checked change to an option block
+type optionChange struct {
+	name     string        // name of the block
+	current  reflect.Value // the block
+	newValue reflect.Value // a copy of the block with the changes made
+	changed  []int         // indexes of the fields changed
+}
+
+// apply writes the changed fields into the block
+func (change *optionChange) apply() {
+	for _, i := range change.changed {
+		change.current.Field(i).Set(change.newValue.Field(i))
+	}
+}
+
+// checkLive decodes the options for the block called name into a
+// copy of current, which should be a pointer to a struct, and returns
+// the change to be made.
+//
+// It returns an error without changing anything if any of the fields
+// changed aren't marked as live.
+func checkLive(name string, current interface{}, options interface{}) (*optionChange, error) {
+	currentValue := reflect.ValueOf(current).Elem()
+	newValue := reflect.New(currentValue.Type())
+	newValue.Elem().Set(currentValue)
+	err := Reshape(newValue.Interface(), options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to write options from block %q", name)
+	}
+	change := &optionChange{
+		name:     name,
+		current:  currentValue,
+		newValue: newValue.Elem(),
+	}
+	for i := 0; i < currentValue.NumField(); i++ {
+		if currentValue.Type().Field(i).PkgPath != "" {
+			// unexported fields aren't set by Reshape
+			continue
+		}
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), newValue.Elem().Field(i).Interface()) {
+			continue
+		}
+		field := currentValue.Type().Field(i).Name
+		if !optionLive[name][field] {
+			return nil, errors.Errorf("option %q in block %q can't be changed while rclone is running", field, name)
+		}
+		change.changed = append(change.changed, i)
+	}
+	return change, nil
+}
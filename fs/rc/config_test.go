@@ -0,0 +1,146 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddOption(t *testing.T) {
+	v := &struct {
+		A int
+		B string
+	}{
+		A: 1,
+		B: "potato",
+	}
+	AddOption("potato", v)
+	assert.Equal(t, v, optionBlock["potato"])
+	delete(optionBlock, "potato")
+
+	AddOptionReload("potato", v, func() error { return nil })
+	assert.Equal(t, v, optionBlock["potato"])
+	require.NotNil(t, optionReload["potato"])
+	delete(optionBlock, "potato")
+	delete(optionReload, "potato")
+}
+
+func TestOptionsBlocks(t *testing.T) {
+	out, err := rcOptionsBlocks(Params{})
+	require.NoError(t, err)
+	assert.Contains(t, out["options"], "main")
+}
+
+func TestOptionsGet(t *testing.T) {
+	v := &struct{ A int }{A: 1}
+	AddOption("potato", v)
+	defer delete(optionBlock, "potato")
+	out, err := rcOptionsGet(Params{})
+	require.NoError(t, err)
+	assert.Equal(t, *v, out["potato"])
+	assert.NotNil(t, out["main"])
+
+	// The returned value should be a copy
+	v.A = 2
+	assert.Equal(t, 1, out["potato"].(struct{ A int }).A)
+}
+
+func TestOptionsSet(t *testing.T) {
+	v := &struct {
+		Int    int
+		String string
+		Fixed  bool
+	}{
+		Int:    1,
+		String: "hello",
+	}
+	reloaded := 0
+	AddOptionReload("potato", v, func() error {
+		reloaded++
+		return nil
+	})
+	AddOptionLive("potato", "Int", "String")
+	defer func() {
+		delete(optionBlock, "potato")
+		delete(optionReload, "potato")
+		delete(optionLive, "potato")
+	}()
+
+	// Set from a JSON object
+	out, err := rcOptionsSet(Params{
+		"potato": Params{
+			"Int": 50,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Params{}, out)
+	assert.Equal(t, 50, v.Int)
+	assert.Equal(t, "hello", v.String)
+	assert.Equal(t, 1, reloaded)
+
+	// Set from a JSON encoded string
+	_, err = rcOptionsSet(Params{
+		"potato": `{"String": "goodbye"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 50, v.Int)
+	assert.Equal(t, "goodbye", v.String)
+	assert.Equal(t, 2, reloaded)
+
+	// Fields which aren't live can't be changed and nothing is
+	// changed if one is supplied
+	_, err = rcOptionsSet(Params{
+		"potato": Params{
+			"Int":   60,
+			"Fixed": true,
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `option "Fixed" in block "potato" can't be changed`)
+	assert.Equal(t, 50, v.Int)
+	assert.False(t, v.Fixed)
+	assert.Equal(t, 2, reloaded)
+
+	// Supplying the current value of a field which isn't live is OK
+	_, err = rcOptionsSet(Params{
+		"potato": Params{
+			"Int":   60,
+			"Fixed": false,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 60, v.Int)
+
+	// Nothing is changed if a later block fails
+	v2 := &struct{ Fixed bool }{}
+	AddOption("potato2", v2)
+	defer delete(optionBlock, "potato2")
+	for i := 0; i < 10; i++ {
+		_, err = rcOptionsSet(Params{
+			"potato": Params{
+				"Int": 70,
+			},
+			"potato2": Params{
+				"Fixed": true,
+			},
+		})
+		require.Error(t, err)
+		assert.Equal(t, 60, v.Int)
+		assert.False(t, v2.Fixed)
+	}
+
+	// Bad JSON
+	_, err = rcOptionsSet(Params{
+		"potato": `{"String": `,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode JSON")
+
+	// Unknown block
+	_, err = rcOptionsSet(Params{
+		"sausage": Params{},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown option block")
+}
@@ -5,7 +5,9 @@ package rc
 import (
 	"os"
 	"runtime"
+	"strings"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/pkg/errors"
 )
 
@@ -42,6 +44,20 @@ the commands response.`,
 		Help: `
 This returns PID of current process.
 Useful for stopping rclone process.`,
+	})
+	Add(Call{
+		Path:  "core/version",
+		Fn:    rcVersion,
+		Title: "Shows the current version of rclone and the go runtime.",
+		Help: `
+This shows the current version of go and the go runtime
+
+- version - rclone version, eg "v1.44"
+- isGit - boolean - true if this was compiled from the git version
+- os - OS in use as according to Go
+- arch - cpu architecture in use according to Go
+- goVersion - version of Go runtime in use
+`,
 	})
 	Add(Call{
 		Path:  "core/memstats",
@@ -57,6 +73,35 @@ The most interesting values for most people are:
 * HeapSys: This is the amount of memory rclone has obtained from the OS
 * Sys: this is the total amount of memory requested from the OS
   * It is virtual memory so may include unused memory
+`,
+	})
+	Add(Call{
+		Path:  "core/errors",
+		Fn:    rcErrors,
+		Title: "Returns the most recent errors",
+		Help: `
+This returns the most recent errors logged (up to 1000), oldest first,
+in the errors response, whether or not they were shown in the log.
+Each error has these keys
+
+- time - when the error was logged
+- object - the file or remote the error was about, if any
+- class - "fatal", "noretry", "retry" or "error"
+- text - the error message
+`,
+	})
+	Add(Call{
+		Path:  "core/suppressed",
+		Fn:    rcSuppressed,
+		Title: "Returns the log lines suppressed by --log-dedupe",
+		Help: `
+This returns the most recent log lines which weren't shown because
+they repeated an identical error with --log-dedupe, oldest first, in
+the lines response.  Each line has these keys
+
+- time - when the line was logged
+- level - the log level, eg "ERROR"
+- text - the full text of the line
 `,
 	})
 	Add(Call{
@@ -95,6 +140,18 @@ func rcPid(in Params) (out Params, err error) {
 	return out, nil
 }
 
+// Return the version of rclone and the go runtime
+func rcVersion(in Params) (out Params, err error) {
+	out = Params{
+		"version":   fs.Version,
+		"isGit":     strings.HasSuffix(fs.Version, "-DEV"),
+		"os":        runtime.GOOS,
+		"arch":      runtime.GOARCH,
+		"goVersion": runtime.Version(),
+	}
+	return out, nil
+}
+
 // Return the memory statistics
 func rcMemStats(in Params) (out Params, err error) {
 	out = make(Params)
@@ -123,6 +180,39 @@ func rcMemStats(in Params) (out Params, err error) {
 	return out, nil
 }
 
+// Return the recent errors
+func rcErrors(in Params) (out Params, err error) {
+	lines := []Params{}
+	for _, line := range fs.RecentErrors() {
+		lines = append(lines, Params{
+			"time":   line.Time,
+			"object": line.Object,
+			"class":  line.Class,
+			"text":   line.Text,
+		})
+	}
+	out = Params{
+		"errors": lines,
+	}
+	return out, nil
+}
+
+// Return the suppressed log lines
+func rcSuppressed(in Params) (out Params, err error) {
+	lines := []Params{}
+	for _, line := range fs.SuppressedLogs() {
+		lines = append(lines, Params{
+			"time":  line.Time,
+			"level": line.Level.String(),
+			"text":  line.Text,
+		})
+	}
+	out = Params{
+		"lines": lines,
+	}
+	return out, nil
+}
+
 // Do a garbage collection run
 func rcGc(in Params) (out Params, err error) {
 	out = make(Params)
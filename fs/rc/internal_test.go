@@ -0,0 +1,49 @@
+package rc
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalVersion(t *testing.T) {
+	call := registry.get("core/version")
+	require.NotNil(t, call)
+	out, err := call.Fn(Params{})
+	require.NoError(t, err)
+	assert.Equal(t, fs.Version, out["version"])
+	assert.Equal(t, runtime.GOOS, out["os"])
+	assert.Equal(t, runtime.GOARCH, out["arch"])
+	assert.Equal(t, runtime.Version(), out["goVersion"])
+	_, ok := out["isGit"].(bool)
+	assert.True(t, ok)
+}
+
+func TestInternalPid(t *testing.T) {
+	call := registry.get("core/pid")
+	require.NotNil(t, call)
+	out, err := call.Fn(Params{})
+	require.NoError(t, err)
+	assert.NotZero(t, out["pid"])
+}
+
+func TestInternalSuppressed(t *testing.T) {
+	call := registry.get("core/suppressed")
+	require.NotNil(t, call)
+	out, err := call.Fn(Params{})
+	require.NoError(t, err)
+	_, ok := out["lines"].([]Params)
+	assert.True(t, ok)
+}
+
+func TestInternalErrors(t *testing.T) {
+	call := registry.get("core/errors")
+	require.NotNil(t, call)
+	out, err := call.Fn(Params{})
+	require.NoError(t, err)
+	_, ok := out["errors"].([]Params)
+	assert.True(t, ok)
+}
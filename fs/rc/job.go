@@ -0,0 +1,279 @@
+// Run rc calls in the background as jobs
+
+package rc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Add(Call{
+		Path:  "job/status",
+		Fn:    rcJobStatus,
+		Title: "Reads the status of the job ID",
+		Help: `Parameters
+
+- jobid - id of the job (integer)
+
+Results
+
+- finished - boolean whether the job has finished or not
+- duration - time in seconds that the job ran for
+- endTime - time the job finished (eg "2018-10-26T18:50:20.528746884+01:00")
+- error - error from the job or empty string for no error
+- id - as passed in above
+- path - the remote control command the job is running
+- startTime - time the job started (eg "2018-10-26T18:50:20.528336039+01:00")
+- success - boolean - true for success false otherwise
+- output - output of the job as would have been returned if called synchronously
+`,
+	})
+	Add(Call{
+		Path:  "job/list",
+		Fn:    rcJobList,
+		Title: "Lists the IDs of the running jobs",
+		Help: `Parameters - None
+
+Results
+
+- jobids - array of integer job ids
+`,
+	})
+	Add(Call{
+		Path:  "job/stop",
+		Fn:    rcJobStop,
+		Title: "Stop the running job",
+		Help: `Parameters
+
+- jobid - id of the job (integer)
+
+This asks the job to stop.  Only calls which check for it, such as
+sync/sync, sync/copy and sync/move, will stop early - others carry on
+until they are finished.  Use job/status to see when the job has
+finished.
+`,
+	})
+}
+
+// Job describes an asynchronous task started via the rc package
+type Job struct {
+	mu        sync.Mutex
+	ID        int64     `json:"id"`
+	Path      string    `json:"path"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Error     string    `json:"error"`
+	Finished  bool      `json:"finished"`
+	Success   bool      `json:"success"`
+	Duration  float64   `json:"duration"`
+	Output    Params    `json:"output"`
+	stop      context.CancelFunc
+}
+
+// Jobs describes a collection of running tasks
+type Jobs struct {
+	mu     sync.Mutex
+	jobs   map[int64]*Job
+	lastID int64
+	expire time.Duration // how long finished jobs are kept for
+}
+
+// JobExpireDuration is the default time finished jobs are kept for
+const JobExpireDuration = 60 * time.Second
+
+var jobs = newJobs()
+
+// newJobs makes a new Jobs structure
+func newJobs() *Jobs {
+	return &Jobs{
+		jobs:   map[int64]*Job{},
+		expire: JobExpireDuration,
+	}
+}
+
+// SetJobExpireDuration sets how long finished jobs are kept for
+func SetJobExpireDuration(expire time.Duration) {
+	jobs.mu.Lock()
+	jobs.expire = expire
+	jobs.mu.Unlock()
+}
+
+// kickExpire removes the finished jobs which have expired
+//
+// Call with the lock held
+func (jobs *Jobs) kickExpire() {
+	now := time.Now()
+	for ID, job := range jobs.jobs {
+		job.mu.Lock()
+		if job.Finished && now.Sub(job.EndTime) > jobs.expire {
+			delete(jobs.jobs, ID)
+		}
+		job.mu.Unlock()
+	}
+}
+
+// IDs returns the IDs of the jobs in ascending order
+func (jobs *Jobs) IDs() (IDs []int64) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.kickExpire()
+	IDs = []int64{}
+	for ID := range jobs.jobs {
+		IDs = append(IDs, ID)
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+	return IDs
+}
+
+// Get a job with a given ID or nil if it doesn't exist
+func (jobs *Jobs) Get(ID int64) *Job {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	jobs.kickExpire()
+	return jobs.jobs[ID]
+}
+
+// NewJob starts running fn with in in the background returning the
+// Job.  path is the path of the call for the status.
+func (jobs *Jobs) NewJob(path string, fn Func, in Params) *Job {
+	ctx, stop := context.WithCancel(context.Background())
+	jobs.mu.Lock()
+	jobs.kickExpire()
+	jobs.lastID++
+	job := &Job{
+		ID:        jobs.lastID,
+		Path:      path,
+		StartTime: time.Now(),
+		stop:      stop,
+	}
+	jobs.jobs[job.ID] = job
+	jobs.mu.Unlock()
+	in["_context"] = ctx
+	go job.run(fn, in)
+	return job
+}
+
+// run fn with in recording the result in the job
+func (job *Job) run(fn Func, in Params) {
+	var (
+		out Params
+		err error
+	)
+	defer func() {
+		job.stop()
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic received: %v", r)
+		}
+		job.finish(out, err)
+	}()
+	out, err = fn(in)
+}
+
+// finish marks the job as finished recording out and err
+func (job *Job) finish(out Params, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.EndTime = time.Now()
+	if out == nil {
+		out = make(Params)
+	}
+	// calls which echo their input mustn't return the context
+	delete(out, "_context")
+	job.Output = out
+	job.Duration = job.EndTime.Sub(job.StartTime).Seconds()
+	if err != nil {
+		fs.Errorf(nil, "rc: job %d: %q: error: %v", job.ID, job.Path, err)
+		job.Error = err.Error()
+		job.Success = false
+	} else {
+		job.Error = ""
+		job.Success = true
+	}
+	job.Finished = true
+}
+
+// Stop asks the job to stop
+func (job *Job) Stop() {
+	job.stop()
+}
+
+// status returns the status of the job as Params
+func (job *Job) status() Params {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return Params{
+		"id":        job.ID,
+		"path":      job.Path,
+		"startTime": job.StartTime,
+		"endTime":   job.EndTime,
+		"error":     job.Error,
+		"finished":  job.Finished,
+		"success":   job.Success,
+		"duration":  job.Duration,
+		"output":    job.Output,
+	}
+}
+
+// StartJob starts the call in the background returning Params with
+// the "jobid" in.
+func StartJob(call *Call, in Params) (Params, error) {
+	if call.NeedsResponse {
+		return nil, errors.Errorf("%q can't be run as an async job", call.Path)
+	}
+	job := jobs.NewJob(call.Path, call.Fn, in)
+	return Params{"jobid": job.ID}, nil
+}
+
+// GetContext returns the context passed to a call running as a job
+// which is cancelled when the job is stopped, or
+// context.Background() if it isn't running as a job.
+func GetContext(in Params) context.Context {
+	if ctx, ok := in["_context"].(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// getJob returns the job whose ID is in "jobid"
+func getJob(in Params) (*Job, error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	job := jobs.Get(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	return job, nil
+}
+
+// rcJobStatus returns the status of a job
+func rcJobStatus(in Params) (out Params, err error) {
+	job, err := getJob(in)
+	if err != nil {
+		return nil, err
+	}
+	return job.status(), nil
+}
+
+// rcJobList returns the IDs of the jobs
+func rcJobList(in Params) (out Params, err error) {
+	return Params{"jobids": jobs.IDs()}, nil
+}
+
+// rcJobStop stops a job
+func rcJobStop(in Params) (out Params, err error) {
+	job, err := getJob(in)
+	if err != nil {
+		return nil, err
+	}
+	fs.Infof(nil, "rc: job %d: stopping", job.ID)
+	job.Stop()
+	return Params{}, nil
+}
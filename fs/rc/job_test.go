@@ -0,0 +1,115 @@
+package rc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFinished waits for the job to finish returning its status
+func waitFinished(t *testing.T, job *Job) Params {
+	for i := 0; i < 100; i++ {
+		status := job.status()
+		if status["finished"].(bool) {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %d didn't finish", job.ID)
+	return nil
+}
+
+func TestJobRun(t *testing.T) {
+	jobs := newJobs()
+
+	job := jobs.NewJob("test/ok", func(in Params) (Params, error) {
+		return Params{"potato": in["potato"]}, nil
+	}, Params{"potato": 1})
+	status := waitFinished(t, job)
+	assert.Equal(t, true, status["success"])
+	assert.Equal(t, "", status["error"])
+	assert.Equal(t, "test/ok", status["path"])
+	assert.Equal(t, Params{"potato": 1}, status["output"])
+
+	job = jobs.NewJob("test/error", func(in Params) (Params, error) {
+		return nil, errors.New("potato")
+	}, Params{})
+	status = waitFinished(t, job)
+	assert.Equal(t, false, status["success"])
+	assert.Equal(t, "potato", status["error"])
+	assert.Equal(t, Params{}, status["output"])
+
+	job = jobs.NewJob("test/panic", func(in Params) (Params, error) {
+		panic("boom")
+	}, Params{})
+	status = waitFinished(t, job)
+	assert.Equal(t, false, status["success"])
+	assert.Contains(t, status["error"], "boom")
+
+	assert.Equal(t, []int64{1, 2, 3}, jobs.IDs())
+	assert.Nil(t, jobs.Get(4))
+}
+
+func TestJobStop(t *testing.T) {
+	jobs := newJobs()
+	started := make(chan struct{})
+	job := jobs.NewJob("test/wait", func(in Params) (Params, error) {
+		close(started)
+		ctx := GetContext(in)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Params{})
+	<-started
+	assert.Equal(t, false, job.status()["finished"])
+	job.Stop()
+	status := waitFinished(t, job)
+	assert.Equal(t, context.Canceled.Error(), status["error"])
+}
+
+func TestJobExpire(t *testing.T) {
+	jobs := newJobs()
+	jobs.expire = time.Millisecond
+	job := jobs.NewJob("test/ok", func(in Params) (Params, error) {
+		return nil, nil
+	}, Params{})
+	waitFinished(t, job)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []int64{}, jobs.IDs())
+}
+
+func TestStartJob(t *testing.T) {
+	call := registry.get("rc/noop")
+	require.NotNil(t, call)
+	out, err := StartJob(call, Params{"potato": "1"})
+	require.NoError(t, err)
+	jobID := out["jobid"].(int64)
+
+	var status Params
+	for i := 0; i < 100; i++ {
+		status, err = rcJobStatus(Params{"jobid": jobID})
+		require.NoError(t, err)
+		if status["finished"].(bool) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, true, status["success"])
+	assert.Equal(t, Params{"potato": "1"}, status["output"])
+
+	out, err = rcJobList(Params{})
+	require.NoError(t, err)
+	assert.Contains(t, out["jobids"], jobID)
+
+	_, err = rcJobStop(Params{"jobid": jobID})
+	require.NoError(t, err)
+
+	_, err = rcJobStatus(Params{"jobid": int64(-1)})
+	assert.Error(t, err)
+
+	_, err = StartJob(&Call{Path: "test/response", NeedsResponse: true}, Params{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+// Parameter parsing
+
+package rc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Reshape reshapes one blob of data into another via json serialization
+//
+// out should be a pointer type
+//
+// This isn't a very efficient way of dealing with this!
+func Reshape(out interface{}, in interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrapf(err, "Reshape failed to Marshal")
+	}
+	err = json.Unmarshal(b, out)
+	if err != nil {
+		return errors.Wrapf(err, "Reshape failed to Unmarshal")
+	}
+	return nil
+}
+
+// ErrParamNotFound is returned by the Get functions when the
+// parameter isn't found
+type ErrParamNotFound string
+
+// Error turns this error into a string
+func (e ErrParamNotFound) Error() string {
+	return fmt.Sprintf("Didn't find key %q in input", string(e))
+}
+
+// IsErrParamNotFound returns whether err is ErrParamNotFound
+func IsErrParamNotFound(err error) bool {
+	_, isNotFound := err.(ErrParamNotFound)
+	return isNotFound
+}
+
+// Get gets a parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be nil.
+func (p Params) Get(key string) (interface{}, error) {
+	value, ok := p[key]
+	if !ok {
+		return nil, ErrParamNotFound(key)
+	}
+	return value, nil
+}
+
+// GetString gets a string parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be "".
+func (p Params) GetString(key string) (string, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return "", err
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", errors.Errorf("expecting string value for key %q (was %T)", key, value)
+	}
+	return str, nil
+}
+
+// GetInt64 gets an int64 parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be 0.
+func (p Params) GetInt64(key string) (int64, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch x := value.(type) {
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		if x != math.Trunc(x) || x > math.MaxInt64 || x < math.MinInt64 {
+			return 0, errors.Errorf("key %q (%v) overflows int64 ", key, value)
+		}
+		return int64(x), nil
+	case string:
+		i, err := strconv.ParseInt(x, 10, 0)
+		if err != nil {
+			return 0, errors.Wrapf(err, "couldn't parse key %q (%v) as int64", key, value)
+		}
+		return i, nil
+	}
+	return 0, errors.Errorf("expecting int64 value for key %q (was %T)", key, value)
+}
+
+// GetBool gets a boolean parameter from the input
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and the returned value will be false.
+func (p Params) GetBool(key string) (bool, error) {
+	value, err := p.Get(key)
+	if err != nil {
+		return false, err
+	}
+	switch x := value.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, errors.Wrapf(err, "couldn't parse key %q (%v) as bool", key, value)
+		}
+		return b, nil
+	}
+	return false, errors.Errorf("expecting bool value for key %q (was %T)", key, value)
+}
+
+// GetStruct gets a struct from key from the input into the struct
+// pointed to by out.  out must be a pointer type.
+//
+// The value may be a JSON object or a string containing one, as
+// parameters passed in the URL or a form are.
+//
+// If the parameter isn't found then error will be of type
+// ErrParamNotFound and out will be left alone.
+func (p Params) GetStruct(key string, out interface{}) error {
+	value, err := p.Get(key)
+	if err != nil {
+		return err
+	}
+	if str, ok := value.(string); ok {
+		err = json.Unmarshal([]byte(str), out)
+	} else {
+		err = Reshape(out, value)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "key %q", key)
+	}
+	return nil
+}
+
+// GetFsNamed gets the fs.Fs named by the fsName parameter from the
+// input, eg "drive:" or "/tmp/dir"
+func GetFsNamed(in Params, fsName string) (fs.Fs, error) {
+	fsString, err := in.GetString(fsName)
+	if err != nil {
+		return nil, err
+	}
+	return fs.NewFs(fsString)
+}
+
+// GetFs gets the fs.Fs named by the "fs" parameter from the input,
+// eg "drive:" or "/tmp/dir"
+func GetFs(in Params) (fs.Fs, error) {
+	return GetFsNamed(in, "fs")
+}
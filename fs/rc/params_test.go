@@ -0,0 +1,95 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrParamNotFound(t *testing.T) {
+	err := ErrParamNotFound("key")
+	assert.Equal(t, `Didn't find key "key" in input`, err.Error())
+	assert.True(t, IsErrParamNotFound(err))
+	assert.False(t, IsErrParamNotFound(nil))
+}
+
+func TestGetString(t *testing.T) {
+	in := Params{
+		"string":    "one",
+		"notString": 17,
+	}
+	v, err := in.GetString("string")
+	require.NoError(t, err)
+	assert.Equal(t, "one", v)
+
+	_, err = in.GetString("notFound")
+	assert.True(t, IsErrParamNotFound(err))
+
+	_, err = in.GetString("notString")
+	assert.Error(t, err)
+	assert.False(t, IsErrParamNotFound(err))
+}
+
+func TestGetInt64(t *testing.T) {
+	in := Params{
+		"int":        1,
+		"int64":      int64(2),
+		"float64":    float64(3),
+		"string":     "4",
+		"badFloat64": float64(3.5),
+		"badString":  "four",
+		"wrongType":  true,
+	}
+	for key, want := range map[string]int64{"int": 1, "int64": 2, "float64": 3, "string": 4} {
+		v, err := in.GetInt64(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, v, key)
+	}
+	for _, key := range []string{"badFloat64", "badString", "wrongType"} {
+		_, err := in.GetInt64(key)
+		assert.Error(t, err, key)
+		assert.False(t, IsErrParamNotFound(err), key)
+	}
+	_, err := in.GetInt64("notFound")
+	assert.True(t, IsErrParamNotFound(err))
+}
+
+func TestGetBool(t *testing.T) {
+	in := Params{
+		"bool":      true,
+		"string":    "true",
+		"badString": "potato",
+		"wrongType": 1,
+	}
+	for _, key := range []string{"bool", "string"} {
+		v, err := in.GetBool(key)
+		require.NoError(t, err, key)
+		assert.True(t, v, key)
+	}
+	for _, key := range []string{"badString", "wrongType"} {
+		_, err := in.GetBool(key)
+		assert.Error(t, err, key)
+	}
+	_, err := in.GetBool("notFound")
+	assert.True(t, IsErrParamNotFound(err))
+}
+
+func TestGetStruct(t *testing.T) {
+	type opt struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	in := Params{
+		"map":    map[string]interface{}{"a": 1, "b": "two"},
+		"string": `{"a":3,"b":"four"}`,
+		"bad":    "{",
+	}
+	var out opt
+	require.NoError(t, in.GetStruct("map", &out))
+	assert.Equal(t, opt{A: 1, B: "two"}, out)
+	require.NoError(t, in.GetStruct("string", &out))
+	assert.Equal(t, opt{A: 3, B: "four"}, out)
+	assert.Error(t, in.GetStruct("bad", &out))
+	assert.True(t, IsErrParamNotFound(in.GetStruct("notFound", &out)))
+}
@@ -0,0 +1,183 @@
+// Run several rc calls one after another
+
+package rc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Add(Call{
+		Path:  "core/pipeline",
+		Fn:    rcPipeline,
+		Title: "Run remote control commands one after another",
+		Help: `
+This runs a series of remote control commands one after the other and
+returns what each of them did.  It is useful for workflows like "sync
+then check then purge the backup directory" which otherwise need
+something outside rclone to run the steps in order.
+
+Parameters
+
+- steps - a JSON array of the steps to run, in order
+
+Each step is an object with these keys
+
+- path - the remote control command to run, eg "vfs/refresh"
+- params - an object with the parameters for the command (optional)
+- name - a name for the step (optional - defaults to "step N")
+- after - an array of the names of earlier steps which must succeed for this step to run (optional)
+- onError - what to do if this step fails, "stop" (the default) to skip all the remaining steps or "continue" to carry on with the steps which don't depend on it
+
+The steps are all checked before any of them are run, so a pipeline
+with an unknown command or a bad dependency does nothing.
+
+This returns
+
+- ok - true if all the steps succeeded
+- steps - an array with an entry for each step with its name, path, whether it was run ("skipped" is true if not), the "output" of the command and the "error" if it failed
+
+For example
+
+    rclone rc core/pipeline steps='[{"path":"vfs/refresh","params":{"recursive":true}},{"path":"core/stats","after":["step 1"]}]'
+
+Note that a failed pipeline doesn't return an error - check "ok".
+`,
+	})
+}
+
+// What to do when a pipeline step fails
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+)
+
+// pipelineStep is a single rc call in a pipeline
+type pipelineStep struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Params  Params   `json:"params"`
+	After   []string `json:"after"`
+	OnError string   `json:"onError"`
+
+	call *Call
+}
+
+// pipelineResult is the result of running a pipelineStep
+type pipelineResult struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Skipped bool   `json:"skipped"`
+	Output  Params `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// parsePipeline reads the steps from in and checks them
+func parsePipeline(in Params) (steps []*pipelineStep, err error) {
+	stepsIn, ok := in["steps"]
+	if !ok {
+		return nil, errors.New("need steps parameter")
+	}
+	// steps may be passed as a JSON string, eg from the command line
+	if s, ok := stepsIn.(string); ok {
+		err = json.Unmarshal([]byte(s), &steps)
+	} else {
+		err = Reshape(&steps, stepsIn)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read steps")
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("no steps in pipeline")
+	}
+	seen := map[string]bool{}
+	for i, step := range steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if seen[step.Name] {
+			return nil, errors.Errorf("duplicate step name %q", step.Name)
+		}
+		step.call = registry.get(step.Path)
+		if step.call == nil {
+			return nil, errors.Errorf("step %q: couldn't find method %q", step.Name, step.Path)
+		}
+		for _, after := range step.After {
+			if !seen[after] {
+				return nil, errors.Errorf("step %q: %q isn't an earlier step", step.Name, after)
+			}
+		}
+		switch step.OnError {
+		case "":
+			step.OnError = onErrorStop
+		case onErrorStop, onErrorContinue:
+		default:
+			return nil, errors.Errorf("step %q: onError must be %q or %q not %q", step.Name, onErrorStop, onErrorContinue, step.OnError)
+		}
+		if step.Params == nil {
+			step.Params = Params{}
+		}
+		seen[step.Name] = true
+	}
+	return steps, nil
+}
+
+// Run the steps of a pipeline
+func rcPipeline(in Params) (out Params, err error) {
+	steps, err := parsePipeline(in)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		results   = make([]pipelineResult, 0, len(steps))
+		succeeded = map[string]bool{}
+		stopped   = false
+		allOK     = true
+		ctx       = GetContext(in)
+	)
+	for _, step := range steps {
+		result := pipelineResult{
+			Name:    step.Name,
+			Path:    step.Path,
+			Skipped: stopped || ctx.Err() != nil,
+		}
+		for _, after := range step.After {
+			if !succeeded[after] {
+				result.Skipped = true
+			}
+		}
+		if result.Skipped {
+			fs.Debugf(nil, "rc: pipeline: skipping %q", step.Name)
+			allOK = false
+			results = append(results, result)
+			continue
+		}
+		fs.Debugf(nil, "rc: pipeline: running %q: %q with parameters %+v", step.Name, step.Path, step.Params)
+		if ctx, ok := in["_context"]; ok {
+			// pass the job context on so stopping the job stops the step
+			step.Params["_context"] = ctx
+		}
+		result.Output, err = step.call.Fn(step.Params)
+		delete(result.Output, "_context")
+		if err != nil {
+			fs.Errorf(nil, "rc: pipeline: %q failed: %v", step.Name, err)
+			result.Error = err.Error()
+			allOK = false
+			if step.OnError == onErrorStop {
+				stopped = true
+			}
+		} else {
+			succeeded[step.Name] = true
+		}
+		results = append(results, result)
+	}
+	out = Params{
+		"ok":    allOK,
+		"steps": results,
+	}
+	return out, nil
+}
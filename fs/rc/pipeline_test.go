@@ -0,0 +1,74 @@
+package rc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	call := registry.get("core/pipeline")
+	require.NotNil(t, call)
+
+	out, err := call.Fn(Params{
+		"steps": `[
+			{"name": "a", "path": "rc/noop", "params": {"potato": 1}},
+			{"name": "b", "path": "rc/error", "onError": "continue"},
+			{"name": "c", "path": "rc/noop", "after": ["b"]},
+			{"name": "d", "path": "rc/noop", "after": ["a"]},
+			{"name": "e", "path": "rc/error"},
+			{"name": "f", "path": "rc/noop"}
+		]`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, false, out["ok"])
+	results := out["steps"].([]pipelineResult)
+	require.Len(t, results, 6)
+
+	assert.Equal(t, pipelineResult{Name: "a", Path: "rc/noop", Output: Params{"potato": float64(1)}}, results[0])
+	assert.Equal(t, "b", results[1].Name)
+	assert.False(t, results[1].Skipped)
+	assert.Contains(t, results[1].Error, "arbitrary error")
+	assert.True(t, results[2].Skipped, "c depends on b which failed")
+	assert.False(t, results[3].Skipped, "d runs as b continues")
+	assert.Equal(t, "", results[3].Error)
+	assert.NotEqual(t, "", results[4].Error)
+	assert.True(t, results[5].Skipped, "f skipped as e stops")
+
+	// All OK with default names and params passed directly
+	out, err = call.Fn(Params{
+		"steps": []interface{}{
+			map[string]interface{}{"path": "rc/noop"},
+			map[string]interface{}{"path": "core/pid", "after": []string{"step 1"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, true, out["ok"])
+	results = out["steps"].([]pipelineResult)
+	require.Len(t, results, 2)
+	assert.Equal(t, "step 1", results[0].Name)
+	assert.NotNil(t, results[1].Output["pid"])
+}
+
+func TestPipelineErrors(t *testing.T) {
+	call := registry.get("core/pipeline")
+	require.NotNil(t, call)
+	for _, test := range []struct {
+		steps string
+		want  string
+	}{
+		{``, "failed to read steps"},
+		{`[]`, "no steps"},
+		{`[{"path": "rc/potato"}]`, "couldn't find method"},
+		{`[{"name": "a", "path": "rc/noop"}, {"name": "a", "path": "rc/noop"}]`, "duplicate step name"},
+		{`[{"path": "rc/noop", "after": ["step 2"]}, {"path": "rc/noop"}]`, "isn't an earlier step"},
+		{`[{"path": "rc/noop", "onError": "explode"}]`, "onError must be"},
+	} {
+		_, err := call.Fn(Params{"steps": test.steps})
+		require.Error(t, err, test.steps)
+		assert.Contains(t, err.Error(), test.want, test.steps)
+	}
+	_, err := call.Fn(Params{})
+	assert.Error(t, err)
+}
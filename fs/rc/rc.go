@@ -14,30 +14,36 @@ import (
 	_ "net/http/pprof" // install the pprof http handlers
 
 	"strings"
+	"time"
 
 	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/sdactivation"
 	"github.com/pkg/errors"
 )
 
 // Options contains options for the remote control server
 type Options struct {
-	HTTPOptions httplib.Options
-	Enabled     bool
+	HTTPOptions       httplib.Options
+	Enabled           bool
+	JobExpireDuration time.Duration // how long finished async jobs are kept for
 }
 
 // DefaultOpt is the default values used for Options
 var DefaultOpt = Options{
-	HTTPOptions: httplib.DefaultOpt,
-	Enabled:     false,
+	HTTPOptions:       httplib.DefaultOpt,
+	Enabled:           false,
+	JobExpireDuration: JobExpireDuration,
 }
 
 func init() {
 	DefaultOpt.HTTPOptions.ListenAddr = "localhost:5572"
+	DefaultOpt.HTTPOptions.SocketName = sdactivation.RC
 }
 
 // Start the remote control server if configured
 func Start(opt *Options) {
+	SetJobExpireDuration(opt.JobExpireDuration)
 	if opt.Enabled {
 		s := newServer(opt)
 		go s.serve()
@@ -137,6 +143,7 @@ func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 
 func (s *server) handlePost(w http.ResponseWriter, r *http.Request, path string, in Params) {
 	writeError := func(err error, status int) {
+		delete(in, "_response")
 		fs.Errorf(nil, "rc: %q: error: %v", path, err)
 		w.WriteHeader(status)
 		err = WriteJSON(w, Params{
@@ -156,12 +163,32 @@ func (s *server) handlePost(w http.ResponseWriter, r *http.Request, path string,
 		return
 	}
 
+	// Check to see if it should be run as an async job
+	async, err := in.GetBool("_async")
+	if err != nil && !IsErrParamNotFound(err) {
+		writeError(err, http.StatusBadRequest)
+		return
+	}
+	delete(in, "_async")
+
 	fs.Debugf(nil, "rc: %q: with parameters %+v", path, in)
-	out, err := call.Fn(in)
+	var out Params
+	if async {
+		out, err = StartJob(call, in)
+	} else {
+		if call.NeedsResponse {
+			in["_response"] = w
+		}
+		out, err = call.Fn(in)
+	}
 	if err != nil {
 		writeError(errors.Wrap(err, "remote control command failed"), http.StatusInternalServerError)
 		return
 	}
+	if out == nil && call.NeedsResponse {
+		// the call wrote the response itself
+		return
+	}
 
 	fs.Debugf(nil, "rc: %q: reply %+v: %v", path, out, err)
 	err = WriteJSON(w, out)
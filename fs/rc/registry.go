@@ -19,10 +19,11 @@ type Func func(in Params) (out Params, err error)
 // Call defines info about a remote control function and is used in
 // the Add function to create new entry points.
 type Call struct {
-	Path  string // path to activate this RC
-	Fn    Func   `json:"-"` // function to call
-	Title string // help for the function
-	Help  string // multi-line markdown formatted help
+	Path          string // path to activate this RC
+	Fn            Func   `json:"-"` // function to call
+	Title         string // help for the function
+	Help          string // multi-line markdown formatted help
+	NeedsResponse bool   // set to pass the http.ResponseWriter in as "_response"
 }
 
 // Registry holds the list of all the registered remote control functions
@@ -77,3 +78,8 @@ var registry = NewRegistry()
 func Add(call Call) {
 	registry.add(call)
 }
+
+// Get a Call from the global registry by path or nil if not found
+func Get(path string) *Call {
+	return registry.get(path)
+}
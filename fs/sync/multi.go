@@ -0,0 +1,114 @@
+// Copy a directory to several destinations reading the source once
+
+package sync
+
+import (
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// listObjects returns the objects in f by remote
+func listObjects(f fs.Fs) (map[string]fs.Object, error) {
+	objs, _, err := walk.GetAll(f, "", false, fs.Config.MaxDepth)
+	if err == fs.ErrorDirNotFound {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]fs.Object, len(objs))
+	for _, o := range objs {
+		objects[o.Remote()] = o
+	}
+	return objects, nil
+}
+
+// CopyDirMulti copies fsrc into each of fdsts.
+//
+// Each file which needs copying to more than one destination is read
+// once and uploaded to them all at the same time with
+// operations.CopyMulti so the source is only downloaded once.
+func CopyDirMulti(fdsts []fs.Fs, fsrc fs.Fs) error {
+	if len(fdsts) == 1 {
+		return CopyDir(fdsts[0], fsrc)
+	}
+	for _, fdst := range fdsts {
+		if operations.Same(fdst, fsrc) {
+			return errors.Errorf("can't copy %v to itself", fdst)
+		}
+	}
+	srcObjects, err := listObjects(fsrc)
+	if err != nil {
+		return errors.Wrap(err, "failed to list source")
+	}
+	dstObjects := make([]map[string]fs.Object, len(fdsts))
+	for i, fdst := range fdsts {
+		err = operations.Mkdir(fdst, "")
+		if err != nil {
+			return err
+		}
+		dstObjects[i], err = listObjects(fdst)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list destination %v", fdst)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		lastErr error
+		wg      sync.WaitGroup
+		todo    = make(chan fs.Object, fs.Config.Transfers)
+	)
+	for i := 0; i < fs.Config.Transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for src := range todo {
+				remote := src.Remote()
+				var needFs []fs.Fs
+				var needDsts []fs.Object
+				var err error
+				accounting.Stats.Checking(remote)
+				for i, fdst := range fdsts {
+					dst := dstObjects[i][remote]
+					if !operations.NeedTransfer(dst, src) {
+						continue
+					}
+					if dst != nil && fs.Config.Immutable {
+						fs.Errorf(dst, "Source and destination exist but do not match: immutable file modified")
+						err = fs.ErrorImmutableModified
+						fs.CountError(err)
+						continue
+					}
+					needFs = append(needFs, fdst)
+					needDsts = append(needDsts, dst)
+				}
+				accounting.Stats.DoneChecking(remote)
+				if len(needFs) > 0 {
+					accounting.Stats.Transferring(remote)
+					copyErr := operations.CopyMulti(needFs, needDsts, remote, src)
+					accounting.Stats.DoneTransferring(remote, copyErr == nil)
+					if copyErr != nil {
+						err = copyErr
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					lastErr = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, src := range srcObjects {
+		todo <- src
+	}
+	close(todo)
+	wg.Wait()
+	return lastErr
+}
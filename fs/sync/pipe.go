@@ -2,9 +2,16 @@ package sync
 
 import (
 	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fs/limiter"
+	"github.com/pkg/errors"
 )
 
 // pipe provides an unbounded channel like experience
@@ -13,17 +20,156 @@ import (
 type pipe struct {
 	mu        sync.Mutex
 	c         chan struct{}
-	queue     []fs.ObjectPair
+	queue     []pipeItem
+	waiting   []fs.ObjectPair // pairs taken by GetLimited which couldn't start yet
 	closed    bool
 	totalSize int64
 	stats     func(items int, totalSize int64)
+	less      lessFn              // if set the queue is kept sorted with this
+	fraction  int                 // percentage of gets from the start of the queue if mixed, or -1
+	gets      int                 // number of gets so far for mixed
+	schedule  string              // --dir-schedule: "", "depth" or "breadth"
+	dirs      map[string]*pipeDir // directories seen for schedule
 }
 
-func newPipe(stats func(items int, totalSize int64), maxBacklog int) *pipe {
+// pipeItem is a pair in the queue with its place in the schedule
+type pipeItem struct {
+	pair  fs.ObjectPair
+	dir   int // order its directory was first seen in
+	round int // number of pairs put from its directory before it
+}
+
+// pipeDir is a directory of pairs put into the pipe
+type pipeDir struct {
+	seq int // order this directory was first seen in
+	n   int // number of pairs put from this directory
+}
+
+// lessFn returns true if a should be transferred before b
+type lessFn func(a, b fs.ObjectPair) bool
+
+// newPipe makes a pipe ordered according to orderBy which is in the
+// format of --order-by, or unordered if it is empty.
+//
+// schedule is the format of --dir-schedule - "depth" gets all the
+// pairs from one directory before the next, "breadth" gets a pair
+// from each directory in turn.  orderBy then orders the pairs within
+// each directory for depth, or each turn for breadth.
+func newPipe(orderBy string, schedule string, stats func(items int, totalSize int64), maxBacklog int) (*pipe, error) {
+	less, fraction, err := newLess(orderBy)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	schedule = strings.ToLower(schedule)
+	switch schedule {
+	case "", "depth", "breadth":
+	default:
+		return nil, fserrors.FatalError(errors.Errorf("unknown --dir-schedule %q - must be depth or breadth", schedule))
+	}
+	if schedule != "" && fraction >= 0 {
+		return nil, fserrors.FatalError(errors.New("can't use --order-by mixed with --dir-schedule"))
+	}
 	return &pipe{
-		c:     make(chan struct{}, maxBacklog),
-		stats: stats,
+		c:        make(chan struct{}, maxBacklog),
+		stats:    stats,
+		less:     less,
+		fraction: fraction,
+		schedule: schedule,
+		dirs:     make(map[string]*pipeDir),
+	}, nil
+}
+
+// newItem makes a pipeItem for pair, noting its directory for the
+// schedule - call with the lock held
+func (p *pipe) newItem(pair fs.ObjectPair) (item pipeItem) {
+	item.pair = pair
+	if p.schedule == "" {
+		return item
+	}
+	dirPath := path.Dir(pair.Src.Remote())
+	dir := p.dirs[dirPath]
+	if dir == nil {
+		dir = &pipeDir{seq: len(p.dirs)}
+		p.dirs[dirPath] = dir
+	}
+	item.dir, item.round = dir.seq, dir.n
+	dir.n++
+	return item
+}
+
+// before returns true if a should be got before b
+func (p *pipe) before(a, b pipeItem) bool {
+	switch p.schedule {
+	case "depth":
+		if a.dir != b.dir {
+			return a.dir < b.dir
+		}
+	case "breadth":
+		if a.round != b.round {
+			return a.round < b.round
+		}
+	}
+	if p.less != nil {
+		return p.less(a.pair, b.pair)
+	}
+	return false
+}
+
+// newLess parses orderBy which should be in the form
+// "key[,modifier[,N]]" where key is size, name or modtime and modifier
+// is ascending, descending or mixed.  For mixed, N is the percentage
+// of the gets which should take the first item in ascending order -
+// the rest take the last.
+func newLess(orderBy string) (less lessFn, fraction int, err error) {
+	fraction = -1
+	if orderBy == "" {
+		return nil, fraction, nil
+	}
+	parts := strings.Split(strings.ToLower(orderBy), ",")
+	switch parts[0] {
+	case "name":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.Remote() < b.Src.Remote()
+		}
+	case "size":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.Size() < b.Src.Size()
+		}
+	case "modtime":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.ModTime().Before(b.Src.ModTime())
+		}
+	default:
+		return nil, fraction, errors.Errorf("unknown --order-by comparison %q", parts[0])
+	}
+	descending := false
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "ascending", "asc":
+		case "descending", "desc":
+			descending = true
+		case "mixed":
+			fraction = 50
+			if len(parts) > 2 {
+				fraction, err = strconv.Atoi(parts[2])
+				if err != nil || fraction < 0 || fraction > 100 {
+					return nil, -1, errors.Errorf("bad mixed percentage %q in --order-by - must be 0 to 100", parts[2])
+				}
+			}
+		default:
+			return nil, -1, errors.Errorf("unknown --order-by modifier %q", parts[1])
+		}
+	}
+	if len(parts) > 3 || (len(parts) > 2 && fraction < 0) {
+		return nil, -1, errors.Errorf("too many parts in --order-by %q", orderBy)
 	}
+	if descending {
+		ascending := less
+		less = func(a, b fs.ObjectPair) bool {
+			return ascending(b, a)
+		}
+	}
+	return less, fraction, nil
 }
 
 // Put an pair into the pipe
@@ -36,12 +182,23 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 		return false
 	}
 	p.mu.Lock()
-	p.queue = append(p.queue, pair)
+	item := p.newItem(pair)
+	if p.less == nil && p.schedule == "" {
+		p.queue = append(p.queue, item)
+	} else {
+		// Insert after any equal pairs to keep the order stable
+		i := sort.Search(len(p.queue), func(i int) bool {
+			return p.before(item, p.queue[i])
+		})
+		p.queue = append(p.queue, pipeItem{})
+		copy(p.queue[i+1:], p.queue[i:])
+		p.queue[i] = item
+	}
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize += size
 	}
-	p.stats(len(p.queue), p.totalSize)
+	p.stats(len(p.queue)+len(p.waiting), p.totalSize)
 	p.mu.Unlock()
 	select {
 	case <-ctx.Done():
@@ -56,19 +213,50 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 // It returns ok = false if the context was cancelled or Close() has
 // been called.
 func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
+	pair, ok, _ = p.get(ctx, nil)
+	return pair, ok
+}
+
+// get a pair from the pipe as Get does, but return woken = true
+// without a pair if wake is closed first.
+func (p *pipe) get(ctx context.Context, wake <-chan struct{}) (pair fs.ObjectPair, ok bool, woken bool) {
 	if ctx.Err() != nil {
 		return
 	}
 	select {
 	case <-ctx.Done():
 		return
+	case <-wake:
+		return pair, false, true
 	case _, ok = <-p.c:
 		if !ok {
 			return
 		}
 	}
 	p.mu.Lock()
-	pair, p.queue = p.queue[0], p.queue[1:]
+	var item pipeItem
+	if p.fraction >= 0 && !p.takeFirst() {
+		last := len(p.queue) - 1
+		item, p.queue = p.queue[last], p.queue[:last]
+	} else {
+		item, p.queue = p.queue[0], p.queue[1:]
+	}
+	pair = item.pair
+	p.remove(pair)
+	p.mu.Unlock()
+	return pair, true, false
+}
+
+// takeFirst returns whether this get should take the first item in
+// the queue when mixed, spreading them evenly through the gets - call
+// with the lock held
+func (p *pipe) takeFirst() bool {
+	p.gets++
+	return p.gets*p.fraction/100 != (p.gets-1)*p.fraction/100
+}
+
+// remove accounts for pair leaving the pipe - call with the lock held
+func (p *pipe) remove(pair fs.ObjectPair) {
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize -= size
@@ -76,15 +264,82 @@ func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
 	if p.totalSize < 0 {
 		p.totalSize = 0
 	}
-	p.stats(len(p.queue), p.totalSize)
-	p.mu.Unlock()
-	return pair, true
+	p.stats(len(p.queue)+len(p.waiting), p.totalSize)
+}
+
+// GetLimited gets a pair from the pipe which try says can start.
+//
+// try should call a limiter function such as limiter.TryTransfer and
+// return what it returns.  The done function returned with the pair
+// must be called when the work on it has finished.
+//
+// Pairs which can't start yet because their remotes are at their
+// limits are kept back and the next pair is tried instead, so a
+// worker isn't stuck waiting for one remote while there is work for
+// others.  The pairs kept back are started as soon as the limits
+// allow.
+//
+// It returns ok = false if the context was cancelled or Close() has
+// been called and all the pairs have been got.
+func (p *pipe) GetLimited(ctx context.Context, try func(pair fs.ObjectPair) (done func(), ok bool)) (pair fs.ObjectPair, done func(), ok bool) {
+	for {
+		// Read this before trying so a release isn't missed
+		released := limiter.Released()
+
+		// Try the pairs already kept back first
+		p.mu.Lock()
+		for i, waiting := range p.waiting {
+			if done, ok = try(waiting); ok {
+				p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+				p.remove(waiting)
+				p.mu.Unlock()
+				return waiting, done, true
+			}
+		}
+		p.mu.Unlock()
+
+		pair, ok, woken := p.get(ctx, released)
+		if woken {
+			continue
+		}
+		if !ok {
+			if ctx.Err() != nil {
+				return pair, nil, false
+			}
+			// Closed so wait for the pairs kept back
+			p.mu.Lock()
+			nWaiting := len(p.waiting)
+			p.mu.Unlock()
+			if nWaiting == 0 {
+				return pair, nil, false
+			}
+			select {
+			case <-ctx.Done():
+				return pair, nil, false
+			case <-released:
+			}
+			continue
+		}
+		if done, ok = try(pair); ok {
+			return pair, done, true
+		}
+
+		// Keep the pair back until its remotes are free
+		p.mu.Lock()
+		p.waiting = append(p.waiting, pair)
+		size := pair.Src.Size()
+		if size > 0 {
+			p.totalSize += size
+		}
+		p.stats(len(p.queue)+len(p.waiting), p.totalSize)
+		p.mu.Unlock()
+	}
 }
 
 // Stats reads the number of items in the queue and the totalSize
 func (p *pipe) Stats() (items int, totalSize int64) {
 	p.mu.Lock()
-	items, totalSize = len(p.queue), p.totalSize
+	items, totalSize = len(p.queue)+len(p.waiting), p.totalSize
 	p.mu.Unlock()
 	return items, totalSize
 }
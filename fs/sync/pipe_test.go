@@ -5,10 +5,14 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/limiter"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPipe(t *testing.T) {
@@ -19,7 +23,8 @@ func TestPipe(t *testing.T) {
 	}
 
 	// Make a new pipe
-	p := newPipe(stats, 10)
+	p, err := newPipe("", "", stats, 10)
+	require.NoError(t, err)
 
 	checkStats := func(expectedN int, expectedSize int64) {
 		n, size := p.Stats()
@@ -60,7 +65,8 @@ func TestPipe(t *testing.T) {
 	assert.Panics(t, func() { p.Put(ctx, pair1) })
 
 	// Make a new pipe
-	p = newPipe(stats, 10)
+	p, err = newPipe("", "", stats, 10)
+	require.NoError(t, err)
 	ctx2, cancel := context.WithCancel(ctx)
 
 	// cancel it in the background - check read ceases
@@ -86,7 +92,8 @@ func TestPipeConcurrent(t *testing.T) {
 	stats := func(n int, size int64) {}
 
 	// Make a new pipe
-	p := newPipe(stats, 10)
+	p, err := newPipe("", "", stats, 10)
+	require.NoError(t, err)
 
 	var wg sync.WaitGroup
 	obj1 := mockobject.New("potato").WithContent([]byte("hello"), mockobject.SeekModeNone)
@@ -120,3 +127,197 @@ func TestPipeConcurrent(t *testing.T) {
 
 	assert.Equal(t, int64(0), count)
 }
+
+// limitInfo is an fs.Info for a remote called by its value
+type limitInfo string
+
+func (l limitInfo) Name() string             { return string(l) }
+func (l limitInfo) Root() string             { return "" }
+func (l limitInfo) String() string           { return string(l) + ":" }
+func (l limitInfo) Precision() time.Duration { return time.Second }
+func (l limitInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (l limitInfo) Features() *fs.Features   { return &fs.Features{} }
+
+func TestPipeGetLimited(t *testing.T) {
+	oldConfigFileGet := fs.ConfigFileGet
+	fs.ConfigFileGet = func(section, key string) (string, bool) {
+		switch {
+		case section != "pipeslow":
+		case key == "type":
+			return "local", true
+		case key == limiter.TransfersKey:
+			return "1", true
+		}
+		return "", false
+	}
+	defer func() {
+		fs.ConfigFileGet = oldConfigFileGet
+	}()
+
+	stats := func(n int, size int64) {}
+	p, err := newPipe("", "", stats, 10)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// The remote each object is on
+	remotes := map[string]fs.Info{
+		"slow1": limitInfo("pipeslow"),
+		"slow2": limitInfo("pipeslow"),
+		"fast":  limitInfo("pipefast"),
+	}
+	try := func(pair fs.ObjectPair) (func(), bool) {
+		return limiter.TryTransfer(remotes[pair.Src.Remote()])
+	}
+	for _, remote := range []string{"slow1", "slow2", "fast"} {
+		require.True(t, p.Put(ctx, fs.ObjectPair{Src: mockobject.Object(remote)}))
+	}
+	p.Close()
+
+	pair, done1, ok := p.GetLimited(ctx, try)
+	require.True(t, ok)
+	assert.Equal(t, "slow1", pair.Src.Remote())
+
+	// slow2 can't start so fast is returned instead
+	pair, done2, ok := p.GetLimited(ctx, try)
+	require.True(t, ok)
+	assert.Equal(t, "fast", pair.Src.Remote())
+	done2()
+	items, _ := p.Stats()
+	assert.Equal(t, 1, items)
+
+	// slow2 is returned once slow1 is done
+	got := make(chan string)
+	go func() {
+		pair, done, ok := p.GetLimited(ctx, try)
+		assert.True(t, ok)
+		done()
+		got <- pair.Src.Remote()
+	}()
+	select {
+	case remote := <-got:
+		t.Fatalf("got %q before slow1 was done", remote)
+	case <-time.After(50 * time.Millisecond):
+	}
+	done1()
+	assert.Equal(t, "slow2", <-got)
+
+	// Nothing left
+	_, _, ok = p.GetLimited(ctx, try)
+	assert.False(t, ok)
+}
+
+func TestNewLess(t *testing.T) {
+	for _, test := range []struct {
+		orderBy  string
+		wantLess bool
+		fraction int
+		wantErr  bool
+	}{
+		{"", false, -1, false},
+		{"size", true, -1, false},
+		{"Name,Descending", true, -1, false},
+		{"modtime,asc", true, -1, false},
+		{"size,mixed", true, 50, false},
+		{"size,mixed,25", true, 25, false},
+		{"potato", false, -1, true},
+		{"size,potato", false, -1, true},
+		{"size,mixed,101", false, -1, true},
+		{"size,mixed,x", false, -1, true},
+		{"size,descending,25", false, -1, true},
+		{"size,mixed,25,1", false, -1, true},
+	} {
+		less, fraction, err := newLess(test.orderBy)
+		what := test.orderBy
+		if test.wantErr {
+			assert.Error(t, err, what)
+			continue
+		}
+		require.NoError(t, err, what)
+		assert.Equal(t, test.wantLess, less != nil, what)
+		assert.Equal(t, test.fraction, fraction, what)
+	}
+}
+
+func TestPipeOrderBy(t *testing.T) {
+	stats := func(n int, size int64) {}
+	ctx := context.Background()
+	obj := func(remote string, size int) fs.Object {
+		return mockobject.New(remote).WithContent(make([]byte, size), mockobject.SeekModeNone)
+	}
+	objects := []fs.Object{obj("b", 2), obj("d", 3), obj("a", 1), obj("c", 3), obj("e", 0)}
+
+	for _, test := range []struct {
+		orderBy string
+		want    []string
+	}{
+		{"", []string{"b", "d", "a", "c", "e"}},
+		{"name", []string{"a", "b", "c", "d", "e"}},
+		{"name,descending", []string{"e", "d", "c", "b", "a"}},
+		{"size", []string{"e", "a", "b", "d", "c"}},
+		{"size,desc", []string{"d", "c", "b", "a", "e"}},
+		{"size,mixed", []string{"c", "e", "d", "a", "b"}},
+		{"size,mixed,0", []string{"c", "d", "b", "a", "e"}},
+		{"size,mixed,100", []string{"e", "a", "b", "d", "c"}},
+	} {
+		p, err := newPipe(test.orderBy, "", stats, 10)
+		require.NoError(t, err)
+		for _, o := range objects {
+			require.True(t, p.Put(ctx, fs.ObjectPair{Src: o}))
+		}
+		p.Close()
+		var got []string
+		for {
+			pair, ok := p.Get(ctx)
+			if !ok {
+				break
+			}
+			got = append(got, pair.Src.Remote())
+		}
+		assert.Equal(t, test.want, got, test.orderBy)
+	}
+
+	_, err := newPipe("potato", "", stats, 10)
+	assert.Error(t, err)
+}
+
+func TestPipeDirSchedule(t *testing.T) {
+	stats := func(n int, size int64) {}
+	ctx := context.Background()
+	obj := func(remote string, size int) fs.Object {
+		return mockobject.New(remote).WithContent(make([]byte, size), mockobject.SeekModeNone)
+	}
+	objects := []fs.Object{obj("a/1", 1), obj("b/1", 2), obj("a/2", 3), obj("c/1", 1), obj("b/2", 1), obj("a/3", 2)}
+
+	for _, test := range []struct {
+		orderBy  string
+		schedule string
+		want     []string
+	}{
+		{"", "depth", []string{"a/1", "a/2", "a/3", "b/1", "b/2", "c/1"}},
+		{"", "breadth", []string{"a/1", "b/1", "c/1", "a/2", "b/2", "a/3"}},
+		{"size,desc", "Depth", []string{"a/2", "a/3", "a/1", "b/1", "b/2", "c/1"}},
+		{"size,desc", "breadth", []string{"b/1", "a/1", "c/1", "a/2", "b/2", "a/3"}},
+	} {
+		what := test.orderBy + "/" + test.schedule
+		p, err := newPipe(test.orderBy, test.schedule, stats, 10)
+		require.NoError(t, err, what)
+		for _, o := range objects {
+			require.True(t, p.Put(ctx, fs.ObjectPair{Src: o}))
+		}
+		p.Close()
+		var got []string
+		for {
+			pair, ok := p.Get(ctx)
+			if !ok {
+				break
+			}
+			got = append(got, pair.Src.Remote())
+		}
+		assert.Equal(t, test.want, got, what)
+	}
+
+	_, err := newPipe("", "potato", stats, 10)
+	assert.Error(t, err)
+	_, err = newPipe("size,mixed", "depth", stats, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+// Remote control calls for sync
+
+package sync
+
+import (
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+)
+
+func init() {
+	for _, name := range []string{"sync", "copy", "move"} {
+		name := name
+		moveHelp := ""
+		if name == "move" {
+			moveHelp = "- deleteEmptySrcDirs - delete empty src directories if set\n"
+		}
+		rc.Add(rc.Call{
+			Path: "sync/" + name,
+			Fn: func(in rc.Params) (rc.Params, error) {
+				return rcSyncCopyMove(in, name)
+			},
+			Title: name + " a directory from source remote to destination remote",
+			Help: `This takes the following parameters
+
+- srcFs - a remote name string eg "drive:src" for the source
+- dstFs - a remote name string eg "drive:dst" for the destination
+` + moveHelp + `
+This returns
+
+- {} - empty dictionary on success
+
+See the [` + name + ` command](/commands/rclone_` + name + `/) for more
+information on the above.
+
+The call doesn't return until the ` + name + ` has finished, so use
+core/stats to see its progress, or run it with "_async" set to get a
+job ID and use job/status to see when it has finished and job/stop to
+stop it.
+`,
+		})
+	}
+}
+
+// Sync/Copy/Move a file
+func rcSyncCopyMove(in rc.Params, name string) (out rc.Params, err error) {
+	srcFs, err := rc.GetFsNamed(in, "srcFs")
+	if err != nil {
+		return nil, err
+	}
+	dstFs, err := rc.GetFsNamed(in, "dstFs")
+	if err != nil {
+		return nil, err
+	}
+	deleteEmptySrcDirs, err := in.GetBool("deleteEmptySrcDirs")
+	if rc.IsErrParamNotFound(err) {
+		deleteEmptySrcDirs = false
+	} else if err != nil {
+		return nil, err
+	}
+	ctx := rc.GetContext(in)
+	switch name {
+	case "sync":
+		err = runSyncCopyMove(ctx, dstFs, srcFs, "", fs.Config.DeleteMode, false, false)
+	case "copy":
+		err = runSyncCopyMove(ctx, dstFs, srcFs, "", fs.DeleteModeOff, false, false)
+	case "move":
+		err = moveDirContext(ctx, dstFs, srcFs, deleteEmptySrcDirs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, nil
+}
@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rcCall(t *testing.T, path string, in rc.Params) (rc.Params, error) {
+	call := rc.Get(path)
+	require.NotNil(t, call, path)
+	return call.Fn(in)
+}
+
+func TestRcCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteObject("file2", "file2 contents", t2)
+
+	out, err := rcCall(t, "sync/copy", rc.Params{
+		"srcFs": r.LocalName,
+		"dstFs": r.FremoteName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{}, out)
+
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestRcSync(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	r.WriteObject("file2", "file2 contents", t2)
+
+	_, err := rcCall(t, "sync/sync", rc.Params{
+		"srcFs": r.LocalName,
+		"dstFs": r.FremoteName,
+	})
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+func TestRcMove(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+
+	_, err := rcCall(t, "sync/move", rc.Params{
+		"srcFs":              r.LocalName,
+		"dstFs":              r.FremoteName,
+		"deleteEmptySrcDirs": true,
+	})
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	_, err = rcCall(t, "sync/move", rc.Params{
+		"srcFs": r.LocalName,
+	})
+	assert.Error(t, err)
+}
+
+func TestRcSyncStopped(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteObject("file2", "file2 contents", t2)
+
+	// a sync whose job has been stopped mustn't delete anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := rcCall(t, "sync/sync", rc.Params{
+		"srcFs":    r.LocalName,
+		"dstFs":    r.FremoteName,
+		"_context": ctx,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sync stopped")
+
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file2)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/limiter"
+	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/march"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
@@ -28,6 +30,7 @@ type syncCopyMove struct {
 	dir                string
 	// internal state
 	ctx            context.Context        // internal context for controlling go-routines
+	stopCtx        context.Context        // context passed in to stop the sync from outside
 	cancel         func()                 // cancel the context
 	deletersWg     sync.WaitGroup         // for delete before go routine
 	deleteFilesCh  chan fs.Object         // channel to receive deletes if delete before
@@ -60,9 +63,10 @@ type syncCopyMove struct {
 	renameCheck    []fs.Object            // accumulate files to check for rename here
 	backupDir      fs.Fs                  // place to store overwrites/deletes
 	suffix         string                 // suffix to add to files placed in backupDir
+	compareOrCopy  fs.Fs                  // --compare-dest or --copy-dest directory
 }
 
-func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
+func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
 	s := &syncCopyMove{
 		fdst:               fdst,
 		fsrc:               fsrc,
@@ -75,15 +79,26 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		dstFilesResult:     make(chan error, 1),
 		dstEmptyDirs:       make(map[string]fs.DirEntry),
 		srcEmptyDirs:       make(map[string]fs.DirEntry),
-		toBeChecked:        newPipe(accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog),
-		toBeUploaded:       newPipe(accounting.Stats.SetTransferQueue, fs.Config.MaxBacklog),
 		deleteFilesCh:      make(chan fs.Object, fs.Config.Checkers),
 		trackRenames:       fs.Config.TrackRenames,
 		commonHash:         fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
-		toBeRenamed:        newPipe(accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog),
 		trackRenamesCh:     make(chan fs.Object, fs.Config.Checkers),
 	}
-	s.ctx, s.cancel = context.WithCancel(context.Background())
+	var err error
+	s.toBeChecked, err = newPipe(fs.Config.OrderBy, fs.Config.DirSchedule, accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, err
+	}
+	s.toBeUploaded, err = newPipe(fs.Config.OrderBy, fs.Config.DirSchedule, accounting.Stats.SetTransferQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, err
+	}
+	s.toBeRenamed, err = newPipe(fs.Config.OrderBy, fs.Config.DirSchedule, accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, err
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.stopCtx = ctx
 	if s.trackRenames {
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
@@ -126,6 +141,27 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		}
 		s.suffix = fs.Config.Suffix
 	}
+	// Make Fs for --compare-dest or --copy-dest if required
+	if fs.Config.CompareDest != "" || fs.Config.CopyDest != "" {
+		flag, dir := "--compare-dest", fs.Config.CompareDest
+		if fs.Config.CopyDest != "" {
+			flag, dir = "--copy-dest", fs.Config.CopyDest
+		}
+		if DoMove {
+			return nil, fserrors.FatalError(errors.Errorf("can't use %s with move", flag))
+		}
+		var err error
+		s.compareOrCopy, err = fs.NewFs(dir)
+		if err != nil {
+			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for %s %q: %v", flag, dir, err))
+		}
+		if fs.Config.CopyDest != "" && !operations.SameConfig(fdst, s.compareOrCopy) {
+			return nil, fserrors.FatalError(errors.New("parameter to --copy-dest has to be on the same remote as destination"))
+		}
+		if operations.Overlapping(fdst, s.compareOrCopy) {
+			return nil, fserrors.FatalError(errors.Errorf("destination and parameter to %s mustn't overlap", flag))
+		}
+	}
 	return s, nil
 }
 
@@ -195,15 +231,29 @@ func (s *syncCopyMove) currentError() error {
 func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
-		pair, ok := in.Get(s.ctx)
+		pair, done, ok := in.GetLimited(s.ctx, func(pair fs.ObjectPair) (func(), bool) {
+			return limiter.TryCheck(s.fdst, pair.Src.Fs())
+		})
 		if !ok {
 			return
 		}
 		src := pair.Src
 		accounting.Stats.Checking(src.Remote())
 		// Check to see if can store this
-		if src.Storable() {
-			if operations.NeedTransfer(pair.Dst, pair.Src) {
+		storable := src.Storable()
+		needTransfer := storable && operations.NeedTransfer(pair.Dst, pair.Src)
+		done()
+		if needTransfer && s.compareOrCopy != nil {
+			noNeedTransfer, err := operations.CompareOrCopyDest(s.fdst, pair.Dst, src, s.compareOrCopy)
+			if err != nil {
+				s.processError(err)
+			}
+			if noNeedTransfer || err != nil {
+				needTransfer = false
+			}
+		}
+		if storable {
+			if needTransfer {
 				// If files are treated as immutable, fail if destination exists and does not match
 				if fs.Config.Immutable && pair.Dst != nil {
 					fs.Errorf(pair.Dst, "Source and destination exist but do not match: immutable file modified")
@@ -217,6 +267,7 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 						if err != nil {
 							s.processError(err)
 						} else {
+							operations.RecordOverwrite(pair.Dst, s.backupDir, remoteWithSuffix)
 							// If successful zero out the dst as it is no longer there and copy the file
 							pair.Dst = nil
 							ok = out.Put(s.ctx, pair)
@@ -268,7 +319,9 @@ func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, wg *sync.WaitGroup)
 	defer wg.Done()
 	var err error
 	for {
-		pair, ok := in.Get(s.ctx)
+		pair, done, ok := in.GetLimited(s.ctx, func(pair fs.ObjectPair) (func(), bool) {
+			return limiter.TryTransfer(fdst, pair.Src.Fs())
+		})
 		if !ok {
 			return
 		}
@@ -279,6 +332,7 @@ func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, wg *sync.WaitGroup)
 		} else {
 			_, err = operations.Copy(fdst, pair.Dst, src.Remote(), src)
 		}
+		done()
 		s.processError(err)
 		accounting.Stats.DoneTransferring(src.Remote(), err == nil)
 	}
@@ -472,6 +526,7 @@ func copyEmptyDirectories(f fs.Fs, entries map[string]fs.DirEntry) error {
 		dir, ok := entry.(fs.Directory)
 		if ok {
 			err := f.Mkdir(dir.Remote())
+			list.Changed(f, dir.Remote())
 			if err != nil {
 				fs.Errorf(fs.LogDirName(f, dir.Remote()), "Failed to Mkdir: %v", err)
 				accounting.Stats.Error(err)
@@ -648,7 +703,7 @@ func (s *syncCopyMove) run() error {
 
 	// set up a march over fdst and fsrc
 	m := march.New(s.ctx, s.fdst, s.fsrc, s.dir, s)
-	m.Run()
+	marchErr := m.Run()
 
 	s.stopTrackRenames()
 	if s.trackRenames {
@@ -669,6 +724,18 @@ func (s *syncCopyMove) run() error {
 	s.stopTransfers()
 	s.stopDeleters()
 
+	// The directory to sync wasn't found in the source so there
+	// is nothing more to do
+	if marchErr != nil {
+		s.cancel()
+		return marchErr
+	}
+
+	// Don't carry on to the deletions if stopped from outside
+	if err := s.stopCtx.Err(); err != nil {
+		s.processError(fserrors.FatalError(errors.Wrap(err, "sync stopped")))
+	}
+
 	s.processError(copyEmptyDirectories(s.fdst, s.srcEmptyDirs))
 
 	// Delete files after
@@ -760,6 +827,12 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 				return
 			case s.trackRenamesCh <- x:
 			}
+		} else if s.compareOrCopy != nil {
+			// Check against --compare-dest or --copy-dest first
+			ok := s.toBeChecked.Put(s.ctx, fs.ObjectPair{Src: x, Dst: nil})
+			if !ok {
+				return
+			}
 		} else {
 			// No need to check since doesn't exist
 			ok := s.toBeUploaded.Put(s.ctx, fs.ObjectPair{Src: x, Dst: nil})
@@ -832,20 +905,31 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
+//
+// Cancelling ctx stops the sync
+func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, dir string, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (err error) {
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
+	// Report what was deleted at the end
+	mark := operations.UndoMark()
+	defer func() {
+		reportErr := operations.UndoReport(fdst, mark)
+		if err == nil {
+			err = reportErr
+		}
+	}()
 	// Run an extra pass to delete only
 	if deleteMode == fs.DeleteModeBefore {
 		if fs.Config.TrackRenames {
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
+		do, err := newSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
 		if err != nil {
 			return err
 		}
+		do.dir = dir
 		err = do.run()
 		if err != nil {
 			return err
@@ -853,30 +937,36 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
+	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
 	if err != nil {
 		return err
 	}
+	do.dir = dir
 	return do.run()
 }
 
 // Sync fsrc into fdst
 func Sync(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false)
+	return runSyncCopyMove(context.Background(), fdst, fsrc, "", fs.Config.DeleteMode, false, false)
 }
 
 // CopyDir copies fsrc into fdst
 func CopyDir(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false)
+	return runSyncCopyMove(context.Background(), fdst, fsrc, "", fs.DeleteModeOff, false, false)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
+func moveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+	return runSyncCopyMove(ctx, fdst, fsrc, "", fs.DeleteModeOff, true, deleteEmptySrcDirs)
 }
 
 // MoveDir moves fsrc into fdst
 func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+	return moveDirContext(context.Background(), fdst, fsrc, deleteEmptySrcDirs)
+}
+
+// moveDirContext moves fsrc into fdst - cancelling ctx stops the move
+func moveDirContext(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -890,6 +980,8 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 		}
 		fs.Debugf(fdst, "Using server side directory move")
 		err := fdstDirMove(fsrc, "", "")
+		list.ChangedTree(fsrc, "")
+		list.ChangedTree(fdst, "")
 		switch err {
 		case fs.ErrorCantDirMove, fs.ErrorDirExists:
 			fs.Infof(fdst, "Server side directory move failed - fallback to file moves: %v", err)
@@ -911,5 +1003,5 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc, deleteEmptySrcDirs)
+	return moveDir(ctx, fdst, fsrc, deleteEmptySrcDirs)
 }
@@ -3,6 +3,12 @@
 package sync
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -47,6 +53,44 @@ func TestCopyWithDryRun(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote)
 }
 
+// Test sync with --dry-run-json
+func TestSyncWithDryRunJSON(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteFile("new", "new file", t1)
+	file2 := r.WriteBoth("same", "same file", t1)
+	file3 := r.WriteObject("extra", "extra file", t1)
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+
+	var buf bytes.Buffer
+	oldOutput := operations.PlanOutput
+	operations.PlanOutput = &buf
+	fs.Config.DryRun = true
+	fs.Config.DryRunJSON = true
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal)
+	fs.Config.DryRun = false
+	fs.Config.DryRunJSON = false
+	operations.PlanOutput = oldOutput
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+
+	actions := map[string]operations.PlanEntry{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var entry operations.PlanEntry
+		require.NoError(t, decoder.Decode(&entry))
+		actions[entry.Action] = entry
+	}
+	require.Equal(t, 3, len(actions))
+	assert.Equal(t, "new file", actions[operations.PlanCopy].Reason)
+	assert.Contains(t, actions[operations.PlanCopy].Dst, "new")
+	assert.Equal(t, int64(8), actions[operations.PlanCopy].Size)
+	assert.Equal(t, "unchanged", actions[operations.PlanSkip].Reason)
+	assert.Contains(t, actions[operations.PlanDelete].Dst, "extra")
+	assert.Equal(t, "", actions[operations.PlanDelete].Src)
+}
+
 // Now without dry run
 func TestCopy(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -1117,6 +1161,74 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 func TestSyncBackupDir(t *testing.T)           { testSyncBackupDir(t, "") }
 func TestSyncBackupDirWithSuffix(t *testing.T) { testSyncBackupDir(t, ".bak") }
 
+// Test with --compare-dest
+func TestSyncCompareDest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.CompareDest = r.FremoteName + "/cmp"
+	defer func() {
+		fs.Config.CompareDest = ""
+	}()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	// one identical in the compare dir, two different, three missing
+	file1 := r.WriteObject("cmp/one", "one", t1)
+	file2 := r.WriteObject("cmp/two", "two", t1)
+	file1a := r.WriteFile("one", "one", t1)
+	file2a := r.WriteFile("two", "twoA", t2)
+	file3a := r.WriteFile("three", "three", t1)
+	fstest.CheckItems(t, r.Flocal, file1a, file2a, file3a)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	// only two and three should have been copied
+	file2a.Path = "dst/two"
+	file3a.Path = "dst/three"
+	fstest.CheckItems(t, r.Fremote, file1, file2, file2a, file3a)
+}
+
+// Test with --copy-dest
+func TestSyncCopyDest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.CopyDest = r.FremoteName + "/cpy"
+	defer func() {
+		fs.Config.CopyDest = ""
+	}()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	// one identical in the copy dir, two different, three missing
+	file1 := r.WriteObject("cpy/one", "one", t1)
+	file2 := r.WriteObject("cpy/two", "two", t1)
+	file1a := r.WriteFile("one", "one", t1)
+	file2a := r.WriteFile("two", "twoA", t2)
+	file3a := r.WriteFile("three", "three", t1)
+	fstest.CheckItems(t, r.Flocal, file1a, file2a, file3a)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	// one should have been copied from cpy, two and three uploaded
+	file1b := file1
+	file1b.Path = "dst/one"
+	file2a.Path = "dst/two"
+	file3a.Path = "dst/three"
+	fstest.CheckItems(t, r.Fremote, file1, file2, file1b, file2a, file3a)
+
+	// --copy-dest can't be used with move
+	_, err = newSyncCopyMove(context.Background(), fdst, r.Flocal, fs.DeleteModeOff, true, false)
+	assert.Error(t, err)
+}
+
 // Check we can sync two files with differing UTF-8 representations
 func TestSyncUTFNorm(t *testing.T) {
 	if runtime.GOOS == "darwin" {
@@ -1216,3 +1328,102 @@ func TestAbort(t *testing.T) {
 	err := Sync(r.Fremote, r.Flocal)
 	assert.Equal(t, accounting.ErrorMaxTransferLimitReached, err)
 }
+
+// Test Watch syncs the changes made to the source
+func TestWatch(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Watch(r.Fremote, r.Flocal, 100*time.Millisecond, 100*time.Millisecond, stop)
+	}()
+
+	// waitFor waits until the object remote exists (or not) in
+	// the destination
+	waitFor := func(remote string, exists bool) {
+		for i := 0; i < 100; i++ {
+			_, err := r.Fremote.NewObject(remote)
+			if (err == nil) == exists {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %q exists=%v", remote, exists)
+	}
+
+	// The initial sync
+	waitFor("file1", true)
+
+	// A new file in a new directory
+	file2 := r.WriteFile("sub dir/file2", "file2 contents", t2)
+	waitFor("sub dir/file2", true)
+
+	// A file removed
+	require.NoError(t, os.Remove(filepath.Join(r.LocalName, "file1")))
+	waitFor("file1", false)
+
+	close(stop)
+	require.NoError(t, <-done)
+	fstest.CheckItems(t, r.Fremote, file2)
+}
+
+// Test the watch syncs the nearest directory which exists when a
+// changed directory has been removed
+func TestWatchRemovedDir(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("a/one", "one", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	w := &watchSync{ctx: context.Background(), fdst: r.Fremote, fsrc: r.Flocal}
+	accounting.Stats.ResetCounters()
+	w.sync("a/b/c")
+	assert.Equal(t, int64(0), accounting.Stats.GetErrors())
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Test copying to more than one destination
+func TestCopyDirMulti(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	dir, err := ioutil.TempDir("", "rclone-copy-multi-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	fdst2, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	file1 := r.WriteFile("file1", "file one", t1)
+	file2 := r.WriteFile("sub dir/file2", "file two", t2)
+	_, err = operations.Copy(fdst2, nil, "file1", mustObject(t, r.Flocal, "file1"))
+	require.NoError(t, err)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDirMulti([]fs.Fs{r.Fremote, fdst2}, r.Flocal)
+	require.NoError(t, err)
+	// each file was read from the source once
+	assert.Equal(t, int64(2), accounting.Stats.GetTransfers())
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	fstest.CheckItems(t, fdst2, file1, file2)
+
+	// nothing to do the second time
+	accounting.Stats.ResetCounters()
+	err = CopyDirMulti([]fs.Fs{r.Fremote, fdst2}, r.Flocal)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), accounting.Stats.GetTransfers())
+}
+
+// mustObject finds remote on f
+func mustObject(t *testing.T, f fs.Fs, remote string) fs.Object {
+	o, err := f.NewObject(remote)
+	require.NoError(t, err)
+	return o
+}
@@ -0,0 +1,172 @@
+package sync
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/pkg/errors"
+)
+
+// Watch syncs fsrc into fdst and then keeps fdst in sync with fsrc
+// until stop is closed.
+//
+// If fsrc supports change notification then once the changes have
+// been quiet for debounce only the directories they were in are
+// synced again.  Otherwise the whole of fsrc is synced again every
+// pollInterval.
+//
+// Errors syncing are logged and counted but don't stop the watch.
+// The error count is reset before each sync.  Closing stop cancels
+// any sync in progress.
+func Watch(fdst, fsrc fs.Fs, debounce, pollInterval time.Duration, stop <-chan struct{}) error {
+	if debounce <= 0 {
+		debounce = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	w := &watchSync{
+		ctx:   ctx,
+		fdst:  fdst,
+		fsrc:  fsrc,
+		dirs:  make(map[string]struct{}),
+		kick:  make(chan struct{}, 1),
+		after: debounce,
+	}
+	doChangeNotify := fsrc.Features().ChangeNotify
+	if doChangeNotify != nil {
+		// Subscribe before the first sync so no changes are missed
+		pollIntervalChan := make(chan time.Duration, 1)
+		pollIntervalChan <- debounce
+		defer close(pollIntervalChan)
+		doChangeNotify(w.notify, pollIntervalChan)
+	} else {
+		fs.Logf(fsrc, "Can't be watched for changes so syncing it every %v", pollInterval)
+	}
+	w.sync("")
+	fs.Logf(fsrc, "Watching for changes")
+	var pollC <-chan time.Time
+	if doChangeNotify == nil && pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		pollC = ticker.C
+	}
+	var timer *time.Timer
+	var timerC <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case <-w.kick:
+			// restart the debounce timer for every change
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.NewTimer(w.after)
+			timerC = timer.C
+		case <-timerC:
+			timer, timerC = nil, nil
+			for _, dir := range w.changedDirs() {
+				w.sync(dir)
+			}
+		case <-pollC:
+			w.sync("")
+		}
+	}
+}
+
+// watchSync holds the state for Watch
+type watchSync struct {
+	ctx   context.Context // cancelled when the watch is stopped
+	fdst  fs.Fs
+	fsrc  fs.Fs
+	mu    sync.Mutex
+	dirs  map[string]struct{} // directories with changes not synced yet
+	kick  chan struct{}       // signalled on each change
+	after time.Duration       // debounce
+}
+
+// notify is called by the source with each changed entry
+func (w *watchSync) notify(remote string, entryType fs.EntryType) {
+	fs.Debugf(w.fsrc, "Change notified: %q", remote)
+	// Sync the directory the entry is in as that is where it was
+	// added to, removed from or changed
+	dir := ""
+	if remote != "" {
+		dir = path.Dir(remote)
+		if dir == "." {
+			dir = ""
+		}
+	}
+	w.mu.Lock()
+	w.dirs[dir] = struct{}{}
+	w.mu.Unlock()
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// changedDirs returns the directories with changes to sync, leaving
+// out any which are inside others in the list, and forgets them
+func (w *watchSync) changedDirs() (dirs []string) {
+	w.mu.Lock()
+	for dir := range w.dirs {
+		dirs = append(dirs, dir)
+	}
+	w.dirs = make(map[string]struct{})
+	w.mu.Unlock()
+	sort.Strings(dirs)
+	var out []string
+outer:
+	for _, dir := range dirs {
+		for _, parent := range out {
+			if parent == "" || strings.HasPrefix(dir, parent+"/") {
+				continue outer
+			}
+		}
+		out = append(out, dir)
+	}
+	return out
+}
+
+// sync the directory dir, or the nearest directory above it which
+// still exists in the source
+func (w *watchSync) sync(dir string) {
+	for w.ctx.Err() == nil {
+		fs.Infof(w.fsrc, "Syncing %q", dir)
+		// Each sync starts afresh, as with a retry, so an error in
+		// one doesn't stop deletions in all the rest
+		accounting.Stats.ResetErrors()
+		err := runSyncCopyMove(w.ctx, w.fdst, w.fsrc, dir, fs.Config.DeleteMode, false, false)
+		if errors.Cause(err) == fs.ErrorDirNotFound && dir != "" {
+			// The directory was removed so sync the one it was in
+			fs.Debugf(w.fsrc, "%q not found so syncing the directory above", dir)
+			dir = path.Dir(dir)
+			if dir == "." {
+				dir = ""
+			}
+			continue
+		}
+		if err != nil && w.ctx.Err() == nil {
+			fs.Errorf(w.fdst, "Failed to sync %q: %v", dir, err)
+			fs.CountError(err)
+		}
+		return
+	}
+}
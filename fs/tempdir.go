@@ -0,0 +1,73 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// spoolPrefix is the prefix of the files and directories rclone
+// spools data into so they can be found if they are left behind
+const spoolPrefix = "rclone-spool"
+
+// TempDir returns the directory rclone uses for temporary files.
+//
+// This is --temp-dir if set or the OS default otherwise.
+func TempDir() string {
+	if Config.TempDir != "" {
+		return Config.TempDir
+	}
+	return os.TempDir()
+}
+
+// SpoolFile creates a new temporary file in TempDir() to spool data
+// into.  The caller must remove it when done.
+func SpoolFile() (*os.File, error) {
+	return ioutil.TempFile(TempDir(), spoolPrefix)
+}
+
+// SpoolDir creates a new temporary directory in TempDir() to spool
+// data into.  The caller must remove it when done.
+func SpoolDir() (string, error) {
+	return ioutil.TempDir(TempDir(), spoolPrefix)
+}
+
+// CleanTempDir removes the spool files and directories in TempDir()
+// which haven't been modified for maxAge.  These are left behind if
+// rclone is killed during a transfer.
+func CleanTempDir(maxAge time.Duration) {
+	dir := TempDir()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		Debugf(nil, "Failed to read temporary directory %q: %v", dir, err)
+		return
+	}
+	var removed int
+	var size int64
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), spoolPrefix) || entry.ModTime().After(cutoff) {
+			continue
+		}
+		entryPath := filepath.Join(dir, entry.Name())
+		var entrySize int64
+		_ = filepath.Walk(entryPath, func(_ string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				entrySize += info.Size()
+			}
+			return nil
+		})
+		err = os.RemoveAll(entryPath)
+		if err != nil {
+			Errorf(nil, "Failed to remove old temporary file %q: %v", entryPath, err)
+			continue
+		}
+		removed++
+		size += entrySize
+	}
+	if removed > 0 {
+		Infof(nil, "Removed %d old temporary files using %v from %q", removed, SizeSuffix(size), dir)
+	}
+}
@@ -0,0 +1,50 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanTempDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-tempdir-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	oldTempDir := Config.TempDir
+	Config.TempDir = dir
+	defer func() { Config.TempDir = oldTempDir }()
+	assert.Equal(t, dir, TempDir())
+
+	old := time.Now().Add(-48 * time.Hour)
+	makeFile := func(name string, modTime time.Time) {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+		require.NoError(t, ioutil.WriteFile(p, []byte("potato"), 0600))
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+	}
+	makeFile("rclone-spool123", old)
+	makeFile("rclone-spool456/file", old)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "rclone-spool456"), old, old))
+	makeFile("rclone-spool789", time.Now())
+	makeFile("other", old)
+
+	f, err := SpoolFile()
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	CleanTempDir(24 * time.Hour)
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"other", filepath.Base(f.Name()), "rclone-spool789"}, names)
+}
@@ -56,7 +56,7 @@ type Func func(path string, entries fs.DirEntries, err error) error
 //
 // NB (f, path) to be replaced by fs.Dir at some point
 func Walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
-	if (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && f.Features().ListR != nil {
+	if (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && !fs.Config.UseListingsCache && f.Features().ListR != nil {
 		return walkListR(f, path, includeAll, maxLevel, fn)
 	}
 	return walkListDirSorted(f, path, includeAll, maxLevel, fn)
@@ -363,6 +363,9 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 			slashes := strings.Count(entry.Remote(), "/")
 			switch x := entry.(type) {
 			case fs.Object:
+				if list.IsHashSidecar(x.Remote()) {
+					continue
+				}
 				// Make sure we don't delete excluded files if not required
 				if includeAll || filter.Active.IncludeObject(x) {
 					if maxLevel < 0 || slashes <= maxLevel-1 {
@@ -454,7 +457,7 @@ func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir l
 //
 // NB (f, path) to be replaced by fs.Dir at some point
 func NewDirTree(f fs.Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
-	if ListR := f.Features().ListR; (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && ListR != nil {
+	if ListR := f.Features().ListR; (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && !fs.Config.UseListingsCache && ListR != nil {
 		return walkRDirTree(f, path, includeAll, maxLevel, ListR)
 	}
 	return walkNDirTree(f, path, includeAll, maxLevel, list.DirSorted)
@@ -0,0 +1,76 @@
+// Package health keeps track of the health of the parts of rclone
+// which are running so it can be reported to probes, eg a /healthz
+// endpoint.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Check returns nil if the thing it checks is healthy or an error
+// saying what is wrong
+type Check func() error
+
+var (
+	mu     sync.Mutex
+	checks = map[string]Check{}
+)
+
+// Add registers check under name, replacing any check already
+// registered with that name.  It returns a function to remove it.
+func Add(name string, check Check) (remove func()) {
+	mu.Lock()
+	checks[name] = check
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		delete(checks, name)
+		mu.Unlock()
+	}
+}
+
+// Status runs all the checks returning the errors from the failing
+// ones indexed by name.  It returns an empty map if everything is
+// healthy.
+func Status() map[string]error {
+	mu.Lock()
+	current := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		current[name] = check
+	}
+	mu.Unlock()
+	failed := map[string]error{}
+	for name, check := range current {
+		if err := check(); err != nil {
+			failed[name] = err
+		}
+	}
+	return failed
+}
+
+// Handler serves the health status for probes.
+//
+// It returns 200 and "ok" if all the checks pass, otherwise 503 and
+// a line for each failing check.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	failed := Status()
+	if len(failed) == 0 {
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintln(w, "ok")
+		return
+	}
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "%s: %v\n", name, failed[name])
+	}
+}
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get() *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	Handler(w, httptest.NewRequest("GET", "/healthz", nil))
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+
+	removeGood := Add("good", func() error { return nil })
+	w = get()
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	removeB := Add("b", func() error { return errors.New("b failed") })
+	removeA := Add("a", func() error { return errors.New("a failed") })
+	w = get()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "a: a failed\nb: b failed\n", w.Body.String())
+	assert.Len(t, Status(), 2)
+
+	removeA()
+	removeB()
+	removeGood()
+	assert.Len(t, Status(), 0)
+	w = get()
+	assert.Equal(t, http.StatusOK, w.Code)
+}
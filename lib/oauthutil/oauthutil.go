@@ -315,11 +315,12 @@ func doConfig(id, name string, m configmap.Mapper, errorHandler func(*http.Reque
 	auto, ok := m.Get(config.ConfigAutomatic)
 	automatic := ok && auto != ""
 
-	// See if already have a token
+	// See if already have a token - keep it if not asked, eg when
+	// the token is passed in to "rclone config create"
 	tokenString, ok := m.Get("token")
 	if ok && tokenString != "" {
 		fmt.Printf("Already have a token - refresh?\n")
-		if !config.Confirm() {
+		if !config.ConfirmWithDefault(false) {
 			return nil
 		}
 	}
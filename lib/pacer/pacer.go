@@ -24,6 +24,8 @@ type Pacer struct {
 	connTokens         chan struct{} // Connection tokens
 	calculatePace      func(bool)    // switchable pacing algorithm - call with mu held
 	consecutiveRetries int           // number of consecutive retries
+	limited            time.Time     // when the last retry happened
+	limitedSleep       time.Duration // the sleep time set by the last retry
 }
 
 // Type is for selecting different pacing algorithms
@@ -372,6 +374,10 @@ func (p *Pacer) endCall(retry bool) {
 		p.consecutiveRetries = 0
 	}
 	p.calculatePace(retry)
+	if retry {
+		p.limited = time.Now()
+		p.limitedSleep = p.sleepTime
+	}
 	p.mu.Unlock()
 }
 
@@ -0,0 +1,162 @@
+// Save the state of the pacers between runs
+
+package pacer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/pkg/errors"
+)
+
+// stateMaxAge is how old saved state can be before it is ignored
+const stateMaxAge = 24 * time.Hour
+
+// savedPacer is the state of a named pacer in the --pacer-state file
+type savedPacer struct {
+	Sleep   time.Duration `json:"sleep"`   // sleep time needed after the last rate limit
+	Limited time.Time     `json:"limited"` // when the remote last rate limited
+}
+
+// pacerState holds the pacer state loaded from and saved to the
+// --pacer-state file
+var pacerState = struct {
+	mu         sync.Mutex
+	loaded     bool
+	saved      map[string]savedPacer
+	pacers     map[string]*Pacer
+	registered bool
+}{
+	pacers: make(map[string]*Pacer),
+}
+
+// readPacerState reads the saved state from fileName.  A missing file
+// isn't an error.
+func readPacerState(fileName string) (map[string]savedPacer, error) {
+	saved := make(map[string]savedPacer)
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return saved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q", fileName)
+	}
+	return saved, nil
+}
+
+// writePacerState writes saved to fileName atomically
+func writePacerState(fileName string, saved map[string]savedPacer) error {
+	data, err := json.MarshalIndent(saved, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fileName)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// SetName sets the name the state of the pacer is saved under, which
+// should be the name of the remote, and restores the state saved with
+// that name if --pacer-state is set.
+//
+// This should be called after the other settings of the pacer as
+// they can reset the sleep time.
+func (p *Pacer) SetName(name string) *Pacer {
+	fileName := fs.Config.PacerState
+	if fileName == "" {
+		return p
+	}
+	pacerState.mu.Lock()
+	defer pacerState.mu.Unlock()
+	if !pacerState.loaded {
+		pacerState.loaded = true
+		saved, err := readPacerState(fileName)
+		if err != nil {
+			fs.Errorf(nil, "Failed to read --pacer-state: %v", err)
+			saved = make(map[string]savedPacer)
+		}
+		pacerState.saved = saved
+	}
+	if !pacerState.registered {
+		pacerState.registered = true
+		atexit.Register(savePacerState)
+	}
+	pacerState.pacers[name] = p
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	saved, ok := pacerState.saved[name]
+	if !ok || time.Since(saved.Limited) > stateMaxAge {
+		return p
+	}
+	sleep := saved.Sleep
+	if sleep > p.maxSleep {
+		sleep = p.maxSleep
+	}
+	if sleep > p.sleepTime {
+		p.sleepTime = sleep
+		p.limitedSleep = sleep
+		p.limited = saved.Limited
+		fs.Debugf("pacer", "%s: starting with sleep %v as rate limited at %v", name, sleep, saved.Limited.Format(time.RFC3339))
+	}
+	return p
+}
+
+// savePacerState writes the state of the named pacers to the
+// --pacer-state file, keeping the state of the remotes which weren't
+// used this time.
+func savePacerState() {
+	fileName := fs.Config.PacerState
+	pacerState.mu.Lock()
+	defer pacerState.mu.Unlock()
+	// re-read in case another rclone has written it
+	saved, err := readPacerState(fileName)
+	if err != nil {
+		fs.Errorf(nil, "Failed to read --pacer-state: %v", err)
+		saved = make(map[string]savedPacer)
+	}
+	for name, p := range pacerState.pacers {
+		p.mu.Lock()
+		limited, limitedSleep := p.limited, p.limitedSleep
+		p.mu.Unlock()
+		if limited.IsZero() || limited.Before(saved[name].Limited) {
+			continue
+		}
+		saved[name] = savedPacer{
+			Sleep:   limitedSleep,
+			Limited: limited,
+		}
+	}
+	for name, s := range saved {
+		if time.Since(s.Limited) > stateMaxAge {
+			delete(saved, name)
+		}
+	}
+	err = writePacerState(fileName, saved)
+	if err != nil {
+		fs.Errorf(nil, "Failed to write --pacer-state: %v", err)
+	}
+}
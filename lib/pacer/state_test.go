@@ -0,0 +1,93 @@
+package pacer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetPacerState forgets the loaded pacer state
+func resetPacerState() {
+	pacerState.mu.Lock()
+	pacerState.loaded = false
+	pacerState.saved = nil
+	pacerState.pacers = make(map[string]*Pacer)
+	pacerState.mu.Unlock()
+}
+
+func TestPacerState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-pacer-state")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldPacerState := fs.Config.PacerState
+	defer func() {
+		fs.Config.PacerState = oldPacerState
+		resetPacerState()
+	}()
+
+	// without --pacer-state nothing happens
+	fs.Config.PacerState = ""
+	resetPacerState()
+	p := New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("remote")
+	assert.Equal(t, time.Millisecond, p.GetSleep())
+	assert.Len(t, pacerState.pacers, 0)
+
+	fileName := filepath.Join(dir, "pacer.json")
+	fs.Config.PacerState = fileName
+	stale := time.Now().Add(-2 * stateMaxAge)
+	require.NoError(t, writePacerState(fileName, map[string]savedPacer{
+		"other": {Sleep: 50 * time.Millisecond, Limited: time.Now()},
+		"stale": {Sleep: 50 * time.Millisecond, Limited: stale},
+		"big":   {Sleep: time.Hour, Limited: time.Now()},
+	}))
+
+	// starts with the saved sleep time
+	resetPacerState()
+	p = New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("other")
+	assert.Equal(t, 50*time.Millisecond, p.GetSleep())
+
+	// which is limited to maxSleep
+	p = New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("big")
+	assert.Equal(t, time.Second, p.GetSleep())
+
+	// stale state is ignored
+	p = New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("stale")
+	assert.Equal(t, time.Millisecond, p.GetSleep())
+
+	// a remote which gets rate limited is saved
+	p = New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("remote")
+	p.beginCall()
+	p.endCall(true)
+	limitedSleep := p.GetSleep()
+	assert.True(t, limitedSleep > time.Millisecond)
+	p.beginCall()
+	p.endCall(false)
+	assert.True(t, p.GetSleep() < limitedSleep)
+
+	savePacerState()
+	saved, err := readPacerState(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, limitedSleep, saved["remote"].Sleep)
+	assert.Equal(t, 50*time.Millisecond, saved["other"].Sleep)
+	assert.Equal(t, time.Second, saved["big"].Sleep)
+	assert.NotContains(t, saved, "stale")
+
+	// and used next time
+	resetPacerState()
+	p = New().SetMinSleep(time.Millisecond).SetMaxSleep(time.Second).SetName("remote")
+	assert.Equal(t, limitedSleep, p.GetSleep())
+}
+
+func TestReadPacerStateMissing(t *testing.T) {
+	saved, err := readPacerState(filepath.Join(os.TempDir(), "rclone-pacer-state-not-found.json"))
+	require.NoError(t, err)
+	assert.Len(t, saved, 0)
+}
@@ -0,0 +1,110 @@
+// Package sdactivation implements systemd socket activation for the
+// rclone servers.
+//
+// When systemd starts rclone because a connection arrived on a socket
+// it is listening on, it passes the listening sockets in as file
+// descriptors 3 onwards and describes them in the LISTEN_PID,
+// LISTEN_FDS and LISTEN_FDNAMES environment variables.  Using these
+// sockets instead of listening on the configured address means rclone
+// can be started on demand and restarted without refusing any
+// connections.
+//
+// See http://0pointer.de/blog/projects/socket-activation.html
+package sdactivation
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// firstFD is the first file descriptor passed in by systemd
+const firstFD = 3
+
+// RC is the name of the socket the remote control server uses.  Set
+// this with FileDescriptorName=rc in the socket unit.
+const RC = "rc"
+
+// socket is a listening socket passed in by systemd
+type socket struct {
+	name     string
+	listener net.Listener
+	used     bool
+}
+
+var (
+	mu      sync.Mutex
+	once    sync.Once
+	sockets []*socket
+)
+
+// Listener returns the socket activated listener called name, or nil
+// if rclone wasn't socket activated or there is no socket of that
+// name left.
+//
+// If name is empty then the first socket not already used or named
+// RC is returned so that the servers work without the sockets being
+// named.
+func Listener(name string) net.Listener {
+	once.Do(func() {
+		sockets = listeners(os.Getenv, firstFD)
+		// Don't pass the sockets on to child processes
+		for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+			_ = os.Unsetenv(key)
+		}
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range sockets {
+		if s.used {
+			continue
+		}
+		if s.name == name || (name == "" && s.name != RC) {
+			s.used = true
+			fs.Infof(nil, "Using systemd socket %q listening on %v", s.name, s.listener.Addr())
+			return s.listener
+		}
+	}
+	return nil
+}
+
+// listeners reads the sockets passed in by systemd from the
+// environment returned by getenv.  The sockets start at file
+// descriptor start.
+func listeners(getenv func(string) string, start int) (sockets []*socket) {
+	pid, err := strconv.Atoi(getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		// not for us
+		return nil
+	}
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil
+	}
+	var names []string
+	if fdNames := getenv("LISTEN_FDNAMES"); fdNames != "" {
+		names = strings.Split(fdNames, ":")
+	}
+	for i := 0; i < n; i++ {
+		fd := start + i
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(fd), name)
+		// FileListener makes a copy of the file descriptor so
+		// we close the original
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			fs.Errorf(nil, "Ignoring systemd socket %q: %v", name, err)
+			continue
+		}
+		sockets = append(sockets, &socket{name: name, listener: listener})
+	}
+	return sockets
+}
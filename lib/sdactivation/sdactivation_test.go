@@ -0,0 +1,98 @@
+package sdactivation
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeSockets makes n listening sockets returning the file descriptor
+// of the first.  The file descriptors are consecutive.
+func makeSockets(t *testing.T, n int) (start int, addrs []string) {
+	for {
+		var files []*os.File
+		addrs = nil
+		for i := 0; i < n; i++ {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			file, err := ln.(*net.TCPListener).File()
+			require.NoError(t, err)
+			require.NoError(t, ln.Close())
+			files = append(files, file)
+			addrs = append(addrs, ln.Addr().String())
+		}
+		start = int(files[0].Fd())
+		consecutive := true
+		for i, file := range files {
+			if int(file.Fd()) != start+i {
+				consecutive = false
+			}
+		}
+		if consecutive {
+			return start, addrs
+		}
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}
+}
+
+func TestListeners(t *testing.T) {
+	start, addrs := makeSockets(t, 3)
+	env := map[string]string{
+		"LISTEN_PID":     strconv.Itoa(os.Getpid()),
+		"LISTEN_FDS":     "3",
+		"LISTEN_FDNAMES": "rclone.socket:rc:other",
+	}
+	sockets = listeners(func(key string) string { return env[key] }, start)
+	once.Do(func() {})
+	defer func() {
+		for _, s := range sockets {
+			_ = s.listener.Close()
+		}
+	}()
+	require.Equal(t, 3, len(sockets))
+	assert.Equal(t, "rclone.socket", sockets[0].name)
+	assert.Equal(t, addrs[0], sockets[0].listener.Addr().String())
+
+	// named sockets
+	ln := Listener(RC)
+	require.NotNil(t, ln)
+	assert.Equal(t, addrs[1], ln.Addr().String())
+	assert.Nil(t, Listener(RC))
+
+	// unnamed take the rest in order
+	ln = Listener("")
+	require.NotNil(t, ln)
+	assert.Equal(t, addrs[0], ln.Addr().String())
+	ln = Listener("")
+	require.NotNil(t, ln)
+	assert.Equal(t, addrs[2], ln.Addr().String())
+	assert.Nil(t, Listener(""))
+
+	// the listener works
+	go func() {
+		conn, err := net.Dial("tcp", addrs[2])
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+}
+
+func TestListenersNotForUs(t *testing.T) {
+	for _, env := range []map[string]string{
+		{},
+		{"LISTEN_PID": "1", "LISTEN_FDS": "1"},
+		{"LISTEN_PID": strconv.Itoa(os.Getpid()), "LISTEN_FDS": "0"},
+		{"LISTEN_PID": strconv.Itoa(os.Getpid()), "LISTEN_FDS": "potato"},
+	} {
+		assert.Nil(t, listeners(func(key string) string { return env[key] }, 1000), env)
+	}
+}
@@ -64,11 +64,15 @@ func (l *CacheMode) Type() string {
 
 // cache opened files
 type cache struct {
-	f      fs.Fs                 // fs for the cache directory
-	opt    *Options              // vfs Options
-	root   string                // root of the cache directory
-	itemMu sync.Mutex            // protects the next two maps
-	item   map[string]*cacheItem // files/directories in the cache
+	f        fs.Fs                 // fs for the cache directory
+	cipher   *cacheCipher          // if set the cache files are encrypted with this
+	opt      *Options              // vfs Options
+	root     string                // root of the cache directory
+	metaRoot string                // root of the cache metadata directory
+	itemMu   sync.Mutex            // protects the following
+	item     map[string]*cacheItem // files/directories in the cache
+	files    int                   // number of files with a known size in the cache
+	bytes    int64                 // total size of the files with a known size
 }
 
 // cacheItem is stored in the item map
@@ -76,11 +80,12 @@ type cacheItem struct {
 	opens  int       // number of times file is open
 	atime  time.Time // last time file was accessed
 	isFile bool      // if this is a file or a directory
+	size   int64     // size of the file in the cache or -1 if not known
 }
 
 // newCacheItem returns an item for the cache
 func newCacheItem(isFile bool) *cacheItem {
-	return &cacheItem{atime: time.Now(), isFile: isFile}
+	return &cacheItem{atime: time.Now(), isFile: isFile, size: -1}
 }
 
 // newCache creates a new cache heirachy for f
@@ -88,6 +93,14 @@ func newCacheItem(isFile bool) *cacheItem {
 // This starts background goroutines which can be cancelled with the
 // context passed in.
 func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
+	var cipher *cacheCipher
+	if opt.CacheEncrypt {
+		var err error
+		cipher, err = newCacheCipher(f)
+		if err != nil {
+			return nil, err
+		}
+	}
 	fRoot := filepath.FromSlash(f.Root())
 	if runtime.GOOS == "windows" {
 		if strings.HasPrefix(fRoot, `\\?`) {
@@ -96,6 +109,7 @@ func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
 		fRoot = strings.Replace(fRoot, ":", "", -1)
 	}
 	root := filepath.Join(config.CacheDir, "vfs", f.Name(), fRoot)
+	metaRoot := filepath.Join(config.CacheDir, "vfsMeta", f.Name(), fRoot)
 	fs.Debugf(nil, "vfs cache root is %q", root)
 
 	f, err := fs.NewFs(root)
@@ -103,11 +117,18 @@ func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
 		return nil, errors.Wrap(err, "failed to create cache remote")
 	}
 
+	if cipher != nil {
+		fs.Debugf(nil, "vfs cache files are encrypted")
+		f = newCryptCacheFs(f, cipher)
+	}
+
 	c := &cache{
-		f:    f,
-		opt:  opt,
-		root: root,
-		item: make(map[string]*cacheItem),
+		f:        f,
+		cipher:   cipher,
+		opt:      opt,
+		root:     root,
+		metaRoot: metaRoot,
+		item:     make(map[string]*cacheItem),
 	}
 
 	go c.cleaner(ctx)
@@ -274,18 +295,89 @@ func (c *cache) _close(isFile bool, name string) {
 	}
 }
 
-// close marks name as closed
+// stat returns the FileInfo of the cache file for name.  If the cache
+// is encrypted the size is that of the plaintext.
+//
+// name should be a remote path not an osPath
+func (c *cache) stat(name string) (os.FileInfo, error) {
+	fi, err := os.Stat(c.toOSPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if c.cipher != nil {
+		fi = cryptFileInfo{FileInfo: fi}
+	}
+	return fi, nil
+}
+
+// close marks name as closed and records its size in the cache
 //
 // name should be a remote path not an osPath
 func (c *cache) close(name string) {
 	name = clean(name)
 	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
 	c._close(true, name)
-	c.itemMu.Unlock()
+	// read the size with the lock held so a remove can't come in
+	// between and leave the file counted
+	fi, err := c.stat(name)
+	if err == nil {
+		c._setSize(c.item[name], fi.Size())
+	}
+}
+
+// _setSize sets the size of the file item updating the running
+// totals.  Use a size of -1 if the file is no longer in the cache.
+//
+// must be called with itemMu held
+func (c *cache) _setSize(item *cacheItem, size int64) {
+	if item.size >= 0 {
+		c.files--
+		c.bytes -= item.size
+	}
+	item.size = size
+	if item.size >= 0 {
+		c.files++
+		c.bytes += item.size
+	}
+}
+
+// updateSize reads the size of name from the cache directory and
+// records it
+//
+// The size is read with itemMu held so a file removed after it was
+// found by a walk isn't counted again.
+//
+// name should be a remote path not an osPath
+func (c *cache) updateSize(name string) {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	fi, err := c.stat(name)
+	if err != nil {
+		if item := c.item[name]; item != nil {
+			c._setSize(item, -1)
+		}
+		return
+	}
+	item, _ := c._get(true, name)
+	c._setSize(item, fi.Size())
 }
 
 // remove should be called if name is deleted
 func (c *cache) remove(name string) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	c._remove(name)
+	if item := c.item[clean(name)]; item != nil {
+		c._setSize(item, -1)
+	}
+}
+
+// _remove removes name from the cache directory
+//
+// must be called with itemMu held
+func (c *cache) _remove(name string) {
 	osPath := c.toOSPath(name)
 	err := os.Remove(osPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -314,6 +406,15 @@ func (c *cache) removeDir(dir string) bool {
 
 // cleanUp empties the cache of everything
 func (c *cache) cleanUp() error {
+	err := os.RemoveAll(c.metaRoot)
+	if err != nil {
+		return err
+	}
+	c.itemMu.Lock()
+	for _, item := range c.item {
+		c._setSize(item, -1)
+	}
+	c.itemMu.Unlock()
 	return os.RemoveAll(c.root)
 }
 
@@ -338,6 +439,17 @@ func (c *cache) walk(fn func(osPath string, fi os.FileInfo, name string) error)
 	})
 }
 
+// usage returns the number of files in the cache and their total size
+//
+// This is kept as a running total, updated when files are closed or
+// removed and each time the cache is cleaned, so it doesn't include
+// changes to files which are open.
+func (c *cache) usage() (files int, bytes int64) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	return c.files, c.bytes
+}
+
 // updateAtimes walks the cache updating any atimes it finds
 func (c *cache) updateAtimes() error {
 	return c.walk(func(osPath string, fi os.FileInfo, name string) error {
@@ -345,6 +457,7 @@ func (c *cache) updateAtimes() error {
 			// Update the atime with that of the file
 			atime := times.Get(fi).AccessTime()
 			c.updateTime(name, atime)
+			c.updateSize(name)
 		} else {
 			c.cacheDir(name)
 		}
@@ -354,7 +467,7 @@ func (c *cache) updateAtimes() error {
 
 // purgeOld gets rid of any files that are over age
 func (c *cache) purgeOld(maxAge time.Duration) {
-	c._purgeOld(maxAge, c.remove, c.removeDir)
+	c._purgeOld(maxAge, c._remove, c.removeDir)
 }
 
 func (c *cache) _purgeOld(maxAge time.Duration, remove func(name string), removeDir func(name string) bool) {
@@ -369,6 +482,7 @@ func (c *cache) _purgeOld(maxAge time.Duration, remove func(name string), remove
 			if dt < 0 {
 				remove(name)
 				// Remove the entry
+				c._setSize(item, -1)
 				delete(c.item, name)
 			}
 		}
@@ -0,0 +1,531 @@
+// Encryption of the files in the cache
+
+package vfs
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"io"
+	"os"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/pkg/errors"
+)
+
+// The encrypted cache files are a header of the magic string and a
+// random nonce followed by the data encrypted with AES-256 in CTR
+// mode.  CTR mode is used as it allows reads and writes anywhere in
+// the file without re-encrypting the rest of it.
+//
+// This stops the plaintext being read from the cache files, but as
+// the nonce isn't changed when parts of a file are rewritten it
+// doesn't protect against someone who can compare old and new
+// copies of a cache file.
+const (
+	cacheCryptMagic      = "RCLONEVC"
+	cacheCryptNonceSize  = aes.BlockSize
+	cacheCryptHeaderSize = len(cacheCryptMagic) + cacheCryptNonceSize
+	cacheKeyPurpose      = "vfs cache"
+)
+
+// Errors returned by the encrypted cache
+var (
+	errCacheCryptNoKey    = errors.New("--vfs-cache-encrypt needs a crypt remote to get the key from")
+	errCacheCryptBadMagic = errors.New("cache file isn't encrypted - bad magic string")
+)
+
+// keyDeriver is implemented by remotes which can supply a key to
+// encrypt the cache with, eg crypt
+type keyDeriver interface {
+	DeriveKey(purpose string) []byte
+}
+
+// cacheFile is an open file in the cache - either an *os.File or a
+// *cryptFile
+type cacheFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	io.Closer
+	WriteString(s string) (n int, err error)
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+	Sync() error
+}
+
+// cacheCipher encrypts and decrypts the files in the cache
+type cacheCipher struct {
+	block gocipher.Block
+}
+
+// newCacheCipher makes a cacheCipher with a key from f
+func newCacheCipher(f fs.Fs) (*cacheCipher, error) {
+	do, ok := f.(keyDeriver)
+	if !ok {
+		return nil, errCacheCryptNoKey
+	}
+	block, err := aes.NewCipher(do.DeriveKey(cacheKeyPurpose))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make cache cipher")
+	}
+	return &cacheCipher{block: block}, nil
+}
+
+// newHeader makes a header with a new random nonce
+func (c *cacheCipher) newHeader() (header []byte, err error) {
+	header = make([]byte, cacheCryptHeaderSize)
+	copy(header, cacheCryptMagic)
+	_, err = io.ReadFull(rand.Reader, header[len(cacheCryptMagic):])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make nonce")
+	}
+	return header, nil
+}
+
+// checkHeader checks the header and returns the nonce from it
+func checkHeader(header []byte) (nonce []byte, err error) {
+	if len(header) != cacheCryptHeaderSize || string(header[:len(cacheCryptMagic)]) != cacheCryptMagic {
+		return nil, errCacheCryptBadMagic
+	}
+	return header[len(cacheCryptMagic):], nil
+}
+
+// stream returns the cipher stream for the file with nonce starting
+// at offset in the plaintext
+func (c *cacheCipher) stream(nonce []byte, offset int64) gocipher.Stream {
+	var iv [aes.BlockSize]byte
+	copy(iv[:], nonce)
+	// add the block number to the nonce as a big endian number
+	carry := uint64(offset / aes.BlockSize)
+	for i := len(iv) - 1; i >= 0 && carry != 0; i-- {
+		carry += uint64(iv[i])
+		iv[i] = byte(carry)
+		carry >>= 8
+	}
+	s := gocipher.NewCTR(c.block, iv[:])
+	// skip the start of the block before offset
+	var skip [aes.BlockSize]byte
+	n := offset % aes.BlockSize
+	s.XORKeyStream(skip[:n], skip[:n])
+	return s
+}
+
+// cryptFile is an encrypted file in the cache
+//
+// The methods it doesn't override, such as Close and Sync, are those
+// of the underlying file.
+type cryptFile struct {
+	*os.File
+	c      *cacheCipher
+	nonce  []byte
+	offset int64 // file position in the plaintext
+	append bool  // set if writes should always go at the end
+}
+
+// newCryptFile reads the header from fd, or writes a new one if fd is
+// empty.  fd must be opened for read and write without O_APPEND.
+func (c *cacheCipher) newCryptFile(fd *os.File, appendMode bool) (*cryptFile, error) {
+	fi, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var header []byte
+	if fi.Size() == 0 {
+		header, err = c.newHeader()
+		if err == nil {
+			_, err = fd.WriteAt(header, 0)
+		}
+	} else {
+		header = make([]byte, cacheCryptHeaderSize)
+		_, err = fd.ReadAt(header, 0)
+		if err == io.EOF {
+			err = errCacheCryptBadMagic
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := checkHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return &cryptFile{
+		File:   fd,
+		c:      c,
+		nonce:  nonce,
+		append: appendMode,
+	}, nil
+}
+
+// size returns the size of the plaintext
+func (f *cryptFile) size() (int64, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size() - int64(cacheCryptHeaderSize), nil
+}
+
+// ReadAt reads len(b) bytes from the file starting at off
+func (f *cryptFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	n, err = f.File.ReadAt(b, off+int64(cacheCryptHeaderSize))
+	f.c.stream(f.nonce, off).XORKeyStream(b[:n], b[:n])
+	return n, err
+}
+
+// Read reads up to len(b) bytes from the file
+func (f *cryptFile) Read(b []byte) (n int, err error) {
+	n, err = f.ReadAt(b, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// writeAt encrypts b and writes it at off which mustn't be beyond the
+// end of the file
+func (f *cryptFile) writeAt(b []byte, off int64) (n int, err error) {
+	buf := make([]byte, len(b))
+	f.c.stream(f.nonce, off).XORKeyStream(buf, b)
+	return f.File.WriteAt(buf, off+int64(cacheCryptHeaderSize))
+}
+
+// zeroFill writes zeros from the end of the file up to off, as the
+// holes the OS would leave wouldn't decrypt to zeros
+func (f *cryptFile) zeroFill(off int64) error {
+	size, err := f.size()
+	if err != nil {
+		return err
+	}
+	const chunk = 64 * 1024
+	zeros := make([]byte, chunk)
+	for size < off {
+		n := off - size
+		if n > chunk {
+			n = chunk
+		}
+		_, err = f.writeAt(zeros[:n], size)
+		if err != nil {
+			return err
+		}
+		size += n
+	}
+	return nil
+}
+
+// WriteAt writes len(b) bytes to the file starting at off
+func (f *cryptFile) WriteAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	err = f.zeroFill(off)
+	if err != nil {
+		return 0, err
+	}
+	return f.writeAt(b, off)
+}
+
+// Write writes len(b) bytes to the file
+func (f *cryptFile) Write(b []byte) (n int, err error) {
+	if f.append {
+		f.offset, err = f.size()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err = f.WriteAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// WriteString writes the contents of s to the file
+func (f *cryptFile) WriteString(s string) (n int, err error) {
+	return f.Write([]byte(s))
+}
+
+// Seek sets the offset for the next Read or Write on the file
+func (f *cryptFile) Seek(offset int64, whence int) (ret int64, err error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.size()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, errors.Errorf("bad whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Truncate changes the size of the file
+func (f *cryptFile) Truncate(size int64) error {
+	current, err := f.size()
+	if err != nil {
+		return err
+	}
+	if size > current {
+		return f.zeroFill(size)
+	}
+	return f.File.Truncate(size + int64(cacheCryptHeaderSize))
+}
+
+// Stat returns the FileInfo for the file with the size of the
+// plaintext
+func (f *cryptFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return cryptFileInfo{FileInfo: fi}, nil
+}
+
+// cryptFileInfo is the os.FileInfo of an encrypted file with the size
+// of the plaintext
+type cryptFileInfo struct {
+	os.FileInfo
+}
+
+// Size returns the size of the plaintext
+func (fi cryptFileInfo) Size() int64 {
+	size := fi.FileInfo.Size() - int64(cacheCryptHeaderSize)
+	if size < 0 {
+		size = 0
+	}
+	return size
+}
+
+// openCacheFile opens the cache file at osPath with flags returning
+// the underlying file and the cacheFile to read and write it with,
+// which will be encrypted if c is set.
+func openCacheFile(c *cacheCipher, osPath string, flags int) (fd *os.File, cf cacheFile, err error) {
+	if c == nil {
+		fd, err = os.OpenFile(osPath, flags, 0600)
+		return fd, fd, err
+	}
+	// The header needs reading and writing whatever the mode and
+	// cryptFile does the appending itself
+	appendMode := flags&os.O_APPEND != 0
+	flags = flags&^(accessModeMask|os.O_APPEND) | os.O_RDWR
+	fd, err = os.OpenFile(osPath, flags, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	cf, err = c.newCryptFile(fd, appendMode)
+	if err != nil {
+		_ = fd.Close()
+		return nil, nil, errors.Wrapf(err, "failed to open encrypted cache file %q", osPath)
+	}
+	return fd, cf, nil
+}
+
+// cryptCacheFs wraps the Fs of the cache directory so the files
+// copied to and from it are encrypted
+type cryptCacheFs struct {
+	fs.Fs
+	c        *cacheCipher
+	features *fs.Features
+}
+
+// newCryptCacheFs wraps f so the files in it are encrypted with c
+func newCryptCacheFs(f fs.Fs, c *cacheCipher) *cryptCacheFs {
+	cf := &cryptCacheFs{
+		Fs: f,
+		c:  c,
+	}
+	cf.features = (&fs.Features{}).Fill(cf)
+	return cf
+}
+
+// Features returns the optional features of this Fs
+//
+// Only the features which go through the wrapper are returned, so
+// server side copies can't bypass the encryption.
+func (f *cryptCacheFs) Features() *fs.Features {
+	return f.features
+}
+
+// Hashes returns no hashes as the hashes of the underlying files are
+// of the encrypted data
+func (f *cryptCacheFs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// List the objects and directories in dir into entries
+func (f *cryptCacheFs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = f.newObject(o)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote
+func (f *cryptCacheFs) NewObject(remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// encrypt returns a reader of in encrypted and the ObjectInfo for it
+func (f *cryptCacheFs) encrypt(in io.Reader, src fs.ObjectInfo) (io.Reader, fs.ObjectInfo, error) {
+	header, err := f.c.newHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := src.Size()
+	if size >= 0 {
+		size += int64(cacheCryptHeaderSize)
+	}
+	in = io.MultiReader(bytes.NewReader(header), gocipher.StreamReader{
+		S: f.c.stream(header[len(cacheCryptMagic):], 0),
+		R: in,
+	})
+	return in, object.NewStaticObjectInfo(src.Remote(), src.ModTime(), size, true, nil, f), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *cryptCacheFs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	in, src, err := f.encrypt(in, src)
+	if err != nil {
+		return nil, err
+	}
+	o, err := f.Fs.Put(in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// cryptCacheObject is an encrypted file in the cache
+type cryptCacheObject struct {
+	fs.Object
+	f *cryptCacheFs
+}
+
+// newObject wraps o
+func (f *cryptCacheFs) newObject(o fs.Object) *cryptCacheObject {
+	return &cryptCacheObject{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns the parent Fs
+func (o *cryptCacheObject) Fs() fs.Info {
+	return o.f
+}
+
+// Size returns the size of the plaintext
+func (o *cryptCacheObject) Size() int64 {
+	size := o.Object.Size() - int64(cacheCryptHeaderSize)
+	if size < 0 {
+		size = 0
+	}
+	return size
+}
+
+// Hash returns an error as the hashes aren't known
+func (o *cryptCacheObject) Hash(ht hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// UnWrap returns the wrapped Object
+func (o *cryptCacheObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Open an object for read decrypting it
+func (o *cryptCacheObject) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	// read the header
+	in, err := o.Object.Open(&fs.RangeOption{Start: 0, End: int64(cacheCryptHeaderSize) - 1})
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, cacheCryptHeaderSize)
+	_, err = io.ReadFull(in, header)
+	_ = in.Close()
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errCacheCryptBadMagic
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read encrypted cache file")
+	}
+	nonce, err := checkHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out where to read from in the plaintext
+	var offset, limit int64 = 0, -1
+	var openOptions []fs.OpenOption
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset, limit = x.Offset, -1
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			openOptions = append(openOptions, option)
+		}
+	}
+	start := offset + int64(cacheCryptHeaderSize)
+	if limit >= 0 {
+		openOptions = append(openOptions, &fs.RangeOption{Start: start, End: start + limit - 1})
+	} else {
+		openOptions = append(openOptions, &fs.SeekOption{Offset: start})
+	}
+	in, err = o.Object.Open(openOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: gocipher.StreamReader{S: o.f.c.stream(nonce, offset), R: in},
+		Closer: in,
+	}, nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *cryptCacheObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	in, src, err := o.f.encrypt(in, src)
+	if err != nil {
+		return err
+	}
+	return o.Object.Update(in, src, options...)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ cacheFile          = (*os.File)(nil)
+	_ cacheFile          = (*cryptFile)(nil)
+	_ fs.Fs              = (*cryptCacheFs)(nil)
+	_ fs.Object          = (*cryptCacheObject)(nil)
+	_ fs.ObjectUnWrapper = (*cryptCacheObject)(nil)
+)
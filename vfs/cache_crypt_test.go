@@ -0,0 +1,161 @@
+package vfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyFs is an Fs which supplies a key for the encrypted cache
+type keyFs struct {
+	fs.Fs
+}
+
+func (f keyFs) DeriveKey(purpose string) []byte {
+	return bytes.Repeat([]byte{1}, 32)
+}
+
+func newTestCacheCipher(t *testing.T) *cacheCipher {
+	c, err := newCacheCipher(keyFs{})
+	require.NoError(t, err)
+	return c
+}
+
+func TestCacheCipherNoKey(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	_, err := newCacheCipher(r.Fremote)
+	assert.Equal(t, errCacheCryptNoKey, err)
+}
+
+func TestCacheCipherStream(t *testing.T) {
+	c := newTestCacheCipher(t)
+	nonce := bytes.Repeat([]byte{0xFF}, cacheCryptNonceSize)
+	plain := make([]byte, 100)
+	whole := make([]byte, len(plain))
+	c.stream(nonce, 0).XORKeyStream(whole, plain)
+	// encrypting from any offset must match encrypting the whole
+	// thing, including when the counter carries out of the nonce
+	for _, offset := range []int{1, 15, 16, 17, 50, 99} {
+		part := make([]byte, len(plain)-offset)
+		c.stream(nonce, int64(offset)).XORKeyStream(part, plain[offset:])
+		assert.Equal(t, whole[offset:], part, offset)
+	}
+}
+
+func TestCryptFile(t *testing.T) {
+	c := newTestCacheCipher(t)
+	dir, err := ioutil.TempDir("", "rclone-vfs-crypt")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	osPath := filepath.Join(dir, "file")
+
+	_, cf, err := openCacheFile(c, osPath, os.O_WRONLY|os.O_CREATE)
+	require.NoError(t, err)
+	_, err = cf.WriteString("hello")
+	require.NoError(t, err)
+	_, err = cf.WriteAt([]byte("world"), 10)
+	require.NoError(t, err)
+	fi, err := cf.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), fi.Size())
+	require.NoError(t, cf.Close())
+
+	raw, err := ioutil.ReadFile(osPath)
+	require.NoError(t, err)
+	assert.Equal(t, cacheCryptHeaderSize+15, len(raw))
+	assert.Equal(t, cacheCryptMagic, string(raw[:len(cacheCryptMagic)]))
+	assert.NotContains(t, string(raw), "hello")
+	assert.NotContains(t, string(raw), "world")
+
+	_, cf, err = openCacheFile(c, osPath, os.O_RDWR|os.O_APPEND)
+	require.NoError(t, err)
+	_, err = cf.Write([]byte("!"))
+	require.NoError(t, err)
+	pos, err := cf.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pos)
+	got, err := ioutil.ReadAll(cf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\x00\x00\x00\x00\x00world!", string(got))
+
+	require.NoError(t, cf.Truncate(3))
+	require.NoError(t, cf.Truncate(5))
+	buf := make([]byte, 5)
+	_, err = cf.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hel\x00\x00", string(buf))
+	require.NoError(t, cf.Close())
+
+	// files which aren't encrypted can't be opened
+	require.NoError(t, ioutil.WriteFile(osPath, []byte("plaintext which is long enough"), 0600))
+	_, _, err = openCacheFile(c, osPath, os.O_RDONLY)
+	require.Error(t, err)
+	assert.Equal(t, errCacheCryptBadMagic, errors.Cause(err))
+}
+
+func TestCacheEncrypt(t *testing.T) {
+	r := fstest.NewRun(t)
+	file2 := r.WriteObject("file2", "more secrets", t2)
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeFull
+	opt.CacheEncrypt = true
+	vfs := New(keyFs{Fs: r.Fremote}, &opt)
+	defer cleanup(t, r, vfs)
+	require.NotNil(t, vfs.cache)
+
+	// write a file through the cache
+	h, err := vfs.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = h.WriteString("secret contents")
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+
+	// the remote gets the plaintext
+	file1 := fstest.NewItem("file1", "secret contents", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2}, nil, fs.ModTimeNotSupported)
+
+	// the cache file doesn't have it
+	raw, err := ioutil.ReadFile(vfs.cache.toOSPath("file1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret")
+
+	// read it back from the cache
+	h, err = vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(h)
+	require.NoError(t, err)
+	assert.Equal(t, "secret contents", string(got))
+	require.NoError(t, h.Close())
+
+	// a file on the remote is downloaded encrypted
+	h, err = vfs.OpenFile("file2", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	got, err = ioutil.ReadAll(h)
+	require.NoError(t, err)
+	assert.Equal(t, "more secrets", string(got))
+	require.NoError(t, h.Close())
+	raw, err = ioutil.ReadFile(vfs.cache.toOSPath("file2"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secrets")
+
+	// read from the cache at an offset
+	o, err := vfs.cache.f.NewObject("file2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), o.Size())
+	in, err := o.Open(&fs.RangeOption{Start: 5, End: 7})
+	require.NoError(t, err)
+	got, err = ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "sec", string(got))
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/pkg/errors"
@@ -206,7 +207,9 @@ func (d *Dir) _readDir() error {
 	} else {
 		return nil
 	}
-	entries, err := list.DirSorted(d.f, false, d.path)
+	done := accounting.Metadata()
+	entries, err := list.DirSortedNoCache(d.f, false, d.path)
+	done()
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
 		// create directories on the fly
@@ -277,6 +280,17 @@ func (d *Dir) _readDirFromEntries(entries fs.DirEntries, dirTree walk.DirTree, w
 		}
 		d.items[name] = node
 	}
+	// Keep files which are waiting to be written back as they may
+	// not be on the remote yet
+	d.vfs.writeBack.pendingIn(d.path, func(leaf string) *File {
+		found[leaf] = struct{}{}
+		file, ok := d.items[leaf].(*File)
+		if !ok {
+			file = newFile(d, nil, leaf)
+			d.items[leaf] = file
+		}
+		return file
+	})
 	// delete unused entries
 	for name := range d.items {
 		if _, ok := found[name]; !ok {
@@ -325,6 +339,21 @@ func (d *Dir) stat(leaf string) (Node, error) {
 		return nil, err
 	}
 	item, ok := d.items[leaf]
+	if !ok && d.vfs.Opt.CaseInsensitive {
+		// This scans every item in the directory, but only when
+		// the exact name isn't found
+		leafLower := strings.ToLower(leaf)
+		for name, node := range d.items {
+			if strings.ToLower(name) == leafLower {
+				if ok {
+					// duplicate case insensitive match is an error
+					return nil, errors.Errorf("duplicate filename %q detected with --vfs-case-insensitive set", leaf)
+				}
+				// found a case insensitive match
+				item, ok = node, true
+			}
+		}
+	}
 	if !ok {
 		return nil, ENOENT
 	}
@@ -458,7 +487,9 @@ func (d *Dir) Mkdir(name string) (*Dir, error) {
 	}
 	path := path.Join(d.path, name)
 	// fs.Debugf(path, "Dir.Mkdir")
+	done := accounting.Metadata()
 	err := d.f.Mkdir(path)
+	done()
 	if err != nil {
 		fs.Errorf(d, "Dir.Mkdir failed to create directory: %v", err)
 		return nil, err
@@ -486,7 +517,9 @@ func (d *Dir) Remove() error {
 		return ENOTEMPTY
 	}
 	// remove directory
+	done := accounting.Metadata()
 	err = d.f.Rmdir(d.path)
+	done()
 	if err != nil {
 		fs.Errorf(d, "Dir.Remove failed to remove directory: %v", err)
 		return err
@@ -241,6 +241,39 @@ func TestDirStat(t *testing.T) {
 	assert.Equal(t, ENOENT, err)
 }
 
+func TestDirStatCaseInsensitive(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs, dir, _ := dirCreate(t, r)
+
+	vfs.Opt.CaseInsensitive = false
+	_, err := dir.Stat("FILE1")
+	assert.Equal(t, ENOENT, err)
+
+	vfs.Opt.CaseInsensitive = true
+	node, err := dir.Stat("FILE1")
+	require.NoError(t, err)
+	assert.Equal(t, "file1", node.Name())
+
+	if r.Fremote.Features().CaseInsensitive {
+		t.Skip("Can't test names differing only in case on a case insensitive remote")
+	}
+
+	// An exact match is preferred
+	r.WriteObject("dir/FILE1", "FILE1 contents!", t1)
+	r.WriteObject("dir/File1", "File1 contents!!", t1)
+	dir.ForgetAll()
+	node, err = vfs.Stat("dir/FILE1")
+	require.NoError(t, err)
+	assert.Equal(t, "FILE1", node.Name())
+	assert.Equal(t, int64(15), node.Size())
+
+	// Ambiguous matches are an error
+	_, err = vfs.Stat("dir/fILE1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate filename")
+}
+
 // This lists dir and checks the listing is as expected
 func checkListing(t *testing.T, dir *Dir, want []string) {
 	var got []string
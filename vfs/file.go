@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/pkg/errors"
@@ -115,9 +116,27 @@ func (f *File) rename(destDir *Dir, newName string) error {
 		return err
 	}
 
+	// Upload the file first if it is waiting to be written back
+	oldPath := f.Path()
+	if f.activeWriters() == 0 {
+		err := f.d.vfs.writeBack.uploadNow(oldPath)
+		if err != nil {
+			fs.Errorf(f.Path(), "File.Rename error: %v", err)
+			return err
+		}
+	}
+
 	renameCall := func() error {
+		// Upload the file if it was queued while the rename was pending
+		err := f.d.vfs.writeBack.uploadNow(oldPath)
+		if err != nil {
+			fs.Errorf(f.Path(), "File.Rename error: %v", err)
+			return err
+		}
 		newPath := path.Join(destDir.path, newName)
+		done := accounting.Metadata()
 		dstOverwritten, _ := f.d.f.NewObject(newPath)
+		done()
 		newObject, err := operations.Move(f.d.f, dstOverwritten, newPath, f.o)
 		if err != nil {
 			fs.Errorf(f.Path(), "File.Rename error: %v", err)
@@ -152,7 +171,9 @@ func (f *File) rename(destDir *Dir, newName string) error {
 func (f *File) addWriter(h Handle) {
 	f.mu.Lock()
 	f.writers = append(f.writers, h)
-	atomic.AddInt32(&f.nwriters, 1)
+	if atomic.AddInt32(&f.nwriters, 1) == 1 {
+		atomic.AddInt32(&f.d.vfs.writeFiles, 1)
+	}
 	if _, ok := h.(*RWFileHandle); ok {
 		f.readWriters++
 	}
@@ -172,7 +193,9 @@ func (f *File) delWriter(h Handle, modifiedCacheFile bool) (lastWriterAndModifie
 	}
 	if found >= 0 {
 		f.writers = append(f.writers[:found], f.writers[found+1:]...)
-		atomic.AddInt32(&f.nwriters, -1)
+		if atomic.AddInt32(&f.nwriters, -1) == 0 {
+			atomic.AddInt32(&f.d.vfs.writeFiles, -1)
+		}
 	} else {
 		fs.Debugf(f.o, "File.delWriter couldn't find handle")
 	}
@@ -240,6 +263,13 @@ func (f *File) ModTime() (modTime time.Time) {
 	defer f.mu.Unlock()
 
 	if !f.d.vfs.Opt.NoModTime {
+		// if the file is waiting to be written back the cache file is newer than o
+		if fi := f._writeBackInfo(); fi != nil {
+			if !f.pendingModTime.IsZero() {
+				return f.pendingModTime
+			}
+			return fi.ModTime()
+		}
 		// if o is nil it isn't valid yet or there are writers, so return the size so far
 		if f.o == nil || len(f.writers) != 0 || f.readWriterClosing {
 			if !f.pendingModTime.IsZero() {
@@ -266,6 +296,10 @@ func (f *File) Size() int64 {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	// if the file is waiting to be written back the cache file is newer than o
+	if fi := f._writeBackInfo(); fi != nil {
+		return fi.Size()
+	}
 	// if o is nil it isn't valid yet or there are writers, so return the size so far
 	if f.writingInProgress() {
 		return atomic.LoadInt64(&f.size)
@@ -273,6 +307,17 @@ func (f *File) Size() int64 {
 	return nonNegative(f.o.Size())
 }
 
+// _writeBackInfo returns the info of the cache file if the file is
+// closed and waiting to be written back, or nil otherwise.
+//
+// Call with the lock held
+func (f *File) _writeBackInfo() os.FileInfo {
+	if len(f.writers) != 0 || f.readWriterClosing {
+		return nil
+	}
+	return f.d.vfs.writeBack.info(f.Path())
+}
+
 // SetModTime sets the modtime for the file
 func (f *File) SetModTime(modTime time.Time) error {
 	if f.d.vfs.Opt.ReadOnly {
@@ -458,6 +503,7 @@ func (f *File) Remove() error {
 	f.d.delObject(f.Name())
 	// Remove the object from the cache
 	if f.d.vfs.Opt.CacheMode >= CacheModeMinimal {
+		f.d.vfs.writeBack.remove(f.Path())
 		f.d.vfs.cache.remove(f.Path())
 	}
 	return nil
@@ -566,6 +612,9 @@ func (f *File) Open(flags int) (fd Handle, err error) {
 		fs.Errorf(f, "Can't figure out how to open with flags: 0x%X", flags)
 		return nil, EPERM
 	}
+	if err == nil {
+		atomic.AddInt32(&f.d.vfs.openFiles, 1)
+	}
 	return fd, err
 }
 
@@ -11,10 +11,17 @@ backend. Changes made locally in the mount may appear immediately or
 invalidate the cache. However, changes done on the remote will only
 be picked up once the cache expires.
 
+If the backend supports change notifications (eg Google Drive,
+Amazon Drive, Dropbox and OneDrive) then rclone checks for changes on the
+remote every ` + "`--poll-interval`" + ` and only invalidates the
+directories which changed, so remote changes show up without waiting
+for ` + "`--dir-cache-time`" + `.  Set ` + "`--poll-interval 0`" + ` to
+disable this.
+
 Alternatively, you can send a ` + "`SIGHUP`" + ` signal to rclone for
-it to flush all directory caches, regardless of how old they are.
-Assuming only one rclone instance is running, you can reset the cache
-like this:
+it to flush all directory caches, regardless of how old they are, and
+re-read the root directory from the remote.  Assuming only one rclone
+instance is running, you can reset the cache like this:
 
     kill -SIGHUP $(pidof rclone)
 
@@ -27,6 +34,30 @@ Or individual files or directories:
 
     rclone rc vfs/forget file=path/to/file dir=path/to/dir
 
+To re-read directories from the remote straight away rather than
+waiting for them to be next accessed use:
+
+    rclone rc vfs/refresh dir=path/to/dir recursive=true
+
+### File and Directory Permissions
+
+Remotes don't store the owner and permissions of files, so by default
+all files are shown as owned by the user running rclone with
+permissions 0666 and all directories with 0777, less the ` + "`--umask`" + `.
+
+Use ` + "`--file-perms`" + ` and ` + "`--dir-perms`" + ` to set the
+permissions (in octal), ` + "`--umask`" + ` to remove bits from them,
+and ` + "`--uid`" + ` and ` + "`--gid`" + ` to set the owner.  This is
+useful when the files are used by other users, eg with
+` + "`--allow-other`" + ` or in a container, for example
+
+    --uid 1000 --gid 1000 --dir-perms 0755 --file-perms 0644 --umask 022
+
+Note that the permissions are only checked by the kernel if
+` + "`--default-permissions`" + ` is in use, and that they can't be
+changed with chmod and chown.  The ` + "`--uid`" + `, ` + "`--gid`" + `
+and ` + "`--umask`" + ` flags are not available on Windows.
+
 ### File Buffering
 
 The ` + "`--buffer-size`" + ` flag determines the amount of memory,
@@ -44,6 +75,34 @@ will be used.
 The maximum memory used by rclone for buffering can be up to
 ` + "`--buffer-size * open files`" + `.
 
+### Chunked reading
+
+` + "`--vfs-read-chunk-size`" + ` will enable reading the source objects
+in parts.  This can reduce the used download quota for some remotes by
+requesting only chunks from the remote that are actually read at the
+cost of an increased number of requests.  Each chunk is read with a
+ranged request rather than an open ended stream, so only the data
+which is actually needed is fetched from the remote.
+
+The default is 128M.  Setting it to 0 disables chunked reading and
+files will be read with a single request.
+
+When ` + "`--vfs-read-chunk-size-limit`" + ` is also specified and greater
+than ` + "`--vfs-read-chunk-size`" + `, the chunk size for each open file
+will get doubled for each chunk read, until the specified value is
+reached.  A value of "off" (the default) will disable the limit and
+the chunk size will grow indefinitely.
+
+With ` + "`--vfs-read-chunk-size 100M`" + ` and
+` + "`--vfs-read-chunk-size-limit 0`" + ` the following parts will be
+downloaded: 0-100M, 100M-200M, 200M-300M, 300M-400M and so on.  When
+` + "`--vfs-read-chunk-size-limit 500M`" + ` is specified, the result would
+be 0-100M, 100M-300M, 300M-700M, 700M-1200M, 1200M-1700M and so on.
+
+Chunked reading will only work with ` + "`--vfs-cache-mode`" + ` < full, as
+the file will always be copied to the vfs cache before opening with
+` + "`--vfs-cache-mode full`" + `.
+
 ### File Caching
 
 **NB** File caching is **EXPERIMENTAL** - use with care!
@@ -59,9 +118,11 @@ Note that the VFS cache works in addition to the cache backend and you
 may find that you need one or the other or both.
 
     --cache-dir string                   Directory rclone will use for caching.
+    --vfs-cache-encrypt                  Encrypt the files in the cache with a key from the crypt remote.
     --vfs-cache-max-age duration         Max age of objects in the cache. (default 1h0m0s)
     --vfs-cache-mode string              Cache mode off|minimal|writes|full (default "off")
     --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects. (default 1m0s)
+    --vfs-write-back duration            Time to wait after a file is closed before uploading it in the background. 0 uploads on close.
 
 If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
@@ -77,6 +138,44 @@ closed so if rclone is quit or dies with open files then these won't
 get written back to the remote.  However they will still be in the on
 disk cache.
 
+#### Write back
+
+Normally files written through the cache are uploaded when they are
+closed and the close doesn't return until the upload has finished.
+
+If ` + "`--vfs-write-back`" + ` is set to a duration greater than 0 then
+closing the file returns immediately and the file is uploaded in the
+background that long after it was last closed.  This makes
+applications which save files often, such as office suites, much more
+responsive, and if the file is written again before it is uploaded
+only the latest version will be uploaded.
+
+Files are uploaded in the order they were closed in.  If an upload
+fails it is retried with exponential backoff (1s, 2s, 4s... up to 5
+minutes) and the files queued after it wait until it succeeds.
+
+The upload queue is stored in the cache directory, so if rclone is
+stopped with uploads pending they will be resumed the next time it is
+run with the same remote and cache directory.  The files are kept in
+the cache until they have been uploaded.  While they are waiting they
+are shown in directory listings with the size and modification time
+of the cached copy, even if the directory is re-read from the remote.
+
+This needs ` + "`--vfs-cache-mode writes`" + ` or ` + "`full`" + ` - it is
+ignored with an error message with the other cache modes.
+
+#### Encrypting the cache
+
+If the remote is a crypt remote then ` + "`--vfs-cache-encrypt`" + ` stores
+the contents of the files in the cache encrypted with a key derived
+from the crypt password, so the plaintext isn't written to the local
+disk.  This is useful on shared machines.  The names of the files in
+the cache aren't encrypted.
+
+Clear the cache directory when turning this on or off as files cached
+the other way can't be read.  If the remote isn't a crypt remote the
+cache is disabled with an error message.
+
 #### --vfs-cache-mode off
 
 In this mode the cache will read directly from the remote and write
@@ -132,4 +231,42 @@ This mode should support all normal file system operations.
 
 If an upload or download fails it will be retried up to
 --low-level-retries times.
+
+### Case Sensitivity
+
+Linux file systems are case-sensitive: two files can differ only
+by case, and the exact case must be used when opening a file.
+
+Windows is not like most other operating systems supported by rclone.
+File systems in modern Windows are case-insensitive but case-preserving:
+although existing files can be opened using any case, the exact case used
+to create the file is preserved and available for programs to query.
+It is not allowed for two files in the same directory to differ only by case.
+
+Usually file systems on macOS are case-insensitive. It is possible to make macOS
+file systems case-sensitive but that is not the default.
+
+The "--vfs-case-insensitive" flag controls how rclone handles these
+two cases. If its value is "false", rclone passes file names to the remote
+as-is. If the flag is "true" (or appears without a value on command line),
+rclone may perform a "fixup" as explained below.
+
+The user may specify a file name to open/delete/rename/etc with a case
+different than what is stored on the remote. If an argument refers
+to an existing file with exactly the same name, then the case of the existing
+file on the disk will be used. However, if a file name with exactly the same
+name is not found but a name differing only by case exists, rclone will
+transparently fixup the name. This fixup happens only when an existing file
+is requested. Case sensitivity of file names created anew by rclone is
+controlled by the underlying remote.
+
+If more than one file matches the name ignoring case then rclone
+will return an error rather than guess which one was meant.
+
+Looking up a name which doesn't exist has to compare it with every
+name in the directory, which may be slow in directories with very
+many files.
+
+The default is "false" so you will probably want to set it on Windows
+and macOS.
 `
@@ -1,11 +1,14 @@
 package vfs
 
 import (
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/pkg/errors"
 )
@@ -195,6 +198,83 @@ might not get picked up by the polling function, depending on the
 used remote.
 `,
 	})
+	rc.Add(rc.Call{
+		Path: "vfs/stats",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			return vfs.Stats(), nil
+		},
+		Title: "Stats for a VFS.",
+		Help: `
+This returns stats for the VFS used by mount or serve so it can be
+monitored.
+
+    rclone rc vfs/stats
+
+Returns the following values:
+
+` + "```" + `
+{
+	"fs": the remote the VFS is for,
+	"openFiles": number of open file handles,
+	"filesOpenForWrite": number of files open for write,
+	"diskCache": {
+		"path": directory the cache is stored in,
+		"files": number of files in the cache,
+		"bytesUsed": bytes used by the files in the cache,
+		"uploadsQueued": number of files waiting to be uploaded by --vfs-write-back
+	}
+}
+` + "```" + `
+
+The "diskCache" is only present if --vfs-cache-mode is not "off".
+
+The stats of all the VFSes in use are also included in the output of
+core/stats as a list called "vfs".
+`,
+	})
+	addStats(vfs)
+}
+
+// VFSes whose stats are included in the output of core/stats
+var (
+	statsMu  sync.Mutex
+	statsVFS = map[*VFS]struct{}{}
+)
+
+func init() {
+	accounting.SetStatsFunc("vfs", allStats)
+}
+
+// addStats adds the stats of vfs to the output of core/stats
+func addStats(vfs *VFS) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	statsVFS[vfs] = struct{}{}
+}
+
+// removeStats removes the stats of vfs from the output of core/stats
+func removeStats(vfs *VFS) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	delete(statsVFS, vfs)
+}
+
+// allStats returns the stats for all the VFSes in use sorted by
+// remote, or nil if there aren't any
+func allStats() interface{} {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if len(statsVFS) == 0 {
+		return nil
+	}
+	out := make([]rc.Params, 0, len(statsVFS))
+	for vfs := range statsVFS {
+		out = append(out, vfs.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i]["fs"].(string) < out[j]["fs"].(string)
+	})
+	return out
 }
 
 func rcPollFunc(vfs *VFS) (rcPollFunc rc.Func) {
@@ -7,6 +7,7 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
@@ -21,6 +22,7 @@ import (
 // transferred to the remote.
 type RWFileHandle struct {
 	*os.File
+	fd          cacheFile // what to read and write File with - File itself unless encrypted
 	mu          sync.Mutex
 	closed      bool // set if handle has been closed
 	remote      string
@@ -107,13 +109,17 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 
 	o := fh.file.getObject()
 
-	var fd *os.File
+	var (
+		fd *os.File
+		cf cacheFile
+	)
 	cacheFileOpenFlags := fh.flags
 	// if not truncating the file, need to read it first
 	if fh.flags&os.O_TRUNC == 0 && !truncate {
 		// If the remote object exists AND its cached file exists locally AND there are no
 		// other RW handles with it open, then attempt to update it.
-		if o != nil && fh.file.rwOpens() == 0 {
+		// Don't do this if the cached file is waiting to be written back.
+		if o != nil && fh.file.rwOpens() == 0 && !fh.d.vfs.writeBack.pending(fh.remote) {
 			cacheObj, err := fh.d.vfs.cache.f.NewObject(fh.remote)
 			if err == nil && cacheObj != nil {
 				_, err = copyObj(fh.d.vfs.cache.f, cacheObj, fh.remote, o)
@@ -124,7 +130,7 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 		}
 
 		// try to open a exising cache file
-		fd, err = os.OpenFile(fh.osPath, cacheFileOpenFlags&^os.O_CREATE, 0600)
+		fd, cf, err = openCacheFile(fh.d.vfs.cache.cipher, fh.osPath, cacheFileOpenFlags&^os.O_CREATE)
 		if os.IsNotExist(err) {
 			// cache file does not exist, so need to fetch it if we have an object to fetch
 			// it from
@@ -185,12 +191,13 @@ func (fh *RWFileHandle) openPending(truncate bool) (err error) {
 
 	if fd == nil {
 		fs.Debugf(fh.logPrefix(), "Opening cached copy with flags=%s", decodeOpenFlags(fh.flags))
-		fd, err = os.OpenFile(fh.osPath, cacheFileOpenFlags, 0600)
+		fd, cf, err = openCacheFile(fh.d.vfs.cache.cipher, fh.osPath, cacheFileOpenFlags)
 		if err != nil {
 			return errors.Wrap(err, "cache open file failed")
 		}
 	}
 	fh.File = fd
+	fh.fd = cf
 	fh.opened = true
 	fh.file.addRWOpen()
 	fh.d.addObject(fh.file) // make sure the directory has this object in it now
@@ -247,6 +254,7 @@ func (fh *RWFileHandle) close() (err error) {
 		return ECLOSED
 	}
 	fh.closed = true
+	atomic.AddInt32(&fh.d.vfs.openFiles, -1)
 	defer func() {
 		if fh.opened {
 			fh.file.delRWOpen()
@@ -276,7 +284,7 @@ func (fh *RWFileHandle) close() (err error) {
 	}
 
 	if writer && fh.opened {
-		fi, err := fh.File.Stat()
+		fi, err := fh.fd.Stat()
 		if err != nil {
 			fs.Errorf(fh.logPrefix(), "Failed to stat cache file: %v", err)
 		} else {
@@ -286,14 +294,17 @@ func (fh *RWFileHandle) close() (err error) {
 
 	// Close the underlying file
 	if fh.opened {
-		err = fh.File.Close()
+		err = fh.fd.Close()
 		if err != nil {
 			err = errors.Wrap(err, "failed to close cache file")
 			return err
 		}
 	}
 
-	if isCopied {
+	if isCopied && fh.d.vfs.writeBack != nil {
+		// Queue the temp file to be transferred to the remote
+		fh.d.vfs.writeBack.add(fh.file, fh.remote)
+	} else if isCopied {
 		// Transfer the temp file to the remote
 		cacheObj, err := fh.d.vfs.cache.f.NewObject(fh.remote)
 		if err != nil {
@@ -384,7 +395,7 @@ func (fh *RWFileHandle) Size() int64 {
 	if !fh.opened {
 		return fh.file.Size()
 	}
-	fi, err := fh.File.Stat()
+	fi, err := fh.fd.Stat()
 	if err != nil {
 		return 0
 	}
@@ -418,14 +429,14 @@ func (fh *RWFileHandle) readFn(read func() (int, error)) (n int, err error) {
 // Read bytes from the file
 func (fh *RWFileHandle) Read(b []byte) (n int, err error) {
 	return fh.readFn(func() (int, error) {
-		return fh.File.Read(b)
+		return fh.fd.Read(b)
 	})
 }
 
 // ReadAt bytes from the file at off
 func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 	return fh.readFn(func() (int, error) {
-		return fh.File.ReadAt(b, off)
+		return fh.fd.ReadAt(b, off)
 	})
 }
 
@@ -442,7 +453,7 @@ func (fh *RWFileHandle) Seek(offset int64, whence int) (ret int64, err error) {
 	if err = fh.openPending(false); err != nil {
 		return ret, err
 	}
-	return fh.File.Seek(offset, whence)
+	return fh.fd.Seek(offset, whence)
 }
 
 // writeFn general purpose write call
@@ -465,7 +476,7 @@ func (fh *RWFileHandle) writeFn(write func() error) (err error) {
 	if err != nil {
 		return err
 	}
-	fi, err := fh.File.Stat()
+	fi, err := fh.fd.Stat()
 	if err != nil {
 		return errors.Wrap(err, "failed to stat cache file")
 	}
@@ -476,7 +487,7 @@ func (fh *RWFileHandle) writeFn(write func() error) (err error) {
 // Write bytes to the file
 func (fh *RWFileHandle) Write(b []byte) (n int, err error) {
 	err = fh.writeFn(func() error {
-		n, err = fh.File.Write(b)
+		n, err = fh.fd.Write(b)
 		return err
 	})
 	return n, err
@@ -485,7 +496,7 @@ func (fh *RWFileHandle) Write(b []byte) (n int, err error) {
 // WriteAt bytes to the file at off
 func (fh *RWFileHandle) WriteAt(b []byte, off int64) (n int, err error) {
 	err = fh.writeFn(func() error {
-		n, err = fh.File.WriteAt(b, off)
+		n, err = fh.fd.WriteAt(b, off)
 		return err
 	})
 	return n, err
@@ -494,7 +505,7 @@ func (fh *RWFileHandle) WriteAt(b []byte, off int64) (n int, err error) {
 // WriteString a string to the file
 func (fh *RWFileHandle) WriteString(s string) (n int, err error) {
 	err = fh.writeFn(func() error {
-		n, err = fh.File.WriteString(s)
+		n, err = fh.fd.WriteString(s)
 		return err
 	})
 	return n, err
@@ -512,7 +523,7 @@ func (fh *RWFileHandle) Truncate(size int64) (err error) {
 	}
 	fh.changed = true
 	fh.file.setSize(size)
-	return fh.File.Truncate(size)
+	return fh.fd.Truncate(size)
 }
 
 // Sync commits the current contents of the file to stable storage. Typically,
@@ -530,7 +541,7 @@ func (fh *RWFileHandle) Sync() error {
 	if fh.flags&accessModeMask == os.O_RDONLY {
 		return nil
 	}
-	return fh.File.Sync()
+	return fh.fd.Sync()
 }
 
 func (fh *RWFileHandle) logPrefix() string {
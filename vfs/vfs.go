@@ -30,6 +30,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/rc"
 )
 
 // DefaultOpt is the default values uses for Opt
@@ -48,8 +49,11 @@ var DefaultOpt = Options{
 	CacheMode:         CacheModeOff,
 	CacheMaxAge:       3600 * time.Second,
 	CachePollInterval: 60 * time.Second,
+	WriteBack:         0,
+	CacheEncrypt:      false,
 	ChunkSize:         128 * fs.MebiByte,
 	ChunkSizeLimit:    -1,
+	CaseInsensitive:   false,
 }
 
 // Node represents either a directory (*Dir) or a file (*File)
@@ -168,15 +172,19 @@ var (
 
 // VFS represents the top level filing system
 type VFS struct {
-	f         fs.Fs
-	root      *Dir
-	Opt       Options
-	cache     *cache
-	cancel    context.CancelFunc
-	usageMu   sync.Mutex
-	usageTime time.Time
-	usage     *fs.Usage
-	pollChan  chan time.Duration
+	f          fs.Fs
+	root       *Dir
+	Opt        Options
+	cache      *cache
+	writeBack  *writeBack
+	cancel     context.CancelFunc
+	usageMu    sync.Mutex
+	usageTime  time.Time
+	usage      *fs.Usage
+	pollChan   chan time.Duration
+	inUse      int   // number of users of a shared VFS - protected by activeMu
+	openFiles  int32 // number of open file handles - read and written with atomic
+	writeFiles int32 // number of files open for write - read and written with atomic
 }
 
 // Options is options for creating the vfs
@@ -197,6 +205,9 @@ type Options struct {
 	CacheMode         CacheMode
 	CacheMaxAge       time.Duration
 	CachePollInterval time.Duration
+	WriteBack         time.Duration // if > 0 upload files this long after they are closed in the background
+	CacheEncrypt      bool          // if set encrypt the files in the cache with a key from the crypt remote
+	CaseInsensitive   bool          // if set look up file names ignoring case
 }
 
 // New creates a new VFS and root directory.  If opt is nil, then
@@ -214,12 +225,7 @@ func New(f fs.Fs, opt *Options) *VFS {
 		vfs.Opt = DefaultOpt
 	}
 
-	// Mask the permissions with the umask
-	vfs.Opt.DirPerms &= ^os.FileMode(vfs.Opt.Umask)
-	vfs.Opt.FilePerms &= ^os.FileMode(vfs.Opt.Umask)
-
-	// Make sure directories are returned as directories
-	vfs.Opt.DirPerms |= os.ModeDir
+	vfs.maskPerms()
 
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
@@ -240,10 +246,103 @@ func New(f fs.Fs, opt *Options) *VFS {
 	return vfs
 }
 
+// maskPerms masks the permissions with the umask and makes sure
+// directories are returned as directories
+func (vfs *VFS) maskPerms() {
+	vfs.Opt.DirPerms &= ^os.FileMode(vfs.Opt.Umask)
+	vfs.Opt.FilePerms &= ^os.FileMode(vfs.Opt.Umask)
+	vfs.Opt.DirPerms |= os.ModeDir
+}
+
+// Active VFS instances which can be shared between users, indexed
+// by remote name
+var (
+	activeMu sync.Mutex
+	active   = map[string][]*VFS{}
+)
+
+// activeKey returns the key used to index f in the active VFS map
+func activeKey(f fs.Fs) string {
+	return f.Name() + ":" + f.Root()
+}
+
+// NewShared returns a VFS for f with the options passed in.  If opt
+// is nil, then DefaultOpt will be used.
+//
+// If there is already a shared VFS active for the same remote with
+// the same options then that is returned instead of making a new
+// one.  This means that mounts and serve commands for the same
+// remote in the same process share a single directory and file
+// cache, so changes made through one are immediately visible
+// through the others.
+//
+// Call Shutdown on the VFS when finished with it.
+func NewShared(f fs.Fs, opt *Options) *VFS {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if opt == nil {
+		opt = &DefaultOpt
+	}
+	key := activeKey(f)
+	for _, vfs := range active[key] {
+		if vfs.sameOptions(opt) {
+			fs.Debugf(f, "Re-using active VFS")
+			vfs.inUse++
+			return vfs
+		}
+	}
+	vfs := New(f, opt)
+	vfs.inUse = 1
+	active[key] = append(active[key], vfs)
+	return vfs
+}
+
+// sameOptions returns true if opt would make a VFS with the same
+// options as vfs
+func (vfs *VFS) sameOptions(opt *Options) bool {
+	other := VFS{Opt: *opt}
+	other.maskPerms()
+	return vfs.Opt == other.Opt
+}
+
+// release decrements the use count of a shared VFS, removing it from
+// the active list when it is no longer in use.
+//
+// It returns true if the VFS is no longer in use.
+func (vfs *VFS) release() bool {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if vfs.inUse <= 0 {
+		return true
+	}
+	vfs.inUse--
+	if vfs.inUse > 0 {
+		return false
+	}
+	key := activeKey(vfs.f)
+	vfses := active[key]
+	for i, activeVFS := range vfses {
+		if activeVFS == vfs {
+			vfses = append(vfses[:i], vfses[i+1:]...)
+			break
+		}
+	}
+	if len(vfses) == 0 {
+		delete(active, key)
+	} else {
+		active[key] = vfses
+	}
+	return true
+}
+
 // SetCacheMode change the cache mode
 func (vfs *VFS) SetCacheMode(cacheMode CacheMode) {
-	vfs.Shutdown()
+	vfs.stopCache()
 	vfs.cache = nil
+	vfs.writeBack = nil
+	if vfs.Opt.WriteBack > 0 && vfs.Opt.CacheMode < CacheModeWrites {
+		fs.Errorf(nil, "--vfs-write-back needs --vfs-cache-mode writes or full - ignoring it")
+	}
 	if vfs.Opt.CacheMode > CacheModeOff {
 		ctx, cancel := context.WithCancel(context.Background())
 		cache, err := newCache(ctx, vfs.f, &vfs.Opt) // FIXME pass on context or get from Opt?
@@ -255,11 +354,26 @@ func (vfs *VFS) SetCacheMode(cacheMode CacheMode) {
 		}
 		vfs.cancel = cancel
 		vfs.cache = cache
+		if vfs.Opt.WriteBack > 0 && vfs.Opt.CacheMode >= CacheModeWrites {
+			vfs.writeBack = newWriteBack(ctx, vfs)
+		}
 	}
 }
 
 // Shutdown stops any background go-routines
+//
+// If the VFS was made with NewShared then this only takes effect when
+// the last user of the VFS calls it.
 func (vfs *VFS) Shutdown() {
+	if !vfs.release() {
+		return
+	}
+	removeStats(vfs)
+	vfs.stopCache()
+}
+
+// stopCache stops the cache background go-routines if running
+func (vfs *VFS) stopCache() {
 	if vfs.cancel != nil {
 		vfs.cancel()
 		vfs.cancel = nil
@@ -274,11 +388,37 @@ func (vfs *VFS) CleanUp() error {
 	return vfs.cache.cleanUp()
 }
 
+// Stats returns info about the VFS for monitoring
+func (vfs *VFS) Stats() (out rc.Params) {
+	out = make(rc.Params)
+	out["fs"] = vfs.f.Name() + ":" + vfs.f.Root()
+	out["openFiles"] = atomic.LoadInt32(&vfs.openFiles)
+	out["filesOpenForWrite"] = atomic.LoadInt32(&vfs.writeFiles)
+	if vfs.Opt.CacheMode > CacheModeOff {
+		files, bytes := vfs.cache.usage()
+		out["diskCache"] = rc.Params{
+			"path":          vfs.cache.root,
+			"files":         files,
+			"bytesUsed":     bytes,
+			"uploadsQueued": vfs.writeBack.queued(),
+		}
+	}
+	return out
+}
+
 // FlushDirCache empties the directory cache
 func (vfs *VFS) FlushDirCache() {
 	vfs.root.ForgetAll()
 }
 
+// Refresh empties the directory cache and re-reads the root
+// directory from the remote so changes made on the remote outside
+// the VFS become visible straight away
+func (vfs *VFS) Refresh() error {
+	vfs.FlushDirCache()
+	return vfs.root.readDir()
+}
+
 // WaitForWriters sleeps until all writers have finished or
 // time.Duration has elapsed
 func (vfs *VFS) WaitForWriters(timeout time.Duration) {
@@ -496,5 +636,14 @@ func (vfs *VFS) Statfs() (total, used, free int64) {
 			used = *u.Used
 		}
 	}
+	// Work out any missing value from the other two
+	switch {
+	case total < 0 && used >= 0 && free >= 0:
+		total = used + free
+	case used < 0 && total >= 0 && free >= 0:
+		used = total - free
+	case free < 0 && total >= 0 && used >= 0:
+		free = total - used
+	}
 	return
 }
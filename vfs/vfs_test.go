@@ -8,6 +8,9 @@ import (
 	"testing"
 
 	_ "github.com/ncw/rclone/backend/all" // import all the backends
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/rc"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -252,6 +255,36 @@ func TestVFSRename(t *testing.T) {
 	assert.Equal(t, os.ErrNotExist, err)
 }
 
+func TestVFSRefresh(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	vfs := New(r.Fremote, nil)
+
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	root, err := vfs.Root()
+	require.NoError(t, err)
+	nodes, err := root.ReadDirAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(nodes))
+
+	// Change the remote behind the VFS's back
+	file2 := r.WriteObject("file2", "file2 contents", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// Cached listing is unchanged
+	nodes, err = root.ReadDirAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(nodes))
+
+	// Refresh should pick up the new file
+	require.NoError(t, vfs.Refresh())
+	nodes, err = root.ReadDirAll()
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(nodes))
+}
+
 func TestVFSStatfs(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -267,18 +300,12 @@ func TestVFSStatfs(t *testing.T) {
 	assert.False(t, vfs.usageTime.IsZero())
 	if vfs.usage.Total != nil {
 		assert.Equal(t, *vfs.usage.Total, total)
-	} else {
-		assert.Equal(t, -1, total)
 	}
 	if vfs.usage.Free != nil {
 		assert.Equal(t, *vfs.usage.Free, free)
-	} else {
-		assert.Equal(t, -1, free)
 	}
 	if vfs.usage.Used != nil {
 		assert.Equal(t, *vfs.usage.Used, used)
-	} else {
-		assert.Equal(t, -1, used)
 	}
 
 	// read cached
@@ -290,4 +317,138 @@ func TestVFSStatfs(t *testing.T) {
 	assert.Equal(t, used, used2)
 	assert.Equal(t, free, free2)
 	assert.Equal(t, oldTime, vfs.usageTime)
+
+	// missing values are worked out from the others
+	n := func(i int64) *int64 { return &i }
+	for _, test := range []struct {
+		usage             fs.Usage
+		total, used, free int64
+	}{
+		{fs.Usage{Total: n(100), Used: n(30), Free: n(60)}, 100, 30, 60},
+		{fs.Usage{Used: n(30), Free: n(60)}, 90, 30, 60},
+		{fs.Usage{Total: n(100), Free: n(60)}, 100, 40, 60},
+		{fs.Usage{Total: n(100), Used: n(30)}, 100, 30, 70},
+		{fs.Usage{Used: n(30)}, -1, 30, -1},
+		{fs.Usage{}, -1, -1, -1},
+	} {
+		usage := test.usage
+		vfs.usage = &usage
+		total, used, free := vfs.Statfs()
+		assert.Equal(t, test.total, total)
+		assert.Equal(t, test.used, used)
+		assert.Equal(t, test.free, free)
+	}
+}
+
+func TestVFSNewShared(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Same remote and options should share the VFS
+	vfs1 := NewShared(r.Fremote, nil)
+	vfs2 := NewShared(r.Fremote, &DefaultOpt)
+	assert.True(t, vfs1 == vfs2)
+	assert.Equal(t, 2, vfs1.inUse)
+
+	// Different options should make a new VFS
+	var opt = DefaultOpt
+	opt.ReadOnly = true
+	vfs3 := NewShared(r.Fremote, &opt)
+	assert.False(t, vfs1 == vfs3)
+	assert.Equal(t, 2, len(active[activeKey(r.Fremote)]))
+
+	// An unshared VFS isn't added to the active list
+	vfs4 := New(r.Fremote, nil)
+	assert.False(t, vfs1 == vfs4)
+	assert.Equal(t, 2, len(active[activeKey(r.Fremote)]))
+	vfs4.Shutdown()
+
+	// Changes made through one user are visible through the other
+	fd, err := vfs1.OpenFile("file1", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	node, err := vfs2.Stat("file1")
+	require.NoError(t, err)
+	assert.True(t, node.IsFile())
+
+	// Shutdown only removes the VFS when the last user has finished
+	vfs1.Shutdown()
+	assert.Equal(t, 2, len(active[activeKey(r.Fremote)]))
+	vfs2.Shutdown()
+	assert.Equal(t, 1, len(active[activeKey(r.Fremote)]))
+	vfs3.Shutdown()
+	_, found := active[activeKey(r.Fremote)]
+	assert.False(t, found)
+
+	// A new VFS is made once the old one has been shut down
+	vfs5 := NewShared(r.Fremote, nil)
+	assert.False(t, vfs1 == vfs5)
+	vfs5.Shutdown()
+}
+
+func TestVFSStats(t *testing.T) {
+	r := fstest.NewRun(t)
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeWrites
+	vfs := New(r.Fremote, &opt)
+	defer cleanup(t, r, vfs)
+
+	// Count any files other tests left in the cache now rather
+	// than letting the cleaner find them part way through
+	require.NoError(t, vfs.cache.updateAtimes())
+
+	stats := vfs.Stats()
+	assert.Equal(t, int32(0), stats["openFiles"])
+	assert.Equal(t, int32(0), stats["filesOpenForWrite"])
+	diskCache, ok := stats["diskCache"].(rc.Params)
+	require.True(t, ok)
+	files, bytesUsed := diskCache["files"].(int), diskCache["bytesUsed"].(int64)
+	assert.Equal(t, 0, diskCache["uploadsQueued"])
+
+	fd1, err := vfs.OpenFile("statsfile", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = fd1.WriteString("hello")
+	require.NoError(t, err)
+	fd2, err := vfs.OpenFile("statsfile", os.O_RDONLY, 0)
+	require.NoError(t, err)
+
+	stats = vfs.Stats()
+	assert.Equal(t, int32(2), stats["openFiles"])
+	assert.Equal(t, int32(1), stats["filesOpenForWrite"])
+
+	// The disk cache usage is updated when the files are closed
+	require.NoError(t, fd1.Close())
+	require.NoError(t, fd2.Close())
+	stats = vfs.Stats()
+	assert.Equal(t, int32(0), stats["openFiles"])
+	assert.Equal(t, int32(0), stats["filesOpenForWrite"])
+	diskCache = stats["diskCache"].(rc.Params)
+	assert.Equal(t, files+1, diskCache["files"])
+	assert.Equal(t, bytesUsed+5, diskCache["bytesUsed"])
+
+	node, err := vfs.Stat("statsfile")
+	require.NoError(t, err)
+	require.NoError(t, node.Remove())
+	diskCache = vfs.Stats()["diskCache"].(rc.Params)
+	assert.Equal(t, files, diskCache["files"])
+	assert.Equal(t, bytesUsed, diskCache["bytesUsed"])
+
+	// The stats should be in core/stats too until the VFS is shut
+	// down.  Other tests may have left VFSes for the same remote
+	// so count them.
+	inCoreStats := func() (n int) {
+		out, err := accounting.Stats.RemoteStats(nil)
+		require.NoError(t, err)
+		all, _ := out["vfs"].([]rc.Params)
+		for _, stats := range all {
+			if stats["fs"] == vfs.Stats()["fs"] {
+				n++
+			}
+		}
+		return n
+	}
+	n := inCoreStats()
+	assert.True(t, n > 0)
+	vfs.Shutdown()
+	assert.Equal(t, n-1, inCoreStats())
 }
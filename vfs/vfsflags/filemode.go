@@ -0,0 +1,37 @@
+package vfsflags
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// FileMode is a command line friendly os.FileMode
+type FileMode struct {
+	Mode *os.FileMode
+}
+
+// String turns FileMode into a string
+func (x *FileMode) String() string {
+	return fmt.Sprintf("0%03o", x.Mode.Perm())
+}
+
+// Set a FileMode
+func (x *FileMode) Set(s string) error {
+	i, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return errors.Wrap(err, "bad file mode - must be octal digits")
+	}
+	if i&^uint64(os.ModePerm) != 0 {
+		return errors.Errorf("bad file mode %q - must be no more than 0777", s)
+	}
+	*x.Mode = (*x.Mode &^ os.ModePerm) | os.FileMode(i)
+	return nil
+}
+
+// Type of the value
+func (x *FileMode) Type() string {
+	return "int"
+}
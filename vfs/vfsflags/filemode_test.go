@@ -0,0 +1,42 @@
+package vfsflags
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*FileMode)(nil)
+
+func TestFileMode(t *testing.T) {
+	mode := os.ModeDir | 0777
+	x := FileMode{Mode: &mode}
+	assert.Equal(t, "0777", x.String())
+
+	for _, test := range []struct {
+		in   string
+		want os.FileMode
+		err  bool
+	}{
+		{in: "755", want: os.ModeDir | 0755},
+		{in: "0700", want: os.ModeDir | 0700},
+		{in: "0", want: os.ModeDir},
+		{in: "potato", err: true},
+		{in: "789", err: true},
+		{in: "1777", err: true},
+	} {
+		mode = os.ModeDir | 0777
+		err := x.Set(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+			assert.Equal(t, os.ModeDir|0777, mode, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+			assert.Equal(t, test.want, mode, test.in)
+		}
+	}
+	assert.Equal(t, "int", x.Type())
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -180,6 +181,7 @@ func (fh *WriteFileHandle) close() (err error) {
 		return ECLOSED
 	}
 	fh.closed = true
+	atomic.AddInt32(&fh.file.d.vfs.openFiles, -1)
 	// leave writer open until file is transferred
 	defer func() {
 		fh.file.delWriter(fh, false)
@@ -0,0 +1,399 @@
+package vfs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+const (
+	writeBackMinSleep = time.Second     // initial time to wait before retrying a failed upload
+	writeBackMaxSleep = 5 * time.Minute // maximum time to wait before retrying a failed upload
+)
+
+// writeBack uploads files from the cache to the remote in the
+// background.
+//
+// Files are uploaded in the order they were closed in.  If an upload
+// fails it is retried with exponential backoff and the files queued
+// after it wait for it to succeed.
+//
+// The queue is persisted in the cache directory so uploads which
+// were pending when rclone stopped are resumed when it next starts.
+type writeBack struct {
+	vfs       *VFS
+	statePath string        // where the queue is persisted
+	kick      chan struct{} // sent to when the queue changes
+	uploadMu  sync.Mutex    // held while uploading so only one upload runs at once
+
+	mu    sync.Mutex       // protects the following
+	items []*writeBackItem // the upload queue in upload order
+}
+
+// writeBackItem is a file waiting to be uploaded
+type writeBackItem struct {
+	Remote string    // path of the file
+	Expiry time.Time // time to upload the file after
+	Tries  int       // number of failed uploads
+	file   *File     // file to update when uploaded - may be nil
+}
+
+// newWriteBack creates a write back queue for vfs, restoring any
+// uploads which were pending and starts the uploader running.
+//
+// The uploader exits when ctx is cancelled.
+func newWriteBack(ctx context.Context, vfs *VFS) *writeBack {
+	wb := &writeBack{
+		vfs:       vfs,
+		statePath: filepath.Join(vfs.cache.metaRoot, "writeback.json"),
+		kick:      make(chan struct{}, 1),
+	}
+	err := wb.load()
+	if err != nil {
+		fs.Errorf(nil, "vfs write back: failed to restore upload queue: %v", err)
+	}
+	go wb.uploader(ctx)
+	return wb
+}
+
+// load restores the queue from the state file
+func (wb *writeBack) load() error {
+	data, err := ioutil.ReadFile(wb.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var items []*writeBackItem
+	err = json.Unmarshal(data, &items)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode upload queue")
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	now := time.Now()
+	for _, item := range items {
+		if _, err := wb.vfs.cache.stat(item.Remote); err != nil {
+			fs.Errorf(item.Remote, "vfs write back: can't resume upload: %v", err)
+			continue
+		}
+		fs.Infof(item.Remote, "vfs write back: resuming upload")
+		item.Expiry = now
+		item.Tries = 0
+		wb.vfs.cache.open(item.Remote)
+		wb.items = append(wb.items, item)
+	}
+	return wb._save()
+}
+
+// _save persists the queue to the state file - call with the lock held
+func (wb *writeBack) _save() error {
+	if len(wb.items) == 0 {
+		err := os.Remove(wb.statePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(wb.items)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(wb.statePath), 0700)
+	if err != nil {
+		return err
+	}
+	tmpPath := wb.statePath + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, wb.statePath)
+}
+
+// save persists the queue logging any errors - call with the lock held
+func (wb *writeBack) save() {
+	err := wb._save()
+	if err != nil {
+		fs.Errorf(nil, "vfs write back: failed to save upload queue: %v", err)
+	}
+}
+
+// _find returns the index of remote in the queue or -1 if not found
+//
+// call with the lock held
+func (wb *writeBack) _find(remote string) int {
+	for i, item := range wb.items {
+		if item.Remote == remote {
+			return i
+		}
+	}
+	return -1
+}
+
+// _delete removes the item at index i from the queue
+//
+// call with the lock held
+func (wb *writeBack) _delete(i int) {
+	wb.items = append(wb.items[:i], wb.items[i+1:]...)
+}
+
+// signal the uploader that the queue has changed
+func (wb *writeBack) signal() {
+	select {
+	case wb.kick <- struct{}{}:
+	default:
+	}
+}
+
+// add queues file for upload from the cache.
+//
+// If the file is already queued it is moved to the back of the queue.
+func (wb *writeBack) add(file *File, remote string) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if i := wb._find(remote); i >= 0 {
+		wb._delete(i)
+	} else {
+		// keep the file in the cache until it is uploaded
+		wb.vfs.cache.open(remote)
+	}
+	fs.Debugf(remote, "vfs write back: queued for upload in %v", wb.vfs.Opt.WriteBack)
+	wb.items = append(wb.items, &writeBackItem{
+		Remote: remote,
+		Expiry: time.Now().Add(wb.vfs.Opt.WriteBack),
+		file:   file,
+	})
+	wb.save()
+	wb.signal()
+}
+
+// pending returns true if remote is waiting to be uploaded
+func (wb *writeBack) pending(remote string) bool {
+	if wb == nil {
+		return false
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return wb._find(remote) >= 0
+}
+
+// info returns the info of the cache file for remote if it is
+// waiting to be uploaded, or nil if it isn't.
+//
+// This is used to report the size and modification time of files
+// which are newer in the cache than on the remote.
+func (wb *writeBack) info(remote string) os.FileInfo {
+	if !wb.pending(remote) {
+		return nil
+	}
+	fi, err := wb.vfs.cache.stat(remote)
+	if err != nil {
+		return nil
+	}
+	return fi
+}
+
+// pendingIn calls fn with the leaf name of each file in dir waiting
+// to be uploaded.  fn should return the File for the leaf which is
+// recorded in the queue if it didn't have one already, eg because it
+// was restored from the persisted queue.
+//
+// This is used to stop files which aren't on the remote yet
+// disappearing from the directory when it is re-read.
+func (wb *writeBack) pendingIn(dir string, fn func(leaf string) *File) {
+	if wb == nil {
+		return
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	for _, item := range wb.items {
+		if findParent(item.Remote) != dir {
+			continue
+		}
+		file := fn(path.Base(item.Remote))
+		if item.file == nil {
+			item.file = file
+		}
+	}
+}
+
+// queued returns the number of files waiting to be uploaded
+func (wb *writeBack) queued() int {
+	if wb == nil {
+		return 0
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return len(wb.items)
+}
+
+// remove cancels the upload of remote if it is queued, eg because
+// the file has been deleted.
+func (wb *writeBack) remove(remote string) {
+	if wb == nil {
+		return
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	i := wb._find(remote)
+	if i < 0 {
+		return
+	}
+	fs.Debugf(remote, "vfs write back: upload cancelled")
+	wb._delete(i)
+	wb.vfs.cache.close(remote)
+	wb.save()
+	wb.signal()
+}
+
+// uploadNow uploads remote straight away if it is queued, eg before
+// the file is renamed.
+func (wb *writeBack) uploadNow(remote string) error {
+	if wb == nil {
+		return nil
+	}
+	wb.uploadMu.Lock()
+	defer wb.uploadMu.Unlock()
+	wb.mu.Lock()
+	i := wb._find(remote)
+	if i < 0 {
+		wb.mu.Unlock()
+		return nil
+	}
+	item := wb.items[i]
+	wb.mu.Unlock()
+	err := wb.upload(item)
+	wb.done(item, err)
+	return err
+}
+
+// upload transfers the file in item from the cache to the remote
+//
+// call with uploadMu held
+func (wb *writeBack) upload(item *writeBackItem) error {
+	o, err := wb.transfer(item)
+	if err != nil {
+		return err
+	}
+	if item.file != nil {
+		item.file.setObject(o)
+	}
+	fs.Debugf(o, "transferred to remote")
+	return nil
+}
+
+// transfer copies the cache file for item to the remote
+//
+// If the file is opened for write while it is being transferred then
+// it will be queued again when it is closed.
+func (wb *writeBack) transfer(item *writeBackItem) (fs.Object, error) {
+	var dst fs.Object
+	if item.file != nil {
+		dst = item.file.getObject()
+	} else {
+		dst, _ = wb.vfs.f.NewObject(item.Remote)
+	}
+	cacheObj, err := wb.vfs.cache.f.NewObject(item.Remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find cache file")
+	}
+	o, err := copyObj(wb.vfs.f, dst, item.Remote, cacheObj)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to transfer file from cache to remote")
+	}
+	return o, nil
+}
+
+// done removes item from the queue if err is nil, otherwise it
+// schedules a retry with exponential backoff.
+func (wb *writeBack) done(item *writeBackItem, err error) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	i := -1
+	for j := range wb.items {
+		if wb.items[j] == item {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		// item was removed or re-queued while uploading
+		return
+	}
+	if err != nil {
+		item.Tries++
+		sleep := writeBackMinSleep << uint(item.Tries-1)
+		if sleep > writeBackMaxSleep || sleep <= 0 {
+			sleep = writeBackMaxSleep
+		}
+		fs.Errorf(item.Remote, "vfs write back: upload failed (try %d) - retrying in %v: %v", item.Tries, sleep, err)
+		item.Expiry = time.Now().Add(sleep)
+		return
+	}
+	wb._delete(i)
+	wb.vfs.cache.close(item.Remote)
+	wb.save()
+}
+
+// next returns the item at the head of the queue if it is ready to
+// be uploaded, or how long to wait before checking again
+func (wb *writeBack) next() (item *writeBackItem, wait time.Duration) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if len(wb.items) == 0 {
+		return nil, -1
+	}
+	item = wb.items[0]
+	if wait = item.Expiry.Sub(time.Now()); wait > 0 {
+		return nil, wait
+	}
+	if item.file != nil && item.file.activeWriters() > 0 {
+		// the file will be queued again when it is closed
+		return nil, writeBackMinSleep
+	}
+	return item, 0
+}
+
+// uploader uploads the items in the queue in order until ctx is
+// cancelled
+func (wb *writeBack) uploader(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		wb.uploadMu.Lock()
+		item, wait := wb.next()
+		if item != nil {
+			wb.done(item, wb.upload(item))
+			wb.uploadMu.Unlock()
+			continue
+		}
+		wb.uploadMu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if wait >= 0 {
+			timer.Reset(wait)
+		}
+		select {
+		case <-ctx.Done():
+			if n := wb.queued(); n > 0 {
+				fs.Infof(nil, "vfs write back: %d uploads pending - they will be resumed when rclone is next run with this cache", n)
+			}
+			return
+		case <-wb.kick:
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package vfs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWriteBackVFS makes a VFS which writes back files after delay
+func newWriteBackVFS(t *testing.T, r *fstest.Run, delay time.Duration) *VFS {
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeWrites
+	opt.WriteBack = delay
+	vfs := New(r.Fremote, &opt)
+	require.NotNil(t, vfs.writeBack)
+	return vfs
+}
+
+// writeBackWrite writes contents to name through the vfs
+func writeBackWrite(t *testing.T, vfs *VFS, name, contents string) {
+	h, err := vfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0777)
+	require.NoError(t, err)
+	_, err = h.WriteString(contents)
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+}
+
+// waitForWriteBack waits for the upload queue to empty
+func waitForWriteBack(t *testing.T, vfs *VFS) {
+	for i := 0; i < 100 && vfs.writeBack.queued() > 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.Equal(t, 0, vfs.writeBack.queued())
+}
+
+// checkRemote checks that name has contents on the remote
+func checkRemote(t *testing.T, f fs.Fs, name, contents string) {
+	o, err := f.NewObject(name)
+	require.NoError(t, err)
+	in, err := o.Open()
+	require.NoError(t, err)
+	buf, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(buf))
+}
+
+func TestWriteBack(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(t, r, 500*time.Millisecond)
+	defer cleanup(t, r, vfs)
+
+	writeBackWrite(t, vfs, "file1", "hello")
+	assert.True(t, vfs.writeBack.pending("file1"))
+	assert.Equal(t, 1, vfs.writeBack.queued())
+	_, err := r.Fremote.NewObject("file1")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Should be readable from the cache before it is uploaded
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	buf, err := ioutil.ReadAll(h)
+	require.NoError(t, err)
+	require.NoError(t, h.Close())
+	assert.Equal(t, "hello", string(buf))
+
+	waitForWriteBack(t, vfs)
+	checkRemote(t, r.Fremote, "file1", "hello")
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	assert.NotNil(t, node.(*File).getObject())
+}
+
+func TestWriteBackRemove(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(t, r, time.Hour)
+	defer cleanup(t, r, vfs)
+
+	writeBackWrite(t, vfs, "file1", "hello")
+	assert.Equal(t, 1, vfs.writeBack.queued())
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	require.NoError(t, node.Remove())
+	assert.Equal(t, 0, vfs.writeBack.queued())
+	fstest.CheckItems(t, r.Fremote)
+}
+
+func TestWriteBackRename(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(t, r, time.Hour)
+	defer cleanup(t, r, vfs)
+
+	writeBackWrite(t, vfs, "file1", "hello")
+	require.NoError(t, vfs.Rename("file1", "file2"))
+	assert.Equal(t, 0, vfs.writeBack.queued())
+	checkRemote(t, r.Fremote, "file2", "hello")
+}
+
+func TestWriteBackResume(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs1 := newWriteBackVFS(t, r, time.Hour)
+
+	writeBackWrite(t, vfs1, "file1", "hello")
+	assert.Equal(t, 1, vfs1.writeBack.queued())
+	vfs1.Shutdown()
+
+	// The upload should be resumed straight away by a new VFS
+	vfs2 := newWriteBackVFS(t, r, time.Hour)
+	defer cleanup(t, r, vfs2)
+	waitForWriteBack(t, vfs2)
+	checkRemote(t, r.Fremote, "file1", "hello")
+}
+
+func TestWriteBackRetry(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(t, r, time.Hour)
+	defer cleanup(t, r, vfs)
+
+	writeBackWrite(t, vfs, "file1", "hello")
+	writeBackWrite(t, vfs, "file2", "potato")
+	wb := vfs.writeBack
+	item := wb.items[0]
+	assert.Equal(t, "file1", item.Remote)
+
+	// Failed uploads should back off exponentially and stay at
+	// the head of the queue
+	for tries, sleep := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		start := time.Now()
+		wb.done(item, errors.New("upload failed"))
+		assert.Equal(t, tries+1, item.Tries)
+		assert.WithinDuration(t, start.Add(sleep), item.Expiry, time.Second)
+		assert.Equal(t, item, wb.items[0])
+		gotItem, wait := wb.next()
+		assert.Nil(t, gotItem)
+		assert.True(t, wait > 0)
+	}
+
+	item.Tries = 100
+	wb.done(item, errors.New("upload failed"))
+	assert.WithinDuration(t, time.Now().Add(writeBackMaxSleep), item.Expiry, time.Second)
+
+	// Uploading now should remove the file from the queue
+	require.NoError(t, wb.uploadNow("file1"))
+	assert.Equal(t, 1, wb.queued())
+	assert.Equal(t, "file2", wb.items[0].Remote)
+	checkRemote(t, r.Fremote, "file1", "hello")
+}
+
+func TestWriteBackListing(t *testing.T) {
+	r := fstest.NewRun(t)
+	vfs := newWriteBackVFS(t, r, time.Hour)
+	defer cleanup(t, r, vfs)
+	r.WriteObject("dir/existing", "old", t1)
+
+	writeBackWrite(t, vfs, "dir/new", "hello")
+	writeBackWrite(t, vfs, "dir/existing", "new contents")
+	assert.Equal(t, 2, vfs.writeBack.queued())
+
+	// Re-read the directory from the remote - the pending files
+	// should still be there with the size and time of the cache file
+	dir, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	require.NoError(t, dir.(*Dir).readDir())
+
+	node, err := vfs.Stat("dir/new")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), node.Size())
+	node, err = vfs.Stat("dir/existing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), node.Size())
+	assert.NotEqual(t, t1, node.ModTime())
+
+	// Once uploaded the remote object should be used
+	require.NoError(t, vfs.writeBack.uploadNow("dir/new"))
+	require.NoError(t, vfs.writeBack.uploadNow("dir/existing"))
+	require.NoError(t, dir.(*Dir).readDir())
+	node, err = vfs.Stat("dir/existing")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), node.Size())
+	assert.NotNil(t, node.(*File).getObject())
+}
+
+func TestWriteBackCacheModeMinimal(t *testing.T) {
+	r := fstest.NewRun(t)
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeMinimal
+	opt.WriteBack = time.Hour
+	vfs := New(r.Fremote, &opt)
+	defer cleanup(t, r, vfs)
+	assert.Nil(t, vfs.writeBack)
+}
+
+func TestWriteBackEncryptedSize(t *testing.T) {
+	r := fstest.NewRun(t)
+	opt := DefaultOpt
+	opt.CacheMode = CacheModeWrites
+	opt.WriteBack = 10 * time.Second
+	opt.CacheEncrypt = true
+	vfs := New(keyFs{Fs: r.Fremote}, &opt)
+	defer cleanup(t, r, vfs)
+	require.NotNil(t, vfs.writeBack)
+
+	// the size is that of the plaintext while the upload is pending
+	writeBackWrite(t, vfs, "file1", "hello")
+	require.True(t, vfs.writeBack.pending("file1"))
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), node.Size())
+	assert.Equal(t, int64(5), vfs.writeBack.info("file1").Size())
+
+	// and it is accounted for in the cache with that size
+	require.NoError(t, vfs.cache.updateAtimes())
+	vfs.cache.itemMu.Lock()
+	assert.Equal(t, int64(5), vfs.cache.item["file1"].size)
+	vfs.cache.itemMu.Unlock()
+}